@@ -0,0 +1,631 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/audio"
+	"moviepy-go/pkg/compositing"
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/registry"
+	"moviepy-go/pkg/render"
+	"moviepy-go/pkg/screencast"
+	"moviepy-go/pkg/subtitles"
+	"moviepy-go/pkg/video"
+)
+
+var infoCommand = &command{
+	name:    "info",
+	usage:   "<文件>",
+	summary: "显示媒体文件的容器、流和章节信息",
+	run:     runInfo,
+}
+
+// runInfo 打印 ffmpeg.Probe 的结果
+func runInfo(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	asJSON := fs.Bool("json", false, "以 JSON 输出完整信息")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+
+	probe, err := ffmpeg.Probe(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(probe)
+	}
+
+	format := probe.Format
+	fmt.Printf("文件: %s\n", format.Filename)
+	fmt.Printf("格式: %s (%s)\n", format.FormatName, format.FormatLongName)
+	fmt.Printf("时长: %.3fs  大小: %d 字节  码率: %d bit/s\n", format.Duration, format.Size, format.BitRate)
+	for _, stream := range probe.Streams {
+		fmt.Printf("流 #%d %s: %s", stream.Index, stream.CodecType, stream.CodecName)
+		switch stream.CodecType {
+		case "video":
+			fmt.Printf(" %dx%d %s %s fps", stream.Width, stream.Height, stream.PixelFormat, stream.FrameRate)
+			if depth := stream.Depth(); depth > 8 {
+				fmt.Printf(" %d-bit", depth)
+			}
+			if stream.Color.IsHDR() {
+				fmt.Printf(" HDR(%s/%s)", stream.Color.Transfer, stream.Color.Primaries)
+			}
+		case "audio":
+			fmt.Printf(" %d Hz %d 声道 %s", stream.SampleRate, stream.Channels, stream.ChannelLayout)
+		}
+		if lang := stream.Language(); lang != "" {
+			fmt.Printf(" [%s]", lang)
+		}
+		if stream.Disposition.Default {
+			fmt.Print(" (默认)")
+		}
+		fmt.Println()
+	}
+	for _, chapter := range probe.Chapters {
+		fmt.Printf("章节 %.3fs - %.3fs %s\n", chapter.StartTime, chapter.EndTime, chapter.Tags["title"])
+	}
+	return nil
+}
+
+var trimCommand = &command{
+	name:    "trim",
+	usage:   "[-smart] -o <输出> <输入>",
+	summary: "截取视频片段并重新编码，-smart 时只重新编码切点附近",
+	run:     runTrim,
+}
+
+// runTrim 对应 VideoFileClip.Subclip + WriteToFile，-smart 时对应 render.SmartCut
+func runTrim(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	var start, end timeFlag
+	fs.Var(&start, "start", "开始时间，默认 0")
+	fs.Var(&end, "end", "结束时间，默认到结尾")
+	output := fs.String("o", "", "输出文件")
+	smart := fs.Bool("smart", false, "复制关键帧之间的码流，只重新编码切点附近的帧（忽略编码选项，-codec 除外）")
+	var write writeFlags
+	write.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+
+	if *smart {
+		result, err := render.SmartCut(fs.Arg(0), []render.CutRange{{Start: start.value, End: end.value}}, *output, &render.SmartCutOptions{
+			Codec:      write.codec,
+			ProcessMgr: env.processMgr,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("复制 %v，重新编码 %v\n", result.Copied, result.Encoded)
+		return nil
+	}
+
+	clip, err := openVideo(env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer clip.Close()
+
+	subclip, err := trimClip(clip, start, end)
+	if err != nil {
+		return err
+	}
+	defer subclip.Close()
+	options, err := write.options(subclip)
+	if err != nil {
+		return err
+	}
+	return subclip.WriteToFile(*output, options)
+}
+
+var splitCommand = &command{
+	name:    "split",
+	usage:   "(-every <时长> | -at <时间,...>) -o <文件名模板> <输入>",
+	summary: "按固定时长或指定时间点把视频切成多个文件",
+	run:     runSplit,
+}
+
+// runSplit 对应 video.SplitEvery / video.SplitAt + video.WriteSegments
+func runSplit(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	var every timeFlag
+	fs.Var(&every, "every", "每段时长，如 60 或 2m30s")
+	at := fs.String("at", "", "逗号分隔的切点，如 10,1:30")
+	output := fs.String("o", "", "包含 %d 的输出文件名模板，如 part-%02d.mp4")
+	var write writeFlags
+	write.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+	if every.set == (*at != "") {
+		fs.Usage()
+		return fmt.Errorf("需要指定 -every 或 -at 之一")
+	}
+
+	clip, err := openVideo(env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer clip.Close()
+
+	var segments []core.Clip
+	if every.set {
+		segments, err = video.SplitEvery(clip, every.value)
+	} else {
+		var times []time.Duration
+		for _, part := range strings.Split(*at, ",") {
+			t, err := parseTime(part)
+			if err != nil {
+				return err
+			}
+			times = append(times, t)
+		}
+		segments, err = video.SplitAt(clip, times...)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, segment := range segments {
+			segment.Close()
+		}
+	}()
+	options, err := write.options(clip)
+	if err != nil {
+		return err
+	}
+	written, err := video.WriteSegments(segments, *output, options)
+	for _, filename := range written {
+		fmt.Println(filename)
+	}
+	return err
+}
+
+var concatCommand = &command{
+	name:    "concat",
+	usage:   "-o <输出> <输入1> <输入2> ...",
+	summary: "无损拼接编码参数相同的多个文件",
+	run:     runConcat,
+}
+
+// runConcat 对应 render.ConcatSegments
+func runConcat(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	output := fs.String("o", "", "输出文件")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 2, -1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+	return render.ConcatSegments(context.Background(), fs.Args(), *output, env.processMgr)
+}
+
+var resizeCommand = &command{
+	name:    "resize",
+	usage:   "-width <宽> [-height <高>] -o <输出> <输入>",
+	summary: "缩放视频，只指定一边时保持宽高比",
+	run:     runResize,
+}
+
+// runResize 对应 EffectVideoClip + ResizeEffect
+func runResize(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	width := fs.Int("width", 0, "输出宽度")
+	height := fs.Int("height", 0, "输出高度")
+	output := fs.String("o", "", "输出文件")
+	var write writeFlags
+	write.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+	if *width <= 0 && *height <= 0 {
+		fs.Usage()
+		return fmt.Errorf("需要指定 -width 或 -height")
+	}
+
+	clip, err := openVideo(env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer clip.Close()
+
+	resized := resizeClip(env, clip, *width, *height)
+	defer resized.Close()
+	options, err := write.options(resized)
+	if err != nil {
+		return err
+	}
+	return resized.WriteToFile(*output, options)
+}
+
+var gifCommand = &command{
+	name:    "gif",
+	usage:   "-o <输出.gif> <输入>",
+	summary: "将视频片段转换为 GIF 动图",
+	run:     runGIF,
+}
+
+// runGIF 截取、缩放后以 gif 编码器写出
+func runGIF(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	var start, end timeFlag
+	fs.Var(&start, "start", "开始时间，默认 0")
+	fs.Var(&end, "end", "结束时间，默认到结尾")
+	width := fs.Int("width", 480, "输出宽度，高度按比例计算")
+	fps := fs.Float64("fps", 10, "输出帧率")
+	output := fs.String("o", "", "输出文件")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+
+	clip, err := openVideo(env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer clip.Close()
+
+	subclip, err := trimClip(clip, start, end)
+	if err != nil {
+		return err
+	}
+	defer subclip.Close()
+
+	var gifClip core.VideoClip = subclip
+	if *width > 0 && *width < subclip.Width() {
+		resized := resizeClip(env, subclip, *width, 0)
+		defer resized.Close()
+		gifClip = resized
+	}
+	return gifClip.WriteToFile(*output, &core.WriteOptions{Codec: "gif", FPS: *fps})
+}
+
+var thumbnailCommand = &command{
+	name:    "thumbnail",
+	usage:   "[-t <时间> | -best] -o <输出.png|.jpg|.webp> <输入>",
+	summary: "将指定时间的帧或自动选出的封面帧保存为图片",
+	run:     runThumbnail,
+}
+
+// runThumbnail 对应 VideoFileClip.SaveFrame，-best 时先用 video.BestThumbnail 选帧
+func runThumbnail(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	var at timeFlag
+	fs.Var(&at, "t", "帧时间，默认 0")
+	best := fs.Bool("best", false, "按清晰度和曝光自动选择封面帧")
+	quality := fs.Int("quality", 0, "JPEG/WebP 质量（1-100），默认 90")
+	output := fs.String("o", "", "输出图片")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+
+	clip, err := openVideo(env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer clip.Close()
+
+	t := at.value
+	if *best {
+		if at.set {
+			return fmt.Errorf("-t 与 -best 不能同时使用")
+		}
+		result, err := video.BestThumbnail(clip, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("选中 %v（得分 %.2f）\n", result.Time, result.Score)
+		t = result.Time
+	}
+	return clip.SaveFrame(t, *output, &ffmpeg.ImageOptions{Quality: *quality})
+}
+
+var pluginsCommand = &command{
+	name:    "plugins",
+	usage:   "",
+	summary: "列出已注册的特效和剪辑来源",
+	run:     runPlugins,
+}
+
+// runPlugins 打印 registry 中的注册项
+func runPlugins(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 0, 0); err != nil {
+		return err
+	}
+	fmt.Printf("视频特效: %s\n", strings.Join(registry.VideoEffects(), " "))
+	fmt.Printf("音频特效: %s\n", strings.Join(registry.AudioEffects(), " "))
+	fmt.Printf("来源: file %s\n", strings.Join(registry.Sources(), " "))
+	return nil
+}
+
+var subtitlesCommand = &command{
+	name:    "subtitles",
+	usage:   "-ass <字幕.ass> [-native] -o <输出> <输入>",
+	summary: "将 ASS/SSA 字幕烧录到视频中",
+	run:     runSubtitles,
+}
+
+// runSubtitles 对应 ASSScript.Burn（libass），-native 时对应 NewASSClip + CompositeVideoClip
+func runSubtitles(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	assFile := fs.String("ass", "", "ASS/SSA 字幕文件")
+	fontsDir := fs.String("fontsdir", "", "字幕使用的额外字体目录（libass）")
+	native := fs.Bool("native", false, "在 Go 中渲染基本样式和定位，不依赖带 libass 的 FFmpeg")
+	output := fs.String("o", "", "输出文件")
+	var write writeFlags
+	write.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+	if *assFile == "" {
+		fs.Usage()
+		return fmt.Errorf("需要指定 -ass")
+	}
+	script, err := subtitles.ReadASS(*assFile)
+	if err != nil {
+		return err
+	}
+	if !*native {
+		return script.Burn(context.Background(), fs.Arg(0), *output, &subtitles.ASSBurnOptions{
+			FontsDir:   *fontsDir,
+			ProcessMgr: env.processMgr,
+		})
+	}
+
+	for _, feature := range script.NativeUnsupported() {
+		fmt.Fprintln(os.Stderr, "警告: -native 忽略", feature)
+	}
+	clip, err := openVideo(env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer clip.Close()
+	width, height := clip.Size()
+	layer := subtitles.NewASSClip(script, width, height, clip.Duration(), clip.FPS(), env.processMgr)
+	defer layer.Close()
+	composite, err := compositing.NewCompositeVideoClip([]core.VideoClip{clip, layer}, nil, compositing.Normal, env.processMgr)
+	if err != nil {
+		return err
+	}
+	defer composite.Close()
+	options, err := write.options(composite)
+	if err != nil {
+		return err
+	}
+	return composite.WriteToFile(*output, options)
+}
+
+var reviewCommand = &command{
+	name:    "review",
+	usage:   "-o <输出> <输入>",
+	summary: "导出烧录时间码和水印的低码率审阅副本",
+	run:     runReview,
+}
+
+// runReview 对应 render.ExportReviewCopy
+func runReview(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	width := fs.Int("width", 960, "代理宽度")
+	bitrate := fs.String("bitrate", "1500k", "视频码率")
+	watermark := fs.String("watermark", render.DefaultReviewWatermark, "水印文字，为空时不显示")
+	var offset timeFlag
+	fs.Var(&offset, "tc-start", "第一帧对应的时间码（时:分:秒），如 01:00:00")
+	drop := fs.Bool("drop-frame", false, "29.97/59.94 帧率下使用丢帧时间码")
+	frames := fs.Bool("frame-numbers", false, "在右上角显示帧号")
+	output := fs.String("o", "", "输出文件")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+
+	clip, err := openVideo(env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer clip.Close()
+	return render.ExportReviewCopy(clip, *output, &render.ReviewOptions{
+		Width:          *width,
+		Bitrate:        *bitrate,
+		Watermark:      *watermark,
+		NoWatermark:    *watermark == "",
+		TimecodeOffset: offset.value,
+		DropFrame:      *drop,
+		FrameNumbers:   *frames,
+		ProcessMgr:     env.processMgr,
+	})
+}
+
+var screencastCommand = &command{
+	name:    "screencast",
+	usage:   "-events <事件.jsonl> -o <输出> <录屏>",
+	summary: "按录制的鼠标和键盘事件高亮光标、显示按键并自动推近点击处",
+	run:     runScreencast,
+}
+
+// runScreencast 对应 screencast.Process，-no-* 关闭对应的处理
+func runScreencast(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	eventsFile := fs.String("events", "", "JSON Lines 事件文件（move/click/key）")
+	noCursor := fs.Bool("no-cursor", false, "不高亮光标和点击")
+	noKeys := fs.Bool("no-keys", false, "不显示按键")
+	noZoom := fs.Bool("no-zoom", false, "不自动推近")
+	zoom := fs.Float64("zoom", 2, "自动推近的放大倍数")
+	keysFile := fs.String("keys-ass", "", "同时把按键字幕写出为 ASS 文件")
+	output := fs.String("o", "", "输出文件")
+	var write writeFlags
+	write.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+	if *eventsFile == "" {
+		fs.Usage()
+		return fmt.Errorf("需要指定 -events")
+	}
+	events, err := screencast.ReadEvents(*eventsFile)
+	if err != nil {
+		return err
+	}
+
+	clip, err := openVideo(env, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer clip.Close()
+
+	options := &screencast.Options{}
+	if !*noCursor {
+		options.Cursor = &screencast.CursorOptions{}
+	}
+	if !*noKeys {
+		options.Keys = &screencast.KeyOptions{}
+	}
+	if !*noZoom {
+		options.Zoom = &screencast.ZoomOptions{Factor: *zoom}
+	}
+	if *keysFile != "" {
+		width, height := clip.Size()
+		if err := screencast.KeystrokeScript(events, width, height, options.Keys).WriteFile(*keysFile); err != nil {
+			return err
+		}
+	}
+	processed, err := screencast.Process(clip, events, options, env.processMgr)
+	if err != nil {
+		return err
+	}
+	defer processed.Close()
+	writeOptions, err := write.options(processed)
+	if err != nil {
+		return err
+	}
+	return processed.WriteToFile(*output, writeOptions)
+}
+
+var extractAudioCommand = &command{
+	name:    "extract-audio",
+	usage:   "-o <输出> <输入>",
+	summary: "提取音轨并编码为音频文件",
+	run:     runExtractAudio,
+}
+
+// runExtractAudio 对应 AudioFileClip.WriteToFile
+func runExtractAudio(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	codec := fs.String("codec", "", "音频编码器，默认 aac")
+	bitrate := fs.String("bitrate", "", "音频码率，默认 128k")
+	stream := fs.Int("stream", 0, "音频流序号（从 0 开始）")
+	language := fs.String("lang", "", "按语言选择音频流，如 eng")
+	output := fs.String("o", "", "输出文件")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireArgs(fs, 1, 1); err != nil {
+		return err
+	}
+	if err := requireOutput(fs, *output); err != nil {
+		return err
+	}
+
+	clip := audio.NewAudioFileClipWithOptions(fs.Arg(0), &audio.AudioFileClipOptions{
+		Stream: ffmpeg.StreamSelector{Index: *stream, Language: *language},
+	}, env.processMgr)
+	if err := clip.Open(); err != nil {
+		return err
+	}
+	defer clip.Close()
+	return clip.WriteToFile(*output, &core.WriteOptions{AudioCodec: *codec, AudioBitrate: *bitrate})
+}
+
+// openVideo 打开视频文件
+func openVideo(env *cliEnv, filename string) (*video.VideoFileClip, error) {
+	clip := video.NewVideoFileClip(filename, env.processMgr)
+	if err := clip.Open(); err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// trimClip 按 start/end 截取，未指定的一端保持不变
+func trimClip(clip core.VideoClip, start, end timeFlag) (core.VideoClip, error) {
+	to := clip.Duration()
+	if end.set {
+		to = end.value
+	}
+	subclip, err := clip.Subclip(start.value, to)
+	if err != nil {
+		return nil, err
+	}
+	videoClip, ok := subclip.(core.VideoClip)
+	if !ok {
+		return nil, fmt.Errorf("子剪辑不是视频剪辑")
+	}
+	return videoClip, nil
+}
+
+// resizeClip 缩放剪辑，宽或高为 0 时按比例计算并取偶数
+func resizeClip(env *cliEnv, clip core.VideoClip, width, height int) *video.EffectVideoClip {
+	if width <= 0 {
+		width = clip.Width() * height / clip.Height() &^ 1
+	}
+	if height <= 0 {
+		height = clip.Height() * width / clip.Width() &^ 1
+	}
+	resized := video.NewEffectVideoClip(clip, env.processMgr)
+	resized.AddEffect(effects.NewResizeEffect(width, height))
+	return resized
+}
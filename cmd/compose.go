@@ -0,0 +1,651 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/audio"
+	"moviepy-go/pkg/compositing"
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/expr"
+	"moviepy-go/pkg/registry"
+	"moviepy-go/pkg/render"
+	"moviepy-go/pkg/video"
+)
+
+var composeCommand = &command{
+	name:    "compose",
+	usage:   "-spec <project.json> [-data records.csv]",
+	summary: "按 JSON 工程文件叠加多个视频并导出，可按数据记录批量生成",
+	run:     runCompose,
+}
+
+// composeSpec 工程文件格式
+//
+//	{
+//	  "output": "out.mp4",
+//	  "mode": "overlay",
+//	  "audio": "music.mp3",
+//	  "clips": [
+//	    {"file": "bg.mp4", "start": "0", "end": "10"},
+//	    {"file": "glow.mp4", "mode": "screen"},
+//	    {"file": "logo.gif", "x": "20+100*t", "y": 20, "width": 200,
+//	     "opacity": "clamp(t, 0, 1)",
+//	     "effects": [{"name": "sepia", "params": {"strength": 0.6}}]},
+//	    {"file": "mark.png", "relative": true, "x": 1, "y": 1, "anchor": "bottom_right",
+//	     "offset_x": -20, "offset_y": -20},
+//	    {"text": "恭喜 {{name}}", "y": 800, "height": 200, "font_size": 96, "color": "#FFCC00",
+//	     "animation": "typewriter", "animation_delay": "0.5"},
+//	    {"lower_third": {"name": "{{name}}", "subtitle": "{{title}}", "delay": "1", "length": "5"}},
+//	    {"overlay_track": {"events": "kills.csv", "feed": "top_right"}, "font_size": 32}
+//	  ]
+//	}
+//
+// 第一个剪辑为背景，决定输出尺寸和帧率；设置了 width、height 或 background 时改为输出到独立的画布，
+// 背景剪辑也按其 x、y、scale 摆放。x、y、scale、opacity 和 brightness 的 factor
+// 可以写成以 t（秒）为变量的表达式（见 expr 包）。file 可以是带 scheme 的 uri，
+// effects 中的名称见 moviego plugins，均通过 registry 解析。
+//
+// text 图层在 width×height（默认输出尺寸）的透明画布上居中绘制文字，持续整个背景时长；
+// animation 为文字加上逐字、逐词或整体的入场动画。lower_third 图层在字幕安全区底部显示带底条的
+// 姓名和说明，按 delay、length 入场和出场。overlay_track 图层按事件文件（见 compositing.ReadOverlayEvents）
+// 在各事件时间显示短暂的文字和图标，未给出位置的事件在 feed 角落排成消息流。
+// 使用 -data 时工程文件中的 {{字段}} 由每条记录填充（包括 output 和图片、视频路径），
+// 每条记录导出一个文件。
+type composeSpec struct {
+	Output        string        `json:"output"`
+	Mode          string        `json:"mode"`    // overlay/add/multiply/screen/darken/lighten/normal，默认 overlay；图层可以用自己的 mode 覆盖
+	Audio         string        `json:"audio"`   // 替换音轨的音频文件，为空时使用背景剪辑的音轨
+	Codec         string        `json:"codec"`   // 视频编码器
+	Bitrate       string        `json:"bitrate"` // 视频码率
+	FPS           float64       `json:"fps"`
+	Strict        bool          `json:"strict"` // 图层取帧失败时中止导出，默认跳过该图层
+	Width         int           `json:"width"`  // 输出画布尺寸，默认为背景剪辑的尺寸
+	Height        int           `json:"height"`
+	Background    string        `json:"background"`    // 画布底色，"#RRGGBB"、"#RRGGBBAA" 或 "transparent"，默认黑色
+	Guides        bool          `json:"guides"`        // 在最上层画出安全区和三分线，用于预览布局
+	Premultiplied bool          `json:"premultiplied"` // 按预乘 alpha 的标准公式混合，半透明图层和透明画布上更准确
+	LinearLight   bool          `json:"linear_light"`  // 在线性光下混合图层并处理图层的模糊、缩放特效，隐含 premultiplied
+	Clips         []composeClip `json:"clips"`
+}
+
+// composeClip 工程中的一个图层
+type composeClip struct {
+	File   string         `json:"file"`
+	Text   string         `json:"text"`  // 文字图层内容，与 file 二选一
+	Start  string         `json:"start"` // 源文件中的开始时间
+	End    string         `json:"end"`   // 源文件中的结束时间
+	X      *animatedValue `json:"x"`
+	Y      *animatedValue `json:"y"`
+	Center bool           `json:"center"`
+	Mode   string         `json:"mode"` // 本图层的合成模式，默认使用工程的 mode
+	// Relative 为 true 时 x、y 是画布宽高的比例（0–1）；anchor 为图层上与 (x, y) 对齐的点，
+	// 如 center、bottom_right，默认 top_left；offset_x、offset_y 为附加的像素偏移
+	Relative bool            `json:"relative"`
+	Safe     string          `json:"safe"` // title 或 action：相对坐标以该安全区为准，隐含 relative
+	Anchor   string          `json:"anchor"`
+	OffsetX  int             `json:"offset_x"`
+	OffsetY  int             `json:"offset_y"`
+	Width    int             `json:"width"` // 缩放后的宽度，0 表示不缩放
+	Height   int             `json:"height"`
+	Scale    *animatedValue  `json:"scale"`   // 默认 1
+	Opacity  *animatedValue  `json:"opacity"` // 默认 1
+	Effects  []composeEffect `json:"effects"`
+	// 文字图层样式
+	Font     string `json:"font"`      // 字体文件
+	FontSize int    `json:"font_size"` // 默认画布高度的 1/4
+	Color    string `json:"color"`     // drawtext 颜色，默认 white
+	Border   int    `json:"border"`    // 黑色描边宽度
+	// Fonts 字体族名回退链，非拉丁文字和 emoji 按顺序选择字体（需要 pango-view）
+	Fonts []string `json:"fonts"`
+	Wrap  int      `json:"wrap"` // 自动换行宽度（像素），按 Unicode 规则断行
+	// 文字入场动画：typewriter、fade_words、slide_in 或 pop_in，为空时为静态文字
+	Animation         string         `json:"animation"`
+	AnimationDelay    string         `json:"animation_delay"`
+	AnimationDuration string         `json:"animation_duration"`
+	Easing            string         `json:"easing"`   // linear/ease_in/ease_out/ease_in_out/back
+	Progress          *animatedValue `json:"progress"` // 以 t 为变量的 0–1 动画进度，设置后忽略延迟、时长和缓动
+	// LowerThird 下三分之一字幕条图层，与 file、text 三选一；字体和字号（姓名）使用上面的文字样式
+	LowerThird *composeLowerThird `json:"lower_third"`
+	// OverlayTrack 事件叠加轨道图层，与 file、text、lower_third 四选一；使用上面的文字样式
+	OverlayTrack *composeOverlayTrack `json:"overlay_track"`
+}
+
+// composeOverlayTrack 叠加轨道的事件文件、消息流位置和动画
+type composeOverlayTrack struct {
+	Events     string `json:"events"`     // .json 或 .csv 事件文件
+	Feed       string `json:"feed"`       // 消息流所在的角落，如 top_right，默认 top_left
+	MaxFeed    int    `json:"max_feed"`   // 消息流同时显示的最多条目，默认 5
+	Duration   string `json:"duration"`   // 事件默认的显示时长，默认 4 秒
+	In         string `json:"in"`         // 入场动画时长，默认 0.2 秒
+	Out        string `json:"out"`        // 出场动画时长，默认 0.3 秒
+	Background string `json:"background"` // 条目底色，颜色格式见 registry.ParseColor
+}
+
+// composeLowerThird 字幕条的内容、配色和动画，颜色格式见 registry.ParseColor
+type composeLowerThird struct {
+	Name          string `json:"name"`
+	Subtitle      string `json:"subtitle"`
+	NameColor     string `json:"name_color"`     // drawtext 颜色
+	SubtitleColor string `json:"subtitle_color"` // drawtext 颜色
+	BarColor      string `json:"bar_color"`
+	AccentColor   string `json:"accent_color"`
+	Align         string `json:"align"`     // left/center/right，默认 left
+	Animation     string `json:"animation"` // slide/fade/wipe/none，默认 slide
+	Delay         string `json:"delay"`     // 入场时间
+	Length        string `json:"length"`    // 显示时长，默认到结尾
+	In            string `json:"in"`        // 入场动画时长，默认 0.5 秒
+	Out           string `json:"out"`       // 出场动画时长，默认 0.5 秒
+}
+
+// animatedValue 数值或随时间变化的表达式，如 "x": 20 或 "x": "20+100*t"
+type animatedValue struct {
+	expr *expr.Expr
+}
+
+// UnmarshalJSON 接受 JSON 数字或表达式字符串
+func (v *animatedValue) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		v.expr = expr.Constant(number)
+		return nil
+	}
+	var source string
+	if err := json.Unmarshal(data, &source); err != nil {
+		return fmt.Errorf("需要数字或表达式字符串: %s", data)
+	}
+	e, err := expr.Parse(source)
+	if err != nil {
+		return err
+	}
+	v.expr = e
+	return nil
+}
+
+// apply 常量写入 static，表达式写入 at
+func (v *animatedValue) apply(static *float64, at *func(t time.Duration) float64) {
+	if v == nil {
+		return
+	}
+	if v.expr.IsConstant() {
+		*static = v.expr.At(0)
+		return
+	}
+	*at = v.expr.Func()
+}
+
+// composeEffect 图层上按顺序应用的特效
+type composeEffect struct {
+	Name   string          `json:"name"`
+	Params registry.Params `json:"params"`
+}
+
+// runCompose 对应 CompositeVideoClip
+func runCompose(env *cliEnv, fs *flag.FlagSet, args []string) error {
+	specFile := fs.String("spec", "", "工程文件")
+	output := fs.String("o", "", "输出文件，覆盖工程文件中的 output；批量时可包含 {{字段}}")
+	dataFile := fs.String("data", "", "数据记录（.csv 或 .json），每条记录填充工程文件并导出一个视频")
+	workers := fs.Int("workers", 1, "批量时同时渲染的记录数")
+	keepGoing := fs.Bool("keep-going", false, "批量时某条记录失败后继续渲染其余记录")
+	var write writeFlags
+	write.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *specFile == "" {
+		fs.Usage()
+		return fmt.Errorf("缺少工程文件 -spec")
+	}
+
+	project, err := os.ReadFile(*specFile)
+	if err != nil {
+		return fmt.Errorf("读取工程文件失败: %w", err)
+	}
+	if *dataFile == "" {
+		spec, err := parseComposeSpec(project, *output)
+		if err != nil {
+			return err
+		}
+		return renderCompose(context.Background(), env, spec, &write)
+	}
+
+	records, err := render.LoadRecords(*dataFile)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("数据文件 %s 没有记录", *dataFile)
+	}
+	// 先填充全部记录，在开始渲染前发现缺失字段和重名输出
+	specs := make([]*composeSpec, len(records))
+	outputs := map[string]int{}
+	for i, record := range records {
+		spec, err := fillComposeSpec(project, *output, record)
+		if err != nil {
+			return fmt.Errorf("记录 %d: %w", i, err)
+		}
+		if previous, ok := outputs[spec.Output]; ok {
+			return fmt.Errorf("记录 %d 与记录 %d 的输出文件相同: %s，output 中需要包含区分记录的 {{字段}}", previous, i, spec.Output)
+		}
+		outputs[spec.Output] = i
+		specs[i] = spec
+	}
+
+	return render.RenderBatch(context.Background(), project, records, &render.BatchOptions{
+		Workers:         *workers,
+		ContinueOnError: *keepGoing,
+		Progress: func(done, total int, index int, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[%d/%d] 记录 %d 失败: %v\n", done, total, index, err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "[%d/%d] 记录 %d 完成\n", done, total, index)
+		},
+	}, func(ctx context.Context, index int, _ render.Record, _ []byte) error {
+		return renderCompose(ctx, env, specs[index], &write)
+	})
+}
+
+// parseComposeSpec 解析工程文件，output 非空时覆盖其中的 output
+func parseComposeSpec(project []byte, output string) (*composeSpec, error) {
+	var spec composeSpec
+	if err := json.Unmarshal(project, &spec); err != nil {
+		return nil, fmt.Errorf("解析工程文件失败: %w", err)
+	}
+	if output != "" {
+		spec.Output = output
+	}
+	if spec.Output == "" {
+		return nil, fmt.Errorf("工程文件缺少 output")
+	}
+	if len(spec.Clips) == 0 {
+		return nil, fmt.Errorf("工程文件没有剪辑")
+	}
+	return &spec, nil
+}
+
+// fillComposeSpec 用记录填充工程文件和 -o 指定的输出文件名后解析
+func fillComposeSpec(project []byte, output string, record render.Record) (*composeSpec, error) {
+	filled, err := render.FillTemplate(project, record)
+	if err != nil {
+		return nil, err
+	}
+	if output != "" {
+		quoted, _ := json.Marshal(output)
+		filledOutput, err := render.FillTemplate(quoted, record)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(filledOutput, &output); err != nil {
+			return nil, err
+		}
+	}
+	return parseComposeSpec(filled, output)
+}
+
+// renderCompose 打开全部图层、合成并导出，ctx 用于取消写入
+func renderCompose(ctx context.Context, env *cliEnv, spec *composeSpec, write *writeFlags) error {
+	mode, err := parseCompositeMode(spec.Mode)
+	if err != nil {
+		return err
+	}
+	canvas := &compositing.CompositeOptions{Strict: spec.Strict, Width: spec.Width, Height: spec.Height, Premultiplied: spec.Premultiplied, LinearLight: spec.LinearLight}
+	if spec.Background != "" {
+		if canvas.BackgroundColor, err = registry.ParseColor(spec.Background); err != nil {
+			return err
+		}
+	}
+	if spec.Clips[0].Text != "" || spec.Clips[0].LowerThird != nil || spec.Clips[0].OverlayTrack != nil {
+		return fmt.Errorf("第一个图层为背景，不能是文字图层")
+	}
+
+	var layers []core.VideoClip
+	var positions []*compositing.Position
+	for i, layer := range spec.Clips {
+		var clip core.VideoClip
+		switch {
+		case layer.LowerThird != nil:
+			clip, err = openLowerThirdLayer(env, layer, layers[0], canvas)
+		case layer.OverlayTrack != nil:
+			clip, err = openOverlayTrackLayer(env, layer, layers[0], canvas)
+		case layer.Text != "":
+			clip, err = openTextLayer(env, layer, layers[0], canvas)
+		default:
+			clip, err = openLayer(env, layer, canvas)
+		}
+		if err != nil {
+			name := layer.File
+			switch {
+			case layer.LowerThird != nil:
+				name = layer.LowerThird.Name
+			case layer.OverlayTrack != nil:
+				name = layer.OverlayTrack.Events
+			case layer.Text != "":
+				name = layer.Text
+			}
+			return fmt.Errorf("图层 %d (%s): %w", i, name, err)
+		}
+		defer clip.Close()
+		layers = append(layers, clip)
+
+		position := compositing.NewPosition(0, 0)
+		position.Center = layer.Center
+		position.Relative = layer.Relative
+		position.OffsetX, position.OffsetY = layer.OffsetX, layer.OffsetY
+		if layer.Anchor != "" {
+			if position.Anchor, err = compositing.ParseAnchor(layer.Anchor); err != nil {
+				return fmt.Errorf("图层 %d: %w", i, err)
+			}
+		}
+		if layer.Mode != "" {
+			mode, err := parseCompositeMode(layer.Mode)
+			if err != nil {
+				return fmt.Errorf("图层 %d: %w", i, err)
+			}
+			position.WithMode(mode)
+		}
+		if layer.Safe != "" {
+			if position.Safe, err = parseSafeArea(layer.Safe); err != nil {
+				return fmt.Errorf("图层 %d: %w", i, err)
+			}
+			position.Relative = true
+		}
+		layer.X.apply(&position.X, &position.XAt)
+		layer.Y.apply(&position.Y, &position.YAt)
+		layer.Scale.apply(&position.Scale, &position.ScaleAt)
+		layer.Opacity.apply(&position.Opacity, &position.OpacityAt)
+		positions = append(positions, position)
+	}
+
+	if spec.Guides {
+		width := cmp.Or(canvas.Width, layers[0].Width())
+		height := cmp.Or(canvas.Height, layers[0].Height())
+		guides := compositing.NewSafeAreaGuides(width, height, layers[0].Duration(), layers[0].FPS(), env.processMgr)
+		defer guides.Close()
+		layers = append(layers, guides)
+		positions = append(positions, compositing.NewPosition(0, 0))
+	}
+
+	composite, err := compositing.NewCompositeVideoClipWithOptions(layers, positions, mode, canvas, env.processMgr)
+	if err != nil {
+		return err
+	}
+	defer composite.Close()
+
+	var result core.Clip = composite
+	if spec.Audio != "" {
+		soundtrack := audio.NewAudioFileClip(spec.Audio, env.processMgr)
+		if err := soundtrack.Open(); err != nil {
+			return fmt.Errorf("打开音轨失败: %w", err)
+		}
+		defer soundtrack.Close()
+		if result, err = composite.WithAudio(soundtrack); err != nil {
+			return err
+		}
+	}
+
+	options, err := write.options(result)
+	if err != nil {
+		return err
+	}
+	if options.Codec == "" {
+		options.Codec = spec.Codec
+	}
+	if options.Bitrate == "" {
+		options.Bitrate = spec.Bitrate
+	}
+	if options.FPS == 0 {
+		options.FPS = spec.FPS
+	}
+	options.Context = ctx
+	return result.WriteToFile(spec.Output, options)
+}
+
+// openLayer 打开图层文件并按需截取、缩放
+func openLayer(env *cliEnv, layer composeClip, canvas *compositing.CompositeOptions) (core.VideoClip, error) {
+	clip, err := registry.OpenVideo(layer.File, env.processMgr)
+	if err != nil {
+		return nil, err
+	}
+
+	var start, end timeFlag
+	if layer.Start != "" {
+		if err := start.Set(layer.Start); err != nil {
+			clip.Close()
+			return nil, err
+		}
+	}
+	if layer.End != "" {
+		if err := end.Set(layer.End); err != nil {
+			clip.Close()
+			return nil, err
+		}
+	}
+
+	var result core.VideoClip = clip
+	if start.set || end.set {
+		if result, err = trimClip(clip, start, end); err != nil {
+			clip.Close()
+			return nil, err
+		}
+		clip.Close()
+	}
+	if layer.Width > 0 || layer.Height > 0 {
+		result = resizeClip(env, result, layer.Width, layer.Height)
+	}
+	return applyLayerEffects(env, result, layer.Effects, canvas)
+}
+
+// openTextLayer 在输出尺寸（或图层指定的 width×height）的透明画布上渲染文字
+func openTextLayer(env *cliEnv, layer composeClip, background core.VideoClip, canvas *compositing.CompositeOptions) (core.VideoClip, error) {
+	width := cmp.Or(canvas.Width, background.Width())
+	height := cmp.Or(canvas.Height, background.Height())
+	if layer.Width > 0 {
+		width = layer.Width
+	}
+	if layer.Height > 0 {
+		height = layer.Height
+	}
+	options := &video.TextClipOptions{
+		FontFile:    layer.Font,
+		FontSize:    layer.FontSize,
+		Color:       layer.Color,
+		BorderWidth: layer.Border,
+		Fonts:       layer.Fonts,
+		WrapWidth:   layer.Wrap,
+	}
+	var clip core.VideoClip
+	if layer.Animation == "" {
+		text, err := video.NewTextClip(layer.Text, width, height, options, background.Duration(), background.FPS(), env.processMgr)
+		if err != nil {
+			return nil, err
+		}
+		clip = text
+	} else {
+		animation, err := textAnimation(layer)
+		if err != nil {
+			return nil, err
+		}
+		text, err := video.NewAnimatedTextClip(layer.Text, width, height, options, animation, background.Duration(), background.FPS(), env.processMgr)
+		if err != nil {
+			return nil, err
+		}
+		clip = text
+	}
+	return applyLayerEffects(env, clip, layer.Effects, canvas)
+}
+
+// openLowerThirdLayer 创建输出尺寸的字幕条图层，持续整个背景时长
+func openLowerThirdLayer(env *cliEnv, layer composeClip, background core.VideoClip, canvas *compositing.CompositeOptions) (core.VideoClip, error) {
+	spec := layer.LowerThird
+	options := &compositing.LowerThirdOptions{
+		Subtitle:      spec.Subtitle,
+		FontFile:      layer.Font,
+		Fonts:         layer.Fonts,
+		NameSize:      layer.FontSize,
+		NameColor:     spec.NameColor,
+		SubtitleColor: spec.SubtitleColor,
+	}
+	var err error
+	for _, c := range []struct {
+		value string
+		dst   *color.Color
+	}{{spec.BarColor, &options.BarColor}, {spec.AccentColor, &options.AccentColor}} {
+		if c.value != "" {
+			if *c.dst, err = registry.ParseColor(c.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if spec.Align != "" {
+		if options.Align, err = compositing.ParseAnchor(spec.Align); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Animation != "" {
+		if options.Animation, err = compositing.ParseLowerThirdAnimation(spec.Animation); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range []struct {
+		value string
+		dst   *time.Duration
+	}{{spec.Delay, &options.Delay}, {spec.Length, &options.Length}, {spec.In, &options.In}, {spec.Out, &options.Out}} {
+		if d.value != "" {
+			if *d.dst, err = parseTime(d.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	width := cmp.Or(canvas.Width, background.Width())
+	height := cmp.Or(canvas.Height, background.Height())
+	clip, err := compositing.NewLowerThird(spec.Name, width, height, options, background.Duration(), background.FPS(), env.processMgr)
+	if err != nil {
+		return nil, err
+	}
+	return applyLayerEffects(env, clip, layer.Effects, canvas)
+}
+
+// openOverlayTrackLayer 创建输出尺寸的事件叠加轨道图层，持续整个背景时长
+func openOverlayTrackLayer(env *cliEnv, layer composeClip, background core.VideoClip, canvas *compositing.CompositeOptions) (core.VideoClip, error) {
+	spec := layer.OverlayTrack
+	if spec.Events == "" {
+		return nil, fmt.Errorf("叠加轨道缺少事件文件 events")
+	}
+	events, err := compositing.ReadOverlayEvents(spec.Events)
+	if err != nil {
+		return nil, err
+	}
+	options := &compositing.OverlayTrackOptions{
+		MaxFeed:  spec.MaxFeed,
+		FontFile: layer.Font,
+		Fonts:    layer.Fonts,
+		FontSize: layer.FontSize,
+		Color:    layer.Color,
+	}
+	if spec.Feed != "" {
+		if options.Feed, err = compositing.ParseAnchor(spec.Feed); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Background != "" {
+		if options.Background, err = registry.ParseColor(spec.Background); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range []struct {
+		value string
+		dst   *time.Duration
+	}{{spec.Duration, &options.Duration}, {spec.In, &options.In}, {spec.Out, &options.Out}} {
+		if d.value != "" {
+			if *d.dst, err = parseTime(d.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	width := cmp.Or(canvas.Width, background.Width())
+	height := cmp.Or(canvas.Height, background.Height())
+	clip, err := compositing.NewOverlayTrack(events, width, height, options, background.Duration(), background.FPS(), env.processMgr)
+	if err != nil {
+		return nil, err
+	}
+	return applyLayerEffects(env, clip, layer.Effects, canvas)
+}
+
+// textAnimation 解析文字图层的动画设置
+func textAnimation(layer composeClip) (*video.TextAnimationOptions, error) {
+	mode, err := video.ParseTextAnimation(layer.Animation)
+	if err != nil {
+		return nil, err
+	}
+	animation := &video.TextAnimationOptions{Mode: mode}
+	var delay, duration timeFlag
+	if layer.AnimationDelay != "" {
+		if err := delay.Set(layer.AnimationDelay); err != nil {
+			return nil, err
+		}
+	}
+	if layer.AnimationDuration != "" {
+		if err := duration.Set(layer.AnimationDuration); err != nil {
+			return nil, err
+		}
+	}
+	animation.Delay, animation.Duration = delay.value, duration.value
+	if layer.Easing != "" {
+		if animation.Easing, err = video.ParseEasing(layer.Easing); err != nil {
+			return nil, err
+		}
+	}
+	if layer.Progress != nil {
+		var static float64
+		animation.Progress = func(time.Duration) float64 { return static }
+		layer.Progress.apply(&static, &animation.Progress)
+	}
+	return animation, nil
+}
+
+// applyLayerEffects 按顺序为图层添加特效，失败时关闭 clip；画布开启线性光时特效也在线性光下处理
+func applyLayerEffects(env *cliEnv, clip core.VideoClip, specs []composeEffect, canvas *compositing.CompositeOptions) (core.VideoClip, error) {
+	if len(specs) == 0 {
+		return clip, nil
+	}
+	withEffects := video.NewEffectVideoClipWithOptions(clip, &video.EffectClipOptions{LinearLight: canvas.LinearLight}, env.processMgr)
+	for _, spec := range specs {
+		effect, err := registry.NewVideoEffect(spec.Name, spec.Params)
+		if err != nil {
+			clip.Close()
+			return nil, err
+		}
+		withEffects.AddEffect(effect)
+	}
+	return withEffects, nil
+}
+
+// parseSafeArea 解析安全区名称
+func parseSafeArea(name string) (float64, error) {
+	switch strings.ToLower(name) {
+	case "title":
+		return compositing.TitleSafe, nil
+	case "action":
+		return compositing.ActionSafe, nil
+	default:
+		return 0, fmt.Errorf("未知的安全区: %s（支持 title、action）", name)
+	}
+}
+
+// parseCompositeMode 解析合成模式名称，空字符串表示 overlay
+func parseCompositeMode(name string) (compositing.CompositeMode, error) {
+	if name == "" {
+		return compositing.Overlay, nil
+	}
+	return compositing.ParseCompositeMode(strings.ToLower(name))
+}
@@ -1,125 +1,248 @@
 package main
 
 import (
-	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"image"
-	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"moviepy-go/pkg/analysis"
+	"moviepy-go/pkg/config"
+	"moviepy-go/pkg/core"
+	_ "moviepy-go/pkg/effects/opengl" // 以 opengl 标签构建时注册 GPU 特效后端
 	"moviepy-go/pkg/ffmpeg"
-	"moviepy-go/pkg/video"
+	"moviepy-go/pkg/presets"
 )
 
-func main() {
-	// 创建进程管理器
-	processMgr := ffmpeg.NewProcessManager()
-	defer processMgr.Close()
+// command 一个子命令
+type command struct {
+	name    string
+	usage   string // 参数说明，显示在命令名之后
+	summary string
+	run     func(env *cliEnv, fs *flag.FlagSet, args []string) error // fs 已由 newFlagSet 创建
+}
+
+// cliEnv 子命令共享的运行环境
+type cliEnv struct {
+	processMgr *ffmpeg.ProcessManager
+}
+
+// commands 所有子命令，按帮助中的显示顺序排列
+var commands = []*command{
+	infoCommand,
+	trimCommand,
+	splitCommand,
+	concatCommand,
+	resizeCommand,
+	gifCommand,
+	thumbnailCommand,
+	composeCommand,
+	extractAudioCommand,
+	subtitlesCommand,
+	screencastCommand,
+	reviewCommand,
+	pluginsCommand,
+}
 
-	// 检查命令行参数
+func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("用法: moviepy-go <视频文件路径>")
-		os.Exit(1)
+		printUsage()
+		os.Exit(2)
 	}
 
-	filename := os.Args[1]
-
-	fmt.Printf("开始处理视频文件: %s\n", filename)
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage()
+		return
+	}
 
-	// 创建视频剪辑
-	clip := video.NewVideoFileClip(filename, processMgr)
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", name)
+		printUsage()
+		os.Exit(2)
+	}
 
-	// 打开视频文件
-	fmt.Println("正在打开视频文件...")
-	if err := clip.Open(); err != nil {
-		log.Fatalf("打开视频失败: %v", err)
+	// 部署默认值来自 MOVIEGO_CONFIG 配置文件和 MOVIEGO_* 环境变量
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "moviego: 加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.Install(); err != nil {
+		fmt.Fprintf(os.Stderr, "moviego: %v\n", err)
 	}
-	defer clip.Close()
 
-	// 打印视频信息
-	fmt.Printf("视频信息:\n")
-	fmt.Printf("  文件名: %s\n", filename)
-	fmt.Printf("  时长: %v\n", clip.Duration())
-	fmt.Printf("  帧率: %.2f fps\n", clip.FPS())
-	fmt.Printf("  尺寸: %dx%d\n", clip.Width(), clip.Height())
-	fmt.Printf("  宽高比: %.2f\n", clip.AspectRatio())
+	processMgr := ffmpeg.NewProcessManager()
+	err = cmd.run(&cliEnv{processMgr: processMgr}, newFlagSet(cmd), os.Args[2:])
+	processMgr.Close()
 
-	// 演示获取帧（带超时）
-	fmt.Printf("\n获取帧示例:\n")
-	for i := 0; i < 3; i++ {
-		t := time.Duration(i) * time.Second
-		if t > clip.Duration() {
-			break
-		}
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "moviego %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
 
-		fmt.Printf("  正在获取第 %d 帧 (时间: %v)...\n", i+1, t)
+// findCommand 按名称查找子命令
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
 
-		// 创建带超时的上下文
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// printUsage 打印总体帮助
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "用法: moviego <命令> [选项] [参数]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "使用 moviego <命令> -h 查看命令的选项")
+}
 
-		// 在 goroutine 中获取帧
-		frameChan := make(chan interface{}, 1)
-		errChan := make(chan error, 1)
+// newFlagSet 创建子命令的参数集，帮助信息包含命令用法
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "用法: moviego %s [选项] %s\n\n%s\n\n选项:\n", cmd.name, cmd.usage, cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-		go func() {
-			frame, err := clip.GetFrame(t)
-			if err != nil {
-				errChan <- err
-				return
-			}
-			frameChan <- frame
-		}()
-
-		// 等待结果或超时
-		select {
-		case frame := <-frameChan:
-			bounds := frame.(image.Image).Bounds()
-			fmt.Printf("    成功: 帧尺寸 %dx%d\n", bounds.Dx(), bounds.Dy())
-		case err := <-errChan:
-			log.Printf("    失败: %v", err)
-		case <-ctx.Done():
-			fmt.Printf("    超时: 获取帧超时 (10秒)\n")
-		}
+// writeFlags 输出视频的通用选项
+type writeFlags struct {
+	codec    string
+	bitrate  string
+	fps      float64
+	logLevel string
+	verify   bool
+	preset   string
+	dither   string
+}
 
-		cancel()
-	}
+// register 在参数集上注册编码选项
+func (w *writeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&w.codec, "codec", "", "视频编码器，默认 libx264")
+	fs.StringVar(&w.bitrate, "bitrate", "", "视频码率，如 2000k")
+	fs.Float64Var(&w.fps, "fps", 0, "输出帧率，默认沿用源文件")
+	fs.StringVar(&w.logLevel, "loglevel", "", "FFmpeg 日志级别（quiet/error/info/debug）")
+	fs.BoolVar(&w.verify, "verify", false, "写入后探测输出文件并校验时长、尺寸和编码")
+	fs.StringVar(&w.preset, "preset", "", "导出预设（如 youtube-1080p、instagram-reel），显式参数优先")
+	fs.StringVar(&w.dither, "dither", "", "量化为 8 位时的抖动（none/ordered/blue_noise），减轻渐变色带")
+}
 
-	// 演示子剪辑
-	fmt.Printf("\n创建子剪辑:\n")
-	if clip.Duration() > 2*time.Second {
-		subclip, err := clip.Subclip(time.Second, 2*time.Second)
+// options 转换为写入选项，指定预设时以预设为基础并打印 clip 不符合预设的警告
+func (w *writeFlags) options(clip core.Clip) (*core.WriteOptions, error) {
+	options := &core.WriteOptions{}
+	if w.preset != "" {
+		preset, err := presets.Get(w.preset)
 		if err != nil {
-			log.Printf("创建子剪辑失败: %v", err)
-		} else {
-			fmt.Printf("  子剪辑时长: %v\n", subclip.Duration())
-			subclip.Close()
+			return nil, err
+		}
+		for _, warning := range preset.Check(clip) {
+			fmt.Fprintln(os.Stderr, "警告:", warning)
 		}
+		options = preset.WriteOptions()
+	}
+	if w.codec != "" {
+		options.Codec = w.codec
+	}
+	if w.bitrate != "" {
+		options.Bitrate = w.bitrate
 	}
+	if w.fps != 0 {
+		options.FPS = w.fps
+	}
+	if w.logLevel != "" {
+		options.LogLevel = w.logLevel
+	}
+	if w.dither != "" {
+		options.Dither = w.dither
+	}
+	if w.verify {
+		options.Verify = analysis.VerifyHook(nil, nil)
+	}
+	return options, nil
+}
+
+// timeFlag 接受 "1.5"（秒）、"1m30s" 或 "01:02:03.5" 形式的时间
+type timeFlag struct {
+	value time.Duration
+	set   bool
+}
 
-	// 演示速度调整
-	fmt.Printf("\n速度调整:\n")
-	fastClip, err := clip.WithSpeed(2.0)
+// String 实现 flag.Value
+func (f *timeFlag) String() string {
+	if !f.set {
+		return ""
+	}
+	return f.value.String()
+}
+
+// Set 实现 flag.Value
+func (f *timeFlag) Set(s string) error {
+	d, err := parseTime(s)
 	if err != nil {
-		log.Printf("速度调整失败: %v", err)
-	} else {
-		fmt.Printf("  2倍速剪辑时长: %v\n", fastClip.Duration())
-		fastClip.Close()
+		return err
 	}
+	f.value, f.set = d, true
+	return nil
+}
 
-	// 演示音量调整
-	fmt.Printf("\n音量调整:\n")
-	volumeClip, err := clip.WithVolume(0.5)
+// parseTime 解析秒数、Go 时长或以冒号分隔的时钟格式
+func parseTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("时间不能为空")
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	if strings.Contains(s, ":") {
+		var total float64
+		for _, part := range strings.Split(s, ":") {
+			v, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return 0, fmt.Errorf("无效的时间: %s", s)
+			}
+			total = total*60 + v
+		}
+		return time.Duration(total * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
 	if err != nil {
-		log.Printf("音量调整失败: %v", err)
-	} else {
-		fmt.Printf("  音量减半剪辑时长: %v\n", volumeClip.Duration())
-		volumeClip.Close()
+		return 0, fmt.Errorf("无效的时间: %s", s)
 	}
+	return d, nil
+}
 
-	// 打印进程管理信息
-	fmt.Printf("\n进程管理:\n")
-	fmt.Printf("  当前管理的进程数: %d\n", processMgr.GetProcessCount())
+// requireArgs 检查位置参数数量
+func requireArgs(fs *flag.FlagSet, min, max int) error {
+	n := fs.NArg()
+	if n < min || (max >= 0 && n > max) {
+		fs.Usage()
+		return fmt.Errorf("参数数量错误")
+	}
+	return nil
+}
 
-	fmt.Println("\n演示完成!")
+// requireOutput 检查必须的输出路径
+func requireOutput(fs *flag.FlagSet, output string) error {
+	if output == "" {
+		fs.Usage()
+		return fmt.Errorf("缺少输出文件 -o")
+	}
+	return nil
 }
@@ -64,7 +64,7 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 		compositing.NewPosition(50, 50),
 	}
 
-	compositeClip := compositing.NewCompositeVideoClip(clips, positions, compositing.Overlay, processMgr)
+	compositeClip := newComposite(clips, positions, compositing.Overlay, processMgr)
 
 	options := &core.WriteOptions{
 		Codec:   "libx264",
@@ -82,7 +82,7 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 	// 2. 相加合成
 	fmt.Printf("\n2. 相加合成 (Add)\n")
 	clips = createClips()
-	compositeClip = compositing.NewCompositeVideoClip(clips, positions, compositing.Add, processMgr)
+	compositeClip = newComposite(clips, positions, compositing.Add, processMgr)
 
 	if err := compositeClip.WriteToFile("add_composite.mp4", options); err != nil {
 		log.Printf("写入相加合成视频失败: %v", err)
@@ -94,7 +94,7 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 	// 3. 相乘合成
 	fmt.Printf("\n3. 相乘合成 (Multiply)\n")
 	clips = createClips()
-	compositeClip = compositing.NewCompositeVideoClip(clips, positions, compositing.Multiply, processMgr)
+	compositeClip = newComposite(clips, positions, compositing.Multiply, processMgr)
 
 	if err := compositeClip.WriteToFile("multiply_composite.mp4", options); err != nil {
 		log.Printf("写入相乘合成视频失败: %v", err)
@@ -106,7 +106,7 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 	// 4. 屏幕合成
 	fmt.Printf("\n4. 屏幕合成 (Screen)\n")
 	clips = createClips()
-	compositeClip = compositing.NewCompositeVideoClip(clips, positions, compositing.Screen, processMgr)
+	compositeClip = newComposite(clips, positions, compositing.Screen, processMgr)
 
 	if err := compositeClip.WriteToFile("screen_composite.mp4", options); err != nil {
 		log.Printf("写入屏幕合成视频失败: %v", err)
@@ -118,7 +118,7 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 	// 5. 变暗合成
 	fmt.Printf("\n5. 变暗合成 (Darken)\n")
 	clips = createClips()
-	compositeClip = compositing.NewCompositeVideoClip(clips, positions, compositing.Darken, processMgr)
+	compositeClip = newComposite(clips, positions, compositing.Darken, processMgr)
 
 	if err := compositeClip.WriteToFile("darken_composite.mp4", options); err != nil {
 		log.Printf("写入变暗合成视频失败: %v", err)
@@ -130,7 +130,7 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 	// 6. 变亮合成
 	fmt.Printf("\n6. 变亮合成 (Lighten)\n")
 	clips = createClips()
-	compositeClip = compositing.NewCompositeVideoClip(clips, positions, compositing.Lighten, processMgr)
+	compositeClip = newComposite(clips, positions, compositing.Lighten, processMgr)
 
 	if err := compositeClip.WriteToFile("lighten_composite.mp4", options); err != nil {
 		log.Printf("写入变亮合成视频失败: %v", err)
@@ -162,7 +162,7 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 						compositing.NewPosition(200, 200),
 					}
 
-					multiComposite := compositing.NewCompositeVideoClip(multiClips, multiPositions, compositing.Overlay, processMgr)
+					multiComposite := newComposite(multiClips, multiPositions, compositing.Overlay, processMgr)
 
 					if err := multiComposite.WriteToFile("multi_composite.mp4", options); err != nil {
 						log.Printf("写入多剪辑合成视频失败: %v", err)
@@ -183,7 +183,7 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 		compositing.NewCenteredPosition(),
 	}
 
-	centeredComposite := compositing.NewCompositeVideoClip(centeredClips, centeredPositions, compositing.Overlay, processMgr)
+	centeredComposite := newComposite(centeredClips, centeredPositions, compositing.Overlay, processMgr)
 
 	if err := centeredComposite.WriteToFile("centered_composite.mp4", options); err != nil {
 		log.Printf("写入居中合成视频失败: %v", err)
@@ -205,3 +205,12 @@ func demonstrateCompositing(originalClip core.VideoClip, processMgr *ffmpeg.Proc
 		fmt.Printf("  - multi_composite.mp4 (多剪辑合成)\n")
 	}
 }
+
+// newComposite 创建合成剪辑，参数错误时退出
+func newComposite(clips []core.VideoClip, positions []*compositing.Position, mode compositing.CompositeMode, processMgr *ffmpeg.ProcessManager) *compositing.CompositeVideoClip {
+	composite, err := compositing.NewCompositeVideoClip(clips, positions, mode, processMgr)
+	if err != nil {
+		log.Fatalf("创建合成剪辑失败: %v", err)
+	}
+	return composite
+}
@@ -0,0 +1,358 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// AudioAnalysisOptions 音频包络与频谱分析选项
+type AudioAnalysisOptions struct {
+	FPS     float64 // 每秒分析的帧数，默认 30，通常与视频帧率一致
+	Bands   int     // 频谱按对数频率划分的段数，默认 16
+	MinFreq float64 // 频谱的最低频率（Hz），默认 40
+	MaxFreq float64 // 频谱的最高频率（Hz），默认 16000，不超过采样率的一半
+	// Floor 频谱的动态范围（dB），比全曲最强频段低 Floor 以下映射为 0，默认 60
+	Floor float64
+	// Attack、Release 数值上升和回落的平滑时间常数，默认 0（立即上升）和 150ms，使画面脉动而不闪烁
+	Attack, Release time.Duration
+}
+
+// AudioEnvelope 逐帧的音量包络和频谱，数值已归一化到 0–1，按时间线性插值后作为动画参数
+//
+// LevelAt、BandAt 等方法值可以直接赋给 compositing.Position 的 ScaleAt、OpacityAt，
+// 传给 effects.NewAnimatedBrightnessEffect，或在 video.NewAnimatedShapeClip 的生成函数中驱动柱高。
+type AudioEnvelope struct {
+	fps    float64
+	levels []float64   // 按全曲最大值归一化的 RMS 音量
+	bands  [][]float64 // bands[i][b] 为第 i 帧第 b 段的频谱强度
+}
+
+// AnalyzeAudio 顺序读取音频剪辑，计算每帧的 RMS 音量和对数频段的频谱
+func AnalyzeAudio(clip core.AudioClip, options *AudioAnalysisOptions) (*AudioEnvelope, error) {
+	if clip == nil {
+		return nil, fmt.Errorf("音频分析的剪辑不能为空")
+	}
+	opts := AudioAnalysisOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.FPS == 0 {
+		opts.FPS = 30
+	}
+	if opts.FPS < 0 {
+		return nil, fmt.Errorf("无效的分析帧率: %f", opts.FPS)
+	}
+	if opts.Bands <= 0 {
+		opts.Bands = 16
+	}
+	if opts.MinFreq <= 0 {
+		opts.MinFreq = 40
+	}
+	if opts.MaxFreq <= 0 {
+		opts.MaxFreq = 16000
+	}
+	if opts.Floor <= 0 {
+		opts.Floor = 60
+	}
+	if opts.Release == 0 {
+		opts.Release = 150 * time.Millisecond
+	}
+
+	rate, channels := clip.SampleRate(), clip.Channels()
+	if rate <= 0 || channels <= 0 {
+		return nil, fmt.Errorf("无效的音频格式: %d Hz, %d 声道", rate, channels)
+	}
+	opts.MaxFreq = math.Min(opts.MaxFreq, float64(rate)/2)
+	if opts.MinFreq >= opts.MaxFreq {
+		return nil, fmt.Errorf("无效的频率范围: %.0f–%.0f Hz", opts.MinFreq, opts.MaxFreq)
+	}
+
+	duration := clip.Duration()
+	frames := core.FrameCount(duration, opts.FPS)
+	if frames == 0 {
+		return nil, fmt.Errorf("没有可分析的音频")
+	}
+
+	// 窗口取两倍帧间隔向上到 2 的幂，以帧时间为中心
+	size := 256
+	for float64(size) < 2*float64(rate)/opts.FPS {
+		size *= 2
+	}
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size-1))
+	}
+	edges := bandEdges(opts.Bands, opts.MinFreq, opts.MaxFreq, rate, size)
+
+	reader := &monoReader{clip: clip, channels: channels, rate: rate}
+	envelope := &AudioEnvelope{
+		fps:    opts.FPS,
+		levels: make([]float64, frames),
+		bands:  make([][]float64, frames),
+	}
+	spectrum := make([]complex128, size)
+	for i := 0; i < frames; i++ {
+		t := core.FrameTime(i, opts.FPS)
+		first := int(math.Round(t.Seconds()*float64(rate))) - size/2
+		samples, err := reader.read(first, size)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %v 处的音频失败: %w", t, err)
+		}
+
+		var sum float64
+		for j, v := range samples {
+			sum += v * v
+			spectrum[j] = complex(v*window[j], 0)
+		}
+		envelope.levels[i] = math.Sqrt(sum / float64(size))
+
+		fft(spectrum)
+		bands := make([]float64, opts.Bands)
+		for b := range bands {
+			var power float64
+			for k := edges[b]; k < edges[b+1]; k++ {
+				power += real(spectrum[k])*real(spectrum[k]) + imag(spectrum[k])*imag(spectrum[k])
+			}
+			bands[b] = power / float64(edges[b+1]-edges[b])
+		}
+		envelope.bands[i] = bands
+	}
+
+	envelope.normalize(opts.Floor)
+	envelope.smooth(opts.Attack, opts.Release)
+	return envelope, nil
+}
+
+// normalize 音量按最大值线性归一化，频谱按全曲最强频段换算为 floor dB 范围内的比例
+func (e *AudioEnvelope) normalize(floor float64) {
+	var peakLevel, peakPower float64
+	for i := range e.levels {
+		peakLevel = math.Max(peakLevel, e.levels[i])
+		for _, power := range e.bands[i] {
+			peakPower = math.Max(peakPower, power)
+		}
+	}
+	for i := range e.levels {
+		if peakLevel > 0 {
+			e.levels[i] /= peakLevel
+		}
+		for b, power := range e.bands[i] {
+			if power <= 0 || peakPower <= 0 {
+				e.bands[i][b] = 0
+				continue
+			}
+			db := 10 * math.Log10(power/peakPower)
+			e.bands[i][b] = math.Max(0, 1+db/floor)
+		}
+	}
+}
+
+// smooth 一阶平滑，上升和回落各用自己的时间常数
+func (e *AudioEnvelope) smooth(attack, release time.Duration) {
+	coefficient := func(tau time.Duration) float64 {
+		if tau <= 0 {
+			return 0
+		}
+		return math.Exp(-1 / (e.fps * tau.Seconds()))
+	}
+	up, down := coefficient(attack), coefficient(release)
+	follow := func(previous, current float64) float64 {
+		k := down
+		if current > previous {
+			k = up
+		}
+		return current + (previous-current)*k
+	}
+	for i := 1; i < len(e.levels); i++ {
+		e.levels[i] = follow(e.levels[i-1], e.levels[i])
+		for b := range e.bands[i] {
+			e.bands[i][b] = follow(e.bands[i-1][b], e.bands[i][b])
+		}
+	}
+}
+
+// FPS 返回分析帧率
+func (e *AudioEnvelope) FPS() float64 {
+	return e.fps
+}
+
+// Bands 返回频谱段数
+func (e *AudioEnvelope) Bands() int {
+	if len(e.bands) == 0 {
+		return 0
+	}
+	return len(e.bands[0])
+}
+
+// LevelAt 返回时间 t 处的音量，0–1
+func (e *AudioEnvelope) LevelAt(t time.Duration) float64 {
+	i, k := e.locate(t)
+	if k == 0 {
+		return e.levels[i]
+	}
+	return e.levels[i] + (e.levels[i+1]-e.levels[i])*k
+}
+
+// BandAt 返回时间 t 处第 band 段（0 为最低频）的频谱强度，0–1，band 越界时为 0
+func (e *AudioEnvelope) BandAt(band int, t time.Duration) float64 {
+	if band < 0 || band >= e.Bands() {
+		return 0
+	}
+	i, k := e.locate(t)
+	if k == 0 {
+		return e.bands[i][band]
+	}
+	return e.bands[i][band] + (e.bands[i+1][band]-e.bands[i][band])*k
+}
+
+// SpectrumAt 返回时间 t 处各频段的强度，由低频到高频
+func (e *AudioEnvelope) SpectrumAt(t time.Duration) []float64 {
+	spectrum := make([]float64, e.Bands())
+	for b := range spectrum {
+		spectrum[b] = e.BandAt(b, t)
+	}
+	return spectrum
+}
+
+// Band 返回第 band 段频谱强度的动画函数
+func (e *AudioEnvelope) Band(band int) func(t time.Duration) float64 {
+	return func(t time.Duration) float64 { return e.BandAt(band, t) }
+}
+
+// BandRange 返回第 lo 到 hi 段（含）平均强度的动画函数，如低频段用于跟随鼓点
+func (e *AudioEnvelope) BandRange(lo, hi int) func(t time.Duration) float64 {
+	lo, hi = max(lo, 0), min(hi, e.Bands()-1)
+	return func(t time.Duration) float64 {
+		if lo > hi {
+			return 0
+		}
+		var sum float64
+		for b := lo; b <= hi; b++ {
+			sum += e.BandAt(b, t)
+		}
+		return sum / float64(hi-lo+1)
+	}
+}
+
+// Map 把 0–1 的动画输入线性映射到 [from, to]，如 Map(e.LevelAt, 1, 1.2) 作为随音乐脉动的缩放
+func Map(input func(t time.Duration) float64, from, to float64) func(t time.Duration) float64 {
+	return func(t time.Duration) float64 { return from + (to-from)*input(t) }
+}
+
+// locate 返回 t 所在的帧和到下一帧的插值比例
+func (e *AudioEnvelope) locate(t time.Duration) (int, float64) {
+	position := t.Seconds() * e.fps
+	if position <= 0 {
+		return 0, 0
+	}
+	i := int(position)
+	if i >= len(e.levels)-1 {
+		return len(e.levels) - 1, 0
+	}
+	return i, position - float64(i)
+}
+
+// bandEdges 返回对数间隔频段在 FFT 结果中的下标边界，每段至少一个频点
+func bandEdges(bands int, minFreq, maxFreq float64, rate, size int) []int {
+	edges := make([]int, bands+1)
+	binWidth := float64(rate) / float64(size)
+	for b := 0; b <= bands; b++ {
+		freq := minFreq * math.Pow(maxFreq/minFreq, float64(b)/float64(bands))
+		edges[b] = max(1, min(int(math.Round(freq/binWidth)), size/2))
+	}
+	for b := 1; b <= bands; b++ {
+		edges[b] = max(edges[b], edges[b-1]+1)
+	}
+	return edges
+}
+
+// fft 原地计算长度为 2 的幂的离散傅里叶变换
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for length := 2; length <= n; length <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(length)))
+		for start := 0; start < n; start += length {
+			w := complex(1, 0)
+			for k := 0; k < length/2; k++ {
+				a, b := x[start+k], x[start+k+length/2]*w
+				x[start+k], x[start+k+length/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
+
+// monoReader 按样本下标顺序读取混为单声道的音频，只保留尚未用到的样本
+type monoReader struct {
+	clip     core.AudioClip
+	channels int
+	rate     int
+	buffer   []float64
+	start    int // buffer[0] 的样本下标
+	next     time.Duration
+	done     bool
+}
+
+// read 返回从样本 first 开始的 count 个样本，剪辑范围外为静音；first 不应小于上次读取的位置
+func (r *monoReader) read(first, count int) ([]float64, error) {
+	if first > r.start {
+		drop := min(first-r.start, len(r.buffer))
+		r.buffer = r.buffer[drop:]
+		r.start += drop
+		if len(r.buffer) == 0 {
+			r.start = max(r.start, first)
+		}
+	}
+	for !r.done && r.start+len(r.buffer) < first+count {
+		if r.next >= r.clip.Duration() {
+			r.done = true
+			break
+		}
+		frame, err := r.clip.GetAudioFrame(r.next)
+		if err != nil {
+			return nil, err
+		}
+		n := len(frame) / r.channels
+		if n == 0 {
+			r.done = true
+			break
+		}
+		base := int(math.Round(r.next.Seconds() * float64(r.rate)))
+		// 缓冲区与读取位置之间的空隙（如读取起点前移后）补静音
+		for r.start+len(r.buffer) < base {
+			r.buffer = append(r.buffer, 0)
+		}
+		skip := r.start + len(r.buffer) - base
+		for i := max(skip, 0); i < n; i++ {
+			var sum float64
+			for c := 0; c < r.channels; c++ {
+				sum += frame[i*r.channels+c]
+			}
+			r.buffer = append(r.buffer, sum/float64(r.channels))
+		}
+		r.next += time.Duration(float64(n) / float64(r.rate) * float64(time.Second))
+	}
+
+	samples := make([]float64, count)
+	for i := range samples {
+		j := first + i - r.start
+		if j >= 0 && j < len(r.buffer) {
+			samples[i] = r.buffer[j]
+		}
+	}
+	return samples, nil
+}
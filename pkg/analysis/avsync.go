@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// DefaultAVSyncThreshold 默认允许的音视频偏差
+const DefaultAVSyncThreshold = 100 * time.Millisecond
+
+// AVSyncReport 音视频同步检查结果，时间均来自容器中的流信息
+type AVSyncReport struct {
+	HasVideo bool
+	HasAudio bool
+
+	VideoStart    time.Duration
+	VideoDuration time.Duration
+	AudioStart    time.Duration
+	AudioDuration time.Duration
+
+	StartOffset  time.Duration // 音频起点 - 视频起点
+	DurationDiff time.Duration // 音频时长 - 视频时长
+	EndDrift     time.Duration // 音频终点 - 视频终点
+}
+
+// InSync 判断起点偏差和终点漂移是否都在 threshold 以内，缺少音频或视频时视为同步
+func (r *AVSyncReport) InSync(threshold time.Duration) bool {
+	if !r.HasVideo || !r.HasAudio {
+		return true
+	}
+	return absDuration(r.StartOffset) <= threshold && absDuration(r.EndDrift) <= threshold
+}
+
+// String 返回便于日志输出的摘要
+func (r *AVSyncReport) String() string {
+	return fmt.Sprintf("视频 %v+%v，音频 %v+%v，起点偏差 %v，终点漂移 %v",
+		r.VideoStart, r.VideoDuration, r.AudioStart, r.AudioDuration, r.StartOffset, r.EndDrift)
+}
+
+// CheckAVSync 探测文件中第一条视频流和音频流的起点与时长，报告两者的偏差
+func CheckAVSync(filename string) (*AVSyncReport, error) {
+	probe, err := ffmpeg.Probe(filename)
+	if err != nil {
+		return nil, fmt.Errorf("探测文件失败: %w", err)
+	}
+
+	report := &AVSyncReport{}
+	if streams := probe.VideoStreams(); len(streams) > 0 {
+		report.HasVideo = true
+		report.VideoStart = seconds(streams[0].StartTime)
+		report.VideoDuration = streamDuration(&streams[0], probe.Format.Duration)
+	}
+	if streams := probe.AudioStreams(); len(streams) > 0 {
+		report.HasAudio = true
+		report.AudioStart = seconds(streams[0].StartTime)
+		report.AudioDuration = streamDuration(&streams[0], probe.Format.Duration)
+	}
+
+	if report.HasVideo && report.HasAudio {
+		report.StartOffset = report.AudioStart - report.VideoStart
+		report.DurationDiff = report.AudioDuration - report.VideoDuration
+		report.EndDrift = (report.AudioStart + report.AudioDuration) - (report.VideoStart + report.VideoDuration)
+	}
+	return report, nil
+}
+
+// WarnAVSync 检查导出文件的音视频同步，偏差超过 threshold 或检查失败时通过 logger 警告
+//
+// threshold 为 0 时使用 DefaultAVSyncThreshold，logger 为 nil 时使用 log.Default()。
+func WarnAVSync(filename string, threshold time.Duration, logger *log.Logger) (*AVSyncReport, error) {
+	if threshold <= 0 {
+		threshold = DefaultAVSyncThreshold
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	report, err := CheckAVSync(filename)
+	if err != nil {
+		logger.Printf("警告: %s 音视频同步检查失败: %v", filename, err)
+		return nil, err
+	}
+	if !report.InSync(threshold) {
+		logger.Printf("警告: %s 音视频偏差超过 %v: %s", filename, threshold, report)
+	}
+	return report, nil
+}
+
+// streamDuration 返回流时长，依次回退到 DURATION 标签（Matroska）和容器时长
+func streamDuration(stream *ffmpeg.ProbeStream, formatDuration float64) time.Duration {
+	if stream.Duration > 0 {
+		return seconds(stream.Duration)
+	}
+	if tag, ok := stream.Tags["DURATION"]; ok {
+		if d, err := parseClockDuration(tag); err == nil {
+			return d
+		}
+	}
+	return seconds(formatDuration)
+}
+
+// parseClockDuration 解析 "HH:MM:SS.fraction" 形式的时长
+func parseClockDuration(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("无效的时长: %s", s)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("无效的时长: %s", s)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("无效的时长: %s", s)
+	}
+	secs, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时长: %s", s)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + seconds(secs), nil
+}
+
+// seconds 将秒数转换为 time.Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// absDuration 返回绝对值
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// MotionOptions 运动检测选项
+type MotionOptions struct {
+	FPS            float64 // 采样帧率，默认 5
+	Width          int     // 差分前缩小到的宽度（像素），默认 160，降低噪点与计算量
+	PixelThreshold float64 // 亮度差超过此值（0–255）的像素视为变化，默认 16
+}
+
+// MotionLevel 一个时间窗口内的运动强度
+type MotionLevel struct {
+	Start time.Duration
+	End   time.Duration
+	Score float64 // 窗口内相邻采样帧变化像素比例的平均值，0–1
+}
+
+// ActivityOptions 高活动片段提取选项
+type ActivityOptions struct {
+	Threshold   float64       // 运动强度阈值，默认 0.02
+	MinGap      time.Duration // 间隔短于此值的相邻片段合并
+	MinDuration time.Duration // 短于此值的片段丢弃
+	Padding     time.Duration // 片段前后各扩展的时长
+}
+
+// ActivitySegment 高活动片段
+type ActivitySegment struct {
+	Start time.Duration
+	End   time.Duration
+	Peak  float64 // 片段内最高运动强度
+}
+
+// MotionLevels 按 windowSize 时间窗口统计帧差分运动强度，适用于监控画面和体育集锦
+func MotionLevels(clip core.VideoClip, windowSize time.Duration, options *MotionOptions) ([]MotionLevel, error) {
+	if clip == nil {
+		return nil, fmt.Errorf("运动检测的剪辑不能为空")
+	}
+	if windowSize <= 0 {
+		return nil, fmt.Errorf("无效的窗口时长: %v", windowSize)
+	}
+	if options == nil {
+		options = &MotionOptions{}
+	}
+	fps := options.FPS
+	if fps == 0 {
+		fps = 5
+	}
+	if fps < 0 {
+		return nil, fmt.Errorf("无效的采样帧率: %f", fps)
+	}
+	width := options.Width
+	if width <= 0 {
+		width = 160
+	}
+	pixelThreshold := options.PixelThreshold
+	if pixelThreshold == 0 {
+		pixelThreshold = 16
+	}
+
+	duration := clip.Duration()
+	totalFrames := core.FrameCount(duration, fps)
+	if totalFrames == 0 {
+		return nil, fmt.Errorf("没有可检测的帧")
+	}
+
+	windows := int(math.Ceil(float64(duration) / float64(windowSize)))
+	levels := make([]MotionLevel, windows)
+	counts := make([]int, windows)
+	for i := range levels {
+		levels[i].Start = time.Duration(i) * windowSize
+		levels[i].End = min(levels[i].Start+windowSize, duration)
+	}
+
+	var previous []float64
+	for i := 0; i < totalFrames; i++ {
+		t := core.FrameTime(i, fps)
+		frame, err := clip.GetFrame(t)
+		if err != nil {
+			return nil, fmt.Errorf("获取第 %d 帧失败: %w", i, err)
+		}
+		current := smallLuma(frame, width)
+		if previous != nil {
+			w := min(int(t/windowSize), windows-1)
+			levels[w].Score += changedRatio(previous, current, pixelThreshold)
+			counts[w]++
+		}
+		previous = current
+	}
+
+	for i := range levels {
+		if counts[i] > 0 {
+			levels[i].Score /= float64(counts[i])
+		}
+	}
+	return levels, nil
+}
+
+// ActiveSegments 从运动强度中提取高于阈值的片段，相邻片段按 MinGap 合并
+func ActiveSegments(levels []MotionLevel, options *ActivityOptions) []ActivitySegment {
+	if options == nil {
+		options = &ActivityOptions{}
+	}
+	threshold := options.Threshold
+	if threshold == 0 {
+		threshold = 0.02
+	}
+
+	var segments []ActivitySegment
+	for _, level := range levels {
+		if level.Score < threshold {
+			continue
+		}
+		if n := len(segments); n > 0 && level.Start-segments[n-1].End <= options.MinGap {
+			segments[n-1].End = level.End
+			segments[n-1].Peak = math.Max(segments[n-1].Peak, level.Score)
+			continue
+		}
+		segments = append(segments, ActivitySegment{Start: level.Start, End: level.End, Peak: level.Score})
+	}
+
+	var end time.Duration
+	if len(levels) > 0 {
+		end = levels[len(levels)-1].End
+	}
+	result := segments[:0]
+	for _, segment := range segments {
+		if segment.End-segment.Start < options.MinDuration {
+			continue
+		}
+		segment.Start = max(segment.Start-options.Padding, 0)
+		segment.End = min(segment.End+options.Padding, end)
+		// 扩展后与上一片段重叠时合并
+		if n := len(result); n > 0 && segment.Start <= result[n-1].End {
+			result[n-1].End = segment.End
+			result[n-1].Peak = math.Max(result[n-1].Peak, segment.Peak)
+			continue
+		}
+		result = append(result, segment)
+	}
+	return result
+}
+
+// ExtractActive 按片段创建子剪辑，调用方负责关闭返回的剪辑
+func ExtractActive(clip core.Clip, segments []ActivitySegment) ([]core.Clip, error) {
+	clips := make([]core.Clip, 0, len(segments))
+	for _, segment := range segments {
+		sub, err := clip.Subclip(segment.Start, segment.End)
+		if err != nil {
+			for _, c := range clips {
+				c.Close()
+			}
+			return nil, fmt.Errorf("创建片段 %v-%v 失败: %w", segment.Start, segment.End, err)
+		}
+		clips = append(clips, sub)
+	}
+	return clips, nil
+}
+
+// smallLuma 以最近邻缩小到指定宽度后计算亮度
+func smallLuma(img image.Image, width int) []float64 {
+	rgba := toRGBA(img)
+	srcWidth, srcHeight := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if width > srcWidth {
+		width = srcWidth
+	}
+	height := max(srcHeight*width/srcWidth, 1)
+
+	result := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		sy := y * srcHeight / height
+		for x := 0; x < width; x++ {
+			i := sy*rgba.Stride + (x*srcWidth/width)*4
+			result[y*width+x] = 0.299*float64(rgba.Pix[i]) +
+				0.587*float64(rgba.Pix[i+1]) +
+				0.114*float64(rgba.Pix[i+2])
+		}
+	}
+	return result
+}
+
+// changedRatio 亮度差超过阈值的像素比例
+func changedRatio(a, b []float64, threshold float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	changed := 0
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > threshold {
+			changed++
+		}
+	}
+	return float64(changed) / float64(len(a))
+}
@@ -0,0 +1,361 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// MaxPSNR 两帧完全相同时报告的 PSNR 上限（dB）
+const MaxPSNR = 100.0
+
+// CompareOptions 质量对比选项
+type CompareOptions struct {
+	FPS        float64 // 采样帧率，0 表示使用 a 的帧率
+	MaxFrames  int     // 最多对比的帧数，0 表示不限
+	EnableVMAF bool    // 是否尝试通过 ffmpeg libvmaf 计算 VMAF
+
+	Context    context.Context        // 取消 VMAF 计算，nil 表示不取消
+	ProcessMgr *ffmpeg.ProcessManager // VMAF 使用的进程管理器，nil 时临时创建
+}
+
+// FrameMetrics 单帧质量指标
+type FrameMetrics struct {
+	Index int
+	Time  time.Duration
+	PSNR  float64
+	SSIM  float64
+}
+
+// CompareResult 质量对比结果
+type CompareResult struct {
+	Frames   []FrameMetrics
+	MeanPSNR float64
+	MinPSNR  float64
+	MeanSSIM float64
+	MinSSIM  float64
+	VMAF     float64
+	HasVMAF  bool
+}
+
+// sourceClip 能提供源文件路径和源文件中时间范围的剪辑，用于 VMAF 计算
+type sourceClip interface {
+	Filename() string
+	Start() time.Duration
+	End() time.Duration
+	Duration() time.Duration
+}
+
+// vmafInput 返回剪辑对应的 VMAF 输入；变速剪辑的源文件范围与剪辑时间不一致，不能直接对比
+func vmafInput(clip core.VideoClip) (VMAFInput, bool) {
+	source, ok := clip.(sourceClip)
+	if !ok || source.End()-source.Start() != source.Duration() {
+		return VMAFInput{}, false
+	}
+	return VMAFInput{Filename: source.Filename(), Start: source.Start()}, true
+}
+
+// Compare 逐帧对比两个视频剪辑的质量，a 为参考，b 为待测
+func Compare(a, b core.VideoClip, options *CompareOptions) (*CompareResult, error) {
+	if a == nil || b == nil {
+		return nil, fmt.Errorf("对比的剪辑不能为空")
+	}
+	if a.Width() != b.Width() || a.Height() != b.Height() {
+		return nil, fmt.Errorf("剪辑尺寸不一致: %dx%d 与 %dx%d",
+			a.Width(), a.Height(), b.Width(), b.Height())
+	}
+
+	if options == nil {
+		options = &CompareOptions{}
+	}
+	fps := options.FPS
+	if fps == 0 {
+		fps = a.FPS()
+	}
+	if fps <= 0 {
+		return nil, fmt.Errorf("无效的采样帧率: %f", fps)
+	}
+
+	duration := a.Duration()
+	if b.Duration() < duration {
+		duration = b.Duration()
+	}
+
+	totalFrames := core.FrameCount(duration, fps)
+	if options.MaxFrames > 0 && totalFrames > options.MaxFrames {
+		totalFrames = options.MaxFrames
+	}
+	if totalFrames == 0 {
+		return nil, fmt.Errorf("没有可对比的帧")
+	}
+
+	result := &CompareResult{
+		Frames:  make([]FrameMetrics, 0, totalFrames),
+		MinPSNR: math.Inf(1),
+		MinSSIM: math.Inf(1),
+	}
+
+	for i := 0; i < totalFrames; i++ {
+		t := core.FrameTime(i, fps)
+
+		frameA, err := a.GetFrame(t)
+		if err != nil {
+			return nil, fmt.Errorf("获取参考帧 %d 失败: %w", i, err)
+		}
+		frameB, err := b.GetFrame(t)
+		if err != nil {
+			return nil, fmt.Errorf("获取待测帧 %d 失败: %w", i, err)
+		}
+
+		psnr, err := PSNR(frameA, frameB)
+		if err != nil {
+			return nil, fmt.Errorf("计算第 %d 帧 PSNR 失败: %w", i, err)
+		}
+		ssim, err := SSIM(frameA, frameB)
+		if err != nil {
+			return nil, fmt.Errorf("计算第 %d 帧 SSIM 失败: %w", i, err)
+		}
+
+		result.Frames = append(result.Frames, FrameMetrics{Index: i, Time: t, PSNR: psnr, SSIM: ssim})
+		result.MeanPSNR += psnr
+		result.MeanSSIM += ssim
+		result.MinPSNR = math.Min(result.MinPSNR, psnr)
+		result.MinSSIM = math.Min(result.MinSSIM, ssim)
+	}
+
+	result.MeanPSNR /= float64(len(result.Frames))
+	result.MeanSSIM /= float64(len(result.Frames))
+
+	if options.EnableVMAF {
+		ctx := options.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		processMgr := options.ProcessMgr
+		if processMgr == nil {
+			processMgr = ffmpeg.NewProcessManager()
+			defer processMgr.Close()
+		}
+
+		// VMAF 只对比逐帧对比过的范围：子剪辑的起点、较短剪辑的时长和 MaxFrames
+		inputA, okA := vmafInput(a)
+		inputB, okB := vmafInput(b)
+		if okA && okB && VMAFAvailable(ctx, processMgr) {
+			span := min(duration, core.FrameTime(totalFrames, fps))
+			frames := 0
+			if fps == a.FPS() {
+				frames = totalFrames
+			}
+			score, err := VMAF(ctx, inputA, inputB, span, frames, processMgr)
+			if err != nil {
+				return nil, fmt.Errorf("计算 VMAF 失败: %w", err)
+			}
+			result.VMAF = score
+			result.HasVMAF = true
+		}
+	}
+
+	return result, nil
+}
+
+// PSNR 计算两帧在 RGB 通道上的峰值信噪比（dB）
+func PSNR(a, b image.Image) (float64, error) {
+	ra, rb, err := sameSizeRGBA(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	width := ra.Bounds().Dx()
+	height := ra.Bounds().Dy()
+
+	var sum float64
+	for y := 0; y < height; y++ {
+		ia := y * ra.Stride
+		ib := y * rb.Stride
+		for x := 0; x < width*4; x += 4 {
+			for c := 0; c < 3; c++ {
+				d := float64(ra.Pix[ia+x+c]) - float64(rb.Pix[ib+x+c])
+				sum += d * d
+			}
+		}
+	}
+
+	mse := sum / float64(width*height*3)
+	if mse == 0 {
+		return MaxPSNR, nil
+	}
+	return math.Min(MaxPSNR, 10*math.Log10(255*255/mse)), nil
+}
+
+// SSIM 计算两帧亮度通道上的结构相似度（8x8 窗口，步长 4）
+func SSIM(a, b image.Image) (float64, error) {
+	ra, rb, err := sameSizeRGBA(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	width := ra.Bounds().Dx()
+	height := ra.Bounds().Dy()
+	la := luma(ra)
+	lb := luma(rb)
+
+	const (
+		window = 8
+		step   = 4
+		c1     = (0.01 * 255) * (0.01 * 255)
+		c2     = (0.03 * 255) * (0.03 * 255)
+	)
+
+	// 图像小于窗口时退化为整帧计算
+	winW, winH := window, window
+	if width < winW {
+		winW = width
+	}
+	if height < winH {
+		winH = height
+	}
+
+	var total float64
+	var windows int
+	for y := 0; y+winH <= height; y += step {
+		for x := 0; x+winW <= width; x += step {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			for wy := 0; wy < winH; wy++ {
+				row := (y + wy) * width
+				for wx := 0; wx < winW; wx++ {
+					va := la[row+x+wx]
+					vb := lb[row+x+wx]
+					sumA += va
+					sumB += vb
+					sumAA += va * va
+					sumBB += vb * vb
+					sumAB += va * vb
+				}
+			}
+
+			n := float64(winW * winH)
+			meanA := sumA / n
+			meanB := sumB / n
+			varA := sumAA/n - meanA*meanA
+			varB := sumBB/n - meanB*meanB
+			cov := sumAB/n - meanA*meanB
+
+			total += ((2*meanA*meanB + c1) * (2*cov + c2)) /
+				((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			windows++
+		}
+	}
+
+	if windows == 0 {
+		return 0, fmt.Errorf("图像过小，无法计算 SSIM")
+	}
+	return total / float64(windows), nil
+}
+
+// vmafScorePattern 匹配 libvmaf 输出的总分
+var vmafScorePattern = regexp.MustCompile(`VMAF score[:=]\s*([0-9.]+)`)
+
+// VMAFAvailable 检查管理器所用 ffmpeg 是否编译了 libvmaf 滤镜
+func VMAFAvailable(ctx context.Context, processMgr *ffmpeg.ProcessManager) bool {
+	output, err := processMgr.Output(ctx, "ffmpeg", []string{"-hide_banner", "-filters"})
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(output), "libvmaf")
+}
+
+// VMAFInput VMAF 对比的一路输入：源文件和对比范围在其中的起始时间
+type VMAFInput struct {
+	Filename string
+	Start    time.Duration
+}
+
+// args 返回按起始时间和时长截取该输入的参数，duration 为 0 时不限制时长
+func (in VMAFInput) args(duration time.Duration) []string {
+	var args []string
+	if in.Start > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.3f", in.Start.Seconds()))
+	}
+	if duration > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", duration.Seconds()))
+	}
+	return append(args, "-i", in.Filename)
+}
+
+// vmafArgs 构建计算 VMAF 的 FFmpeg 参数，libvmaf 的第一路输入为待测、第二路为参考
+func vmafArgs(reference, distorted VMAFInput, duration time.Duration, frames int) []string {
+	args := []string{"-hide_banner"}
+	args = append(args, distorted.args(duration)...)
+	args = append(args, reference.args(duration)...)
+	args = append(args, "-lavfi", "libvmaf")
+	if frames > 0 {
+		args = append(args, "-frames:v", strconv.Itoa(frames))
+	}
+	return append(args, "-f", "null", "-")
+}
+
+// VMAF 通过 ffmpeg libvmaf 计算待测相对参考的 VMAF 分数，两路各从 Start 起对比 duration
+//
+// duration 为 0 时对比到较短的文件结尾；frames 大于 0 时最多对比该帧数。ctx 取消时终止 FFmpeg。
+func VMAF(ctx context.Context, reference, distorted VMAFInput, duration time.Duration, frames int, processMgr *ffmpeg.ProcessManager) (float64, error) {
+	var stderr bytes.Buffer
+	process, err := processMgr.StartProcessWithPipes(ctx, "ffmpeg", vmafArgs(reference, distorted, duration, frames), nil, &ffmpeg.ProcessPipes{Stderr: &stderr})
+	if err != nil {
+		return 0, fmt.Errorf("启动 ffmpeg 失败: %w", err)
+	}
+	if err := process.Wait(); err != nil {
+		return 0, fmt.Errorf("ffmpeg 执行失败: %w", err)
+	}
+
+	match := vmafScorePattern.FindStringSubmatch(stderr.String())
+	if match == nil {
+		return 0, fmt.Errorf("未能从 ffmpeg 输出中解析 VMAF 分数")
+	}
+	return strconv.ParseFloat(match[1], 64)
+}
+
+// sameSizeRGBA 将两帧转换为以 (0,0) 为原点的 RGBA 并检查尺寸
+func sameSizeRGBA(a, b image.Image) (*image.RGBA, *image.RGBA, error) {
+	if a.Bounds().Dx() != b.Bounds().Dx() || a.Bounds().Dy() != b.Bounds().Dy() {
+		return nil, nil, fmt.Errorf("帧尺寸不一致: %dx%d 与 %dx%d",
+			a.Bounds().Dx(), a.Bounds().Dy(), b.Bounds().Dx(), b.Bounds().Dy())
+	}
+	return toRGBA(a), toRGBA(b), nil
+}
+
+// toRGBA 转换为以 (0,0) 为原点的 *image.RGBA，已是该格式时直接返回
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// luma 计算 BT.601 亮度平面
+func luma(img *image.RGBA) []float64 {
+	width := img.Bounds().Dx()
+	height := img.Bounds().Dy()
+	result := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*img.Stride + x*4
+			result[y*width+x] = 0.299*float64(img.Pix[i]) +
+				0.587*float64(img.Pix[i+1]) +
+				0.114*float64(img.Pix[i+2])
+		}
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core/coretest"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// fileClip 模拟源文件中 [start, start+duration) 段的文件剪辑
+type fileClip struct {
+	*coretest.MockVideoClip
+	filename string
+	start    time.Duration
+}
+
+func (fc *fileClip) Filename() string     { return fc.filename }
+func (fc *fileClip) Start() time.Duration { return fc.start }
+func (fc *fileClip) End() time.Duration   { return fc.start + fc.Duration() }
+
+func TestCompareVMAFUsesComparedRange(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "ffmpeg.log")
+	path := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\n" +
+		"case \"$*\" in *-filters*) echo ' ... libvmaf VV->V Calculate the VMAF'; exit 0;; esac\n" +
+		"echo \"$@\" >> '" + log + "'\n" +
+		"echo 'VMAF score: 93.5' >&2\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pm := ffmpeg.NewProcessManagerWithOptions(&ffmpeg.ProcessManagerOptions{FFmpegPath: path, FFprobePath: path})
+	defer pm.Close()
+
+	a := &fileClip{MockVideoClip: coretest.NewCounterClip(4, 4, 2*time.Second, 10), filename: "ref.mp4", start: 3 * time.Second}
+	b := &fileClip{MockVideoClip: coretest.NewCounterClip(4, 4, 3*time.Second, 10), filename: "out.mp4"}
+	result, err := Compare(a, b, &CompareOptions{MaxFrames: 5, EnableVMAF: true, ProcessMgr: pm})
+	if err != nil {
+		t.Fatalf("对比失败: %v", err)
+	}
+	if !result.HasVMAF || result.VMAF != 93.5 {
+		t.Fatalf("VMAF = %v (%v)，期望 93.5", result.VMAF, result.HasVMAF)
+	}
+
+	logged, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5 帧 10 fps 对应 0.5 秒，参考剪辑从源文件 3 秒处开始
+	want := "-t 0.500 -i out.mp4 -ss 3.000 -t 0.500 -i ref.mp4 -lavfi libvmaf -frames:v 5"
+	if !strings.Contains(string(logged), want) {
+		t.Fatalf("VMAF 参数 %q 应包含 %q", logged, want)
+	}
+}
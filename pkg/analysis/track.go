@@ -0,0 +1,256 @@
+package analysis
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// TrackOptions 区域跟踪选项
+type TrackOptions struct {
+	FPS          float64 // 采样帧率，0 表示使用剪辑帧率
+	SearchRadius int     // 相邻采样帧间的最大搜索位移（像素），默认 32
+	UpdateRate   float64 // 模板更新速率 0–1，适应目标外观变化，默认 0.1
+	MinScore     float64 // 归一化相关系数低于此值视为丢失，默认 0.4
+}
+
+// TrackPoint 单个采样时刻的跟踪结果
+type TrackPoint struct {
+	Time  time.Duration
+	Rect  image.Rectangle
+	Score float64 // 归一化相关系数，-1–1
+	Lost  bool    // 匹配度不足，Rect 沿用上一帧
+}
+
+// Track 跟踪得到的区域曲线，采样点之间线性插值
+//
+// RectAt 可直接用于 effects.NewAnimatedRegionEffect，XAt/YAt 可用于 compositing.Position。
+type Track struct {
+	Points []TrackPoint
+}
+
+// TrackRegion 使用模板匹配（归一化互相关）跟踪 initialRect 中的目标
+//
+// 每个采样帧在上一位置附近先以步长 2 粗搜、再逐像素细化，适合人脸、车牌等移动平缓的目标。
+func TrackRegion(clip core.VideoClip, initialRect image.Rectangle, options *TrackOptions) (*Track, error) {
+	if clip == nil {
+		return nil, fmt.Errorf("跟踪的剪辑不能为空")
+	}
+	if options == nil {
+		options = &TrackOptions{}
+	}
+	fps := options.FPS
+	if fps == 0 {
+		fps = clip.FPS()
+	}
+	if fps <= 0 {
+		return nil, fmt.Errorf("无效的采样帧率: %f", fps)
+	}
+	radius := options.SearchRadius
+	if radius <= 0 {
+		radius = 32
+	}
+	rate := options.UpdateRate
+	if rate == 0 {
+		rate = 0.1
+	}
+	minScore := options.MinScore
+	if minScore == 0 {
+		minScore = 0.4
+	}
+
+	frameRect := image.Rect(0, 0, clip.Width(), clip.Height())
+	if initialRect.Empty() || !initialRect.In(frameRect) {
+		return nil, fmt.Errorf("初始区域 %v 超出帧范围 %v", initialRect, frameRect)
+	}
+
+	totalFrames := core.FrameCount(clip.Duration(), fps)
+	if totalFrames == 0 {
+		return nil, fmt.Errorf("没有可跟踪的帧")
+	}
+
+	first, err := clip.GetFrame(0)
+	if err != nil {
+		return nil, fmt.Errorf("获取第 0 帧失败: %w", err)
+	}
+	plane := newLumaPlane(first)
+	template := plane.patch(initialRect)
+
+	track := &Track{Points: make([]TrackPoint, 0, totalFrames)}
+	track.Points = append(track.Points, TrackPoint{Time: 0, Rect: initialRect, Score: 1})
+	current := initialRect
+
+	for i := 1; i < totalFrames; i++ {
+		t := core.FrameTime(i, fps)
+		frame, err := clip.GetFrame(t)
+		if err != nil {
+			return nil, fmt.Errorf("获取第 %d 帧失败: %w", i, err)
+		}
+		plane = newLumaPlane(frame)
+
+		best, score := plane.search(template, current, radius)
+		point := TrackPoint{Time: t, Rect: best, Score: score}
+		if score < minScore {
+			point.Rect = current
+			point.Lost = true
+		} else {
+			current = best
+			template.blend(plane.patch(best), rate)
+		}
+		track.Points = append(track.Points, point)
+	}
+
+	return track, nil
+}
+
+// RectAt 返回时间 t 处的区域，采样点之间线性插值，超出范围取首尾值
+func (tr *Track) RectAt(t time.Duration) image.Rectangle {
+	if len(tr.Points) == 0 {
+		return image.Rectangle{}
+	}
+	i := sort.Search(len(tr.Points), func(i int) bool { return tr.Points[i].Time > t })
+	if i == 0 {
+		return tr.Points[0].Rect
+	}
+	if i == len(tr.Points) {
+		return tr.Points[i-1].Rect
+	}
+	a, b := tr.Points[i-1], tr.Points[i]
+	f := float64(t-a.Time) / float64(b.Time-a.Time)
+	lerp := func(x, y int) int { return int(math.Round(float64(x) + (float64(y)-float64(x))*f)) }
+	return image.Rect(
+		lerp(a.Rect.Min.X, b.Rect.Min.X), lerp(a.Rect.Min.Y, b.Rect.Min.Y),
+		lerp(a.Rect.Max.X, b.Rect.Max.X), lerp(a.Rect.Max.Y, b.Rect.Max.Y),
+	)
+}
+
+// XAt 区域左上角 X 坐标，可加偏移放置跟随目标的标注
+func (tr *Track) XAt(t time.Duration) float64 {
+	return float64(tr.RectAt(t).Min.X)
+}
+
+// YAt 区域左上角 Y 坐标
+func (tr *Track) YAt(t time.Duration) float64 {
+	return float64(tr.RectAt(t).Min.Y)
+}
+
+// CenterAt 区域中心点
+func (tr *Track) CenterAt(t time.Duration) (x, y float64) {
+	rect := tr.RectAt(t)
+	return float64(rect.Min.X+rect.Max.X) / 2, float64(rect.Min.Y+rect.Max.Y) / 2
+}
+
+// LostRatio 丢失目标的采样点比例
+func (tr *Track) LostRatio() float64 {
+	if len(tr.Points) == 0 {
+		return 0
+	}
+	lost := 0
+	for _, p := range tr.Points {
+		if p.Lost {
+			lost++
+		}
+	}
+	return float64(lost) / float64(len(tr.Points))
+}
+
+// lumaPlane 以 (0,0) 为原点的亮度平面
+type lumaPlane struct {
+	width, height int
+	pix           []float64
+}
+
+// newLumaPlane 计算帧的亮度平面
+func newLumaPlane(img image.Image) *lumaPlane {
+	rgba := toRGBA(img)
+	return &lumaPlane{width: rgba.Bounds().Dx(), height: rgba.Bounds().Dy(), pix: luma(rgba)}
+}
+
+// patch 复制 rect 内的亮度作为模板
+func (lp *lumaPlane) patch(rect image.Rectangle) *lumaPlane {
+	w, h := rect.Dx(), rect.Dy()
+	p := &lumaPlane{width: w, height: h, pix: make([]float64, w*h)}
+	for y := 0; y < h; y++ {
+		copy(p.pix[y*w:(y+1)*w], lp.pix[(rect.Min.Y+y)*lp.width+rect.Min.X:])
+	}
+	return p
+}
+
+// blend 按 rate 将 other 混入模板
+func (lp *lumaPlane) blend(other *lumaPlane, rate float64) {
+	for i := range lp.pix {
+		lp.pix[i] += (other.pix[i] - lp.pix[i]) * rate
+	}
+}
+
+// search 在 around 附近 radius 范围内寻找与模板最相关的位置
+func (lp *lumaPlane) search(template *lumaPlane, around image.Rectangle, radius int) (image.Rectangle, float64) {
+	maxX, maxY := lp.width-template.width, lp.height-template.height
+	bestX, bestY, bestScore := around.Min.X, around.Min.Y, math.Inf(-1)
+
+	try := func(x, y int) {
+		if x < 0 || y < 0 || x > maxX || y > maxY {
+			return
+		}
+		if score := lp.ncc(template, x, y); score > bestScore {
+			bestX, bestY, bestScore = x, y, score
+		}
+	}
+
+	for dy := -radius; dy <= radius; dy += 2 {
+		for dx := -radius; dx <= radius; dx += 2 {
+			try(around.Min.X+dx, around.Min.Y+dy)
+		}
+	}
+	cx, cy := bestX, bestY
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			try(cx+dx, cy+dy)
+		}
+	}
+
+	if math.IsInf(bestScore, -1) {
+		return around, 0
+	}
+	return image.Rect(bestX, bestY, bestX+template.width, bestY+template.height), bestScore
+}
+
+// ncc 模板与 (x,y) 处同尺寸区域的零均值归一化互相关
+func (lp *lumaPlane) ncc(template *lumaPlane, x, y int) float64 {
+	w, h := template.width, template.height
+	n := float64(w * h)
+
+	var sumA, sumB float64
+	for ty := 0; ty < h; ty++ {
+		row := lp.pix[(y+ty)*lp.width+x:]
+		for tx := 0; tx < w; tx++ {
+			sumA += row[tx]
+			sumB += template.pix[ty*w+tx]
+		}
+	}
+	meanA, meanB := sumA/n, sumB/n
+
+	var cross, varA, varB float64
+	for ty := 0; ty < h; ty++ {
+		row := lp.pix[(y+ty)*lp.width+x:]
+		for tx := 0; tx < w; tx++ {
+			a := row[tx] - meanA
+			b := template.pix[ty*w+tx] - meanB
+			cross += a * b
+			varA += a * a
+			varB += b * b
+		}
+	}
+	if varA == 0 || varB == 0 {
+		// 平坦区域无法区分位置，均为平坦时视为匹配
+		if varA == varB {
+			return 1
+		}
+		return 0
+	}
+	return cross / math.Sqrt(varA*varB)
+}
@@ -0,0 +1,198 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// DefaultDurationTolerance 校验时长时默认允许的偏差
+const DefaultDurationTolerance = 200 * time.Millisecond
+
+// VerifyOptions 输出校验选项
+type VerifyOptions struct {
+	DurationTolerance time.Duration // 时长允许的偏差，默认 200ms
+	SpotFrames        int           // 均匀抽取并解码的帧数，0 表示不解码
+	ProcessMgr        *ffmpeg.ProcessManager
+}
+
+// VerifyCheck 单项校验结果
+type VerifyCheck struct {
+	Name     string
+	Passed   bool
+	Expected string
+	Actual   string
+}
+
+// VerifyReport 输出校验报告
+type VerifyReport struct {
+	Filename string
+	Probe    *ffmpeg.ProbeResult
+	Checks   []VerifyCheck
+}
+
+// Passed 判断所有校验项是否通过
+func (r *VerifyReport) Passed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Err 有未通过的校验项时返回包装 core.ErrVerificationFailed 的错误
+func (r *VerifyReport) Err() error {
+	var failed []string
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, fmt.Sprintf("%s（期望 %s，实际 %s）", check.Name, check.Expected, check.Actual))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s: %s", core.ErrVerificationFailed, r.Filename, strings.Join(failed, "；"))
+}
+
+// add 追加一项校验
+func (r *VerifyReport) add(name string, passed bool, expected, actual string) {
+	r.Checks = append(r.Checks, VerifyCheck{Name: name, Passed: passed, Expected: expected, Actual: actual})
+}
+
+// Verify 探测输出文件并对照预期校验流数量、时长、尺寸和编码，可选抽帧解码
+//
+// 流数量总是校验，时长、尺寸和编码为零值时跳过。探测本身失败时返回错误，校验不通过只反映在报告中。
+func Verify(filename string, expect core.OutputExpectation, options *VerifyOptions) (*VerifyReport, error) {
+	if options == nil {
+		options = &VerifyOptions{}
+	}
+	if options.DurationTolerance == 0 {
+		options.DurationTolerance = DefaultDurationTolerance
+	}
+
+	probe, err := ffmpeg.ProbeContext(context.Background(), filename, options.ProcessMgr)
+	if err != nil {
+		return nil, fmt.Errorf("探测输出文件失败: %w", err)
+	}
+
+	report := &VerifyReport{Filename: filename, Probe: probe}
+	videoStreams := probe.VideoStreams()
+	audioStreams := probe.AudioStreams()
+
+	report.add("视频流数量", len(videoStreams) == expect.VideoStreams,
+		strconv.Itoa(expect.VideoStreams), strconv.Itoa(len(videoStreams)))
+	report.add("音频流数量", len(audioStreams) == expect.AudioStreams,
+		strconv.Itoa(expect.AudioStreams), strconv.Itoa(len(audioStreams)))
+
+	if expect.Duration > 0 {
+		actual := seconds(probe.Format.Duration)
+		report.add("时长", absDuration(actual-expect.Duration) <= options.DurationTolerance,
+			expect.Duration.String(), actual.String())
+	}
+
+	if len(videoStreams) > 0 {
+		video := videoStreams[0]
+		if expect.Width > 0 && expect.Height > 0 {
+			report.add("分辨率", video.Width == expect.Width && video.Height == expect.Height,
+				fmt.Sprintf("%dx%d", expect.Width, expect.Height), fmt.Sprintf("%dx%d", video.Width, video.Height))
+		}
+		if expect.VideoCodec != "" {
+			codec := CodecForEncoder(expect.VideoCodec)
+			report.add("视频编码", video.CodecName == codec, codec, video.CodecName)
+		}
+	}
+	if len(audioStreams) > 0 && expect.AudioCodec != "" {
+		codec := CodecForEncoder(expect.AudioCodec)
+		report.add("音频编码", audioStreams[0].CodecName == codec, codec, audioStreams[0].CodecName)
+	}
+
+	if options.SpotFrames > 0 && len(videoStreams) > 0 {
+		decoded, err := spotCheck(filename, options.SpotFrames, options.ProcessMgr)
+		actual := fmt.Sprintf("%d 帧", decoded)
+		if err != nil {
+			actual = fmt.Sprintf("%s，%v", actual, err)
+		}
+		report.add("抽帧解码", err == nil, fmt.Sprintf("%d 帧", options.SpotFrames), actual)
+	}
+
+	return report, nil
+}
+
+// VerifyHook 返回可用作 core.WriteOptions.Verify 的校验函数，onReport 非 nil 时接收每次的报告
+func VerifyHook(options *VerifyOptions, onReport func(*VerifyReport)) func(filename string, expect core.OutputExpectation) error {
+	return func(filename string, expect core.OutputExpectation) error {
+		report, err := Verify(filename, expect, options)
+		if err != nil {
+			return err
+		}
+		if onReport != nil {
+			onReport(report)
+		}
+		return report.Err()
+	}
+}
+
+// spotCheck 在时长内均匀解码 n 帧，返回成功解码的帧数
+func spotCheck(filename string, n int, processMgr *ffmpeg.ProcessManager) (int, error) {
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	reader := ffmpeg.NewVideoReader(filename, processMgr)
+	if err := reader.Open(); err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	duration := seconds(reader.GetInfo().Duration)
+	for i := 0; i < n; i++ {
+		// 取各等分区间的中点，避开首尾的边界帧
+		t := time.Duration((float64(i) + 0.5) / float64(n) * float64(duration))
+		if _, err := reader.GetFrame(t); err != nil {
+			return i, fmt.Errorf("解码 %v 处的帧失败: %w", t, err)
+		}
+	}
+	return n, nil
+}
+
+// encoderCodecs 编码器名称到 ffprobe codec_name 的映射
+var encoderCodecs = map[string]string{
+	"libx264":    "h264",
+	"libx264rgb": "h264",
+	"libx265":    "hevc",
+	"libvpx":     "vp8",
+	"libvpx-vp9": "vp9",
+	"libaom-av1": "av1",
+	"libsvtav1":  "av1",
+	"librav1e":   "av1",
+	"prores_ks":  "prores",
+	"libmp3lame": "mp3",
+	"libopus":    "opus",
+	"libvorbis":  "vorbis",
+	"libfdk_aac": "aac",
+}
+
+// CodecForEncoder 返回编码器输出的 codec_name，如 libx264 → h264、h264_nvenc → h264
+func CodecForEncoder(encoder string) string {
+	if codec, ok := encoderCodecs[encoder]; ok {
+		return codec
+	}
+	// 硬件编码器以 codec_后端 命名
+	if i := strings.Index(encoder, "_"); i > 0 {
+		switch prefix := encoder[:i]; prefix {
+		case "h264", "hevc", "av1", "vp8", "vp9", "mjpeg", "mpeg2":
+			if prefix == "mpeg2" {
+				return "mpeg2video"
+			}
+			return prefix
+		}
+	}
+	return encoder
+}
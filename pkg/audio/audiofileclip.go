@@ -2,10 +2,14 @@ package audio
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"moviepy-go/pkg/core"
 	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/leakcheck"
 )
 
 // AudioFileClip 音频文件剪辑
@@ -15,15 +19,52 @@ type AudioFileClip struct {
 	reader     *ffmpeg.AudioReader
 	processMgr *ffmpeg.ProcessManager
 	closed     bool
+
+	speedFactor   float64 // 相对源文件的速度因子，0 表示正常速度
+	preservePitch bool    // 变速时保持音高
+	gain          float64 // 音量增益，1.0 表示原始音量
+	stream        ffmpeg.StreamSelector
+	timeout       time.Duration
+	leak          *leakcheck.Guard
+}
+
+// AudioFileClipOptions 打开音频文件的选项
+type AudioFileClipOptions struct {
+	// Stream 选择音频流（按序号或语言），默认第一个
+	Stream ffmpeg.StreamSelector
+	// Timeout 单次探测或读取音频的最长时间，超时后终止 FFmpeg 并返回错误，0 表示不限
+	Timeout time.Duration
+}
+
+// audioFramesPerSecond 写入时每秒的音频帧数，GetAudioFrame 每次返回 0.1 秒的样本
+const audioFramesPerSecond = 10
+
+// SpeedOptions 变速选项
+type SpeedOptions struct {
+	// PreservePitch 使用 atempo 只改变速度、保持音高；默认同时改变音高（类似磁带快放）
+	PreservePitch bool
 }
 
 // NewAudioFileClip 创建新的音频文件剪辑
 func NewAudioFileClip(filename string, processMgr *ffmpeg.ProcessManager) *AudioFileClip {
-	return &AudioFileClip{
+	return NewAudioFileClipWithOptions(filename, nil, processMgr)
+}
+
+// NewAudioFileClipWithOptions 使用指定选项创建音频文件剪辑
+func NewAudioFileClipWithOptions(filename string, options *AudioFileClipOptions, processMgr *ffmpeg.ProcessManager) *AudioFileClip {
+	if options == nil {
+		options = &AudioFileClipOptions{}
+	}
+	afc := &AudioFileClip{
 		BaseAudioClip: core.NewBaseAudioClip(0, 0, 0, 0, 0, 0),
 		filename:      filename,
 		processMgr:    processMgr,
+		gain:          1.0,
+		stream:        options.Stream,
+		timeout:       options.Timeout,
 	}
+	afc.leak = leakcheck.Track(afc, "AudioFileClip", filename)
+	return afc
 }
 
 // Open 打开音频文件
@@ -32,11 +73,18 @@ func (afc *AudioFileClip) Open() error {
 		return fmt.Errorf("剪辑已关闭")
 	}
 
+	// 重复打开时先释放上一次的读取器
+	if afc.reader != nil {
+		afc.reader.Release()
+	}
+
 	// 创建读取器
-	afc.reader = ffmpeg.NewAudioReader(afc.filename, afc.processMgr)
+	afc.reader = ffmpeg.NewAudioReaderWithOptions(afc.filename, &ffmpeg.AudioReaderOptions{Stream: afc.stream, Timeout: afc.timeout}, afc.processMgr)
 
 	// 打开音频
 	if err := afc.reader.Open(); err != nil {
+		afc.reader.Close()
+		afc.reader = nil
 		return fmt.Errorf("打开音频失败: %w", err)
 	}
 
@@ -63,7 +111,82 @@ func (afc *AudioFileClip) GetAudioFrame(t time.Duration) ([]float64, error) {
 		return nil, fmt.Errorf("音频未打开")
 	}
 
-	return afc.reader.GetAudioFrame(t)
+	// 子剪辑共享父剪辑的读取器，需要加上起始偏移
+	speed := afc.speed()
+	source := afc.Start() + time.Duration(float64(t)*speed)
+
+	var samples []float64
+	var err error
+	if speed == 1.0 {
+		samples, err = afc.reader.GetAudioFrame(source)
+	} else {
+		// 变速时读取 0.1*speed 秒的源音频，经滤镜处理为 0.1 秒的帧
+		frameSize := int(0.1 * float64(afc.SampleRate()) * float64(afc.Channels()))
+		window := time.Duration(0.1 * speed * float64(time.Second))
+		samples, err = afc.reader.ReadSamples(source, window, speedFilter(speed, afc.preservePitch, afc.SampleRate()), frameSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if afc.gain != 1.0 {
+		for i := range samples {
+			samples[i] *= afc.gain
+		}
+	}
+	return samples, nil
+}
+
+// speed 返回有效的速度因子
+func (afc *AudioFileClip) speed() float64 {
+	if afc.speedFactor == 0 {
+		return 1.0
+	}
+	return afc.speedFactor
+}
+
+// speedFilter 构建变速滤镜；atempo 单级只支持 0.5-2.0，超出时串联多级
+func speedFilter(speed float64, preservePitch bool, sampleRate int) string {
+	if !preservePitch {
+		// 按新采样率解释样本再重采样回原采样率，速度和音高同时改变
+		return fmt.Sprintf("asetrate=%d,aresample=%d", int(math.Round(float64(sampleRate)*speed)), sampleRate)
+	}
+
+	var stages []string
+	for speed > 2.0 {
+		stages = append(stages, "atempo=2.0")
+		speed /= 2.0
+	}
+	for speed < 0.5 {
+		stages = append(stages, "atempo=0.5")
+		speed /= 0.5
+	}
+	stages = append(stages, "atempo="+strconv.FormatFloat(speed, 'f', -1, 64))
+	return strings.Join(stages, ",")
+}
+
+// derive 创建共享同一读取器的派生剪辑，读取器引用计数加一
+func (afc *AudioFileClip) derive(base *core.BaseAudioClip) *AudioFileClip {
+	clip := &AudioFileClip{
+		BaseAudioClip: base,
+		filename:      afc.filename,
+		processMgr:    afc.processMgr,
+		speedFactor:   afc.speedFactor,
+		preservePitch: afc.preservePitch,
+		gain:          afc.gain,
+		stream:        afc.stream,
+		timeout:       afc.timeout,
+	}
+	if afc.reader != nil && afc.reader.Retain() == nil {
+		clip.reader = afc.reader
+	}
+	clip.leak = leakcheck.Track(clip, "AudioFileClip", afc.filename)
+	return clip
+}
+
+// Clone 创建共享读取器的副本，副本与原剪辑可按任意顺序关闭
+func (afc *AudioFileClip) Clone() *AudioFileClip {
+	return afc.derive(core.NewBaseAudioClip(afc.Start(), afc.End(), afc.Duration(), afc.FPS(), afc.Channels(), afc.SampleRate()))
 }
 
 // Subclip 创建子剪辑
@@ -76,18 +199,32 @@ func (afc *AudioFileClip) Subclip(start, end time.Duration) (core.Clip, error) {
 		return nil, core.ErrInvalidTimeRange
 	}
 
-	// 创建新的子剪辑
-	subclip := &AudioFileClip{
-		BaseAudioClip: core.NewBaseAudioClip(start, end, end-start, afc.FPS(), afc.Channels(), afc.SampleRate()),
-		filename:      afc.filename,
-		processMgr:    afc.processMgr,
-	}
+	// start、end 为剪辑内时间，换算为源文件时间以支持嵌套子剪辑和变速剪辑
+	speed := afc.speed()
+	sourceStart := afc.Start() + time.Duration(float64(start)*speed)
+	sourceEnd := afc.Start() + time.Duration(float64(end)*speed)
+	subclip := afc.derive(core.NewBaseAudioClip(sourceStart, sourceEnd, end-start, afc.FPS(), afc.Channels(), afc.SampleRate()))
 
 	return subclip, nil
 }
 
-// WithSpeed 调整播放速度
+// WithStart 设置源文件中的开始时间，结束时间不变；只能在当前范围内收缩
+func (afc *AudioFileClip) WithStart(start time.Duration) (core.Clip, error) {
+	return afc.Subclip(time.Duration(float64(start-afc.Start())/afc.speed()), afc.Duration())
+}
+
+// WithEnd 设置源文件中的结束时间，开始时间不变；只能在当前范围内收缩
+func (afc *AudioFileClip) WithEnd(end time.Duration) (core.Clip, error) {
+	return afc.Subclip(0, time.Duration(float64(end-afc.Start())/afc.speed()))
+}
+
+// WithSpeed 调整播放速度，音高随速度改变
 func (afc *AudioFileClip) WithSpeed(factor float64) (core.Clip, error) {
+	return afc.WithSpeedOptions(factor, nil)
+}
+
+// WithSpeedOptions 按选项调整播放速度，可保持音高
+func (afc *AudioFileClip) WithSpeedOptions(factor float64, options *SpeedOptions) (*AudioFileClip, error) {
 	if afc.closed {
 		return nil, fmt.Errorf("剪辑已关闭")
 	}
@@ -95,14 +232,16 @@ func (afc *AudioFileClip) WithSpeed(factor float64) (core.Clip, error) {
 	if factor <= 0 {
 		return nil, core.ErrInvalidSpeedFactor
 	}
-
-	// 创建新的剪辑
-	speedClip := &AudioFileClip{
-		BaseAudioClip: core.NewBaseAudioClip(afc.Start(), afc.End(), afc.Duration()/time.Duration(factor*float64(time.Second)), afc.FPS()*factor, afc.Channels(), afc.SampleRate()),
-		filename:      afc.filename,
-		processMgr:    afc.processMgr,
+	if options == nil {
+		options = &SpeedOptions{}
 	}
 
+	// 速度加快时长变短，源文件范围不变
+	newDuration := time.Duration(float64(afc.Duration()) / factor)
+	speedClip := afc.derive(core.NewBaseAudioClip(afc.Start(), afc.End(), newDuration, afc.FPS(), afc.Channels(), afc.SampleRate()))
+	speedClip.speedFactor = afc.speed() * factor
+	speedClip.preservePitch = options.PreservePitch
+
 	return speedClip, nil
 }
 
@@ -116,18 +255,18 @@ func (afc *AudioFileClip) WithVolume(factor float64) (core.Clip, error) {
 		return nil, core.ErrInvalidVolumeFactor
 	}
 
-	// 创建新的剪辑
-	volumeClip := &AudioFileClip{
-		BaseAudioClip: core.NewBaseAudioClip(afc.Start(), afc.End(), afc.Duration(), afc.FPS(), afc.Channels(), afc.SampleRate()),
-		filename:      afc.filename,
-		processMgr:    afc.processMgr,
-	}
+	// 创建新的剪辑，增益与已有增益累乘，在读取样本时应用
+	volumeClip := afc.derive(core.NewBaseAudioClip(afc.Start(), afc.End(), afc.Duration(), afc.FPS(), afc.Channels(), afc.SampleRate()))
+	volumeClip.gain = afc.gain * factor
 
-	// 这里应该实现音量调整逻辑
-	// 简化实现，直接返回
 	return volumeClip, nil
 }
 
+// Gain 返回音量增益
+func (afc *AudioFileClip) Gain() float64 {
+	return afc.gain
+}
+
 // WithChannels 设置声道数
 func (afc *AudioFileClip) WithChannels(channels int) (core.AudioClip, error) {
 	if afc.closed {
@@ -139,11 +278,7 @@ func (afc *AudioFileClip) WithChannels(channels int) (core.AudioClip, error) {
 	}
 
 	// 创建新的剪辑
-	channelsClip := &AudioFileClip{
-		BaseAudioClip: core.NewBaseAudioClip(afc.Start(), afc.End(), afc.Duration(), afc.FPS(), channels, afc.SampleRate()),
-		filename:      afc.filename,
-		processMgr:    afc.processMgr,
-	}
+	channelsClip := afc.derive(core.NewBaseAudioClip(afc.Start(), afc.End(), afc.Duration(), afc.FPS(), channels, afc.SampleRate()))
 
 	return channelsClip, nil
 }
@@ -159,11 +294,7 @@ func (afc *AudioFileClip) WithSampleRate(sampleRate int) (core.AudioClip, error)
 	}
 
 	// 创建新的剪辑
-	sampleRateClip := &AudioFileClip{
-		BaseAudioClip: core.NewBaseAudioClip(afc.Start(), afc.End(), afc.Duration(), afc.FPS(), afc.Channels(), sampleRate),
-		filename:      afc.filename,
-		processMgr:    afc.processMgr,
-	}
+	sampleRateClip := afc.derive(core.NewBaseAudioClip(afc.Start(), afc.End(), afc.Duration(), afc.FPS(), afc.Channels(), sampleRate))
 
 	return sampleRateClip, nil
 }
@@ -196,10 +327,10 @@ func (afc *AudioFileClip) WriteToFile(filename string, options *core.WriteOption
 		return fmt.Errorf("剪辑已关闭")
 	}
 
-	// 设置默认选项
-	if options == nil {
-		options = &core.WriteOptions{}
-	}
+	// 设置默认选项，未设置的字段先取 core.SetWriteDefaults 配置的值
+	options = core.ApplyWriteDefaults(options)
+	ctx, done := core.BeginRender(options.Context, afc, filename)
+	defer done()
 	if options.AudioCodec == "" {
 		options.AudioCodec = "aac"
 	}
@@ -209,32 +340,47 @@ func (afc *AudioFileClip) WriteToFile(filename string, options *core.WriteOption
 
 	// 创建音频写入器
 	writerOptions := &ffmpeg.AudioWriterOptions{
-		Codec:      options.AudioCodec,
-		Bitrate:    options.AudioBitrate,
-		SampleRate: afc.SampleRate(),
-		Channels:   afc.Channels(),
+		Codec:       options.AudioCodec,
+		Bitrate:     options.AudioBitrate,
+		SampleRate:  afc.SampleRate(),
+		Channels:    afc.Channels(),
+		Filter:      options.AudioFilter,
+		DirectWrite: options.DirectWrite,
+		LogLevel:    ffmpeg.LogLevel(options.LogLevel),
+		Logger:      options.Logger,
 	}
 
 	writer := ffmpeg.NewAudioWriter(filename, writerOptions, afc.processMgr)
 
+	// 报告将要执行的命令，试运行时到此为止
+	if options.OnCommand != nil {
+		command := writer.Command()
+		options.OnCommand(command.Name, command.Args)
+	}
+	if options.DryRun {
+		return nil
+	}
+
 	// 打开写入器
 	if err := writer.Open(); err != nil {
 		return fmt.Errorf("打开写入器失败: %w", err)
 	}
-	defer writer.Close()
+	// 出错时中止写入，成功关闭后 Abort 为空操作
+	defer writer.Abort()
 
-	// 计算总帧数
-	totalFrames := int(afc.Duration().Seconds() * afc.FPS())
-	frameInterval := time.Duration(float64(time.Second) / afc.FPS())
+	// 计算总帧数，每帧为 0.1 秒的样本
+	totalFrames := core.FrameCount(afc.Duration(), audioFramesPerSecond)
+	frameInterval := core.FrameTime(1, audioFramesPerSecond)
 
 	fmt.Printf("开始写入音频: %s\n", filename)
 	fmt.Printf("总帧数: %d, 帧间隔: %v\n", totalFrames, frameInterval)
 
 	// 逐帧写入
 	for i := 0; i < totalFrames; i++ {
-		t := time.Duration(i) * frameInterval
-		if t > afc.Duration() {
-			break
+		t := core.FrameTime(i, audioFramesPerSecond)
+
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%w: %v", core.ErrContextCancelled, err)
 		}
 
 		frame, err := afc.GetAudioFrame(t)
@@ -242,10 +388,22 @@ func (afc *AudioFileClip) WriteToFile(filename string, options *core.WriteOption
 			return fmt.Errorf("获取第 %d 帧失败: %w", i, err)
 		}
 
+		// 最后一帧只保留剪辑时长内的样本
+		if remaining := afc.Duration() - t; remaining < frameInterval {
+			keep := int(remaining.Seconds()*float64(afc.SampleRate())) * afc.Channels()
+			if keep < len(frame) {
+				frame = frame[:keep]
+			}
+		}
+
 		if err := writer.WriteAudioFrame(frame); err != nil {
 			return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
 		}
 
+		if options.Progress != nil {
+			options.Progress(i+1, totalFrames)
+		}
+
 		// 显示进度
 		if i%100 == 0 {
 			progress := float64(i) / float64(totalFrames) * 100
@@ -253,6 +411,21 @@ func (afc *AudioFileClip) WriteToFile(filename string, options *core.WriteOption
 		}
 	}
 
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("关闭写入器失败: %w", err)
+	}
+
+	if options.Verify != nil {
+		expect := core.OutputExpectation{
+			Duration:     afc.Duration(),
+			AudioCodec:   options.AudioCodec,
+			AudioStreams: 1,
+		}
+		if err := options.Verify(filename, expect); err != nil {
+			return fmt.Errorf("校验输出失败: %w", err)
+		}
+	}
+
 	fmt.Printf("音频写入完成: %s\n", filename)
 	return nil
 }
@@ -264,10 +437,11 @@ func (afc *AudioFileClip) Close() error {
 	}
 
 	afc.closed = true
+	afc.leak.Close()
 
-	// 关闭读取器
+	// 释放读取器，最后一个引用释放时才真正关闭
 	if afc.reader != nil {
-		afc.reader.Close()
+		afc.reader.Release()
 		afc.reader = nil
 	}
 
@@ -0,0 +1,160 @@
+package audio
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// fakeProbe 1000 Hz 单声道、2 秒音频的 ffprobe 输出
+const fakeProbe = `{
+  "format": {"format_name": "wav", "duration": "2.000000"},
+  "streams": [{"index": 0, "codec_type": "audio", "codec_name": "pcm_f32le", "sample_rate": "1000", "channels": 1}]
+}`
+
+// fakeLevel 模拟 ffmpeg 输出的样本值
+const fakeLevel = 0.5
+
+// openFakeClip 用记录参数、输出恒定样本的脚本代替 ffprobe/ffmpeg 打开音频剪辑，返回剪辑和参数日志路径
+func openFakeClip(t *testing.T) (*AudioFileClip, string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "ffmpeg.log")
+	ffprobe := filepath.Join(dir, "ffprobe")
+	ffmpegPath := filepath.Join(dir, "ffmpeg")
+	input := filepath.Join(dir, "in.wav")
+
+	// 200 个小端 float32 的 0.5（0x3f000000），多于一帧，由读取器截断
+	scripts := map[string]string{
+		ffprobe:    "#!/bin/sh\ncat <<'JSON'\n" + fakeProbe + "\nJSON\n",
+		ffmpegPath: "#!/bin/sh\necho \"$@\" >> '" + log + "'\ni=0\nwhile [ $i -lt 200 ]; do printf '\\000\\000\\000\\077'; i=$((i+1)); done\n",
+		input:      "",
+	}
+	for path, content := range scripts {
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pm := ffmpeg.NewProcessManagerWithOptions(&ffmpeg.ProcessManagerOptions{FFmpegPath: ffmpegPath, FFprobePath: ffprobe})
+	t.Cleanup(func() { pm.Close() })
+	afc := NewAudioFileClip(input, pm)
+	if err := afc.Open(); err != nil {
+		t.Fatalf("打开音频失败: %v", err)
+	}
+	t.Cleanup(func() { afc.Close() })
+	return afc, log
+}
+
+// lastArgs 返回日志中最后一次 ffmpeg 调用的参数
+func lastArgs(t *testing.T, log string) string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return lines[len(lines)-1]
+}
+
+// frame 读取 t 处的音频帧并检查样本数
+func frame(t *testing.T, clip core.Clip, at time.Duration) []float64 {
+	t.Helper()
+	samples, err := clip.(core.AudioClip).GetAudioFrame(at)
+	if err != nil {
+		t.Fatalf("读取 %v 处的音频帧失败: %v", at, err)
+	}
+	// 每帧 0.1 秒，1000 Hz 单声道
+	if len(samples) != 100 {
+		t.Fatalf("音频帧样本数 %d，期望 100", len(samples))
+	}
+	return samples
+}
+
+func TestWithVolumeScalesAmplitude(t *testing.T) {
+	afc, _ := openFakeClip(t)
+
+	tests := []struct {
+		name    string
+		factors []float64
+		want    float64
+	}{
+		{"原始音量", nil, fakeLevel},
+		{"减半", []float64{0.5}, fakeLevel * 0.5},
+		{"静音", []float64{0}, 0},
+		{"增益累乘", []float64{0.5, 3}, fakeLevel * 1.5},
+	}
+	for _, tt := range tests {
+		var clip core.Clip = afc
+		for _, factor := range tt.factors {
+			next, err := clip.WithVolume(factor)
+			if err != nil {
+				t.Fatalf("%s: WithVolume(%g) 失败: %v", tt.name, factor, err)
+			}
+			defer next.Close()
+			clip = next
+		}
+		for i, v := range frame(t, clip, 500*time.Millisecond) {
+			if math.Abs(v-tt.want) > 1e-6 {
+				t.Fatalf("%s: 第 %d 个样本 %g，期望 %g", tt.name, i, v, tt.want)
+			}
+		}
+	}
+
+	if _, err := afc.WithVolume(-1); err != core.ErrInvalidVolumeFactor {
+		t.Errorf("负增益返回 %v，期望 ErrInvalidVolumeFactor", err)
+	}
+}
+
+func TestAudioFrameWindow(t *testing.T) {
+	afc, log := openFakeClip(t)
+
+	sub, err := afc.Subclip(500*time.Millisecond, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	fast, err := afc.WithSpeed(1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fast.Close()
+	slow, err := afc.WithSpeedOptions(0.25, &SpeedOptions{PreservePitch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+	fastSub, err := fast.Subclip(200*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fastSub.Close()
+
+	tests := []struct {
+		name string
+		clip core.Clip
+		at   time.Duration
+		want []string // 期望出现在 ffmpeg 参数中的片段
+	}{
+		{"原速", afc, 300 * time.Millisecond, []string{"-ss 0.300 ", "-t 0.1 "}},
+		{"子剪辑加上起始偏移", sub, 300 * time.Millisecond, []string{"-ss 0.800 ", "-t 0.1 "}},
+		{"加速读取更长的源音频", fast, 200 * time.Millisecond, []string{"-ss 0.300 ", "-t 0.15 ", "-af asetrate=1500,aresample=1000 "}},
+		{"保持音高的慢放", slow, 400 * time.Millisecond, []string{"-ss 0.100 ", "-t 0.025 ", "-af atempo=0.5,atempo=0.5 "}},
+		{"变速后的子剪辑", fastSub, 200 * time.Millisecond, []string{"-ss 0.600 ", "-t 0.15 "}},
+	}
+	for _, tt := range tests {
+		frame(t, tt.clip, tt.at)
+		args := lastArgs(t, log)
+		for _, want := range tt.want {
+			if !strings.Contains(args+" ", want) {
+				t.Errorf("%s: ffmpeg 参数 %q 缺少 %q", tt.name, args, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Package batch 对目录中的每个文件执行同一套编辑并导出，如统一加水印、转码
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+// Pipeline 对一个输入剪辑施加编辑，返回要写出的剪辑（可以就是 clip）
+//
+// 批处理负责关闭 clip 和返回的剪辑，Pipeline 内部另外打开的资源（如水印图片）由其自行管理。
+type Pipeline func(clip core.VideoClip) (core.Clip, error)
+
+// Options 批处理选项
+type Options struct {
+	Concurrency  int                // 同时处理的文件数，默认 1；每个文件还会启动自己的 FFmpeg 进程
+	Write        *core.WriteOptions // 每个文件的写入选项，Context 和 Progress 由批处理设置
+	SkipExisting bool               // 输出文件已存在时跳过
+	Context      context.Context    // 取消后不再开始新文件，并中止正在写入的文件
+	// Progress 每写入一帧或一个文件结束时回调，多个文件并发时从不同协程调用但不会重入
+	Progress   func(Progress)
+	ProcessMgr *ffmpeg.ProcessManager
+}
+
+// Progress 整批的进度
+type Progress struct {
+	Total     int     // 文件总数
+	Done      int     // 已结束（成功、失败或跳过）的文件数
+	Failed    int     // 失败的文件数
+	Fraction  float64 // 0–1，已结束的文件计 1，进行中的文件按已写入帧数计
+	Input     string  // 触发本次回调的文件
+	FileDone  bool    // Input 已结束
+	FileError error   // Input 失败的原因
+}
+
+// Result 单个文件的处理结果
+type Result struct {
+	Input    string
+	Output   string
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+}
+
+// Report 批处理汇总，Results 与匹配到的文件顺序一致
+type Report struct {
+	Results   []Result
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Elapsed   time.Duration
+}
+
+// Failures 返回失败的结果
+func (r *Report) Failures() []Result {
+	var failures []Result
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// Err 合并全部失败，没有失败时返回 nil
+func (r *Report) Err() error {
+	var errs []error
+	for _, result := range r.Failures() {
+		errs = append(errs, fmt.Errorf("%s: %w", result.Input, result.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// String 返回可打印的汇总，列出每个失败的文件
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "共 %d 个文件：成功 %d，失败 %d，跳过 %d，耗时 %v\n",
+		len(r.Results), r.Succeeded, r.Failed, r.Skipped, r.Elapsed.Round(time.Millisecond))
+	for _, result := range r.Failures() {
+		fmt.Fprintf(&b, "  失败 %s: %v\n", result.Input, result.Err)
+	}
+	return b.String()
+}
+
+// Process 对 glob 匹配的每个文件执行 pipeline 并按 outputPattern 写出
+//
+// outputPattern 中的 {name}（不含扩展名的文件名）、{ext}（不含点的扩展名）、{dir}（所在目录）
+// 和 {index}（从 0 开始的序号）会被替换，如 "out/{name}_wm.mp4"；输出目录不存在时自动创建。
+// 单个文件失败不影响其他文件，失败记录在 Report 中；只有参数错误时返回 error。
+func Process(glob string, pipeline Pipeline, outputPattern string, concurrency int) (*Report, error) {
+	return ProcessWithOptions(glob, pipeline, outputPattern, &Options{Concurrency: concurrency})
+}
+
+// ProcessWithOptions 使用自定义选项批处理
+func ProcessWithOptions(glob string, pipeline Pipeline, outputPattern string, options *Options) (*Report, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	inputs, err := filepath.Glob(glob)
+	if err != nil {
+		return nil, fmt.Errorf("无效的匹配模式 %s: %w", glob, err)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%s 没有匹配的文件", glob)
+	}
+	sort.Strings(inputs)
+	outputs, err := outputNames(inputs, outputPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	processMgr := options.ProcessMgr
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	concurrency := max(options.Concurrency, 1)
+
+	report := &Report{Results: make([]Result, len(inputs))}
+	tracker := newTracker(len(inputs), options.Progress)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	indices := make(chan int)
+	for w := 0; w < min(concurrency, len(inputs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				result := &report.Results[i]
+				*result = Result{Input: inputs[i], Output: outputs[i]}
+				begin := time.Now()
+				if options.SkipExisting && fileExists(outputs[i]) {
+					result.Skipped = true
+				} else {
+					result.Err = processFile(ctx, inputs[i], outputs[i], pipeline, options.Write, processMgr, func(current, total int) {
+						tracker.update(i, inputs[i], float64(current)/float64(max(total, 1)))
+					})
+				}
+				result.Duration = time.Since(begin)
+				tracker.finish(i, inputs[i], result.Err)
+			}
+		}()
+	}
+
+feed:
+	for i := range inputs {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	for i := range report.Results {
+		result := &report.Results[i]
+		switch {
+		case result.Input == "":
+			// 取消后未开始的文件
+			*result = Result{Input: inputs[i], Output: outputs[i], Err: ctx.Err()}
+			report.Failed++
+		case result.Err != nil:
+			report.Failed++
+		case result.Skipped:
+			report.Skipped++
+		default:
+			report.Succeeded++
+		}
+	}
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// processFile 打开 input，执行 pipeline 并写入 output
+func processFile(ctx context.Context, input, output string, pipeline Pipeline, write *core.WriteOptions, processMgr *ffmpeg.ProcessManager, progress func(current, total int)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+
+	clip := video.NewVideoFileClip(input, processMgr)
+	if err := clip.Open(); err != nil {
+		return err
+	}
+	defer clip.Close()
+
+	result, err := pipeline(clip)
+	if err != nil {
+		return fmt.Errorf("处理失败: %w", err)
+	}
+	if result != core.Clip(clip) {
+		defer result.Close()
+	}
+
+	options := core.WriteOptions{}
+	if write != nil {
+		options = *write
+	}
+	options.Context = core.WithJobID(ctx, input)
+	userProgress := options.Progress
+	options.Progress = func(current, total int) {
+		progress(current, total)
+		if userProgress != nil {
+			userProgress(current, total)
+		}
+	}
+	return result.WriteToFile(output, &options)
+}
+
+// outputNames 展开每个输入的输出路径，拒绝重名和覆盖输入
+func outputNames(inputs []string, pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("缺少输出模式")
+	}
+	outputs := make([]string, len(inputs))
+	seen := map[string]string{}
+	for i, input := range inputs {
+		ext := filepath.Ext(input)
+		output := strings.NewReplacer(
+			"{name}", strings.TrimSuffix(filepath.Base(input), ext),
+			"{ext}", strings.TrimPrefix(ext, "."),
+			"{dir}", filepath.Dir(input),
+			"{index}", strconv.Itoa(i),
+		).Replace(pattern)
+		output = filepath.Clean(output)
+
+		if filepath.Clean(input) == output {
+			return nil, fmt.Errorf("输出会覆盖输入文件 %s", input)
+		}
+		if previous, ok := seen[output]; ok {
+			return nil, fmt.Errorf("%s 与 %s 的输出文件相同: %s", previous, input, output)
+		}
+		seen[output] = input
+		outputs[i] = output
+	}
+	return outputs, nil
+}
+
+// fileExists 判断文件是否已存在
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// tracker 汇总各文件的进度
+type tracker struct {
+	mutex    sync.Mutex
+	callback func(Progress)
+	fraction []float64
+	progress Progress
+}
+
+// newTracker 创建进度汇总，callback 为 nil 时不记录
+func newTracker(total int, callback func(Progress)) *tracker {
+	return &tracker{
+		callback: callback,
+		fraction: make([]float64, total),
+		progress: Progress{Total: total},
+	}
+}
+
+// update 更新文件 i 的完成比例
+func (t *tracker) update(i int, input string, fraction float64) {
+	if t.callback == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.fraction[i] = fraction
+	t.report(Progress{Input: input})
+}
+
+// finish 记录文件 i 结束
+func (t *tracker) finish(i int, input string, err error) {
+	if t.callback == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.fraction[i] = 1
+	t.progress.Done++
+	if err != nil {
+		t.progress.Failed++
+	}
+	t.report(Progress{Input: input, FileDone: true, FileError: err})
+}
+
+// report 合并累计值后回调，调用方持有锁
+func (t *tracker) report(event Progress) {
+	sum := 0.0
+	for _, f := range t.fraction {
+		sum += f
+	}
+	event.Total, event.Done, event.Failed = t.progress.Total, t.progress.Done, t.progress.Failed
+	event.Fraction = sum / float64(t.progress.Total)
+	t.callback(event)
+}
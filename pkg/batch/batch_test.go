@@ -0,0 +1,66 @@
+package batch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOutputNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []string
+		pattern string
+		want    []string
+	}{
+		{"文件名和扩展名", []string{"in/a.mov", "in/b.mp4"}, "out/{name}.{ext}",
+			[]string{"out/a.mov", "out/b.mp4"}},
+		{"源目录", []string{"in/a.mov", "clips/b.mov"}, "{dir}/{name}_web.mp4",
+			[]string{"in/a_web.mp4", "clips/b_web.mp4"}},
+		{"序号", []string{"x/clip.mov", "y/clip.mov"}, "out/{index}-{name}.mp4",
+			[]string{"out/0-clip.mp4", "out/1-clip.mp4"}},
+		{"多个扩展名只去掉最后一个", []string{"a.tar.mov"}, "out/{name}.mp4", []string{"out/a.tar.mp4"}},
+		{"没有扩展名", []string{"in/raw"}, "out/{name}.{ext}.mp4", []string{"out/raw..mp4"}},
+		{"同一占位符多次出现", []string{"in/a.mov"}, "{dir}/{name}/{name}.mp4", []string{"in/a/a.mp4"}},
+		{"未知占位符原样保留", []string{"in/a.mov"}, "out/{name}-{date}.mp4", []string{"out/a-{date}.mp4"}},
+		{"清理路径", []string{"in/a.mov"}, "out//./{name}.mp4", []string{"out/a.mp4"}},
+		{"没有输入", nil, "out/{name}.mp4", []string{}},
+	}
+	for _, tt := range tests {
+		got, err := outputNames(tt.inputs, tt.pattern)
+		if err != nil {
+			t.Errorf("%s: 展开失败: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 输出为 %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOutputNamesRejectsConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []string
+		pattern string
+		want    string // 错误信息中应包含的内容
+	}{
+		{"缺少模式", []string{"a.mov"}, "", "缺少输出模式"},
+		{"固定文件名", []string{"a.mov", "b.mov"}, "out/result.mp4", "a.mov 与 b.mov 的输出文件相同: out/result.mp4"},
+		{"不同目录的同名文件", []string{"x/clip.mov", "y/clip.mov"}, "out/{name}.mp4", "x/clip.mov 与 y/clip.mov"},
+		{"只有扩展名不同", []string{"in/a.mov", "in/a.mp4"}, "{dir}/{name}.mkv", "in/a.mov 与 in/a.mp4"},
+		{"清理后相同", []string{"in/a.mov", "in/a.mp4"}, "out/./{name}.webm", "的输出文件相同: out/a.webm"},
+		{"覆盖输入", []string{"in/a.mov"}, "{dir}/{name}.{ext}", "输出会覆盖输入文件 in/a.mov"},
+		{"清理后覆盖输入", []string{"in/./a.mov"}, "in/{name}.mov", "输出会覆盖输入文件"},
+	}
+	for _, tt := range tests {
+		_, err := outputNames(tt.inputs, tt.pattern)
+		if err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: 错误 %q 应包含 %q", tt.name, err, tt.want)
+		}
+	}
+}
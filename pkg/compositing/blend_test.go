@@ -0,0 +1,43 @@
+package compositing
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/core/coretest"
+)
+
+// compositeRow 把 dst、src 各作为一行像素的图层，按 mode 合成后写回 dst
+func compositeRow(t *testing.T, mode CompositeMode, options *CompositeOptions) func(dst, src []byte) {
+	return func(dst, src []byte) {
+		layer := func(pix []byte) core.VideoClip {
+			width := len(pix) / 4
+			return coretest.NewMockVideoClip(width, 1, time.Second, 1, func(int, time.Duration) image.Image {
+				frame := image.NewRGBA(image.Rect(0, 0, width, 1))
+				copy(frame.Pix, pix)
+				return frame
+			})
+		}
+		cvc, err := NewCompositeVideoClipWithOptions([]core.VideoClip{layer(dst), layer(src)}, nil, mode, options, nil)
+		if err != nil {
+			t.Fatalf("创建合成剪辑失败: %v", err)
+		}
+		defer cvc.Close()
+		frame, err := cvc.GetFrame(0)
+		if err != nil {
+			t.Fatalf("合成失败: %v", err)
+		}
+		copy(dst, frame.(*image.RGBA).Pix)
+	}
+}
+
+func TestCompositeBlendModes(t *testing.T) {
+	for mode, blend := range blendModes {
+		t.Run(blend.String(), func(t *testing.T) {
+			coretest.CheckBlend(t, blend, compositeRow(t, mode, &CompositeOptions{Premultiplied: true, Strict: true}), 1)
+			coretest.CheckBlendOpaque(t, blend, compositeRow(t, mode, &CompositeOptions{Strict: true}), 1)
+		})
+	}
+}
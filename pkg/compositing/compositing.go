@@ -1,13 +1,24 @@
 package compositing
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
+	"math"
+	"strings"
 	"time"
 
 	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
 	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/leakcheck"
+	"moviepy-go/pkg/pixel"
+	"moviepy-go/pkg/preview"
+	"moviepy-go/pkg/video"
 )
 
 // CompositeMode 合成模式
@@ -20,16 +31,133 @@ const (
 	Screen
 	Darken
 	Lighten
+	// Normal 直接覆盖（按 alpha 混合），用于分屏和遮罩显示
+	Normal
 )
 
+// compositeModeNames 按 CompositeMode 取值排列的名称
+var compositeModeNames = []string{"overlay", "add", "multiply", "screen", "darken", "lighten", "normal"}
+
+// String 返回合成模式名称
+func (m CompositeMode) String() string {
+	if m >= 0 && int(m) < len(compositeModeNames) {
+		return compositeModeNames[m]
+	}
+	return fmt.Sprintf("CompositeMode(%d)", int(m))
+}
+
+// ParseCompositeMode 解析合成模式名称，如 "screen"、"multiply"
+func ParseCompositeMode(name string) (CompositeMode, error) {
+	for i, n := range compositeModeNames {
+		if n == name {
+			return CompositeMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的合成模式: %q（支持 %s）", name, strings.Join(compositeModeNames, "、"))
+}
+
+// Anchor 图层上与 (X, Y) 对齐的点
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// anchorNames 按 Anchor 取值排列的名称
+var anchorNames = []string{"top_left", "top", "top_right", "left", "center", "right", "bottom_left", "bottom", "bottom_right"}
+
+// String 返回锚点名称
+func (a Anchor) String() string {
+	if a >= 0 && int(a) < len(anchorNames) {
+		return anchorNames[a]
+	}
+	return fmt.Sprintf("Anchor(%d)", int(a))
+}
+
+// ParseAnchor 解析锚点名称，如 "top_left"、"center"、"bottom_right"
+func ParseAnchor(name string) (Anchor, error) {
+	for i, n := range anchorNames {
+		if n == name {
+			return Anchor(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的锚点: %q（支持 %s）", name, strings.Join(anchorNames, "、"))
+}
+
+// fraction 返回锚点在图层宽高上的比例，左上角为 (0, 0)，右下角为 (1, 1)
+func (a Anchor) fraction() (float64, float64) {
+	if a < 0 || int(a) >= len(anchorNames) {
+		return 0, 0
+	}
+	return float64(a%3) / 2, float64(a/3) / 2
+}
+
+// column 返回锚点所在的列，0 为左、1 为中、2 为右
+func (a Anchor) column() int {
+	x, _ := a.fraction()
+	return int(x * 2)
+}
+
 // Position 位置定义
+//
+// 图层上的 Anchor 点放在画布的 (X, Y) 处，再平移 OffsetX、OffsetY 像素。Relative 为 true 时
+// X、Y 是画布宽高的比例（0–1），布局不随分辨率变化；Center 为 true 时忽略 X、Y 和 Anchor，图层居中。
 type Position struct {
 	X, Y     float64
 	Relative bool
+	// Safe 大于 0 时相对坐标以居中、占画布宽高 Safe 的安全区为准（见 TitleSafe、ActionSafe）
+	Safe     float64
 	Center   bool
+	Anchor   Anchor
+	OffsetX  int
+	OffsetY  int
 	Scale    float64
 	Rotation float64
 	Opacity  float64
+	// Mode 本图层与下方画面的合成模式，nil 使用合成剪辑的模式（画布上的第一个图层为 Normal）
+	Mode *CompositeMode
+
+	// 动画参数，非 nil 时覆盖对应的静态值，t 为合成剪辑内的时间（可用 expr.Expr.Func 生成）
+	XAt       func(t time.Duration) float64
+	YAt       func(t time.Duration) float64
+	ScaleAt   func(t time.Duration) float64
+	OpacityAt func(t time.Duration) float64
+}
+
+// At 返回时间 t 处的静态位置
+func (p *Position) At(t time.Duration) *Position {
+	if p.XAt == nil && p.YAt == nil && p.ScaleAt == nil && p.OpacityAt == nil {
+		return p
+	}
+	resolved := *p
+	resolved.XAt, resolved.YAt, resolved.ScaleAt, resolved.OpacityAt = nil, nil, nil, nil
+	if p.XAt != nil {
+		resolved.X = p.XAt(t)
+	}
+	if p.YAt != nil {
+		resolved.Y = p.YAt(t)
+	}
+	if p.ScaleAt != nil {
+		resolved.Scale = math.Max(p.ScaleAt(t), 0)
+	}
+	if p.OpacityAt != nil {
+		resolved.Opacity = math.Max(0, math.Min(1, p.OpacityAt(t)))
+	}
+	return &resolved
+}
+
+// WithMode 设置本图层的合成模式并返回 p，如 NewPosition(0, 0).WithMode(Screen)
+func (p *Position) WithMode(mode CompositeMode) *Position {
+	p.Mode = &mode
+	return p
 }
 
 // NewPosition 创建新位置
@@ -45,6 +173,14 @@ func NewPosition(x, y float64) *Position {
 	}
 }
 
+// NewRelativePosition 创建按画布比例定位的位置，如 (1, 1, AnchorBottomRight) 贴住右下角
+func NewRelativePosition(x, y float64, anchor Anchor) *Position {
+	position := NewPosition(x, y)
+	position.Relative = true
+	position.Anchor = anchor
+	return position
+}
+
 // NewCenteredPosition 创建居中位置
 func NewCenteredPosition() *Position {
 	return &Position{
@@ -58,26 +194,104 @@ func NewCenteredPosition() *Position {
 	}
 }
 
+var (
+	// ErrNoLayers 没有传入任何图层
+	ErrNoLayers = errors.New("没有可合成的剪辑")
+	// ErrPositionCount 位置数量与图层数量不一致
+	ErrPositionCount = errors.New("位置数量与图层数量不一致")
+)
+
+// CompositeOptions 合成剪辑选项
+type CompositeOptions struct {
+	// Strict 图层取帧或变换失败时返回错误，默认跳过该图层继续合成
+	Strict bool
+
+	// Width、Height 画布尺寸，为 0 时取第一个图层的尺寸
+	Width, Height int
+	// BackgroundColor 画布底色，默认黑色
+	//
+	// 设置了画布尺寸或底色时第一个图层也按其位置（缩放、不透明度）以 Normal 模式绘制到画布上，
+	// 超出第一个图层的部分不会被裁掉；都未设置时第一个图层直接作为画布。
+	BackgroundColor color.Color
+
+	// Premultiplied 按预乘 alpha 的 source-over 公式（W3C Compositing and Blending）混合图层，
+	// 半透明的图层、不透明度小于 1 的图层和透明画布上的结果正确且没有色带；
+	// 默认使用早期版本的公式，底图按不透明处理，保持已有工程的输出不变
+	Premultiplied bool
+	// LinearLight 在线性光下按预乘公式混合图层（隐含 Premultiplied），Multiply、Screen 等模式和
+	// 半透明边缘不再因 sRGB 编码而偏暗；图层的取帧和特效不受影响，需要时在各自的 EffectClipOptions 中开启
+	LinearLight bool
+}
+
+// canvas 是否使用独立的画布，而不是直接以第一个图层为底
+func (o *CompositeOptions) canvas() bool {
+	return o.Width > 0 || o.Height > 0 || o.BackgroundColor != nil
+}
+
 // CompositeVideoClip 合成视频剪辑
 type CompositeVideoClip struct {
 	*core.BaseVideoClip
 	clips      []core.VideoClip
 	positions  []*Position
 	mode       CompositeMode
+	options    CompositeOptions
 	processMgr *ffmpeg.ProcessManager
 	closed     bool
+
+	// audio 由 WithAudio/WithoutAudio 指定或派生时变换得到的音轨，audioSet 为 false 时使用第一个剪辑的音轨
+	audio    core.AudioClip
+	audioSet bool
+
+	// owned 子剪辑由 Subclip、WithSpeed、派生剪辑的 WithAudio 等创建，Close 时一并关闭；
+	// ownsAudio audio 由派生时的变换创建，Close 时一并关闭
+	owned     bool
+	ownsAudio bool
+	leak      *leakcheck.Guard
+}
+
+// NewCompositeVideoClip 创建新的合成视频剪辑，尺寸和帧率取自第一个图层
+func NewCompositeVideoClip(clips []core.VideoClip, positions []*Position, mode CompositeMode, processMgr *ffmpeg.ProcessManager) (*CompositeVideoClip, error) {
+	return NewCompositeVideoClipWithOptions(clips, positions, mode, nil, processMgr)
 }
 
-// NewCompositeVideoClip 创建新的合成视频剪辑
-func NewCompositeVideoClip(clips []core.VideoClip, positions []*Position, mode CompositeMode, processMgr *ffmpeg.ProcessManager) *CompositeVideoClip {
+// NewCompositeVideoClipWithOptions 使用指定选项创建合成视频剪辑
+//
+// positions 与 clips 一一对应，为空或其中的元素为 nil 时该图层使用 NewPosition(0, 0)。
+func NewCompositeVideoClipWithOptions(clips []core.VideoClip, positions []*Position, mode CompositeMode, options *CompositeOptions, processMgr *ffmpeg.ProcessManager) (*CompositeVideoClip, error) {
 	if len(clips) == 0 {
-		return nil
+		return nil, ErrNoLayers
+	}
+	if len(positions) != 0 && len(positions) != len(clips) {
+		return nil, fmt.Errorf("%w: %d 个图层, %d 个位置", ErrPositionCount, len(clips), len(positions))
+	}
+	for i, clip := range clips {
+		if clip == nil {
+			return nil, fmt.Errorf("%w: 第 %d 个图层为 nil", core.ErrInvalidLayer, i)
+		}
+	}
+	if options == nil {
+		options = &CompositeOptions{}
+	}
+	if options.Width < 0 || options.Height < 0 {
+		return nil, fmt.Errorf("无效的画布尺寸: %dx%d", options.Width, options.Height)
 	}
 
-	baseClip := clips[0]
-	width := baseClip.Width()
-	height := baseClip.Height()
+	resolved := make([]*Position, len(clips))
+	for i := range resolved {
+		if i < len(positions) && positions[i] != nil {
+			resolved[i] = positions[i]
+		} else {
+			resolved[i] = NewPosition(0, 0)
+		}
+	}
+	return newCompositeVideoClip(clips, resolved, mode, *options, processMgr), nil
+}
 
+// newCompositeVideoClip 用已校验的图层和位置创建合成剪辑，时长为最长图层的时长
+func newCompositeVideoClip(clips []core.VideoClip, positions []*Position, mode CompositeMode, options CompositeOptions, processMgr *ffmpeg.ProcessManager) *CompositeVideoClip {
+	baseClip := clips[0]
+	width := cmp.Or(options.Width, baseClip.Width())
+	height := cmp.Or(options.Height, baseClip.Height())
 	maxDuration := baseClip.Duration()
 	for _, clip := range clips {
 		if clip.Duration() > maxDuration {
@@ -90,48 +304,82 @@ func NewCompositeVideoClip(clips []core.VideoClip, positions []*Position, mode C
 		clips:         clips,
 		positions:     positions,
 		mode:          mode,
+		options:       options,
 		processMgr:    processMgr,
 	}
 }
 
 // GetFrame 获取合成帧
 func (cvc *CompositeVideoClip) GetFrame(t time.Duration) (image.Image, error) {
-	if cvc.closed {
-		return nil, fmt.Errorf("剪辑已关闭")
-	}
+	return cvc.GetFrameContext(context.Background(), t)
+}
 
-	if len(cvc.clips) == 0 {
-		return nil, fmt.Errorf("没有可合成的剪辑")
+// GetFrameContext 合成时间 t 处的帧，追踪开启时记录合成区间，各图层的取帧作为其子区间
+func (cvc *CompositeVideoClip) GetFrameContext(ctx context.Context, t time.Duration) (frame image.Image, err error) {
+	if core.Tracing() {
+		var span core.Span
+		ctx, span = core.StartSpan(ctx, core.SpanComposite,
+			core.Attr("clip.id", core.ClipID(cvc)), core.Attr("layers", len(cvc.clips)))
+		defer func() { core.EndSpan(span, err) }()
 	}
-
-	baseFrame, err := cvc.clips[0].GetFrame(t)
-	if err != nil {
-		return nil, fmt.Errorf("获取基础帧失败: %w", err)
+	if cvc.closed {
+		return nil, fmt.Errorf("剪辑已关闭")
 	}
 
-	composite := image.NewRGBA(baseFrame.Bounds())
-	bounds := baseFrame.Bounds()
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			composite.Set(x, y, baseFrame.At(x, y))
+	var composite *image.RGBA
+	first := 0
+	if cvc.options.canvas() {
+		var background color.Color = color.Black
+		if cvc.options.BackgroundColor != nil {
+			background = cvc.options.BackgroundColor
 		}
+		composite = image.NewRGBA(image.Rect(0, 0, cvc.Width(), cvc.Height()))
+		draw.Draw(composite, composite.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	} else {
+		baseFrame, err := core.GetFrameContext(ctx, cvc.clips[0], t)
+		if err != nil {
+			return nil, fmt.Errorf("获取基础帧失败: %w", err)
+		}
+
+		// RGBA 基础帧由 draw.Draw 按行复制 Pix
+		composite = image.NewRGBA(baseFrame.Bounds())
+		draw.Draw(composite, composite.Bounds(), baseFrame, baseFrame.Bounds().Min, draw.Src)
+		first = 1
 	}
 
-	for i := 1; i < len(cvc.clips); i++ {
+	for i := first; i < len(cvc.clips); i++ {
 		clip := cvc.clips[i]
-		position := cvc.positions[i]
+		position := cvc.positions[i].At(t)
+
+		// 画布上的第一个图层是底图，未指定模式时与底色按 alpha 混合
+		mode := cvc.mode
+		switch {
+		case position.Mode != nil:
+			mode = *position.Mode
+		case i == 0:
+			mode = Normal
+		}
 
-		clipFrame, err := clip.GetFrame(t)
+		clipFrame, err := core.GetFrameContext(ctx, clip, t)
 		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("获取基础帧失败: %w", err)
+			}
+			if cvc.options.Strict {
+				return nil, fmt.Errorf("获取第 %d 个图层的帧失败: %w", i, err)
+			}
 			continue
 		}
 
 		transformedFrame, err := cvc.applyTransform(clipFrame, position)
 		if err != nil {
+			if cvc.options.Strict {
+				return nil, fmt.Errorf("变换第 %d 个图层失败: %w", i, err)
+			}
 			continue
 		}
 
-		cvc.compositeFrame(composite, transformedFrame, position, cvc.mode)
+		cvc.compositeFrame(composite, transformedFrame, position, mode)
 	}
 
 	return composite, nil
@@ -145,24 +393,28 @@ func (cvc *CompositeVideoClip) applyTransform(frame image.Image, position *Posit
 
 	targetWidth := int(float64(width) * position.Scale)
 	targetHeight := int(float64(height) * position.Scale)
+	if targetWidth == width && targetHeight == height && bounds.Min == (image.Point{}) {
+		return frame, nil
+	}
 
 	transformed := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
-
-	for y := 0; y < targetHeight; y++ {
-		for x := 0; x < targetWidth; x++ {
-			srcX := int(float64(x) * float64(width) / float64(targetWidth))
-			srcY := int(float64(y) * float64(height) / float64(targetHeight))
-
-			if srcX >= width {
-				srcX = width - 1
+	src := pixel.ToRGBA(frame)
+
+	// 最近邻缩放：预先计算每列的源横坐标，按行分段并行复制 4 字节像素
+	srcXs := make([]int, targetWidth)
+	for x := range srcXs {
+		srcXs[x] = min(int(float64(x)*float64(width)/float64(targetWidth)), width-1)
+	}
+	effects.Parallel(targetHeight, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			srcY := min(int(float64(y)*float64(height)/float64(targetHeight)), height-1)
+			row := src.PixOffset(bounds.Min.X, bounds.Min.Y+srcY)
+			out := transformed.Pix[y*transformed.Stride:]
+			for x, srcX := range srcXs {
+				copy(out[x*4:x*4+4], src.Pix[row+srcX*4:row+srcX*4+4])
 			}
-			if srcY >= height {
-				srcY = height - 1
-			}
-
-			transformed.Set(x, y, frame.At(srcX, srcY))
 		}
-	}
+	})
 
 	return transformed, nil
 }
@@ -174,6 +426,26 @@ func (cvc *CompositeVideoClip) compositeFrame(base, overlay image.Image, positio
 
 	offsetX, offsetY := cvc.calculateOffset(baseBounds, overlayBounds, position)
 
+	// 常见情况（RGBA 底图、不透明度为 1）按行调用 pixel.Blend
+	baseRGBA, ok := base.(*image.RGBA)
+	if ok && position.Opacity >= 1.0 {
+		cvc.compositeRows(baseRGBA, pixel.ToRGBA(overlay), offsetX, offsetY, mode)
+		return
+	}
+	// 预乘模式下不透明度直接缩放预乘的图层，不经过 16 位颜色的逐像素换算
+	if ok && (cvc.options.Premultiplied || cvc.options.LinearLight) {
+		layer := pixel.ToRGBA(overlay)
+		faded := image.NewRGBA(layer.Bounds())
+		effects.Parallel(layer.Bounds().Dy(), func(y0, y1 int) {
+			for y := layer.Rect.Min.Y + y0; y < layer.Rect.Min.Y+y1; y++ {
+				row := layer.Pix[layer.PixOffset(layer.Rect.Min.X, y):][:layer.Rect.Dx()*4]
+				pixel.Fade(faded.Pix[faded.PixOffset(layer.Rect.Min.X, y):][:len(row)], row, position.Opacity)
+			}
+		})
+		cvc.compositeRows(baseRGBA, faded, offsetX, offsetY, mode)
+		return
+	}
+
 	for y := overlayBounds.Min.Y; y < overlayBounds.Max.Y; y++ {
 		for x := overlayBounds.Min.X; x < overlayBounds.Max.X; x++ {
 			targetX := offsetX + x
@@ -197,36 +469,90 @@ func (cvc *CompositeVideoClip) compositeFrame(base, overlay image.Image, positio
 	}
 }
 
-// calculateOffset 计算偏移量
+// compositeRows 将 overlay 偏移 (offsetX, offsetY) 后与 base 重叠的部分逐行混合
+func (cvc *CompositeVideoClip) compositeRows(base, overlay *image.RGBA, offsetX, offsetY int, mode CompositeMode) {
+	target := overlay.Bounds().Add(image.Pt(offsetX, offsetY)).Intersect(base.Bounds())
+	if target.Empty() {
+		return
+	}
+	rowBytes := target.Dx() * 4
+	blend := blendModes[mode]
+	kernel := pixel.Blend
+	switch {
+	case cvc.options.LinearLight:
+		kernel = pixel.BlendLinear
+	case cvc.options.Premultiplied:
+		kernel = pixel.BlendPremultiplied
+	}
+	effects.Parallel(target.Dy(), func(y0, y1 int) {
+		for y := target.Min.Y + y0; y < target.Min.Y+y1; y++ {
+			dst := base.PixOffset(target.Min.X, y)
+			src := overlay.PixOffset(target.Min.X-offsetX, y-offsetY)
+			kernel(blend, base.Pix[dst:dst+rowBytes], overlay.Pix[src:src+rowBytes])
+		}
+	})
+}
+
+// blendModes 合成模式对应的像素混合模式
+var blendModes = map[CompositeMode]pixel.BlendMode{
+	Overlay:  pixel.BlendOverlay,
+	Add:      pixel.BlendAdd,
+	Multiply: pixel.BlendMultiply,
+	Screen:   pixel.BlendScreen,
+	Darken:   pixel.BlendDarken,
+	Lighten:  pixel.BlendLighten,
+	Normal:   pixel.BlendNormal,
+}
+
+// calculateOffset 计算叠加层左上角在底图上的偏移量
 func (cvc *CompositeVideoClip) calculateOffset(baseBounds, overlayBounds image.Rectangle, position *Position) (int, int) {
 	baseWidth := baseBounds.Dx()
 	baseHeight := baseBounds.Dy()
 	overlayWidth := overlayBounds.Dx()
 	overlayHeight := overlayBounds.Dy()
 
-	var offsetX, offsetY int
-
 	if position.Center {
-		offsetX = (baseWidth - overlayWidth) / 2
-		offsetY = (baseHeight - overlayHeight) / 2
-	} else {
-		offsetX = int(position.X)
-		offsetY = int(position.Y)
+		return (baseWidth-overlayWidth)/2 + position.OffsetX, (baseHeight-overlayHeight)/2 + position.OffsetY
 	}
 
-	return offsetX, offsetY
+	x, y := position.X, position.Y
+	if position.Relative {
+		area := image.Rect(0, 0, baseWidth, baseHeight)
+		if position.Safe > 0 {
+			area = SafeRect(baseWidth, baseHeight, position.Safe)
+		}
+		x = float64(area.Min.X) + x*float64(area.Dx())
+		y = float64(area.Min.Y) + y*float64(area.Dy())
+	}
+	anchorX, anchorY := position.Anchor.fraction()
+	x -= anchorX * float64(overlayWidth)
+	y -= anchorY * float64(overlayHeight)
+
+	return int(x) + position.OffsetX, int(y) + position.OffsetY
 }
 
-// applyOpacity 应用透明度
-func (cvc *CompositeVideoClip) applyOpacity(color color.Color, opacity float64) color.Color {
-	// 简化实现，直接返回原颜色
-	return color
+// applyOpacity 应用透明度，按预乘 alpha 缩放所有通道
+func (cvc *CompositeVideoClip) applyOpacity(c color.Color, opacity float64) color.Color {
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{
+		R: uint16(float64(r) * opacity),
+		G: uint16(float64(g) * opacity),
+		B: uint16(float64(b) * opacity),
+		A: uint16(float64(a) * opacity),
+	}
 }
 
-// blendColors 混合颜色
+// blendColors 混合颜色，叠加层的 alpha（来自遮罩或透明度）决定混合结果与底色的比例
 func (cvc *CompositeVideoClip) blendColors(base, overlay color.Color, mode CompositeMode) color.Color {
-	r1, g1, b1, _ := base.RGBA()
+	r1, g1, b1, a1 := base.RGBA()
 	r2, g2, b2, a2 := overlay.RGBA()
+	if a2 == 0 {
+		return base
+	}
+	if a2 < 65535 {
+		// 混合公式作用于非预乘颜色
+		r2, g2, b2 = r2*65535/a2, g2*65535/a2, b2*65535/a2
+	}
 
 	var r, g, b uint32
 
@@ -261,11 +587,17 @@ func (cvc *CompositeVideoClip) blendColors(base, overlay color.Color, mode Compo
 		b = b2
 	}
 
+	if a2 < 65535 {
+		r = (r*a2 + r1*(65535-a2)) / 65535
+		g = (g*a2 + g1*(65535-a2)) / 65535
+		b = (b*a2 + b1*(65535-a2)) / 65535
+	}
+
 	return color.RGBA64{
 		R: uint16(r),
 		G: uint16(g),
 		B: uint16(b),
-		A: uint16(a2),
+		A: uint16(max(a1, a2)),
 	}
 }
 
@@ -312,6 +644,13 @@ func (cvc *CompositeVideoClip) GetAudioFrame(t time.Duration) ([]float64, error)
 		return nil, fmt.Errorf("剪辑已关闭")
 	}
 
+	if cvc.audioSet {
+		if cvc.audio == nil {
+			return nil, fmt.Errorf("没有音频")
+		}
+		return cvc.audio.GetAudioFrame(t)
+	}
+
 	if len(cvc.clips) > 0 {
 		return cvc.clips[0].GetAudioFrame(t)
 	}
@@ -319,6 +658,150 @@ func (cvc *CompositeVideoClip) GetAudioFrame(t time.Duration) ([]float64, error)
 	return nil, fmt.Errorf("没有音频")
 }
 
+// Audio 返回合成剪辑的音轨，未指定时为第一个剪辑的音轨
+func (cvc *CompositeVideoClip) Audio() core.AudioClip {
+	if cvc.audioSet {
+		return cvc.audio
+	}
+	if len(cvc.clips) > 0 {
+		if src, ok := cvc.clips[0].(core.AudioSource); ok {
+			return src.Audio()
+		}
+	}
+	return nil
+}
+
+// derive 用新的子剪辑创建合成剪辑，音轨经 transform 做与图层相同的变换后保留
+//
+// 未指定音轨时变换第一个图层的音轨，而不是依赖派生出的图层自己跟随变换，保证导出时音画同步。
+// transform 返回 nil 表示变换后的区间内没有音频。第一个图层不提供音轨时仍向其取音频帧。
+func (cvc *CompositeVideoClip) derive(clips []core.VideoClip, transform func(core.AudioClip) (core.Clip, error)) (*CompositeVideoClip, error) {
+	derived := newCompositeVideoClip(clips, cvc.positions, cvc.mode, cvc.options, cvc.processMgr)
+	derived.owned = true
+	derived.ownsAudio = true
+	derived.leak = leakcheck.Track(derived, "CompositeVideoClip", fmt.Sprintf("%d 个图层", len(clips)))
+
+	source := cvc.Audio()
+	if !cvc.audioSet {
+		if _, ok := cvc.clips[0].(core.AudioSource); !ok {
+			return derived, nil
+		}
+	}
+	derived.audioSet = true
+	if source == nil {
+		return derived, nil
+	}
+	transformed, err := transform(source)
+	if err != nil {
+		derived.Close()
+		return nil, fmt.Errorf("变换音轨失败: %w", err)
+	}
+	if transformed == nil {
+		return derived, nil
+	}
+	audioClip, ok := transformed.(core.AudioClip)
+	if !ok {
+		transformed.Close()
+		derived.Close()
+		return nil, fmt.Errorf("变换后的音轨不是音频剪辑")
+	}
+	derived.audio = audioClip
+	return derived, nil
+}
+
+// shareLayers 返回只替换音轨的派生剪辑（WithAudio、WithoutAudio）使用的图层
+//
+// 调用者传入的图层直接共享；派生剪辑的图层归其自身所有、关闭时一并关闭，因此为结果创建各图层的副本，
+// 返回的 owned 为 true 时由新剪辑在 Close 时关闭这些副本。
+func (cvc *CompositeVideoClip) shareLayers() (layers []core.VideoClip, owned bool, err error) {
+	if !cvc.owned {
+		return cvc.clips, false, nil
+	}
+	layers = make([]core.VideoClip, len(cvc.clips))
+	for i, clip := range cvc.clips {
+		layer, err := clip.Subclip(0, clip.Duration())
+		if err != nil {
+			closeClips(layers[:i])
+			return nil, false, fmt.Errorf("复制图层失败: %w", err)
+		}
+		videoLayer, ok := layer.(core.VideoClip)
+		if !ok {
+			layer.Close()
+			closeClips(layers[:i])
+			return nil, false, fmt.Errorf("复制的图层不是视频剪辑")
+		}
+		layers[i] = videoLayer
+	}
+	return layers, true, nil
+}
+
+// replaceAudio 用 shareLayers 的图层创建待设置音轨的派生剪辑
+func (cvc *CompositeVideoClip) replaceAudio() (*CompositeVideoClip, error) {
+	layers, owned, err := cvc.shareLayers()
+	if err != nil {
+		return nil, err
+	}
+	derived := newCompositeVideoClip(layers, cvc.positions, cvc.mode, cvc.options, cvc.processMgr)
+	derived.audioSet = true
+	if owned {
+		derived.owned = true
+		derived.leak = leakcheck.Track(derived, "CompositeVideoClip", fmt.Sprintf("%d 个图层", len(layers)))
+	}
+	return derived, nil
+}
+
+// WithoutProxy 有图层包含代理时，返回各图层切换到原始分辨率后的合成剪辑，供渲染使用；否则返回 nil
+//
+// 未设置画布尺寸时画布随第一个图层变为原始分辨率，图层位置和音轨保持不变；
+// 不含代理的图层使用副本，结果关闭时只关闭这些派生图层，不影响原剪辑。
+func (cvc *CompositeVideoClip) WithoutProxy() (core.VideoClip, error) {
+	layers := make([]core.VideoClip, len(cvc.clips))
+	resolved := false
+	for i, clip := range cvc.clips {
+		full, err := video.FullResolution(clip)
+		if err != nil {
+			closeClips(layers[:i])
+			return nil, fmt.Errorf("第 %d 个图层切换原始分辨率失败: %w", i, err)
+		}
+		layers[i] = full
+		resolved = resolved || full != nil
+	}
+	if !resolved {
+		return nil, nil
+	}
+	for i, clip := range cvc.clips {
+		if layers[i] != nil {
+			continue
+		}
+		layer, err := clip.Subclip(0, clip.Duration())
+		if err == nil {
+			if videoLayer, ok := layer.(core.VideoClip); ok {
+				layers[i] = videoLayer
+				continue
+			}
+			layer.Close()
+			err = fmt.Errorf("复制的图层不是视频剪辑")
+		}
+		closeClips(layers)
+		return nil, fmt.Errorf("复制第 %d 个图层失败: %w", i, err)
+	}
+
+	derived := newCompositeVideoClip(layers, cvc.positions, cvc.mode, cvc.options, cvc.processMgr)
+	derived.audio, derived.audioSet = cvc.audio, cvc.audioSet
+	derived.owned = true
+	derived.leak = leakcheck.Track(derived, "CompositeVideoClip", fmt.Sprintf("%d 个图层", len(layers)))
+	return derived, nil
+}
+
+// closeClips 关闭派生到一半失败时已创建的子剪辑，跳过尚未创建的 nil
+func closeClips(clips []core.VideoClip) {
+	for _, clip := range clips {
+		if clip != nil {
+			clip.Close()
+		}
+	}
+}
+
 // Subclip 创建子剪辑
 func (cvc *CompositeVideoClip) Subclip(start, end time.Duration) (core.Clip, error) {
 	if start < 0 || end > cvc.Duration() || start >= end {
@@ -330,17 +813,37 @@ func (cvc *CompositeVideoClip) Subclip(start, end time.Duration) (core.Clip, err
 	for i, clip := range cvc.clips {
 		subclip, err := clip.Subclip(start, end)
 		if err != nil {
+			closeClips(subclips[:i])
 			return nil, fmt.Errorf("创建子剪辑失败: %w", err)
 		}
 
 		videoSubclip, ok := subclip.(core.VideoClip)
 		if !ok {
+			subclip.Close()
+			closeClips(subclips[:i])
 			return nil, fmt.Errorf("子剪辑不是视频剪辑")
 		}
 		subclips[i] = videoSubclip
 	}
 
-	return NewCompositeVideoClip(subclips, cvc.positions, cvc.mode, cvc.processMgr), nil
+	return cvc.derive(subclips, func(audio core.AudioClip) (core.Clip, error) {
+		// 音轨可能短于合成剪辑，截取时不超过其时长，完全在音轨之后的区间没有音频
+		audioEnd := min(end, audio.Duration())
+		if start >= audioEnd {
+			return nil, nil
+		}
+		return audio.Subclip(start, audioEnd)
+	})
+}
+
+// WithStart 设置开始时间，结束时间不变
+func (cvc *CompositeVideoClip) WithStart(start time.Duration) (core.Clip, error) {
+	return cvc.Subclip(start-cvc.Start(), cvc.Duration())
+}
+
+// WithEnd 设置结束时间，开始时间不变
+func (cvc *CompositeVideoClip) WithEnd(end time.Duration) (core.Clip, error) {
+	return cvc.Subclip(0, end-cvc.Start())
 }
 
 // WithSpeed 调整播放速度
@@ -354,17 +857,22 @@ func (cvc *CompositeVideoClip) WithSpeed(factor float64) (core.Clip, error) {
 	for i, clip := range cvc.clips {
 		speedClip, err := clip.WithSpeed(factor)
 		if err != nil {
+			closeClips(speedClips[:i])
 			return nil, fmt.Errorf("调整剪辑速度失败: %w", err)
 		}
 
 		videoSpeedClip, ok := speedClip.(core.VideoClip)
 		if !ok {
+			speedClip.Close()
+			closeClips(speedClips[:i])
 			return nil, fmt.Errorf("速度剪辑不是视频剪辑")
 		}
 		speedClips[i] = videoSpeedClip
 	}
 
-	return NewCompositeVideoClip(speedClips, cvc.positions, cvc.mode, cvc.processMgr), nil
+	return cvc.derive(speedClips, func(audio core.AudioClip) (core.Clip, error) {
+		return audio.WithSpeed(factor)
+	})
 }
 
 // WithVolume 调整音量
@@ -378,112 +886,90 @@ func (cvc *CompositeVideoClip) WithVolume(factor float64) (core.Clip, error) {
 	for i, clip := range cvc.clips {
 		volumeClip, err := clip.WithVolume(factor)
 		if err != nil {
+			closeClips(volumeClips[:i])
 			return nil, fmt.Errorf("调整剪辑音量失败: %w", err)
 		}
 
 		videoVolumeClip, ok := volumeClip.(core.VideoClip)
 		if !ok {
+			volumeClip.Close()
+			closeClips(volumeClips[:i])
 			return nil, fmt.Errorf("音量剪辑不是视频剪辑")
 		}
 		volumeClips[i] = videoVolumeClip
 	}
 
-	return NewCompositeVideoClip(volumeClips, cvc.positions, cvc.mode, cvc.processMgr), nil
+	return cvc.derive(volumeClips, func(audio core.AudioClip) (core.Clip, error) {
+		return audio.WithVolume(factor)
+	})
 }
 
-// WithAudio 添加音频
+// WithAudio 替换音轨，返回的新剪辑使用 audio 作为配乐
+//
+// audio 归调用者所有。cvc 为派生剪辑时新剪辑持有各图层的副本，两者可按任意顺序关闭。
 func (cvc *CompositeVideoClip) WithAudio(audio core.AudioClip) (core.Clip, error) {
-	// 简化实现，直接返回原剪辑
-	return cvc, nil
+	audioClip, err := cvc.replaceAudio()
+	if err != nil {
+		return nil, err
+	}
+	audioClip.audio = audio
+	return audioClip, nil
 }
 
-// WithoutAudio 移除音频
+// WithoutAudio 移除音轨，cvc 为派生剪辑时新剪辑持有各图层的副本
 func (cvc *CompositeVideoClip) WithoutAudio() (core.Clip, error) {
-	// 简化实现，直接返回原剪辑
-	return cvc, nil
+	return cvc.replaceAudio()
 }
 
 // WriteToFile 写入文件
 func (cvc *CompositeVideoClip) WriteToFile(filename string, options *core.WriteOptions) error {
+	return core.TraceRender(options, cvc, filename, func(ctx context.Context) error {
+		return cvc.writeToFile(ctx, filename, options)
+	})
+}
+
+// writeToFile 逐帧渲染并写入，ctx 携带渲染区间
+func (cvc *CompositeVideoClip) writeToFile(ctx context.Context, filename string, options *core.WriteOptions) error {
 	if cvc.closed {
 		return fmt.Errorf("剪辑已关闭")
 	}
 
-	if options == nil {
-		options = &core.WriteOptions{}
-	}
-	if options.Codec == "" {
-		options.Codec = "libx264"
-	}
-	if options.Bitrate == "" {
-		options.Bitrate = "2000k"
-	}
-	if options.FPS == 0 {
-		options.FPS = cvc.FPS()
-	}
-
-	writerOptions := &ffmpeg.VideoWriterOptions{
-		Codec:   options.Codec,
-		Bitrate: options.Bitrate,
-		FPS:     options.FPS,
-	}
-
-	writer := ffmpeg.NewVideoWriter(filename, cvc.Width(), cvc.Height(), writerOptions, cvc.processMgr)
-
-	if err := writer.Open(); err != nil {
-		return fmt.Errorf("打开写入器失败: %w", err)
-	}
-	defer writer.Close()
-
-	totalFrames := int(cvc.Duration().Seconds() * options.FPS)
-	frameInterval := time.Duration(float64(time.Second) / options.FPS)
-
-	fmt.Printf("开始写入合成视频: %s\n", filename)
-	fmt.Printf("剪辑数量: %d\n", len(cvc.clips))
-	fmt.Printf("合成模式: %d\n", cvc.mode)
-	fmt.Printf("总帧数: %d, 帧间隔: %v\n", totalFrames, frameInterval)
-
-	for i := 0; i < totalFrames; i++ {
-		t := time.Duration(i) * frameInterval
-		if t > cvc.Duration() {
-			break
-		}
-
-		frame, err := cvc.GetFrame(t)
-		if err != nil {
-			return fmt.Errorf("获取第 %d 帧失败: %w", i, err)
-		}
-
-		if err := writer.WriteFrame(frame); err != nil {
-			return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
-		}
-
-		if i%100 == 0 {
-			progress := float64(i) / float64(totalFrames) * 100
-			fmt.Printf("进度: %.1f%% (%d/%d)\n", progress, i, totalFrames)
-		}
-	}
-
-	fmt.Printf("合成视频写入完成: %s\n", filename)
-	return nil
+	return video.Render(ctx, cvc, filename, options, video.RenderSpec{
+		Label:      "合成视频",
+		Bitrate:    "2000k",
+		ProcessMgr: cvc.processMgr,
+		Describe: func() {
+			fmt.Printf("剪辑数量: %d\n", len(cvc.clips))
+			fmt.Printf("合成模式: %v\n", cvc.mode)
+		},
+	})
 }
 
 // Close 关闭剪辑
+//
+// NewCompositeVideoClip 传入的图层和 WithAudio 传入的音轨归调用者所有，不在这里关闭；
+// Subclip、WithSpeed 等派生的剪辑（以及派生剪辑的 WithAudio/WithoutAudio）自己创建了各图层的派生版本，Close 时一并关闭。
 func (cvc *CompositeVideoClip) Close() error {
 	if cvc.closed {
 		return nil
 	}
 	cvc.closed = true
+	cvc.leak.Close()
 
-	// 不关闭子剪辑，让调用者管理剪辑的生命周期
-	// 这样可以避免多个合成操作之间的剪辑关闭冲突
-	// for _, clip := range cvc.clips {
-	// 	if closer, ok := clip.(interface{ Close() error }); ok {
-	// 		closer.Close()
-	// 	}
-	// }
-
-	return nil
+	var errs []error
+	if cvc.owned {
+		for _, clip := range cvc.clips {
+			if err := clip.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if cvc.ownsAudio && cvc.audio != nil {
+		if err := cvc.audio.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // GetClips 获取所有剪辑
@@ -496,7 +982,27 @@ func (cvc *CompositeVideoClip) GetPositions() []*Position {
 	return cvc.positions
 }
 
-// GetMode 获取合成模式
+// GetMode 获取合成模式，即未设置 Position.Mode 的图层使用的模式
 func (cvc *CompositeVideoClip) GetMode() CompositeMode {
 	return cvc.mode
 }
+
+// SaveFrame 将 t 处的帧保存为图片，格式按扩展名（.png/.jpg/.webp）或 options.Format 决定
+func (cvc *CompositeVideoClip) SaveFrame(t time.Duration, path string, options *ffmpeg.ImageOptions) error {
+	frame, err := cvc.GetFrame(t)
+	if err != nil {
+		return fmt.Errorf("获取帧失败: %w", err)
+	}
+	if err := ffmpeg.SaveImage(context.Background(), frame, path, options, cvc.processMgr); err != nil {
+		return fmt.Errorf("保存帧失败: %w", err)
+	}
+	return nil
+}
+
+// Preview 使用 ffplay 预览合成结果
+func (cvc *CompositeVideoClip) Preview(options *preview.PlayOptions) error {
+	if cvc.closed {
+		return fmt.Errorf("剪辑已关闭")
+	}
+	return preview.Play(cvc, options)
+}
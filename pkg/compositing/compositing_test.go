@@ -0,0 +1,160 @@
+package compositing
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/core/coretest"
+)
+
+func TestDerivedWithAudioOwnsLayerCopies(t *testing.T) {
+	base := coretest.NewSolidClip(color.RGBA{R: 200, A: 255}, 8, 8, 2*time.Second, 10)
+	top := coretest.NewSolidClip(color.RGBA{G: 200, A: 255}, 4, 4, 2*time.Second, 10)
+	cvc, err := NewCompositeVideoClipWithOptions([]core.VideoClip{base, top}, nil, Normal, &CompositeOptions{Strict: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cvc.Close()
+
+	sub, err := cvc.Subclip(500*time.Millisecond, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	music := coretest.NewSineClip(440, 0.5, time.Second, 2, 8000)
+	withMusic, err := sub.(*CompositeVideoClip).WithAudio(music)
+	if err != nil {
+		t.Fatal(err)
+	}
+	silent, err := sub.(*CompositeVideoClip).WithoutAudio()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 关闭派生剪辑后，由它替换音轨得到的剪辑仍可取帧
+	if err := sub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, clip := range []core.Clip{withMusic, silent} {
+		if _, err := clip.(core.VideoClip).GetFrame(200 * time.Millisecond); err != nil {
+			t.Errorf("关闭派生剪辑后取帧失败: %v", err)
+		}
+	}
+	if _, err := withMusic.(*CompositeVideoClip).GetAudioFrame(0); err != nil {
+		t.Errorf("读取替换的音轨失败: %v", err)
+	}
+
+	// WithAudio 传入的音轨归调用者所有
+	withMusic.Close()
+	silent.Close()
+	if music.Closed() {
+		t.Errorf("关闭剪辑时关闭了调用者的音轨")
+	}
+	if base.Closed() || top.Closed() {
+		t.Errorf("关闭派生剪辑时关闭了调用者的图层")
+	}
+	if _, err := cvc.GetFrame(0); err != nil {
+		t.Errorf("原合成剪辑取帧失败: %v", err)
+	}
+}
+
+func TestWithAudioSharesCallerLayers(t *testing.T) {
+	base := coretest.NewSolidClip(color.RGBA{B: 200, A: 255}, 8, 8, time.Second, 10)
+	cvc, err := NewCompositeVideoClip([]core.VideoClip{base}, nil, Normal, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withAudio, err := cvc.WithAudio(coretest.NewSilentClip(time.Second, 1, 8000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := withAudio.(*CompositeVideoClip).GetClips()[0]; got != core.VideoClip(base) {
+		t.Errorf("调用者传入的图层被复制")
+	}
+	withAudio.Close()
+	cvc.Close()
+	if base.Closed() {
+		t.Errorf("关闭剪辑时关闭了调用者的图层")
+	}
+}
+
+// proxyLayer 模拟代理剪辑，WithoutProxy 返回原始分辨率的 full
+type proxyLayer struct {
+	*coretest.MockVideoClip
+	full *coretest.MockVideoClip
+}
+
+func (pl *proxyLayer) WithoutProxy() (core.VideoClip, error) {
+	return pl.full, nil
+}
+
+func TestWithoutProxyResolvesLayers(t *testing.T) {
+	proxy := &proxyLayer{
+		MockVideoClip: coretest.NewSolidClip(color.RGBA{R: 200, A: 255}, 4, 2, time.Second, 10),
+		full:          coretest.NewSolidClip(color.RGBA{R: 200, A: 255}, 8, 4, time.Second, 10),
+	}
+	top := coretest.NewSolidClip(color.RGBA{G: 200, A: 255}, 2, 2, time.Second, 10)
+	cvc, err := NewCompositeVideoClip([]core.VideoClip{proxy, top}, nil, Normal, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cvc.Close()
+
+	full, err := cvc.WithoutProxy()
+	if err != nil {
+		t.Fatalf("切换原始分辨率失败: %v", err)
+	}
+	if full == nil {
+		t.Fatal("包含代理图层时应返回派生剪辑")
+	}
+	if full.Width() != 8 || full.Height() != 4 {
+		t.Fatalf("画布 %dx%d，期望随第一个图层变为 8x4", full.Width(), full.Height())
+	}
+	if _, err := full.GetFrame(0); err != nil {
+		t.Fatalf("取帧失败: %v", err)
+	}
+
+	// 关闭派生剪辑只关闭其派生图层
+	full.Close()
+	if !proxy.full.Closed() {
+		t.Error("关闭派生剪辑时未关闭原始分辨率图层")
+	}
+	if proxy.Closed() || top.Closed() {
+		t.Error("关闭派生剪辑时关闭了调用者的图层")
+	}
+	if cvc.Width() != 4 {
+		t.Errorf("原合成剪辑宽度 %d，期望保持 4", cvc.Width())
+	}
+
+	// 不含代理时返回 nil
+	plain, err := NewCompositeVideoClip([]core.VideoClip{top}, nil, Normal, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if resolved, err := plain.WithoutProxy(); err != nil || resolved != nil {
+		t.Fatalf("不含代理时返回 %v, %v，期望 nil", resolved, err)
+	}
+}
+
+func TestNewCompositeRejectsInvalidLayers(t *testing.T) {
+	clip := coretest.NewSolidClip(color.RGBA{A: 255}, 4, 4, time.Second, 10)
+	tests := []struct {
+		name      string
+		clips     []core.VideoClip
+		positions []*Position
+		want      error
+	}{
+		{"没有图层", nil, nil, ErrNoLayers},
+		{"位置数量不一致", []core.VideoClip{clip, clip}, []*Position{NewPosition(0, 0)}, ErrPositionCount},
+		{"nil 图层", []core.VideoClip{clip, nil}, nil, core.ErrInvalidLayer},
+	}
+	for _, tt := range tests {
+		_, err := NewCompositeVideoClipWithOptions(tt.clips, tt.positions, Normal, nil, nil)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: 错误为 %v，期望 %v", tt.name, err, tt.want)
+		}
+	}
+}
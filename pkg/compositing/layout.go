@@ -0,0 +1,105 @@
+package compositing
+
+import (
+	"image"
+	"math"
+	"time"
+
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+const (
+	// ActionSafe 动作安全区占画面宽高的比例（SMPTE ST 2046-1），重要画面内容应位于其中
+	ActionSafe = 0.93
+	// TitleSafe 字幕安全区占画面宽高的比例，文字、台标应位于其中
+	TitleSafe = 0.90
+)
+
+// SafeRect 返回 width×height 画面中居中、宽高各占 fraction 的安全区
+func SafeRect(width, height int, fraction float64) image.Rectangle {
+	marginX := int(math.Round(float64(width) * (1 - fraction) / 2))
+	marginY := int(math.Round(float64(height) * (1 - fraction) / 2))
+	return image.Rect(marginX, marginY, width-marginX, height-marginY)
+}
+
+// ActionSafeRect 返回动作安全区
+func ActionSafeRect(width, height int) image.Rectangle {
+	return SafeRect(width, height, ActionSafe)
+}
+
+// TitleSafeRect 返回字幕安全区
+func TitleSafeRect(width, height int) image.Rectangle {
+	return SafeRect(width, height, TitleSafe)
+}
+
+// PositionSafe 把图层的 anchor 点对齐到安全区的同名点，如 AnchorBottomRight 贴住安全区右下角
+func PositionSafe(anchor Anchor, fraction float64) *Position {
+	x, y := anchor.fraction()
+	position := NewRelativePosition(x, y, anchor)
+	position.Safe = fraction
+	return position
+}
+
+// PositionTitleSafe 在字幕安全区内按 anchor 对齐，适合文字和台标水印
+func PositionTitleSafe(anchor Anchor) *Position {
+	return PositionSafe(anchor, TitleSafe)
+}
+
+// PositionActionSafe 在动作安全区内按 anchor 对齐
+func PositionActionSafe(anchor Anchor) *Position {
+	return PositionSafe(anchor, ActionSafe)
+}
+
+// PositionBottomThird 下三分之一字幕条的位置：图层水平居中，中心位于字幕安全区下三分之一的中心
+func PositionBottomThird() *Position {
+	position := NewRelativePosition(0.5, 5.0/6, AnchorCenter)
+	position.Safe = TitleSafe
+	return position
+}
+
+// NewSafeAreaGuides 创建标出动作安全区、字幕安全区和三分线的白色参考线图层，叠加在最上层预览布局
+func NewSafeAreaGuides(width, height int, duration time.Duration, fps float64, processMgr *ffmpeg.ProcessManager) *video.EffectVideoClip {
+	lines := video.NewMaskClip(width, height, duration, fps, true, func(time.Duration) (*image.Gray, error) {
+		return safeAreaGuides(width, height), nil
+	})
+	// 与分割线相同，遮罩同时作为颜色与 alpha
+	guides := video.NewEffectVideoClip(lines, processMgr)
+	guides.AddEffect(effects.NewMaskEffect(lines))
+	return guides
+}
+
+// safeAreaGuides 两个安全区的边框为实线，三分线为半透明
+func safeAreaGuides(width, height int) *image.Gray {
+	mask := image.NewGray(image.Rect(0, 0, width, height))
+	hline := func(y, x0, x1 int, v uint8) {
+		if y < 0 || y >= height {
+			return
+		}
+		row := mask.Pix[y*mask.Stride:]
+		for x := max(x0, 0); x < min(x1, width); x++ {
+			row[x] = max(row[x], v)
+		}
+	}
+	vline := func(x, y0, y1 int, v uint8) {
+		if x < 0 || x >= width {
+			return
+		}
+		for y := max(y0, 0); y < min(y1, height); y++ {
+			mask.Pix[y*mask.Stride+x] = max(mask.Pix[y*mask.Stride+x], v)
+		}
+	}
+
+	for i := 1; i <= 2; i++ {
+		vline(width*i/3, 0, height, 96)
+		hline(height*i/3, 0, width, 96)
+	}
+	for _, r := range []image.Rectangle{ActionSafeRect(width, height), TitleSafeRect(width, height)} {
+		hline(r.Min.Y, r.Min.X, r.Max.X, 255)
+		hline(r.Max.Y-1, r.Min.X, r.Max.X, 255)
+		vline(r.Min.X, r.Min.Y, r.Max.Y, 255)
+		vline(r.Max.X-1, r.Min.Y, r.Max.Y, 255)
+	}
+	return mask
+}
@@ -0,0 +1,338 @@
+package compositing
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+// LowerThirdAnimation 下三分之一字幕条的入场和出场方式
+type LowerThirdAnimation int
+
+const (
+	// LowerThirdSlide 从所在一侧的画面外滑入，出场时滑回（居中的字幕条从下方滑入）
+	LowerThirdSlide LowerThirdAnimation = iota
+	// LowerThirdFade 淡入淡出
+	LowerThirdFade
+	// LowerThirdWipe 从对齐的一侧向另一侧展开，出场时收回
+	LowerThirdWipe
+	// LowerThirdNone 整段时间静态显示
+	LowerThirdNone
+)
+
+// lowerThirdAnimationNames 按 LowerThirdAnimation 取值排列的名称
+var lowerThirdAnimationNames = []string{"slide", "fade", "wipe", "none"}
+
+// String 返回动画名称
+func (a LowerThirdAnimation) String() string {
+	if a >= 0 && int(a) < len(lowerThirdAnimationNames) {
+		return lowerThirdAnimationNames[a]
+	}
+	return fmt.Sprintf("LowerThirdAnimation(%d)", int(a))
+}
+
+// ParseLowerThirdAnimation 解析动画名称
+func ParseLowerThirdAnimation(name string) (LowerThirdAnimation, error) {
+	for i, n := range lowerThirdAnimationNames {
+		if n == name {
+			return LowerThirdAnimation(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的字幕条动画: %q（支持 %s）", name, strings.Join(lowerThirdAnimationNames, "、"))
+}
+
+// LowerThirdOptions 下三分之一字幕条选项
+type LowerThirdOptions struct {
+	Subtitle string // 第二行文字，如职位，为空时只显示姓名
+
+	// 文字样式，颜色为 drawtext 颜色；姓名使用粗体
+	FontFile      string
+	Fonts         []string
+	NameSize      int    // 姓名字号，默认画面高度的 1/18
+	SubtitleSize  int    // 第二行字号，默认姓名字号的 0.6 倍
+	NameColor     string // 默认 white
+	SubtitleColor string // 默认 #DDDDDD
+
+	// BarColor 底条颜色，默认半透明黑色
+	BarColor color.Color
+	// AccentColor 底条靠对齐一侧的强调色竖条，默认蓝色，color.Transparent 表示不画
+	AccentColor color.Color
+	// Padding 文字与底条边缘的距离，默认姓名字号的一半
+	Padding int
+
+	// Align 水平对齐，只取锚点所在的列（如 AnchorLeft、AnchorBottom、AnchorRight），默认靠左；
+	// 字幕条总是贴住字幕安全区的底边
+	Align Anchor
+
+	Animation LowerThirdAnimation
+	// Delay 开始入场的时间，Length 从入场到出场结束的显示时长，0 表示显示到剪辑结尾
+	Delay, Length time.Duration
+	In, Out       time.Duration // 入场、出场时长，默认各 500ms，不超过显示时长的一半
+	Easing        video.Easing  // 默认 video.EaseOutCubic
+}
+
+// LowerThirdClip 画面尺寸的透明图层，在字幕安全区内显示带底条的姓名和说明文字
+//
+// 以 NewPosition(0, 0) 作为图层放入 CompositeVideoClip，入场与出场时间按原剪辑的时间计算。
+type LowerThirdClip struct {
+	*core.BaseVideoClip
+	panel   *image.RGBA     // 底条和文字，只渲染一次
+	box     image.Rectangle // 完全显示时底条在画面中的位置
+	options LowerThirdOptions
+	offset  time.Duration // Subclip 后相对原剪辑起点的偏移
+	full    *image.RGBA   // 完全显示时的画面
+	empty   *image.RGBA   // 完全隐藏时的画面
+}
+
+// NewLowerThird 渲染 name 和 options.Subtitle，创建 width×height、时长为 duration 的字幕条图层
+func NewLowerThird(name string, width, height int, options *LowerThirdOptions, duration time.Duration, fps float64, processMgr *ffmpeg.ProcessManager) (*LowerThirdClip, error) {
+	if name == "" {
+		return nil, fmt.Errorf("字幕条姓名为空")
+	}
+	if options == nil {
+		options = &LowerThirdOptions{}
+	}
+	o := *options
+	if o.NameSize <= 0 {
+		o.NameSize = max(height/18, 8)
+	}
+	if o.SubtitleSize <= 0 {
+		o.SubtitleSize = max(o.NameSize*3/5, 6)
+	}
+	if o.NameColor == "" {
+		o.NameColor = "white"
+	}
+	if o.SubtitleColor == "" {
+		o.SubtitleColor = "#DDDDDD"
+	}
+	if o.BarColor == nil {
+		o.BarColor = color.NRGBA{A: 180}
+	}
+	if o.AccentColor == nil {
+		o.AccentColor = color.NRGBA{R: 0x1E, G: 0x88, B: 0xE5, A: 255}
+	}
+	if o.Padding <= 0 {
+		o.Padding = o.NameSize / 2
+	}
+	if o.In <= 0 {
+		o.In = 500 * time.Millisecond
+	}
+	if o.Out <= 0 {
+		o.Out = 500 * time.Millisecond
+	}
+	if o.Length <= 0 || o.Delay+o.Length > duration {
+		o.Length = max(duration-o.Delay, 0)
+	}
+	o.In, o.Out = min(o.In, o.Length/2), min(o.Out, o.Length/2)
+	if o.Easing == nil {
+		o.Easing = video.EaseOutCubic
+	}
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	nameImage, err := renderTextLine(name, width, o.NameSize, o.NameColor, true, o.FontFile, o.Fonts, processMgr)
+	if err != nil {
+		return nil, fmt.Errorf("渲染字幕条姓名失败: %w", err)
+	}
+	var subtitleImage *image.RGBA
+	if o.Subtitle != "" {
+		if subtitleImage, err = renderTextLine(o.Subtitle, width, o.SubtitleSize, o.SubtitleColor, false, o.FontFile, o.Fonts, processMgr); err != nil {
+			return nil, fmt.Errorf("渲染字幕条说明失败: %w", err)
+		}
+	}
+
+	panel := lowerThirdPanel(nameImage, subtitleImage, &o)
+	safe := TitleSafeRect(width, height)
+	x := safe.Min.X + o.Align.column()*(safe.Dx()-panel.Bounds().Dx())/2
+	box := panel.Bounds().Add(image.Pt(x, safe.Max.Y-panel.Bounds().Dy()))
+
+	full := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(full, box, panel, image.Point{}, draw.Src)
+	return newLowerThirdClip(panel, box, o, full, image.NewRGBA(full.Bounds()), 0, duration, fps), nil
+}
+
+// newLowerThirdClip 由已渲染的底条创建字幕条图层
+func newLowerThirdClip(panel *image.RGBA, box image.Rectangle, options LowerThirdOptions, full, empty *image.RGBA, offset, duration time.Duration, fps float64) *LowerThirdClip {
+	return &LowerThirdClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, duration, duration, fps, full.Bounds().Dx(), full.Bounds().Dy()),
+		panel:         panel,
+		box:           box,
+		options:       options,
+		offset:        offset,
+		full:          full,
+		empty:         empty,
+	}
+}
+
+// renderTextLine 渲染一行文字并裁到字形范围
+func renderTextLine(text string, width, size int, textColor string, bold bool, fontFile string, fonts []string, processMgr *ffmpeg.ProcessManager) (*image.RGBA, error) {
+	clip, err := video.NewTextClip(text, width, size*2, &video.TextClipOptions{
+		FontFile: fontFile,
+		Fonts:    fonts,
+		FontSize: size,
+		Color:    textColor,
+		Bold:     bold,
+	}, time.Second, 1, processMgr)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := clip.GetFrame(0)
+	if err != nil {
+		return nil, err
+	}
+	rgba := frame.(*image.RGBA)
+	ink := alphaBounds(rgba)
+	if ink.Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 1, size)), nil
+	}
+	return rgba.SubImage(ink).(*image.RGBA), nil
+}
+
+// lowerThirdPanel 绘制底条：强调色竖条在对齐的一侧，姓名在上、说明在下
+func lowerThirdPanel(name, subtitle *image.RGBA, options *LowerThirdOptions) *image.RGBA {
+	pad := options.Padding
+	accent := 0
+	if _, _, _, a := options.AccentColor.RGBA(); a > 0 {
+		accent = max(options.NameSize/6, 2)
+	}
+
+	textWidth, textHeight := name.Bounds().Dx(), name.Bounds().Dy()
+	gap := options.NameSize / 4
+	if subtitle != nil {
+		textWidth = max(textWidth, subtitle.Bounds().Dx())
+		textHeight += gap + subtitle.Bounds().Dy()
+	}
+	panel := image.NewRGBA(image.Rect(0, 0, accent+2*pad+textWidth, 2*pad+textHeight))
+	draw.Draw(panel, panel.Bounds(), image.NewUniform(options.BarColor), image.Point{}, draw.Src)
+
+	// 右对齐的字幕条把竖条放在右侧，文字也靠右
+	right := options.Align.column() == 2
+	textLeft := accent + pad
+	accentRect := image.Rect(0, 0, accent, panel.Bounds().Dy())
+	if right {
+		textLeft = pad
+		accentRect = accentRect.Add(image.Pt(panel.Bounds().Dx()-accent, 0))
+	}
+	draw.Draw(panel, accentRect, image.NewUniform(options.AccentColor), image.Point{}, draw.Src)
+
+	place := func(line *image.RGBA, y int) {
+		x := textLeft
+		if right {
+			x = textLeft + textWidth - line.Bounds().Dx()
+		}
+		dst := line.Bounds().Sub(line.Bounds().Min).Add(image.Pt(x, y))
+		draw.Draw(panel, dst, line, line.Bounds().Min, draw.Over)
+	}
+	place(name, pad)
+	if subtitle != nil {
+		place(subtitle, pad+name.Bounds().Dy()+gap)
+	}
+	return panel
+}
+
+// alphaBounds 返回不透明像素的包围矩形
+func alphaBounds(img *image.RGBA) image.Rectangle {
+	var ink image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			if row[x*4+3] != 0 {
+				ink = ink.Union(image.Rect(b.Min.X+x, y, b.Min.X+x+1, y+1))
+			}
+		}
+	}
+	return ink
+}
+
+// progress 返回时间 t 处字幕条的显示程度，0 为完全隐藏、1 为完全显示
+func (lc *LowerThirdClip) progress(t time.Duration) float64 {
+	t += lc.offset - lc.options.Delay
+	if t < 0 || t >= lc.options.Length {
+		return 0
+	}
+	if lc.options.Animation == LowerThirdNone {
+		return 1
+	}
+	in, out := 1.0, 1.0
+	if lc.options.In > 0 {
+		in = math.Min(1, float64(t)/float64(lc.options.In))
+	}
+	if lc.options.Out > 0 {
+		out = math.Min(1, float64(lc.options.Length-t)/float64(lc.options.Out))
+	}
+	linear := math.Min(in, out)
+	if linear <= 0 || linear >= 1 {
+		return linear
+	}
+	return lc.options.Easing(linear)
+}
+
+// GetFrame 返回时间 t 处的画面，完全显示和完全隐藏时返回共享的画面，调用方不应修改
+func (lc *LowerThirdClip) GetFrame(t time.Duration) (image.Image, error) {
+	p := lc.progress(t)
+	switch {
+	case p >= 1:
+		return lc.full, nil
+	case p <= 0:
+		return lc.empty, nil
+	}
+
+	frame := image.NewRGBA(lc.full.Bounds())
+	switch lc.options.Animation {
+	case LowerThirdFade:
+		alpha := image.NewUniform(color.Alpha{A: uint8(math.Round(p * 255))})
+		draw.DrawMask(frame, lc.box, lc.panel, image.Point{}, alpha, image.Point{}, draw.Over)
+	case LowerThirdWipe:
+		visible := int(math.Round(p * float64(lc.box.Dx())))
+		dst := image.Rect(lc.box.Min.X, lc.box.Min.Y, lc.box.Min.X+visible, lc.box.Max.Y)
+		src := image.Point{}
+		switch lc.options.Align.column() {
+		case 1:
+			dst = dst.Add(image.Pt((lc.box.Dx()-visible)/2, 0))
+			src.X = (lc.box.Dx() - visible) / 2
+		case 2:
+			dst = dst.Add(image.Pt(lc.box.Dx()-visible, 0))
+			src.X = lc.box.Dx() - visible
+		}
+		draw.Draw(frame, dst, lc.panel, src, draw.Src)
+	default:
+		// 滑到画面之外：左侧的向左、右侧的向右、居中的向下
+		var shift image.Point
+		switch lc.options.Align.column() {
+		case 0:
+			shift.X = -lc.box.Max.X
+		case 2:
+			shift.X = lc.full.Bounds().Dx() - lc.box.Min.X
+		default:
+			shift.Y = lc.full.Bounds().Dy() - lc.box.Min.Y
+		}
+		d := 1 - p
+		dst := lc.box.Add(image.Pt(int(math.Round(float64(shift.X)*d)), int(math.Round(float64(shift.Y)*d))))
+		draw.Draw(frame, dst, lc.panel, image.Point{}, draw.Src)
+	}
+	return frame, nil
+}
+
+// Subclip 截取时间段，动画进度按原剪辑的时间计算
+func (lc *LowerThirdClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > lc.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	return newLowerThirdClip(lc.panel, lc.box, lc.options, lc.full, lc.empty, lc.offset+start, end-start, lc.FPS()), nil
+}
+
+// Close 字幕条不持有资源
+func (lc *LowerThirdClip) Close() error {
+	return nil
+}
@@ -0,0 +1,471 @@
+package compositing
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+// OverlayEvent 叠加轨道上的一个事件，在 Time 处出现，显示 Duration 后消失
+type OverlayEvent struct {
+	Time     time.Duration
+	Duration time.Duration // 显示时长，0 表示使用 OverlayTrackOptions.Duration
+	Text     string
+	Icon     string // 显示在文字左侧的 PNG 或 JPEG 图标，可为空
+	Color    string // 文字颜色，drawtext 颜色，为空时使用 OverlayTrackOptions.Color
+
+	// Positioned 为 true 时条目的 Anchor 点放在 (X, Y) 处，X、Y 为画面宽高的比例（0–1）；
+	// 否则条目进入消息流
+	Positioned bool
+	X, Y       float64
+	Anchor     Anchor
+}
+
+// ReadOverlayEvents 读取事件文件，.json 为对象数组，.csv 以首行为字段名
+//
+// 字段为 t（出现时间，秒）、duration（秒）、text、icon、color、x、y 和 anchor，给出 x 或 y 的
+// 事件按位置显示。相对路径的图标相对于事件文件所在目录。返回的事件按时间排序。
+func ReadOverlayEvents(filename string) ([]OverlayEvent, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取事件文件失败: %w", err)
+	}
+	var records []map[string]string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		records, err = parseOverlayCSV(data)
+	case ".json":
+		records, err = parseOverlayJSON(data)
+	default:
+		return nil, fmt.Errorf("不支持的事件文件格式: %s", filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]OverlayEvent, 0, len(records))
+	for i, record := range records {
+		event, err := parseOverlayRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个事件: %w", i+1, err)
+		}
+		if event.Icon != "" && !filepath.IsAbs(event.Icon) {
+			event.Icon = filepath.Join(filepath.Dir(filename), event.Icon)
+		}
+		events = append(events, event)
+	}
+	slices.SortStableFunc(events, func(a, b OverlayEvent) int { return cmp.Compare(a.Time, b.Time) })
+	return events, nil
+}
+
+// parseOverlayCSV 解析带表头的 CSV
+func parseOverlayCSV(data []byte) ([]map[string]string, error) {
+	rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析 CSV 失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]string, len(rows[0]))
+		for i, name := range rows[0] {
+			if value := strings.TrimSpace(row[i]); value != "" {
+				record[strings.TrimSpace(name)] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseOverlayJSON 解析对象数组，数字保持其 JSON 文本
+func parseOverlayJSON(data []byte) ([]map[string]string, error) {
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("解析 JSON 事件失败: %w", err)
+	}
+	records := make([]map[string]string, len(objects))
+	for i, object := range objects {
+		records[i] = make(map[string]string, len(object))
+		for name, raw := range object {
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				s = string(raw)
+			}
+			records[i][name] = s
+		}
+	}
+	return records, nil
+}
+
+// parseOverlayRecord 把一条记录转换为事件
+func parseOverlayRecord(record map[string]string) (OverlayEvent, error) {
+	event := OverlayEvent{Text: record["text"], Icon: record["icon"], Color: record["color"]}
+	if event.Text == "" && event.Icon == "" {
+		return event, fmt.Errorf("缺少 text 或 icon")
+	}
+	seconds := func(name string) (time.Duration, error) {
+		value, ok := record[name]
+		if !ok {
+			return 0, nil
+		}
+		s, err := strconv.ParseFloat(value, 64)
+		if err != nil || s < 0 {
+			return 0, fmt.Errorf("无效的 %s: %q", name, value)
+		}
+		return time.Duration(s * float64(time.Second)), nil
+	}
+	var err error
+	if _, ok := record["t"]; !ok {
+		return event, fmt.Errorf("缺少出现时间 t")
+	}
+	if event.Time, err = seconds("t"); err != nil {
+		return event, err
+	}
+	if event.Duration, err = seconds("duration"); err != nil {
+		return event, err
+	}
+
+	for _, c := range []struct {
+		name string
+		dst  *float64
+	}{{"x", &event.X}, {"y", &event.Y}} {
+		if value, ok := record[c.name]; ok {
+			if *c.dst, err = strconv.ParseFloat(value, 64); err != nil {
+				return event, fmt.Errorf("无效的 %s: %q", c.name, value)
+			}
+			event.Positioned = true
+		}
+	}
+	if anchor, ok := record["anchor"]; ok {
+		if event.Anchor, err = ParseAnchor(anchor); err != nil {
+			return event, err
+		}
+	}
+	return event, nil
+}
+
+// OverlayTrackOptions 叠加轨道选项
+type OverlayTrackOptions struct {
+	Duration time.Duration // 事件默认的显示时长，默认 4 秒
+	In, Out  time.Duration // 入场、出场时长，默认 200ms 和 300ms，不超过显示时长的一半
+	Easing   video.Easing  // 默认 video.EaseOutCubic
+
+	// Feed 消息流所在的字幕安全区角落，默认左上角，击杀提示通常用 AnchorTopRight；
+	// 最新的条目贴住角落，较早的条目依次让开
+	Feed Anchor
+	// MaxFeed 消息流同时显示的最多条目，默认 5，超出时最早的条目提前出场
+	MaxFeed int
+	Gap     int // 消息流条目的间距，默认字号的 1/3
+
+	// 文字样式，颜色为 drawtext 颜色
+	FontFile string
+	Fonts    []string
+	FontSize int    // 默认画面高度的 1/30
+	Color    string // 默认 white
+	Bold     bool
+	// Background 条目底色，默认半透明黑色，color.Transparent 表示不画
+	Background color.Color
+	Padding    int // 文字与底色边缘的距离，默认字号的 1/3
+	IconSize   int // 图标高度，默认字号的 1.2 倍
+}
+
+// OverlayTrackClip 画面尺寸的透明图层，按事件时间显示短暂的文字和图标条目，如游戏击杀提示和比分播报
+//
+// 未指定位置的事件进入角落的消息流，新条目滑入时较早的条目平滑让位；指定了位置的事件在原地淡入淡出。
+// 以 NewPosition(0, 0) 作为图层放入 CompositeVideoClip，事件时间按原剪辑的时间计算。
+type OverlayTrackClip struct {
+	*core.BaseVideoClip
+	entries []*overlayEntry // 按出现时间排序
+	options OverlayTrackOptions
+	offset  time.Duration // Subclip 后相对原剪辑起点的偏移
+	empty   *image.RGBA
+}
+
+// overlayEntry 一个已渲染的事件条目
+type overlayEntry struct {
+	event      OverlayEvent
+	panel      *image.RGBA
+	start, end time.Duration // 显示区间，消息流条目的 end 已考虑被挤出的时间
+	in, out    time.Duration
+}
+
+// NewOverlayTrack 渲染 events 的条目，创建 width×height、时长为 duration 的叠加轨道
+func NewOverlayTrack(events []OverlayEvent, width, height int, options *OverlayTrackOptions, duration time.Duration, fps float64, processMgr *ffmpeg.ProcessManager) (*OverlayTrackClip, error) {
+	if options == nil {
+		options = &OverlayTrackOptions{}
+	}
+	o := *options
+	if o.Duration <= 0 {
+		o.Duration = 4 * time.Second
+	}
+	if o.In <= 0 {
+		o.In = 200 * time.Millisecond
+	}
+	if o.Out <= 0 {
+		o.Out = 300 * time.Millisecond
+	}
+	if o.Easing == nil {
+		o.Easing = video.EaseOutCubic
+	}
+	if o.MaxFeed <= 0 {
+		o.MaxFeed = 5
+	}
+	if o.FontSize <= 0 {
+		o.FontSize = max(height/30, 8)
+	}
+	if o.Gap <= 0 {
+		o.Gap = max(o.FontSize/3, 2)
+	}
+	if o.Color == "" {
+		o.Color = "white"
+	}
+	if o.Background == nil {
+		o.Background = color.NRGBA{A: 160}
+	}
+	if o.Padding <= 0 {
+		o.Padding = max(o.FontSize/3, 2)
+	}
+	if o.IconSize <= 0 {
+		o.IconSize = o.FontSize * 6 / 5
+	}
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	sorted := slices.Clone(events)
+	slices.SortStableFunc(sorted, func(a, b OverlayEvent) int { return cmp.Compare(a.Time, b.Time) })
+	var entries, feed []*overlayEntry
+	for i, event := range sorted {
+		panel, err := renderOverlayPanel(event, width, &o, processMgr)
+		if err != nil {
+			return nil, fmt.Errorf("渲染第 %d 个事件失败: %w", i+1, err)
+		}
+		length := event.Duration
+		if length <= 0 {
+			length = o.Duration
+		}
+		entry := &overlayEntry{event: event, panel: panel, start: event.Time, end: event.Time + length}
+		entries = append(entries, entry)
+		if !event.Positioned {
+			feed = append(feed, entry)
+		}
+	}
+	// 第 MaxFeed 个更新的条目出现时开始出场
+	for i, entry := range feed {
+		if j := i + o.MaxFeed; j < len(feed) && feed[j].start+o.Out < entry.end {
+			entry.end = feed[j].start + o.Out
+		}
+	}
+	for _, entry := range entries {
+		length := entry.end - entry.start
+		entry.in, entry.out = min(o.In, length/2), min(o.Out, length/2)
+	}
+
+	return newOverlayTrackClip(entries, o, image.NewRGBA(image.Rect(0, 0, width, height)), 0, duration, fps), nil
+}
+
+// newOverlayTrackClip 由已渲染的条目创建叠加轨道
+func newOverlayTrackClip(entries []*overlayEntry, options OverlayTrackOptions, empty *image.RGBA, offset, duration time.Duration, fps float64) *OverlayTrackClip {
+	return &OverlayTrackClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, duration, duration, fps, empty.Bounds().Dx(), empty.Bounds().Dy()),
+		entries:       entries,
+		options:       options,
+		offset:        offset,
+		empty:         empty,
+	}
+}
+
+// renderOverlayPanel 绘制条目：底色上左侧为图标、右侧为文字
+func renderOverlayPanel(event OverlayEvent, width int, options *OverlayTrackOptions, processMgr *ffmpeg.ProcessManager) (*image.RGBA, error) {
+	var icon, text image.Image
+	if event.Icon != "" {
+		var err error
+		if icon, err = loadOverlayIcon(event.Icon, options.IconSize); err != nil {
+			return nil, err
+		}
+	}
+	if event.Text != "" {
+		textColor := event.Color
+		if textColor == "" {
+			textColor = options.Color
+		}
+		var err error
+		if text, err = renderTextLine(event.Text, width, options.FontSize, textColor, options.Bold, options.FontFile, options.Fonts, processMgr); err != nil {
+			return nil, err
+		}
+	}
+
+	pad := options.Padding
+	contentWidth, contentHeight := 0, 0
+	for _, part := range []image.Image{icon, text} {
+		if part == nil {
+			continue
+		}
+		if contentWidth > 0 {
+			contentWidth += pad
+		}
+		contentWidth += part.Bounds().Dx()
+		contentHeight = max(contentHeight, part.Bounds().Dy())
+	}
+	panel := image.NewRGBA(image.Rect(0, 0, contentWidth+2*pad, contentHeight+2*pad))
+	draw.Draw(panel, panel.Bounds(), image.NewUniform(options.Background), image.Point{}, draw.Src)
+	x := pad
+	for _, part := range []image.Image{icon, text} {
+		if part == nil {
+			continue
+		}
+		b := part.Bounds()
+		dst := b.Sub(b.Min).Add(image.Pt(x, pad+(contentHeight-b.Dy())/2))
+		draw.Draw(panel, dst, part, b.Min, draw.Over)
+		x += b.Dx() + pad
+	}
+	return panel, nil
+}
+
+// loadOverlayIcon 读取图标并按高度等比缩放
+func loadOverlayIcon(path string, size int) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开图标失败: %w", err)
+	}
+	defer f.Close()
+	icon, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("解码图标 %s 失败: %w", path, err)
+	}
+	b := icon.Bounds()
+	if b.Dy() == size || b.Empty() {
+		return icon, nil
+	}
+	return effects.NewResizeEffect(max(b.Dx()*size/b.Dy(), 1), size).ApplyToFrame(icon)
+}
+
+// progress 返回条目在时间 t 处的显示程度，0 为完全隐藏、1 为完全显示
+func (oc *OverlayTrackClip) progress(entry *overlayEntry, t time.Duration) float64 {
+	if t < entry.start || t >= entry.end {
+		return 0
+	}
+	in, out := 1.0, 1.0
+	if entry.in > 0 {
+		in = math.Min(1, float64(t-entry.start)/float64(entry.in))
+	}
+	if entry.out > 0 {
+		out = math.Min(1, float64(entry.end-t)/float64(entry.out))
+	}
+	linear := math.Min(in, out)
+	if linear <= 0 || linear >= 1 {
+		return linear
+	}
+	return oc.options.Easing(linear)
+}
+
+// GetFrame 返回时间 t 处的画面，没有条目显示时返回共享的空白画面，调用方不应修改
+func (oc *OverlayTrackClip) GetFrame(t time.Duration) (image.Image, error) {
+	t += oc.offset
+	var frame *image.RGBA
+	canvas := func() *image.RGBA {
+		if frame == nil {
+			frame = image.NewRGBA(oc.empty.Bounds())
+		}
+		return frame
+	}
+
+	width, height := oc.Width(), oc.Height()
+	safe := TitleSafeRect(width, height)
+	_, row := oc.options.Feed.fraction()
+	column := oc.options.Feed.column()
+	// 消息流从最新的条目开始向远离角落的方向排列，每个条目按显示程度占用空间，使其他条目平滑让位
+	var stacked float64
+	for i := len(oc.entries) - 1; i >= 0; i-- {
+		entry := oc.entries[i]
+		if entry.event.Positioned {
+			continue
+		}
+		p := oc.progress(entry, t)
+		if p <= 0 {
+			continue
+		}
+		b := entry.panel.Bounds()
+		x := float64(safe.Min.X + column*(safe.Dx()-b.Dx())/2)
+		y := float64(safe.Min.Y) + stacked
+		if row == 1 {
+			y = float64(safe.Max.Y-b.Dy()) - stacked
+		}
+		// 左右两侧的条目从所在一侧滑入，居中的原地淡入
+		slide := (1 - p) * float64(b.Dx()) / 3
+		switch column {
+		case 0:
+			x -= slide
+		case 2:
+			x += slide
+		}
+		drawOverlayEntry(canvas(), entry.panel, image.Pt(int(math.Round(x)), int(math.Round(y))), p)
+		stacked += float64(b.Dy()+oc.options.Gap) * p
+	}
+
+	for _, entry := range oc.entries {
+		if !entry.event.Positioned {
+			continue
+		}
+		p := oc.progress(entry, t)
+		if p <= 0 {
+			continue
+		}
+		b := entry.panel.Bounds()
+		ax, ay := entry.event.Anchor.fraction()
+		x := entry.event.X*float64(width) - ax*float64(b.Dx())
+		// 入场时从略低处上浮
+		y := entry.event.Y*float64(height) - ay*float64(b.Dy()) + (1-p)*float64(b.Dy())/2
+		drawOverlayEntry(canvas(), entry.panel, image.Pt(int(math.Round(x)), int(math.Round(y))), p)
+	}
+
+	if frame == nil {
+		return oc.empty, nil
+	}
+	return frame, nil
+}
+
+// drawOverlayEntry 按不透明度 alpha 把条目画在 at 处
+func drawOverlayEntry(frame, panel *image.RGBA, at image.Point, alpha float64) {
+	dst := panel.Bounds().Add(at)
+	if alpha >= 1 {
+		draw.Draw(frame, dst, panel, image.Point{}, draw.Over)
+		return
+	}
+	mask := image.NewUniform(color.Alpha{A: uint8(math.Round(alpha * 255))})
+	draw.DrawMask(frame, dst, panel, image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// Subclip 截取时间段，事件时间按原剪辑的时间计算
+func (oc *OverlayTrackClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > oc.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	return newOverlayTrackClip(oc.entries, oc.options, oc.empty, oc.offset+start, end-start, oc.FPS()), nil
+}
+
+// Close 叠加轨道不持有资源
+func (oc *OverlayTrackClip) Close() error {
+	return nil
+}
@@ -0,0 +1,165 @@
+package compositing
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+// Orientation 分屏方向
+type Orientation int
+
+const (
+	// SideBySide 左右分屏，a 在左、b 在右，分割线竖直
+	SideBySide Orientation = iota
+	// TopBottom 上下分屏，a 在上、b 在下，分割线水平
+	TopBottom
+)
+
+// SplitOptions 分屏选项
+type SplitOptions struct {
+	Orientation Orientation
+	// Divider 分割线位置，相对宽度（或高度）的 0–1，默认 0.5
+	Divider float64
+	// DividerAt 非 nil 时分割线随时间移动（对比擦除），覆盖 Divider
+	DividerAt func(t time.Duration) float64
+	// DividerWidth 分割线宽度（像素），0 表示不画线
+	DividerWidth int
+	// Feather 两侧过渡的羽化宽度（像素），0 为硬边
+	Feather int
+}
+
+// SplitScreen 创建静态分屏：dividerPos 之前显示 a，之后显示 b
+func SplitScreen(a, b core.VideoClip, orientation Orientation, dividerPos float64, processMgr *ffmpeg.ProcessManager) (*CompositeVideoClip, error) {
+	return NewSplitScreen(a, b, &SplitOptions{Orientation: orientation, Divider: dividerPos}, processMgr)
+}
+
+// ComparisonWipe 创建分割线随时间移动的对比擦除，常用于调色前后对比
+//
+// dividerAt 返回相对位置 0–1，如 expr.MustParse("t/5").Func() 在 5 秒内从 a 擦到 b。
+func ComparisonWipe(a, b core.VideoClip, orientation Orientation, dividerAt func(t time.Duration) float64, processMgr *ffmpeg.ProcessManager) (*CompositeVideoClip, error) {
+	if dividerAt == nil {
+		return nil, fmt.Errorf("对比擦除需要分割线位置函数")
+	}
+	return NewSplitScreen(a, b, &SplitOptions{Orientation: orientation, DividerAt: dividerAt, DividerWidth: 2}, processMgr)
+}
+
+// NewSplitScreen 使用指定选项创建分屏，a 与 b 的尺寸必须相同
+//
+// b 通过遮罩叠加在 a 之上，分割线（可选）为白色。返回的合成剪辑不关闭 a 和 b。
+func NewSplitScreen(a, b core.VideoClip, options *SplitOptions, processMgr *ffmpeg.ProcessManager) (*CompositeVideoClip, error) {
+	if options == nil {
+		options = &SplitOptions{}
+	}
+	if a.Width() != b.Width() || a.Height() != b.Height() {
+		return nil, fmt.Errorf("分屏的两个剪辑尺寸不同: %dx%d 与 %dx%d", a.Width(), a.Height(), b.Width(), b.Height())
+	}
+
+	divider := options.DividerAt
+	if divider == nil {
+		pos := options.Divider
+		if pos == 0 {
+			pos = 0.5
+		}
+		divider = func(time.Duration) float64 { return pos }
+	}
+
+	width, height := a.Width(), a.Height()
+	duration := a.Duration()
+	if b.Duration() > duration {
+		duration = b.Duration()
+	}
+	static := options.DividerAt == nil
+
+	// b 只显示分割线之后的部分
+	side := video.NewMaskClip(width, height, duration, a.FPS(), static, func(t time.Duration) (*image.Gray, error) {
+		return splitMask(width, height, options.Orientation, divider(t), float64(options.Feather)), nil
+	})
+	masked := video.NewEffectVideoClip(b, processMgr)
+	masked.AddEffect(effects.NewMaskEffect(side))
+
+	clips := []core.VideoClip{a, masked}
+	positions := []*Position{NewPosition(0, 0), NewPosition(0, 0)}
+
+	if options.DividerWidth > 0 {
+		line := video.NewMaskClip(width, height, duration, a.FPS(), static, func(t time.Duration) (*image.Gray, error) {
+			return dividerLine(width, height, options.Orientation, divider(t), options.DividerWidth), nil
+		})
+		// 线条遮罩同时作为颜色与 alpha：白线之外完全透明
+		lineLayer := video.NewEffectVideoClip(line, processMgr)
+		lineLayer.AddEffect(effects.NewMaskEffect(line))
+		clips = append(clips, lineLayer)
+		positions = append(positions, NewPosition(0, 0))
+	}
+
+	return NewCompositeVideoClip(clips, positions, Normal, processMgr)
+}
+
+// splitMask 分割线之前为黑（显示 a）、之后为白（显示 b）
+func splitMask(width, height int, orientation Orientation, divider, feather float64) *image.Gray {
+	mask := image.NewGray(image.Rect(0, 0, width, height))
+	length := width
+	if orientation == TopBottom {
+		length = height
+	}
+	edge := divider * float64(length)
+
+	// 沿分割方向每个位置的灰度相同，先算一行再复制
+	levels := make([]uint8, length)
+	for i := range levels {
+		d := float64(i) + 0.5 - edge
+		var v float64
+		switch {
+		case feather <= 0:
+			if d >= 0 {
+				v = 1
+			}
+		default:
+			v = math.Max(0, math.Min(1, d/feather+0.5))
+		}
+		levels[i] = uint8(math.Round(v * 255))
+	}
+
+	for y := 0; y < height; y++ {
+		row := mask.Pix[y*mask.Stride : y*mask.Stride+width]
+		if orientation == TopBottom {
+			for x := range row {
+				row[x] = levels[y]
+			}
+		} else {
+			copy(row, levels)
+		}
+	}
+	return mask
+}
+
+// dividerLine 以分割线为中心、宽 lineWidth 的白线
+func dividerLine(width, height int, orientation Orientation, divider float64, lineWidth int) *image.Gray {
+	mask := image.NewGray(image.Rect(0, 0, width, height))
+	length := width
+	if orientation == TopBottom {
+		length = height
+	}
+	start := int(math.Round(divider*float64(length))) - lineWidth/2
+	from, to := max(start, 0), min(start+lineWidth, length)
+
+	for i := from; i < to; i++ {
+		if orientation == TopBottom {
+			row := mask.Pix[i*mask.Stride : i*mask.Stride+width]
+			for x := range row {
+				row[x] = 255
+			}
+		} else {
+			for y := 0; y < height; y++ {
+				mask.Pix[y*mask.Stride+i] = 255
+			}
+		}
+	}
+	return mask
+}
@@ -0,0 +1,331 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// EnvConfigFile 指定配置文件路径的环境变量
+const EnvConfigFile = "MOVIEGO_CONFIG"
+
+// Config 部署级默认配置，覆盖代码中的内置默认值
+//
+// 配置文件为扁平的 YAML（每行一个 key: value，# 开头为注释），例如：
+//
+//	codec: libx265
+//	crf: 28
+//	threads: 4
+//	ffmpeg_path: /opt/ffmpeg/bin/ffmpeg
+//	temp_dir: /var/tmp/moviego
+//	temp_quota: 20G
+//	process_timeout: 10m
+//	log_level: warning
+//	backend: cpu
+//
+// 每个键都可以用环境变量覆盖，如 MOVIEGO_CODEC、MOVIEGO_CRF、MOVIEGO_FFMPEG_PATH。
+type Config struct {
+	Codec        string // 视频编码器
+	Bitrate      string // 视频码率
+	CRF          int    // 恒定质量因子
+	Threads      int    // 编码线程数
+	AudioCodec   string // 音频编码器
+	AudioBitrate string // 音频码率
+	FFmpegPath   string // ffmpeg 可执行文件路径
+	FFprobePath  string // ffprobe 可执行文件路径
+	TempDir      string // 中间文件目录
+	TempQuota    int64  // 每个进程管理器的中间文件总大小上限（字节）
+	LogLevel     string // FFmpeg 日志级别
+	MaxProcesses int    // 最大并发进程数
+	Backend      string // 像素特效后端，如 cpu 或已注册的 GPU 后端（以 opengl 标签构建时的 opengl）
+
+	ProcessTimeout time.Duration // 单个 FFmpeg/ffprobe 进程的最长运行时间，0 表示不限
+}
+
+// field 配置项：文件中的键名与对应的 Config 字段
+type field struct {
+	key string
+	set func(c *Config, value string) error
+}
+
+// fields 所有配置项，环境变量名为 MOVIEGO_ 加大写键名
+var fields = []field{
+	{"codec", stringField(func(c *Config) *string { return &c.Codec })},
+	{"bitrate", stringField(func(c *Config) *string { return &c.Bitrate })},
+	{"crf", intField(func(c *Config) *int { return &c.CRF })},
+	{"threads", intField(func(c *Config) *int { return &c.Threads })},
+	{"audio_codec", stringField(func(c *Config) *string { return &c.AudioCodec })},
+	{"audio_bitrate", stringField(func(c *Config) *string { return &c.AudioBitrate })},
+	{"ffmpeg_path", stringField(func(c *Config) *string { return &c.FFmpegPath })},
+	{"ffprobe_path", stringField(func(c *Config) *string { return &c.FFprobePath })},
+	{"temp_dir", stringField(func(c *Config) *string { return &c.TempDir })},
+	{"temp_quota", tempQuotaField},
+	{"log_level", logLevelField},
+	{"max_processes", intField(func(c *Config) *int { return &c.MaxProcesses })},
+	{"backend", stringField(func(c *Config) *string { return &c.Backend })},
+	{"process_timeout", processTimeoutField},
+}
+
+// Load 读取配置文件，再用环境变量覆盖
+func Load(filename string) (*Config, error) {
+	c := &Config{}
+	if err := c.loadFile(filename); err != nil {
+		return nil, err
+	}
+	if err := c.applyEnv(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadDefault 读取 MOVIEGO_CONFIG 指定的配置文件（未设置时跳过），再用环境变量覆盖
+func LoadDefault() (*Config, error) {
+	c := &Config{}
+	if filename := os.Getenv(EnvConfigFile); filename != "" {
+		if err := c.loadFile(filename); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.applyEnv(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Parse 解析配置内容，不读取环境变量
+func Parse(r io.Reader) (*Config, error) {
+	c := &Config{}
+	if err := c.parse(r); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// loadFile 从文件读取配置
+func (c *Config) loadFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("打开配置文件失败: %w", err)
+	}
+	defer file.Close()
+	if err := c.parse(file); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
+
+// parse 逐行解析 key: value
+func (c *Config) parse(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" || line == "---" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("第 %d 行: 缺少冒号: %s", lineNo, line)
+		}
+		key = strings.TrimSpace(key)
+		value = unquote(strings.TrimSpace(value))
+		f := lookupField(key)
+		if f == nil {
+			return fmt.Errorf("第 %d 行: 未知的配置项: %s", lineNo, key)
+		}
+		if err := f.set(c, value); err != nil {
+			return fmt.Errorf("第 %d 行: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取配置失败: %w", err)
+	}
+	return nil
+}
+
+// applyEnv 用 MOVIEGO_* 环境变量覆盖配置
+func (c *Config) applyEnv() error {
+	for _, f := range fields {
+		name := EnvName(f.key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := f.set(c, value); err != nil {
+			return fmt.Errorf("环境变量 %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// EnvName 返回配置项对应的环境变量名，如 ffmpeg_path → MOVIEGO_FFMPEG_PATH
+func EnvName(key string) string {
+	return "MOVIEGO_" + strings.ToUpper(key)
+}
+
+// WriteDefaults 转换为默认写入选项
+func (c *Config) WriteDefaults() core.WriteOptions {
+	return core.WriteOptions{
+		Codec:        c.Codec,
+		Bitrate:      c.Bitrate,
+		CRF:          c.CRF,
+		Threads:      c.Threads,
+		AudioCodec:   c.AudioCodec,
+		AudioBitrate: c.AudioBitrate,
+		LogLevel:     c.LogLevel,
+	}
+}
+
+// ProcessManagerOptions 转换为默认进程管理器选项
+func (c *Config) ProcessManagerOptions() ffmpeg.ProcessManagerOptions {
+	return ffmpeg.ProcessManagerOptions{
+		MaxProcesses:   c.MaxProcesses,
+		ProcessTimeout: c.ProcessTimeout,
+		FFmpegPath:     c.FFmpegPath,
+		FFprobePath:    c.FFprobePath,
+		TempQuota:      c.TempQuota,
+	}
+}
+
+// Install 将配置设为全局默认：WriteToFile 的默认写入选项、NewProcessManager 的默认选项、中间文件目录和特效后端
+//
+// 特效后端不可用（未注册或设备初始化失败）时保持 CPU 实现并返回原因，其余设置仍然生效。
+func (c *Config) Install() error {
+	core.SetWriteDefaults(c.WriteDefaults())
+	ffmpeg.SetDefaultProcessManagerOptions(c.ProcessManagerOptions())
+	ffmpeg.SetTempDir(c.TempDir)
+	if c.Backend != "" {
+		if err := effects.UseBackend(c.Backend); err != nil {
+			return fmt.Errorf("设置特效后端失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// lookupField 按键名查找配置项，键名中的 - 视同 _
+func lookupField(key string) *field {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for i := range fields {
+		if fields[i].key == key {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// stringField 字符串配置项
+func stringField(ptr func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*ptr(c) = value
+		return nil
+	}
+}
+
+// intField 整数配置项，空值表示 0（使用内置默认）
+func intField(ptr func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		if value == "" {
+			*ptr(c) = 0
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("无效的整数: %s", value)
+		}
+		*ptr(c) = n
+		return nil
+	}
+}
+
+// tempQuotaField 临时文件配额配置项，接受 K/M/G 后缀（1024 进制）
+func tempQuotaField(c *Config, value string) error {
+	if value == "" {
+		c.TempQuota = 0
+		return nil
+	}
+	multiplier := int64(1)
+	switch strings.ToUpper(value[len(value)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("无效的大小: %s", value)
+	}
+	c.TempQuota = n * multiplier
+	return nil
+}
+
+// processTimeoutField 进程超时配置项，使用 Go 的时长格式（如 90s、10m）
+func processTimeoutField(c *Config, value string) error {
+	if value == "" {
+		c.ProcessTimeout = 0
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fmt.Errorf("无效的时长: %s", value)
+	}
+	c.ProcessTimeout = d
+	return nil
+}
+
+// logLevels ffmpeg -loglevel 接受的级别名称
+var logLevels = map[string]bool{
+	"quiet": true, "panic": true, "fatal": true, "error": true,
+	"warning": true, "info": true, "verbose": true, "debug": true, "trace": true,
+}
+
+// logLevelField 日志级别配置项，只接受 ffmpeg 支持的级别
+func logLevelField(c *Config, value string) error {
+	if value != "" && !logLevels[value] {
+		return fmt.Errorf("无效的日志级别: %s", value)
+	}
+	c.LogLevel = value
+	return nil
+}
+
+// stripComment 去掉 # 注释，引号内的 # 保留
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote 去掉成对的单引号或双引号
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	input := `---
+# 部署默认值
+codec: libx265
+crf: 28   # 行尾注释
+threads: 4
+audio-codec: "libopus"
+audio_bitrate: '128k'
+ffmpeg_path: "/opt/ffmpeg #1/bin/ffmpeg"
+FFprobe_Path: /opt/ffmpeg/bin/ffprobe
+temp_dir: /var/tmp/moviego
+temp_quota: 20G
+log_level: warning
+max_processes: 8
+backend: cpu
+process_timeout: 10m
+bitrate:
+`
+	c, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	want := Config{
+		Codec:          "libx265",
+		CRF:            28,
+		Threads:        4,
+		AudioCodec:     "libopus",
+		AudioBitrate:   "128k",
+		FFmpegPath:     "/opt/ffmpeg #1/bin/ffmpeg",
+		FFprobePath:    "/opt/ffmpeg/bin/ffprobe",
+		TempDir:        "/var/tmp/moviego",
+		TempQuota:      20 << 30,
+		LogLevel:       "warning",
+		MaxProcesses:   8,
+		Backend:        "cpu",
+		ProcessTimeout: 10 * time.Minute,
+	}
+	if *c != want {
+		t.Fatalf("解析结果为\n%+v\n期望\n%+v", *c, want)
+	}
+}
+
+func TestParseTempQuota(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"", 0},
+		{"1048576", 1 << 20},
+		{"512K", 512 << 10},
+		{"64m", 64 << 20},
+		{"2G", 2 << 30},
+	}
+	for _, tt := range tests {
+		c, err := Parse(strings.NewReader("temp_quota: " + tt.value))
+		if err != nil {
+			t.Errorf("%q: 解析失败: %v", tt.value, err)
+			continue
+		}
+		if c.TempQuota != tt.want {
+			t.Errorf("%q: TempQuota 为 %d，期望 %d", tt.value, c.TempQuota, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // 错误信息中应包含的内容
+	}{
+		{"未知配置项", "codec: libx264\nresolution: 1080p\n", "第 2 行: 未知的配置项: resolution"},
+		{"拼写错误的键", "ffmpegpath: /usr/bin/ffmpeg\n", "第 1 行: 未知的配置项: ffmpegpath"},
+		{"缺少冒号", "# 注释\n\ncodec libx264\n", "第 3 行: 缺少冒号"},
+		{"整数不是数字", "crf: high\n", "第 1 行: 无效的整数: high"},
+		{"负整数", "threads: -2\n", "无效的整数: -2"},
+		{"小数", "max_processes: 1.5\n", "无效的整数: 1.5"},
+		{"无效的大小", "temp_quota: 10T\n", "无效的大小"},
+		{"只有单位", "temp_quota: G\n", "无效的大小"},
+		{"负大小", "temp_quota: -1G\n", "无效的大小"},
+		{"无效的时长", "process_timeout: 10\n", "无效的时长: 10"},
+		{"负时长", "process_timeout: -5s\n", "无效的时长: -5s"},
+		{"无效的日志级别", "log_level: loud\n", "无效的日志级别: loud"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.input))
+		if err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: 错误 %q 应包含 %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// writeConfig 在临时目录写入配置文件并返回路径
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "moviego.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	path := writeConfig(t, "codec: libx265\ncrf: 28\nthreads: 4\n")
+	t.Setenv("MOVIEGO_CRF", "18")
+	t.Setenv("MOVIEGO_THREADS", "")
+	t.Setenv("MOVIEGO_FFMPEG_PATH", "/env/ffmpeg")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("加载失败: %v", err)
+	}
+	if c.Codec != "libx265" {
+		t.Errorf("未被覆盖的键应取文件中的值，实际 Codec=%q", c.Codec)
+	}
+	if c.CRF != 18 {
+		t.Errorf("环境变量应覆盖文件，实际 CRF=%d", c.CRF)
+	}
+	if c.Threads != 0 {
+		t.Errorf("设置为空的环境变量应恢复内置默认，实际 Threads=%d", c.Threads)
+	}
+	if c.FFmpegPath != "/env/ffmpeg" {
+		t.Errorf("只在环境变量中设置的键应生效，实际 FFmpegPath=%q", c.FFmpegPath)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("配置文件不存在时应返回错误")
+	}
+
+	path := writeConfig(t, "codec: libx264\ncrf: x\n")
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), path) || !strings.Contains(err.Error(), "第 2 行") {
+		t.Errorf("文件中的错误应包含文件名和行号，实际 %v", err)
+	}
+
+	t.Setenv("MOVIEGO_PROCESS_TIMEOUT", "soon")
+	_, err = Load(writeConfig(t, "codec: libx264\n"))
+	if err == nil || !strings.Contains(err.Error(), "MOVIEGO_PROCESS_TIMEOUT") {
+		t.Errorf("环境变量的错误应包含变量名，实际 %v", err)
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	// 未设置 MOVIEGO_CONFIG 时只读取环境变量
+	t.Setenv(EnvConfigFile, "")
+	t.Setenv("MOVIEGO_CODEC", "libvpx-vp9")
+	c, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("加载失败: %v", err)
+	}
+	if c.Codec != "libvpx-vp9" {
+		t.Errorf("Codec 为 %q，期望 libvpx-vp9", c.Codec)
+	}
+
+	t.Setenv(EnvConfigFile, writeConfig(t, "codec: libx265\nlog_level: error\n"))
+	c, err = LoadDefault()
+	if err != nil {
+		t.Fatalf("加载失败: %v", err)
+	}
+	if c.Codec != "libvpx-vp9" || c.LogLevel != "error" {
+		t.Errorf("应先读取 MOVIEGO_CONFIG 再用环境变量覆盖，实际 Codec=%q LogLevel=%q", c.Codec, c.LogLevel)
+	}
+
+	t.Setenv(EnvConfigFile, filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := LoadDefault(); err == nil {
+		t.Error("MOVIEGO_CONFIG 指向不存在的文件时应返回错误")
+	}
+}
+
+func TestParseIgnoresEnv(t *testing.T) {
+	t.Setenv("MOVIEGO_CODEC", "libx265")
+	c, err := Parse(strings.NewReader("crf: 20\n"))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if c.Codec != "" {
+		t.Errorf("Parse 不应读取环境变量，实际 Codec=%q", c.Codec)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for _, f := range fields {
+		if lookupField(strings.ToUpper(strings.ReplaceAll(f.key, "_", "-"))) == nil {
+			t.Errorf("键 %s 应不区分大小写并接受 - 分隔", f.key)
+		}
+	}
+	if got := EnvName("ffmpeg_path"); got != "MOVIEGO_FFMPEG_PATH" {
+		t.Errorf("EnvName 为 %s", got)
+	}
+}
@@ -19,6 +19,11 @@ type AudioClip interface {
 	Mix(other AudioClip) (AudioClip, error)
 }
 
+// AudioSource 能提供音轨的剪辑，如视频文件剪辑、特效剪辑和合成剪辑；没有音轨时 Audio 返回 nil
+type AudioSource interface {
+	Audio() AudioClip
+}
+
 // BaseAudioClip 音频剪辑基础实现
 type BaseAudioClip struct {
 	*BaseClip
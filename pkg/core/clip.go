@@ -2,11 +2,19 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"image"
+	"log"
+	"math"
+	"sync"
 	"time"
 )
 
 // Clip 是视频和音频剪辑的基类接口
+//
+// 所有权规则：创建或打开剪辑的一方负责 Close。Subclip、With* 返回的剪辑是新的所有者，
+// 与原剪辑可以按任意顺序关闭；特效、合成等包装剪辑不关闭调用者传入的剪辑，
+// 只关闭它们自己派生出来的剪辑。调试时可设置 MOVIEGO_LEAKCHECK=1 报告未关闭就被回收的剪辑（见 leakcheck 包）。
 type Clip interface {
 	// 基础属性
 	Duration() time.Duration
@@ -20,6 +28,8 @@ type Clip interface {
 
 	// 变换操作
 	Subclip(start, end time.Duration) (Clip, error)
+	WithStart(start time.Duration) (Clip, error)
+	WithEnd(end time.Duration) (Clip, error)
 	WithSpeed(factor float64) (Clip, error)
 	WithVolume(factor float64) (Clip, error)
 
@@ -42,8 +52,67 @@ type WriteOptions struct {
 	Codec        string
 	Bitrate      string
 	FPS          float64
+	FrameRate    string // 精确帧率（如 "30000/1001"），优先于 FPS
+	CRF          int    // 恒定质量因子，0 表示默认 23
+	Threads      int    // 编码线程数，0 表示默认 1
 	AudioCodec   string
 	AudioBitrate string
+	Proxy        bool // 以代理（低分辨率）模式渲染，默认切换回原始分辨率
+	DirectWrite  bool // 直接写入目标文件，不使用临时文件加重命名
+
+	// StartTime/EndTime 只渲染剪辑内的这段窗口，无需为每个图层创建子剪辑；EndTime 为 0 表示剪辑结尾
+	StartTime time.Duration
+	EndTime   time.Duration
+	// FrameStep 每 FrameStep 帧取一帧并按相应降低的帧率编码，用于快速审阅导出，0 或 1 表示逐帧
+	FrameStep int
+	// Filter 编码前对输出画面应用的 FFmpeg -vf 滤镜链，如烧录时间码的 drawtext，不应改变画面尺寸
+	Filter string
+	// AudioFilter 编码音轨时应用的 FFmpeg -af 滤镜链，如导出预设的 loudnorm 响度标准化
+	AudioFilter string
+	// Dither 高位深帧量化为 8 位输出时的抖动方式：ordered（8×8 Bayer）或 blue_noise（蓝噪声），
+	// 空或 none 表示直接截断；可消除暗角、淡入淡出等渐变上的色带
+	Dither string
+
+	// Context 用于取消渲染，nil 表示不可取消
+	Context context.Context
+	// Progress 每写入一帧后回调，current 从 1 开始
+	Progress func(current, total int)
+	// FrameHook 在特效应用之后、编码之前对每帧调用，i 从 0 开始，返回的帧替代原帧写入；
+	// 可用于自定义处理、质检或逐帧日志，返回错误时中止写入
+	FrameHook func(i int, t time.Duration, frame image.Image) (image.Image, error)
+
+	// DryRun 只生成 FFmpeg 命令并通过 OnCommand 报告，不执行任何进程
+	DryRun bool
+	// OnCommand 报告将要执行（或试运行时本应执行）的 FFmpeg 命令
+	OnCommand func(name string, args []string)
+
+	// LogLevel FFmpeg 日志级别（quiet/error/info/debug），默认 error
+	LogLevel string
+	// Logger 接收 FFmpeg stderr 输出，nil 表示 log.Default()
+	Logger *log.Logger
+
+	// AVSyncCheck 写入完成后探测输出文件，音视频偏差超过 AVSyncThreshold 时通过 Logger 警告
+	AVSyncCheck bool
+	// AVSyncThreshold 允许的音视频偏差，默认 100ms
+	AVSyncThreshold time.Duration
+
+	// Cover 非 nil 时写入完成后嵌入为封面（MP4/MOV 的 attached_pic 或 MKV 附件），如 video.BestThumbnail 的结果
+	Cover image.Image
+
+	// Verify 写入完成后调用以校验输出文件（如 analysis.VerifyHook），返回的错误作为 WriteToFile 的结果；
+	// 原子写入时 filename 为尚未重命名的同目录临时文件，校验失败时目标文件保持不变
+	Verify func(filename string, expect OutputExpectation) error
+}
+
+// OutputExpectation 写入方对输出文件的预期，传给 WriteOptions.Verify
+type OutputExpectation struct {
+	Duration     time.Duration
+	Width        int
+	Height       int
+	VideoCodec   string // 编码器名称，如 libx264
+	AudioCodec   string
+	VideoStreams int
+	AudioStreams int
 }
 
 // BaseClip 提供 Clip 接口的基础实现
@@ -55,6 +124,90 @@ type BaseClip struct {
 	ctx      context.Context
 }
 
+// frameEpsilon 计算帧数时容忍的浮点误差（以帧为单位）
+const frameEpsilon = 1e-6
+
+// writeDefaults 由 SetWriteDefaults 设置的部署级默认写入选项
+var (
+	writeDefaultsMutex sync.RWMutex
+	writeDefaults      WriteOptions
+)
+
+// SetWriteDefaults 设置写入选项的默认值（编码器、码率、CRF、线程数、日志级别），
+// 各 WriteToFile 对调用方未设置的字段使用这些值，仍未设置的再取内置默认
+func SetWriteDefaults(defaults WriteOptions) {
+	writeDefaultsMutex.Lock()
+	defer writeDefaultsMutex.Unlock()
+	writeDefaults = defaults
+}
+
+// WriteDefaults 返回当前的默认写入选项
+func WriteDefaults() WriteOptions {
+	writeDefaultsMutex.RLock()
+	defer writeDefaultsMutex.RUnlock()
+	return writeDefaults
+}
+
+// ApplyWriteDefaults 用 SetWriteDefaults 设置的值填充 options 中未设置的字段，options 为 nil 时返回新的选项
+func ApplyWriteDefaults(options *WriteOptions) *WriteOptions {
+	if options == nil {
+		options = &WriteOptions{}
+	}
+	defaults := WriteDefaults()
+	if options.Codec == "" {
+		options.Codec = defaults.Codec
+	}
+	if options.Bitrate == "" {
+		options.Bitrate = defaults.Bitrate
+	}
+	if options.CRF == 0 {
+		options.CRF = defaults.CRF
+	}
+	if options.Threads == 0 {
+		options.Threads = defaults.Threads
+	}
+	if options.AudioCodec == "" {
+		options.AudioCodec = defaults.AudioCodec
+	}
+	if options.AudioBitrate == "" {
+		options.AudioBitrate = defaults.AudioBitrate
+	}
+	if options.LogLevel == "" {
+		options.LogLevel = defaults.LogLevel
+	}
+	return options
+}
+
+// RenderWindow 按 options 的 StartTime/EndTime 计算要渲染的窗口，EndTime 为 0 时取 duration
+func RenderWindow(options *WriteOptions, duration time.Duration) (start, end time.Duration, err error) {
+	start, end = options.StartTime, options.EndTime
+	if end == 0 {
+		end = duration
+	}
+	if start < 0 || end > duration || start >= end {
+		return 0, 0, fmt.Errorf("%w: 渲染窗口 %v-%v 超出剪辑时长 %v", ErrInvalidTimeRange, start, end, duration)
+	}
+	return start, end, nil
+}
+
+// FrameCount 返回时长内按 fps 采样的帧数，最后不足一帧的部分也计为一帧
+//
+// 第 i 帧的时间戳为 FrameTime(i, fps)，所有帧的时间戳都严格小于 duration。
+func FrameCount(duration time.Duration, fps float64) int {
+	if duration <= 0 || fps <= 0 {
+		return 0
+	}
+	return int(math.Ceil(duration.Seconds()*fps - frameEpsilon))
+}
+
+// FrameTime 返回第 index 帧的时间戳，由帧序号直接换算，避免累加帧间隔产生漂移
+func FrameTime(index int, fps float64) time.Duration {
+	if fps <= 0 {
+		return 0
+	}
+	return time.Duration(math.Round(float64(index) * float64(time.Second) / fps))
+}
+
 // NewBaseClip 创建新的基础剪辑
 func NewBaseClip(start, end, duration time.Duration, fps float64) *BaseClip {
 	return &BaseClip{
@@ -101,6 +254,16 @@ func (bc *BaseClip) Subclip(start, end time.Duration) (Clip, error) {
 	return nil, ErrNotImplemented
 }
 
+// WithStart 设置开始时间（基础实现返回错误）
+func (bc *BaseClip) WithStart(start time.Duration) (Clip, error) {
+	return nil, ErrNotImplemented
+}
+
+// WithEnd 设置结束时间（基础实现返回错误）
+func (bc *BaseClip) WithEnd(end time.Duration) (Clip, error) {
+	return nil, ErrNotImplemented
+}
+
 // WithSpeed 调整速度（基础实现返回错误）
 func (bc *BaseClip) WithSpeed(factor float64) (Clip, error) {
 	return nil, ErrNotImplemented
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// ntsc 29.97 帧率的精确值
+const ntsc = 30000.0 / 1001
+
+func TestFrameTime(t *testing.T) {
+	tests := []struct {
+		fps   float64
+		index int
+		want  time.Duration
+	}{
+		{24, 0, 0},
+		{24, 1, 41666667 * time.Nanosecond},
+		{24, 24, time.Second},
+		{24, 2399, 99958333333 * time.Nanosecond},
+		{25, 1, 40 * time.Millisecond},
+		{25, 250, 10 * time.Second},
+		{ntsc, 1, 33366667 * time.Nanosecond},
+		{ntsc, 30, 1001 * time.Millisecond},
+		{ntsc, 30000, 1001 * time.Second},
+		{60, 1, 16666667 * time.Nanosecond},
+		{60, 3, 50 * time.Millisecond},
+		{60, 216000, time.Hour},
+		{0, 10, 0},
+	}
+	for _, tt := range tests {
+		if got := FrameTime(tt.index, tt.fps); got != tt.want {
+			t.Errorf("FrameTime(%d, %g) = %v，期望 %v", tt.index, tt.fps, got, tt.want)
+		}
+	}
+}
+
+func TestFrameCount(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		fps      float64
+		want     int
+	}{
+		{time.Second, 24, 24},
+		{10 * time.Second, 24, 240},
+		{1010 * time.Millisecond, 24, 25}, // 最后不足一帧的部分计为一帧
+		{40 * time.Millisecond, 25, 1},
+		{41 * time.Millisecond, 25, 2},
+		{10 * time.Second, 25, 250},
+		{10 * time.Second, ntsc, 300}, // 299.7 帧
+		{1001 * time.Second, ntsc, 30000},
+		{FrameTime(30000, ntsc), ntsc, 30000},
+		{500 * time.Millisecond, 60, 30},
+		{time.Hour, 60, 216000},
+		{0, 30, 0},
+		{time.Second, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := FrameCount(tt.duration, tt.fps); got != tt.want {
+			t.Errorf("FrameCount(%v, %g) = %d，期望 %d", tt.duration, tt.fps, got, tt.want)
+		}
+	}
+}
+
+// 所有帧时间戳都落在时长内，且下一帧已超出时长，保证最后一帧被导出且不多导出一帧
+func TestFrameCountCoversDuration(t *testing.T) {
+	durations := []time.Duration{
+		time.Second,
+		1001 * time.Millisecond,
+		3*time.Second + 337*time.Millisecond,
+		FrameTime(299, ntsc),
+		FrameTime(300, ntsc) + time.Nanosecond,
+		time.Hour + time.Millisecond,
+	}
+	for _, fps := range []float64{24, 25, ntsc, 60} {
+		for _, duration := range durations {
+			n := FrameCount(duration, fps)
+			if last := FrameTime(n-1, fps); last >= duration {
+				t.Errorf("fps %g 时长 %v: 第 %d 帧 %v 超出时长", fps, duration, n-1, last)
+			}
+			if next := FrameTime(n, fps); next < duration-time.Microsecond {
+				t.Errorf("fps %g 时长 %v: 第 %d 帧 %v 仍在时长内却未计入", fps, duration, n, next)
+			}
+		}
+	}
+}
+
+func TestRenderWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end time.Duration
+		wantStart  time.Duration
+		wantEnd    time.Duration
+		wantErr    bool
+	}{
+		{"默认整段", 0, 0, 0, 10 * time.Second, false},
+		{"结束于时长", 2 * time.Second, 10 * time.Second, 2 * time.Second, 10 * time.Second, false},
+		{"只设置开始", 9 * time.Second, 0, 9 * time.Second, 10 * time.Second, false},
+		{"负的开始", -time.Nanosecond, 0, 0, 0, true},
+		{"结束超出时长", 0, 10*time.Second + time.Nanosecond, 0, 0, true},
+		{"开始等于结束", 5 * time.Second, 5 * time.Second, 0, 0, true},
+		{"开始等于时长", 10 * time.Second, 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		start, end, err := RenderWindow(&WriteOptions{StartTime: tt.start, EndTime: tt.end}, 10*time.Second)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidTimeRange) {
+				t.Errorf("%s: 错误 %v，期望 ErrInvalidTimeRange", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("%s: 得到 %v-%v（%v），期望 %v-%v", tt.name, start, end, err, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package coretest
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// audioFrameRate 与 AudioFileClip 相同，每帧 0.1 秒
+const audioFrameRate = 10
+
+// MockAudioClip 正弦波音频剪辑，各声道样本相同、交错排列
+//
+// 样本按绝对样本序号计算，任意时间取帧的相位都连续，便于比较拼接和截取的结果。
+type MockAudioClip struct {
+	*core.BaseAudioClip
+	frequency float64
+	amplitude float64
+	offset    time.Duration // Subclip 后相对波形起点的偏移
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// NewSineClip 创建频率为 frequency（Hz）、振幅为 amplitude（0–1）的正弦波剪辑，frequency 为 0 时为静音
+func NewSineClip(frequency, amplitude float64, duration time.Duration, channels, sampleRate int) *MockAudioClip {
+	return &MockAudioClip{
+		BaseAudioClip: core.NewBaseAudioClip(0, duration, duration, audioFrameRate, channels, sampleRate),
+		frequency:     frequency,
+		amplitude:     amplitude,
+	}
+}
+
+// NewSilentClip 创建静音剪辑
+func NewSilentClip(duration time.Duration, channels, sampleRate int) *MockAudioClip {
+	return NewSineClip(0, 0, duration, channels, sampleRate)
+}
+
+// Sample 第 n 个样本（单声道）的值
+func (ac *MockAudioClip) Sample(n int) float64 {
+	if ac.frequency == 0 {
+		return 0
+	}
+	return ac.amplitude * math.Sin(2*math.Pi*ac.frequency*float64(n)/float64(ac.SampleRate()))
+}
+
+// GetAudioFrame 返回从 t 开始 0.1 秒的交错样本，超出剪辑结尾的部分为静音
+func (ac *MockAudioClip) GetAudioFrame(t time.Duration) ([]float64, error) {
+	ac.mutex.Lock()
+	closed := ac.closed
+	ac.mutex.Unlock()
+	if closed {
+		return nil, core.ErrResourceClosed
+	}
+	if t < 0 || t >= ac.Duration() {
+		return nil, fmt.Errorf("%w: %v 超出剪辑时长 %v", core.ErrInvalidTimeRange, t, ac.Duration())
+	}
+
+	rate := ac.SampleRate()
+	channels := ac.Channels()
+	count := rate / audioFrameRate
+	first := int(math.Round((t + ac.offset).Seconds() * float64(rate)))
+	last := int(math.Round((ac.Duration() + ac.offset).Seconds() * float64(rate)))
+	samples := make([]float64, count*channels)
+	for i := 0; i < count && first+i < last; i++ {
+		v := ac.Sample(first + i)
+		for c := 0; c < channels; c++ {
+			samples[i*channels+c] = v
+		}
+	}
+	return samples, nil
+}
+
+// Subclip 截取时间段，波形相位与原剪辑一致
+func (ac *MockAudioClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > ac.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	sub := NewSineClip(ac.frequency, ac.amplitude, end-start, ac.Channels(), ac.SampleRate())
+	sub.offset = ac.offset + start
+	return sub, nil
+}
+
+// WithVolume 返回振幅乘以 factor 的副本
+func (ac *MockAudioClip) WithVolume(factor float64) (core.Clip, error) {
+	if factor < 0 {
+		return nil, core.ErrInvalidVolumeFactor
+	}
+	clone := NewSineClip(ac.frequency, ac.amplitude*factor, ac.Duration(), ac.Channels(), ac.SampleRate())
+	clone.offset = ac.offset
+	return clone, nil
+}
+
+// WithChannels 返回声道数不同的副本
+func (ac *MockAudioClip) WithChannels(channels int) (core.AudioClip, error) {
+	if channels <= 0 {
+		return nil, core.ErrInvalidFormat
+	}
+	clone := NewSineClip(ac.frequency, ac.amplitude, ac.Duration(), channels, ac.SampleRate())
+	clone.offset = ac.offset
+	return clone, nil
+}
+
+// WithSampleRate 返回采样率不同的副本
+func (ac *MockAudioClip) WithSampleRate(sampleRate int) (core.AudioClip, error) {
+	if sampleRate <= 0 {
+		return nil, core.ErrInvalidFormat
+	}
+	clone := NewSineClip(ac.frequency, ac.amplitude, ac.Duration(), ac.Channels(), sampleRate)
+	clone.offset = ac.offset
+	return clone, nil
+}
+
+// Close 标记为已关闭
+func (ac *MockAudioClip) Close() error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+	ac.closed = true
+	return nil
+}
+
+// Closed 是否已关闭
+func (ac *MockAudioClip) Closed() bool {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+	return ac.closed
+}
@@ -0,0 +1,123 @@
+package coretest
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"moviepy-go/pkg/pixel"
+)
+
+// blendLevels CheckBlend 扫描的通道取值，包含两端、中点两侧和低 alpha 处容易出现色带的值
+var blendLevels = []uint8{0, 1, 16, 64, 127, 128, 192, 254, 255}
+
+// BlendReference 按 W3C Compositing and Blending 规范以浮点计算在 base 上按 mode 叠加 over 的结果
+//
+// 输入输出均为预乘颜色，结果四舍五入到 8 位，作为 pixel.BlendPremultiplied 等定点实现的参考。
+func BlendReference(mode pixel.BlendMode, base, over color.RGBA) color.RGBA {
+	ab, as := float64(base.A)/255, float64(over.A)/255
+	ao := as + ab*(1-as)
+	channel := func(cb8, cs8 uint8) uint8 {
+		cb, cs := float64(cb8)/255, float64(cs8)/255
+		var straightB, straightS float64
+		if ab > 0 {
+			straightB = math.Min(cb/ab, 1)
+		}
+		if as > 0 {
+			straightS = math.Min(cs/as, 1)
+		}
+		co := cs*(1-ab) + cb*(1-as) + as*ab*blendFunction(mode, straightB, straightS)
+		return uint8(math.Round(math.Min(co, ao) * 255))
+	}
+	return color.RGBA{
+		R: channel(base.R, over.R),
+		G: channel(base.G, over.G),
+		B: channel(base.B, over.B),
+		A: uint8(math.Round(ao * 255)),
+	}
+}
+
+// blendFunction 规范中的可分离混合函数 B(Cb, Cs)，Add 为截断到 1 的相加
+func blendFunction(mode pixel.BlendMode, cb, cs float64) float64 {
+	switch mode {
+	case pixel.BlendAdd:
+		return math.Min(cb+cs, 1)
+	case pixel.BlendMultiply:
+		return cb * cs
+	case pixel.BlendScreen:
+		return cb + cs - cb*cs
+	case pixel.BlendDarken:
+		return math.Min(cb, cs)
+	case pixel.BlendLighten:
+		return math.Max(cb, cs)
+	case pixel.BlendOverlay:
+		if cb <= 0.5 {
+			return 2 * cb * cs
+		}
+		return 1 - 2*(1-cb)*(1-cs)
+	default:
+		return cs
+	}
+}
+
+// CheckBlend 在底色、叠加色和两者 alpha 的网格上比较 blend 与 BlendReference 的结果，
+// 任一预乘通道的差值超过 tolerance 时使测试失败，如
+//
+//	coretest.CheckBlend(t, pixel.BlendScreen, func(dst, src []byte) { pixel.BlendPremultiplied(pixel.BlendScreen, dst, src) }, 1)
+//
+// 叠加色全部不透明的网格单独作为一行混合，以覆盖只处理不透明行的 SIMD 内核。
+func CheckBlend(tb testing.TB, mode pixel.BlendMode, blend func(dst, src []byte), tolerance int) {
+	tb.Helper()
+	checkBlend(tb, mode, blend, tolerance, blendLevels)
+}
+
+// CheckBlendOpaque 与 CheckBlend 相同，但底色全部不透明，用于 pixel.Blend 等假定底图不透明的实现
+func CheckBlendOpaque(tb testing.TB, mode pixel.BlendMode, blend func(dst, src []byte), tolerance int) {
+	tb.Helper()
+	checkBlend(tb, mode, blend, tolerance, []uint8{255})
+}
+
+// checkBlend 按底色 alpha 取 baseAlphas 的网格比较 blend 与 BlendReference
+func checkBlend(tb testing.TB, mode pixel.BlendMode, blend func(dst, src []byte), tolerance int, baseAlphas []uint8) {
+	tb.Helper()
+	failures := 0
+	for _, overAlphas := range [][]uint8{blendLevels, {255}} {
+		var dst, src []byte
+		var cases [][2]color.RGBA
+		for _, ab := range baseAlphas {
+			for _, as := range overAlphas {
+				for _, cb := range blendLevels {
+					for _, cs := range blendLevels {
+						// 预乘颜色的通道不超过 alpha
+						base := color.RGBA{R: min(cb, ab), G: ab / 2, B: 255 - max(cb, 255-ab), A: ab}
+						over := color.RGBA{R: min(cs, as), G: as, B: as / 3, A: as}
+						cases = append(cases, [2]color.RGBA{base, over})
+						dst = append(dst, base.R, base.G, base.B, base.A)
+						src = append(src, over.R, over.G, over.B, over.A)
+					}
+				}
+			}
+		}
+		blend(dst, src)
+
+		for i, c := range cases {
+			want := BlendReference(mode, c[0], c[1])
+			got := color.RGBA{R: dst[i*4], G: dst[i*4+1], B: dst[i*4+2], A: dst[i*4+3]}
+			if channelDiff(got, want) > tolerance {
+				tb.Errorf("%v 混合 %v 上的 %v: 得到 %v，参考值 %v", mode, c[0], c[1], got, want)
+				if failures++; failures >= 10 {
+					tb.Fatalf("超出容差 %d 的结果过多，停止比较", tolerance)
+				}
+			}
+		}
+	}
+}
+
+// channelDiff 返回两个颜色各通道差值的最大值
+func channelDiff(a, b color.RGBA) int {
+	d := 0
+	for _, pair := range [][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}, {a.A, b.A}} {
+		d = max(d, int(max(pair[0], pair[1])-min(pair[0], pair[1])))
+	}
+	return d
+}
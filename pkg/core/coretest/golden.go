@@ -0,0 +1,80 @@
+package coretest
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"moviepy-go/pkg/imagetools"
+)
+
+// UpdateGoldenEnv 设置该环境变量（非空）时 CheckGolden 用当前结果重写黄金帧
+const UpdateGoldenEnv = "MOVIEGO_UPDATE_GOLDEN"
+
+// LoadGolden 读取 PNG 黄金帧
+func LoadGolden(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开黄金帧失败: %w", err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("解码黄金帧 %s 失败: %w", path, err)
+	}
+	return img, nil
+}
+
+// WriteGolden 把帧保存为 PNG 黄金帧，自动创建目录
+func WriteGolden(path string, frame image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建黄金帧目录失败: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建黄金帧失败: %w", err)
+	}
+	if err := png.Encode(file, frame); err != nil {
+		file.Close()
+		return fmt.Errorf("编码黄金帧失败: %w", err)
+	}
+	return file.Close()
+}
+
+// CheckGolden 比较 got 与 path 处的黄金帧，tolerance 为单个通道允许的最大差值
+//
+// 超出容差时使测试失败，并在 path 旁写入实际结果 .actual.png 和可视化差异 .diff.png（见 imagetools.Diff）。
+// 黄金帧不存在时测试失败；设置 MOVIEGO_UPDATE_GOLDEN=1 运行测试可生成或更新黄金帧。
+func CheckGolden(tb testing.TB, got image.Image, path string, tolerance int) {
+	tb.Helper()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := WriteGolden(path, got); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+	want, err := LoadGolden(path)
+	if err != nil {
+		tb.Fatalf("%v（设置 %s=1 生成黄金帧）", err, UpdateGoldenEnv)
+		return
+	}
+	diff := imagetools.Diff(got, want, tolerance)
+	if diff.Equal() {
+		return
+	}
+	base := path[:len(path)-len(filepath.Ext(path))]
+	if err := WriteGolden(base+".actual.png", got); err != nil {
+		tb.Logf("保存实际结果失败: %v", err)
+	}
+	if diff.SizeMismatch {
+		tb.Fatalf("帧尺寸 %v 与黄金帧 %s 的 %v 不同，实际结果已写入 %s.actual.png", got.Bounds().Size(), path, want.Bounds().Size(), base)
+		return
+	}
+	if err := WriteGolden(base+".diff.png", diff.Image); err != nil {
+		tb.Logf("保存差异图失败: %v", err)
+	}
+	tb.Fatalf("帧与黄金帧 %s 不一致: %v，实际结果和差异图已写入 %s.actual.png/.diff.png", path, diff, base)
+}
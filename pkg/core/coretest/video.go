@@ -0,0 +1,166 @@
+// Package coretest 提供确定性的模拟剪辑和黄金帧比较，用于在没有媒体文件和 FFmpeg 的环境中测试处理流程
+//
+// 示例：
+//
+//	clip := coretest.NewCounterClip(64, 36, time.Second, 30)
+//	evc := video.NewEffectVideoClip(clip, nil)
+//	evc.AddEffect(effects.NewSepiaEffect(1))
+//	frame, _ := evc.GetFrame(500 * time.Millisecond)
+//	coretest.CheckGolden(t, frame, "testdata/sepia.png", 2)
+package coretest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// FrameFunc 生成第 i 帧（时间 t）的画面
+type FrameFunc func(i int, t time.Duration) image.Image
+
+// MockVideoClip 由函数生成画面的视频剪辑，记录每次取帧的时间
+//
+// 写入文件需要 FFmpeg，WriteToFile 返回 core.ErrNotImplemented；测试渲染流程时可用 core.RenderFrames。
+type MockVideoClip struct {
+	*core.BaseVideoClip
+	frame  FrameFunc
+	offset time.Duration // Subclip 后相对生成函数的时间偏移
+	audio  core.AudioClip
+
+	mutex  sync.Mutex
+	calls  []time.Duration
+	closed bool
+}
+
+// NewMockVideoClip 创建模拟视频剪辑，frame 的帧序号按 fps 由 t 换算
+func NewMockVideoClip(width, height int, duration time.Duration, fps float64, frame FrameFunc) *MockVideoClip {
+	return &MockVideoClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, duration, duration, fps, width, height),
+		frame:         frame,
+	}
+}
+
+// NewSolidClip 每帧都是纯色 c 的剪辑
+func NewSolidClip(c color.Color, width, height int, duration time.Duration, fps float64) *MockVideoClip {
+	solid := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(solid, solid.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return NewMockVideoClip(width, height, duration, fps, func(int, time.Duration) image.Image {
+		frame := image.NewRGBA(solid.Rect)
+		copy(frame.Pix, solid.Pix)
+		return frame
+	})
+}
+
+// NewCounterClip 把帧序号编码进整帧颜色的剪辑，用 FrameIndex 读回，用于检查取帧时间和顺序
+func NewCounterClip(width, height int, duration time.Duration, fps float64) *MockVideoClip {
+	return NewMockVideoClip(width, height, duration, fps, func(i int, t time.Duration) image.Image {
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(frame, frame.Bounds(), image.NewUniform(CounterColor(i)), image.Point{}, draw.Src)
+		return frame
+	})
+}
+
+// CounterColor 第 i 帧的颜色：低 24 位依次写入 R、G、B
+func CounterColor(i int) color.RGBA {
+	return color.RGBA{uint8(i), uint8(i >> 8), uint8(i >> 16), 255}
+}
+
+// FrameIndex 读取 NewCounterClip 帧中心像素编码的帧序号
+func FrameIndex(frame image.Image) int {
+	bounds := frame.Bounds()
+	c := color.RGBAModel.Convert(frame.At((bounds.Min.X+bounds.Max.X)/2, (bounds.Min.Y+bounds.Max.Y)/2)).(color.RGBA)
+	return int(c.R) | int(c.G)<<8 | int(c.B)<<16
+}
+
+// GetFrame 生成时间 t 处的帧
+func (mc *MockVideoClip) GetFrame(t time.Duration) (image.Image, error) {
+	mc.mutex.Lock()
+	closed := mc.closed
+	mc.calls = append(mc.calls, t)
+	mc.mutex.Unlock()
+	if closed {
+		return nil, core.ErrResourceClosed
+	}
+	if t < 0 || t >= mc.Duration() {
+		return nil, fmt.Errorf("%w: %v 超出剪辑时长 %v", core.ErrInvalidTimeRange, t, mc.Duration())
+	}
+	source := t + mc.offset
+	return mc.frame(frameIndex(source, mc.FPS()), source), nil
+}
+
+// frameIndex 时间 t 所在的帧序号，容忍 FrameTime 取整到纳秒的误差
+func frameIndex(t time.Duration, fps float64) int {
+	return int(math.Floor(t.Seconds()*fps + 1e-6))
+}
+
+// Calls 返回到目前为止每次 GetFrame 的时间
+func (mc *MockVideoClip) Calls() []time.Duration {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return append([]time.Duration(nil), mc.calls...)
+}
+
+// ResetCalls 清空取帧记录
+func (mc *MockVideoClip) ResetCalls() {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	mc.calls = nil
+}
+
+// Subclip 截取时间段，子剪辑独立记录取帧
+func (mc *MockVideoClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > mc.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	sub := NewMockVideoClip(mc.Width(), mc.Height(), end-start, mc.FPS(), mc.frame)
+	sub.offset = mc.offset + start
+	sub.audio = mc.audio
+	return sub, nil
+}
+
+// WithAudio 返回带音轨的副本
+func (mc *MockVideoClip) WithAudio(audio core.AudioClip) (core.Clip, error) {
+	clone := NewMockVideoClip(mc.Width(), mc.Height(), mc.Duration(), mc.FPS(), mc.frame)
+	clone.offset = mc.offset
+	clone.audio = audio
+	return clone, nil
+}
+
+// WithoutAudio 返回不带音轨的副本
+func (mc *MockVideoClip) WithoutAudio() (core.Clip, error) {
+	return mc.WithAudio(nil)
+}
+
+// Audio 返回音轨，没有时为 nil
+func (mc *MockVideoClip) Audio() core.AudioClip {
+	return mc.audio
+}
+
+// GetAudioFrame 从音轨取样本，没有音轨时返回错误
+func (mc *MockVideoClip) GetAudioFrame(t time.Duration) ([]float64, error) {
+	if mc.audio == nil {
+		return nil, fmt.Errorf("剪辑没有音轨")
+	}
+	return mc.audio.GetAudioFrame(t)
+}
+
+// Close 标记为已关闭，之后 GetFrame 返回 core.ErrResourceClosed
+func (mc *MockVideoClip) Close() error {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	mc.closed = true
+	return nil
+}
+
+// Closed 是否已关闭，用于检查流程是否释放了剪辑
+func (mc *MockVideoClip) Closed() bool {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	return mc.closed
+}
@@ -18,4 +18,8 @@ var (
 	ErrUnsupportedCodec    = errors.New("不支持的编解码器")
 	ErrMemoryLimit         = errors.New("内存使用超出限制")
 	ErrProcessTerminated   = errors.New("进程被终止")
+	ErrVerificationFailed  = errors.New("输出文件校验失败")
+	ErrUnknownPlugin       = errors.New("未注册的插件")
+	ErrInvalidWriteOptions = errors.New("无效的写入选项")
+	ErrInvalidLayer        = errors.New("无效的图层")
 )
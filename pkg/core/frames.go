@@ -0,0 +1,209 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"iter"
+	"time"
+)
+
+// FrameLayout 帧缓冲区的像素排列
+type FrameLayout int
+
+const (
+	// LayoutRGB24 交错排列 RGBRGB...（HWC），适合自定义编码器和 WebRTC
+	LayoutRGB24 FrameLayout = iota
+	// LayoutPlanarRGB 每帧依次存放 R、G、B 三个平面（CHW），适合机器学习模型输入
+	LayoutPlanarRGB
+)
+
+// FrameBuffer 连续存放若干帧 8 位 RGB 像素的紧凑缓冲区，不含 alpha
+type FrameBuffer struct {
+	Width  int
+	Height int
+	Count  int
+	FPS    float64
+	Start  time.Duration // 第 0 帧相对剪辑开头的时间
+	Layout FrameLayout
+	Data   []byte // Count 帧，每帧 FrameSize() 字节
+}
+
+// FrameSize 每帧字节数
+func (fb *FrameBuffer) FrameSize() int {
+	return fb.Width * fb.Height * 3
+}
+
+// Frame 返回第 i 帧的像素数据（与 Data 共享内存）
+func (fb *FrameBuffer) Frame(i int) []byte {
+	size := fb.FrameSize()
+	return fb.Data[i*size : (i+1)*size]
+}
+
+// Time 返回第 i 帧相对剪辑开头的时间
+func (fb *FrameBuffer) Time(i int) time.Duration {
+	return fb.Start + FrameTime(i, fb.FPS)
+}
+
+// Image 将第 i 帧转换为 *image.RGBA
+func (fb *FrameBuffer) Image(i int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, fb.Width, fb.Height))
+	frame := fb.Frame(i)
+	plane := fb.Width * fb.Height
+	for p := 0; p < plane; p++ {
+		if fb.Layout == LayoutPlanarRGB {
+			img.Pix[p*4], img.Pix[p*4+1], img.Pix[p*4+2] = frame[p], frame[plane+p], frame[2*plane+p]
+		} else {
+			img.Pix[p*4], img.Pix[p*4+1], img.Pix[p*4+2] = frame[p*3], frame[p*3+1], frame[p*3+2]
+		}
+		img.Pix[p*4+3] = 255
+	}
+	return img
+}
+
+// Frame 流式接口返回的一帧及其呈现时间
+type Frame struct {
+	Image    image.Image
+	PTS      time.Duration // 呈现时间，相对剪辑开头，即取帧所用的 t
+	Index    int           // 在本次遍历中的序号，从 0 开始
+	KeyFrame bool          // 源文件中的关键帧，剪辑未实现 KeyFrameReporter 时为 false
+}
+
+// KeyFrameReporter 能判断某一时间的帧是否为源文件关键帧的剪辑，如 VideoFileClip
+type KeyFrameReporter interface {
+	IsKeyFrame(t time.Duration) bool
+}
+
+// IsKeyFrame clip 实现 KeyFrameReporter 时返回 t 处是否为关键帧，否则返回 false
+func IsKeyFrame(clip Clip, t time.Duration) bool {
+	if reporter, ok := clip.(KeyFrameReporter); ok {
+		return reporter.IsKeyFrame(t)
+	}
+	return false
+}
+
+// Frames 返回按 fps 依次解码 [start, end) 内帧的迭代器，不写文件也不保留帧
+//
+// end 为 0 表示剪辑末尾，fps 为 0 时使用剪辑帧率。出错或 ctx 取消时产出一次非 nil 的错误后结束。
+func Frames(ctx context.Context, clip VideoClip, start, end time.Duration, fps float64) iter.Seq2[Frame, error] {
+	return func(yield func(Frame, error) bool) {
+		err := EachFrame(ctx, clip, start, end, fps, func(frame Frame) error {
+			if !yield(frame, nil) {
+				return errStopFrames
+			}
+			return nil
+		})
+		if err != nil && err != errStopFrames {
+			yield(Frame{}, err)
+		}
+	}
+}
+
+// errStopFrames 迭代器的调用方提前结束
+var errStopFrames = errors.New("停止遍历")
+
+// EachFrame 按 fps 依次解码 [start, end) 内的帧并调用 fn，不写文件也不保留帧
+//
+// end 为 0 表示剪辑末尾，fps 为 0 时使用剪辑帧率；fn 返回错误或 ctx 取消时停止。
+func EachFrame(ctx context.Context, clip VideoClip, start, end time.Duration, fps float64, fn func(frame Frame) error) error {
+	start, end, fps, err := frameWindow(clip, start, end, fps)
+	if err != nil {
+		return err
+	}
+	count := FrameCount(end-start, fps)
+	for i := 0; i < count; i++ {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%w: %w", ErrContextCancelled, err)
+		}
+		t := start + FrameTime(i, fps)
+		img, err := GetFrameContext(ctx, clip, t)
+		if err != nil {
+			return fmt.Errorf("获取第 %d 帧 (%v) 失败: %w", i, t, err)
+		}
+		if err := fn(Frame{Image: img, PTS: t, Index: i, KeyFrame: IsKeyFrame(clip, t)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderFrames 渲染 [start, end) 内的帧并以切片返回，所有帧都保留在内存中
+func RenderFrames(clip VideoClip, start, end time.Duration, fps float64) ([]image.Image, error) {
+	var frames []image.Image
+	err := EachFrame(context.Background(), clip, start, end, fps, func(frame Frame) error {
+		frames = append(frames, frame.Image)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+// RenderFrameBuffer 渲染 [start, end) 内的帧到按 layout 排列的紧凑 RGB 缓冲区
+//
+// 尺寸与剪辑不一致的帧会返回 ErrInvalidFrame；缓冲区超过 maxBytes（大于 0 时）返回 ErrMemoryLimit。
+func RenderFrameBuffer(clip VideoClip, start, end time.Duration, fps float64, layout FrameLayout, maxBytes int64) (*FrameBuffer, error) {
+	start, end, fps, err := frameWindow(clip, start, end, fps)
+	if err != nil {
+		return nil, err
+	}
+	fb := &FrameBuffer{
+		Width:  clip.Width(),
+		Height: clip.Height(),
+		Count:  FrameCount(end-start, fps),
+		FPS:    fps,
+		Start:  start,
+		Layout: layout,
+	}
+	total := int64(fb.FrameSize()) * int64(fb.Count)
+	if maxBytes > 0 && total > maxBytes {
+		return nil, fmt.Errorf("%w: %d 帧 %dx%d 需要 %d 字节，上限 %d", ErrMemoryLimit, fb.Count, fb.Width, fb.Height, total, maxBytes)
+	}
+	fb.Data = make([]byte, total)
+
+	rgba := image.NewRGBA(image.Rect(0, 0, fb.Width, fb.Height))
+	err = EachFrame(context.Background(), clip, start, end, fps, func(frame Frame) error {
+		bounds := frame.Image.Bounds()
+		if bounds.Dx() != fb.Width || bounds.Dy() != fb.Height {
+			return fmt.Errorf("%w: 第 %d 帧尺寸 %dx%d 与剪辑 %dx%d 不一致", ErrInvalidFrame, frame.Index, bounds.Dx(), bounds.Dy(), fb.Width, fb.Height)
+		}
+		draw.Draw(rgba, rgba.Bounds(), frame.Image, bounds.Min, draw.Src)
+		fb.pack(fb.Frame(frame.Index), rgba)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fb, nil
+}
+
+// pack 将 RGBA 像素按 layout 写入 dst，丢弃 alpha
+func (fb *FrameBuffer) pack(dst []byte, rgba *image.RGBA) {
+	plane := fb.Width * fb.Height
+	for p := 0; p < plane; p++ {
+		r, g, b := rgba.Pix[p*4], rgba.Pix[p*4+1], rgba.Pix[p*4+2]
+		if fb.Layout == LayoutPlanarRGB {
+			dst[p], dst[plane+p], dst[2*plane+p] = r, g, b
+		} else {
+			dst[p*3], dst[p*3+1], dst[p*3+2] = r, g, b
+		}
+	}
+}
+
+// frameWindow 校验渲染窗口并补全默认值
+func frameWindow(clip VideoClip, start, end time.Duration, fps float64) (time.Duration, time.Duration, float64, error) {
+	if fps == 0 {
+		fps = clip.FPS()
+	}
+	if fps <= 0 {
+		return 0, 0, 0, fmt.Errorf("无效的帧率: %f", fps)
+	}
+	start, end, err := RenderWindow(&WriteOptions{StartTime: start, EndTime: end}, clip.Duration())
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return start, end, fps, nil
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RenderInfo 一个进行中的渲染
+type RenderInfo struct {
+	Clip    string // ClipID
+	Output  string
+	Started time.Time
+}
+
+// activeRender 登记中的渲染，cancel 用于关闭时中止
+type activeRender struct {
+	info   RenderInfo
+	cancel context.CancelFunc
+}
+
+// renders 进程内所有进行中的渲染，WriteToFile 开始时登记、结束时注销
+var renders = struct {
+	mutex  sync.Mutex
+	active map[*activeRender]struct{}
+	idle   *sync.Cond // active 变空时广播
+}{active: make(map[*activeRender]struct{})}
+
+func init() {
+	renders.idle = sync.NewCond(&renders.mutex)
+}
+
+// BeginRender 登记一个进行中的渲染，返回的 ctx 在 parent 取消或 CancelRenders 时取消；
+// 渲染结束后调用 done。parent 为 nil 时使用 context.Background()
+//
+// 各剪辑的 WriteToFile 已通过 TraceRender 登记，自定义的渲染循环可用它参与 DrainRenders。
+func BeginRender(parent context.Context, clip Clip, output string) (ctx context.Context, done func()) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	r := &activeRender{
+		info:   RenderInfo{Clip: ClipID(clip), Output: output, Started: time.Now()},
+		cancel: cancel,
+	}
+	renders.mutex.Lock()
+	renders.active[r] = struct{}{}
+	renders.mutex.Unlock()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			renders.mutex.Lock()
+			delete(renders.active, r)
+			if len(renders.active) == 0 {
+				renders.idle.Broadcast()
+			}
+			renders.mutex.Unlock()
+		})
+	}
+}
+
+// ActiveRenders 返回进行中的渲染，按开始时间排序
+func ActiveRenders() []RenderInfo {
+	renders.mutex.Lock()
+	infos := make([]RenderInfo, 0, len(renders.active))
+	for r := range renders.active {
+		infos = append(infos, r.info)
+	}
+	renders.mutex.Unlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Started.Before(infos[j].Started) })
+	return infos
+}
+
+// CancelRenders 取消所有进行中的渲染，返回被取消的渲染；渲染在下一帧检查上下文时返回 ErrContextCancelled
+func CancelRenders() []RenderInfo {
+	renders.mutex.Lock()
+	defer renders.mutex.Unlock()
+	infos := make([]RenderInfo, 0, len(renders.active))
+	for r := range renders.active {
+		r.cancel()
+		infos = append(infos, r.info)
+	}
+	return infos
+}
+
+// DrainRenders 等待所有进行中的渲染结束，ctx 先结束时返回 ctx.Err()（不取消渲染）
+func DrainRenders(ctx context.Context) error {
+	idle := make(chan struct{})
+	go func() {
+		renders.mutex.Lock()
+		for len(renders.active) > 0 && ctx.Err() == nil {
+			renders.idle.Wait()
+		}
+		renders.mutex.Unlock()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		// 唤醒等待协程使其退出
+		renders.mutex.Lock()
+		renders.idle.Broadcast()
+		renders.mutex.Unlock()
+		<-idle
+		renders.mutex.Lock()
+		defer renders.mutex.Unlock()
+		if len(renders.active) == 0 {
+			return nil
+		}
+		return ctx.Err()
+	}
+}
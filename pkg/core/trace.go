@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync/atomic"
+	"time"
+)
+
+// Attribute 追踪区间的属性
+type Attribute struct {
+	Key   string
+	Value any // string、bool、int、int64、float64 或 time.Duration，其他类型按 fmt.Sprint 记录
+}
+
+// Attr 创建属性
+func Attr(key string, value any) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span 一个追踪区间
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer 追踪后端，如 telemetry 包中的 OpenTelemetry 适配器
+type Tracer interface {
+	// Start 以 ctx 中的区间为父区间开始新区间，返回携带新区间的 ctx
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// 渲染各阶段的区间名称
+const (
+	SpanRender    = "moviego.render"    // 一次 WriteToFile
+	SpanFrame     = "moviego.frame"     // 渲染中的一帧
+	SpanDecode    = "moviego.decode"    // 从源文件解码一帧
+	SpanEffect    = "moviego.effect"    // 对一帧应用一个特效
+	SpanComposite = "moviego.composite" // 合成一帧的全部图层
+	SpanEncode    = "moviego.encode"    // 把一帧写入编码器
+)
+
+// tracerHolder 包装 Tracer 以便原子替换
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var currentTracer atomic.Pointer[tracerHolder]
+
+// SetTracer 设置全局追踪后端，nil 表示关闭（默认）
+//
+// 关闭时各阶段只多一次原子读取，不分配区间。
+func SetTracer(tracer Tracer) {
+	if tracer == nil {
+		currentTracer.Store(nil)
+		return
+	}
+	currentTracer.Store(&tracerHolder{tracer: tracer})
+}
+
+// Tracing 是否设置了追踪后端
+func Tracing() bool {
+	return currentTracer.Load() != nil
+}
+
+// StartSpan 开始一个区间，ctx 中带有任务 ID 时自动附加 job.id 属性
+func StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	holder := currentTracer.Load()
+	if holder == nil {
+		return ctx, noopSpan{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if id := JobID(ctx); id != "" {
+		attrs = append(attrs, Attr("job.id", id))
+	}
+	return holder.tracer.Start(ctx, name, attrs...)
+}
+
+// EndSpan 记录非 nil 的 err 后结束区间，便于 defer 使用
+func EndSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// noopSpan 未设置追踪后端时的空区间
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// jobIDKey ctx 中任务 ID 的键
+type jobIDKey struct{}
+
+// WithJobID 在 ctx 中记录渲染任务 ID，之后开始的区间都带有 job.id 属性
+func WithJobID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, jobIDKey{}, id)
+}
+
+// JobID 返回 ctx 中的任务 ID，没有时为空
+func JobID(ctx context.Context) string {
+	id, _ := ctx.Value(jobIDKey{}).(string)
+	return id
+}
+
+// ClipID 返回剪辑在本进程内的标识（类型与地址），用于关联同一剪辑的区间
+func ClipID(clip any) string {
+	return fmt.Sprintf("%T@%p", clip, clip)
+}
+
+// ContextFrameGetter 可以接收 ctx 的取帧接口，ctx 携带父区间，使解码、特效等区间归入所属的帧
+type ContextFrameGetter interface {
+	GetFrameContext(ctx context.Context, t time.Duration) (image.Image, error)
+}
+
+// GetFrameContext clip 实现 ContextFrameGetter 时带 ctx 取帧，否则调用 GetFrame
+func GetFrameContext(ctx context.Context, clip Clip, t time.Duration) (image.Image, error) {
+	if getter, ok := clip.(ContextFrameGetter); ok && ctx != nil {
+		return getter.GetFrameContext(ctx, t)
+	}
+	return clip.GetFrame(t)
+}
+
+// TraceRender 在渲染区间内执行 render，ctx 派生自 options.Context 并携带该区间
+//
+// 渲染期间登记为进行中的渲染（见 BeginRender），CancelRenders 会取消 ctx，render 应据此检查取消。
+func TraceRender(options *WriteOptions, clip Clip, filename string, render func(ctx context.Context) error) error {
+	var parent context.Context
+	if options != nil {
+		parent = options.Context
+	}
+	parent, done := BeginRender(parent, clip, filename)
+	defer done()
+	if !Tracing() {
+		return render(parent)
+	}
+	ctx, span := StartSpan(parent, SpanRender, Attr("clip.id", ClipID(clip)), Attr("output", filename))
+	err := render(ctx)
+	EndSpan(span, err)
+	return err
+}
+
+// TraceFrame 取渲染中的第 i 帧，追踪开启时记录帧区间，解码、特效等区间作为其子区间
+func TraceFrame(ctx context.Context, clip Clip, i int, t time.Duration) (image.Image, error) {
+	if !Tracing() {
+		return GetFrameContext(ctx, clip, t)
+	}
+	frameCtx, span := StartSpan(ctx, SpanFrame, Attr("frame", i), Attr("t", t))
+	frame, err := GetFrameContext(frameCtx, clip, t)
+	EndSpan(span, err)
+	return frame, err
+}
+
+// TraceEncode 执行 write（把第 i 帧写入编码器），追踪开启时记录编码区间
+func TraceEncode(ctx context.Context, i int, write func() error) error {
+	if !Tracing() {
+		return write()
+	}
+	_, span := StartSpan(ctx, SpanEncode, Attr("frame", i))
+	err := write()
+	EndSpan(span, err)
+	return err
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// bitratePattern 码率格式：数字加可选的 k/M/G 后缀，如 2000k、2.5M、800000
+var bitratePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kKmMgG]?$`)
+
+// containerCodecs 常见封装格式允许的视频编码器，未列出的扩展名不检查
+var containerCodecs = map[string][]string{
+	".mp4":  {"libx264", "libx265", "h264_nvenc", "hevc_nvenc", "h264_qsv", "hevc_qsv", "h264_videotoolbox", "hevc_videotoolbox", "libaom-av1", "libsvtav1", "mpeg4", "copy"},
+	".mov":  {"libx264", "libx265", "prores", "prores_ks", "h264_videotoolbox", "hevc_videotoolbox", "mjpeg", "copy"},
+	".webm": {"libvpx", "libvpx-vp9", "libaom-av1", "libsvtav1", "copy"},
+	".gif":  {"gif"},
+	".avi":  {"mpeg4", "libxvid", "mjpeg", "libx264", "copy"},
+}
+
+// containerAudioCodecs 常见封装格式允许的音频编码器，第一个为默认编码器；
+// 列表为空的封装（动图、图片）不能包含音频，未列出的扩展名不检查、默认 aac
+var containerAudioCodecs = map[string][]string{
+	".mp4":  {"aac", "libmp3lame", "libopus", "ac3", "alac", "flac", "copy"},
+	".m4v":  {"aac", "ac3", "alac", "copy"},
+	".mov":  {"aac", "alac", "pcm_s16le", "pcm_s24le", "libmp3lame", "ac3", "copy"},
+	".webm": {"libopus", "libvorbis", "copy"},
+	".ogg":  {"libopus", "libvorbis", "flac", "copy"},
+	".ogv":  {"libopus", "libvorbis", "flac", "copy"},
+	".avi":  {"libmp3lame", "ac3", "pcm_s16le", "aac", "copy"},
+	".gif":  {},
+	".apng": {},
+	".png":  {},
+	".jpg":  {},
+	".jpeg": {},
+	".webp": {},
+	".bmp":  {},
+	".tif":  {},
+	".tiff": {},
+}
+
+// DefaultAudioCodec 返回 filename 的封装格式默认的音频编码器，如 webm 为 libopus；封装不能包含音频时 ok 为 false
+func DefaultAudioCodec(filename string) (codec string, ok bool) {
+	codecs, listed := containerAudioCodecs[strings.ToLower(filepath.Ext(filename))]
+	if !listed {
+		return "aac", true
+	}
+	if len(codecs) == 0 {
+		return "", false
+	}
+	return codecs[0], true
+}
+
+// yuv420Codecs 输出 yuv420p 的编码器，要求宽高为偶数
+var yuv420Codecs = map[string]bool{
+	"libx264": true, "libx265": true, "h264_nvenc": true, "hevc_nvenc": true,
+	"h264_qsv": true, "hevc_qsv": true, "libvpx": true, "libvpx-vp9": true, "mpeg4": true,
+}
+
+// crfLimits 各编码器 CRF 的上限，8 位 H.264/HEVC 为 51，VP9、AV1 为 63；未列出的编码器按 63 检查
+var crfLimits = map[string]int{
+	"libx264": 51, "libx265": 51, "h264_nvenc": 51, "hevc_nvenc": 51, "h264_qsv": 51, "hevc_qsv": 51,
+	"libvpx": 63, "libvpx-vp9": 63, "libaom-av1": 63, "libsvtav1": 63,
+}
+
+// Validate 在启动 FFmpeg 前检查写入选项，返回包含 ErrInvalidWriteOptions 或 ErrUnsupportedCodec 的具体错误
+//
+// 检查帧率、码率格式、按编码器的 CRF 范围、yuv420p 要求的偶数尺寸以及封装格式与视频、音频编码器的兼容性；
+// 不能包含音频的封装（如 gif）不检查音频编码器，写入时不混入音轨。
+// 编码器是否可用由 ffmpeg.CheckEncoder 在打开写入器时检查。width/height 为 0 时跳过尺寸检查。
+func (o *WriteOptions) Validate(filename string, width, height int) error {
+	if o.FPS < 0 || o.FPS > 1000 {
+		return fmt.Errorf("%w: 帧率 %g 超出 0–1000", ErrInvalidWriteOptions, o.FPS)
+	}
+	if o.Bitrate != "" && !bitratePattern.MatchString(o.Bitrate) {
+		return fmt.Errorf("%w: 码率 %q 格式错误，应为 2000k、2.5M 等", ErrInvalidWriteOptions, o.Bitrate)
+	}
+	if o.AudioBitrate != "" && !bitratePattern.MatchString(o.AudioBitrate) {
+		return fmt.Errorf("%w: 音频码率 %q 格式错误，应为 128k 等", ErrInvalidWriteOptions, o.AudioBitrate)
+	}
+	// 未指定编码器时各写入器默认使用 libx264
+	codec := o.Codec
+	if codec == "" {
+		codec = "libx264"
+	}
+	crfLimit, ok := crfLimits[codec]
+	if !ok {
+		crfLimit = 63
+	}
+	if o.CRF < 0 || o.CRF > crfLimit {
+		return fmt.Errorf("%w: %s 的 CRF %d 超出 0–%d", ErrInvalidWriteOptions, codec, o.CRF, crfLimit)
+	}
+	if o.Threads < 0 {
+		return fmt.Errorf("%w: 线程数 %d 不能为负", ErrInvalidWriteOptions, o.Threads)
+	}
+	if o.FrameStep < 0 {
+		return fmt.Errorf("%w: FrameStep %d 不能为负", ErrInvalidWriteOptions, o.FrameStep)
+	}
+	switch o.Dither {
+	case "", "none", "ordered", "blue_noise":
+	default:
+		return fmt.Errorf("%w: 未知的抖动方式 %q（支持 none、ordered、blue_noise）", ErrInvalidWriteOptions, o.Dither)
+	}
+
+	if width < 0 || height < 0 {
+		return fmt.Errorf("%w: 无效的尺寸 %dx%d", ErrInvalidWriteOptions, width, height)
+	}
+	if yuv420Codecs[o.Codec] && (width%2 != 0 || height%2 != 0) {
+		return fmt.Errorf("%w: %s 输出 yuv420p 要求宽高为偶数，当前 %dx%d", ErrInvalidWriteOptions, o.Codec, width, height)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if codecs, ok := containerCodecs[ext]; ok && o.Codec != "" {
+		compatible := false
+		for _, codec := range codecs {
+			if codec == o.Codec {
+				compatible = true
+				break
+			}
+		}
+		if !compatible {
+			return fmt.Errorf("%w: %s 封装不支持编码器 %s，可选 %s", ErrUnsupportedCodec, ext, o.Codec, strings.Join(codecs, "、"))
+		}
+	}
+	if codecs := containerAudioCodecs[ext]; len(codecs) > 0 && o.AudioCodec != "" && !slices.Contains(codecs, o.AudioCodec) {
+		return fmt.Errorf("%w: %s 封装不支持音频编码器 %s，可选 %s", ErrUnsupportedCodec, ext, o.AudioCodec, strings.Join(codecs, "、"))
+	}
+	return nil
+}
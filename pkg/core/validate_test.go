@@ -0,0 +1,54 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefaultAudioCodec(t *testing.T) {
+	tests := []struct {
+		filename string
+		codec    string
+		ok       bool
+	}{
+		{"out.mp4", "aac", true},
+		{"out.WEBM", "libopus", true},
+		{"out.ogg", "libopus", true},
+		{"out.mkv", "aac", true},
+		{"out.gif", "", false},
+		{"frame.png", "", false},
+	}
+	for _, tt := range tests {
+		codec, ok := DefaultAudioCodec(tt.filename)
+		if codec != tt.codec || ok != tt.ok {
+			t.Errorf("DefaultAudioCodec(%q) = %q, %v，期望 %q, %v", tt.filename, codec, ok, tt.codec, tt.ok)
+		}
+	}
+}
+
+func TestValidateAudioCodecContainer(t *testing.T) {
+	tests := []struct {
+		filename   string
+		codec      string
+		audioCodec string
+		wantErr    bool
+	}{
+		{"out.mp4", "libx264", "aac", false},
+		{"out.webm", "libvpx-vp9", "aac", true},
+		{"out.webm", "libvpx-vp9", "libopus", false},
+		{"out.mov", "prores", "libvorbis", true},
+		// 不能包含音频的封装写入时丢弃音轨，不检查音频编码器
+		{"out.gif", "gif", "aac", false},
+		{"out.mkv", "libx264", "libvorbis", false},
+	}
+	for _, tt := range tests {
+		options := &WriteOptions{Codec: tt.codec, AudioCodec: tt.audioCodec}
+		err := options.Validate(tt.filename, 16, 16)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s 使用 %s/%s: 错误 %v，期望出错 %v", tt.filename, tt.codec, tt.audioCodec, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrUnsupportedCodec) {
+			t.Errorf("%s 使用 %s: 错误 %v 应包含 ErrUnsupportedCodec", tt.filename, tt.audioCodec, err)
+		}
+	}
+}
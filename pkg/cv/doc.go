@@ -0,0 +1,12 @@
+// Package cv 在剪辑帧与 gocv.Mat 之间转换，并把 OpenCV 操作包装为视频特效
+//
+// 依赖 gocv（cgo + OpenCV 4），默认不参与构建。使用前在项目中添加依赖并以 gocv 标签编译：
+//
+//	go get gocv.io/x/gocv
+//	go build -tags gocv ./...
+//
+// 示例：在渲染中插入人脸检测并标注
+//
+//	detect, err := cv.NewCascadeDetectEffect("haarcascade_frontalface_default.xml", color.RGBA{0, 255, 0, 255}, 2)
+//	clip.AddEffect(detect)
+package cv
@@ -0,0 +1,138 @@
+//go:build gocv
+
+package cv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"moviepy-go/pkg/effects"
+)
+
+// ToMat 将帧转换为 RGBA 顺序的 CV_8UC4 Mat，调用方负责 Close
+//
+// 原点为 (0,0) 且行间无填充的 *image.RGBA 直接共享 Pix 内存（零拷贝），
+// 此时 Mat 只在 frame 存活期间有效，且对 Mat 的写入会改变 frame；其他帧会先复制为 RGBA。
+func ToMat(frame image.Image) (gocv.Mat, error) {
+	rgba, ok := frame.(*image.RGBA)
+	bounds := frame.Bounds()
+	if !ok || bounds.Min != (image.Point{}) || rgba.Stride != bounds.Dx()*4 {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), frame, bounds.Min, draw.Src)
+	}
+	mat, err := gocv.NewMatFromBytes(bounds.Dy(), bounds.Dx(), gocv.MatTypeCV8UC4, rgba.Pix)
+	if err != nil {
+		return gocv.Mat{}, fmt.Errorf("创建 Mat 失败: %w", err)
+	}
+	return mat, nil
+}
+
+// ToMatBGR 将帧复制为 OpenCV 惯用的 BGR 顺序 CV_8UC3 Mat，调用方负责 Close
+func ToMatBGR(frame image.Image) (gocv.Mat, error) {
+	rgba, err := ToMat(frame)
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	defer rgba.Close()
+	bgr := gocv.NewMat()
+	gocv.CvtColor(rgba, &bgr, gocv.ColorRGBAToBGR)
+	return bgr, nil
+}
+
+// FromMat 将 Mat 复制为 *image.RGBA
+//
+// CV_8UC4 视为 RGBA 顺序（与 ToMat 一致），CV_8UC3 视为 BGR，CV_8UC1 视为灰度。
+func FromMat(mat gocv.Mat) (*image.RGBA, error) {
+	if mat.Empty() {
+		return nil, fmt.Errorf("Mat 为空")
+	}
+	rgbaMat := mat
+	switch mat.Type() {
+	case gocv.MatTypeCV8UC4:
+	case gocv.MatTypeCV8UC3:
+		rgbaMat = gocv.NewMat()
+		defer rgbaMat.Close()
+		gocv.CvtColor(mat, &rgbaMat, gocv.ColorBGRToRGBA)
+	case gocv.MatTypeCV8UC1:
+		rgbaMat = gocv.NewMat()
+		defer rgbaMat.Close()
+		gocv.CvtColor(mat, &rgbaMat, gocv.ColorGrayToBGRA)
+	default:
+		return nil, fmt.Errorf("不支持的 Mat 类型: %v", mat.Type())
+	}
+
+	data, err := rgbaMat.DataPtrUint8()
+	if err != nil {
+		return nil, fmt.Errorf("读取 Mat 数据失败: %w", err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, rgbaMat.Cols(), rgbaMat.Rows()))
+	if len(data) < len(img.Pix) {
+		return nil, fmt.Errorf("Mat 数据不连续: %d 字节，需要 %d", len(data), len(img.Pix))
+	}
+	copy(img.Pix, data)
+	return img, nil
+}
+
+// MatOp 对 RGBA 顺序的 src 执行 OpenCV 操作，结果写入 dst（可为 CV_8UC4/CV_8UC3/CV_8UC1）
+type MatOp func(src gocv.Mat, dst *gocv.Mat, t time.Duration) error
+
+// NewMatEffect 将 OpenCV 操作包装为特效，op 不应改变尺寸
+func NewMatEffect(name string, op MatOp) *effects.FuncEffect {
+	return effects.NewFuncEffect(name, func(frame image.Image, t time.Duration) (image.Image, error) {
+		src, err := ToMat(frame)
+		if err != nil {
+			return nil, err
+		}
+		defer src.Close()
+		dst := gocv.NewMat()
+		defer dst.Close()
+		if err := op(src, &dst, t); err != nil {
+			return nil, fmt.Errorf("OpenCV 操作 %s 失败: %w", name, err)
+		}
+		return FromMat(dst)
+	})
+}
+
+// NewCannyEffect Canny 边缘检测，输出白色边缘的灰度画面
+func NewCannyEffect(low, high float32) *effects.FuncEffect {
+	return NewMatEffect("canny", func(src gocv.Mat, dst *gocv.Mat, t time.Duration) error {
+		gray := gocv.NewMat()
+		defer gray.Close()
+		gocv.CvtColor(src, &gray, gocv.ColorRGBAToGray)
+		gocv.Canny(gray, dst, low, high)
+		return nil
+	})
+}
+
+// NewCascadeDetectEffect 用 Haar/LBP 级联分类器检测目标（如人脸）并在帧上画框
+//
+// 分类器不是并发安全的，检测在互斥锁内进行。
+func NewCascadeDetectEffect(cascadeFile string, boxColor color.RGBA, thickness int) (*effects.FuncEffect, error) {
+	classifier := gocv.NewCascadeClassifier()
+	if !classifier.Load(cascadeFile) {
+		classifier.Close()
+		return nil, fmt.Errorf("加载级联分类器 %s 失败", cascadeFile)
+	}
+	var mutex sync.Mutex
+	return NewMatEffect("cascade_detect", func(src gocv.Mat, dst *gocv.Mat, t time.Duration) error {
+		bgr := gocv.NewMat()
+		defer bgr.Close()
+		gocv.CvtColor(src, &bgr, gocv.ColorRGBAToBGR)
+
+		mutex.Lock()
+		rects := classifier.DetectMultiScale(bgr)
+		mutex.Unlock()
+
+		for _, rect := range rects {
+			gocv.Rectangle(&bgr, rect, boxColor, thickness)
+		}
+		bgr.CopyTo(dst)
+		return nil
+	}), nil
+}
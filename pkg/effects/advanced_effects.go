@@ -1,12 +1,15 @@
 package effects
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"math"
 	"math/rand"
+	"time"
 
 	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/pixel"
 )
 
 // BlurEffect 模糊特效
@@ -15,6 +18,11 @@ type BlurEffect struct {
 	radius int // 模糊半径
 }
 
+// CacheKey 返回模糊半径
+func (be *BlurEffect) CacheKey() string {
+	return fmt.Sprintf("blur(%d)", be.radius)
+}
+
 // Apply 应用模糊特效
 func (be *BlurEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了模糊特效
@@ -36,8 +44,24 @@ func NewBlurEffect(radius int) *BlurEffect {
 	}
 }
 
+// Radius 返回模糊半径
+func (be *BlurEffect) Radius() int {
+	return be.radius
+}
+
+// SetRadius 修改模糊半径，限制在 1–20
+func (be *BlurEffect) SetRadius(radius int) {
+	be.radius = max(1, min(radius, 20))
+}
+
 // ApplyToFrame 应用模糊特效到帧
 func (be *BlurEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	if result, ok, err := accelerate(func(backend Backend) (image.Image, error) {
+		return backend.Blur(frame, be.radius)
+	}); ok {
+		return result, err
+	}
+
 	bounds := frame.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
@@ -46,48 +70,70 @@ func (be *BlurEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 	dst := image.NewRGBA(image.Rect(0, 0, width, height))
 
 	// 应用高斯模糊
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			var sumR, sumG, sumB, sumA uint32
-			var count int
-
-			// 在模糊半径内采样
-			for dy := -be.radius; dy <= be.radius; dy++ {
-				for dx := -be.radius; dx <= be.radius; dx++ {
-					srcX := x + dx
-					srcY := y + dy
-
-					// 检查边界
-					if srcX >= 0 && srcX < width && srcY >= 0 && srcY < height {
-						r, g, b, a := frame.At(srcX, srcY).RGBA()
-						sumR += r
-						sumG += g
-						sumB += b
-						sumA += a
-						count++
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				var sumR, sumG, sumB, sumA uint32
+				var count int
+
+				// 在模糊半径内采样
+				for dy := -be.radius; dy <= be.radius; dy++ {
+					for dx := -be.radius; dx <= be.radius; dx++ {
+						srcX := x + dx
+						srcY := y + dy
+
+						// 检查边界
+						if srcX >= 0 && srcX < width && srcY >= 0 && srcY < height {
+							r, g, b, a := frame.At(bounds.Min.X+srcX, bounds.Min.Y+srcY).RGBA()
+							sumR += r
+							sumG += g
+							sumB += b
+							sumA += a
+							count++
+						}
 					}
 				}
-			}
 
-			// 计算平均值
-			if count > 0 {
-				dst.Set(x, y, color.RGBA{
-					R: uint8(sumR / uint32(count) >> 8),
-					G: uint8(sumG / uint32(count) >> 8),
-					B: uint8(sumB / uint32(count) >> 8),
-					A: uint8(sumA / uint32(count) >> 8),
-				})
+				// 计算平均值
+				if count > 0 {
+					dst.Set(x, y, color.RGBA{
+						R: uint8(sumR / uint32(count) >> 8),
+						G: uint8(sumG / uint32(count) >> 8),
+						B: uint8(sumB / uint32(count) >> 8),
+						A: uint8(sumA / uint32(count) >> 8),
+					})
+				}
 			}
 		}
-	}
+	})
 
 	return dst, nil
 }
 
-// SharpenEffect 锐化特效
+// ApplyToFrameLinear 在线性光下做与 ApplyToFrame 相同的均值模糊，亮暗交界处的模糊边缘不再偏暗
+func (be *BlurEffect) ApplyToFrameLinear(frame image.Image) (image.Image, error) {
+	return toLinear(frame).boxBlur(be.radius).fromLinear(), nil
+}
+
+// ApplyToFrame64 以 16 位精度做与 ApplyToFrame 相同的均值模糊
+func (be *BlurEffect) ApplyToFrame64(frame image.Image, t time.Duration) (image.Image, error) {
+	return toFrame16(frame).boxBlur(be.radius).toRGBA64(), nil
+}
+
+// SharpenEffect 锐化特效，按反锐化掩模（unsharp mask）实现：out = in + amount·(in − blur(in))
+//
+// 差值在非预乘颜色上计算，alpha 保持不变，透明边缘不会出现光晕或改变透明度；
+// 与模糊结果相差不超过 threshold 的像素不锐化，避免放大平坦区域的噪点。
 type SharpenEffect struct {
 	TransformEffect
-	strength float64 // 锐化强度
+	amount    float64 // 锐化强度，0 表示不变
+	radius    float64 // 模糊的高斯标准差（像素）
+	threshold float64 // 不锐化的差值上限（0–1）
+}
+
+// CacheKey 返回锐化参数
+func (se *SharpenEffect) CacheKey() string {
+	return fmt.Sprintf("sharpen(%g,%g,%g)", se.amount, se.radius, se.threshold)
 }
 
 // Apply 应用锐化特效
@@ -97,105 +143,82 @@ func (se *SharpenEffect) Apply(clip core.Clip) (core.Clip, error) {
 	return clip, nil
 }
 
-// NewSharpenEffect 创建锐化特效
+// NewSharpenEffect 创建锐化特效，strength（0–2）为强度，等价于 NewUnsharpMaskEffect(strength, 1, 0)
 func NewSharpenEffect(strength float64) *SharpenEffect {
-	if strength < 0 {
-		strength = 0
-	}
-	if strength > 2 {
-		strength = 2
-	}
+	return NewUnsharpMaskEffect(max(0, min(strength, 2)), 1, 0)
+}
+
+// NewUnsharpMaskEffect 创建反锐化掩模特效
+//
+// amount 为强度（常用 0.5–2，不小于 0），radius 为模糊的高斯标准差（0.3–20 像素），threshold 为
+// 不锐化的差值上限（0–1，如 0.02 约为 8 位下的 5 级）。
+func NewUnsharpMaskEffect(amount, radius, threshold float64) *SharpenEffect {
 	return &SharpenEffect{
 		TransformEffect: TransformEffect{name: "sharpen"},
-		strength:        strength,
+		amount:          max(amount, 0),
+		radius:          max(0.3, min(radius, 20)),
+		threshold:       clamp01(threshold),
 	}
 }
 
-// ApplyToFrame 应用锐化特效到帧
-func (se *SharpenEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
-	bounds := frame.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// 创建新图像
-	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+// Amount 返回锐化强度
+func (se *SharpenEffect) Amount() float64 {
+	return se.amount
+}
 
-	// 锐化卷积核
-	kernel := [3][3]float64{
-		{0, -1, 0},
-		{-1, 5, -1},
-		{0, -1, 0},
-	}
+// SetAmount 修改锐化强度，通过 EffectVideoClip.UpdateEffect 调用以避免与渲染并发
+func (se *SharpenEffect) SetAmount(amount float64) {
+	se.amount = max(amount, 0)
+}
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			var sumR, sumG, sumB, sumA float64
-
-			// 应用卷积核
-			for ky := -1; ky <= 1; ky++ {
-				for kx := -1; kx <= 1; kx++ {
-					srcX := x + kx
-					srcY := y + ky
-
-					// 边界处理
-					if srcX < 0 {
-						srcX = 0
-					} else if srcX >= width {
-						srcX = width - 1
-					}
-					if srcY < 0 {
-						srcY = 0
-					} else if srcY >= height {
-						srcY = height - 1
-					}
+// Radius 返回模糊的高斯标准差
+func (se *SharpenEffect) Radius() float64 {
+	return se.radius
+}
 
-					r, g, b, a := frame.At(srcX, srcY).RGBA()
-					weight := kernel[ky+1][kx+1] * se.strength
+// Threshold 返回不锐化的差值上限
+func (se *SharpenEffect) Threshold() float64 {
+	return se.threshold
+}
 
-					sumR += float64(r) * weight
-					sumG += float64(g) * weight
-					sumB += float64(b) * weight
-					sumA += float64(a) * weight
-				}
-			}
+// ApplyToFrame 应用锐化特效到帧
+func (se *SharpenEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	return se.sharpen(frame).toRGBA(), nil
+}
 
-			// 确保值在有效范围内
-			r := int(sumR)
-			g := int(sumG)
-			b := int(sumB)
-			a := int(sumA)
+// ApplyToFrame64 以 16 位精度应用锐化特效
+func (se *SharpenEffect) ApplyToFrame64(frame image.Image, t time.Duration) (image.Image, error) {
+	return se.sharpen(frame).toRGBA64(), nil
+}
 
-			if r < 0 {
-				r = 0
-			} else if r > 65535 {
-				r = 65535
-			}
-			if g < 0 {
-				g = 0
-			} else if g > 65535 {
-				g = 65535
-			}
-			if b < 0 {
-				b = 0
-			} else if b > 65535 {
-				b = 65535
+// sharpen 在 16 位精度下计算反锐化掩模
+func (se *SharpenEffect) sharpen(frame image.Image) *frame16 {
+	src := toFrame16(frame)
+	blurred := src.gaussianBlur(se.radius)
+	dst := newFrame16(src.width, src.height)
+	threshold := se.threshold * 65535
+	Parallel(src.height, func(y0, y1 int) {
+		for i := y0 * src.width * 4; i < y1*src.width*4; i += 4 {
+			a, blurA := float64(src.pix[i+3]), float64(blurred.pix[i+3])
+			dst.pix[i+3] = src.pix[i+3]
+			if a == 0 {
+				continue
 			}
-			if a < 0 {
-				a = 0
-			} else if a > 65535 {
-				a = 65535
+			for k := 0; k < 3; k++ {
+				// 反预乘后比较，模糊结果按其自身的 alpha 反预乘，透明的邻居不会把边缘拉暗
+				c := min(float64(src.pix[i+k])*65535/a, 65535)
+				var b float64
+				if blurA > 0 {
+					b = min(float64(blurred.pix[i+k])*65535/blurA, 65535)
+				}
+				if d := c - b; math.Abs(d) > threshold {
+					c = max(0, min(c+se.amount*d, 65535))
+				}
+				dst.pix[i+k] = uint16(c*a/65535 + 0.5)
 			}
-
-			dst.Set(x, y, color.RGBA{
-				R: uint8(r >> 8),
-				G: uint8(g >> 8),
-				B: uint8(b >> 8),
-				A: uint8(a >> 8),
-			})
 		}
-	}
-
-	return dst, nil
+	})
+	return dst
 }
 
 // SaturationEffect 饱和度调整特效
@@ -204,6 +227,11 @@ type SaturationEffect struct {
 	factor float64 // 饱和度因子，1.0为正常，>1.0为更高饱和度，<1.0为更低饱和度
 }
 
+// CacheKey 返回饱和度因子
+func (se *SaturationEffect) CacheKey() string {
+	return fmt.Sprintf("saturation(%g)", se.factor)
+}
+
 // Apply 应用饱和度调整特效
 func (se *SaturationEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了饱和度调整特效
@@ -219,59 +247,34 @@ func NewSaturationEffect(factor float64) *SaturationEffect {
 	}
 }
 
-// ApplyToFrame 应用饱和度调整特效到帧
-func (se *SaturationEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
-	bounds := frame.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// 创建新图像
-	dst := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := frame.At(x, y).RGBA()
-
-			// 转换为HSL
-			rf := float64(r) / 65535.0
-			gf := float64(g) / 65535.0
-			bf := float64(b) / 65535.0
-
-			// 计算亮度
-			luminance := 0.299*rf + 0.587*gf + 0.114*bf
-
-			// 调整饱和度
-			newR := luminance + (rf-luminance)*se.factor
-			newG := luminance + (gf-luminance)*se.factor
-			newB := luminance + (bf-luminance)*se.factor
+// Factor 返回饱和度因子
+func (se *SaturationEffect) Factor() float64 {
+	return se.factor
+}
 
-			// 确保值在0-1范围内
-			if newR < 0 {
-				newR = 0
-			} else if newR > 1 {
-				newR = 1
-			}
-			if newG < 0 {
-				newG = 0
-			} else if newG > 1 {
-				newG = 1
-			}
-			if newB < 0 {
-				newB = 0
-			} else if newB > 1 {
-				newB = 1
-			}
+// SetFactor 修改饱和度因子
+func (se *SaturationEffect) SetFactor(factor float64) {
+	se.factor = factor
+}
 
-			dst.Set(x, y, color.RGBA{
-				R: uint8(newR * 255),
-				G: uint8(newG * 255),
-				B: uint8(newB * 255),
-				A: uint8(a >> 8),
-			})
-		}
+// ApplyToFrame 应用饱和度调整特效到帧
+func (se *SaturationEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	if result, ok, err := accelerate(func(backend Backend) (image.Image, error) {
+		return backend.Adjust(frame, ColorAdjust{Brightness: 1, Contrast: 1, Saturation: se.factor})
+	}); ok {
+		return result, err
 	}
 
-	return dst, nil
+	return parallelKernel(frame, func(dst, src []byte) {
+		pixel.Saturation(dst, src, se.factor)
+	}), nil
+}
+
+// ApplyToFrame64 以 16 位精度调整饱和度
+func (se *SaturationEffect) ApplyToFrame64(frame image.Image, t time.Duration) (image.Image, error) {
+	return parallelKernel64(frame, func(dst, src []byte) {
+		pixel.Saturation64(dst, src, se.factor)
+	}), nil
 }
 
 // NoiseEffect 噪点特效
@@ -310,43 +313,45 @@ func (ne *NoiseEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 	// 创建新图像
 	dst := image.NewRGBA(image.Rect(0, 0, width, height))
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := frame.At(x, y).RGBA()
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b, a := frame.At(x, y).RGBA()
 
-			// 生成随机噪点
-			noise := (rand.Float64() - 0.5) * 2 * ne.intensity
+				// 生成随机噪点
+				noise := (rand.Float64() - 0.5) * 2 * ne.intensity
 
-			// 应用噪点
-			newR := float64(r)/65535.0 + noise
-			newG := float64(g)/65535.0 + noise
-			newB := float64(b)/65535.0 + noise
+				// 应用噪点
+				newR := float64(r)/65535.0 + noise
+				newG := float64(g)/65535.0 + noise
+				newB := float64(b)/65535.0 + noise
 
-			// 确保值在0-1范围内
-			if newR < 0 {
-				newR = 0
-			} else if newR > 1 {
-				newR = 1
-			}
-			if newG < 0 {
-				newG = 0
-			} else if newG > 1 {
-				newG = 1
-			}
-			if newB < 0 {
-				newB = 0
-			} else if newB > 1 {
-				newB = 1
-			}
+				// 确保值在0-1范围内
+				if newR < 0 {
+					newR = 0
+				} else if newR > 1 {
+					newR = 1
+				}
+				if newG < 0 {
+					newG = 0
+				} else if newG > 1 {
+					newG = 1
+				}
+				if newB < 0 {
+					newB = 0
+				} else if newB > 1 {
+					newB = 1
+				}
 
-			dst.Set(x, y, color.RGBA{
-				R: uint8(newR * 255),
-				G: uint8(newG * 255),
-				B: uint8(newB * 255),
-				A: uint8(a >> 8),
-			})
+				dst.Set(x, y, color.RGBA{
+					R: uint8(newR * 255),
+					G: uint8(newG * 255),
+					B: uint8(newB * 255),
+					A: uint8(a >> 8),
+				})
+			}
 		}
-	}
+	})
 
 	return dst, nil
 }
@@ -357,6 +362,11 @@ type SepiaEffect struct {
 	strength float64 // 棕褐色强度，0.0为原色，1.0为完全棕褐色
 }
 
+// CacheKey 返回棕褐色强度
+func (se *SepiaEffect) CacheKey() string {
+	return fmt.Sprintf("sepia(%g)", se.strength)
+}
+
 // Apply 应用棕褐色特效
 func (se *SepiaEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了棕褐色特效
@@ -387,42 +397,44 @@ func (se *SepiaEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 	// 创建新图像
 	dst := image.NewRGBA(image.Rect(0, 0, width, height))
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := frame.At(x, y).RGBA()
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b, a := frame.At(x, y).RGBA()
 
-			// 转换为灰度
-			gray := 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+				// 转换为灰度
+				gray := 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
 
-			// 应用棕褐色滤镜
-			sepiaR := gray*0.393 + gray*0.769 + gray*0.189
-			sepiaG := gray*0.349 + gray*0.686 + gray*0.168
-			sepiaB := gray*0.272 + gray*0.534 + gray*0.131
+				// 应用棕褐色滤镜
+				sepiaR := gray*0.393 + gray*0.769 + gray*0.189
+				sepiaG := gray*0.349 + gray*0.686 + gray*0.168
+				sepiaB := gray*0.272 + gray*0.534 + gray*0.131
 
-			// 混合原色和棕褐色
-			finalR := float64(r)*(1-se.strength) + sepiaR*se.strength
-			finalG := float64(g)*(1-se.strength) + sepiaG*se.strength
-			finalB := float64(b)*(1-se.strength) + sepiaB*se.strength
+				// 混合原色和棕褐色
+				finalR := float64(r)*(1-se.strength) + sepiaR*se.strength
+				finalG := float64(g)*(1-se.strength) + sepiaG*se.strength
+				finalB := float64(b)*(1-se.strength) + sepiaB*se.strength
 
-			// 确保值在有效范围内
-			if finalR > 65535 {
-				finalR = 65535
-			}
-			if finalG > 65535 {
-				finalG = 65535
-			}
-			if finalB > 65535 {
-				finalB = 65535
-			}
+				// 确保值在有效范围内
+				if finalR > 65535 {
+					finalR = 65535
+				}
+				if finalG > 65535 {
+					finalG = 65535
+				}
+				if finalB > 65535 {
+					finalB = 65535
+				}
 
-			dst.Set(x, y, color.RGBA{
-				R: uint8(finalR / 256),
-				G: uint8(finalG / 256),
-				B: uint8(finalB / 256),
-				A: uint8(a >> 8),
-			})
+				dst.Set(x, y, color.RGBA{
+					R: uint8(finalR / 256),
+					G: uint8(finalG / 256),
+					B: uint8(finalB / 256),
+					A: uint8(a >> 8),
+				})
+			}
 		}
-	}
+	})
 
 	return dst, nil
 }
@@ -434,6 +446,11 @@ type VignetteEffect struct {
 	radius   float64 // 暗角半径，0.0为中心点，1.0为整个图像
 }
 
+// CacheKey 返回暗角强度和半径
+func (ve *VignetteEffect) CacheKey() string {
+	return fmt.Sprintf("vignette(%g,%g)", ve.strength, ve.radius)
+}
+
 // Apply 应用暗角特效
 func (ve *VignetteEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了暗角特效
@@ -468,43 +485,121 @@ func (ve *VignetteEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 	width := bounds.Dx()
 	height := bounds.Dy()
 
-	// 创建新图像
-	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	// 输出 16 位图像，保留平滑渐变的精度，由写入器截断或抖动到 8 位
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
 
 	// 计算中心点
 	centerX := float64(width) / 2.0
 	centerY := float64(height) / 2.0
 	maxDistance := math.Sqrt(centerX*centerX+centerY*centerY) * ve.radius
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := frame.At(x, y).RGBA()
-
-			// 计算到中心的距离
-			dx := float64(x) - centerX
-			dy := float64(y) - centerY
-			distance := math.Sqrt(dx*dx + dy*dy)
-
-			// 计算暗角因子
-			vignetteFactor := 1.0
-			if distance > 0 {
-				vignetteFactor = 1.0 - (distance/maxDistance)*ve.strength
-				if vignetteFactor < 0 {
-					vignetteFactor = 0
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b, a := frame.At(x, y).RGBA()
+
+				// 计算到中心的距离
+				dx := float64(x) - centerX
+				dy := float64(y) - centerY
+				distance := math.Sqrt(dx*dx + dy*dy)
+
+				// 计算暗角因子
+				vignetteFactor := 1.0
+				if distance > 0 {
+					vignetteFactor = 1.0 - (distance/maxDistance)*ve.strength
+					if vignetteFactor < 0 {
+						vignetteFactor = 0
+					}
+				}
+
+				// 应用暗角
+				newR := uint32(float64(r) * vignetteFactor)
+				newG := uint32(float64(g) * vignetteFactor)
+				newB := uint32(float64(b) * vignetteFactor)
+
+				dst.SetRGBA64(x, y, color.RGBA64{
+					R: uint16(newR),
+					G: uint16(newG),
+					B: uint16(newB),
+					A: uint16(a),
+				})
+			}
+		}
+	})
+
+	return dst, nil
+}
+
+// ApplyToFrame64 ApplyToFrame 本身以 16 位精度计算并输出 *image.RGBA64
+func (ve *VignetteEffect) ApplyToFrame64(frame image.Image, t time.Duration) (image.Image, error) {
+	return ve.ApplyToFrame(frame)
+}
+
+// PixelateEffect 马赛克特效，常配合 RegionEffect 遮挡人脸、车牌
+type PixelateEffect struct {
+	TransformEffect
+	blockSize int // 马赛克块大小（像素）
+}
+
+// CacheKey 返回马赛克块大小
+func (pe *PixelateEffect) CacheKey() string {
+	return fmt.Sprintf("pixelate(%d)", pe.blockSize)
+}
+
+// Apply 应用马赛克特效
+func (pe *PixelateEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 这里应该返回一个新的剪辑，应用了马赛克特效
+	// 简化实现，直接返回原剪辑
+	return clip, nil
+}
+
+// NewPixelateEffect 创建马赛克特效
+func NewPixelateEffect(blockSize int) *PixelateEffect {
+	if blockSize < 2 {
+		blockSize = 2
+	}
+	return &PixelateEffect{
+		TransformEffect: TransformEffect{name: "pixelate"},
+		blockSize:       blockSize,
+	}
+}
+
+// ApplyToFrame 应用马赛克特效到帧，每块取平均色
+func (pe *PixelateEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	bounds := frame.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for by := 0; by < height; by += pe.blockSize {
+		for bx := 0; bx < width; bx += pe.blockSize {
+			maxX := min(bx+pe.blockSize, width)
+			maxY := min(by+pe.blockSize, height)
+
+			var sumR, sumG, sumB, sumA uint32
+			for y := by; y < maxY; y++ {
+				for x := bx; x < maxX; x++ {
+					r, g, b, a := frame.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+					sumR += r >> 8
+					sumG += g >> 8
+					sumB += b >> 8
+					sumA += a >> 8
 				}
 			}
 
-			// 应用暗角
-			newR := uint32(float64(r) * vignetteFactor)
-			newG := uint32(float64(g) * vignetteFactor)
-			newB := uint32(float64(b) * vignetteFactor)
-
-			dst.Set(x, y, color.RGBA{
-				R: uint8(newR >> 8),
-				G: uint8(newG >> 8),
-				B: uint8(newB >> 8),
-				A: uint8(a >> 8),
-			})
+			count := uint32((maxX - bx) * (maxY - by))
+			c := color.RGBA{
+				R: uint8(sumR / count),
+				G: uint8(sumG / count),
+				B: uint8(sumB / count),
+				A: uint8(sumA / count),
+			}
+			for y := by; y < maxY; y++ {
+				for x := bx; x < maxX; x++ {
+					dst.SetRGBA(x, y, c)
+				}
+			}
 		}
 	}
 
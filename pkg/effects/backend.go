@@ -0,0 +1,145 @@
+package effects
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+	"sync"
+)
+
+// ErrBackendUnsupported 后端不支持该操作或输入格式，调用方回退到内置 CPU 实现
+var ErrBackendUnsupported = errors.New("后端不支持该操作")
+
+// CPUBackend 内置 CPU 实现的名称，始终可用
+const CPUBackend = "cpu"
+
+// Backend 像素特效的加速后端（如 OpenGL/Vulkan/Metal 计算着色器）
+//
+// 后端实现位于独立的包中（如 pkg/effects/opengl），通过构建标签编译并在 init 中调用 RegisterBackend 注册，
+// 本包不依赖任何 GPU 绑定。任一方法返回 ErrBackendUnsupported 时该次调用回退到 CPU。
+type Backend interface {
+	// Name 后端名称
+	Name() string
+
+	// Blur 半径 radius 的均值模糊，与 BlurEffect 语义一致
+	Blur(frame image.Image, radius int) (image.Image, error)
+
+	// Adjust 依次应用亮度、对比度、饱和度调整，因子为 1 表示不变
+	Adjust(frame image.Image, adjust ColorAdjust) (image.Image, error)
+
+	// ApplyLUT 三线性插值应用 3D LUT
+	ApplyLUT(frame image.Image, lut *LUT) (image.Image, error)
+
+	// Scale 缩放到 width x height
+	Scale(frame image.Image, width, height int) (image.Image, error)
+
+	// Close 释放设备资源
+	Close() error
+}
+
+// ColorAdjust 颜色调整参数
+type ColorAdjust struct {
+	Brightness float64
+	Contrast   float64
+	Saturation float64
+}
+
+// BackendFactory 创建后端，设备不可用时返回错误
+type BackendFactory func() (Backend, error)
+
+// 已注册的后端与当前后端，current 为 nil 表示使用内置 CPU 实现
+var (
+	backendMutex     sync.RWMutex
+	backendFactories = map[string]BackendFactory{}
+	current          Backend
+)
+
+// RegisterBackend 注册加速后端，名称重复时 panic
+func RegisterBackend(name string, factory BackendFactory) {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+	if name == CPUBackend {
+		panic("effects: 后端名称 cpu 为内置保留")
+	}
+	if _, exists := backendFactories[name]; exists {
+		panic("effects: 后端 " + name + " 重复注册")
+	}
+	backendFactories[name] = factory
+}
+
+// Backends 返回可选的后端名称（已排序，包含 cpu）
+func Backends() []string {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	return backendNames()
+}
+
+// UseBackend 切换当前后端，创建失败时返回错误并保持 CPU 实现；name 为 cpu 时关闭已有后端
+func UseBackend(name string) error {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+
+	var next Backend
+	if name != CPUBackend {
+		factory, ok := backendFactories[name]
+		if !ok {
+			resetBackend()
+			return fmt.Errorf("未注册的特效后端: %s（可用 %v）", name, backendNames())
+		}
+		backend, err := factory()
+		if err != nil {
+			resetBackend()
+			return fmt.Errorf("初始化特效后端 %s 失败，使用 CPU: %w", name, err)
+		}
+		next = backend
+	}
+	resetBackend()
+	current = next
+	return nil
+}
+
+// CurrentBackend 返回当前后端名称
+func CurrentBackend() string {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	if current == nil {
+		return CPUBackend
+	}
+	return current.Name()
+}
+
+// resetBackend 关闭当前后端并回到 CPU，调用方需持有写锁
+func resetBackend() {
+	if current != nil {
+		current.Close()
+		current = nil
+	}
+}
+
+// backendNames 已注册的后端名称，调用方需持有锁
+func backendNames() []string {
+	names := []string{CPUBackend}
+	for name := range backendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// accelerate 在当前后端上执行 op，ok 为 false 时调用方使用 CPU 实现
+func accelerate(op func(backend Backend) (image.Image, error)) (image.Image, bool, error) {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	if current == nil {
+		return nil, false, nil
+	}
+	result, err := op(current)
+	if errors.Is(err, ErrBackendUnsupported) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("%s 后端: %w", current.Name(), err)
+	}
+	return result, true, nil
+}
@@ -0,0 +1,276 @@
+package effects
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// ZoomCalloutOptions 放大镜插图选项，零值使用默认值
+type ZoomCalloutOptions struct {
+	// Center 插图中心在帧中的位置，零值时放在与区域相对的一侧
+	Center image.Point
+	// Transition 插图从区域处放大展开、结束时收回的时长，默认 300ms
+	Transition time.Duration
+	// BorderColor 插图和原区域的边框颜色，默认白色
+	BorderColor color.Color
+	// BorderWidth 边框宽度，默认 2，小于 0 表示不画边框和连线
+	BorderWidth int
+	// Lines 从原区域向插图画连接线
+	Lines bool
+}
+
+// ZoomCalloutEffect 在 [start, end] 内把帧中的一块区域放大显示为插图，常用于教程和录屏讲解
+//
+// 插图在 Transition 内从原区域处缓动展开到目标位置，结束前收回；区域外和时间段外帧保持不变。
+type ZoomCalloutEffect struct {
+	TransformEffect
+	region     image.Rectangle
+	zoom       float64
+	start, end time.Duration
+	options    ZoomCalloutOptions
+}
+
+// NewZoomCalloutEffect 创建带边框的放大镜插图特效
+func NewZoomCalloutEffect(region image.Rectangle, zoom float64, start, end time.Duration) *ZoomCalloutEffect {
+	return NewZoomCalloutEffectWithOptions(region, zoom, start, end, nil)
+}
+
+// NewZoomCalloutEffectWithOptions 使用指定选项创建放大镜插图特效
+func NewZoomCalloutEffectWithOptions(region image.Rectangle, zoom float64, start, end time.Duration, options *ZoomCalloutOptions) *ZoomCalloutEffect {
+	opts := ZoomCalloutOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Transition <= 0 {
+		opts.Transition = 300 * time.Millisecond
+	}
+	if opts.BorderColor == nil {
+		opts.BorderColor = color.White
+	}
+	if opts.BorderWidth == 0 {
+		opts.BorderWidth = 2
+	}
+	if zoom <= 0 {
+		zoom = 2
+	}
+	return &ZoomCalloutEffect{
+		TransformEffect: TransformEffect{name: "zoom_callout"},
+		region:          region.Canon(),
+		zoom:            zoom,
+		start:           start,
+		end:             end,
+		options:         opts,
+	}
+}
+
+// CacheKey 返回区域、倍数、时间段和选项
+func (ze *ZoomCalloutEffect) CacheKey() string {
+	return fmt.Sprintf("zoom_callout(%v,%g,%v,%v,%v,%v,%s,%d,%t)", ze.region, ze.zoom, ze.start, ze.end,
+		ze.options.Center, ze.options.Transition, colorKey(ze.options.BorderColor), ze.options.BorderWidth, ze.options.Lines)
+}
+
+// Apply 应用放大镜插图特效
+func (ze *ZoomCalloutEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 插图按时间出现，由 EffectVideoClip 调用 ApplyToFrameAt
+	return clip, nil
+}
+
+// ApplyToFrame 使用 t=0 处的插图
+func (ze *ZoomCalloutEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	return ze.ApplyToFrameAt(frame, 0)
+}
+
+// ApplyToFrameAt 在时间 t 处叠加插图
+func (ze *ZoomCalloutEffect) ApplyToFrameAt(frame image.Image, t time.Duration) (image.Image, error) {
+	bounds := frame.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	region := ze.region.Intersect(image.Rect(0, 0, width, height))
+	if t < ze.start || t > ze.end || region.Empty() {
+		return frame, nil
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), frame, bounds.Min, draw.Src)
+
+	// 展开与收回的进度，时间段短于两倍过渡时各占一半
+	transition := min(ze.options.Transition, (ze.end-ze.start)/2)
+	progress := 1.0
+	if transition > 0 {
+		progress = math.Min(float64(t-ze.start), float64(ze.end-t)) / float64(transition)
+		progress = math.Max(0, math.Min(1, progress))
+	}
+	progress = progress * progress * (3 - 2*progress)
+
+	target := ze.target(region, width, height)
+	inset := image.Rect(
+		lerpInt(region.Min.X, target.Min.X, progress), lerpInt(region.Min.Y, target.Min.Y, progress),
+		lerpInt(region.Max.X, target.Max.X, progress), lerpInt(region.Max.Y, target.Max.Y, progress),
+	)
+
+	// 先从原帧取区域，避免插图覆盖原区域时读到已放大的像素
+	src := image.NewRGBA(image.Rect(0, 0, region.Dx(), region.Dy()))
+	draw.Draw(src, src.Bounds(), dst, region.Min, draw.Src)
+
+	if ze.options.BorderWidth > 0 {
+		c := ze.options.BorderColor
+		lw := float64(ze.options.BorderWidth)
+		strokeRect(dst, region, lw, c)
+		if ze.options.Lines {
+			for _, segment := range calloutLines(region, inset) {
+				strokeSegment(dst, segment, lw/2+0.5, c)
+			}
+		}
+	}
+	scaleBilinear(dst, inset, src)
+	if ze.options.BorderWidth > 0 {
+		strokeRect(dst, inset, float64(ze.options.BorderWidth), ze.options.BorderColor)
+	}
+	return dst, nil
+}
+
+// target 返回插图最终的矩形：区域放大 zoom 倍，未指定中心时放在与区域相对的半边，并限制在帧内
+func (ze *ZoomCalloutEffect) target(region image.Rectangle, width, height int) image.Rectangle {
+	w := min(int(math.Round(float64(region.Dx())*ze.zoom)), width)
+	h := min(int(math.Round(float64(region.Dy())*ze.zoom)), height)
+	center := ze.options.Center
+	if center == (image.Point{}) {
+		rc := region.Min.Add(region.Max).Div(2)
+		center = image.Pt(width/4, height/4)
+		if rc.X < width/2 {
+			center.X = width * 3 / 4
+		}
+		if rc.Y < height/2 {
+			center.Y = height * 3 / 4
+		}
+	}
+	x := max(0, min(center.X-w/2, width-w))
+	y := max(0, min(center.Y-h/2, height-h))
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// calloutLines 返回连接两个矩形对应角、且不穿过任一矩形的线段，即两矩形凸包的侧边
+func calloutLines(a, b image.Rectangle) [][4]float64 {
+	corners := func(r image.Rectangle) [4][2]float64 {
+		x0, y0, x1, y1 := float64(r.Min.X), float64(r.Min.Y), float64(r.Max.X), float64(r.Max.Y)
+		return [4][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+	}
+	ca, cb := corners(a), corners(b)
+	var all [][2]float64
+	all = append(append(all, ca[:]...), cb[:]...)
+
+	var lines [][4]float64
+	for i := range ca {
+		p, q := ca[i], cb[i]
+		if p == q {
+			continue
+		}
+		// 所有角都在线段所在直线的同一侧时，线段才是凸包的边
+		var below, above bool
+		for _, c := range all {
+			cross := (q[0]-p[0])*(c[1]-p[1]) - (q[1]-p[1])*(c[0]-p[0])
+			below = below || cross < -1e-9
+			above = above || cross > 1e-9
+		}
+		if !(below && above) {
+			lines = append(lines, [4]float64{p[0], p[1], q[0], q[1]})
+		}
+	}
+	return lines
+}
+
+// scaleBilinear 把 src 双线性缩放到 dst 的 rect 区域
+func scaleBilinear(dst *image.RGBA, rect image.Rectangle, src *image.RGBA) {
+	area := rect.Intersect(dst.Bounds())
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if area.Empty() || sw == 0 || sh == 0 {
+		return
+	}
+	scaleX := float64(sw) / float64(rect.Dx())
+	scaleY := float64(sh) / float64(rect.Dy())
+	Parallel(area.Dy(), func(y0, y1 int) {
+		for y := area.Min.Y + y0; y < area.Min.Y+y1; y++ {
+			fy := math.Max(0, (float64(y-rect.Min.Y)+0.5)*scaleY-0.5)
+			iy := min(int(fy), sh-1)
+			iy1 := min(iy+1, sh-1)
+			wy := fy - float64(iy)
+			for x := area.Min.X; x < area.Max.X; x++ {
+				fx := math.Max(0, (float64(x-rect.Min.X)+0.5)*scaleX-0.5)
+				ix := min(int(fx), sw-1)
+				ix1 := min(ix+1, sw-1)
+				wx := fx - float64(ix)
+
+				p00 := src.Pix[src.PixOffset(ix, iy):]
+				p10 := src.Pix[src.PixOffset(ix1, iy):]
+				p01 := src.Pix[src.PixOffset(ix, iy1):]
+				p11 := src.Pix[src.PixOffset(ix1, iy1):]
+				d := dst.Pix[dst.PixOffset(x, y):]
+				for c := 0; c < 4; c++ {
+					top := float64(p00[c])*(1-wx) + float64(p10[c])*wx
+					bottom := float64(p01[c])*(1-wx) + float64(p11[c])*wx
+					d[c] = uint8(top*(1-wy) + bottom*wy + 0.5)
+				}
+			}
+		}
+	})
+}
+
+// strokeRect 沿矩形内侧画宽 lineWidth 的边框
+func strokeRect(dst *image.RGBA, rect image.Rectangle, lineWidth float64, c color.Color) {
+	w := int(math.Ceil(lineWidth))
+	src := image.NewUniform(c)
+	for _, edge := range []image.Rectangle{
+		image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Min.Y+w),
+		image.Rect(rect.Min.X, rect.Max.Y-w, rect.Max.X, rect.Max.Y),
+		image.Rect(rect.Min.X, rect.Min.Y+w, rect.Min.X+w, rect.Max.Y-w),
+		image.Rect(rect.Max.X-w, rect.Min.Y+w, rect.Max.X, rect.Max.Y-w),
+	} {
+		draw.Draw(dst, edge.Intersect(rect), src, image.Point{}, draw.Over)
+	}
+}
+
+// strokeSegment 画半宽为 half 的抗锯齿线段，segment 为 x0, y0, x1, y1
+func strokeSegment(dst *image.RGBA, segment [4]float64, half float64, c color.Color) {
+	x0, y0, x1, y1 := segment[0], segment[1], segment[2], segment[3]
+	area := image.Rect(
+		int(math.Floor(math.Min(x0, x1)-half-1)), int(math.Floor(math.Min(y0, y1)-half-1)),
+		int(math.Ceil(math.Max(x0, x1)+half+1)), int(math.Ceil(math.Max(y0, y1)+half+1)),
+	).Intersect(dst.Bounds())
+	r, g, b, a := c.RGBA()
+	if area.Empty() || a == 0 {
+		return
+	}
+	dx, dy := x1-x0, y1-y0
+	length := dx*dx + dy*dy
+	Parallel(area.Dy(), func(ya, yb int) {
+		for y := area.Min.Y + ya; y < area.Min.Y+yb; y++ {
+			for x := area.Min.X; x < area.Max.X; x++ {
+				px, py := float64(x)+0.5, float64(y)+0.5
+				k := 0.0
+				if length > 0 {
+					k = math.Max(0, math.Min(1, ((px-x0)*dx+(py-y0)*dy)/length))
+				}
+				cover := math.Max(0, math.Min(1, half-math.Hypot(px-(x0+k*dx), py-(y0+k*dy))+0.5))
+				if cover == 0 {
+					continue
+				}
+				// 预乘颜色按覆盖率缩放后做 source-over
+				keep := 1 - float64(a)*cover/0xffff
+				p := dst.Pix[dst.PixOffset(x, y):]
+				for i, v := range [4]uint32{r, g, b, a} {
+					p[i] = uint8(float64(v)*cover/0x101 + float64(p[i])*keep + 0.5)
+				}
+			}
+		}
+	})
+}
+
+// lerpInt 在 a 和 b 之间按 k 线性插值并取整
+func lerpInt(a, b int, k float64) int {
+	return int(math.Round(float64(a) + float64(b-a)*k))
+}
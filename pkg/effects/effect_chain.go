@@ -3,13 +3,17 @@ package effects
 import (
 	"fmt"
 	"image"
+	"strings"
+	"time"
 
 	"moviepy-go/pkg/core"
 )
 
 // EffectChain 特效链，可以组合多个特效
 type EffectChain struct {
-	effects []VideoEffect
+	effects       []VideoEffect
+	linearLight   bool // 支持的特效在线性光下处理
+	highPrecision bool // 支持的特效以 16 位精度处理
 }
 
 // NewEffectChain 创建新的特效链
@@ -24,13 +28,39 @@ func (ec *EffectChain) AddEffect(effect VideoEffect) {
 	ec.effects = append(ec.effects, effect)
 }
 
+// SetLinearLight 开启或关闭线性光模式，开启后链中的 LinearLightEffect 在线性光下处理
+func (ec *EffectChain) SetLinearLight(enabled bool) {
+	ec.linearLight = enabled
+}
+
+// LinearLight 返回是否开启线性光模式
+func (ec *EffectChain) LinearLight() bool {
+	return ec.linearLight
+}
+
+// SetHighPrecision 开启或关闭 16 位模式，开启后链中的 HighPrecisionEffect 以 *image.RGBA64 处理和传递帧
+func (ec *EffectChain) SetHighPrecision(enabled bool) {
+	ec.highPrecision = enabled
+}
+
+// HighPrecision 返回是否开启 16 位模式
+func (ec *EffectChain) HighPrecision() bool {
+	return ec.highPrecision
+}
+
 // ApplyToFrame 应用特效链到帧
 func (ec *EffectChain) ApplyToFrame(frame image.Image) (image.Image, error) {
 	result := frame
 
 	for i, effect := range ec.effects {
 		var err error
-		result, err = effect.ApplyToFrame(result)
+		if linear, ok := effect.(LinearLightEffect); ok && ec.linearLight {
+			result, err = linear.ApplyToFrameLinear(result)
+		} else if deep, ok := effect.(HighPrecisionEffect); ok && ec.highPrecision {
+			result, err = deep.ApplyToFrame64(result, 0)
+		} else {
+			result, err = effect.ApplyToFrame(result)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("应用特效 %d (%s) 失败: %w", i, effect.GetName(), err)
 		}
@@ -39,6 +69,37 @@ func (ec *EffectChain) ApplyToFrame(frame image.Image) (image.Image, error) {
 	return result, nil
 }
 
+// ApplyToFrameAt 应用特效链到时间 t 处的帧，链中的 TimedVideoEffect 按时间取参数
+func (ec *EffectChain) ApplyToFrameAt(frame image.Image, t time.Duration) (image.Image, error) {
+	result := frame
+
+	for i, effect := range ec.effects {
+		var err error
+		if linear, ok := effect.(LinearLightEffect); ok && ec.linearLight {
+			result, err = linear.ApplyToFrameLinear(result)
+		} else if deep, ok := effect.(HighPrecisionEffect); ok && ec.highPrecision {
+			result, err = deep.ApplyToFrame64(result, t)
+		} else if timed, ok := effect.(TimedVideoEffect); ok {
+			result, err = timed.ApplyToFrameAt(result, t)
+		} else {
+			result, err = effect.ApplyToFrame(result)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("应用特效 %d (%s) 失败: %w", i, effect.GetName(), err)
+		}
+	}
+
+	return result, nil
+}
+
+// OutputSize 依次经过链中每个特效后的尺寸
+func (ec *EffectChain) OutputSize(inW, inH int) (int, int) {
+	for _, effect := range ec.effects {
+		inW, inH = effect.OutputSize(inW, inH)
+	}
+	return inW, inH
+}
+
 // GetEffects 获取所有特效
 func (ec *EffectChain) GetEffects() []VideoEffect {
 	return ec.effects
@@ -54,6 +115,25 @@ func (ec *EffectChain) GetName() string {
 	return "effect_chain"
 }
 
+// CacheKey 依次拼接链中特效的键，任一特效不可缓存时返回空字符串
+func (ec *EffectChain) CacheKey() string {
+	keys := make([]string, 0, len(ec.effects)+2)
+	if ec.linearLight {
+		keys = append(keys, "linear")
+	}
+	if ec.highPrecision {
+		keys = append(keys, "rgba64")
+	}
+	for _, effect := range ec.effects {
+		key := CacheKey(effect)
+		if key == "" {
+			return ""
+		}
+		keys = append(keys, key)
+	}
+	return "chain[" + strings.Join(keys, ";") + "]"
+}
+
 // Apply 应用特效链到剪辑
 func (ec *EffectChain) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了特效链
@@ -95,6 +175,25 @@ func (ce *CompositeEffect) ApplyToFrame(frame image.Image) (image.Image, error)
 	return result, nil
 }
 
+// OutputSize 依次经过每个特效链后的尺寸
+func (ce *CompositeEffect) OutputSize(inW, inH int) (int, int) {
+	for _, chain := range ce.chains {
+		inW, inH = chain.OutputSize(inW, inH)
+	}
+	return inW, inH
+}
+
+// CacheKey 依次拼接各特效链的键，任一特效链不可缓存时返回空字符串
+func (ce *CompositeEffect) CacheKey() string {
+	keys := make([]string, len(ce.chains))
+	for i, chain := range ce.chains {
+		if keys[i] = chain.CacheKey(); keys[i] == "" {
+			return ""
+		}
+	}
+	return "composite[" + strings.Join(keys, ";") + "]"
+}
+
 // Apply 应用复合特效到剪辑
 func (ce *CompositeEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了复合特效
@@ -126,12 +225,24 @@ func (eb *EffectBuilder) Rotate(angle float64) *EffectBuilder {
 	return eb
 }
 
+// RotateWithOptions 使用指定选项添加旋转特效
+func (eb *EffectBuilder) RotateWithOptions(angle float64, options *RotateOptions) *EffectBuilder {
+	eb.chain.AddEffect(NewRotateEffectWithOptions(angle, options))
+	return eb
+}
+
 // Crop 添加裁剪特效
 func (eb *EffectBuilder) Crop(x, y, width, height int) *EffectBuilder {
 	eb.chain.AddEffect(NewCropEffect(x, y, width, height))
 	return eb
 }
 
+// CenterCrop 添加从中心裁剪 width×height 的特效
+func (eb *EffectBuilder) CenterCrop(width, height int) *EffectBuilder {
+	eb.chain.AddEffect(NewCenterCropEffect(width, height))
+	return eb
+}
+
 // Brightness 添加亮度调整特效
 func (eb *EffectBuilder) Brightness(factor float64) *EffectBuilder {
 	eb.chain.AddEffect(NewBrightnessEffect(factor))
@@ -156,6 +267,12 @@ func (eb *EffectBuilder) Sharpen(strength float64) *EffectBuilder {
 	return eb
 }
 
+// UnsharpMask 添加反锐化掩模特效
+func (eb *EffectBuilder) UnsharpMask(amount, radius, threshold float64) *EffectBuilder {
+	eb.chain.AddEffect(NewUnsharpMaskEffect(amount, radius, threshold))
+	return eb
+}
+
 // Saturation 添加饱和度调整特效
 func (eb *EffectBuilder) Saturation(factor float64) *EffectBuilder {
 	eb.chain.AddEffect(NewSaturationEffect(factor))
@@ -180,6 +297,18 @@ func (eb *EffectBuilder) Vignette(strength, radius float64) *EffectBuilder {
 	return eb
 }
 
+// Pixelate 添加马赛克特效
+func (eb *EffectBuilder) Pixelate(blockSize int) *EffectBuilder {
+	eb.chain.AddEffect(NewPixelateEffect(blockSize))
+	return eb
+}
+
+// LUT 添加 3D LUT 调色特效
+func (eb *EffectBuilder) LUT(lut *LUT, intensity float64) *EffectBuilder {
+	eb.chain.AddEffect(NewLUTEffect(lut, intensity))
+	return eb
+}
+
 // Build 构建特效链
 func (eb *EffectBuilder) Build() *EffectChain {
 	return eb.chain
@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"math"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/pixel"
 )
 
 // Effect 特效接口
@@ -24,6 +29,90 @@ type VideoEffect interface {
 
 	// ApplyToFrame 应用特效到单个帧
 	ApplyToFrame(frame image.Image) (image.Image, error)
+
+	// OutputSize 返回输入为 inW x inH 时输出帧的尺寸，不处理像素
+	OutputSize(inW, inH int) (int, int)
+}
+
+// TimedVideoEffect 参数随时间变化的视频特效，EffectVideoClip 优先调用 ApplyToFrameAt
+type TimedVideoEffect interface {
+	VideoEffect
+
+	// ApplyToFrameAt 应用特效到剪辑内时间 t 处的帧
+	ApplyToFrameAt(frame image.Image, t time.Duration) (image.Image, error)
+}
+
+// LinearLightEffect 可在线性光下处理的视频特效（如模糊、缩放），开启线性光模式的 EffectVideoClip
+// 和 EffectChain 优先调用 ApplyToFrameLinear
+type LinearLightEffect interface {
+	VideoEffect
+
+	// ApplyToFrameLinear 先把 sRGB 编码的帧还原为线性光再应用特效，结果编码回 sRGB
+	ApplyToFrameLinear(frame image.Image) (image.Image, error)
+}
+
+// HighPrecisionEffect 可在 16 位精度下处理的视频特效（如亮度、对比度、饱和度、LUT），开启 16 位模式的
+// EffectVideoClip 和 EffectChain 优先调用 ApplyToFrame64，结果以 *image.RGBA64 传给下一个特效，
+// 直到编码时才量化为 8 位（可配合 WriteOptions.Dither），长特效链不会逐级累积舍入误差
+type HighPrecisionEffect interface {
+	VideoEffect
+
+	// ApplyToFrame64 以 16 位精度应用特效到剪辑内时间 t 处的帧，返回 *image.RGBA64；参数不随时间变化的特效忽略 t
+	ApplyToFrame64(frame image.Image, t time.Duration) (image.Image, error)
+}
+
+// CacheableEffect 可用字符串描述全部参数的视频特效，video.EffectCache 以 CacheKey 区分特效链前缀的中间结果
+//
+// 参数相同的特效返回相同的键，参数（含嵌套特效的参数）被修改后键随之改变；依赖剪辑的参数按剪辑标识区分，
+// 依赖函数的参数按特效实例的修订号区分，函数的行为改变后需调用 MarkUpdated。
+// 返回空字符串、或没有实现该接口的特效（如随机噪点、任意函数）及其之后的特效不参与缓存。
+type CacheableEffect interface {
+	VideoEffect
+
+	// CacheKey 返回描述特效参数的键
+	CacheKey() string
+}
+
+// CacheKey 返回 effect 的缓存键，不可缓存时返回空字符串
+func CacheKey(effect VideoEffect) string {
+	if cacheable, ok := effect.(CacheableEffect); ok {
+		return cacheable.CacheKey()
+	}
+	return ""
+}
+
+// MarkUpdated 记录 effect（含嵌套特效）的参数已被修改，之后按修订号区分的特效返回新的缓存键
+//
+// 依赖函数的特效（如动画亮度、视口）无法从键中看出函数内部状态的变化，EffectVideoClip.UpdateEffect
+// 修改特效后会自动调用；在 UpdateEffect 之外修改时需自行调用。
+func MarkUpdated(effect VideoEffect) {
+	switch e := effect.(type) {
+	case *EffectChain:
+		for _, inner := range e.effects {
+			MarkUpdated(inner)
+		}
+	case *CompositeEffect:
+		for _, chain := range e.chains {
+			MarkUpdated(chain)
+		}
+	case *RegionEffect:
+		MarkUpdated(e.inner)
+	}
+	if revised, ok := effect.(interface{ renew() }); ok {
+		revised.renew()
+	}
+}
+
+// revisions 全局修订号计数器；与地址不同，修订号在特效被回收后也不会复用
+var revisions atomic.Uint64
+
+// colorKey 把颜色格式化为缓存键的一部分，nil 为 none
+func colorKey(c color.Color) string {
+	if c == nil {
+		return "none"
+	}
+	r, g, b, a := c.RGBA()
+	return fmt.Sprintf("%04x%04x%04x%04x", r, g, b, a)
 }
 
 // AudioEffect 音频特效接口
@@ -36,7 +125,23 @@ type AudioEffect interface {
 
 // TransformEffect 变换特效基础结构
 type TransformEffect struct {
-	name string
+	name     string
+	revision atomic.Uint64 // 缓存键使用的修订号，0 表示尚未分配
+}
+
+// revisionKey 返回特效当前的修订号，首次调用时分配
+func (te *TransformEffect) revisionKey() uint64 {
+	for {
+		if r := te.revision.Load(); r != 0 {
+			return r
+		}
+		te.revision.CompareAndSwap(0, revisions.Add(1))
+	}
+}
+
+// renew 换用新的修订号，见 MarkUpdated
+func (te *TransformEffect) renew() {
+	te.revision.Store(revisions.Add(1))
 }
 
 // GetName 获取特效名称
@@ -44,6 +149,11 @@ func (te *TransformEffect) GetName() string {
 	return te.name
 }
 
+// OutputSize 默认不改变尺寸，缩放、旋转、裁剪等特效会覆盖
+func (te *TransformEffect) OutputSize(inW, inH int) (int, int) {
+	return inW, inH
+}
+
 // ResizeEffect 缩放特效
 type ResizeEffect struct {
 	TransformEffect
@@ -68,6 +178,21 @@ func NewResizeEffect(width, height int) *ResizeEffect {
 	}
 }
 
+// Size 返回缩放后的目标尺寸
+func (re *ResizeEffect) Size() (width, height int) {
+	return re.width, re.height
+}
+
+// OutputSize 缩放后的尺寸与输入无关
+func (re *ResizeEffect) OutputSize(inW, inH int) (int, int) {
+	return re.width, re.height
+}
+
+// CacheKey 返回目标尺寸
+func (re *ResizeEffect) CacheKey() string {
+	return fmt.Sprintf("resize(%dx%d)", re.width, re.height)
+}
+
 // Apply 应用缩放特效
 func (re *ResizeEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了缩放特效
@@ -77,6 +202,12 @@ func (re *ResizeEffect) Apply(clip core.Clip) (core.Clip, error) {
 
 // ApplyToFrame 应用缩放特效到帧
 func (re *ResizeEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	if result, ok, err := accelerate(func(backend Backend) (image.Image, error) {
+		return backend.Scale(frame, re.width, re.height)
+	}); ok {
+		return result, err
+	}
+
 	bounds := frame.Bounds()
 	srcWidth := bounds.Dx()
 	srcHeight := bounds.Dy()
@@ -85,40 +216,144 @@ func (re *ResizeEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 	dst := image.NewRGBA(image.Rect(0, 0, re.width, re.height))
 
 	// 简单的最近邻缩放算法
-	for y := 0; y < re.height; y++ {
-		for x := 0; x < re.width; x++ {
-			// 计算源坐标
-			srcX := int(float64(x) * float64(srcWidth) / float64(re.width))
-			srcY := int(float64(y) * float64(srcHeight) / float64(re.height))
-
-			// 确保坐标在边界内
-			if srcX >= srcWidth {
-				srcX = srcWidth - 1
-			}
-			if srcY >= srcHeight {
-				srcY = srcHeight - 1
+	Parallel(re.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < re.width; x++ {
+				// 计算源坐标
+				srcX := int(float64(x) * float64(srcWidth) / float64(re.width))
+				srcY := int(float64(y) * float64(srcHeight) / float64(re.height))
+
+				// 确保坐标在边界内
+				if srcX >= srcWidth {
+					srcX = srcWidth - 1
+				}
+				if srcY >= srcHeight {
+					srcY = srcHeight - 1
+				}
+
+				// 复制像素
+				dst.Set(x, y, frame.At(bounds.Min.X+srcX, bounds.Min.Y+srcY))
 			}
-
-			// 复制像素
-			dst.Set(x, y, frame.At(srcX, srcY))
 		}
-	}
+	})
 
 	return dst, nil
 }
 
-// RotateEffect 旋转特效
+// ApplyToFrameLinear 在线性光下缩放：每个输出像素取其覆盖的源像素的平均值，
+// 缩小细密的纹理（如棋盘格、文字边缘）时亮度不变
+func (re *ResizeEffect) ApplyToFrameLinear(frame image.Image) (image.Image, error) {
+	src := toLinear(frame)
+	srcWidth, srcHeight := src.width, src.height
+	if srcWidth == 0 || srcHeight == 0 {
+		return nil, fmt.Errorf("无效的输入尺寸: %dx%d", srcWidth, srcHeight)
+	}
+	// span 返回输出坐标 i 覆盖的源坐标区间 [lo, hi)，放大时至少包含一个源像素
+	span := func(i, srcSize, dstSize int) (int, int) {
+		lo := min(i*srcSize/dstSize, srcSize-1)
+		return lo, max((i+1)*srcSize/dstSize, lo+1)
+	}
+	dst := newFrame16(re.width, re.height)
+	Parallel(re.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			top, bottom := span(y, srcHeight, re.height)
+			for x := 0; x < re.width; x++ {
+				left, right := span(x, srcWidth, re.width)
+				count := uint64((bottom - top) * (right - left))
+				for k := 0; k < 4; k++ {
+					var sum uint64
+					for sy := top; sy < bottom; sy++ {
+						row := src.pix[sy*srcWidth*4:]
+						for sx := left; sx < right; sx++ {
+							sum += uint64(row[sx*4+k])
+						}
+					}
+					dst.pix[(y*re.width+x)*4+k] = uint16((sum + count/2) / count)
+				}
+			}
+		}
+	})
+	return dst.fromLinear(), nil
+}
+
+// RotateEffect 旋转特效，正角度为顺时针
 type RotateEffect struct {
 	TransformEffect
-	angle float64 // 角度，以度为单位
+	angle   float64 // 角度，以度为单位
+	options RotateOptions
+}
+
+// RotateOptions 旋转特效选项
+type RotateOptions struct {
+	// Background 旋转后未被原画面覆盖的角落的颜色，nil 表示透明（编码为不带 alpha 的视频时显示为黑色），
+	// 如 color.White 或 color.Transparent
+	Background color.Color
+	// Exact 任意角度都输出完整的旋转边界框；默认单边限制在 4096，超出部分被裁掉。
+	// 两种模式下像素总数超过 16M 时都返回错误
+	Exact bool
+	// Sampling 非 90° 整数倍时的取样方式，默认双线性
+	Sampling Sampling
+	// Supersample 每个输出像素每边的子取样数，0 或 1 不超采样，2 为 2×2 超采样，最大 4；
+	// 小角度旋转的细线和文字边缘更平滑，开销随子取样数成倍增加
+	Supersample int
 }
 
+// maxRotateDimension 非 Exact 模式下旋转输出的单边上限
+const maxRotateDimension = 4096
+
 // NewRotateEffect 创建旋转特效
 func NewRotateEffect(angle float64) *RotateEffect {
+	return NewRotateEffectWithOptions(angle, nil)
+}
+
+// NewRotateEffectWithOptions 使用指定选项创建旋转特效
+func NewRotateEffectWithOptions(angle float64, options *RotateOptions) *RotateEffect {
+	if options == nil {
+		options = &RotateOptions{}
+	}
 	return &RotateEffect{
 		TransformEffect: TransformEffect{name: "rotate"},
 		angle:           angle,
+		options:         *options,
+	}
+}
+
+// quarterTurns 角度为 90° 的整数倍时返回顺时针旋转的四分之一圈数（0–3）
+func (re *RotateEffect) quarterTurns() (int, bool) {
+	turns := re.angle / 90
+	if turns != math.Trunc(turns) {
+		return 0, false
 	}
+	return (int(math.Mod(turns, 4)) + 4) % 4, true
+}
+
+// OutputSize 旋转后的边界框尺寸；90° 的整数倍为精确的转置尺寸，
+// 其他角度在非 Exact 模式下单边最大 4096
+func (re *RotateEffect) OutputSize(inW, inH int) (int, int) {
+	if turns, ok := re.quarterTurns(); ok {
+		if turns%2 == 1 {
+			return inH, inW
+		}
+		return inW, inH
+	}
+
+	radians := re.angle * math.Pi / 180.0
+	absCos := math.Abs(math.Cos(radians))
+	absSin := math.Abs(math.Sin(radians))
+
+	newWidth := int(float64(inW)*absCos + float64(inH)*absSin)
+	newHeight := int(float64(inW)*absSin + float64(inH)*absCos)
+	if re.options.Exact {
+		return newWidth, newHeight
+	}
+
+	// 限制最大尺寸，防止过大的图像
+	return min(newWidth, maxRotateDimension), min(newHeight, maxRotateDimension)
+}
+
+// CacheKey 返回角度和选项
+func (re *RotateEffect) CacheKey() string {
+	return fmt.Sprintf("rotate(%g,%s,%t,%v,%d)", re.angle, colorKey(re.options.Background), re.options.Exact, re.options.Sampling, re.options.Supersample)
 }
 
 // Apply 应用旋转特效
@@ -128,7 +363,8 @@ func (re *RotateEffect) Apply(clip core.Clip) (core.Clip, error) {
 	return clip, nil
 }
 
-// ApplyToFrame 应用旋转特效到帧
+// ApplyToFrame 应用旋转特效到帧，90° 的整数倍按像素转置，不经过插值也不产生空白角落；
+// 其他角度按 Sampling 和 Supersample 逆映射取样
 func (re *RotateEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 	bounds := frame.Bounds()
 	width := bounds.Dx()
@@ -138,47 +374,20 @@ func (re *RotateEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 	if width <= 0 || height <= 0 {
 		return nil, fmt.Errorf("无效的输入尺寸: %dx%d", width, height)
 	}
+	if turns, ok := re.quarterTurns(); ok {
+		return rotateQuarters(pixel.ToRGBA(frame), turns), nil
+	}
 	if width > 8192 || height > 8192 {
 		return nil, fmt.Errorf("输入尺寸过大: %dx%d", width, height)
 	}
 
 	// 将角度转换为弧度
 	radians := re.angle * math.Pi / 180.0
-
-	// 计算旋转后的尺寸
 	cos := math.Cos(radians)
 	sin := math.Sin(radians)
-	absCos := math.Abs(cos)
-	absSin := math.Abs(sin)
 
-	// 计算旋转后的边界框（更准确的计算）
-	newWidth := int(float64(width)*absCos + float64(height)*absSin)
-	newHeight := int(float64(width)*absSin + float64(height)*absCos)
-
-	// 确保尺寸是偶数（H.264编码器要求）
-	if newWidth%2 != 0 {
-		newWidth++
-	}
-	if newHeight%2 != 0 {
-		newHeight++
-	}
-
-	// 限制最大尺寸，防止过大的图像
-	maxDimension := 4096 // 最大4K分辨率
-	if newWidth > maxDimension {
-		newWidth = maxDimension
-		// 确保限制后仍然是偶数
-		if newWidth%2 != 0 {
-			newWidth--
-		}
-	}
-	if newHeight > maxDimension {
-		newHeight = maxDimension
-		// 确保限制后仍然是偶数
-		if newHeight%2 != 0 {
-			newHeight--
-		}
-	}
+	// 计算旋转后的尺寸，奇数尺寸由 EffectVideoClip 的 EvenPolicy 统一处理
+	newWidth, newHeight := re.OutputSize(width, height)
 
 	// 检查计算出的尺寸是否合理
 	if newWidth <= 0 || newHeight <= 0 {
@@ -188,43 +397,69 @@ func (re *RotateEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 		return nil, fmt.Errorf("旋转后尺寸过大: %dx%d (%d 像素)", newWidth, newHeight, newWidth*newHeight)
 	}
 
-	// 创建新图像
-	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-
-	// 计算旋转中心
+	// 输出坐标 → 以两者中心对齐的逆旋转 → 源坐标
 	centerX := float64(width) / 2.0
 	centerY := float64(height) / 2.0
 	newCenterX := float64(newWidth) / 2.0
 	newCenterY := float64(newHeight) / 2.0
+	inverse := projective{
+		cos, sin, centerX - newCenterX*cos - newCenterY*sin,
+		-sin, cos, centerY + newCenterX*sin - newCenterY*cos,
+		0, 0, 1,
+	}
+	return warp(pixel.ToRGBA(frame), newWidth, newHeight, inverse, warpOptions{
+		sampling:    re.options.Sampling,
+		supersample: re.options.Supersample,
+		background:  re.options.Background,
+	}), nil
+}
 
-	// 应用旋转
-	for y := 0; y < newHeight; y++ {
-		for x := 0; x < newWidth; x++ {
-			// 将新坐标转换为原坐标
-			dx := float64(x) - newCenterX
-			dy := float64(y) - newCenterY
-
-			// 应用逆旋转
-			srcX := int(centerX + dx*cos + dy*sin)
-			srcY := int(centerY - dx*sin + dy*cos)
-
-			// 检查边界
-			if srcX >= 0 && srcX < width && srcY >= 0 && srcY < height {
-				dst.Set(x, y, frame.At(srcX, srcY))
+// rotateQuarters 把 src 顺时针旋转 turns 个 90°，逐像素复制
+func rotateQuarters(src *image.RGBA, turns int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dstW, dstH := width, height
+	if turns%2 == 1 {
+		dstW, dstH = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	Parallel(dstH, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := dst.Pix[y*dst.Stride:]
+			for x := 0; x < dstW; x++ {
+				// 目标 (x, y) 对应的源坐标
+				var sx, sy int
+				switch turns {
+				case 1:
+					sx, sy = y, height-1-x
+				case 2:
+					sx, sy = width-1-x, height-1-y
+				case 3:
+					sx, sy = width-1-y, x
+				default:
+					sx, sy = x, y
+				}
+				offset := src.PixOffset(bounds.Min.X+sx, bounds.Min.Y+sy)
+				copy(out[x*4:x*4+4], src.Pix[offset:offset+4])
 			}
 		}
-	}
-
-	return dst, nil
+	})
+	return dst
 }
 
 // CropEffect 裁剪特效
+//
+// 裁剪区域可以是像素坐标、输入中心处的固定尺寸或按输入尺寸比例的区域，每次调用时按当次输入的尺寸计算
+// 并限制在帧内，同一特效可以用于不同尺寸的剪辑。
 type CropEffect struct {
 	TransformEffect
 	x, y, width, height int
+	center              bool       // 以输入中心为中心裁剪 width×height，忽略 x、y
+	relative            bool       // 按 fraction 的比例计算区域，忽略像素字段
+	fraction            [4]float64 // 相对区域的 x、y、宽、高（0–1）
 }
 
-// NewCropEffect 创建裁剪特效
+// NewCropEffect 创建裁剪特效，裁剪左上角为 (x, y) 的 width×height 区域
 func NewCropEffect(x, y, width, height int) *CropEffect {
 	return &CropEffect{
 		TransformEffect: TransformEffect{name: "crop"},
@@ -235,6 +470,103 @@ func NewCropEffect(x, y, width, height int) *CropEffect {
 	}
 }
 
+// NewCenterCropEffect 创建从输入中心裁剪 width×height 的特效，如把 16:9 画面居中裁成 9:16
+func NewCenterCropEffect(width, height int) *CropEffect {
+	ce := NewCropEffect(0, 0, width, height)
+	ce.center = true
+	return ce
+}
+
+// NewRelativeCropEffect 创建按输入尺寸比例裁剪的特效，x、y、width、height 均为 0–1 的比例，
+// 如 NewRelativeCropEffect(0.5, 0, 0.5, 1) 取右半边
+func NewRelativeCropEffect(x, y, width, height float64) *CropEffect {
+	ce := NewCropEffect(0, 0, 0, 0)
+	ce.relative = true
+	ce.fraction = [4]float64{x, y, width, height}
+	return ce
+}
+
+// CropRegion 按名称指定的常用裁剪区域
+type CropRegion int
+
+const (
+	CropLeft        CropRegion = iota // 左半边
+	CropRight                         // 右半边
+	CropTop                           // 上半边
+	CropBottom                        // 下半边
+	CropTopLeft                       // 左上四分之一
+	CropTopRight                      // 右上四分之一
+	CropBottomLeft                    // 左下四分之一
+	CropBottomRight                   // 右下四分之一
+	CropCenter                        // 中央宽高各一半的区域
+)
+
+// cropRegionNames 按 CropRegion 取值排列的名称
+var cropRegionNames = []string{"left", "right", "top", "bottom", "top_left", "top_right", "bottom_left", "bottom_right", "center"}
+
+// cropRegionFractions 各区域相对输入的 x、y、宽、高
+var cropRegionFractions = [][4]float64{
+	{0, 0, 0.5, 1}, {0.5, 0, 0.5, 1}, {0, 0, 1, 0.5}, {0, 0.5, 1, 0.5},
+	{0, 0, 0.5, 0.5}, {0.5, 0, 0.5, 0.5}, {0, 0.5, 0.5, 0.5}, {0.5, 0.5, 0.5, 0.5},
+	{0.25, 0.25, 0.5, 0.5},
+}
+
+// String 返回区域名称
+func (r CropRegion) String() string {
+	if r >= 0 && int(r) < len(cropRegionNames) {
+		return cropRegionNames[r]
+	}
+	return fmt.Sprintf("CropRegion(%d)", int(r))
+}
+
+// ParseCropRegion 解析区域名称（left、right、top、bottom、top_left、top_right、bottom_left、bottom_right、center）
+func ParseCropRegion(name string) (CropRegion, error) {
+	for i, n := range cropRegionNames {
+		if strings.EqualFold(name, n) {
+			return CropRegion(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的裁剪区域: %q（支持 %s）", name, strings.Join(cropRegionNames, "、"))
+}
+
+// NewRegionCropEffect 创建裁剪命名区域的特效，如 NewRegionCropEffect(CropLeft) 取分屏画面的左半边
+func NewRegionCropEffect(region CropRegion) *CropEffect {
+	if region < 0 || int(region) >= len(cropRegionFractions) {
+		region = CropCenter
+	}
+	f := cropRegionFractions[region]
+	return NewRelativeCropEffect(f[0], f[1], f[2], f[3])
+}
+
+// Rect 返回输入为 inW x inH 时实际裁剪的区域（已限制在帧内），区域为空时宽或高为 0
+func (ce *CropEffect) Rect(inW, inH int) image.Rectangle {
+	x, y, width, height := ce.x, ce.y, ce.width, ce.height
+	switch {
+	case ce.relative:
+		x = int(math.Round(ce.fraction[0] * float64(inW)))
+		y = int(math.Round(ce.fraction[1] * float64(inH)))
+		width = int(math.Round((ce.fraction[0]+ce.fraction[2])*float64(inW))) - x
+		height = int(math.Round((ce.fraction[1]+ce.fraction[3])*float64(inH))) - y
+	case ce.center:
+		x, y = (inW-width)/2, (inH-height)/2
+	}
+	if width <= 0 || height <= 0 {
+		return image.Rectangle{}
+	}
+	return image.Rect(x, y, x+width, y+height).Intersect(image.Rect(0, 0, inW, inH))
+}
+
+// OutputSize 裁剪区域限制在输入范围内后的尺寸
+func (ce *CropEffect) OutputSize(inW, inH int) (int, int) {
+	rect := ce.Rect(inW, inH)
+	return rect.Dx(), rect.Dy()
+}
+
+// CacheKey 返回裁剪区域
+func (ce *CropEffect) CacheKey() string {
+	return fmt.Sprintf("crop(%d,%d,%d,%d,%t,%t,%v)", ce.x, ce.y, ce.width, ce.height, ce.center, ce.relative, ce.fraction)
+}
+
 // Apply 应用裁剪特效
 func (ce *CropEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了裁剪特效
@@ -242,45 +574,87 @@ func (ce *CropEffect) Apply(clip core.Clip) (core.Clip, error) {
 	return clip, nil
 }
 
-// ApplyToFrame 应用裁剪特效到帧
+// ApplyToFrame 应用裁剪特效到帧，裁剪区域为空（尺寸不为正或完全在帧外）时返回错误
 func (ce *CropEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
 	bounds := frame.Bounds()
-	srcWidth := bounds.Dx()
-	srcHeight := bounds.Dy()
-
-	// 确保裁剪区域在图像范围内
-	if ce.x < 0 {
-		ce.x = 0
+	rect := ce.Rect(bounds.Dx(), bounds.Dy())
+	if rect.Empty() {
+		return nil, fmt.Errorf("裁剪区域为空: %s（输入 %dx%d）", ce.describe(), bounds.Dx(), bounds.Dy())
 	}
-	if ce.y < 0 {
-		ce.y = 0
+
+	// 复制裁剪区域
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), frame, bounds.Min.Add(rect.Min), draw.Src)
+	return dst, nil
+}
+
+// describe 描述裁剪参数，用于错误信息
+func (ce *CropEffect) describe() string {
+	switch {
+	case ce.relative:
+		return fmt.Sprintf("相对区域 (%g, %g) %g×%g", ce.fraction[0], ce.fraction[1], ce.fraction[2], ce.fraction[3])
+	case ce.center:
+		return fmt.Sprintf("居中 %dx%d", ce.width, ce.height)
 	}
-	if ce.x+ce.width > srcWidth {
-		ce.width = srcWidth - ce.x
+	return fmt.Sprintf("(%d, %d) %dx%d", ce.x, ce.y, ce.width, ce.height)
+}
+
+// MarginEffect 在帧的四周添加边距（信箱边），用于留出字幕安全区或拼接布局
+type MarginEffect struct {
+	TransformEffect
+	top, right, bottom, left int
+	color                    color.Color
+}
+
+// NewMarginEffect 创建边距特效，各边宽度为像素，负值按 0 处理；color 为 nil 时为黑色，
+// 透明色（如 color.Transparent）可留出透明边距供合成使用
+func NewMarginEffect(top, right, bottom, left int, c color.Color) *MarginEffect {
+	if c == nil {
+		c = color.Black
 	}
-	if ce.y+ce.height > srcHeight {
-		ce.height = srcHeight - ce.y
+	return &MarginEffect{
+		TransformEffect: TransformEffect{name: "margin"},
+		top:             max(top, 0),
+		right:           max(right, 0),
+		bottom:          max(bottom, 0),
+		left:            max(left, 0),
+		color:           c,
 	}
+}
 
-	// 创建裁剪后的图像
-	dst := image.NewRGBA(image.Rect(0, 0, ce.width, ce.height))
+// OutputSize 输入尺寸加上两侧边距
+func (me *MarginEffect) OutputSize(inW, inH int) (int, int) {
+	return inW + me.left + me.right, inH + me.top + me.bottom
+}
 
-	// 复制裁剪区域
-	for y := 0; y < ce.height; y++ {
-		for x := 0; x < ce.width; x++ {
-			srcX := ce.x + x
-			srcY := ce.y + y
-			dst.Set(x, y, frame.At(srcX, srcY))
-		}
-	}
+// CacheKey 返回边距和颜色
+func (me *MarginEffect) CacheKey() string {
+	return fmt.Sprintf("margin(%d,%d,%d,%d,%s)", me.top, me.right, me.bottom, me.left, colorKey(me.color))
+}
+
+// Apply 应用边距特效
+func (me *MarginEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 这里应该返回一个新的剪辑，应用了边距特效
+	// 简化实现，直接返回原剪辑
+	return clip, nil
+}
 
+// ApplyToFrame 用边距颜色填充新画布并把原帧绘制到 (left, top)
+func (me *MarginEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	bounds := frame.Bounds()
+	width, height := me.OutputSize(bounds.Dx(), bounds.Dy())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(me.color), image.Point{}, draw.Src)
+	inner := image.Rect(me.left, me.top, me.left+bounds.Dx(), me.top+bounds.Dy())
+	draw.Draw(dst, inner, frame, bounds.Min, draw.Src)
 	return dst, nil
 }
 
 // BrightnessEffect 亮度调整特效
 type BrightnessEffect struct {
 	TransformEffect
-	factor float64 // 亮度因子，1.0为正常，>1.0为更亮，<1.0为更暗
+	factor   float64                       // 亮度因子，1.0为正常，>1.0为更亮，<1.0为更暗
+	factorAt func(t time.Duration) float64 // 非 nil 时为随时间变化的亮度因子
 }
 
 // NewBrightnessEffect 创建亮度调整特效
@@ -291,6 +665,34 @@ func NewBrightnessEffect(factor float64) *BrightnessEffect {
 	}
 }
 
+// NewAnimatedBrightnessEffect 创建亮度随时间变化的特效，如 expr.MustParse("1+0.5*t").Func()
+func NewAnimatedBrightnessEffect(factor func(t time.Duration) float64) *BrightnessEffect {
+	return &BrightnessEffect{
+		TransformEffect: TransformEffect{name: "brightness"},
+		factor:          factor(0),
+		factorAt:        factor,
+	}
+}
+
+// Factor 返回静态亮度因子
+func (be *BrightnessEffect) Factor() float64 {
+	return be.factor
+}
+
+// SetFactor 修改亮度因子并取消动画，通过 EffectVideoClip.UpdateEffect 调用以避免与渲染并发
+func (be *BrightnessEffect) SetFactor(factor float64) {
+	be.factor = factor
+	be.factorAt = nil
+}
+
+// CacheKey 返回亮度因子，动画亮度按修订号区分
+func (be *BrightnessEffect) CacheKey() string {
+	if be.factorAt != nil {
+		return fmt.Sprintf("brightness(#%d)", be.revisionKey())
+	}
+	return fmt.Sprintf("brightness(%g)", be.factor)
+}
+
 // Apply 应用亮度调整特效
 func (be *BrightnessEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了亮度调整特效
@@ -298,46 +700,45 @@ func (be *BrightnessEffect) Apply(clip core.Clip) (core.Clip, error) {
 	return clip, nil
 }
 
-// ApplyToFrame 应用亮度调整特效到帧
+// ApplyToFrame 应用亮度调整特效到帧，动画亮度取 t=0 处的值
 func (be *BrightnessEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
-	bounds := frame.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// 创建新图像
-	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	return be.apply(frame, be.factor)
+}
 
-	// 应用亮度调整
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := frame.At(x, y).RGBA()
+// ApplyToFrameAt 按时间 t 处的亮度因子调整帧
+func (be *BrightnessEffect) ApplyToFrameAt(frame image.Image, t time.Duration) (image.Image, error) {
+	factor := be.factor
+	if be.factorAt != nil {
+		factor = be.factorAt(t)
+	}
+	return be.apply(frame, factor)
+}
 
-			// 调整亮度
-			newR := uint32(float64(r) * be.factor)
-			newG := uint32(float64(g) * be.factor)
-			newB := uint32(float64(b) * be.factor)
+// apply 按亮度因子调整帧
+func (be *BrightnessEffect) apply(frame image.Image, factor float64) (image.Image, error) {
+	if factor < 0 {
+		factor = 0
+	}
+	if result, ok, err := accelerate(func(backend Backend) (image.Image, error) {
+		return backend.Adjust(frame, ColorAdjust{Brightness: factor, Contrast: 1, Saturation: 1})
+	}); ok {
+		return result, err
+	}
 
-			// 确保值在有效范围内
-			if newR > 65535 {
-				newR = 65535
-			}
-			if newG > 65535 {
-				newG = 65535
-			}
-			if newB > 65535 {
-				newB = 65535
-			}
+	return parallelKernel(frame, func(dst, src []byte) {
+		pixel.Brightness(dst, src, factor)
+	}), nil
+}
 
-			dst.Set(x, y, color.RGBA{
-				R: uint8(newR >> 8),
-				G: uint8(newG >> 8),
-				B: uint8(newB >> 8),
-				A: uint8(a >> 8),
-			})
-		}
+// ApplyToFrame64 以 16 位精度按时间 t 处的亮度因子调整帧
+func (be *BrightnessEffect) ApplyToFrame64(frame image.Image, t time.Duration) (image.Image, error) {
+	factor := be.factor
+	if be.factorAt != nil {
+		factor = be.factorAt(t)
 	}
-
-	return dst, nil
+	return parallelKernel64(frame, func(dst, src []byte) {
+		pixel.Brightness64(dst, src, factor)
+	}), nil
 }
 
 // ContrastEffect 对比度调整特效
@@ -354,6 +755,21 @@ func NewContrastEffect(factor float64) *ContrastEffect {
 	}
 }
 
+// Factor 返回对比度因子
+func (ce *ContrastEffect) Factor() float64 {
+	return ce.factor
+}
+
+// SetFactor 修改对比度因子
+func (ce *ContrastEffect) SetFactor(factor float64) {
+	ce.factor = factor
+}
+
+// CacheKey 返回对比度因子
+func (ce *ContrastEffect) CacheKey() string {
+	return fmt.Sprintf("contrast(%g)", ce.factor)
+}
+
 // Apply 应用对比度调整特效
 func (ce *ContrastEffect) Apply(clip core.Clip) (core.Clip, error) {
 	// 这里应该返回一个新的剪辑，应用了对比度调整特效
@@ -363,54 +779,20 @@ func (ce *ContrastEffect) Apply(clip core.Clip) (core.Clip, error) {
 
 // ApplyToFrame 应用对比度调整特效到帧
 func (ce *ContrastEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
-	bounds := frame.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// 创建新图像
-	dst := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	// 应用对比度调整
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			r, g, b, a := frame.At(x, y).RGBA()
-
-			// 将值标准化到0-1范围
-			normR := float64(r) / 65535.0
-			normG := float64(g) / 65535.0
-			normB := float64(b) / 65535.0
-
-			// 应用对比度调整
-			newR := (normR-0.5)*ce.factor + 0.5
-			newG := (normG-0.5)*ce.factor + 0.5
-			newB := (normB-0.5)*ce.factor + 0.5
-
-			// 确保值在0-1范围内
-			if newR < 0 {
-				newR = 0
-			} else if newR > 1 {
-				newR = 1
-			}
-			if newG < 0 {
-				newG = 0
-			} else if newG > 1 {
-				newG = 1
-			}
-			if newB < 0 {
-				newB = 0
-			} else if newB > 1 {
-				newB = 1
-			}
-
-			// 转换回0-255范围
-			dst.Set(x, y, color.RGBA{
-				R: uint8(newR * 255),
-				G: uint8(newG * 255),
-				B: uint8(newB * 255),
-				A: uint8(a >> 8),
-			})
-		}
+	if result, ok, err := accelerate(func(backend Backend) (image.Image, error) {
+		return backend.Adjust(frame, ColorAdjust{Brightness: 1, Contrast: ce.factor, Saturation: 1})
+	}); ok {
+		return result, err
 	}
 
-	return dst, nil
+	return parallelKernel(frame, func(dst, src []byte) {
+		pixel.Contrast(dst, src, ce.factor)
+	}), nil
+}
+
+// ApplyToFrame64 以 16 位精度调整对比度
+func (ce *ContrastEffect) ApplyToFrame64(frame image.Image, t time.Duration) (image.Image, error) {
+	return parallelKernel64(frame, func(dst, src []byte) {
+		pixel.Contrast64(dst, src, ce.factor)
+	}), nil
 }
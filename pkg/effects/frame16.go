@@ -0,0 +1,178 @@
+package effects
+
+import (
+	"image"
+	"math"
+
+	"moviepy-go/pkg/pixel"
+)
+
+// frame16 是按行排列的 16 位预乘 RGBA 帧，每像素 4 个通道，用于线性光和 16 位精度的中间计算
+type frame16 struct {
+	pix           []uint16
+	width, height int
+}
+
+// newFrame16 创建 width×height 的空白帧
+func newFrame16(width, height int) *frame16 {
+	return &frame16{pix: make([]uint16, width*height*4), width: width, height: height}
+}
+
+// toLinear 把帧转为线性光，Bounds 的左上角移到原点
+func toLinear(frame image.Image) *frame16 {
+	src := pixel.ToRGBA(frame)
+	bounds := src.Bounds()
+	f := newFrame16(bounds.Dx(), bounds.Dy())
+	Parallel(f.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:f.width*4]
+			pixel.Linearize(f.pix[y*f.width*4:][:len(row)], row)
+		}
+	})
+	return f
+}
+
+// toFrame16 把帧按 16 位精度读入，不改变编码，Bounds 的左上角移到原点
+func toFrame16(frame image.Image) *frame16 {
+	src := pixel.ToRGBA64(frame)
+	bounds := src.Bounds()
+	f := newFrame16(bounds.Dx(), bounds.Dy())
+	Parallel(f.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:f.width*8]
+			out := f.pix[y*f.width*4:][:f.width*4]
+			for i := range out {
+				out[i] = uint16(row[i*2])<<8 | uint16(row[i*2+1])
+			}
+		}
+	})
+	return f
+}
+
+// toRGBA 四舍五入到 8 位的 *image.RGBA
+func (f *frame16) toRGBA() *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, f.width, f.height))
+	Parallel(f.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := f.pix[y*f.width*4:][:f.width*4]
+			out := dst.Pix[y*dst.Stride:]
+			for i, v := range row {
+				out[i] = uint8((uint32(v) + 128) / 257)
+			}
+		}
+	})
+	return dst
+}
+
+// fromLinear 把线性光帧编码回 sRGB 的 *image.RGBA
+func (f *frame16) fromLinear() *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, f.width, f.height))
+	Parallel(f.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := f.pix[y*f.width*4:][:f.width*4]
+			pixel.Delinearize(dst.Pix[y*dst.Stride:][:len(row)], row)
+		}
+	})
+	return dst
+}
+
+// toRGBA64 原样写出为 *image.RGBA64
+func (f *frame16) toRGBA64() *image.RGBA64 {
+	dst := image.NewRGBA64(image.Rect(0, 0, f.width, f.height))
+	Parallel(f.height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := f.pix[y*f.width*4:][:f.width*4]
+			out := dst.Pix[y*dst.Stride:]
+			for i, v := range row {
+				out[i*2], out[i*2+1] = byte(v>>8), byte(v)
+			}
+		}
+	})
+	return dst
+}
+
+// boxBlur 半径 radius 的均值模糊，只对边界内的像素取平均
+//
+// 边界内的采样窗口是矩形，均值可以先按行、再按列分两遍计算。
+func (f *frame16) boxBlur(radius int) *frame16 {
+	width, height := f.width, f.height
+	rows, dst := newFrame16(width, height), newFrame16(width, height)
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in, out := f.pix[y*width*4:][:width*4], rows.pix[y*width*4:][:width*4]
+			for x := 0; x < width; x++ {
+				lo, hi := max(x-radius, 0), min(x+radius, width-1)
+				count := uint32(hi - lo + 1)
+				for k := 0; k < 4; k++ {
+					var sum uint32
+					for sx := lo; sx <= hi; sx++ {
+						sum += uint32(in[sx*4+k])
+					}
+					out[x*4+k] = uint16((sum + count/2) / count)
+				}
+			}
+		}
+	})
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			lo, hi := max(y-radius, 0), min(y+radius, height-1)
+			count := uint32(hi - lo + 1)
+			out := dst.pix[y*width*4:][:width*4]
+			for i := range out {
+				var sum uint32
+				for sy := lo; sy <= hi; sy++ {
+					sum += uint32(rows.pix[sy*width*4+i])
+				}
+				out[i] = uint16((sum + count/2) / count)
+			}
+		}
+	})
+	return dst
+}
+
+// gaussianBlur 标准差为 sigma 的高斯模糊，按行、按列分两遍计算，边界外取最近的边缘像素
+func (f *frame16) gaussianBlur(sigma float64) *frame16 {
+	radius := max(int(math.Ceil(sigma*3)), 1)
+	weights := make([]float64, 2*radius+1)
+	var total float64
+	for i := range weights {
+		d := float64(i - radius)
+		weights[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		total += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+
+	width, height := f.width, f.height
+	rows, dst := newFrame16(width, height), newFrame16(width, height)
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in, out := f.pix[y*width*4:][:width*4], rows.pix[y*width*4:][:width*4]
+			for x := 0; x < width; x++ {
+				for k := 0; k < 4; k++ {
+					var sum float64
+					for i, w := range weights {
+						sx := max(0, min(x+i-radius, width-1))
+						sum += w * float64(in[sx*4+k])
+					}
+					out[x*4+k] = uint16(min(sum+0.5, 65535))
+				}
+			}
+		}
+	})
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := dst.pix[y*width*4:][:width*4]
+			for i := range out {
+				var sum float64
+				for j, w := range weights {
+					sy := max(0, min(y+j-radius, height-1))
+					sum += w * float64(rows.pix[sy*width*4+i])
+				}
+				out[i] = uint16(min(sum+0.5, 65535))
+			}
+		}
+	})
+	return dst
+}
@@ -0,0 +1,56 @@
+package effects
+
+import (
+	"image"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// FrameFunc 逐帧处理函数，t 为帧在剪辑中的时间
+type FrameFunc func(frame image.Image, t time.Duration) (image.Image, error)
+
+// FuncEffect 由函数实现的特效，用于在渲染中插入检测、标注等外部处理
+type FuncEffect struct {
+	TransformEffect
+	fn         FrameFunc
+	outputSize func(inW, inH int) (int, int)
+}
+
+// NewFuncEffect 创建函数特效，fn 不应改变帧尺寸；改变尺寸时使用 NewResizingFuncEffect
+func NewFuncEffect(name string, fn FrameFunc) *FuncEffect {
+	return NewResizingFuncEffect(name, fn, nil)
+}
+
+// NewResizingFuncEffect 创建会改变帧尺寸的函数特效，outputSize 为 nil 时视为尺寸不变
+func NewResizingFuncEffect(name string, fn FrameFunc, outputSize func(inW, inH int) (int, int)) *FuncEffect {
+	return &FuncEffect{
+		TransformEffect: TransformEffect{name: name},
+		fn:              fn,
+		outputSize:      outputSize,
+	}
+}
+
+// Apply 应用函数特效
+func (fe *FuncEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 函数需要逐帧取时间，由 EffectVideoClip 调用 ApplyToFrameAt
+	return clip, nil
+}
+
+// ApplyToFrame 以 t=0 调用处理函数
+func (fe *FuncEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	return fe.fn(frame, 0)
+}
+
+// ApplyToFrameAt 以帧时间调用处理函数
+func (fe *FuncEffect) ApplyToFrameAt(frame image.Image, t time.Duration) (image.Image, error) {
+	return fe.fn(frame, t)
+}
+
+// OutputSize 由 outputSize 推算输出尺寸
+func (fe *FuncEffect) OutputSize(inW, inH int) (int, int) {
+	if fe.outputSize == nil {
+		return inW, inH
+	}
+	return fe.outputSize(inW, inH)
+}
@@ -0,0 +1,210 @@
+package effects
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// LUT 3D 颜色查找表，Table 按 R 最快、B 最慢的顺序存放 Size³ 个 RGB 三元组（0–1）
+type LUT struct {
+	Title string
+	Size  int
+	Table []float64
+}
+
+// LoadCubeLUT 读取 .cube 格式（Adobe/Resolve）的 3D LUT 文件
+func LoadCubeLUT(filename string) (*LUT, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("打开 LUT 文件失败: %w", err)
+	}
+	defer file.Close()
+	lut, err := ParseCubeLUT(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return lut, nil
+}
+
+// ParseCubeLUT 解析 .cube 内容，支持 TITLE、LUT_3D_SIZE，DOMAIN_MIN/MAX 须为默认的 0 和 1
+func ParseCubeLUT(r io.Reader) (*LUT, error) {
+	lut := &LUT{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "TITLE":
+			lut.Title = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "TITLE")), `"`)
+			continue
+		case "LUT_3D_SIZE":
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 2 || size > 256 {
+				return nil, fmt.Errorf("第 %d 行: 无效的 LUT 尺寸: %s", lineNo, line)
+			}
+			lut.Size = size
+			lut.Table = make([]float64, 0, size*size*size*3)
+			continue
+		case "LUT_1D_SIZE":
+			return nil, fmt.Errorf("第 %d 行: 不支持 1D LUT", lineNo)
+		case "DOMAIN_MIN", "DOMAIN_MAX":
+			want := 0.0
+			if fields[0] == "DOMAIN_MAX" {
+				want = 1
+			}
+			for _, v := range fields[1:] {
+				if f, err := strconv.ParseFloat(v, 64); err != nil || f != want {
+					return nil, fmt.Errorf("第 %d 行: 不支持非默认的定义域: %s", lineNo, line)
+				}
+			}
+			continue
+		}
+
+		if lut.Size == 0 {
+			return nil, fmt.Errorf("第 %d 行: 数据出现在 LUT_3D_SIZE 之前", lineNo)
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("第 %d 行: 每行应为 3 个数值: %s", lineNo, line)
+		}
+		for _, v := range fields {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: 无效的数值: %s", lineNo, v)
+			}
+			lut.Table = append(lut.Table, f)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 LUT 失败: %w", err)
+	}
+	if lut.Size == 0 {
+		return nil, fmt.Errorf("缺少 LUT_3D_SIZE")
+	}
+	if want := lut.Size * lut.Size * lut.Size * 3; len(lut.Table) != want {
+		return nil, fmt.Errorf("LUT 数据数量 %d 与尺寸 %d 不符（应为 %d）", len(lut.Table)/3, lut.Size, want/3)
+	}
+	return lut, nil
+}
+
+// Lookup 三线性插值查找 (r, g, b) 映射后的颜色，输入输出均为 0–1
+func (l *LUT) Lookup(r, g, b float64) (float64, float64, float64) {
+	scale := float64(l.Size - 1)
+	fr, fg, fb := clamp01(r)*scale, clamp01(g)*scale, clamp01(b)*scale
+	r0, g0, b0 := int(fr), int(fg), int(fb)
+	r1, g1, b1 := min(r0+1, l.Size-1), min(g0+1, l.Size-1), min(b0+1, l.Size-1)
+	dr, dg, db := fr-float64(r0), fg-float64(g0), fb-float64(b0)
+
+	var out [3]float64
+	for c := 0; c < 3; c++ {
+		at := func(ri, gi, bi int) float64 {
+			return l.Table[((bi*l.Size+gi)*l.Size+ri)*3+c]
+		}
+		c00 := at(r0, g0, b0)*(1-dr) + at(r1, g0, b0)*dr
+		c10 := at(r0, g1, b0)*(1-dr) + at(r1, g1, b0)*dr
+		c01 := at(r0, g0, b1)*(1-dr) + at(r1, g0, b1)*dr
+		c11 := at(r0, g1, b1)*(1-dr) + at(r1, g1, b1)*dr
+		c0 := c00*(1-dg) + c10*dg
+		c1 := c01*(1-dg) + c11*dg
+		out[c] = c0*(1-db) + c1*db
+	}
+	return out[0], out[1], out[2]
+}
+
+// clamp01 限制在 0–1
+func clamp01(v float64) float64 {
+	return max(0, min(v, 1))
+}
+
+// LUTEffect 3D LUT 调色特效
+type LUTEffect struct {
+	TransformEffect
+	lut       *LUT
+	intensity float64 // 与原色混合的比例，1 为完全应用
+}
+
+// NewLUTEffect 创建 LUT 特效，intensity 限制在 0–1
+func NewLUTEffect(lut *LUT, intensity float64) *LUTEffect {
+	return &LUTEffect{
+		TransformEffect: TransformEffect{name: "lut"},
+		lut:             lut,
+		intensity:       clamp01(intensity),
+	}
+}
+
+// CacheKey 返回LUT 与混合比例，LUT 按地址区分
+func (le *LUTEffect) CacheKey() string {
+	return fmt.Sprintf("lut(%p,%g)", le.lut, le.intensity)
+}
+
+// Apply 应用 LUT 特效
+func (le *LUTEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 这里应该返回一个新的剪辑，应用了 LUT 特效
+	// 简化实现，直接返回原剪辑
+	return clip, nil
+}
+
+// ApplyToFrame 应用 LUT 到帧，后端只处理 intensity 为 1 的情况
+func (le *LUTEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	if le.intensity == 1 {
+		if result, ok, err := accelerate(func(backend Backend) (image.Image, error) {
+			return backend.ApplyLUT(frame, le.lut)
+		}); ok {
+			return result, err
+		}
+	}
+
+	bounds := frame.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	Parallel(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				r, g, b, a := frame.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				rf, gf, bf := float64(r)/65535, float64(g)/65535, float64(b)/65535
+				lr, lg, lb := le.lut.Lookup(rf, gf, bf)
+				dst.Set(x, y, color.RGBA{
+					R: uint8(clamp01(rf+(lr-rf)*le.intensity)*255 + 0.5),
+					G: uint8(clamp01(gf+(lg-gf)*le.intensity)*255 + 0.5),
+					B: uint8(clamp01(bf+(lb-bf)*le.intensity)*255 + 0.5),
+					A: uint8(a >> 8),
+				})
+			}
+		}
+	})
+	return dst, nil
+}
+
+// ApplyToFrame64 以 16 位精度应用 LUT，不经过加速后端
+func (le *LUTEffect) ApplyToFrame64(frame image.Image, t time.Duration) (image.Image, error) {
+	bounds := frame.Bounds()
+	dst := image.NewRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	Parallel(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				r, g, b, a := frame.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				rf, gf, bf := float64(r)/65535, float64(g)/65535, float64(b)/65535
+				lr, lg, lb := le.lut.Lookup(rf, gf, bf)
+				dst.SetRGBA64(x, y, color.RGBA64{
+					R: uint16(clamp01(rf+(lr-rf)*le.intensity)*65535 + 0.5),
+					G: uint16(clamp01(gf+(lg-gf)*le.intensity)*65535 + 0.5),
+					B: uint16(clamp01(bf+(lb-bf)*le.intensity)*65535 + 0.5),
+					A: uint16(a),
+				})
+			}
+		}
+	})
+	return dst, nil
+}
@@ -0,0 +1,187 @@
+package effects
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// MaskEffect 以遮罩剪辑的亮度作为帧的 alpha：白色不透明，黑色完全透明
+//
+// 遮罩尺寸与帧不同时按最近邻缩放，合成时透明部分露出下层剪辑。
+type MaskEffect struct {
+	TransformEffect
+	mask core.VideoClip
+}
+
+// NewMaskEffect 创建遮罩特效
+func NewMaskEffect(mask core.VideoClip) *MaskEffect {
+	return &MaskEffect{
+		TransformEffect: TransformEffect{name: "mask"},
+		mask:            mask,
+	}
+}
+
+// Mask 返回遮罩剪辑
+func (me *MaskEffect) Mask() core.VideoClip {
+	return me.mask
+}
+
+// CacheKey 返回遮罩剪辑，按剪辑标识区分
+func (me *MaskEffect) CacheKey() string {
+	return fmt.Sprintf("mask(%s)", core.ClipID(me.mask))
+}
+
+// Apply 应用遮罩特效
+func (me *MaskEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 遮罩需要逐帧取时间，由 EffectVideoClip 调用 ApplyToFrameAt
+	return clip, nil
+}
+
+// ApplyToFrame 使用遮罩的第一帧
+func (me *MaskEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	return me.ApplyToFrameAt(frame, 0)
+}
+
+// ApplyToFrameAt 使用遮罩在时间 t 处的帧
+func (me *MaskEffect) ApplyToFrameAt(frame image.Image, t time.Duration) (image.Image, error) {
+	maskFrame, err := me.mask.GetFrame(t)
+	if err != nil {
+		return nil, fmt.Errorf("获取遮罩帧失败: %w", err)
+	}
+	return ApplyMask(frame, maskFrame), nil
+}
+
+// ApplyMask 将 mask 的亮度乘到 frame 的 alpha 上，返回 *image.NRGBA
+func ApplyMask(frame, mask image.Image) *image.NRGBA {
+	bounds := frame.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	maskBounds := mask.Bounds()
+	maskWidth, maskHeight := maskBounds.Dx(), maskBounds.Dy()
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	gray, isGray := mask.(*image.Gray)
+	for y := 0; y < height; y++ {
+		my := maskBounds.Min.Y + y*maskHeight/height
+		for x := 0; x < width; x++ {
+			mx := maskBounds.Min.X + x*maskWidth/width
+
+			var luma uint32
+			if isGray {
+				luma = uint32(gray.GrayAt(mx, my).Y) * 0x101
+			} else {
+				luma = uint32(color.Gray16Model.Convert(mask.At(mx, my)).(color.Gray16).Y)
+			}
+
+			c := color.NRGBA64Model.Convert(frame.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA64)
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(c.R >> 8)
+			dst.Pix[i+1] = uint8(c.G >> 8)
+			dst.Pix[i+2] = uint8(c.B >> 8)
+			dst.Pix[i+3] = uint8(uint32(c.A) * luma / 0xffff >> 8)
+		}
+	}
+	return dst
+}
+
+// RoundedCornersEffect 把帧的四角裁成圆角，角外透明，用于画中画等叠加层
+type RoundedCornersEffect struct {
+	TransformEffect
+	radius int
+}
+
+// NewRoundedCornersEffect 创建圆角特效，radius 为像素半径，超过短边一半时按短边一半处理
+func NewRoundedCornersEffect(radius int) *RoundedCornersEffect {
+	return &RoundedCornersEffect{
+		TransformEffect: TransformEffect{name: "rounded_corners"},
+		radius:          max(radius, 0),
+	}
+}
+
+// CacheKey 返回圆角半径
+func (re *RoundedCornersEffect) CacheKey() string {
+	return fmt.Sprintf("rounded_corners(%d)", re.radius)
+}
+
+// Apply 应用圆角特效
+func (re *RoundedCornersEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 形状遮罩只处理像素，由 EffectVideoClip 逐帧调用
+	return clip, nil
+}
+
+// ApplyToFrame 按圆角形状设置 alpha，边缘抗锯齿，返回 *image.NRGBA
+func (re *RoundedCornersEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	bounds := frame.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	radius := math.Min(float64(re.radius), math.Min(width, height)/2)
+	return applyCoverage(frame, func(x, y float64) float64 {
+		// 到最近圆角圆心的偏移，不在角区时为 0
+		dx := math.Max(0, math.Max(radius-x, x-(width-radius)))
+		dy := math.Max(0, math.Max(radius-y, y-(height-radius)))
+		if dx == 0 || dy == 0 {
+			return 1
+		}
+		return edgeCoverage(radius - math.Hypot(dx, dy))
+	}), nil
+}
+
+// CircleMaskEffect 只保留帧中心的内切圆，圆外透明，用于头像式叠加
+type CircleMaskEffect struct {
+	TransformEffect
+}
+
+// NewCircleMaskEffect 创建圆形遮罩特效，直径为帧的短边；非正方形的帧可先用 CropEffect 裁成正方形
+func NewCircleMaskEffect() *CircleMaskEffect {
+	return &CircleMaskEffect{TransformEffect: TransformEffect{name: "circle_mask"}}
+}
+
+// CacheKey 返回固定的键
+func (ce *CircleMaskEffect) CacheKey() string {
+	return "circle_mask"
+}
+
+// Apply 应用圆形遮罩特效
+func (ce *CircleMaskEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 形状遮罩只处理像素，由 EffectVideoClip 逐帧调用
+	return clip, nil
+}
+
+// ApplyToFrame 按内切圆设置 alpha，边缘抗锯齿，返回 *image.NRGBA
+func (ce *CircleMaskEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	bounds := frame.Bounds()
+	cx, cy := float64(bounds.Dx())/2, float64(bounds.Dy())/2
+	radius := math.Min(cx, cy)
+	return applyCoverage(frame, func(x, y float64) float64 {
+		return edgeCoverage(radius - math.Hypot(x-cx, y-cy))
+	}), nil
+}
+
+// edgeCoverage 由像素中心到形状边界的有符号距离（内部为正）估算覆盖率，得到一个像素宽的抗锯齿边缘
+func edgeCoverage(distance float64) float64 {
+	return math.Max(0, math.Min(1, distance+0.5))
+}
+
+// applyCoverage 将 coverage（以像素中心坐标求值，0–1）乘到 frame 的 alpha 上，返回 *image.NRGBA
+func applyCoverage(frame image.Image, coverage func(x, y float64) float64) *image.NRGBA {
+	bounds := frame.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < width; x++ {
+				c := color.NRGBA64Model.Convert(frame.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA64)
+				cover := coverage(float64(x)+0.5, float64(y)+0.5)
+				i := dst.PixOffset(x, y)
+				dst.Pix[i+0] = uint8(c.R >> 8)
+				dst.Pix[i+1] = uint8(c.G >> 8)
+				dst.Pix[i+2] = uint8(c.B >> 8)
+				dst.Pix[i+3] = uint8(math.Round(float64(c.A>>8) * cover))
+			}
+		}
+	})
+	return dst
+}
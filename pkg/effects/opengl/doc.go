@@ -0,0 +1,17 @@
+// Package opengl 以 OpenGL 4.3 计算着色器实现 effects.Backend，加速模糊、颜色调整、3D LUT 和缩放
+//
+// 通过 EGL 创建无窗口（surfaceless）上下文，不需要显示服务器，也可使用 Mesa llvmpipe 等软件驱动。
+// 依赖 cgo 和系统的 libEGL、libOpenGL（GLVND），默认不参与构建，以 opengl 标签编译：
+//
+//	go build -tags opengl ./...
+//
+// 导入本包后在 init 中注册名为 opengl 的后端，结果与内置 CPU 实现逐字节相同（LUT 的插值误差不超过 1）：
+//
+//	import _ "moviepy-go/pkg/effects/opengl"
+//
+//	if err := effects.UseBackend("opengl"); err != nil {
+//		log.Printf("使用 CPU 特效: %v", err)
+//	}
+//
+// 也可以在配置文件中设置 backend: opengl。只处理 *image.RGBA 帧，其他格式回退到 CPU。
+package opengl
@@ -0,0 +1,377 @@
+//go:build opengl
+
+package opengl
+
+/*
+#cgo pkg-config: egl opengl
+#include <stdlib.h>
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+#define GL_GLEXT_PROTOTYPES
+#include <GL/glcorearb.h>
+
+// mgContext 无窗口的 EGL 上下文
+typedef struct {
+	EGLDisplay display;
+	EGLContext context;
+} mgContext;
+
+// mgCreate 创建 OpenGL 4.3 核心上下文并设为当前线程的上下文，失败时返回错误描述
+static const char *mgCreate(mgContext *c) {
+	c->display = EGL_NO_DISPLAY;
+	c->context = EGL_NO_CONTEXT;
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (getPlatformDisplay != NULL) {
+		c->display = getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+	}
+	if (c->display == EGL_NO_DISPLAY) {
+		c->display = eglGetDisplay(EGL_DEFAULT_DISPLAY);
+	}
+	if (c->display == EGL_NO_DISPLAY) {
+		return "没有可用的 EGL 显示";
+	}
+	EGLint major, minor;
+	if (!eglInitialize(c->display, &major, &minor)) {
+		c->display = EGL_NO_DISPLAY;
+		return "初始化 EGL 失败";
+	}
+	if (!eglBindAPI(EGL_OPENGL_API)) {
+		return "EGL 不支持 OpenGL";
+	}
+	EGLint attribs[] = {
+		EGL_CONTEXT_MAJOR_VERSION, 4,
+		EGL_CONTEXT_MINOR_VERSION, 3,
+		EGL_CONTEXT_OPENGL_PROFILE_MASK, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+		EGL_NONE,
+	};
+	// 计算着色器不需要帧缓冲，依赖 EGL_KHR_no_config_context 和 EGL_KHR_surfaceless_context
+	c->context = eglCreateContext(c->display, EGL_NO_CONFIG_KHR, EGL_NO_CONTEXT, attribs);
+	if (c->context == EGL_NO_CONTEXT) {
+		return "创建 OpenGL 4.3 上下文失败";
+	}
+	if (!eglMakeCurrent(c->display, EGL_NO_SURFACE, EGL_NO_SURFACE, c->context)) {
+		return "激活 OpenGL 上下文失败";
+	}
+	return NULL;
+}
+
+// mgDestroy 释放上下文和显示
+static void mgDestroy(mgContext *c) {
+	if (c->display == EGL_NO_DISPLAY) {
+		return;
+	}
+	eglMakeCurrent(c->display, EGL_NO_SURFACE, EGL_NO_SURFACE, EGL_NO_CONTEXT);
+	if (c->context != EGL_NO_CONTEXT) {
+		eglDestroyContext(c->display, c->context);
+	}
+	eglTerminate(c->display);
+	c->display = EGL_NO_DISPLAY;
+	c->context = EGL_NO_CONTEXT;
+}
+
+// mgCompile 编译并链接计算着色器，失败时返回 0 并把日志写入 log
+static GLuint mgCompile(const char *source, char *log, GLsizei size) {
+	GLuint shader = glCreateShader(GL_COMPUTE_SHADER);
+	glShaderSource(shader, 1, &source, NULL);
+	glCompileShader(shader);
+	GLint ok;
+	glGetShaderiv(shader, GL_COMPILE_STATUS, &ok);
+	if (!ok) {
+		glGetShaderInfoLog(shader, size, NULL, log);
+		glDeleteShader(shader);
+		return 0;
+	}
+	GLuint program = glCreateProgram();
+	glAttachShader(program, shader);
+	glLinkProgram(program);
+	glDeleteShader(shader);
+	glGetProgramiv(program, GL_LINK_STATUS, &ok);
+	if (!ok) {
+		glGetProgramInfoLog(program, size, NULL, log);
+		glDeleteProgram(program);
+		return 0;
+	}
+	return program;
+}
+
+// mgUpload 把 data 写入缓冲并绑定到着色器存储块 binding
+static void mgUpload(GLuint buffer, GLuint binding, const void *data, GLsizeiptr size) {
+	glBindBuffer(GL_SHADER_STORAGE_BUFFER, buffer);
+	glBufferData(GL_SHADER_STORAGE_BUFFER, size, data, GL_STREAM_DRAW);
+	glBindBufferBase(GL_SHADER_STORAGE_BUFFER, binding, buffer);
+}
+
+// mgRun 以 16×16 的工作组覆盖 width×height 执行当前程序，把 binding 1 的结果读回 out，返回 GL 错误码
+static GLenum mgRun(GLuint output, GLint width, GLint height, void *out, GLsizeiptr size) {
+	glDispatchCompute((width + 15) / 16, (height + 15) / 16, 1);
+	glMemoryBarrier(GL_BUFFER_UPDATE_BARRIER_BIT);
+	glBindBuffer(GL_SHADER_STORAGE_BUFFER, output);
+	glGetBufferSubData(GL_SHADER_STORAGE_BUFFER, 0, size, out);
+	return glGetError();
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"moviepy-go/pkg/effects"
+)
+
+// Name 后端名称，用于 effects.UseBackend 和配置项 backend
+const Name = "opengl"
+
+func init() {
+	effects.RegisterBackend(Name, New)
+}
+
+// program 着色器程序及其 uniform 位置
+type program struct {
+	id       C.GLuint
+	uniforms map[string]C.GLint
+}
+
+// Backend OpenGL 计算着色器后端
+//
+// OpenGL 上下文只能在创建它的线程上使用，所有 GL 调用都在一个锁定了系统线程的协程中串行执行，
+// 因此 Backend 可被多个协程并发调用。
+type Backend struct {
+	calls     chan func()
+	closed    chan struct{} // Close 时关闭
+	exited    chan struct{} // GL 协程释放资源后关闭
+	closeOnce sync.Once
+	context   C.mgContext
+	programs  map[string]*program
+	buffers   [3]C.GLuint // 输入、输出和 LUT 表
+	maxBytes  int64       // 单个着色器存储块的大小上限
+
+	// lut、lutTable 最近一次上传的 LUT，帧间复用
+	lut      *effects.LUT
+	lutTable []float32
+}
+
+// New 创建后端，没有可用的 OpenGL 4.3 驱动时返回错误
+func New() (effects.Backend, error) {
+	b := &Backend{calls: make(chan func()), closed: make(chan struct{}), exited: make(chan struct{})}
+	ready := make(chan error, 1)
+	go b.loop(ready)
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// loop 在锁定的系统线程上创建上下文并执行调用，直到 Close
+func (b *Backend) loop(ready chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(b.exited)
+
+	if err := b.init(); err != nil {
+		b.release()
+		ready <- err
+		return
+	}
+	ready <- nil
+	for {
+		select {
+		case call := <-b.calls:
+			call()
+		case <-b.closed:
+			b.release()
+			return
+		}
+	}
+}
+
+// init 创建上下文、编译着色器并分配缓冲，在 GL 线程上调用
+func (b *Backend) init() error {
+	if msg := C.mgCreate(&b.context); msg != nil {
+		return fmt.Errorf("OpenGL: %s", C.GoString(msg))
+	}
+	var size C.GLint64
+	C.glGetInteger64v(C.GL_MAX_SHADER_STORAGE_BLOCK_SIZE, &size)
+	b.maxBytes = int64(size)
+
+	sources := map[string]string{"blur": blurShader, "adjust": adjustShader, "lut": lutShader, "scale": scaleShader}
+	b.programs = make(map[string]*program, len(sources))
+	for name, source := range sources {
+		p, err := compile(source)
+		if err != nil {
+			return fmt.Errorf("OpenGL: 编译 %s 着色器失败: %w", name, err)
+		}
+		b.programs[name] = p
+	}
+	C.glGenBuffers(C.GLsizei(len(b.buffers)), &b.buffers[0])
+	if code := C.glGetError(); code != C.GL_NO_ERROR {
+		return fmt.Errorf("OpenGL: 初始化失败（错误 0x%x）", uint32(code))
+	}
+	return nil
+}
+
+// compile 编译着色器程序
+func compile(source string) (*program, error) {
+	csource := C.CString(source)
+	defer C.free(unsafe.Pointer(csource))
+	var log [4096]C.char
+	id := C.mgCompile(csource, &log[0], C.GLsizei(len(log)))
+	if id == 0 {
+		return nil, errors.New(C.GoString(&log[0]))
+	}
+	return &program{id: id, uniforms: map[string]C.GLint{}}, nil
+}
+
+// release 释放 GL 资源和上下文，在 GL 线程上调用
+func (b *Backend) release() {
+	if b.context.context != nil {
+		for _, p := range b.programs {
+			C.glDeleteProgram(p.id)
+		}
+		if b.buffers[0] != 0 {
+			C.glDeleteBuffers(C.GLsizei(len(b.buffers)), &b.buffers[0])
+		}
+	}
+	C.mgDestroy(&b.context)
+}
+
+// Name 后端名称
+func (b *Backend) Name() string {
+	return Name
+}
+
+// Close 释放设备资源，等待正在执行的调用完成
+func (b *Backend) Close() error {
+	b.closeOnce.Do(func() { close(b.closed) })
+	<-b.exited
+	return nil
+}
+
+// do 在 GL 线程上执行 call
+func (b *Backend) do(call func() (image.Image, error)) (result image.Image, err error) {
+	done := make(chan struct{})
+	select {
+	case b.calls <- func() {
+		defer close(done)
+		result, err = call()
+	}:
+	case <-b.closed:
+		return nil, fmt.Errorf("OpenGL 后端已关闭")
+	}
+	<-done
+	return result, err
+}
+
+// uniform 设置当前程序的整数 uniform
+func (p *program) uniform(name string, values ...int) {
+	location, ok := p.uniforms[name]
+	if !ok {
+		cname := C.CString(name)
+		location = C.glGetUniformLocation(p.id, cname)
+		C.free(unsafe.Pointer(cname))
+		p.uniforms[name] = location
+	}
+	switch len(values) {
+	case 1:
+		C.glUniform1i(location, C.GLint(values[0]))
+	case 2:
+		C.glUniform2i(location, C.GLint(values[0]), C.GLint(values[1]))
+	}
+}
+
+// run 以 name 程序把 frame 处理为 width×height 的新帧，setup 在上传像素后设置 uniform 等额外输入
+//
+// 只处理非空的 *image.RGBA，其他帧和超过存储块上限的尺寸返回 effects.ErrBackendUnsupported。
+func (b *Backend) run(name string, frame image.Image, width, height int, setup func(p *program)) (image.Image, error) {
+	rgba, ok := frame.(*image.RGBA)
+	bounds := frame.Bounds()
+	if !ok || bounds.Empty() || width <= 0 || height <= 0 {
+		return nil, effects.ErrBackendUnsupported
+	}
+	inBytes, outBytes := int64(bounds.Dx()*bounds.Dy()*4), int64(width*height*4)
+	if max(inBytes, outBytes) > b.maxBytes {
+		return nil, effects.ErrBackendUnsupported
+	}
+	pix := packed(rgba)
+
+	return b.do(func() (image.Image, error) {
+		p := b.programs[name]
+		C.glUseProgram(p.id)
+		C.mgUpload(b.buffers[0], 0, unsafe.Pointer(&pix[0]), C.GLsizeiptr(inBytes))
+		C.mgUpload(b.buffers[1], 1, nil, C.GLsizeiptr(outBytes))
+		p.uniform("srcSize", bounds.Dx(), bounds.Dy())
+		p.uniform("dstSize", width, height)
+		if setup != nil {
+			setup(p)
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		if code := C.mgRun(b.buffers[1], C.GLint(width), C.GLint(height), unsafe.Pointer(&dst.Pix[0]), C.GLsizeiptr(outBytes)); code != C.GL_NO_ERROR {
+			return nil, fmt.Errorf("%s 着色器执行失败（OpenGL 错误 0x%x）", name, uint32(code))
+		}
+		return dst, nil
+	})
+}
+
+// packed 返回按行紧密排列的像素，原点为 (0,0) 且行间无填充时直接共享 Pix
+func packed(rgba *image.RGBA) []byte {
+	bounds := rgba.Bounds()
+	rowBytes := bounds.Dx() * 4
+	if rgba.Stride == rowBytes && bounds.Min == (image.Point{}) {
+		return rgba.Pix[:rowBytes*bounds.Dy()]
+	}
+	pix := make([]byte, rowBytes*bounds.Dy())
+	for y := 0; y < bounds.Dy(); y++ {
+		copy(pix[y*rowBytes:], rgba.Pix[rgba.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:rowBytes])
+	}
+	return pix
+}
+
+// Blur 半径 radius 的均值模糊
+func (b *Backend) Blur(frame image.Image, radius int) (image.Image, error) {
+	bounds := frame.Bounds()
+	return b.run("blur", frame, bounds.Dx(), bounds.Dy(), func(p *program) {
+		p.uniform("radius", max(radius, 0))
+	})
+}
+
+// Adjust 依次应用亮度、对比度、饱和度调整
+func (b *Backend) Adjust(frame image.Image, adjust effects.ColorAdjust) (image.Image, error) {
+	bounds := frame.Bounds()
+	return b.run("adjust", frame, bounds.Dx(), bounds.Dy(), func(p *program) {
+		p.uniform("brightness", int(math.Round(math.Max(adjust.Brightness, 0)*256)))
+		p.uniform("contrast", int(math.Round(adjust.Contrast*256)))
+		p.uniform("contrastOffset", int(math.Round(128*(1-adjust.Contrast))))
+		p.uniform("saturation", int(math.Round(adjust.Saturation*512)))
+	})
+}
+
+// ApplyLUT 三线性插值应用 3D LUT，同一 LUT 只在第一次使用时上传
+func (b *Backend) ApplyLUT(frame image.Image, lut *effects.LUT) (image.Image, error) {
+	if lut == nil || lut.Size < 2 || len(lut.Table) != lut.Size*lut.Size*lut.Size*3 {
+		return nil, effects.ErrBackendUnsupported
+	}
+	bounds := frame.Bounds()
+	return b.run("lut", frame, bounds.Dx(), bounds.Dy(), func(p *program) {
+		if b.lut != lut {
+			b.lut = lut
+			b.lutTable = make([]float32, len(lut.Table))
+			for i, v := range lut.Table {
+				b.lutTable[i] = float32(v)
+			}
+			C.mgUpload(b.buffers[2], 2, unsafe.Pointer(&b.lutTable[0]), C.GLsizeiptr(len(b.lutTable)*4))
+		}
+		C.glBindBufferBase(C.GL_SHADER_STORAGE_BUFFER, 2, b.buffers[2])
+		p.uniform("lutSize", lut.Size)
+	})
+}
+
+// Scale 最近邻缩放到 width x height
+func (b *Backend) Scale(frame image.Image, width, height int) (image.Image, error) {
+	return b.run("scale", frame, width, height, nil)
+}
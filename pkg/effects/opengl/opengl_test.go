@@ -0,0 +1,116 @@
+//go:build opengl
+
+package opengl
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"moviepy-go/pkg/effects"
+)
+
+// testFrame 随机内容的预乘 RGBA 帧，取自更大画布的子图以覆盖行间填充和非零原点
+func testFrame(width, height int) *image.RGBA {
+	random := rand.New(rand.NewSource(int64(width*1000 + height)))
+	canvas := image.NewRGBA(image.Rect(0, 0, width+7, height+5))
+	for y := 0; y < canvas.Rect.Dy(); y++ {
+		for x := 0; x < canvas.Rect.Dx(); x++ {
+			a := uint8(random.Intn(256))
+			if random.Intn(3) == 0 {
+				a = 255
+			}
+			canvas.SetRGBA(x, y, color.RGBA{
+				R: uint8(random.Intn(int(a) + 1)),
+				G: uint8(random.Intn(int(a) + 1)),
+				B: uint8(random.Intn(int(a) + 1)),
+				A: a,
+			})
+		}
+	}
+	return canvas.SubImage(image.Rect(3, 2, 3+width, 2+height)).(*image.RGBA)
+}
+
+// testLUT 非线性的 LUT，使三线性插值的各项都不为零
+func testLUT(size int) *effects.LUT {
+	lut := &effects.LUT{Size: size}
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				fr, fg, fb := float64(r)/float64(size-1), float64(g)/float64(size-1), float64(b)/float64(size-1)
+				lut.Table = append(lut.Table, fr*fr, (fg+fb)/2, 1-fb*fr)
+			}
+		}
+	}
+	return lut
+}
+
+// compare 比较 GPU 与 CPU 的结果，任一通道差值超过 tolerance 时失败
+func compare(t *testing.T, name string, frame image.Image, effect interface {
+	ApplyToFrame(image.Image) (image.Image, error)
+}, tolerance int) {
+	t.Helper()
+	if err := effects.UseBackend(Name); err != nil {
+		t.Fatal(err)
+	}
+	gpu, err := effect.ApplyToFrame(frame)
+	if err != nil {
+		t.Fatalf("%s: GPU 处理失败: %v", name, err)
+	}
+	if err := effects.UseBackend(effects.CPUBackend); err != nil {
+		t.Fatal(err)
+	}
+	cpu, err := effect.ApplyToFrame(frame)
+	if err != nil {
+		t.Fatalf("%s: CPU 处理失败: %v", name, err)
+	}
+
+	got, want := gpu.(*image.RGBA), cpu.(*image.RGBA)
+	if got.Rect != want.Rect {
+		t.Fatalf("%s: 尺寸 %v，期望 %v", name, got.Rect, want.Rect)
+	}
+	for i := range want.Pix {
+		if d := int(got.Pix[i]) - int(want.Pix[i]); d > tolerance || d < -tolerance {
+			x, y := i/4%want.Rect.Dx(), i/4/want.Rect.Dx()
+			t.Fatalf("%s: (%d, %d) 通道 %d 为 %d，CPU 为 %d", name, x, y, i%4, got.Pix[i], want.Pix[i])
+		}
+	}
+}
+
+func TestBackendMatchesCPU(t *testing.T) {
+	if err := effects.UseBackend(Name); err != nil {
+		t.Skipf("没有可用的 OpenGL 驱动: %v", err)
+	}
+	defer effects.UseBackend(effects.CPUBackend)
+
+	for _, size := range []image.Point{{1, 1}, {17, 9}, {64, 36}} {
+		frame := testFrame(size.X, size.Y)
+		compare(t, "blur(1)", frame, effects.NewBlurEffect(1), 0)
+		compare(t, "blur(5)", frame, effects.NewBlurEffect(5), 0)
+		compare(t, "brightness(1.3)", frame, effects.NewBrightnessEffect(1.3), 0)
+		compare(t, "brightness(0.4)", frame, effects.NewBrightnessEffect(0.4), 0)
+		compare(t, "contrast(1.8)", frame, effects.NewContrastEffect(1.8), 0)
+		compare(t, "contrast(-0.5)", frame, effects.NewContrastEffect(-0.5), 0)
+		compare(t, "saturation(0)", frame, effects.NewSaturationEffect(0), 0)
+		compare(t, "saturation(2.5)", frame, effects.NewSaturationEffect(2.5), 0)
+		compare(t, "lut(17)", frame, effects.NewLUTEffect(testLUT(17), 1), 1)
+		compare(t, "lut(2)", frame, effects.NewLUTEffect(testLUT(2), 1), 1)
+		compare(t, "resize(up)", frame, effects.NewResizeEffect(size.X*3, size.Y*2), 0)
+		compare(t, "resize(down)", frame, effects.NewResizeEffect(max(size.X/3, 2), max(size.Y/2, 2)), 0)
+	}
+}
+
+func TestBackendFallsBackForOtherFormats(t *testing.T) {
+	backend, err := New()
+	if err != nil {
+		t.Skipf("没有可用的 OpenGL 驱动: %v", err)
+	}
+	defer backend.Close()
+	if _, err := backend.Blur(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 1); err != effects.ErrBackendUnsupported {
+		t.Errorf("非 RGBA 帧返回 %v，期望 ErrBackendUnsupported", err)
+	}
+	if _, err := backend.Scale(image.NewRGBA(image.Rect(0, 0, 0, 4)), 2, 2); err != effects.ErrBackendUnsupported {
+		t.Errorf("空帧返回 %v，期望 ErrBackendUnsupported", err)
+	}
+}
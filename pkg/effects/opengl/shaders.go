@@ -0,0 +1,108 @@
+//go:build opengl
+
+package opengl
+
+// shaderHeader 各计算着色器共用的声明：src、dst 为按行紧密排列的 RGBA 像素，每个 uint 一像素（小端 R 在低位）
+const shaderHeader = `#version 430
+layout(local_size_x = 16, local_size_y = 16) in;
+layout(std430, binding = 0) readonly buffer Src { uint src[]; };
+layout(std430, binding = 1) writeonly buffer Dst { uint dst[]; };
+uniform ivec2 srcSize;
+uniform ivec2 dstSize;
+
+uvec4 unpackPixel(uint p) {
+	return uvec4(p & 0xffu, (p >> 8) & 0xffu, (p >> 16) & 0xffu, p >> 24);
+}
+
+uint packPixel(uvec4 c) {
+	return c.r | (c.g << 8) | (c.b << 16) | (c.a << 24);
+}
+`
+
+// blurShader 均值模糊，与 BlurEffect 相同按 16 位值求和后整除，画面外的像素不计入
+const blurShader = shaderHeader + `
+uniform int radius;
+
+void main() {
+	ivec2 p = ivec2(gl_GlobalInvocationID.xy);
+	if (p.x >= dstSize.x || p.y >= dstSize.y) {
+		return;
+	}
+	uvec4 sum = uvec4(0u);
+	uint count = 0u;
+	for (int y = max(p.y - radius, 0); y <= min(p.y + radius, srcSize.y - 1); y++) {
+		for (int x = max(p.x - radius, 0); x <= min(p.x + radius, srcSize.x - 1); x++) {
+			sum += unpackPixel(src[y * srcSize.x + x]) * 257u;
+			count++;
+		}
+	}
+	dst[p.y * dstSize.x + p.x] = packPixel((sum / count) >> 8u);
+}
+`
+
+// adjustShader 依次调整亮度、对比度、饱和度，定点运算与 pixel.Brightness、Contrast、Saturation 相同
+const adjustShader = shaderHeader + `
+uniform int brightness;     // round(factor·256)
+uniform int contrast;       // round(factor·256)
+uniform int contrastOffset; // round(128·(1−factor))
+uniform int saturation;     // round(factor·512)
+
+void main() {
+	ivec2 p = ivec2(gl_GlobalInvocationID.xy);
+	if (p.x >= dstSize.x || p.y >= dstSize.y) {
+		return;
+	}
+	uint i = uint(p.y * dstSize.x + p.x);
+	uvec4 px = unpackPixel(src[i]);
+	ivec3 c = ivec3(px.rgb);
+	c = clamp((c * brightness) >> 8, 0, 255);
+	c = clamp(((c * contrast) >> 8) + contrastOffset, 0, 255);
+	int l = (77 * c.r + 150 * c.g + 29 * c.b) >> 8;
+	c = clamp(l + (((c - l) * saturation) >> 9), 0, 255);
+	dst[i] = packPixel(uvec4(uvec3(c), px.a));
+}
+`
+
+// lutShader 三线性插值应用 3D LUT，table 与 effects.LUT.Table 的排列相同（R 最快、B 最慢）
+const lutShader = shaderHeader + `
+layout(std430, binding = 2) readonly buffer Table { float table[]; };
+uniform int lutSize;
+
+float at(int r, int g, int b, int c) {
+	return table[((b * lutSize + g) * lutSize + r) * 3 + c];
+}
+
+void main() {
+	ivec2 p = ivec2(gl_GlobalInvocationID.xy);
+	if (p.x >= dstSize.x || p.y >= dstSize.y) {
+		return;
+	}
+	uint i = uint(p.y * dstSize.x + p.x);
+	uvec4 px = unpackPixel(src[i]);
+	vec3 f = vec3(px.rgb) / 255.0 * float(lutSize - 1);
+	ivec3 i0 = ivec3(f);
+	ivec3 i1 = min(i0 + 1, lutSize - 1);
+	vec3 d = f - vec3(i0);
+	vec3 o;
+	for (int c = 0; c < 3; c++) {
+		float c00 = mix(at(i0.r, i0.g, i0.b, c), at(i1.r, i0.g, i0.b, c), d.r);
+		float c10 = mix(at(i0.r, i1.g, i0.b, c), at(i1.r, i1.g, i0.b, c), d.r);
+		float c01 = mix(at(i0.r, i0.g, i1.b, c), at(i1.r, i0.g, i1.b, c), d.r);
+		float c11 = mix(at(i0.r, i1.g, i1.b, c), at(i1.r, i1.g, i1.b, c), d.r);
+		o[c] = mix(mix(c00, c10, d.g), mix(c01, c11, d.g), d.b);
+	}
+	dst[i] = packPixel(uvec4(uvec3(clamp(o, 0.0, 1.0) * 255.0 + 0.5), px.a));
+}
+`
+
+// scaleShader 最近邻缩放，源坐标与 ResizeEffect 相同取 floor(x·srcW/dstW)
+const scaleShader = shaderHeader + `
+void main() {
+	ivec2 p = ivec2(gl_GlobalInvocationID.xy);
+	if (p.x >= dstSize.x || p.y >= dstSize.y) {
+		return;
+	}
+	ivec2 s = min(p * srcSize / dstSize, srcSize - 1);
+	dst[p.y * dstSize.x + p.x] = src[s.y * srcSize.x + s.x];
+}
+`
@@ -0,0 +1,88 @@
+package effects
+
+import (
+	"image"
+	"runtime"
+	"sync"
+
+	"moviepy-go/pkg/pixel"
+)
+
+// minBandRows 每个分段的最少行数，小图不值得拆分
+const minBandRows = 16
+
+// bandPool 所有特效共享的 goroutine 池，大小为首次使用时的 GOMAXPROCS
+var bandPool struct {
+	once  sync.Once
+	tasks chan func()
+}
+
+// Parallel 将 [0, height) 切分为水平分段，由共享的 goroutine 池并行调用 fn(y0, y1)，全部完成后返回
+//
+// fn 只应写入自己负责的行。当前 goroutine 也处理一个分段；池中没有空闲 goroutine 时分段在当前
+// goroutine 上执行，因此在特效内（如 RegionEffect 的内部特效）嵌套调用不会死锁。
+func Parallel(height int, fn func(y0, y1 int)) {
+	bands := min(runtime.GOMAXPROCS(0), (height+minBandRows-1)/minBandRows)
+	if bands <= 1 {
+		fn(0, height)
+		return
+	}
+	bandPool.once.Do(func() {
+		bandPool.tasks = make(chan func())
+		for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+			go func() {
+				for task := range bandPool.tasks {
+					task()
+				}
+			}()
+		}
+	})
+
+	size := (height + bands - 1) / bands
+	var wg sync.WaitGroup
+	for y0 := size; y0 < height; y0 += size {
+		y1 := min(y0+size, height)
+		wg.Add(1)
+		task := func() {
+			defer wg.Done()
+			fn(y0, y1)
+		}
+		select {
+		case bandPool.tasks <- task:
+		default:
+			task()
+		}
+	}
+	fn(0, size)
+	wg.Wait()
+}
+
+// parallelKernel 按分段并行地对 frame 的每一行运行像素内核，返回原点为 (0,0) 的新图像
+func parallelKernel(frame image.Image, kernel pixel.Kernel) *image.RGBA {
+	in := pixel.ToRGBA(frame)
+	bounds := in.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	rowBytes := bounds.Dx() * 4
+	Parallel(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			offset := in.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			kernel(out.Pix[y*out.Stride:y*out.Stride+rowBytes], in.Pix[offset:offset+rowBytes])
+		}
+	})
+	return out
+}
+
+// parallelKernel64 与 parallelKernel 相同，但以 *image.RGBA64 的 16 位行运行 kernel
+func parallelKernel64(frame image.Image, kernel pixel.Kernel64) *image.RGBA64 {
+	in := pixel.ToRGBA64(frame)
+	bounds := in.Bounds()
+	out := image.NewRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	rowBytes := bounds.Dx() * 8
+	Parallel(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			offset := in.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			kernel(out.Pix[y*out.Stride:y*out.Stride+rowBytes], in.Pix[offset:offset+rowBytes])
+		}
+	})
+	return out
+}
@@ -0,0 +1,158 @@
+package effects
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// RegionEffect 只在区域内应用内部特效，如模糊或马赛克人脸、车牌
+//
+// 区域可以是矩形（可随时间移动以跟随目标）或遮罩剪辑：遮罩白色部分取特效结果，
+// 黑色部分保留原帧，灰度部分按亮度混合。
+type RegionEffect struct {
+	TransformEffect
+	inner    VideoEffect
+	regionAt func(t time.Duration) image.Rectangle
+	mask     core.VideoClip
+}
+
+// NewRegionEffect 创建固定矩形区域特效
+func NewRegionEffect(rect image.Rectangle, inner VideoEffect) *RegionEffect {
+	return NewAnimatedRegionEffect(func(time.Duration) image.Rectangle { return rect }, inner)
+}
+
+// NewAnimatedRegionEffect 创建区域随时间变化的特效，regionAt 返回帧坐标系中的矩形
+func NewAnimatedRegionEffect(regionAt func(t time.Duration) image.Rectangle, inner VideoEffect) *RegionEffect {
+	return &RegionEffect{
+		TransformEffect: TransformEffect{name: "region"},
+		inner:           inner,
+		regionAt:        regionAt,
+	}
+}
+
+// NewMaskRegionEffect 创建以遮罩剪辑为区域的特效，遮罩尺寸与帧不同时按最近邻缩放
+func NewMaskRegionEffect(mask core.VideoClip, inner VideoEffect) *RegionEffect {
+	return &RegionEffect{
+		TransformEffect: TransformEffect{name: "region"},
+		inner:           inner,
+		mask:            mask,
+	}
+}
+
+// Inner 返回内部特效
+func (re *RegionEffect) Inner() VideoEffect {
+	return re.inner
+}
+
+// CacheKey 返回区域与内部特效的键，内部特效不可缓存时返回空字符串；区域函数按修订号、遮罩按剪辑标识区分
+func (re *RegionEffect) CacheKey() string {
+	inner := CacheKey(re.inner)
+	if inner == "" {
+		return ""
+	}
+	if re.mask != nil {
+		return fmt.Sprintf("region(%s,%s)", core.ClipID(re.mask), inner)
+	}
+	return fmt.Sprintf("region(#%d,%s)", re.revisionKey(), inner)
+}
+
+// Apply 应用区域特效
+func (re *RegionEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 区域需要逐帧取时间，由 EffectVideoClip 调用 ApplyToFrameAt
+	return clip, nil
+}
+
+// ApplyToFrame 使用 t=0 处的区域
+func (re *RegionEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	return re.ApplyToFrameAt(frame, 0)
+}
+
+// ApplyToFrameAt 使用时间 t 处的区域
+func (re *RegionEffect) ApplyToFrameAt(frame image.Image, t time.Duration) (image.Image, error) {
+	if re.mask != nil {
+		return re.applyMask(frame, t)
+	}
+
+	bounds := frame.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	rect := re.regionAt(t).Intersect(image.Rect(0, 0, width, height))
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), frame, bounds.Min, draw.Src)
+	if rect.Empty() {
+		return dst, nil
+	}
+
+	// 内部特效按 (0,0) 起点的图像处理，先把区域复制出来
+	sub := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(sub, sub.Bounds(), dst, rect.Min, draw.Src)
+	result, err := applyInner(re.inner, sub, t)
+	if err != nil {
+		return nil, fmt.Errorf("应用区域内特效失败: %w", err)
+	}
+	draw.Draw(dst, rect, result, result.Bounds().Min, draw.Src)
+	return dst, nil
+}
+
+// applyMask 对整帧应用内部特效后按遮罩亮度与原帧混合
+func (re *RegionEffect) applyMask(frame image.Image, t time.Duration) (image.Image, error) {
+	maskFrame, err := re.mask.GetFrame(t)
+	if err != nil {
+		return nil, fmt.Errorf("获取遮罩帧失败: %w", err)
+	}
+	bounds := frame.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), frame, bounds.Min, draw.Src)
+	result, err := applyInner(re.inner, src, t)
+	if err != nil {
+		return nil, fmt.Errorf("应用区域内特效失败: %w", err)
+	}
+	resultBounds := result.Bounds()
+
+	maskBounds := maskFrame.Bounds()
+	maskWidth, maskHeight := maskBounds.Dx(), maskBounds.Dy()
+	gray, isGray := maskFrame.(*image.Gray)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		my := maskBounds.Min.Y + y*maskHeight/height
+		for x := 0; x < width; x++ {
+			mx := maskBounds.Min.X + x*maskWidth/width
+
+			var level uint32
+			if isGray {
+				level = uint32(gray.GrayAt(mx, my).Y)
+			} else {
+				level = uint32(color.GrayModel.Convert(maskFrame.At(mx, my)).(color.Gray).Y)
+			}
+
+			i := src.PixOffset(x, y)
+			if level == 0 || !(image.Point{X: resultBounds.Min.X + x, Y: resultBounds.Min.Y + y}).In(resultBounds) {
+				copy(dst.Pix[i:i+4], src.Pix[i:i+4])
+				continue
+			}
+			r, g, b, a := result.At(resultBounds.Min.X+x, resultBounds.Min.Y+y).RGBA()
+			effected := [4]uint32{r >> 8, g >> 8, b >> 8, a >> 8}
+			for c := 0; c < 4; c++ {
+				orig := uint32(src.Pix[i+c])
+				dst.Pix[i+c] = uint8((orig*(255-level) + effected[c]*level) / 255)
+			}
+		}
+	}
+	return dst, nil
+}
+
+// applyInner 应用内部特效，时间相关的特效取时间 t
+func applyInner(effect VideoEffect, frame image.Image, t time.Duration) (image.Image, error) {
+	if timed, ok := effect.(TimedVideoEffect); ok {
+		return timed.ApplyToFrameAt(frame, t)
+	}
+	return effect.ApplyToFrame(frame)
+}
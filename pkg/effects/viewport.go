@@ -0,0 +1,63 @@
+package effects
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// ViewportEffect 把帧中随时间变化的矩形视口放大到整帧，用于推近、平移等虚拟镜头运动
+type ViewportEffect struct {
+	TransformEffect
+	viewportAt func(t time.Duration) image.Rectangle
+}
+
+// NewViewportEffect 创建视口特效，viewportAt 返回帧坐标系中的矩形，为空或覆盖整帧时帧不变
+//
+// 视口的宽高比应与帧一致，否则画面会被拉伸。
+func NewViewportEffect(viewportAt func(t time.Duration) image.Rectangle) *ViewportEffect {
+	return &ViewportEffect{
+		TransformEffect: TransformEffect{name: "viewport"},
+		viewportAt:      viewportAt,
+	}
+}
+
+// Viewport 返回时间 t 处的视口
+func (ve *ViewportEffect) Viewport(t time.Duration) image.Rectangle {
+	return ve.viewportAt(t)
+}
+
+// CacheKey 返回视口函数，按修订号区分
+func (ve *ViewportEffect) CacheKey() string {
+	return fmt.Sprintf("viewport(#%d)", ve.revisionKey())
+}
+
+// Apply 应用视口特效
+func (ve *ViewportEffect) Apply(clip core.Clip) (core.Clip, error) {
+	// 视口需要逐帧取时间，由 EffectVideoClip 调用 ApplyToFrameAt
+	return clip, nil
+}
+
+// ApplyToFrame 使用 t=0 处的视口
+func (ve *ViewportEffect) ApplyToFrame(frame image.Image) (image.Image, error) {
+	return ve.ApplyToFrameAt(frame, 0)
+}
+
+// ApplyToFrameAt 把时间 t 处的视口双线性缩放到整帧
+func (ve *ViewportEffect) ApplyToFrameAt(frame image.Image, t time.Duration) (image.Image, error) {
+	bounds := frame.Bounds()
+	full := image.Rect(0, 0, bounds.Dx(), bounds.Dy())
+	viewport := ve.viewportAt(t).Intersect(full)
+	if viewport.Empty() || viewport == full {
+		return frame, nil
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, viewport.Dx(), viewport.Dy()))
+	draw.Draw(src, src.Bounds(), frame, bounds.Min.Add(viewport.Min), draw.Src)
+	dst := image.NewRGBA(full)
+	scaleBilinear(dst, full, src)
+	return dst, nil
+}
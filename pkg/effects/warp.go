@@ -0,0 +1,126 @@
+package effects
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// Sampling 几何变换按逆映射取源像素的方式
+type Sampling int
+
+const (
+	SamplingBilinear Sampling = iota // 双线性插值（默认），斜边平滑
+	SamplingNearest                  // 最近邻，保留像素画等硬边缘，斜边有锯齿
+)
+
+// samplingNames 按 Sampling 取值排列的名称
+var samplingNames = []string{"bilinear", "nearest"}
+
+// String 返回取样方式名称
+func (s Sampling) String() string {
+	if s >= 0 && int(s) < len(samplingNames) {
+		return samplingNames[s]
+	}
+	return fmt.Sprintf("Sampling(%d)", int(s))
+}
+
+// ParseSampling 解析取样方式名称（bilinear、nearest）
+func ParseSampling(name string) (Sampling, error) {
+	for i, n := range samplingNames {
+		if strings.EqualFold(name, n) {
+			return Sampling(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的取样方式: %q（支持 %s）", name, strings.Join(samplingNames, "、"))
+}
+
+// maxSupersample 每边子取样数的上限
+const maxSupersample = 4
+
+// projective 行优先的 3×3 射影变换矩阵，把输出坐标映射为源坐标；仿射变换的最后一行为 0, 0, 1
+type projective [9]float64
+
+// apply 映射点 (x, y)，点位于变换的地平线之后时 ok 为 false
+func (m projective) apply(x, y float64) (sx, sy float64, ok bool) {
+	w := m[6]*x + m[7]*y + m[8]
+	if w <= 1e-12 {
+		return 0, 0, false
+	}
+	return (m[0]*x + m[1]*y + m[2]) / w, (m[3]*x + m[4]*y + m[5]) / w, true
+}
+
+// warpOptions warp 的取样参数
+type warpOptions struct {
+	sampling    Sampling
+	supersample int         // 每个输出像素每边的子取样数，小于 2 时只取像素中心
+	background  color.Color // nil 表示透明
+}
+
+// warp 按逆映射 inverse 把 src 重采样为 width×height 的 *image.RGBA，旋转、透视等几何变换共用
+//
+// 坐标以像素中心为 +0.5 的连续坐标计算；落在源画面外的取样按透明处理，
+// 因此双线性取样下画面边缘同样是抗锯齿的，最后整体合成到背景色上。
+func warp(src *image.RGBA, width, height int, inverse projective, options warpOptions) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	n := max(1, min(options.supersample, maxSupersample))
+	weight := 1 / float64(n*n)
+
+	var bg [4]float64
+	if options.background != nil {
+		r, g, b, a := options.background.RGBA()
+		bg = [4]float64{float64(r >> 8), float64(g >> 8), float64(b >> 8), float64(a >> 8)}
+	}
+
+	// tap 把源像素 (ix, iy) 按权重 w 累加到 acc，超出源画面时视为透明
+	tap := func(acc *[4]float64, ix, iy int, w float64) {
+		if ix < 0 || iy < 0 || ix >= sw || iy >= sh || w == 0 {
+			return
+		}
+		p := src.Pix[src.PixOffset(bounds.Min.X+ix, bounds.Min.Y+iy):]
+		acc[0] += float64(p[0]) * w
+		acc[1] += float64(p[1]) * w
+		acc[2] += float64(p[2]) * w
+		acc[3] += float64(p[3]) * w
+	}
+
+	Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := dst.Pix[y*dst.Stride:]
+			for x := 0; x < width; x++ {
+				var acc [4]float64
+				for j := 0; j < n; j++ {
+					for i := 0; i < n; i++ {
+						u, v, ok := inverse.apply(float64(x)+(float64(i)+0.5)/float64(n), float64(y)+(float64(j)+0.5)/float64(n))
+						if !ok {
+							continue
+						}
+						if options.sampling == SamplingNearest {
+							tap(&acc, int(math.Floor(u)), int(math.Floor(v)), weight)
+							continue
+						}
+						fx, fy := u-0.5, v-0.5
+						ix, iy := int(math.Floor(fx)), int(math.Floor(fy))
+						wx, wy := fx-float64(ix), fy-float64(iy)
+						tap(&acc, ix, iy, (1-wx)*(1-wy)*weight)
+						tap(&acc, ix+1, iy, wx*(1-wy)*weight)
+						tap(&acc, ix, iy+1, (1-wx)*wy*weight)
+						tap(&acc, ix+1, iy+1, wx*wy*weight)
+					}
+				}
+				// 预乘 source-over 合成到背景上
+				rest := 1 - acc[3]/255
+				alpha := uint8(math.Min(acc[3]+bg[3]*rest+0.5, 255))
+				for c := 0; c < 3; c++ {
+					row[x*4+c] = min(uint8(math.Min(acc[c]+bg[c]*rest+0.5, 255)), alpha)
+				}
+				row[x*4+3] = alpha
+			}
+		}
+	})
+	return dst
+}
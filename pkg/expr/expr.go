@@ -0,0 +1,386 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Expr 已解析的动画参数表达式，如 "0.5+0.5*sin(2*pi*t)"
+//
+// 支持 + - * / % ^、一元负号和括号；变量 t 为秒数；常量 pi、e；
+// 函数 sin cos tan abs sqrt exp log floor ceil round min max pow clamp lerp。
+type Expr struct {
+	src      string
+	eval     func(t float64) float64
+	constant bool
+}
+
+// Parse 解析表达式
+func Parse(src string) (*Expr, error) {
+	p := &parser{src: src}
+	p.next()
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("多余的 %q", p.tok.text)
+	}
+	e := &Expr{src: src, eval: node.eval, constant: node.constant}
+	if node.constant {
+		// 常量表达式只求值一次
+		v := node.eval(0)
+		e.eval = func(float64) float64 { return v }
+	}
+	return e, nil
+}
+
+// MustParse 解析表达式，失败时 panic，用于包级变量
+func MustParse(src string) *Expr {
+	e, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Constant 返回固定值的表达式
+func Constant(v float64) *Expr {
+	return &Expr{
+		src:      strconv.FormatFloat(v, 'g', -1, 64),
+		eval:     func(float64) float64 { return v },
+		constant: true,
+	}
+}
+
+// At 返回时间 t 处的值
+func (e *Expr) At(t time.Duration) float64 {
+	return e.eval(t.Seconds())
+}
+
+// Func 返回可直接用作动画参数的函数
+func (e *Expr) Func() func(t time.Duration) float64 {
+	return e.At
+}
+
+// IsConstant 判断表达式是否与时间无关
+func (e *Expr) IsConstant() bool {
+	return e.constant
+}
+
+// String 返回原始表达式
+func (e *Expr) String() string {
+	return e.src
+}
+
+// node 语法树节点，编译为闭包
+type node struct {
+	eval     func(t float64) float64
+	constant bool
+}
+
+// constNode 常量节点
+func constNode(v float64) node {
+	return node{eval: func(float64) float64 { return v }, constant: true}
+}
+
+// tokenKind 词法单元类型
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+// token 词法单元
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// parser 递归下降解析器
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// errorf 返回带位置的解析错误
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("表达式 %q 第 %d 个字符: %s", p.src, p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+// next 读取下一个词法单元
+func (p *parser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		// 科学计数法 1e-3
+		if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			end := p.pos + 1
+			if end < len(p.src) && (p.src[end] == '+' || p.src[end] == '-') {
+				end++
+			}
+			if end < len(p.src) && isDigit(p.src[end]) {
+				for end < len(p.src) && isDigit(p.src[end]) {
+					end++
+				}
+				p.pos = end
+			}
+		}
+		p.tok = token{kind: tokNumber, text: p.src[start:p.pos], pos: start}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isDigit(p.src[p.pos]) || unicode.IsLetter(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos], pos: start}
+	default:
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(c), pos: start}
+	}
+}
+
+// isDigit 判断 ASCII 数字
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isOp 判断当前词法单元是否为指定运算符
+func (p *parser) isOp(ops string) bool {
+	return p.tok.kind == tokOp && strings.Contains(ops, p.tok.text)
+}
+
+// parseExpr 加减：expr = term { ("+"|"-") term }
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return node{}, err
+	}
+	for p.isOp("+-") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return node{}, err
+		}
+		l, r := left.eval, right.eval
+		if op == "+" {
+			left = binary(left, right, func(t float64) float64 { return l(t) + r(t) })
+		} else {
+			left = binary(left, right, func(t float64) float64 { return l(t) - r(t) })
+		}
+	}
+	return left, nil
+}
+
+// parseTerm 乘除取模：term = unary { ("*"|"/"|"%") unary }
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return node{}, err
+	}
+	for p.isOp("*/%") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		l, r := left.eval, right.eval
+		switch op {
+		case "*":
+			left = binary(left, right, func(t float64) float64 { return l(t) * r(t) })
+		case "/":
+			left = binary(left, right, func(t float64) float64 { return l(t) / r(t) })
+		default:
+			left = binary(left, right, func(t float64) float64 { return math.Mod(l(t), r(t)) })
+		}
+	}
+	return left, nil
+}
+
+// parseUnary 一元负号：unary = ("-"|"+") unary | power
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("-+") {
+		op := p.tok.text
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		if op == "+" {
+			return operand, nil
+		}
+		f := operand.eval
+		return node{eval: func(t float64) float64 { return -f(t) }, constant: operand.constant}, nil
+	}
+	return p.parsePower()
+}
+
+// parsePower 乘方，右结合：power = primary [ "^" unary ]
+func (p *parser) parsePower() (node, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+	if p.isOp("^") {
+		p.next()
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		b, x := base.eval, exponent.eval
+		return binary(base, exponent, func(t float64) float64 { return math.Pow(b(t), x(t)) }), nil
+	}
+	return base, nil
+}
+
+// parsePrimary 数字、变量、常量、函数调用或括号
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return node{}, p.errorf("无效的数字 %q", tok.text)
+		}
+		p.next()
+		return constNode(v), nil
+
+	case tokIdent:
+		p.next()
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		switch tok.text {
+		case "t":
+			return node{eval: func(t float64) float64 { return t }}, nil
+		case "pi":
+			return constNode(math.Pi), nil
+		case "e":
+			return constNode(math.E), nil
+		}
+		return node{}, fmt.Errorf("表达式 %q 第 %d 个字符: 未知的变量 %s", p.src, tok.pos+1, tok.text)
+
+	case tokOp:
+		if tok.text == "(" {
+			p.next()
+			inner, err := p.parseExpr()
+			if err != nil {
+				return node{}, err
+			}
+			if !p.isOp(")") {
+				return node{}, p.errorf("缺少右括号")
+			}
+			p.next()
+			return inner, nil
+		}
+	case tokEOF:
+		return node{}, p.errorf("表达式不完整")
+	}
+	return node{}, p.errorf("意外的 %q", tok.text)
+}
+
+// parseCall 函数调用，当前词法单元为 "("
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return node{}, fmt.Errorf("表达式 %q 第 %d 个字符: 未知的函数 %s", p.src, name.pos+1, name.text)
+	}
+	p.next()
+
+	var args []node
+	if !p.isOp(")") {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return node{}, err
+			}
+			args = append(args, arg)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if !p.isOp(")") {
+		return node{}, p.errorf("函数 %s 缺少右括号", name.text)
+	}
+	p.next()
+
+	if len(args) != fn.arity {
+		return node{}, fmt.Errorf("表达式 %q: 函数 %s 需要 %d 个参数，实际 %d 个", p.src, name.text, fn.arity, len(args))
+	}
+
+	evals := make([]func(float64) float64, len(args))
+	constant := true
+	for i, arg := range args {
+		evals[i] = arg.eval
+		constant = constant && arg.constant
+	}
+	call := fn.call
+	values := func(t float64) []float64 {
+		v := make([]float64, len(evals))
+		for i, eval := range evals {
+			v[i] = eval(t)
+		}
+		return v
+	}
+	return node{eval: func(t float64) float64 { return call(values(t)) }, constant: constant}, nil
+}
+
+// binary 组合两个操作数，两者都为常量时结果也为常量
+func binary(left, right node, eval func(t float64) float64) node {
+	return node{eval: eval, constant: left.constant && right.constant}
+}
+
+// function 内置函数
+type function struct {
+	arity int
+	call  func(args []float64) float64
+}
+
+// unaryFunc 单参数函数
+func unaryFunc(f func(float64) float64) function {
+	return function{arity: 1, call: func(args []float64) float64 { return f(args[0]) }}
+}
+
+// functions 内置函数表
+var functions = map[string]function{
+	"sin":   unaryFunc(math.Sin),
+	"cos":   unaryFunc(math.Cos),
+	"tan":   unaryFunc(math.Tan),
+	"abs":   unaryFunc(math.Abs),
+	"sqrt":  unaryFunc(math.Sqrt),
+	"exp":   unaryFunc(math.Exp),
+	"log":   unaryFunc(math.Log),
+	"floor": unaryFunc(math.Floor),
+	"ceil":  unaryFunc(math.Ceil),
+	"round": unaryFunc(math.Round),
+	"min":   {arity: 2, call: func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {arity: 2, call: func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"pow":   {arity: 2, call: func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	// clamp(x, lo, hi)
+	"clamp": {arity: 3, call: func(a []float64) float64 { return math.Max(a[1], math.Min(a[2], a[0])) }},
+	// lerp(a, b, k) = a + (b-a)*k
+	"lerp": {arity: 3, call: func(a []float64) float64 { return a[0] + (a[1]-a[0])*a[2] }},
+}
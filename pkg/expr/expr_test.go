@@ -0,0 +1,144 @@
+package expr
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEvaluates(t *testing.T) {
+	tests := []struct {
+		src  string
+		t    float64 // 求值时间（秒）
+		want float64
+	}{
+		// 优先级与结合性
+		{"1+2*3", 0, 7},
+		{"(1+2)*3", 0, 9},
+		{"10-4-3", 0, 3},
+		{"24/4/2", 0, 3},
+		{"7%4*2", 0, 6},
+		{"1+7%4", 0, 4},
+		{"2*3^2", 0, 18},
+		{"2^3^2", 0, 512},
+		{"(2^3)^2", 0, 64},
+		// 一元负号
+		{"-3", 0, -3},
+		{"--3", 0, 3},
+		{"+3", 0, 3},
+		{"-2^2", 0, -4},
+		{"(-2)^2", 0, 4},
+		{"2^-1", 0, 0.5},
+		{"3*-2", 0, -6},
+		{"1-(-1)", 0, 2},
+		{"-t", 1.5, -1.5},
+		// 数字
+		{"1.5e2", 0, 150},
+		{"2E-1", 0, 0.2},
+		{".5", 0, 0.5},
+		{"  1 +\t2 ", 0, 3},
+		// 变量与常量
+		{"t", 2.5, 2.5},
+		{"2*t+1", 3, 7},
+		{"pi", 0, math.Pi},
+		{"e", 0, math.E},
+		// 函数
+		{"sin(pi/2)", 0, 1},
+		{"cos(0)", 0, 1},
+		{"abs(-2)", 0, 2},
+		{"sqrt(16)", 0, 4},
+		{"floor(1.7)+ceil(1.2)", 0, 3},
+		{"round(2.5)", 0, 3},
+		{"min(3, t)", 1, 1},
+		{"max(3, t)", 1, 3},
+		{"pow(2, 10)", 0, 1024},
+		{"clamp(t, 0, 1)", 5, 1},
+		{"clamp(t, 0, 1)", -5, 0},
+		{"lerp(10, 20, 0.25)", 0, 12.5},
+		{"0.5+0.5*sin(2*pi*t)", 0.25, 1},
+		{"max(min(t, 2), -1)", 3, 2},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("%q: 解析失败: %v", tt.src, err)
+			continue
+		}
+		got := e.At(time.Duration(tt.t * float64(time.Second)))
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q 在 t=%v 处为 %v，期望 %v", tt.src, tt.t, got, tt.want)
+		}
+		if e.String() != tt.src {
+			t.Errorf("%q: String 返回 %q", tt.src, e.String())
+		}
+	}
+}
+
+func TestParseConstant(t *testing.T) {
+	tests := []struct {
+		src      string
+		constant bool
+	}{
+		{"1+2", true},
+		{"sin(pi)", true},
+		{"-(3)", true},
+		{"t", false},
+		{"-t", false},
+		{"1+0*t", false},
+		{"max(1, t)", false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("%q: 解析失败: %v", tt.src, err)
+			continue
+		}
+		if e.IsConstant() != tt.constant {
+			t.Errorf("%q: IsConstant 为 %v，期望 %v", tt.src, e.IsConstant(), tt.constant)
+		}
+	}
+	if c := Constant(0.25); !c.IsConstant() || c.At(time.Hour) != 0.25 || c.String() != "0.25" {
+		t.Errorf("Constant(0.25) 为 %q，值 %v", c.String(), c.At(time.Hour))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // 错误信息中应包含的内容
+	}{
+		{"", "第 1 个字符: 表达式不完整"},
+		{"1+", "第 3 个字符: 表达式不完整"},
+		{"2*)", `第 3 个字符: 意外的 ")"`},
+		{"1 2", `第 3 个字符: 多余的 "2"`},
+		{"(1+2", "第 5 个字符: 缺少右括号"},
+		{"sin(1", "第 6 个字符: 函数 sin 缺少右括号"},
+		{"1+x", "第 3 个字符: 未知的变量 x"},
+		{"2*foo(1)", "第 3 个字符: 未知的函数 foo"},
+		{"1..2", `第 1 个字符: 无效的数字 "1..2"`},
+		{"t $ 1", `第 3 个字符: 多余的 "$"`},
+		{"max(1)", "函数 max 需要 2 个参数，实际 1 个"},
+		{"sin()", "函数 sin 需要 1 个参数，实际 0 个"},
+		{"clamp(1, 2, 3, 4)", "函数 clamp 需要 3 个参数，实际 4 个"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil {
+			t.Errorf("%q: 应返回错误", tt.src)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: 错误 %q 应包含 %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestMustParsePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("无效表达式应 panic")
+		}
+	}()
+	MustParse("1+")
+}
@@ -3,14 +3,15 @@ package ffmpeg
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os"
-	"os/exec"
 	"strconv"
 	"sync"
 	"time"
+
+	"moviepy-go/pkg/leakcheck"
 )
 
 // AudioInfo 音频信息
@@ -21,6 +22,14 @@ type AudioInfo struct {
 	Codec      string  `json:"codec_name"`
 	BitRate    string  `json:"bit_rate"`
 	Format     string  `json:"format_name"`
+	Stream     int     `json:"stream_index"` // 所选流在文件中的序号
+	Language   string  `json:"language"`
+
+	SampleFormat  string  `json:"sample_fmt"`     // 如 fltp、s16
+	BitDepth      int     `json:"bit_depth"`      // 见 ProbeStream.Depth，有损编码常为解码后的采样格式位深
+	ChannelLayout string  `json:"channel_layout"` // 如 stereo、5.1
+	StartTime     float64 `json:"start_time"`     // 音频流首个样本的时间戳（秒），缺失时取容器的起始时间
+	Profile       string  `json:"profile"`        // 编码档次，如 LC、HE-AAC
 }
 
 // AudioReader FFmpeg 音频读取器
@@ -32,18 +41,50 @@ type AudioReader struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	closed     bool
+	refs       int            // 引用计数，降为 0 时关闭
+	stream     StreamSelector // 要读取的音频流
+	timeout    time.Duration  // 单次调用的最长时间，0 表示不限
 	mutex      sync.RWMutex
+	leak       *leakcheck.Guard
+}
+
+// AudioReaderOptions 音频读取器选项
+type AudioReaderOptions struct {
+	// Stream 选择音频流，默认第一个
+	Stream StreamSelector
+	// Timeout 单次 ffprobe/FFmpeg 调用（探测或读取一段样本）的最长时间，超时后终止进程，0 表示不限
+	Timeout time.Duration
 }
 
+// audioFrameSeconds GetAudioFrame 每次读取的时长（秒）
+const audioFrameSeconds = 0.1
+
+// audioTimeTolerance 音频时间戳越界容差（秒），等于一次读取的时长
+const audioTimeTolerance = audioFrameSeconds
+
 // NewAudioReader 创建新的音频读取器
 func NewAudioReader(filename string, processMgr *ProcessManager) *AudioReader {
+	return NewAudioReaderWithOptions(filename, nil, processMgr)
+}
+
+// NewAudioReaderWithOptions 使用指定选项创建音频读取器
+func NewAudioReaderWithOptions(filename string, options *AudioReaderOptions, processMgr *ProcessManager) *AudioReader {
+	if options == nil {
+		options = &AudioReaderOptions{}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	return &AudioReader{
+	ar := &AudioReader{
 		filename:   filename,
 		processMgr: processMgr,
 		ctx:        ctx,
 		cancel:     cancel,
+		refs:       1,
+		stream:     options.Stream,
+		timeout:    options.Timeout,
 	}
+	ar.leak = leakcheck.Track(ar, "AudioReader", filename)
+	return ar
 }
 
 // Open 打开音频文件并获取信息
@@ -61,94 +102,133 @@ func (ar *AudioReader) Open() error {
 	}
 
 	// 获取音频信息
-	info, err := ar.getAudioInfo()
+	ctx, cancel := callContext(context.Background(), ar.ctx, ar.timeout)
+	defer cancel()
+	info, err := ar.getAudioInfo(ctx)
 	if err != nil {
-		return fmt.Errorf("获取音频信息失败: %w", err)
+		return fmt.Errorf("获取音频信息失败: %w", interrupted(ctx, "探测", err))
 	}
 
 	ar.info = info
 	return nil
 }
 
-// getAudioInfo 获取音频信息
-func (ar *AudioReader) getAudioInfo() (*AudioInfo, error) {
-	args := []string{
-		"-i", ar.filename,
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_format",
-		"-show_streams",
-	}
-
-	cmd := exec.Command("ffprobe", args...)
-	output, err := cmd.Output()
+// getAudioInfo 获取所选音频流的信息
+func (ar *AudioReader) getAudioInfo(ctx context.Context) (*AudioInfo, error) {
+	probe, err := ProbeContext(ctx, ar.filename, ar.processMgr)
 	if err != nil {
-		return nil, fmt.Errorf("ffprobe 执行失败: %w", err)
-	}
-
-	var result struct {
-		Format struct {
-			Duration string `json:"duration"`
-			BitRate  string `json:"bit_rate"`
-		} `json:"format"`
-		Streams []struct {
-			CodecName  string `json:"codec_name"`
-			CodecType  string `json:"codec_type"`
-			SampleRate string `json:"sample_rate"`
-			Channels   int    `json:"channels"`
-		} `json:"streams"`
-	}
-
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+		return nil, err
 	}
 
-	// 查找音频流
-	var audioStream *struct {
-		CodecName  string `json:"codec_name"`
-		CodecType  string `json:"codec_type"`
-		SampleRate string `json:"sample_rate"`
-		Channels   int    `json:"channels"`
+	audioStreams := probe.AudioStreams()
+	if len(audioStreams) == 0 {
+		return nil, fmt.Errorf("未找到音频流")
 	}
-
-	for i := range result.Streams {
-		if result.Streams[i].CodecType == "audio" {
-			audioStream = &result.Streams[i]
-			break
-		}
+	audioStream, err := ar.stream.Select(audioStreams)
+	if err != nil {
+		return nil, err
 	}
 
-	if audioStream == nil {
-		return nil, fmt.Errorf("未找到音频流")
+	sampleRate := audioStream.SampleRate
+	if sampleRate == 0 {
+		sampleRate = 44100 // 默认采样率
 	}
 
-	// 解析时长
-	duration, err := strconv.ParseFloat(result.Format.Duration, 64)
-	if err != nil {
-		duration = 0
+	startTime := audioStream.StartTime
+	if startTime == 0 {
+		startTime = probe.Format.StartTime
 	}
 
-	// 解析采样率
-	sampleRate, err := strconv.Atoi(audioStream.SampleRate)
-	if err != nil {
-		sampleRate = 44100 // 默认采样率
+	var bitRate string
+	if probe.Format.BitRate > 0 {
+		bitRate = strconv.FormatInt(probe.Format.BitRate, 10)
 	}
 
 	return &AudioInfo{
-		Duration:   duration,
+		Duration:   resolveStreamDuration(probe.Format.Duration, audioStream),
 		SampleRate: sampleRate,
 		Channels:   audioStream.Channels,
 		Codec:      audioStream.CodecName,
-		BitRate:    result.Format.BitRate,
-		Format:     "unknown",
+		BitRate:    bitRate,
+		Format:     probe.Format.FormatName,
+		Stream:     audioStream.Index,
+		Language:   audioStream.Language(),
+
+		SampleFormat:  audioStream.SampleFormat,
+		BitDepth:      audioStream.Depth(),
+		ChannelLayout: audioStream.ChannelLayout,
+		StartTime:     startTime,
+		Profile:       audioStream.Profile,
 	}, nil
 }
 
-// GetAudioFrame 获取指定时间的音频帧
+// samplesArgs 构建从 timestamp 读取 window 秒音频样本的 FFmpeg 参数，filter 非空时作为 -af，调用者需确保已打开
+func (ar *AudioReader) samplesArgs(timestamp, window float64, filter string) []string {
+	args := []string{
+		"-ss", fmt.Sprintf("%.3f", timestamp),
+		"-i", ar.filename,
+		"-map", mapArg(ar.info.Stream),
+		"-t", strconv.FormatFloat(window, 'f', -1, 64), // 读取的源音频时长
+	}
+	if filter != "" {
+		args = append(args, "-af", filter)
+	}
+	return append(args,
+		"-f", "f32le", // 32位浮点格式
+		"-ac", strconv.Itoa(ar.info.Channels),
+		"-ar", strconv.Itoa(ar.info.SampleRate),
+		"-",
+	)
+}
+
+// ProbeCommand 返回 Open 将执行的 ffprobe 命令（不执行）
+func (ar *AudioReader) ProbeCommand() Command {
+	return Command{Name: "ffprobe", Args: probeArgs(ar.filename)}
+}
+
+// SamplesCommand 返回 GetAudioFrame(t) 将执行的 FFmpeg 命令（不执行），需先 Open
+func (ar *AudioReader) SamplesCommand(t time.Duration) (Command, error) {
+	ar.mutex.RLock()
+	defer ar.mutex.RUnlock()
+	if ar.info == nil {
+		return Command{}, fmt.Errorf("音频未打开")
+	}
+	return Command{Name: "ffmpeg", Args: ar.samplesArgs(t.Seconds(), audioFrameSeconds, "")}, nil
+}
+
+// GetAudioFrame 获取指定时间开始的 0.1 秒音频帧（交错排列的样本）
 func (ar *AudioReader) GetAudioFrame(t time.Duration) ([]float64, error) {
+	return ar.GetAudioFrameContext(context.Background(), t)
+}
+
+// GetAudioFrameContext 与 GetAudioFrame 相同，ctx 取消或到期时终止解码进程；同时受 Options.Timeout 限制
+func (ar *AudioReader) GetAudioFrameContext(ctx context.Context, t time.Duration) ([]float64, error) {
+	ar.mutex.RLock()
+	defer ar.mutex.RUnlock()
+
+	if ar.info == nil {
+		return nil, fmt.Errorf("音频未打开")
+	}
+	frameSize := int(audioFrameSeconds * float64(ar.info.SampleRate) * float64(ar.info.Channels))
+	return ar.readSamplesLocked(ctx, t, time.Duration(audioFrameSeconds*float64(time.Second)), "", frameSize)
+}
+
+// ReadSamples 从 t 开始读取 window 时长的源音频，经 filter（-af，可为空）处理后返回 count 个交错样本
+//
+// 输出不足 count 时以静音补齐，超出时截断。变速等会改变时长的滤镜可借此得到固定长度的帧。
+func (ar *AudioReader) ReadSamples(t, window time.Duration, filter string, count int) ([]float64, error) {
+	return ar.ReadSamplesContext(context.Background(), t, window, filter, count)
+}
+
+// ReadSamplesContext 与 ReadSamples 相同，ctx 取消或到期时终止解码进程
+func (ar *AudioReader) ReadSamplesContext(ctx context.Context, t, window time.Duration, filter string, count int) ([]float64, error) {
 	ar.mutex.RLock()
 	defer ar.mutex.RUnlock()
+	return ar.readSamplesLocked(ctx, t, window, filter, count)
+}
 
+// readSamplesLocked 读取音频样本，调用者需持有读锁
+func (ar *AudioReader) readSamplesLocked(ctx context.Context, t, window time.Duration, filter string, count int) (samples []float64, err error) {
 	if ar.closed {
 		return nil, fmt.Errorf("读取器已关闭")
 	}
@@ -157,66 +237,61 @@ func (ar *AudioReader) GetAudioFrame(t time.Duration) ([]float64, error) {
 		return nil, fmt.Errorf("音频未打开")
 	}
 
-	// 计算时间戳
+	// 计算时间戳，容差内的越界请求钳制到 [0, Duration]
 	timestamp := t.Seconds()
-	if timestamp > ar.info.Duration {
-		return nil, fmt.Errorf("时间超出音频长度")
+	if timestamp < -audioTimeTolerance || timestamp > ar.info.Duration+audioTimeTolerance {
+		return nil, fmt.Errorf("时间超出音频长度: %v", t)
 	}
+	timestamp = math.Min(math.Max(timestamp, 0), ar.info.Duration)
 
 	// 启动 FFmpeg 进程读取音频
-	args := []string{
-		"-ss", fmt.Sprintf("%.3f", timestamp),
-		"-i", ar.filename,
-		"-t", "0.1", // 读取 0.1 秒的音频
-		"-f", "f32le", // 32位浮点格式
-		"-ac", strconv.Itoa(ar.info.Channels),
-		"-ar", strconv.Itoa(ar.info.SampleRate),
-		"-",
-	}
+	args := ar.samplesArgs(timestamp, window.Seconds(), filter)
 
-	// 创建命令
-	cmd := exec.CommandContext(ar.ctx, "ffmpeg", args...)
+	ctx, cancel := callContext(ctx, ar.ctx, ar.timeout)
+	defer cancel()
+	defer func() { err = interrupted(ctx, fmt.Sprintf("读取 %v 处的音频", t), err) }()
 
-	// 在启动进程之前设置输出管道
-	output, err := cmd.StdoutPipe()
+	// 启动受管理的进程
+	stderr := newTailWriter()
+	process, err := ar.processMgr.StartProcessWithPipes(ctx, "ffmpeg", args, nil, &ProcessPipes{Stdout: true, Stderr: stderr})
 	if err != nil {
-		return nil, fmt.Errorf("设置输出管道失败: %w", err)
-	}
-
-	// 启动进程
-	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("启动 FFmpeg 失败: %w", err)
 	}
+	output := process.Stdout()
+	defer output.Close()
 
 	// 读取音频数据
 	reader := bufio.NewReader(output)
-	frameSize := int(0.1 * float64(ar.info.SampleRate) * float64(ar.info.Channels))
-	audioData := make([]byte, frameSize*4) // 32位浮点 = 4字节
+	audioData := make([]byte, count*4) // 32位浮点 = 4字节
 
-	// 使用 io.ReadFull 确保读取完整的数据
+	// 末尾数据不足时 FFmpeg 输出的数据较少，不足部分保持静音
 	_, err = io.ReadFull(reader, audioData)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
 	if err != nil {
-		cmd.Process.Kill()
-		return nil, fmt.Errorf("读取音频数据失败: %w", err)
+		process.Terminate()
+		process.Wait()
+		return nil, fmt.Errorf("读取音频数据失败: %w", classify(err, stderr.Tail()))
 	}
 
+	// 滤镜输出可能略多于 count，丢弃剩余数据让进程正常退出
+	io.Copy(io.Discard, reader)
+
 	// 等待进程结束
-	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("FFmpeg 进程异常退出: %w", err)
+	if err := process.Wait(); err != nil {
+		return nil, fmt.Errorf("FFmpeg 进程异常退出: %w", classify(err, stderr.Tail()))
 	}
 
 	// 转换为浮点数数组
-	samples := make([]float64, frameSize)
-	for i := 0; i < frameSize; i++ {
+	samples = make([]float64, count)
+	for i := range samples {
 		offset := i * 4
-		if offset+3 < len(audioData) {
-			// 将字节转换为32位浮点数
-			bits := uint32(audioData[offset]) |
-				uint32(audioData[offset+1])<<8 |
-				uint32(audioData[offset+2])<<16 |
-				uint32(audioData[offset+3])<<24
-			samples[i] = float64(int32(bits)) / float64(1<<31)
-		}
+		bits := uint32(audioData[offset]) |
+			uint32(audioData[offset+1])<<8 |
+			uint32(audioData[offset+2])<<16 |
+			uint32(audioData[offset+3])<<24
+		samples[i] = float64(math.Float32frombits(bits))
 	}
 
 	return samples, nil
@@ -229,7 +304,36 @@ func (ar *AudioReader) GetInfo() *AudioInfo {
 	return ar.info
 }
 
-// Close 关闭读取器
+// Retain 增加引用计数，供共享同一读取器的剪辑使用，每次 Retain 需对应一次 Release
+func (ar *AudioReader) Retain() error {
+	ar.mutex.Lock()
+	defer ar.mutex.Unlock()
+
+	if ar.closed {
+		return fmt.Errorf("读取器已关闭")
+	}
+	ar.refs++
+	return nil
+}
+
+// Release 减少引用计数，最后一个引用释放时关闭读取器
+func (ar *AudioReader) Release() error {
+	ar.mutex.Lock()
+	if ar.closed {
+		ar.mutex.Unlock()
+		return nil
+	}
+	ar.refs--
+	last := ar.refs <= 0
+	ar.mutex.Unlock()
+
+	if last {
+		return ar.Close()
+	}
+	return nil
+}
+
+// Close 关闭读取器，忽略引用计数立即生效
 func (ar *AudioReader) Close() error {
 	ar.mutex.Lock()
 	defer ar.mutex.Unlock()
@@ -239,6 +343,7 @@ func (ar *AudioReader) Close() error {
 	}
 
 	ar.closed = true
+	ar.leak.Close()
 
 	// 取消上下文
 	if ar.cancel != nil {
@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"math"
-	"os/exec"
+	"os"
 	"strconv"
 	"sync"
 	"time"
+
+	"moviepy-go/pkg/leakcheck"
 )
 
 // AudioWriter FFmpeg 音频写入器
@@ -18,6 +21,7 @@ type AudioWriter struct {
 	channels   int
 	codec      string
 	bitrate    string
+	filter     string // -af 滤镜链
 	processMgr *ProcessManager
 	process    *ManagedProcess
 	ctx        context.Context
@@ -25,6 +29,13 @@ type AudioWriter struct {
 	closed     bool
 	mutex      sync.RWMutex
 	stdin      io.WriteCloser
+	direct     bool // 直接写入目标文件
+	logLevel   LogLevel
+	logger     *log.Logger
+	stderr     *logWriter    // 捕获的 FFmpeg stderr
+	tempFile   string        // 原子写入时使用的临时文件
+	timeout    time.Duration // 单次写入或等待编码结束的最长时间，0 表示不限
+	leak       *leakcheck.Guard
 }
 
 // AudioWriterOptions 音频写入器选项
@@ -33,6 +44,16 @@ type AudioWriterOptions struct {
 	Bitrate    string
 	SampleRate int
 	Channels   int
+	// Filter 编码前应用的 -af 滤镜链，如 loudnorm，为空时不过滤
+	Filter string
+	// DirectWrite 直接写入目标文件；默认先写入同目录临时文件，成功关闭后再重命名
+	DirectWrite bool
+	// LogLevel FFmpeg 日志级别，默认 error
+	LogLevel LogLevel
+	// Logger 接收 FFmpeg stderr 输出，nil 表示 log.Default()
+	Logger *log.Logger
+	// WriteTimeout 单次写入管道、以及 Close 等待编码结束的最长时间，超时后终止 FFmpeg，0 表示不限
+	WriteTimeout time.Duration
 }
 
 // NewAudioWriter 创建新的音频写入器
@@ -62,6 +83,11 @@ func NewAudioWriter(filename string, options *AudioWriterOptions, processMgr *Pr
 		channels:   options.Channels,
 		codec:      options.Codec,
 		bitrate:    options.Bitrate,
+		filter:     options.Filter,
+		direct:     options.DirectWrite,
+		logLevel:   options.LogLevel,
+		logger:     options.Logger,
+		timeout:    options.WriteTimeout,
 		processMgr: processMgr,
 		ctx:        ctx,
 		cancel:     cancel,
@@ -77,64 +103,69 @@ func (aw *AudioWriter) Open() error {
 		return fmt.Errorf("写入器已关闭")
 	}
 
-	// 构建 FFmpeg 命令
-	args := []string{
-		//"-f", "f32le", // 输入格式：32位浮点
-		"-ar", strconv.Itoa(aw.sampleRate), // 采样率
-		"-ac", strconv.Itoa(aw.channels), // 声道数
-		"-i", "-", // 从stdin读取
-		"-c:a", aw.codec, // 音频编码器
-		"-b:a", aw.bitrate, // 音频比特率
-		"-y",        // 覆盖输出文件
-		aw.filename, // 输出文件
+	// 先确认编码器可用，避免启动后才因未知编码器失败
+	if aw.codec != "copy" {
+		if err := aw.processMgr.CheckEncoder(aw.ctx, aw.codec); err != nil {
+			return err
+		}
 	}
 
-	// 创建命令
-	cmd := exec.CommandContext(aw.ctx, "ffmpeg", args...)
-
-	// 在启动进程之前设置输入管道
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("设置输入管道失败: %w", err)
+	// 原子写入时先输出到临时文件
+	output := aw.filename
+	if !aw.direct {
+		tempFile, err := TempOutputPath(aw.filename)
+		if err != nil {
+			return err
+		}
+		aw.tempFile = tempFile
+		output = tempFile
 	}
 
-	// 启动进程
-	if err := cmd.Start(); err != nil {
+	// 构建 FFmpeg 命令
+	args := aw.buildArgs(output)
+
+	// 启动受管理的进程，stderr 写入日志
+	aw.stderr = newLogWriter(aw.logger, "[ffmpeg] ")
+	process, err := aw.processMgr.StartProcessWithPipes(aw.ctx, "ffmpeg", args, nil, &ProcessPipes{
+		Stdin:  true,
+		Stderr: aw.stderr,
+	})
+	if err != nil {
+		aw.removeTemp()
 		return fmt.Errorf("启动 FFmpeg 失败: %w", err)
 	}
 
-	// 创建进程包装器
-	process := &ManagedProcess{
-		cmd:       cmd,
-		pid:       cmd.Process.Pid,
-		startTime: time.Now(),
-		ctx:       aw.ctx,
-		cancel:    aw.cancel,
-		done:      make(chan error, 1),
-	}
-
-	// 注册到进程管理器
-	aw.processMgr.mutex.Lock()
-	aw.processMgr.processes[process.pid] = process
-	aw.processMgr.mutex.Unlock()
-
-	// 启动一个 goroutine 来等待进程结束
-	go func() {
-		err := cmd.Wait()
-		process.done <- err
-
-		// 从管理器中移除
-		aw.processMgr.mutex.Lock()
-		delete(aw.processMgr.processes, process.pid)
-		aw.processMgr.mutex.Unlock()
-	}()
-
 	aw.process = process
-	aw.stdin = stdin
+	aw.stdin = process.Stdin()
+	aw.leak = leakcheck.Track(aw, "AudioWriter", aw.filename)
 
 	return nil
 }
 
+// buildArgs 构建写入到 output 的 FFmpeg 参数
+func (aw *AudioWriter) buildArgs(output string) []string {
+	args := append(logArgs(aw.logLevel),
+		"-f", "f32le", // 输入格式：32位浮点
+		"-ar", strconv.Itoa(aw.sampleRate), // 采样率
+		"-ac", strconv.Itoa(aw.channels), // 声道数
+		"-i", "-", // 从stdin读取
+	)
+	if aw.filter != "" {
+		args = append(args, "-af", aw.filter)
+	}
+	return append(args,
+		"-c:a", aw.codec, // 音频编码器
+		"-b:a", aw.bitrate, // 音频比特率
+		"-y",   // 覆盖输出文件
+		output, // 输出文件
+	)
+}
+
+// Command 返回 Open 将执行的 FFmpeg 命令（不执行）；原子写入时实际输出为同目录临时文件
+func (aw *AudioWriter) Command() Command {
+	return Command{Name: "ffmpeg", Args: aw.buildArgs(aw.filename)}
+}
+
 // WriteSamples 写入音频样本
 func (aw *AudioWriter) WriteSamples(samples []float64) error {
 	aw.mutex.Lock()
@@ -148,7 +179,18 @@ func (aw *AudioWriter) WriteSamples(samples []float64) error {
 		return fmt.Errorf("写入器未打开")
 	}
 
-	// 将浮点数转换为字节数组
+	// 写入数据，编码器卡住时由看门狗终止进程使写入返回
+	dog := startWatchdog(aw.timeout, aw.cancel)
+	_, err := aw.stdin.Write(EncodeFloat32LE(samples))
+	if err = dog.stop("写入音频", err); err != nil {
+		return fmt.Errorf("写入音频数据失败: %w", classify(err, aw.stderr.Tail()))
+	}
+
+	return nil
+}
+
+// EncodeFloat32LE 将浮点样本编码为小端序 32 位浮点字节（f32le）
+func EncodeFloat32LE(samples []float64) []byte {
 	audioData := make([]byte, len(samples)*4)
 	for i, sample := range samples {
 		// 将浮点数转换为32位浮点数（IEEE 754格式）
@@ -161,14 +203,7 @@ func (aw *AudioWriter) WriteSamples(samples []float64) error {
 		audioData[offset+2] = byte(value >> 16)
 		audioData[offset+3] = byte(value >> 24)
 	}
-
-	// 写入数据
-	_, err := aw.stdin.Write(audioData)
-	if err != nil {
-		return fmt.Errorf("写入音频数据失败: %w", err)
-	}
-
-	return nil
+	return audioData
 }
 
 // WriteAudioFrame 写入音频帧
@@ -186,6 +221,7 @@ func (aw *AudioWriter) Close() error {
 	}
 
 	aw.closed = true
+	aw.leak.Close()
 
 	// 关闭 stdin
 	if aw.stdin != nil {
@@ -194,8 +230,10 @@ func (aw *AudioWriter) Close() error {
 	}
 
 	// 等待进程结束
+	var waitErr error
 	if aw.process != nil {
-		aw.process.Wait()
+		dog := startWatchdog(aw.timeout, aw.cancel)
+		waitErr = dog.stop("等待编码结束", aw.process.Wait())
 		aw.process = nil
 	}
 
@@ -204,9 +242,57 @@ func (aw *AudioWriter) Close() error {
 		aw.cancel()
 	}
 
+	if waitErr != nil {
+		aw.removeTemp()
+		return fmt.Errorf("FFmpeg 进程异常退出: %w", classify(waitErr, aw.stderr.Tail()))
+	}
+
+	// 编码成功后再替换目标文件
+	if aw.tempFile != "" {
+		tempFile := aw.tempFile
+		aw.tempFile = ""
+		return CommitOutput(tempFile, aw.filename)
+	}
+
 	return nil
 }
 
+// Abort 中止写入：终止 FFmpeg 并删除临时文件，目标文件保持不变；已关闭时无操作
+func (aw *AudioWriter) Abort() error {
+	aw.mutex.Lock()
+	defer aw.mutex.Unlock()
+
+	if aw.closed {
+		return nil
+	}
+	aw.closed = true
+	aw.leak.Close()
+
+	// 先取消上下文以终止进程，避免 FFmpeg 把不完整的数据封装成文件
+	if aw.cancel != nil {
+		aw.cancel()
+	}
+	if aw.stdin != nil {
+		aw.stdin.Close()
+		aw.stdin = nil
+	}
+	if aw.process != nil {
+		aw.process.Wait()
+		aw.process = nil
+	}
+
+	aw.removeTemp()
+	return nil
+}
+
+// removeTemp 删除临时输出文件
+func (aw *AudioWriter) removeTemp() {
+	if aw.tempFile != "" {
+		os.Remove(aw.tempFile)
+		aw.tempFile = ""
+	}
+}
+
 // IsClosed 检查是否已关闭
 func (aw *AudioWriter) IsClosed() bool {
 	aw.mutex.RLock()
@@ -0,0 +1,35 @@
+package ffmpeg
+
+import (
+	"strings"
+)
+
+// Command 一次 FFmpeg/FFprobe 调用的完整参数，用于试运行和审计
+type Command struct {
+	Name string
+	Args []string
+}
+
+// String 返回可直接粘贴到 shell 中执行的命令行
+func (c Command) String() string {
+	parts := make([]string, 0, len(c.Args)+1)
+	parts = append(parts, shellQuote(c.Name))
+	for _, arg := range c.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellQuote 按 POSIX shell 规则为参数加引号
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune("-_./:=,+%@", r))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,45 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatSeconds 把 d 格式化为 FFmpeg 参数和 concat 脚本中的秒数，保留微秒精度
+func FormatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 6, 64)
+}
+
+// WriteConcatFile 向 concat 分离器脚本写入引用 path 的 file 行，路径转为绝对路径，需配合 -safe 0 使用
+func WriteConcatFile(w io.Writer, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("解析文件路径失败: %w", err)
+	}
+	// concat 脚本中单引号需转义为 '\''
+	_, err = fmt.Fprintf(w, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	return err
+}
+
+// WriteConcatList 把 parts 按顺序写为 concat 分离器的文件列表 listName
+func WriteConcatList(listName string, parts []string) error {
+	listFile, err := os.Create(listName)
+	if err != nil {
+		return fmt.Errorf("创建拼接列表失败: %w", err)
+	}
+	for _, part := range parts {
+		if err := WriteConcatFile(listFile, part); err != nil {
+			listFile.Close()
+			return err
+		}
+	}
+	if err := listFile.Close(); err != nil {
+		return fmt.Errorf("写入拼接列表失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EmbedCover 将 cover 图片嵌入 filename 作为封面，原地替换文件（流复制，不重新编码）
+//
+// MP4/MOV/M4V 写入 attached_pic 视频流并替换已有封面；MKV 写入附件。cover 应为 JPEG 或 PNG。
+func EmbedCover(ctx context.Context, filename, cover string, processMgr *ProcessManager) error {
+	var args []string
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".mp4", ".m4v", ".mov":
+		probe, err := ProbeContext(ctx, filename, processMgr)
+		if err != nil {
+			return fmt.Errorf("探测 %s 失败: %w", filename, err)
+		}
+		// 0:V 只选择非封面的视频流，原有封面被丢弃
+		args = []string{
+			"-i", filename,
+			"-i", cover,
+			"-map", "0", "-map", "-0:v", "-map", "0:V", "-map", "1",
+			"-c", "copy",
+			"-disposition:v:" + strconv.Itoa(len(probe.VideoStreams())), "attached_pic",
+		}
+	case ".mkv":
+		mimetype := "image/jpeg"
+		if strings.EqualFold(filepath.Ext(cover), ".png") {
+			mimetype = "image/png"
+		}
+		args = []string{
+			"-i", filename,
+			"-map", "0",
+			"-c", "copy",
+			"-attach", cover,
+			"-metadata:s:t", "mimetype=" + mimetype,
+			"-metadata:s:t", "filename=cover" + filepath.Ext(cover),
+		}
+	default:
+		return fmt.Errorf("不支持嵌入封面的格式: %s", filename)
+	}
+
+	tempFile, err := TempOutputPath(filename)
+	if err != nil {
+		return err
+	}
+	args = append(append([]string{"-hide_banner", "-loglevel", "error"}, args...), "-y", tempFile)
+
+	process, err := processMgr.StartProcess(ctx, "ffmpeg", args, nil)
+	if err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("启动封面嵌入进程失败: %w", err)
+	}
+	if err := process.Wait(); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("嵌入封面失败: %w", err)
+	}
+	return CommitOutput(tempFile, filename)
+}
+
+// EmbedCoverImage 将 img 编码为 JPEG 后嵌入 filename 作为封面
+func EmbedCoverImage(ctx context.Context, filename string, img image.Image, processMgr *ProcessManager) error {
+	cover, err := processMgr.Temp().CreateFile("cover-*.jpg")
+	if err != nil {
+		return fmt.Errorf("创建封面文件失败: %w", err)
+	}
+	defer processMgr.Temp().Remove(cover)
+
+	if err := encodeImageFile(cover, func(f *os.File) error {
+		return jpeg.Encode(f, img, &jpeg.Options{Quality: 90})
+	}); err != nil {
+		return fmt.Errorf("编码封面失败: %w", err)
+	}
+	return EmbedCover(ctx, filename, cover, processMgr)
+}
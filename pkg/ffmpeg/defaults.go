@@ -0,0 +1,56 @@
+package ffmpeg
+
+import "sync"
+
+// 包级默认设置，通常在程序启动时由配置加载器（config.Config.Install）设置一次
+var (
+	defaultsMutex         sync.RWMutex
+	defaultProcessOptions ProcessManagerOptions
+	defaultTempDir        string
+)
+
+// SetDefaultProcessManagerOptions 设置 NewProcessManager 使用的默认选项
+//
+// 使用 NewProcessManagerWithOptions 显式传入选项时，未设置的 FFmpegPath/FFprobePath 仍取自这里。
+func SetDefaultProcessManagerOptions(options ProcessManagerOptions) {
+	defaultsMutex.Lock()
+	defer defaultsMutex.Unlock()
+	defaultProcessOptions = options
+}
+
+// DefaultProcessManagerOptions 返回当前的默认进程管理器选项
+func DefaultProcessManagerOptions() ProcessManagerOptions {
+	defaultsMutex.RLock()
+	defer defaultsMutex.RUnlock()
+	return defaultProcessOptions
+}
+
+// BinaryPath 返回 ffmpeg/ffprobe 的可执行文件路径，未配置时返回 name 本身（从 PATH 查找）
+func BinaryPath(name string) string {
+	return DefaultProcessManagerOptions().binary(name)
+}
+
+// SetTempDir 设置中间文件（分段、预览代理等）的默认目录，空字符串表示系统临时目录
+func SetTempDir(dir string) {
+	defaultsMutex.Lock()
+	defer defaultsMutex.Unlock()
+	defaultTempDir = dir
+}
+
+// TempDir 返回中间文件的默认目录，未设置时返回空字符串（与 os.MkdirTemp 一致，表示系统临时目录）
+func TempDir() string {
+	defaultsMutex.RLock()
+	defer defaultsMutex.RUnlock()
+	return defaultTempDir
+}
+
+// binary 将 "ffmpeg"/"ffprobe" 映射为配置的路径，其他名称原样返回
+func (o ProcessManagerOptions) binary(name string) string {
+	switch {
+	case name == "ffmpeg" && o.FFmpegPath != "":
+		return o.FFmpegPath
+	case name == "ffprobe" && o.FFprobePath != "":
+		return o.FFprobePath
+	}
+	return name
+}
@@ -0,0 +1,159 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sync"
+)
+
+// Dither 高位深帧量化为 8 位时使用的抖动方式
+type Dither string
+
+const (
+	// DitherNone 直接截断低 8 位（默认）
+	DitherNone Dither = ""
+	// DitherOrdered 8×8 Bayer 有序抖动，开销最低，暗部可能看到规则纹理
+	DitherOrdered Dither = "ordered"
+	// DitherBlueNoise 64×64 蓝噪声阈值图抖动，噪点均匀、无明显纹理
+	DitherBlueNoise Dither = "blue_noise"
+)
+
+// ParseDither 解析抖动方式名称，空字符串和 "none" 表示不抖动
+func ParseDither(name string) (Dither, error) {
+	switch Dither(name) {
+	case DitherNone, "none":
+		return DitherNone, nil
+	case DitherOrdered, DitherBlueNoise:
+		return Dither(name), nil
+	}
+	return DitherNone, fmt.Errorf("未知的抖动方式: %q（支持 none、ordered、blue_noise）", name)
+}
+
+// bayer8 8×8 Bayer 矩阵，取值 0–63
+var bayer8 = [8][8]uint16{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// blueNoiseSize 蓝噪声阈值图边长
+const blueNoiseSize = 64
+
+var (
+	blueNoiseOnce sync.Once
+	blueNoise     []uint16 // 按 void-and-cluster 生成的排名，取值 0–blueNoiseSize²-1
+)
+
+// DitherFrame 把帧量化为 8 位 *image.RGBA，按 dither 在截断前加入阈值抖动
+//
+// 本身就是 8 位的帧（*image.RGBA、*image.NRGBA、*image.YCbCr、*image.Gray 等）没有可保留的精度，
+// 原样返回，DitherNone 和未知的方式同样原样返回；dst 尺寸与帧相同时复用，否则重新分配。alpha 通道只做四舍五入。
+func DitherFrame(dst *image.RGBA, frame image.Image, dither Dither) image.Image {
+	if (dither != DitherOrdered && dither != DitherBlueNoise) || isEightBit(frame) {
+		return frame
+	}
+	bounds := frame.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if dst == nil || dst.Rect.Dx() != w || dst.Rect.Dy() != h {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	// 阈值按 [0, 1) 缩放到 1/65536 单位；三个颜色通道在阈值图中错开，避免灰阶处三通道同时跳变
+	var ranks []uint16
+	if dither == DitherBlueNoise {
+		ranks = blueNoiseRanks()
+	}
+	threshold := func(x, y, channel int) uint32 {
+		if ranks == nil {
+			return (uint32(bayer8[(y+channel*3)&7][(x+channel*5)&7])*2 + 1) << 9
+		}
+		x, y = (x+channel*23)%blueNoiseSize, (y+channel*41)%blueNoiseSize
+		return (uint32(ranks[y*blueNoiseSize+x])*2 + 1) << 3
+	}
+	// quantize 把 16 位值 v 映射为 floor(v*255/65535 + t)
+	quantize := func(v, t uint32) uint8 {
+		return uint8(min((uint64(v)*255*65536/65535+uint64(t))>>16, 255))
+	}
+
+	rgba64, _ := frame.(*image.RGBA64)
+	for y := 0; y < h; y++ {
+		row := dst.Pix[y*dst.Stride:]
+		for x := 0; x < w; x++ {
+			var r, g, b, a uint32
+			if rgba64 != nil {
+				p := rgba64.Pix[rgba64.PixOffset(bounds.Min.X+x, bounds.Min.Y+y):]
+				r, g, b, a = uint32(p[0])<<8|uint32(p[1]), uint32(p[2])<<8|uint32(p[3]), uint32(p[4])<<8|uint32(p[5]), uint32(p[6])<<8|uint32(p[7])
+			} else {
+				r, g, b, a = frame.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			}
+			a8 := uint8((a*255 + 32767) / 65535)
+			// 预乘颜色不能超过 alpha
+			row[x*4] = min(quantize(r, threshold(x, y, 0)), a8)
+			row[x*4+1] = min(quantize(g, threshold(x, y, 1)), a8)
+			row[x*4+2] = min(quantize(b, threshold(x, y, 2)), a8)
+			row[x*4+3] = a8
+		}
+	}
+	return dst
+}
+
+// isEightBit 判断帧类型是否只有 8 位精度
+func isEightBit(frame image.Image) bool {
+	switch frame.(type) {
+	case *image.RGBA, *image.NRGBA, *image.YCbCr, *image.NYCbCrA, *image.Gray, *image.Paletted, *image.Uniform:
+		return true
+	}
+	return false
+}
+
+// blueNoiseRanks 返回蓝噪声阈值图，首次调用时生成
+func blueNoiseRanks() []uint16 {
+	blueNoiseOnce.Do(func() {
+		blueNoise = generateBlueNoise(blueNoiseSize, 1.9)
+	})
+	return blueNoise
+}
+
+// generateBlueNoise 用 void-and-cluster 方法生成 size×size 的排名图：
+// 从一个点开始，每次在高斯加权能量最低（离已有点最远）的空位放下下一个点，放下的顺序即排名
+func generateBlueNoise(size int, sigma float64) []uint16 {
+	n := size * size
+	// 环面上各偏移的高斯权重
+	kernel := make([]float64, n)
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			wx, wy := float64(min(dx, size-dx)), float64(min(dy, size-dy))
+			kernel[dy*size+dx] = math.Exp(-(wx*wx + wy*wy) / (2 * sigma * sigma))
+		}
+	}
+	energy := make([]float64, n)
+	filled := make([]bool, n)
+	ranks := make([]uint16, n)
+	for rank := range n {
+		best := 0
+		if rank > 0 {
+			best = -1
+			for i := range n {
+				if !filled[i] && (best < 0 || energy[i] < energy[best]) {
+					best = i
+				}
+			}
+		}
+		filled[best] = true
+		ranks[best] = uint16(rank)
+		bx, by := best%size, best/size
+		for y := 0; y < size; y++ {
+			ky := (y - by + size) % size
+			for x := 0; x < size; x++ {
+				energy[y*size+x] += kernel[ky*size+(x-bx+size)%size]
+			}
+		}
+	}
+	return ranks
+}
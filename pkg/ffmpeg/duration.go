@@ -0,0 +1,106 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TaggedDuration 返回流标签中的时长（秒），没有时为 0
+//
+// Matroska 封装不写 duration 字段，而是以 "DURATION"（或带语言后缀的 "DURATION-eng"）
+// 标签记录 "01:23:45.678000000" 格式的时长。
+func (s *ProbeStream) TaggedDuration() float64 {
+	for key, value := range s.Tags {
+		upper := strings.ToUpper(key)
+		if upper == "DURATION" || strings.HasPrefix(upper, "DURATION-") {
+			if d := parseClockDuration(value); d > 0 {
+				return d
+			}
+		}
+	}
+	return 0
+}
+
+// parseClockDuration 解析 "HH:MM:SS.fraction" 格式的时长，格式无效时返回 0
+func parseClockDuration(s string) float64 {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	seconds, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0
+	}
+	return float64(hours*3600+minutes*60) + seconds
+}
+
+// CountPackets 用 ffprobe -count_packets 统计第 stream 个流的包数，processMgr 为 nil 时临时创建
+//
+// 只读取封装层，不解码，代价约为顺序读一遍文件；对视频流而言包数即帧数。
+func CountPackets(ctx context.Context, filename string, stream int, processMgr *ProcessManager) (int64, error) {
+	if processMgr == nil {
+		processMgr = NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	output, err := processMgr.Output(ctx, "ffprobe", countPacketsArgs(filename, stream))
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe 统计帧数失败: %w", err)
+	}
+	count, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析帧数失败: %q", strings.TrimSpace(string(output)))
+	}
+	return count, nil
+}
+
+// countPacketsArgs 构建统计包数的 ffprobe 参数
+func countPacketsArgs(filename string, stream int) []string {
+	return []string{
+		"-v", "error",
+		"-select_streams", strconv.Itoa(stream),
+		"-count_packets",
+		"-show_entries", "stream=nb_read_packets",
+		"-of", "default=nokey=1:noprint_wrappers=1",
+		"-i", filename,
+	}
+}
+
+// resolveVideoDuration 容器没有时长时（部分 MKV、直播录制的 TS/FLV）依次退回到流时长、
+// 流的 DURATION 标签、nb_frames 除以帧率，最后用 CountPackets 统计帧数，仍无法确定时保持 0
+func (vr *VideoReader) resolveVideoDuration(ctx context.Context, info *VideoInfo, stream *ProbeStream) {
+	info.Duration = resolveStreamDuration(info.Duration, stream)
+	if info.Duration > 0 || info.FrameRate.IsZero() {
+		return
+	}
+	frames := stream.NumFrames
+	if frames <= 0 {
+		count, err := CountPackets(ctx, vr.filename, stream.Index, vr.processMgr)
+		if err != nil {
+			return
+		}
+		frames = count
+		info.NumFrames = count
+	}
+	// 可变帧率的文件 r_frame_rate 可能远高于实际帧率，优先用平均帧率换算
+	rate := stream.AvgFrameRate
+	if rate.IsZero() {
+		rate = info.FrameRate
+	}
+	info.Duration = float64(frames) / rate.Float64()
+}
+
+// resolveStreamDuration 容器没有时长时退回到流时长或流的 DURATION 标签
+func resolveStreamDuration(duration float64, stream *ProbeStream) float64 {
+	if duration > 0 {
+		return duration
+	}
+	if stream.Duration > 0 {
+		return stream.Duration
+	}
+	return stream.TaggedDuration()
+}
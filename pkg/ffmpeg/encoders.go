@@ -0,0 +1,96 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// encoderCache 按 ffmpeg 可执行文件路径缓存 -encoders 的结果
+var encoderCache = struct {
+	mutex    sync.Mutex
+	encoders map[string]map[string]bool
+}{encoders: map[string]map[string]bool{}}
+
+// encoderQueryTimeout 查询 -encoders 的最长时间
+const encoderQueryTimeout = 10 * time.Second
+
+// Encoders 返回默认 ffmpeg 支持的编码器名称集合，结果会被缓存
+func Encoders() (map[string]bool, error) {
+	pm := NewProcessManager()
+	defer pm.Close()
+	return pm.Encoders(context.Background())
+}
+
+// HasEncoder 检查默认 ffmpeg 是否支持编码器 name
+func HasEncoder(name string) (bool, error) {
+	encoders, err := Encoders()
+	if err != nil {
+		return false, err
+	}
+	return encoders[name], nil
+}
+
+// CheckEncoder 编码器不可用时返回 ErrCodecNotFound；无法查询时返回查询错误
+func CheckEncoder(name string) error {
+	pm := NewProcessManager()
+	defer pm.Close()
+	return pm.CheckEncoder(context.Background(), name)
+}
+
+// Encoders 返回管理器所用 ffmpeg 支持的编码器名称集合，按可执行文件路径缓存
+//
+// 查询作为受管理的进程运行，受 ctx、MaxProcesses 限制，且最长 10 秒。
+func (pm *ProcessManager) Encoders(ctx context.Context) (map[string]bool, error) {
+	binary := pm.options.binary("ffmpeg")
+	encoderCache.mutex.Lock()
+	defer encoderCache.mutex.Unlock()
+	if encoders, ok := encoderCache.encoders[binary]; ok {
+		return encoders, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, encoderQueryTimeout)
+	defer cancel()
+	output, err := pm.Output(ctx, "ffmpeg", []string{"-hide_banner", "-encoders"})
+	if err != nil {
+		return nil, fmt.Errorf("查询 ffmpeg 编码器失败: %w", interrupted(ctx, "查询编码器", err))
+	}
+	encoders := parseEncoders(output)
+	encoderCache.encoders[binary] = encoders
+	return encoders, nil
+}
+
+// CheckEncoder 管理器所用 ffmpeg 不支持编码器 name 时返回 ErrCodecNotFound；无法查询时返回查询错误
+func (pm *ProcessManager) CheckEncoder(ctx context.Context, name string) error {
+	encoders, err := pm.Encoders(ctx)
+	if err != nil {
+		return err
+	}
+	if !encoders[name] {
+		return fmt.Errorf("%w: ffmpeg 不支持编码器 %s（可用 ffmpeg -encoders 查看）", ErrCodecNotFound, name)
+	}
+	return nil
+}
+
+// parseEncoders 解析 "-encoders" 输出，分隔线 "------" 之后每行为 " V....D name 描述"
+func parseEncoders(output []byte) map[string]bool {
+	encoders := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	listing := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !listing {
+			listing = strings.HasPrefix(line, "---")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			encoders[fields[1]] = true
+		}
+	}
+	return encoders
+}
@@ -0,0 +1,125 @@
+package ffmpeg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// FFmpeg/ffprobe 失败分类，可通过 errors.Is 判断
+var (
+	ErrCodecNotFound = errors.New("编解码器不存在")
+	ErrInvalidInput  = errors.New("无效的输入文件")
+	ErrDiskFull      = errors.New("磁盘空间不足")
+	ErrPermission    = errors.New("权限不足")
+	ErrBrokenPipe    = errors.New("管道已断开")
+)
+
+// ErrStreamNotFound 没有符合 StreamSelector 的流
+var ErrStreamNotFound = errors.New("未找到匹配的流")
+
+// ErrTempQuotaExceeded 临时文件总大小超出 TempManager 配额
+var ErrTempQuotaExceeded = errors.New("临时文件超出配额")
+
+// Error FFmpeg/ffprobe 执行失败的详细信息
+type Error struct {
+	Kinds  []error // 匹配到的分类，按可能的根本原因排序
+	Err    error   // 原始错误，通常为 *exec.ExitError 或管道写入错误
+	Stderr string  // stderr 的最后几行
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	var b strings.Builder
+	if len(e.Kinds) > 0 {
+		b.WriteString(e.Kinds[0].Error())
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	if e.Stderr != "" {
+		fmt.Fprintf(&b, "\n%s", e.Stderr)
+	}
+	return b.String()
+}
+
+// Unwrap 同时暴露分类和原始错误，使 errors.Is 对两者都成立
+func (e *Error) Unwrap() []error {
+	return append(append([]error{}, e.Kinds...), e.Err)
+}
+
+// stderrPatterns stderr 关键字到分类的映射（小写匹配）
+var stderrPatterns = []struct {
+	kind     error
+	patterns []string
+}{
+	{ErrCodecNotFound, []string{
+		"unknown encoder",
+		"unknown decoder",
+		"encoder not found",
+		"decoder not found",
+		"codec not currently supported",
+		"unsupported codec",
+	}},
+	{ErrInvalidInput, []string{
+		"invalid data found when processing input",
+		"no such file or directory",
+		"moov atom not found",
+		"could not find codec parameters",
+		"does not contain any stream",
+	}},
+	{ErrDiskFull, []string{
+		"no space left on device",
+		"disk quota exceeded",
+	}},
+	{ErrPermission, []string{
+		"permission denied",
+		"operation not permitted",
+	}},
+	{ErrBrokenPipe, []string{
+		"broken pipe",
+	}},
+}
+
+// classify 根据原始错误和 stderr 内容生成分类错误，无法提供额外信息时原样返回
+func classify(err error, stderr string) error {
+	if err == nil {
+		return nil
+	}
+
+	var kinds []error
+	lower := strings.ToLower(stderr)
+	for _, entry := range stderrPatterns {
+		for _, pattern := range entry.patterns {
+			if strings.Contains(lower, pattern) {
+				kinds = append(kinds, entry.kind)
+				break
+			}
+		}
+	}
+
+	// 管道错误本身只说明进程已退出，根本原因通常在 stderr 中，因此排在最后
+	switch {
+	case errors.Is(err, syscall.EPIPE):
+		kinds = appendKind(kinds, ErrBrokenPipe)
+	case errors.Is(err, syscall.ENOSPC):
+		kinds = appendKind(kinds, ErrDiskFull)
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		kinds = appendKind(kinds, ErrPermission)
+	}
+
+	if len(kinds) == 0 && stderr == "" {
+		return err
+	}
+	return &Error{Kinds: kinds, Err: err, Stderr: stderr}
+}
+
+// appendKind 追加尚未包含的分类
+func appendKind(kinds []error, kind error) []error {
+	for _, k := range kinds {
+		if k == kind {
+			return kinds
+		}
+	}
+	return append(kinds, kind)
+}
@@ -0,0 +1,14 @@
+package ffmpeg
+
+import "strings"
+
+// filterOptionEscaper 转义滤镜选项值中对选项解析有特殊含义的字符
+var filterOptionEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`)
+
+// QuoteFilterArg 转义滤镜参数值，使其中的 :、,、' 和 \ 在滤镜图和滤镜选项两级解析后保持原样
+//
+// FFmpeg 先按滤镜图解析（去掉一层引号和转义）再按 key=value:key=value 解析选项，
+// 因此先用反斜杠转义选项级的 \、' 和 :，再用单引号包裹供滤镜图解析，如 timecode='09\:57\:00\:00'。
+func QuoteFilterArg(s string) string {
+	return "'" + strings.ReplaceAll(filterOptionEscaper.Replace(s), "'", `'\''`) + "'"
+}
@@ -0,0 +1,70 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+)
+
+// getToken 按 FFmpeg av_get_token 的规则读取一个记号：\ 转义下一个字符，单引号内原样保留，遇到 term 中的字符停止
+func getToken(s, term string) (token, rest string) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case strings.IndexByte(term, c) >= 0:
+			return b.String(), s[i:]
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			b.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), ""
+}
+
+// parseFilterOptions 按滤镜图和滤镜选项两级解析 name=key=value:key=value
+func parseFilterOptions(filter string) map[string]string {
+	_, args, _ := strings.Cut(filter, "=")
+	args, _ = getToken(args, "[],;")
+	options := map[string]string{}
+	for args != "" {
+		key, rest := getToken(args, "=")
+		value, rest := getToken(strings.TrimPrefix(rest, "="), ":")
+		options[key] = value
+		args = strings.TrimPrefix(rest, ":")
+	}
+	return options
+}
+
+func TestQuoteFilterArg(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"普通路径", "/tmp/font.ttf", `'/tmp/font.ttf'`},
+		{"时间码中的冒号", "09:57:00:00", `'09\:57\:00\:00'`},
+		{"Windows 路径", `C:\Fonts\a.ttf`, `'C\:\\Fonts\\a.ttf'`},
+		{"单引号", "it's", `'it\'\''s'`},
+		{"逗号", "eq(n,3)", `'eq(n,3)'`},
+		{"混合", `a:b'c\d,e`, `'a\:b\'\''c\\d,e'`},
+		{"空值", "", `''`},
+	}
+	for _, tt := range tests {
+		got := QuoteFilterArg(tt.value)
+		if got != tt.want {
+			t.Errorf("%s: QuoteFilterArg(%q) = %s，期望 %s", tt.name, tt.value, got, tt.want)
+		}
+		options := parseFilterOptions("drawtext=text=" + got + ":fontsize=12")
+		if options["text"] != tt.value || options["fontsize"] != "12" {
+			t.Errorf("%s: 两级解析后得到 %q，期望 text=%q fontsize=12", tt.name, options, tt.value)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ImageFormat 单帧导出的图片格式
+type ImageFormat string
+
+const (
+	ImagePNG  ImageFormat = "png"
+	ImageJPEG ImageFormat = "jpeg"
+	ImageWebP ImageFormat = "webp"
+)
+
+// ImageOptions 单帧导出选项
+type ImageOptions struct {
+	// Format 图片格式，默认按扩展名推断
+	Format ImageFormat
+	// Quality JPEG/WebP 质量（1-100），默认 90
+	Quality int
+	// Lossless WebP 使用无损编码，忽略 Quality
+	Lossless bool
+}
+
+// ImageFormatFromPath 按扩展名推断图片格式
+func ImageFormatFromPath(path string) (ImageFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return ImagePNG, nil
+	case ".jpg", ".jpeg":
+		return ImageJPEG, nil
+	case ".webp":
+		return ImageWebP, nil
+	default:
+		return "", fmt.Errorf("不支持的图片格式: %s", path)
+	}
+}
+
+// SaveImage 将图像编码保存到 path，先写入同目录临时文件再重命名
+//
+// PNG 和 JPEG 使用标准库编码，WebP 通过 FFmpeg 的 libwebp 编码。
+func SaveImage(ctx context.Context, img image.Image, path string, options *ImageOptions, processMgr *ProcessManager) error {
+	if options == nil {
+		options = &ImageOptions{}
+	}
+	format := options.Format
+	if format == "" {
+		var err error
+		if format, err = ImageFormatFromPath(path); err != nil {
+			return err
+		}
+	}
+	quality := options.Quality
+	if quality == 0 {
+		quality = 90
+	}
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("无效的图片质量: %d", quality)
+	}
+
+	tempFile, err := TempOutputPath(path)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case ImagePNG:
+		err = encodeImageFile(tempFile, func(f *os.File) error { return png.Encode(f, img) })
+	case ImageJPEG:
+		err = encodeImageFile(tempFile, func(f *os.File) error {
+			return jpeg.Encode(f, img, &jpeg.Options{Quality: quality})
+		})
+	case ImageWebP:
+		err = encodeWebP(ctx, img, tempFile, quality, options.Lossless, processMgr)
+	default:
+		err = fmt.Errorf("不支持的图片格式: %s", format)
+	}
+	if err != nil {
+		os.Remove(tempFile)
+		return err
+	}
+	return CommitOutput(tempFile, path)
+}
+
+// encodeImageFile 创建文件并用 encode 写入内容
+func encodeImageFile(path string, encode func(f *os.File) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建图片文件失败: %w", err)
+	}
+	if err := encode(file); err != nil {
+		file.Close()
+		return fmt.Errorf("编码图片失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("写入图片文件失败: %w", err)
+	}
+	return nil
+}
+
+// webpArgs 构建从 stdin 读取一帧 RGBA 并编码为 WebP 的 FFmpeg 参数
+func webpArgs(width, height, quality int, lossless bool, output string) []string {
+	args := append(logArgs(LogError),
+		"-f", "rawvideo",
+		"-pix_fmt", string(PixelFormatRGBA),
+		"-s", fmt.Sprintf("%dx%d", width, height),
+		"-i", "-",
+		"-frames:v", "1",
+		"-c:v", "libwebp",
+	)
+	if lossless {
+		args = append(args, "-lossless", "1")
+	} else {
+		args = append(args, "-quality", strconv.Itoa(quality))
+	}
+	return append(args, "-y", output)
+}
+
+// encodeWebP 通过 FFmpeg 将图像编码为 WebP
+func encodeWebP(ctx context.Context, img image.Image, output string, quality int, lossless bool, processMgr *ProcessManager) error {
+	if processMgr == nil {
+		processMgr = NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	buf := make([]byte, PixelFormatRGBA.FrameSize(width, height))
+	EncodeFrame(buf, img, PixelFormatRGBA, width, height)
+
+	stderr := newTailWriter()
+	process, err := processMgr.StartProcessWithPipes(ctx, "ffmpeg", webpArgs(width, height, quality, lossless, output), nil, &ProcessPipes{
+		Stdin:  true,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("启动 FFmpeg 失败: %w", err)
+	}
+
+	_, writeErr := process.Stdin().Write(buf)
+	process.Stdin().Close()
+	if err := process.Wait(); err != nil {
+		return fmt.Errorf("编码 WebP 失败: %w", classify(err, stderr.Tail()))
+	}
+	if writeErr != nil {
+		return fmt.Errorf("写入帧数据失败: %w", classify(writeErr, stderr.Tail()))
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keyframes 用 ffprobe -skip_frame nokey 读取第 stream 个流所有关键帧的时间戳（秒，升序），
+// processMgr 为 nil 时临时创建
+//
+// 只解码关键帧，比完整解码快得多，但仍需读完整个文件。时间戳为流的原始时间，未减去起始时间。
+func Keyframes(ctx context.Context, filename string, stream int, processMgr *ProcessManager) ([]float64, error) {
+	if processMgr == nil {
+		processMgr = NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	output, err := processMgr.Output(ctx, "ffprobe", keyframesArgs(filename, stream))
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe 读取关键帧失败: %w", err)
+	}
+	return parseKeyframes(string(output)), nil
+}
+
+// keyframesArgs 构建只解码关键帧并输出其时间戳的 ffprobe 参数
+func keyframesArgs(filename string, stream int) []string {
+	return []string{
+		"-v", "error",
+		"-select_streams", strconv.Itoa(stream),
+		"-skip_frame", "nokey",
+		"-show_entries", "frame=best_effort_timestamp_time",
+		"-of", "csv=p=0",
+		"-i", filename,
+	}
+}
+
+// parseKeyframes 解析每行一个的时间戳，跳过 N/A 并排序去重
+func parseKeyframes(output string) []float64 {
+	var times []float64
+	for _, line := range strings.Split(output, "\n") {
+		field, _, _ := strings.Cut(strings.TrimSpace(line), ",")
+		t, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			continue
+		}
+		times = append(times, t)
+	}
+	sort.Float64s(times)
+	unique := times[:0]
+	for i, t := range times {
+		if i == 0 || t != times[i-1] {
+			unique = append(unique, t)
+		}
+	}
+	return unique
+}
+
+// Keyframes 返回所选视频流关键帧相对首帧的时间（升序），首次调用时读取并缓存
+//
+// 时间与 GetFrame 使用的时间一致，可直接用于无损剪切（-c copy）的切点对齐。
+func (vr *VideoReader) Keyframes() ([]time.Duration, error) {
+	return vr.KeyframesContext(context.Background())
+}
+
+// KeyframesContext 与 Keyframes 相同，ctx 取消或到期时终止 ffprobe；同时受 Options.Timeout 限制
+func (vr *VideoReader) KeyframesContext(ctx context.Context) ([]time.Duration, error) {
+	vr.keyframeMutex.Lock()
+	defer vr.keyframeMutex.Unlock()
+	if vr.keyframes != nil {
+		return vr.keyframes, nil
+	}
+
+	vr.mutex.RLock()
+	closed, info := vr.closed, vr.info
+	vr.mutex.RUnlock()
+	if closed {
+		return nil, fmt.Errorf("读取器已关闭")
+	}
+	if info == nil {
+		return nil, fmt.Errorf("视频未打开")
+	}
+
+	ctx, cancel := callContext(ctx, vr.ctx, vr.options.Timeout)
+	defer cancel()
+	times, err := Keyframes(ctx, vr.filename, info.VideoStream, vr.processMgr)
+	if err != nil {
+		return nil, interrupted(ctx, "读取关键帧", err)
+	}
+
+	// -ss 以首帧为 0 计时
+	keyframes := make([]time.Duration, len(times))
+	for i, pts := range times {
+		keyframes[i] = time.Duration((pts - info.StartTime) * float64(time.Second))
+	}
+	vr.keyframes = keyframes
+	return keyframes, nil
+}
+
+// KeyframeBefore 返回不晚于 t 的最后一个关键帧，即从 t 开始的无损剪切实际的起点；
+// t 之前没有关键帧时返回第一个关键帧
+func (vr *VideoReader) KeyframeBefore(t time.Duration) (time.Duration, error) {
+	keyframes, err := vr.nonEmptyKeyframes()
+	if err != nil {
+		return 0, err
+	}
+	i := sort.Search(len(keyframes), func(i int) bool { return keyframes[i] > t })
+	if i == 0 {
+		return keyframes[0], nil
+	}
+	return keyframes[i-1], nil
+}
+
+// KeyframeAfter 返回不早于 t 的第一个关键帧；t 之后没有关键帧时返回最后一个关键帧
+func (vr *VideoReader) KeyframeAfter(t time.Duration) (time.Duration, error) {
+	keyframes, err := vr.nonEmptyKeyframes()
+	if err != nil {
+		return 0, err
+	}
+	i := sort.Search(len(keyframes), func(i int) bool { return keyframes[i] >= t })
+	if i == len(keyframes) {
+		return keyframes[len(keyframes)-1], nil
+	}
+	return keyframes[i], nil
+}
+
+// SeekAccuracy 只定位到关键帧的快速跳转（输入侧 -ss 且不精确解码）在 t 处的预期误差，
+// 即 t 与其之前最近关键帧的距离；精确跳转的误差为 0
+func (vr *VideoReader) SeekAccuracy(t time.Duration) (time.Duration, error) {
+	keyframe, err := vr.KeyframeBefore(t)
+	if err != nil {
+		return 0, err
+	}
+	return max(0, t-keyframe), nil
+}
+
+// nonEmptyKeyframes 返回关键帧列表，没有关键帧时返回错误
+func (vr *VideoReader) nonEmptyKeyframes() ([]time.Duration, error) {
+	keyframes, err := vr.Keyframes()
+	if err != nil {
+		return nil, err
+	}
+	if len(keyframes) == 0 {
+		return nil, fmt.Errorf("%s 中没有关键帧", vr.filename)
+	}
+	return keyframes, nil
+}
+
+// IsKeyFrame GetFrame(t) 返回的帧是否为关键帧，即 t 与某个关键帧相差不到半帧；
+// 关键帧无法读取时返回 false
+func (vr *VideoReader) IsKeyFrame(t time.Duration) bool {
+	keyframes, err := vr.Keyframes()
+	if err != nil || len(keyframes) == 0 {
+		return false
+	}
+
+	vr.mutex.RLock()
+	half := time.Duration(float64(time.Second) / vr.fpsLocked() / 2)
+	vr.mutex.RUnlock()
+
+	// 第一个不早于 t-half 的关键帧
+	i := sort.Search(len(keyframes), func(i int) bool { return keyframes[i] >= t-half })
+	return i < len(keyframes) && keyframes[i] < t+half
+}
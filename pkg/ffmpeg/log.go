@@ -0,0 +1,100 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+)
+
+// LogLevel FFmpeg 日志级别，对应 -loglevel 参数
+type LogLevel string
+
+const (
+	LogQuiet LogLevel = "quiet"
+	LogError LogLevel = "error"
+	LogInfo  LogLevel = "info"
+	LogDebug LogLevel = "debug"
+)
+
+// DefaultLogLevel 未指定时使用的日志级别
+const DefaultLogLevel = LogError
+
+// logArgs 返回放在参数开头的全局日志参数
+func logArgs(level LogLevel) []string {
+	if level == "" {
+		level = DefaultLogLevel
+	}
+	return []string{"-hide_banner", "-loglevel", string(level)}
+}
+
+// logWriter 将 FFmpeg 的 stderr 按行写入日志，并保留最后几行用于错误信息
+type logWriter struct {
+	logger *log.Logger
+	prefix string
+
+	mutex   sync.Mutex
+	partial []byte
+	tail    []string
+}
+
+// maxTailLines 保留的 stderr 行数
+const maxTailLines = 20
+
+// newLogWriter 创建日志写入器，logger 为 nil 时使用 log.Default()
+func newLogWriter(logger *log.Logger, prefix string) *logWriter {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &logWriter{logger: logger, prefix: prefix}
+}
+
+// newTailWriter 创建只保留最后几行、不写日志的 stderr 捕获器
+func newTailWriter() *logWriter {
+	return &logWriter{}
+}
+
+// Write 实现 io.Writer
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		// FFmpeg 的进度行以 \r 结尾，同样视为行结束
+		i := bytes.IndexAny(w.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		w.emit(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// emit 输出一行，调用方需持有锁
+func (w *logWriter) emit(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if w.logger != nil {
+		w.logger.Printf("%s%s", w.prefix, line)
+	}
+	w.tail = append(w.tail, line)
+	if len(w.tail) > maxTailLines {
+		w.tail = w.tail[len(w.tail)-maxTailLines:]
+	}
+}
+
+// Tail 返回最后输出的几行，未换行的内容也会包含在内
+func (w *logWriter) Tail() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.partial) > 0 {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
+	return strings.Join(w.tail, "\n")
+}
@@ -0,0 +1,63 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// MuxAudioOptions 混流时音轨的编码参数
+type MuxAudioOptions struct {
+	Codec   string
+	Bitrate string
+	Filter  string // 编码前应用的 -af 滤镜链，如 loudnorm，为空时不过滤
+}
+
+// muxAudioArgs 构建把 audio 混入 filename 并写入 output 的参数：视频流复制，音频按 options 编码
+func muxAudioArgs(filename, audio string, options MuxAudioOptions, output string) []string {
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-i", filename,
+		"-i", audio,
+		"-map", "0:v", "-map", "1:a",
+		"-c:v", "copy",
+	}
+	if options.Filter != "" {
+		args = append(args, "-af", options.Filter)
+	}
+	return append(args,
+		"-c:a", options.Codec,
+		"-b:a", options.Bitrate,
+		"-y", output,
+	)
+}
+
+// MuxAudioCommand 返回 MuxAudio 将执行的 FFmpeg 命令（不执行）；实际输出为同目录临时文件
+func MuxAudioCommand(filename, audio string, options MuxAudioOptions) Command {
+	return Command{Name: "ffmpeg", Args: muxAudioArgs(filename, audio, options, filename)}
+}
+
+// MuxAudio 将音频文件 audio 按 options 编码后混入只含视频的 filename，原地替换文件（视频流复制，不重新编码）
+func MuxAudio(ctx context.Context, filename, audio string, options MuxAudioOptions, processMgr *ProcessManager) error {
+	if err := processMgr.CheckEncoder(ctx, options.Codec); err != nil {
+		return err
+	}
+	tempFile, err := TempOutputPath(filename)
+	if err != nil {
+		return err
+	}
+
+	stderr := newTailWriter()
+	process, err := processMgr.StartProcessWithPipes(ctx, "ffmpeg", muxAudioArgs(filename, audio, options, tempFile), nil, &ProcessPipes{
+		Stderr: stderr,
+	})
+	if err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("启动音频混流进程失败: %w", err)
+	}
+	if err := process.Wait(); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("混入音频失败: %w", classify(err, stderr.Tail()))
+	}
+	return CommitOutput(tempFile, filename)
+}
@@ -0,0 +1,32 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TempOutputPath 在目标文件同目录下创建临时文件路径，保留扩展名以便 FFmpeg 选择封装格式
+func TempOutputPath(filename string) (string, error) {
+	dir := filepath.Dir(filename)
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filepath.Base(filename), ext)
+
+	file, err := os.CreateTemp(dir, "."+base+".moviego-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("创建临时输出文件失败: %w", err)
+	}
+	name := file.Name()
+	file.Close()
+	return name, nil
+}
+
+// CommitOutput 将临时文件重命名为最终文件
+func CommitOutput(tempFile, filename string) error {
+	if err := os.Rename(tempFile, filename); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("重命名输出文件失败: %w", err)
+	}
+	return nil
+}
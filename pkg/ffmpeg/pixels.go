@@ -0,0 +1,158 @@
+package ffmpeg
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// PixelFormat 写入器通过管道发送给 FFmpeg 的原始像素格式
+type PixelFormat string
+
+const (
+	// PixelFormatRGB24 每像素 3 字节 RGB（默认）
+	PixelFormatRGB24 PixelFormat = "rgb24"
+	// PixelFormatRGBA 每像素 4 字节 RGBA，*image.RGBA 帧可按行直接复制
+	PixelFormatRGBA PixelFormat = "rgba"
+	// PixelFormatYUV420P 平面 YUV 4:2:0（全范围），4:2:0 的 *image.YCbCr 帧可直接透传
+	PixelFormatYUV420P PixelFormat = "yuv420p"
+)
+
+// FrameSize 返回一帧在该格式下的字节数
+func (f PixelFormat) FrameSize(width, height int) int {
+	switch f {
+	case PixelFormatRGBA:
+		return width * height * 4
+	case PixelFormatYUV420P:
+		cw, ch := (width+1)/2, (height+1)/2
+		return width*height + 2*cw*ch
+	default:
+		return width * height * 3
+	}
+}
+
+// EncodeFrame 将帧按 format 布局写入 buf，buf 长度需为 format.FrameSize(width, height)
+//
+// *image.RGBA 和 *image.YCbCr 走按行处理的快速路径（rgb24 下 *image.RGBA64 也是），其他类型退化为逐像素 At()。
+func EncodeFrame(buf []byte, frame image.Image, format PixelFormat, width, height int) {
+	switch format {
+	case PixelFormatRGBA:
+		encodeRGBA(buf, frame, width, height)
+	case PixelFormatYUV420P:
+		encodeYUV420P(buf, frame, width, height)
+	default:
+		encodeRGB24(buf, frame, width, height)
+	}
+}
+
+// encodeRGB24 打包为 rgb24
+func encodeRGB24(buf []byte, frame image.Image, width, height int) {
+	bounds := frame.Bounds()
+	idx := 0
+
+	switch img := frame.(type) {
+	case *image.RGBA:
+		for y := 0; y < height; y++ {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := 0; x < width*4; x += 4 {
+				buf[idx] = row[x]
+				buf[idx+1] = row[x+1]
+				buf[idx+2] = row[x+2]
+				idx += 3
+			}
+		}
+	case *image.YCbCr:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				c := img.YCbCrAt(bounds.Min.X+x, bounds.Min.Y+y)
+				buf[idx], buf[idx+1], buf[idx+2] = color.YCbCrToRGB(c.Y, c.Cb, c.Cr)
+				idx += 3
+			}
+		}
+	case *image.RGBA64:
+		// 取每个大端 16 位通道的高字节，与 At().RGBA()>>8 相同
+		for y := 0; y < height; y++ {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := 0; x < width*8; x += 8 {
+				buf[idx] = row[x]
+				buf[idx+1] = row[x+2]
+				buf[idx+2] = row[x+4]
+				idx += 3
+			}
+		}
+	default:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				r, g, b, _ := frame.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				buf[idx] = byte(r >> 8)
+				buf[idx+1] = byte(g >> 8)
+				buf[idx+2] = byte(b >> 8)
+				idx += 3
+			}
+		}
+	}
+}
+
+// encodeRGBA 打包为 rgba，*image.RGBA 按行复制
+func encodeRGBA(buf []byte, frame image.Image, width, height int) {
+	rowBytes := width * 4
+	if img, ok := frame.(*image.RGBA); ok {
+		bounds := img.Bounds()
+		if img.Stride == rowBytes && bounds.Min == (image.Point{}) {
+			copy(buf, img.Pix[:rowBytes*height])
+			return
+		}
+		for y := 0; y < height; y++ {
+			start := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(buf[y*rowBytes:], img.Pix[start:start+rowBytes])
+		}
+		return
+	}
+
+	// 其他类型借助 draw 转换到以 buf 为底层的 RGBA
+	dst := &image.RGBA{Pix: buf, Stride: rowBytes, Rect: image.Rect(0, 0, width, height)}
+	draw.Draw(dst, dst.Rect, frame, frame.Bounds().Min, draw.Src)
+}
+
+// encodeYUV420P 打包为平面 yuv420p，4:2:0 的 *image.YCbCr 按平面逐行复制
+func encodeYUV420P(buf []byte, frame image.Image, width, height int) {
+	cw, ch := (width+1)/2, (height+1)/2
+	yPlane := buf[:width*height]
+	cbPlane := buf[width*height : width*height+cw*ch]
+	crPlane := buf[width*height+cw*ch:]
+	bounds := frame.Bounds()
+
+	if img, ok := frame.(*image.YCbCr); ok && img.SubsampleRatio == image.YCbCrSubsampleRatio420 {
+		for y := 0; y < height; y++ {
+			start := img.YOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(yPlane[y*width:], img.Y[start:start+width])
+		}
+		for y := 0; y < ch; y++ {
+			start := img.COffset(bounds.Min.X, bounds.Min.Y+2*y)
+			copy(cbPlane[y*cw:], img.Cb[start:start+cw])
+			copy(crPlane[y*cw:], img.Cr[start:start+cw])
+		}
+		return
+	}
+
+	// 其他类型逐像素转换，色度取每个 2x2 块左上角的像素
+	pixel := func(x, y int) color.YCbCr {
+		if img, ok := frame.(*image.YCbCr); ok {
+			return img.YCbCrAt(x, y)
+		}
+		r, g, b, _ := frame.At(x, y).RGBA()
+		yy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+		return color.YCbCr{Y: yy, Cb: cb, Cr: cr}
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := pixel(bounds.Min.X+x, bounds.Min.Y+y)
+			yPlane[y*width+x] = c.Y
+			if x%2 == 0 && y%2 == 0 {
+				ci := (y/2)*cw + x/2
+				cbPlane[ci] = c.Cb
+				crPlane[ci] = c.Cr
+			}
+		}
+	}
+}
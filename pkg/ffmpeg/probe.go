@@ -0,0 +1,449 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ProbeResult ffprobe 输出的完整媒体信息
+type ProbeResult struct {
+	Format   ProbeFormat
+	Streams  []ProbeStream
+	Chapters []ProbeChapter
+}
+
+// ProbeFormat 容器信息
+type ProbeFormat struct {
+	Filename       string
+	FormatName     string // 如 "mov,mp4,m4a,3gp,3g2,mj2"
+	FormatLongName string
+	StartTime      float64 // 秒
+	Duration       float64 // 秒
+	Size           int64   // 字节
+	BitRate        int64   // bit/s
+	NumStreams     int
+	NumPrograms    int
+	ProbeScore     int
+	Tags           map[string]string
+}
+
+// ProbeStream 单个流的信息，视频与音频字段只对相应类型的流有效
+type ProbeStream struct {
+	Index         int
+	CodecType     string // video/audio/subtitle/data/attachment
+	CodecName     string
+	CodecLongName string
+	CodecTag      string
+	Profile       string
+	TimeBase      string
+	StartTime     float64 // 秒
+	Duration      float64 // 秒
+	BitRate       int64
+	NumFrames     int64
+
+	// 视频
+	Width              int
+	Height             int
+	PixelFormat        string
+	BitDepth           int // bits_per_raw_sample，未知时为 0
+	Level              int
+	FieldOrder         string
+	SampleAspectRatio  string
+	DisplayAspectRatio string
+	FrameRate          Rational // r_frame_rate
+	AvgFrameRate       Rational // avg_frame_rate
+	Color              ColorInfo
+
+	// 音频
+	SampleFormat  string
+	SampleRate    int
+	Channels      int
+	ChannelLayout string
+	BitsPerSample int
+
+	Disposition Disposition
+	Tags        map[string]string
+}
+
+// ColorInfo 视频流的颜色信息
+type ColorInfo struct {
+	Range          string `json:"range"`     // tv/pc
+	Space          string `json:"space"`     // 矩阵系数，如 bt709、bt2020nc
+	Transfer       string `json:"transfer"`  // 如 bt709、smpte2084（PQ）、arib-std-b67（HLG）
+	Primaries      string `json:"primaries"` // 如 bt709、bt2020
+	ChromaLocation string `json:"chroma_location"`
+}
+
+// IsHDR 传输特性是否为 PQ 或 HLG
+func (c ColorInfo) IsHDR() bool {
+	return c.Transfer == "smpte2084" || c.Transfer == "arib-std-b67"
+}
+
+// Disposition 流的处置标记
+type Disposition struct {
+	Default         bool
+	Dub             bool
+	Original        bool
+	Comment         bool
+	Lyrics          bool
+	Karaoke         bool
+	Forced          bool
+	HearingImpaired bool
+	VisualImpaired  bool
+	CleanEffects    bool
+	AttachedPic     bool
+	TimedThumbnails bool
+	Captions        bool
+	Descriptions    bool
+	Metadata        bool
+}
+
+// ProbeChapter 章节信息
+type ProbeChapter struct {
+	ID        int64
+	TimeBase  string
+	StartTime float64 // 秒
+	EndTime   float64 // 秒
+	Tags      map[string]string
+}
+
+// Language 返回流的语言标签（ISO 639-2，如 "eng"），未标注时为空
+func (s *ProbeStream) Language() string {
+	return s.Tags["language"]
+}
+
+// Depth 返回每个样本的位深：优先取 bits_per_raw_sample，缺失时由像素格式或采样格式推断，未知时为 0
+func (s *ProbeStream) Depth() int {
+	if s.BitDepth > 0 {
+		return s.BitDepth
+	}
+	switch s.CodecType {
+	case "video":
+		return pixelFormatBitDepth(s.PixelFormat)
+	case "audio":
+		if s.BitsPerSample > 0 {
+			return s.BitsPerSample
+		}
+		return sampleFormatBitDepth(s.SampleFormat)
+	}
+	return 0
+}
+
+// Title 返回流的标题标签
+func (s *ProbeStream) Title() string {
+	return s.Tags["title"]
+}
+
+// StreamsOfType 返回指定类型的所有流，按文件中的顺序
+func (r *ProbeResult) StreamsOfType(codecType string) []ProbeStream {
+	var streams []ProbeStream
+	for _, stream := range r.Streams {
+		if stream.CodecType == codecType {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// VideoStreams 返回所有视频流（不含封面图）
+func (r *ProbeResult) VideoStreams() []ProbeStream {
+	var streams []ProbeStream
+	for _, stream := range r.StreamsOfType("video") {
+		if !stream.Disposition.AttachedPic {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+// AudioStreams 返回所有音频流
+func (r *ProbeResult) AudioStreams() []ProbeStream {
+	return r.StreamsOfType("audio")
+}
+
+// StreamSelector 在同类型的流中选择一个，零值表示第一个
+type StreamSelector struct {
+	Index    int    // 候选流中的序号（从 0 开始）
+	Language string // 语言标签（如 "eng"），设置后只在该语言的流中按 Index 选择
+}
+
+// Select 从同类型的流中选择，没有匹配时返回 ErrStreamNotFound
+func (s StreamSelector) Select(streams []ProbeStream) (*ProbeStream, error) {
+	n := 0
+	for i := range streams {
+		if s.Language != "" && streams[i].Language() != s.Language {
+			continue
+		}
+		if n == s.Index {
+			return &streams[i], nil
+		}
+		n++
+	}
+	if s.Language != "" {
+		return nil, fmt.Errorf("%w: 语言 %s 的第 %d 个流", ErrStreamNotFound, s.Language, s.Index)
+	}
+	return nil, fmt.Errorf("%w: 第 %d 个流", ErrStreamNotFound, s.Index)
+}
+
+// mapArg 返回选择指定流的 -map 参数值
+func mapArg(stream int) string {
+	return fmt.Sprintf("0:%d", stream)
+}
+
+// Probe 使用 ffprobe 读取文件的完整媒体信息
+func Probe(filename string) (*ProbeResult, error) {
+	return ProbeContext(context.Background(), filename, nil)
+}
+
+// ProbeContext 使用指定的进程管理器读取媒体信息，processMgr 为 nil 时临时创建
+func ProbeContext(ctx context.Context, filename string, processMgr *ProcessManager) (*ProbeResult, error) {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil, fmt.Errorf("文件不存在: %s", filename)
+	}
+
+	if processMgr == nil {
+		processMgr = NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	output, err := processMgr.Output(ctx, "ffprobe", probeArgs(filename))
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe 执行失败: %w", err)
+	}
+	return parseProbe(output)
+}
+
+// ProbeCommand 返回 Probe 将执行的 ffprobe 命令（不执行）
+func ProbeCommand(filename string) Command {
+	return Command{Name: "ffprobe", Args: probeArgs(filename)}
+}
+
+// probeArgs 构建完整探测的 ffprobe 参数
+func probeArgs(filename string) []string {
+	return []string{
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		"-show_chapters",
+		"-i", filename,
+	}
+}
+
+// rawProbe ffprobe JSON 输出，数值字段多以字符串表示
+type rawProbe struct {
+	Format struct {
+		Filename       string            `json:"filename"`
+		NbStreams      int               `json:"nb_streams"`
+		NbPrograms     int               `json:"nb_programs"`
+		FormatName     string            `json:"format_name"`
+		FormatLongName string            `json:"format_long_name"`
+		StartTime      string            `json:"start_time"`
+		Duration       string            `json:"duration"`
+		Size           string            `json:"size"`
+		BitRate        string            `json:"bit_rate"`
+		ProbeScore     int               `json:"probe_score"`
+		Tags           map[string]string `json:"tags"`
+	} `json:"format"`
+	Streams []struct {
+		Index              int               `json:"index"`
+		CodecName          string            `json:"codec_name"`
+		CodecLongName      string            `json:"codec_long_name"`
+		Profile            string            `json:"profile"`
+		CodecType          string            `json:"codec_type"`
+		CodecTagString     string            `json:"codec_tag_string"`
+		Width              int               `json:"width"`
+		Height             int               `json:"height"`
+		SampleAspectRatio  string            `json:"sample_aspect_ratio"`
+		DisplayAspectRatio string            `json:"display_aspect_ratio"`
+		PixFmt             string            `json:"pix_fmt"`
+		Level              int               `json:"level"`
+		ColorRange         string            `json:"color_range"`
+		ColorSpace         string            `json:"color_space"`
+		ColorTransfer      string            `json:"color_transfer"`
+		ColorPrimaries     string            `json:"color_primaries"`
+		ChromaLocation     string            `json:"chroma_location"`
+		FieldOrder         string            `json:"field_order"`
+		SampleFmt          string            `json:"sample_fmt"`
+		SampleRate         string            `json:"sample_rate"`
+		Channels           int               `json:"channels"`
+		ChannelLayout      string            `json:"channel_layout"`
+		BitsPerSample      int               `json:"bits_per_sample"`
+		RFrameRate         string            `json:"r_frame_rate"`
+		AvgFrameRate       string            `json:"avg_frame_rate"`
+		TimeBase           string            `json:"time_base"`
+		StartTime          string            `json:"start_time"`
+		Duration           string            `json:"duration"`
+		BitRate            string            `json:"bit_rate"`
+		BitsPerRawSample   string            `json:"bits_per_raw_sample"`
+		NbFrames           string            `json:"nb_frames"`
+		Disposition        map[string]int    `json:"disposition"`
+		Tags               map[string]string `json:"tags"`
+	} `json:"streams"`
+	Chapters []struct {
+		ID        int64             `json:"id"`
+		TimeBase  string            `json:"time_base"`
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+}
+
+// parseProbe 将 ffprobe JSON 转换为 ProbeResult
+func parseProbe(output []byte) (*ProbeResult, error) {
+	var raw rawProbe
+	if err := json.Unmarshal(output, &raw); err != nil {
+		return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+
+	result := &ProbeResult{
+		Format: ProbeFormat{
+			Filename:       raw.Format.Filename,
+			FormatName:     raw.Format.FormatName,
+			FormatLongName: raw.Format.FormatLongName,
+			StartTime:      parseFloat(raw.Format.StartTime),
+			Duration:       parseFloat(raw.Format.Duration),
+			Size:           parseInt(raw.Format.Size),
+			BitRate:        parseInt(raw.Format.BitRate),
+			NumStreams:     raw.Format.NbStreams,
+			NumPrograms:    raw.Format.NbPrograms,
+			ProbeScore:     raw.Format.ProbeScore,
+			Tags:           raw.Format.Tags,
+		},
+	}
+
+	for _, s := range raw.Streams {
+		stream := ProbeStream{
+			Index:              s.Index,
+			CodecType:          s.CodecType,
+			CodecName:          s.CodecName,
+			CodecLongName:      s.CodecLongName,
+			CodecTag:           s.CodecTagString,
+			Profile:            s.Profile,
+			TimeBase:           s.TimeBase,
+			StartTime:          parseFloat(s.StartTime),
+			Duration:           parseFloat(s.Duration),
+			BitRate:            parseInt(s.BitRate),
+			NumFrames:          parseInt(s.NbFrames),
+			Width:              s.Width,
+			Height:             s.Height,
+			PixelFormat:        s.PixFmt,
+			BitDepth:           int(parseInt(s.BitsPerRawSample)),
+			Level:              s.Level,
+			FieldOrder:         s.FieldOrder,
+			SampleAspectRatio:  s.SampleAspectRatio,
+			DisplayAspectRatio: s.DisplayAspectRatio,
+			Color: ColorInfo{
+				Range:          s.ColorRange,
+				Space:          s.ColorSpace,
+				Transfer:       s.ColorTransfer,
+				Primaries:      s.ColorPrimaries,
+				ChromaLocation: s.ChromaLocation,
+			},
+			SampleFormat:  s.SampleFmt,
+			SampleRate:    int(parseInt(s.SampleRate)),
+			Channels:      s.Channels,
+			ChannelLayout: s.ChannelLayout,
+			BitsPerSample: s.BitsPerSample,
+			Disposition:   parseDisposition(s.Disposition),
+			Tags:          s.Tags,
+		}
+		// 音频流的帧率为 0/0，解析失败时保持零值
+		stream.FrameRate, _ = ParseRational(s.RFrameRate)
+		stream.AvgFrameRate, _ = ParseRational(s.AvgFrameRate)
+		result.Streams = append(result.Streams, stream)
+	}
+
+	for _, c := range raw.Chapters {
+		result.Chapters = append(result.Chapters, ProbeChapter{
+			ID:        c.ID,
+			TimeBase:  c.TimeBase,
+			StartTime: parseFloat(c.StartTime),
+			EndTime:   parseFloat(c.EndTime),
+			Tags:      c.Tags,
+		})
+	}
+
+	return result, nil
+}
+
+// parseDisposition 将 ffprobe 的 0/1 标记转换为 Disposition
+func parseDisposition(flags map[string]int) Disposition {
+	return Disposition{
+		Default:         flags["default"] != 0,
+		Dub:             flags["dub"] != 0,
+		Original:        flags["original"] != 0,
+		Comment:         flags["comment"] != 0,
+		Lyrics:          flags["lyrics"] != 0,
+		Karaoke:         flags["karaoke"] != 0,
+		Forced:          flags["forced"] != 0,
+		HearingImpaired: flags["hearing_impaired"] != 0,
+		VisualImpaired:  flags["visual_impaired"] != 0,
+		CleanEffects:    flags["clean_effects"] != 0,
+		AttachedPic:     flags["attached_pic"] != 0,
+		TimedThumbnails: flags["timed_thumbnails"] != 0,
+		Captions:        flags["captions"] != 0,
+		Descriptions:    flags["descriptions"] != 0,
+		Metadata:        flags["metadata"] != 0,
+	}
+}
+
+// parseFloat 解析 ffprobe 的数值字符串，缺失或 "N/A" 时返回 0
+func parseFloat(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// parseInt 解析 ffprobe 的整数字符串，缺失或 "N/A" 时返回 0
+func parseInt(s string) int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// highDepthPixelFormat 匹配 yuv420p10le、gray12be、p010le 等带位深后缀的像素格式
+var highDepthPixelFormat = regexp.MustCompile(`(?:p|gray|^p0)(9|10|12|14|16)(?:le|be)?$`)
+
+// pixelFormatBitDepth 由像素格式推断每个分量的位深，未知格式返回 0
+func pixelFormatBitDepth(pixFmt string) int {
+	if pixFmt == "" {
+		return 0
+	}
+	if m := highDepthPixelFormat.FindStringSubmatch(pixFmt); m != nil {
+		depth, _ := strconv.Atoi(m[1])
+		return depth
+	}
+	switch {
+	case strings.HasPrefix(pixFmt, "rgb48"), strings.HasPrefix(pixFmt, "bgr48"),
+		strings.HasPrefix(pixFmt, "rgba64"), strings.HasPrefix(pixFmt, "bgra64"):
+		return 16
+	case strings.HasSuffix(pixFmt, "f32le"), strings.HasSuffix(pixFmt, "f32be"):
+		return 32
+	}
+	return 8
+}
+
+// sampleFormatBitDepth 由音频采样格式（如 s16、fltp）推断位深，未知格式返回 0
+func sampleFormatBitDepth(sampleFmt string) int {
+	switch strings.TrimSuffix(sampleFmt, "p") {
+	case "u8":
+		return 8
+	case "s16":
+		return 16
+	case "s32", "flt":
+		return 32
+	case "s64", "dbl":
+		return 64
+	}
+	return 0
+}
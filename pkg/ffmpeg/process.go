@@ -3,11 +3,14 @@ package ffmpeg
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"sync"
 	"syscall"
 	"time"
+
+	"moviepy-go/pkg/leakcheck"
 )
 
 // ProcessManager 管理 FFmpeg 进程，防止僵尸进程
@@ -16,6 +19,65 @@ type ProcessManager struct {
 	mutex     sync.RWMutex
 	ctx       context.Context
 	cancel    context.CancelFunc
+	options   ProcessManagerOptions
+	leak      *leakcheck.Guard
+	slots     chan struct{} // 并发进程限制，nil 表示不限
+	temp      *TempManager  // 中间文件，随管理器关闭删除
+
+	totalSpawned int64
+	totalExited  int64
+	totalFailed  int64
+	totalTimeout int64
+}
+
+// ProcessManagerOptions 进程管理器选项
+type ProcessManagerOptions struct {
+	// MaxProcesses 最大并发进程数，达到上限时 StartProcess 阻塞等待，0 表示不限。
+	// 写入器在整个写入期间占用一个名额，读取每帧也需要一个名额，因此渲染时至少需要 2。
+	MaxProcesses int
+	// ProcessTimeout 单个进程的最长运行时间，超时后被终止，0 表示不限
+	ProcessTimeout time.Duration
+	// SampleInterval CPU/RSS 采样间隔，0 表示不采样
+	SampleInterval time.Duration
+	// FFmpegPath ffmpeg 可执行文件路径，默认从 PATH 查找
+	FFmpegPath string
+	// FFprobePath ffprobe 可执行文件路径，默认从 PATH 查找
+	FFprobePath string
+	// TempDir 中间文件目录，默认 TempDir()
+	TempDir string
+	// TempQuota 中间文件总大小上限（字节），0 表示不限
+	TempQuota int64
+}
+
+// ProcessUsage 进程资源使用情况
+type ProcessUsage struct {
+	CPUTime    time.Duration // 累计 CPU 时间（用户态 + 内核态）
+	RSS        int64         // 当前常驻内存（字节）
+	PeakRSS    int64         // 采样到的峰值常驻内存（字节）
+	SampledAt  time.Time     // 最近一次采样时间
+	CPUPercent float64       // 最近两次采样间的 CPU 占用率，100 表示一个核心
+}
+
+// ProcessInfo 进程快照
+type ProcessInfo struct {
+	PID       int
+	Args      []string
+	StartTime time.Time
+	Age       time.Duration
+	Usage     ProcessUsage
+}
+
+// ProcessStats 进程管理器统计快照
+type ProcessStats struct {
+	Running      int
+	TotalSpawned int64
+	TotalExited  int64
+	TotalFailed  int64
+	TotalTimeout int64
+	TotalCPUTime time.Duration
+	TotalRSS     int64
+	Oldest       *ProcessInfo
+	Processes    []ProcessInfo
 }
 
 // ManagedProcess 被管理的进程
@@ -25,28 +87,120 @@ type ManagedProcess struct {
 	startTime time.Time
 	ctx       context.Context
 	cancel    context.CancelFunc
-	done      chan error
-	cleanup   func()
+	done      chan struct{} // 进程退出后关闭
+	err       error         // 进程退出状态，done 关闭后有效
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser
+
+	usageMutex sync.RWMutex
+	usage      ProcessUsage
+
+	cleanupMutex sync.Mutex // 保护 cleanup 和 cleaned，SetCleanup 可与监控协程并发
+	cleanup      func()
+	cleaned      bool // 监控协程已取走清理函数，之后设置的清理函数立即执行
+}
+
+// managers 所有未关闭的进程管理器，供 TerminateAll 使用
+var managers = struct {
+	mutex sync.Mutex
+	live  map[*ProcessManager]struct{}
+}{live: make(map[*ProcessManager]struct{})}
+
+// TerminateAll 终止所有未关闭的进程管理器中正在运行的进程，返回终止的进程数；管理器本身仍可继续使用
+func TerminateAll() int {
+	managers.mutex.Lock()
+	live := make([]*ProcessManager, 0, len(managers.live))
+	for pm := range managers.live {
+		live = append(live, pm)
+	}
+	managers.mutex.Unlock()
+
+	count := 0
+	for _, pm := range live {
+		count += pm.GetProcessCount()
+		pm.KillAllProcesses()
+	}
+	return count
 }
 
 // NewProcessManager 创建新的进程管理器
 func NewProcessManager() *ProcessManager {
+	return NewProcessManagerWithOptions(nil)
+}
+
+// NewProcessManagerWithOptions 使用指定选项创建进程管理器
+func NewProcessManagerWithOptions(options *ProcessManagerOptions) *ProcessManager {
+	defaults := DefaultProcessManagerOptions()
+	if options == nil {
+		options = &defaults
+	}
+	if options.FFmpegPath == "" {
+		options.FFmpegPath = defaults.FFmpegPath
+	}
+	if options.FFprobePath == "" {
+		options.FFprobePath = defaults.FFprobePath
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	pm := &ProcessManager{
 		processes: make(map[int]*ManagedProcess),
 		ctx:       ctx,
 		cancel:    cancel,
+		options:   *options,
+		temp:      NewTempManager(&TempManagerOptions{Dir: options.TempDir, Quota: options.TempQuota}),
 	}
+	if options.MaxProcesses > 0 {
+		pm.slots = make(chan struct{}, options.MaxProcesses)
+	}
+	pm.leak = leakcheck.Track(pm, "ProcessManager", "")
+	managers.mutex.Lock()
+	managers.live[pm] = struct{}{}
+	managers.mutex.Unlock()
 
 	// 启动清理协程
 	go pm.cleanupRoutine()
 
+	// 启动资源采样协程
+	if options.SampleInterval > 0 {
+		go pm.sampleRoutine(options.SampleInterval)
+	}
+
 	return pm
 }
 
+// ProcessPipes 进程标准输入输出配置
+type ProcessPipes struct {
+	Stdin  bool      // 创建 stdin 管道，通过 ManagedProcess.Stdin 写入
+	Stdout bool      // 创建 stdout 管道，通过 ManagedProcess.Stdout 读取
+	Stderr io.Writer // stderr 输出目标，nil 表示丢弃
+}
+
 // StartProcess 启动一个受管理的 FFmpeg 进程
+//
+// 设置了 MaxProcesses 时，达到上限后阻塞直到有进程退出或 ctx 被取消。
 func (pm *ProcessManager) StartProcess(ctx context.Context, name string, args []string, env []string) (*ManagedProcess, error) {
-	cmd := exec.CommandContext(ctx, name, args...)
+	return pm.StartProcessWithPipes(ctx, name, args, env, nil)
+}
+
+// StartProcessWithPipes 启动一个受管理的进程并按需创建标准输入输出管道
+func (pm *ProcessManager) StartProcessWithPipes(ctx context.Context, name string, args []string, env []string, pipes *ProcessPipes) (*ManagedProcess, error) {
+	if pipes == nil {
+		pipes = &ProcessPipes{}
+	}
+	if err := pm.acquireSlot(ctx); err != nil {
+		return nil, err
+	}
+
+	// 创建进程上下文，Terminate 和超时都通过它终止进程
+	var procCtx context.Context
+	var cancel context.CancelFunc
+	if pm.options.ProcessTimeout > 0 {
+		procCtx, cancel = context.WithTimeout(ctx, pm.options.ProcessTimeout)
+	} else {
+		procCtx, cancel = context.WithCancel(ctx)
+	}
+
+	cmd := exec.CommandContext(procCtx, pm.options.binary(name), args...)
 	cmd.Env = append(os.Environ(), env...)
 
 	// 设置进程组，便于管理
@@ -54,49 +208,119 @@ func (pm *ProcessManager) StartProcess(ctx context.Context, name string, args []
 		Setpgid: true,
 	}
 
-	// 创建进程上下文
-	procCtx, cancel := context.WithCancel(ctx)
-
 	mp := &ManagedProcess{
 		cmd:       cmd,
 		startTime: time.Now(),
 		ctx:       procCtx,
 		cancel:    cancel,
-		done:      make(chan error, 1),
+		done:      make(chan struct{}),
+	}
+
+	cmd.Stderr = pipes.Stderr
+
+	if pipes.Stdin {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			cancel()
+			pm.releaseSlot()
+			return nil, fmt.Errorf("设置输入管道失败: %w", err)
+		}
+		mp.stdin = stdin
+	}
+
+	// stdout 使用独立的 os.Pipe：cmd.StdoutPipe 会在 Wait 时关闭读端，
+	// 而监控协程会立即调用 Wait，可能丢失尚未读取的数据
+	var stdoutWriter *os.File
+	if pipes.Stdout {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			cancel()
+			pm.releaseSlot()
+			return nil, fmt.Errorf("设置输出管道失败: %w", err)
+		}
+		cmd.Stdout = writer
+		mp.stdout = reader
+		stdoutWriter = writer
 	}
 
 	// 启动进程
 	if err := cmd.Start(); err != nil {
 		cancel()
+		pm.releaseSlot()
+		if mp.stdout != nil {
+			mp.stdout.Close()
+			stdoutWriter.Close()
+		}
 		return nil, fmt.Errorf("启动进程失败: %w", err)
 	}
 
+	// 子进程已继承写端，父进程关闭自己的副本以便读到 EOF
+	if stdoutWriter != nil {
+		stdoutWriter.Close()
+	}
+
 	mp.pid = cmd.Process.Pid
 
 	// 注册进程
 	pm.mutex.Lock()
 	pm.processes[mp.pid] = mp
+	pm.totalSpawned++
 	pm.mutex.Unlock()
 
 	// 监控进程结束
 	go func() {
 		err := cmd.Wait()
-		mp.done <- err
+		// 调用方的 ctx 到期（如读取器的 Timeout）不计为 ProcessTimeout 超时，由调用方报告
+		timedOut := procCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		if timedOut {
+			err = fmt.Errorf("进程运行超过 %v 被终止: %w", pm.options.ProcessTimeout, err)
+		}
+		mp.exit(err)
+		cancel()
 
 		// 从管理器中移除
 		pm.mutex.Lock()
 		delete(pm.processes, mp.pid)
+		pm.totalExited++
+		if err != nil {
+			pm.totalFailed++
+		}
+		if timedOut {
+			pm.totalTimeout++
+		}
 		pm.mutex.Unlock()
+		pm.releaseSlot()
 
-		// 执行清理
-		if mp.cleanup != nil {
-			mp.cleanup()
-		}
+		mp.runCleanup()
 	}()
 
 	return mp, nil
 }
 
+// Output 运行受管理的进程并返回其标准输出，相当于 exec.Cmd.Output
+//
+// 失败时根据 stderr 内容返回分类错误（见 Error）。
+func (pm *ProcessManager) Output(ctx context.Context, name string, args []string) ([]byte, error) {
+	stderr := newTailWriter()
+	process, err := pm.StartProcessWithPipes(ctx, name, args, nil, &ProcessPipes{Stdout: true, Stderr: stderr})
+	if err != nil {
+		return nil, err
+	}
+
+	output, readErr := io.ReadAll(process.Stdout())
+	process.Stdout().Close()
+	if readErr != nil {
+		process.Terminate()
+		process.Wait()
+		return nil, fmt.Errorf("读取进程输出失败: %w", readErr)
+	}
+
+	if err := process.Wait(); err != nil {
+		return nil, classify(err, stderr.Tail())
+	}
+	return output, nil
+}
+
 // TerminateProcess 终止进程
 func (pm *ProcessManager) TerminateProcess(pid int) error {
 	pm.mutex.RLock()
@@ -155,6 +379,89 @@ func (pm *ProcessManager) GetProcessCount() int {
 	return len(pm.processes)
 }
 
+// acquireSlot 获取一个并发进程名额
+func (pm *ProcessManager) acquireSlot(ctx context.Context) error {
+	if pm.slots == nil {
+		return nil
+	}
+	select {
+	case pm.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待进程名额时上下文已取消: %w", ctx.Err())
+	case <-pm.ctx.Done():
+		return fmt.Errorf("进程管理器已关闭")
+	}
+}
+
+// releaseSlot 释放并发进程名额
+func (pm *ProcessManager) releaseSlot() {
+	if pm.slots != nil {
+		<-pm.slots
+	}
+}
+
+// Stats 返回进程管理器统计快照
+func (pm *ProcessManager) Stats() ProcessStats {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	now := time.Now()
+	stats := ProcessStats{
+		Running:      len(pm.processes),
+		TotalSpawned: pm.totalSpawned,
+		TotalExited:  pm.totalExited,
+		TotalFailed:  pm.totalFailed,
+		TotalTimeout: pm.totalTimeout,
+		Processes:    make([]ProcessInfo, 0, len(pm.processes)),
+	}
+
+	for _, mp := range pm.processes {
+		info := ProcessInfo{
+			PID:       mp.pid,
+			Args:      mp.cmd.Args,
+			StartTime: mp.startTime,
+			Age:       now.Sub(mp.startTime),
+			Usage:     mp.Usage(),
+		}
+		stats.TotalCPUTime += info.Usage.CPUTime
+		stats.TotalRSS += info.Usage.RSS
+		stats.Processes = append(stats.Processes, info)
+	}
+
+	for i := range stats.Processes {
+		if stats.Oldest == nil || stats.Processes[i].StartTime.Before(stats.Oldest.StartTime) {
+			stats.Oldest = &stats.Processes[i]
+		}
+	}
+
+	return stats
+}
+
+// sampleRoutine 定期采样所有进程的 CPU 和内存
+func (pm *ProcessManager) sampleRoutine(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pm.ctx.Done():
+			return
+		case <-ticker.C:
+			pm.mutex.RLock()
+			processes := make([]*ManagedProcess, 0, len(pm.processes))
+			for _, mp := range pm.processes {
+				processes = append(processes, mp)
+			}
+			pm.mutex.RUnlock()
+
+			for _, mp := range processes {
+				mp.sample()
+			}
+		}
+	}
+}
+
 // cleanupRoutine 定期清理僵尸进程
 func (pm *ProcessManager) cleanupRoutine() {
 	ticker := time.NewTicker(30 * time.Second)
@@ -181,9 +488,9 @@ func (pm *ProcessManager) cleanupZombies() {
 
 	for _, mp := range processes {
 		select {
-		case err := <-mp.done:
-			if err != nil {
-				fmt.Printf("进程 %d 异常退出: %v\n", mp.pid, err)
+		case <-mp.done:
+			if mp.err != nil {
+				fmt.Printf("进程 %d 异常退出: %v\n", mp.pid, mp.err)
 			}
 		default:
 			// 进程仍在运行
@@ -193,9 +500,19 @@ func (pm *ProcessManager) cleanupZombies() {
 
 // Close 关闭进程管理器
 func (pm *ProcessManager) Close() error {
+	pm.leak.Close()
+	managers.mutex.Lock()
+	delete(managers.live, pm)
+	managers.mutex.Unlock()
 	pm.cancel()
 	pm.KillAllProcesses()
-	return nil
+	// 进程全部退出后才删除它们可能仍在写入的中间文件
+	return pm.temp.Close()
+}
+
+// Temp 返回管理器的临时文件管理器，Close 时删除其中所有文件
+func (pm *ProcessManager) Temp() *TempManager {
+	return pm.temp
 }
 
 // ManagedProcess 方法
@@ -205,9 +522,16 @@ func (mp *ManagedProcess) PID() int {
 	return mp.pid
 }
 
-// Wait 等待进程结束
+// Wait 等待进程结束，可重复调用
 func (mp *ManagedProcess) Wait() error {
-	return <-mp.done
+	<-mp.done
+	return mp.err
+}
+
+// exit 记录退出状态并通知所有等待者
+func (mp *ManagedProcess) exit(err error) {
+	mp.err = err
+	close(mp.done)
 }
 
 // Terminate 终止进程
@@ -226,7 +550,74 @@ func (mp *ManagedProcess) IsRunning() bool {
 	}
 }
 
-// SetCleanup 设置清理函数
+// Stdin 返回 stdin 管道，未请求时为 nil
+func (mp *ManagedProcess) Stdin() io.WriteCloser {
+	return mp.stdin
+}
+
+// Stdout 返回 stdout 管道，未请求时为 nil；读取完毕后由调用者关闭
+func (mp *ManagedProcess) Stdout() io.ReadCloser {
+	return mp.stdout
+}
+
+// StartTime 返回进程启动时间
+func (mp *ManagedProcess) StartTime() time.Time {
+	return mp.startTime
+}
+
+// Usage 返回最近一次采样的资源使用情况
+func (mp *ManagedProcess) Usage() ProcessUsage {
+	mp.usageMutex.RLock()
+	defer mp.usageMutex.RUnlock()
+	return mp.usage
+}
+
+// sample 采样一次进程资源使用
+func (mp *ManagedProcess) sample() {
+	cpuTime, rss, err := readProcessUsage(mp.pid)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	mp.usageMutex.Lock()
+	defer mp.usageMutex.Unlock()
+
+	if !mp.usage.SampledAt.IsZero() {
+		elapsed := now.Sub(mp.usage.SampledAt)
+		if elapsed > 0 {
+			mp.usage.CPUPercent = float64(cpuTime-mp.usage.CPUTime) / float64(elapsed) * 100
+		}
+	}
+	mp.usage.CPUTime = cpuTime
+	mp.usage.RSS = rss
+	if rss > mp.usage.PeakRSS {
+		mp.usage.PeakRSS = rss
+	}
+	mp.usage.SampledAt = now
+}
+
+// SetCleanup 设置进程退出后执行的清理函数，可在进程运行期间随时调用；进程已退出并完成清理时立即执行
 func (mp *ManagedProcess) SetCleanup(cleanup func()) {
+	mp.cleanupMutex.Lock()
+	if mp.cleaned {
+		mp.cleanupMutex.Unlock()
+		if cleanup != nil {
+			cleanup()
+		}
+		return
+	}
 	mp.cleanup = cleanup
+	mp.cleanupMutex.Unlock()
+}
+
+// runCleanup 进程退出后由监控协程调用，执行已设置的清理函数
+func (mp *ManagedProcess) runCleanup() {
+	mp.cleanupMutex.Lock()
+	cleanup := mp.cleanup
+	mp.cleanup, mp.cleaned = nil, true
+	mp.cleanupMutex.Unlock()
+	if cleanup != nil {
+		cleanup()
+	}
 }
@@ -0,0 +1,47 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetCleanupRunsOnceWhenRacingExit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pm := NewProcessManagerWithOptions(&ProcessManagerOptions{FFmpegPath: path, FFprobePath: path})
+	defer pm.Close()
+
+	// 进程立即退出，SetCleanup 与监控协程的清理先后不定
+	for run := 0; run < 20; run++ {
+		process, err := pm.StartProcess(context.Background(), "ffmpeg", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run%2 == 1 {
+			process.Wait()
+			time.Sleep(time.Millisecond)
+		}
+		var calls atomic.Int32
+		done := make(chan struct{})
+		process.SetCleanup(func() {
+			if calls.Add(1) == 1 {
+				close(done)
+			}
+		})
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("第 %d 次：进程退出后清理函数未执行", run)
+		}
+		process.Wait()
+		if n := calls.Load(); n != 1 {
+			t.Fatalf("第 %d 次：清理函数执行了 %d 次", run, n)
+		}
+	}
+}
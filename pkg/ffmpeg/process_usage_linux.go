@@ -0,0 +1,50 @@
+//go:build linux
+
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks Linux 下 /proc/<pid>/stat 的时钟频率（USER_HZ）
+const clockTicks = 100
+
+// readProcessUsage 从 /proc 读取进程累计 CPU 时间和常驻内存
+func readProcessUsage(pid int) (time.Duration, int64, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// comm 字段可能包含空格，从最后一个 ')' 之后开始解析
+	content := string(stat)
+	end := strings.LastIndexByte(content, ')')
+	if end < 0 {
+		return 0, 0, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(content[end+1:])
+	// 去掉 pid 和 comm 后，utime/stime/rss 分别位于第 12、13、22 个字段
+	if len(fields) < 22 {
+		return 0, 0, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+
+	utime, err := strconv.ParseInt(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	stime, err := strconv.ParseInt(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	rssPages, err := strconv.ParseInt(fields[21], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	cpuTime := time.Duration(utime+stime) * time.Second / clockTicks
+	return cpuTime, rssPages * int64(os.Getpagesize()), nil
+}
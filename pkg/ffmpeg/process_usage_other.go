@@ -0,0 +1,13 @@
+//go:build !linux
+
+package ffmpeg
+
+import (
+	"fmt"
+	"time"
+)
+
+// readProcessUsage 非 Linux 平台暂不支持资源采样
+func readProcessUsage(pid int) (time.Duration, int64, error) {
+	return 0, 0, fmt.Errorf("当前平台不支持进程资源采样")
+}
@@ -0,0 +1,113 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Rational 以分数表示的帧率，如 NTSC 的 30000/1001
+type Rational struct {
+	Num int
+	Den int
+}
+
+// ntscNumerators 分母为 1001 的常见帧率分子
+var ntscNumerators = []int{24000, 30000, 48000, 60000, 120000}
+
+// IsZero 判断是否未设置
+func (r Rational) IsZero() bool {
+	return r.Num == 0 || r.Den == 0
+}
+
+// Float64 返回浮点近似值
+func (r Rational) Float64() float64 {
+	if r.Den == 0 {
+		return 0
+	}
+	return float64(r.Num) / float64(r.Den)
+}
+
+// String 返回 FFmpeg 接受的 num/den 形式
+func (r Rational) String() string {
+	if r.Den == 1 {
+		return strconv.Itoa(r.Num)
+	}
+	return fmt.Sprintf("%d/%d", r.Num, r.Den)
+}
+
+// Div 返回帧率除以 n 的结果，用于隔帧导出
+func (r Rational) Div(n int) Rational {
+	if n <= 1 {
+		return r
+	}
+	return reduce(r.Num, r.Den*n)
+}
+
+// ParseRational 解析 "30000/1001"、"25" 或 "29.97" 形式的帧率
+func ParseRational(s string) (Rational, error) {
+	s = strings.TrimSpace(s)
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(num))
+		if err != nil {
+			return Rational{}, fmt.Errorf("无效的帧率 %q: %w", s, err)
+		}
+		d, err := strconv.Atoi(strings.TrimSpace(den))
+		if err != nil {
+			return Rational{}, fmt.Errorf("无效的帧率 %q: %w", s, err)
+		}
+		if n <= 0 || d <= 0 {
+			return Rational{}, fmt.Errorf("无效的帧率 %q", s)
+		}
+		return reduce(n, d), nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Rational{}, fmt.Errorf("无效的帧率 %q: %w", s, err)
+	}
+	if f <= 0 {
+		return Rational{}, fmt.Errorf("无效的帧率 %q", s)
+	}
+	return RationalFromFloat(f), nil
+}
+
+// RationalFromFloat 将浮点帧率转换为分数，29.97 等近似值会还原为 30000/1001
+func RationalFromFloat(fps float64) Rational {
+	if fps <= 0 {
+		return Rational{}
+	}
+	if rounded := math.Round(fps); math.Abs(fps-rounded) < 1e-6 {
+		return Rational{Num: int(rounded), Den: 1}
+	}
+	for _, num := range ntscNumerators {
+		if math.Abs(fps-float64(num)/1001) < 1e-3 {
+			return Rational{Num: num, Den: 1001}
+		}
+	}
+	return reduce(int(math.Round(fps*1000)), 1000)
+}
+
+// ResolveFrameRate 优先解析 rate，为空时由 fps 换算
+func ResolveFrameRate(rate string, fps float64) (Rational, error) {
+	if rate != "" {
+		return ParseRational(rate)
+	}
+	if fps <= 0 {
+		return Rational{}, fmt.Errorf("无效的帧率: %f", fps)
+	}
+	return RationalFromFloat(fps), nil
+}
+
+// reduce 约分
+func reduce(num, den int) Rational {
+	a, b := num, den
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return Rational{Num: num, Den: den}
+	}
+	return Rational{Num: num / a, Den: den / a}
+}
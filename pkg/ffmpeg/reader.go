@@ -3,54 +3,106 @@ package ffmpeg
 import (
 	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"io"
+	"math"
 	"os"
-	"os/exec"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
+
+	"moviepy-go/pkg/leakcheck"
 )
 
 // VideoInfo 视频信息
 type VideoInfo struct {
-	Duration        float64 `json:"duration"`
-	Width           int     `json:"width"`
-	Height          int     `json:"height"`
-	FPS             float64 `json:"fps"`
-	BitRate         string  `json:"bit_rate"`
-	Codec           string  `json:"codec_name"`
-	HasAudio        bool    `json:"has_audio"`
-	AudioCodec      string  `json:"audio_codec"`
-	AudioSampleRate int     `json:"audio_sample_rate"`
-	AudioChannels   int     `json:"audio_channels"`
+	Duration        float64  `json:"duration"`
+	Width           int      `json:"width"`
+	Height          int      `json:"height"`
+	FPS             float64  `json:"fps"`
+	FrameRate       Rational `json:"frame_rate"` // 精确帧率，来自 r_frame_rate
+	BitRate         string   `json:"bit_rate"`
+	Codec           string   `json:"codec_name"`
+	HasAudio        bool     `json:"has_audio"`
+	AudioCodec      string   `json:"audio_codec"`
+	AudioSampleRate int      `json:"audio_sample_rate"`
+	AudioChannels   int      `json:"audio_channels"`
+	VideoStream     int      `json:"video_stream_index"` // 所选视频流在文件中的序号
+	AudioStream     int      `json:"audio_stream_index"` // 所选音频流在文件中的序号，无音频时为 -1
+	AudioLanguage   string   `json:"audio_language"`
+	Animated        bool     `json:"animated"` // GIF/APNG 动图，按 RGBA 解码以保留透明度
+
+	PixelFormat string    `json:"pix_fmt"`     // 源像素格式，如 yuv420p10le
+	BitDepth    int       `json:"bit_depth"`   // 每个分量的位深，见 ProbeStream.Depth
+	Color       ColorInfo `json:"color"`       // 色彩范围、矩阵、传输特性和原色
+	StartTime   float64   `json:"start_time"`  // 视频流首帧的时间戳（秒），缺失时取容器的起始时间
+	NumFrames   int64     `json:"nb_frames"`   // 容器记录的帧数（MP4/MOV 等），未记录时为 0
+	Profile     string    `json:"profile"`     // 编码档次，如 High、Main 10
+	Level       int       `json:"level"`       // 编码级别，ffprobe 的原始值（H.264 为 41 表示 4.1）
+	Format      string    `json:"format_name"` // 容器格式，如 "mov,mp4,m4a,3gp,3g2,mj2"
+}
+
+// animatedFormats 动图封装格式，帧间隔可变且 r_frame_rate 为时基而非实际帧率
+var animatedFormats = map[string]bool{
+	"gif":  true,
+	"apng": true,
 }
 
 // VideoReader FFmpeg 视频读取器
 type VideoReader struct {
-	filename   string
-	info       *VideoInfo
-	processMgr *ProcessManager
-	process    *ManagedProcess
-	ctx        context.Context
-	cancel     context.CancelFunc
-	closed     bool
-	mutex      sync.RWMutex
+	filename      string
+	info          *VideoInfo
+	processMgr    *ProcessManager
+	ctx           context.Context
+	cancel        context.CancelFunc
+	closed        bool
+	refs          int // 引用计数，降为 0 时关闭
+	mutex         sync.RWMutex
+	outWidth      int           // 解码输出宽度，0 表示原始分辨率
+	outHeight     int           // 解码输出高度，0 表示原始分辨率
+	tolerance     time.Duration // 时间戳越界容差，0 表示一帧
+	options       VideoReaderOptions
+	leak          *leakcheck.Guard
+	keyframes     []time.Duration // 关键帧时间缓存，见 Keyframes
+	keyframeMutex sync.Mutex      // 保护 keyframes，读取期间不占用 mutex
+}
+
+// VideoReaderOptions 视频读取器选项
+type VideoReaderOptions struct {
+	// VideoStream 选择视频流，默认第一个（封面图除外）
+	VideoStream StreamSelector
+	// AudioStream 选择 VideoInfo 中报告的音频流，默认第一个
+	AudioStream StreamSelector
+	// Timeout 单次 ffprobe/FFmpeg 调用（探测或取一帧）的最长时间，超时后终止进程，0 表示不限；
+	// 与 ProcessManagerOptions.ProcessTimeout 同时生效。超时错误满足 errors.Is(err, context.DeadlineExceeded)
+	Timeout time.Duration
 }
 
 // NewVideoReader 创建新的视频读取器
 func NewVideoReader(filename string, processMgr *ProcessManager) *VideoReader {
+	return NewVideoReaderWithOptions(filename, nil, processMgr)
+}
+
+// NewVideoReaderWithOptions 使用指定选项创建视频读取器
+func NewVideoReaderWithOptions(filename string, options *VideoReaderOptions, processMgr *ProcessManager) *VideoReader {
+	if options == nil {
+		options = &VideoReaderOptions{}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	return &VideoReader{
+	vr := &VideoReader{
 		filename:   filename,
 		processMgr: processMgr,
 		ctx:        ctx,
 		cancel:     cancel,
+		refs:       1,
+		options:    *options,
 	}
+	vr.leak = leakcheck.Track(vr, "VideoReader", filename)
+	return vr
 }
 
 // Open 打开视频文件并获取信息
@@ -68,98 +120,258 @@ func (vr *VideoReader) Open() error {
 	}
 
 	// 获取视频信息
-	info, err := vr.getVideoInfo()
+	ctx, cancel := callContext(context.Background(), vr.ctx, vr.options.Timeout)
+	defer cancel()
+	info, err := vr.getVideoInfo(ctx)
 	if err != nil {
-		return fmt.Errorf("获取视频信息失败: %w", err)
+		return fmt.Errorf("获取视频信息失败: %w", interrupted(ctx, "探测", err))
 	}
 
 	vr.info = info
 	return nil
 }
 
-// getVideoInfo 获取视频信息
-func (vr *VideoReader) getVideoInfo() (*VideoInfo, error) {
-	args := []string{
-		"-i", vr.filename,
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_format",
-		"-show_streams",
+// getVideoInfo 获取所选视频流和音频流的信息
+func (vr *VideoReader) getVideoInfo(ctx context.Context) (*VideoInfo, error) {
+	probe, err := ProbeContext(ctx, vr.filename, vr.processMgr)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &VideoInfo{
+		Duration:    probe.Format.Duration,
+		AudioStream: -1,
+		Format:      probe.Format.FormatName,
+	}
+	if probe.Format.BitRate > 0 {
+		info.BitRate = strconv.FormatInt(probe.Format.BitRate, 10)
 	}
 
-	cmd := exec.Command("ffprobe", args...)
-	output, err := cmd.Output()
+	// 解析视频流
+	videoStreams := probe.VideoStreams()
+	if len(videoStreams) == 0 {
+		return nil, fmt.Errorf("未找到视频流")
+	}
+	videoStream, err := vr.options.VideoStream.Select(videoStreams)
 	if err != nil {
-		return nil, fmt.Errorf("ffprobe 执行失败: %w", err)
+		return nil, err
+	}
+	info.VideoStream = videoStream.Index
+	info.Width = videoStream.Width
+	info.Height = videoStream.Height
+	info.Codec = videoStream.CodecName
+	info.FrameRate = videoStream.FrameRate
+	info.PixelFormat = videoStream.PixelFormat
+	info.BitDepth = videoStream.Depth()
+	info.Color = videoStream.Color
+	info.StartTime = videoStream.StartTime
+	if info.StartTime == 0 {
+		info.StartTime = probe.Format.StartTime
+	}
+	info.NumFrames = videoStream.NumFrames
+	info.Profile = videoStream.Profile
+	info.Level = videoStream.Level
+	info.Animated = animatedFormats[probe.Format.FormatName] || animatedFormats[videoStream.CodecName]
+	if info.Animated {
+		vr.applyAnimatedTiming(info, videoStream)
 	}
+	info.FPS = info.FrameRate.Float64()
+	vr.resolveVideoDuration(ctx, info, videoStream)
+
+	// 解析音频流，文件没有音频时不视为错误
+	if audioStreams := probe.AudioStreams(); len(audioStreams) > 0 {
+		audioStream, err := vr.options.AudioStream.Select(audioStreams)
+		if err != nil {
+			return nil, err
+		}
+		info.HasAudio = true
+		info.AudioStream = audioStream.Index
+		info.AudioLanguage = audioStream.Language()
+		info.AudioCodec = audioStream.CodecName
+		info.AudioChannels = audioStream.Channels
+		info.AudioSampleRate = audioStream.SampleRate
+	}
+
+	return info, nil
+}
 
-	var result struct {
-		Format struct {
-			Duration string `json:"duration"`
-			BitRate  string `json:"bit_rate"`
-		} `json:"format"`
-		Streams []struct {
-			CodecType  string `json:"codec_type"`
-			CodecName  string `json:"codec_name"`
-			Width      int    `json:"width"`
-			Height     int    `json:"height"`
-			RFrameRate string `json:"r_frame_rate"`
-			SampleRate string `json:"sample_rate"`
-			Channels   int    `json:"channels"`
-		} `json:"streams"`
+// applyAnimatedTiming 修正动图的帧率和时长
+//
+// GIF 的 r_frame_rate 是 1/100 秒的时基（100/1），实际平均帧率在 avg_frame_rate。
+// 按平均帧率采样时，-ss 定位仍落在各帧各自的显示区间内，帧间隔不等的动图也能正确播放；
+// 容器没有时长的动图由 resolveVideoDuration 按帧数推算。
+func (vr *VideoReader) applyAnimatedTiming(info *VideoInfo, stream *ProbeStream) {
+	if !stream.AvgFrameRate.IsZero() {
+		info.FrameRate = stream.AvgFrameRate
 	}
+}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+// pixelFormatLocked 返回解码输出的像素格式，动图使用 rgba，调用者需持有锁
+func (vr *VideoReader) pixelFormatLocked() PixelFormat {
+	if vr.info != nil && vr.info.Animated {
+		return PixelFormatRGBA
 	}
+	return PixelFormatRGB24
+}
 
-	info := &VideoInfo{}
+// frameArgs 构建读取单帧的 FFmpeg 参数，调用者需持有锁
+func (vr *VideoReader) frameArgs(timestamp float64, width, height int) []string {
+	args := []string{
+		"-ss", fmt.Sprintf("%.3f", timestamp),
+		"-i", vr.filename,
+		"-map", mapArg(vr.videoStreamLocked()),
+		"-vframes", "1",
+	}
+	if vr.info != nil && (width != vr.info.Width || height != vr.info.Height) {
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:%d", width, height))
+	}
+	return append(args,
+		"-f", "image2pipe",
+		"-pix_fmt", string(vr.pixelFormatLocked()),
+		"-vcodec", "rawvideo",
+		"-",
+	)
+}
 
-	// 解析时长
-	if result.Format.Duration != "" {
-		if duration, err := strconv.ParseFloat(result.Format.Duration, 64); err == nil {
-			info.Duration = duration
+// videoStreamLocked 返回所选视频流的序号，未打开时为 0，调用者需持有锁
+func (vr *VideoReader) videoStreamLocked() int {
+	if vr.info == nil {
+		return 0
+	}
+	return vr.info.VideoStream
+}
+
+// ProbeCommand 返回 Open 将执行的 ffprobe 命令（不执行）
+func (vr *VideoReader) ProbeCommand() Command {
+	return Command{Name: "ffprobe", Args: probeArgs(vr.filename)}
+}
+
+// FrameCommand 返回 GetFrame(t) 将执行的 FFmpeg 命令（不执行）
+func (vr *VideoReader) FrameCommand(t time.Duration) Command {
+	return vr.FrameCommandSize(t, -1, -1)
+}
+
+// FrameCommandSize 返回 GetFrameSize(ctx, t, width, height) 将执行的 FFmpeg 命令（不执行）
+func (vr *VideoReader) FrameCommandSize(t time.Duration, width, height int) Command {
+	vr.mutex.RLock()
+	defer vr.mutex.RUnlock()
+	width, height = vr.requestSizeLocked(width, height)
+	timestamp := t.Seconds()
+	if vr.info != nil {
+		if clamped, err := vr.clampTimestampLocked(t); err == nil {
+			timestamp = clamped
 		}
 	}
+	return Command{Name: "ffmpeg", Args: vr.frameArgs(timestamp, width, height)}
+}
 
-	info.BitRate = result.Format.BitRate
+// GetFrame 获取指定时间的帧，可被多个协程并发调用，每次调用启动独立的解码进程
+func (vr *VideoReader) GetFrame(t time.Duration) (image.Image, error) {
+	return vr.GetFrameContext(context.Background(), t)
+}
 
-	// 解析视频流
-	for _, stream := range result.Streams {
-		if stream.CodecType == "video" {
-			info.Width = stream.Width
-			info.Height = stream.Height
-			info.Codec = stream.CodecName
-
-			// 解析帧率
-			if stream.RFrameRate != "" {
-				parts := strings.Split(stream.RFrameRate, "/")
-				if len(parts) == 2 {
-					if num, err := strconv.ParseFloat(parts[0], 64); err == nil {
-						if den, err := strconv.ParseFloat(parts[1], 64); err == nil && den != 0 {
-							info.FPS = num / den
-						}
-					}
-				}
-			}
-		} else if stream.CodecType == "audio" {
-			info.HasAudio = true
-			info.AudioCodec = stream.CodecName
-			info.AudioChannels = stream.Channels
-
-			if stream.SampleRate != "" {
-				if sampleRate, err := strconv.Atoi(stream.SampleRate); err == nil {
-					info.AudioSampleRate = sampleRate
-				}
+// GetFrameContext 与 GetFrame 相同，ctx 取消或到期时终止解码进程；同时受 Options.Timeout 限制
+func (vr *VideoReader) GetFrameContext(ctx context.Context, t time.Duration) (image.Image, error) {
+	return vr.GetFrameSize(ctx, t, -1, -1)
+}
+
+// GetFrameSize 与 GetFrameContext 相同，但按 width×height 解码而不使用 SetOutputSize 设置的尺寸
+//
+// 0 表示原始分辨率、负数表示 SetOutputSize 设置的尺寸；共享读取器的剪辑可以各自按不同尺寸取帧。
+// 解码期间不持有锁，Close 会终止进行中的解码。
+func (vr *VideoReader) GetFrameSize(ctx context.Context, t time.Duration, width, height int) (image.Image, error) {
+	request, err := vr.frameRequest(t, width, height)
+	if err != nil {
+		return nil, err
+	}
+	width, height = request.width, request.height
+
+	ctx, cancel := callContext(ctx, vr.ctx, vr.options.Timeout)
+	defer cancel()
+	pixelData, err := vr.readFrame(ctx, request.args, request.format.FrameSize(width, height))
+	if errors.Is(err, io.EOF) && request.fallback != nil && ctx.Err() == nil {
+		// 末尾附近 -ss 可能落在最后一个数据包之后，回退两帧重试
+		pixelData, err = vr.readFrame(ctx, request.fallback, request.format.FrameSize(width, height))
+	}
+	if err != nil {
+		return nil, interrupted(ctx, fmt.Sprintf("读取 %v 处的帧", t), err)
+	}
+
+	// 创建图像
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if request.format == PixelFormatRGBA {
+		// FFmpeg 输出非预乘的 rgba，image.RGBA 需要预乘
+		copy(img.Pix, pixelData)
+		for i := 0; i < len(img.Pix); i += 4 {
+			if a := uint32(img.Pix[i+3]); a < 255 {
+				img.Pix[i] = uint8(uint32(img.Pix[i]) * a / 255)
+				img.Pix[i+1] = uint8(uint32(img.Pix[i+1]) * a / 255)
+				img.Pix[i+2] = uint8(uint32(img.Pix[i+2]) * a / 255)
 			}
 		}
+		return img, nil
 	}
 
-	return info, nil
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			idx := (y*width + x) * 3
+			r := pixelData[idx]
+			g := pixelData[idx+1]
+			b := pixelData[idx+2]
+			img.Set(x, y, color.RGBA{r, g, b, 255})
+		}
+	}
+
+	return img, nil
 }
 
-// GetFrame 获取指定时间的帧
-func (vr *VideoReader) GetFrame(t time.Duration) (image.Image, error) {
+// SetTimeTolerance 设置时间戳越界容差，超出 [0, Duration] 不多于该值的请求被钳制到首帧或末帧；
+// 0 表示一帧间隔
+func (vr *VideoReader) SetTimeTolerance(tolerance time.Duration) {
+	vr.mutex.Lock()
+	defer vr.mutex.Unlock()
+	vr.tolerance = tolerance
+}
+
+// fpsLocked 返回用于时间计算的帧率，调用者需持有锁
+func (vr *VideoReader) fpsLocked() float64 {
+	if vr.info.FPS > 0 {
+		return vr.info.FPS
+	}
+	return 25.0
+}
+
+// clampTimestampLocked 将 t 钳制到 [0, 最后一帧起始时间]，超出容差时返回错误，调用者需持有锁
+func (vr *VideoReader) clampTimestampLocked(t time.Duration) (float64, error) {
+	frame := 1 / vr.fpsLocked()
+	tolerance := vr.tolerance.Seconds()
+	if vr.tolerance == 0 {
+		tolerance = frame
+	}
+
+	timestamp := t.Seconds()
+	if timestamp < -tolerance {
+		return 0, fmt.Errorf("时间不能为负: %v", t)
+	}
+	if timestamp > vr.info.Duration+tolerance {
+		return 0, fmt.Errorf("时间超出视频长度: %v > %.3fs", t, vr.info.Duration)
+	}
+
+	last := math.Max(0, vr.info.Duration-frame)
+	return math.Min(math.Max(timestamp, 0), last), nil
+}
+
+// frameRequest 单次取帧在锁内确定的解码参数，解码本身不持有锁
+type frameRequest struct {
+	args     []string // 解码参数
+	fallback []string // 末尾回退两帧的解码参数，时间戳为 0 时为 nil
+	width    int
+	height   int
+	format   PixelFormat
+}
+
+// frameRequest 在读锁内钳制时间戳并确定解码尺寸和参数
+func (vr *VideoReader) frameRequest(t time.Duration, width, height int) (*frameRequest, error) {
 	vr.mutex.RLock()
 	defer vr.mutex.RUnlock()
 
@@ -171,67 +383,93 @@ func (vr *VideoReader) GetFrame(t time.Duration) (image.Image, error) {
 		return nil, fmt.Errorf("视频未打开")
 	}
 
-	// 计算时间戳
-	timestamp := t.Seconds()
-	if timestamp > vr.info.Duration {
-		return nil, fmt.Errorf("时间超出视频长度")
+	// 将时间戳限制在可解码范围内
+	timestamp, err := vr.clampTimestampLocked(t)
+	if err != nil {
+		return nil, err
 	}
 
-	// 启动 FFmpeg 进程读取帧
-	args := []string{
-		"-ss", fmt.Sprintf("%.3f", timestamp),
-		"-i", vr.filename,
-		"-vframes", "1",
-		"-f", "image2pipe",
-		"-pix_fmt", "rgb24",
-		"-vcodec", "rawvideo",
-		"-",
+	request := &frameRequest{format: vr.pixelFormatLocked()}
+	request.width, request.height = vr.requestSizeLocked(width, height)
+	request.args = vr.frameArgs(timestamp, request.width, request.height)
+	if timestamp > 0 {
+		fallback := math.Max(0, timestamp-2/vr.fpsLocked())
+		request.fallback = vr.frameArgs(fallback, request.width, request.height)
 	}
+	return request, nil
+}
 
-	// 创建命令
-	cmd := exec.CommandContext(vr.ctx, "ffmpeg", args...)
-
-	// 在启动进程之前设置输出管道
-	output, err := cmd.StdoutPipe()
+// readFrame 按 args 启动 FFmpeg 读取一帧 size 字节的原始像素数据
+func (vr *VideoReader) readFrame(ctx context.Context, args []string, size int) ([]byte, error) {
+	// 启动受管理的进程
+	stderr := newTailWriter()
+	process, err := vr.processMgr.StartProcessWithPipes(ctx, "ffmpeg", args, nil, &ProcessPipes{Stdout: true, Stderr: stderr})
 	if err != nil {
-		return nil, fmt.Errorf("设置输出管道失败: %w", err)
-	}
-
-	// 启动进程
-	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("启动 FFmpeg 失败: %w", err)
 	}
+	output := process.Stdout()
+	defer output.Close()
 
 	// 读取原始像素数据
 	reader := bufio.NewReader(output)
-	pixelData := make([]byte, vr.info.Width*vr.info.Height*3)
+	pixelData := make([]byte, size)
 
 	// 使用 io.ReadFull 确保读取完整的数据
 	_, err = io.ReadFull(reader, pixelData)
 	if err != nil {
-		cmd.Process.Kill()
-		return nil, fmt.Errorf("读取像素数据失败: %w", err)
+		process.Terminate()
+		process.Wait()
+		return nil, fmt.Errorf("读取像素数据失败: %w", classify(err, stderr.Tail()))
 	}
 
 	// 等待进程结束
-	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("FFmpeg 进程异常退出: %w", err)
+	if err := process.Wait(); err != nil {
+		return nil, fmt.Errorf("FFmpeg 进程异常退出: %w", classify(err, stderr.Tail()))
 	}
 
-	// 创建图像
-	img := image.NewRGBA(image.Rect(0, 0, vr.info.Width, vr.info.Height))
+	return pixelData, nil
+}
 
-	for y := 0; y < vr.info.Height; y++ {
-		for x := 0; x < vr.info.Width; x++ {
-			idx := (y*vr.info.Width + x) * 3
-			r := pixelData[idx]
-			g := pixelData[idx+1]
-			b := pixelData[idx+2]
-			img.Set(x, y, color.RGBA{r, g, b, 255})
-		}
+// SetOutputSize 设置解码输出尺寸，解码时通过 scale 滤镜缩放；传 0 恢复原始分辨率
+func (vr *VideoReader) SetOutputSize(width, height int) error {
+	if width < 0 || height < 0 {
+		return fmt.Errorf("无效的输出尺寸: %dx%d", width, height)
 	}
 
-	return img, nil
+	vr.mutex.Lock()
+	defer vr.mutex.Unlock()
+	vr.outWidth = width
+	vr.outHeight = height
+	return nil
+}
+
+// OutputSize 返回实际解码输出尺寸
+func (vr *VideoReader) OutputSize() (width, height int) {
+	vr.mutex.RLock()
+	defer vr.mutex.RUnlock()
+	return vr.outputSizeLocked()
+}
+
+// outputSizeLocked 返回输出尺寸，调用者需持有锁
+func (vr *VideoReader) outputSizeLocked() (int, int) {
+	if vr.info == nil {
+		return vr.outWidth, vr.outHeight
+	}
+	if vr.outWidth == 0 || vr.outHeight == 0 {
+		return vr.info.Width, vr.info.Height
+	}
+	return vr.outWidth, vr.outHeight
+}
+
+// requestSizeLocked 返回单次取帧的解码尺寸：负数取 SetOutputSize 设置的尺寸，0 取原始分辨率，调用者需持有锁
+func (vr *VideoReader) requestSizeLocked(width, height int) (int, int) {
+	if width < 0 || height < 0 {
+		return vr.outputSizeLocked()
+	}
+	if (width == 0 || height == 0) && vr.info != nil {
+		return vr.info.Width, vr.info.Height
+	}
+	return width, height
 }
 
 // GetInfo 获取视频信息
@@ -241,22 +479,49 @@ func (vr *VideoReader) GetInfo() *VideoInfo {
 	return vr.info
 }
 
-// Close 关闭读取器
-func (vr *VideoReader) Close() error {
+// Retain 增加引用计数，供共享同一读取器的剪辑使用，每次 Retain 需对应一次 Release
+func (vr *VideoReader) Retain() error {
 	vr.mutex.Lock()
 	defer vr.mutex.Unlock()
 
 	if vr.closed {
+		return fmt.Errorf("读取器已关闭")
+	}
+	vr.refs++
+	return nil
+}
+
+// Release 减少引用计数，最后一个引用释放时关闭读取器
+func (vr *VideoReader) Release() error {
+	vr.mutex.Lock()
+	if vr.closed {
+		vr.mutex.Unlock()
 		return nil
 	}
+	vr.refs--
+	last := vr.refs <= 0
+	vr.mutex.Unlock()
 
-	vr.closed = true
+	if last {
+		return vr.Close()
+	}
+	return nil
+}
+
+// Close 关闭读取器，忽略引用计数立即生效；进行中的探测和解码被终止
+func (vr *VideoReader) Close() error {
+	// 先取消再加锁，持有锁的 Open 探测也能被中断
 	vr.cancel()
 
-	if vr.process != nil {
-		vr.process.Terminate()
+	vr.mutex.Lock()
+	defer vr.mutex.Unlock()
+
+	if vr.closed {
+		return nil
 	}
 
+	vr.closed = true
+	vr.leak.Close()
 	return nil
 }
 
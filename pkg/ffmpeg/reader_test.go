@@ -0,0 +1,127 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClampTimestamp(t *testing.T) {
+	tests := []struct {
+		name      string
+		duration  float64
+		fps       float64
+		tolerance time.Duration
+		t         time.Duration
+		want      float64
+		wantErr   bool
+	}{
+		{"开头", 10, 25, 0, 0, 0, false},
+		{"中间", 10, 25, 0, 5 * time.Second, 5, false},
+		{"容差内的负值钳制到首帧", 10, 25, 0, -time.Millisecond, 0, false},
+		{"超出一帧容差的负值", 10, 25, 0, -50 * time.Millisecond, 0, true},
+		{"最后一帧起始时间", 10, 25, 0, 9960 * time.Millisecond, 9.96, false},
+		{"最后一帧内部钳制到起始时间", 10, 25, 0, 9990 * time.Millisecond, 9.96, false},
+		{"时长处钳制到最后一帧", 10, 25, 0, 10 * time.Second, 9.96, false},
+		{"容差内越过结尾", 10, 25, 0, 10030 * time.Millisecond, 9.96, false},
+		{"超出一帧容差", 10, 25, 0, 10050 * time.Millisecond, 0, true},
+		{"自定义容差", 10, 25, 200 * time.Millisecond, 10150 * time.Millisecond, 9.96, false},
+		{"自定义容差下越界", 10, 25, 200 * time.Millisecond, 10250 * time.Millisecond, 0, true},
+		{"小数时长", 3.337, 29.97, 0, 3337 * time.Millisecond, 3.337 - 1/29.97, false},
+		{"短于一帧的视频", 0.02, 25, 0, 10 * time.Millisecond, 0, false},
+		{"未知帧率按 25 计算", 10, 0, 0, 10 * time.Second, 9.96, false},
+	}
+	for _, tt := range tests {
+		vr := &VideoReader{info: &VideoInfo{Duration: tt.duration, FPS: tt.fps}, tolerance: tt.tolerance}
+		got, err := vr.clampTimestampLocked(tt.t)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: clamp(%v) = %g，期望错误", tt.name, tt.t, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: clamp(%v) 失败: %v", tt.name, tt.t, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: clamp(%v) = %g，期望 %g", tt.name, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestFrameCommandClampsTimestamp(t *testing.T) {
+	vr := &VideoReader{filename: "in.mp4", info: &VideoInfo{Duration: 10, FPS: 25, Width: 64, Height: 36}}
+	args := vr.FrameCommand(10 * time.Second).Args
+	for i, arg := range args {
+		if arg == "-ss" {
+			if args[i+1] != "9.960" {
+				t.Errorf("末尾取帧的 -ss = %s，期望 9.960", args[i+1])
+			}
+			return
+		}
+	}
+	t.Fatalf("取帧命令缺少 -ss: %v", args)
+}
+
+func TestCloseInterruptsFrameDecode(t *testing.T) {
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+	path := filepath.Join(dir, "ffmpeg")
+	// 模拟卡住的解码：标记启动后一直不输出
+	script := "#!/bin/sh\ntouch '" + started + "'\nexec sleep 30\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pm := NewProcessManagerWithOptions(&ProcessManagerOptions{FFmpegPath: path, FFprobePath: path})
+	defer pm.Close()
+
+	vr := NewVideoReader("in.mp4", pm)
+	vr.info = &VideoInfo{Duration: 10, FPS: 25, Width: 8, Height: 4}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := vr.GetFrame(time.Second)
+		result <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("解码进程未启动")
+		}
+	}
+
+	// 解码期间不持有锁，其他调用不被阻塞
+	if _, err := vr.GetFrameSize(context.Background(), 10*time.Hour, 0, 0); err == nil {
+		t.Fatal("越界取帧应立即返回错误")
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- vr.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("关闭失败: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close 被进行中的解码阻塞")
+	}
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("关闭后进行中的取帧应返回取消错误，实际 %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close 未终止进行中的解码")
+	}
+	if _, err := vr.GetFrame(time.Second); err == nil {
+		t.Fatal("关闭后取帧应返回错误")
+	}
+}
@@ -0,0 +1,238 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// TempManager 管理中间文件（两遍编码日志、调色板、分段等）
+//
+// 所有文件位于首次使用时创建的私有目录中，Close 时整体删除。
+// 每个 ProcessManager 持有一个（见 ProcessManager.Temp），随进程管理器一起关闭。
+type TempManager struct {
+	parent string
+	quota  int64
+	mutex  sync.Mutex
+	dir    string // 私有目录，首次使用时创建
+	closed bool
+}
+
+// TempManagerOptions 临时文件管理器选项
+type TempManagerOptions struct {
+	// Dir 私有目录的父目录，默认 TempDir()，仍为空时使用系统临时目录
+	Dir string
+	// Quota 目录内文件总大小上限（字节），超出后创建新文件返回 ErrTempQuotaExceeded，0 表示不限
+	Quota int64
+}
+
+// NewTempManager 创建临时文件管理器，目录在首次使用时才创建
+func NewTempManager(options *TempManagerOptions) *TempManager {
+	if options == nil {
+		options = &TempManagerOptions{}
+	}
+	if options.Dir == "" {
+		options.Dir = TempDir()
+	}
+	return &TempManager{parent: options.Dir, quota: options.Quota}
+}
+
+// Dir 返回私有目录，不存在时创建
+func (tm *TempManager) Dir() (string, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	return tm.dirLocked()
+}
+
+// dirLocked 返回私有目录，调用方需持有锁
+func (tm *TempManager) dirLocked() (string, error) {
+	if tm.closed {
+		return "", fmt.Errorf("临时文件管理器已关闭")
+	}
+	if tm.dir == "" {
+		dir, err := os.MkdirTemp(tm.parent, "moviego-")
+		if err != nil {
+			return "", fmt.Errorf("创建临时目录失败: %w", err)
+		}
+		tm.dir = dir
+	}
+	return tm.dir, nil
+}
+
+// CreateFile 按 os.CreateTemp 的 pattern 规则创建空文件并返回路径
+func (tm *TempManager) CreateFile(pattern string) (string, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	dir, err := tm.prepareLocked()
+	if err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	name := file.Name()
+	file.Close()
+	return name, nil
+}
+
+// CreateTextFile 创建临时文件并写入 text，返回路径
+//
+// 用于 drawtext 的 textfile 等参数：文字写入文件可避免滤镜参数的多层转义。
+func (tm *TempManager) CreateTextFile(pattern, text string) (string, error) {
+	name, err := tm.CreateFile(pattern)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(name, []byte(text), 0o600); err != nil {
+		tm.Remove(name)
+		return "", fmt.Errorf("写入文字失败: %w", err)
+	}
+	return name, nil
+}
+
+// CreateDir 按 os.MkdirTemp 的 pattern 规则创建子目录并返回路径
+func (tm *TempManager) CreateDir(pattern string) (string, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	dir, err := tm.prepareLocked()
+	if err != nil {
+		return "", err
+	}
+	sub, err := os.MkdirTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	return sub, nil
+}
+
+// Path 返回私有目录下 name 的路径但不创建文件，用于由 FFmpeg 自行创建的文件（如 -passlogfile 前缀）
+func (tm *TempManager) Path(name string) (string, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	dir, err := tm.prepareLocked()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// prepareLocked 确保目录存在并检查配额，调用方需持有锁
+func (tm *TempManager) prepareLocked() (string, error) {
+	dir, err := tm.dirLocked()
+	if err != nil {
+		return "", err
+	}
+	if err := tm.checkQuotaLocked(); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Remove 提前删除私有目录中的文件或目录
+func (tm *TempManager) Remove(path string) error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if tm.dir == "" || !tm.contains(path) {
+		return fmt.Errorf("不是临时文件管理器创建的路径: %s", path)
+	}
+	return os.RemoveAll(path)
+}
+
+// contains 判断 path 是否位于私有目录内
+func (tm *TempManager) contains(path string) bool {
+	rel, err := filepath.Rel(tm.dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Usage 返回私有目录内文件的总大小（字节）
+func (tm *TempManager) Usage() (int64, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	return tm.usageLocked()
+}
+
+// usageLocked 统计目录大小，调用方需持有锁
+func (tm *TempManager) usageLocked() (int64, error) {
+	if tm.dir == "" {
+		return 0, nil
+	}
+	var total int64
+	err := filepath.WalkDir(tm.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// 统计期间被 FFmpeg 删除的文件忽略即可
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return total, fmt.Errorf("统计临时文件大小失败: %w", err)
+	}
+	return total, nil
+}
+
+// CheckQuota 检查目录大小是否超出配额，长时间写入的调用方可在每个阶段结束后调用
+func (tm *TempManager) CheckQuota() error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	return tm.checkQuotaLocked()
+}
+
+// checkQuotaLocked 检查配额，调用方需持有锁
+func (tm *TempManager) checkQuotaLocked() error {
+	if tm.quota <= 0 {
+		return nil
+	}
+	usage, err := tm.usageLocked()
+	if err != nil {
+		return err
+	}
+	if usage >= tm.quota {
+		return fmt.Errorf("%w: 已使用 %d 字节，上限 %d 字节", ErrTempQuotaExceeded, usage, tm.quota)
+	}
+	return nil
+}
+
+// Close 删除私有目录及其中所有文件，可重复调用
+func (tm *TempManager) Close() error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tm.closed = true
+	if tm.dir == "" {
+		return nil
+	}
+	dir := tm.dir
+	tm.dir = ""
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("删除临时目录失败: %w", err)
+	}
+	return nil
+}
+
+// CleanupOnPanic 在 defer 中调用：发生 panic 时删除临时文件后继续 panic
+//
+//	tm := ffmpeg.NewTempManager(nil)
+//	defer tm.Close()
+//	defer tm.CleanupOnPanic()
+func (tm *TempManager) CleanupOnPanic() {
+	if r := recover(); r != nil {
+		tm.Close()
+		panic(r)
+	}
+}
@@ -0,0 +1,71 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// callContext 派生单次调用的上下文：调用方的 ctx 取消、owner（读取器自身的上下文，Close 时取消）
+// 取消或超过 timeout（0 表示不限）时结束，用完需调用返回的 cancel
+func callContext(ctx, owner context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	stop := context.AfterFunc(owner, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// interrupted 调用上下文已结束时把 err 包装为可用 errors.Is 判断的 context.DeadlineExceeded
+// 或 context.Canceled，否则原样返回
+func interrupted(ctx context.Context, what string, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s超时: %w", what, ctx.Err())
+	}
+	return fmt.Errorf("%s被取消: %w", what, ctx.Err())
+}
+
+// watchdog 写入器的看门狗：阻塞的管道写入超过时限时调用 cancel 终止 FFmpeg
+type watchdog struct {
+	timer   *time.Timer
+	timeout time.Duration
+	fired   atomic.Bool
+}
+
+// startWatchdog 启动看门狗，timeout 为 0 时返回 nil（nil 的方法均为空操作）
+func startWatchdog(timeout time.Duration, cancel context.CancelFunc) *watchdog {
+	if timeout <= 0 {
+		return nil
+	}
+	w := &watchdog{timeout: timeout}
+	w.timer = time.AfterFunc(timeout, func() {
+		w.fired.Store(true)
+		cancel()
+	})
+	return w
+}
+
+// stop 停止计时，已超时时把 err 包装为 context.DeadlineExceeded
+func (w *watchdog) stop(what string, err error) error {
+	if w == nil {
+		return err
+	}
+	w.timer.Stop()
+	if !w.fired.Load() {
+		return err
+	}
+	if err == nil {
+		err = fmt.Errorf("FFmpeg 已被终止")
+	}
+	return fmt.Errorf("%s超过 %v: %w: %w", what, w.timeout, context.DeadlineExceeded, err)
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"log"
 	"os"
-	"os/exec"
 	"strconv"
 	"sync"
 	"time"
+
+	"moviepy-go/pkg/leakcheck"
 )
 
 // VideoWriter FFmpeg 视频写入器
@@ -17,9 +19,12 @@ type VideoWriter struct {
 	filename   string
 	width      int
 	height     int
-	fps        float64
+	fps        Rational
 	codec      string
 	bitrate    string
+	preset     string
+	crf        int
+	threads    int
 	processMgr *ProcessManager
 	process    *ManagedProcess
 	ctx        context.Context
@@ -27,6 +32,18 @@ type VideoWriter struct {
 	closed     bool
 	mutex      sync.RWMutex
 	stdin      io.WriteCloser
+	direct     bool // 直接写入目标文件
+	logLevel   LogLevel
+	logger     *log.Logger
+	stderr     *logWriter    // 捕获的 FFmpeg stderr
+	tempFile   string        // 原子写入时使用的临时文件
+	pixFmt     PixelFormat   // 管道输入像素格式
+	filter     string        // 编码前的 -vf 滤镜链
+	buf        []byte        // 复用的帧缓冲
+	dither     Dither        // 高位深帧量化为 8 位时的抖动方式
+	ditherBuf  *image.RGBA   // 复用的抖动结果
+	timeout    time.Duration // 单次写入或等待编码结束的最长时间，0 表示不限
+	leak       *leakcheck.Guard
 }
 
 // VideoWriterOptions 视频写入器选项
@@ -34,6 +51,27 @@ type VideoWriterOptions struct {
 	Codec   string
 	Bitrate string
 	FPS     float64
+	// FrameRate 精确帧率，设置后优先于 FPS；未设置时由 FPS 换算（29.97 还原为 30000/1001）
+	FrameRate Rational
+	Preset    string // x264/x265 编码预设，默认 medium
+	CRF       int    // 恒定质量因子，默认 23
+	Threads   int    // 编码线程数，默认 1
+	// DirectWrite 直接写入目标文件；默认先写入同目录临时文件，成功关闭后再重命名
+	DirectWrite bool
+	// LogLevel FFmpeg 日志级别，默认 error
+	LogLevel LogLevel
+	// Logger 接收 FFmpeg stderr 输出，nil 表示 log.Default()
+	Logger *log.Logger
+	// PixelFormat 管道输入像素格式，默认 rgb24；源帧多为 *image.YCbCr 时使用 yuv420p 可免去颜色转换
+	PixelFormat PixelFormat
+	// Filter 编码前应用的 -vf 滤镜链（如 "fps=10,scale=640:-2"），输入帧尺寸仍为 width x height
+	Filter string
+	// WriteTimeout 单帧写入管道、以及 Close 等待编码结束的最长时间，超时后终止 FFmpeg，0 表示不限。
+	// 超时错误满足 errors.Is(err, context.DeadlineExceeded)
+	WriteTimeout time.Duration
+	// Dither 高位深帧（如 *image.RGBA64）量化为 8 位时的抖动方式，默认直接截断；
+	// 渐变较多的画面（暗角、淡入淡出）使用 DitherOrdered 或 DitherBlueNoise 可避免色带
+	Dither Dither
 }
 
 // NewVideoWriter 创建新的视频写入器
@@ -53,14 +91,39 @@ func NewVideoWriter(filename string, width, height int, options *VideoWriterOpti
 	if options.FPS == 0 {
 		options.FPS = 25.0
 	}
+	if options.FrameRate.IsZero() {
+		options.FrameRate = RationalFromFloat(options.FPS)
+	}
+	if options.Preset == "" {
+		options.Preset = "medium"
+	}
+	if options.CRF == 0 {
+		options.CRF = 23
+	}
+	if options.Threads == 0 {
+		options.Threads = 1 // 限制线程数，减少复杂度
+	}
+	if options.PixelFormat == "" {
+		options.PixelFormat = PixelFormatRGB24
+	}
 
 	return &VideoWriter{
 		filename:   filename,
 		width:      width,
 		height:     height,
-		fps:        options.FPS,
+		fps:        options.FrameRate,
 		codec:      options.Codec,
 		bitrate:    options.Bitrate,
+		preset:     options.Preset,
+		crf:        options.CRF,
+		threads:    options.Threads,
+		direct:     options.DirectWrite,
+		logLevel:   options.LogLevel,
+		logger:     options.Logger,
+		pixFmt:     options.PixelFormat,
+		filter:     options.Filter,
+		timeout:    options.WriteTimeout,
+		dither:     options.Dither,
 		processMgr: processMgr,
 		ctx:        ctx,
 		cancel:     cancel,
@@ -76,68 +139,87 @@ func (vw *VideoWriter) Open() error {
 		return fmt.Errorf("写入器已关闭")
 	}
 
-	// 构建 FFmpeg 命令
-	args := []string{
-		"-f", "rawvideo",
-		"-pix_fmt", "rgb24",
-		"-s", fmt.Sprintf("%dx%d", vw.width, vw.height),
-		"-r", strconv.FormatFloat(vw.fps, 'f', -1, 64),
-		"-i", "-",
-		"-c:v", vw.codec,
-		"-b:v", vw.bitrate,
-		"-preset", "medium", // 编码预设
-		"-crf", "23", // 恒定质量因子
-		"-pix_fmt", "yuv420p", // 输出像素格式，确保兼容性
-		"-threads", "1", // 限制线程数，减少复杂度
-		"-loglevel", "verbose", // 显示详细信息用于调试
-		"-y", // 覆盖输出文件
-		vw.filename,
+	// 先确认编码器可用，避免启动后才因未知编码器失败
+	if vw.codec != "copy" {
+		if err := vw.processMgr.CheckEncoder(vw.ctx, vw.codec); err != nil {
+			return err
+		}
 	}
 
-	// 创建命令
-	cmd := exec.CommandContext(vw.ctx, "ffmpeg", args...)
-
-	// 设置stderr到终端，这样可以看到FFmpeg的错误输出
-	cmd.Stderr = os.Stderr
-
-	// 在启动进程之前设置输入管道
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("设置输入管道失败: %w", err)
+	// 原子写入时先输出到临时文件
+	output := vw.filename
+	if !vw.direct {
+		tempFile, err := TempOutputPath(vw.filename)
+		if err != nil {
+			return err
+		}
+		vw.tempFile = tempFile
+		output = tempFile
 	}
 
-	// 启动进程
-	if err := cmd.Start(); err != nil {
+	// 构建 FFmpeg 命令
+	args := vw.buildArgs(output)
+
+	// 启动受管理的进程，stderr 写入日志
+	vw.stderr = newLogWriter(vw.logger, "[ffmpeg] ")
+	process, err := vw.processMgr.StartProcessWithPipes(vw.ctx, "ffmpeg", args, nil, &ProcessPipes{
+		Stdin:  true,
+		Stderr: vw.stderr,
+	})
+	if err != nil {
+		vw.removeTemp()
 		return fmt.Errorf("启动 FFmpeg 失败: %w", err)
 	}
 
-	// 创建进程包装器
-	process := &ManagedProcess{
-		cmd:       cmd,
-		pid:       cmd.Process.Pid,
-		startTime: time.Now(),
-		ctx:       vw.ctx,
-		cancel:    vw.cancel,
-		done:      make(chan error, 1),
-	}
-
-	// 启动一个 goroutine 来等待进程结束
-	go func() {
-		err := cmd.Wait()
-		process.done <- err
-	}()
-
 	vw.process = process
-	vw.stdin = stdin
+	vw.stdin = process.Stdin()
+	vw.leak = leakcheck.Track(vw, "VideoWriter", vw.filename)
 
 	return nil
 }
 
+// buildArgs 构建写入到 output 的 FFmpeg 参数
+func (vw *VideoWriter) buildArgs(output string) []string {
+	args := append(logArgs(vw.logLevel),
+		"-f", "rawvideo",
+		"-pix_fmt", string(vw.pixFmt),
+		"-s", fmt.Sprintf("%dx%d", vw.width, vw.height),
+		"-r", vw.fps.String(),
+	)
+	if vw.pixFmt == PixelFormatYUV420P {
+		// Go 的 image.YCbCr 为 JFIF 全范围
+		args = append(args, "-color_range", "pc")
+	}
+	args = append(args, "-i", "-")
+	if vw.filter != "" {
+		args = append(args, "-vf", vw.filter)
+	}
+	return append(args,
+		"-c:v", vw.codec,
+		"-b:v", vw.bitrate,
+		"-preset", vw.preset, // 编码预设
+		"-crf", strconv.Itoa(vw.crf), // 恒定质量因子
+		"-pix_fmt", "yuv420p", // 输出像素格式，确保兼容性
+		"-threads", strconv.Itoa(vw.threads),
+		"-y", // 覆盖输出文件
+		output,
+	)
+}
+
+// Command 返回 Open 将执行的 FFmpeg 命令（不执行）；原子写入时实际输出为同目录临时文件
+func (vw *VideoWriter) Command() Command {
+	return Command{Name: "ffmpeg", Args: vw.buildArgs(vw.filename)}
+}
+
 // WriteFrame 写入一帧
 func (vw *VideoWriter) WriteFrame(frame image.Image) error {
 	vw.mutex.Lock()
 	defer vw.mutex.Unlock()
+	return vw.writeFrameLocked(frame)
+}
 
+// writeFrameLocked 写入一帧，调用方需持有锁
+func (vw *VideoWriter) writeFrameLocked(frame image.Image) error {
 	if vw.closed {
 		return fmt.Errorf("写入器已关闭")
 	}
@@ -153,43 +235,38 @@ func (vw *VideoWriter) WriteFrame(frame image.Image) error {
 			vw.width, vw.height, bounds.Dx(), bounds.Dy())
 	}
 
-	// 将图像转换为 RGB 字节数组
-	pixelData := make([]byte, vw.width*vw.height*3)
-	idx := 0
-
-	// 确保从 (0,0) 开始遍历，使用帧的实际尺寸
-	for y := 0; y < vw.height; y++ {
-		for x := 0; x < vw.width; x++ {
-			// 映射到帧的实际坐标
-			frameX := bounds.Min.X + x
-			frameY := bounds.Min.Y + y
-
-			r, g, b, _ := frame.At(frameX, frameY).RGBA()
-			pixelData[idx] = byte(r >> 8)
-			pixelData[idx+1] = byte(g >> 8)
-			pixelData[idx+2] = byte(b >> 8)
-			idx += 3
+	// 按输入像素格式打包，缓冲区在帧之间复用
+	if vw.buf == nil {
+		vw.buf = make([]byte, vw.pixFmt.FrameSize(vw.width, vw.height))
+	}
+	pixelData := vw.buf
+	if vw.dither != DitherNone {
+		if vw.ditherBuf == nil {
+			vw.ditherBuf = image.NewRGBA(image.Rect(0, 0, vw.width, vw.height))
 		}
+		frame = DitherFrame(vw.ditherBuf, frame, vw.dither)
 	}
+	EncodeFrame(pixelData, frame, vw.pixFmt, vw.width, vw.height)
 
 	// 检查进程是否还在运行
 	select {
-	case processErr := <-vw.process.done:
+	case <-vw.process.done:
 		// 进程已经退出
-		return fmt.Errorf("FFmpeg进程已退出: %v", processErr)
+		return fmt.Errorf("FFmpeg进程已退出: %v", vw.process.err)
 	default:
 		// 进程仍在运行，继续写入
 	}
 
-	// 写入数据
+	// 写入数据，编码器卡住时由看门狗终止进程使写入返回
+	dog := startWatchdog(vw.timeout, vw.cancel)
 	_, err := vw.stdin.Write(pixelData)
-	if err != nil {
+	if err = dog.stop("写入帧", err); err != nil {
 		// 如果写入失败，检查进程状态
 		select {
-		case processErr := <-vw.process.done:
-			return fmt.Errorf("写入帧数据失败，FFmpeg进程已退出: %v, 写入错误: %w", processErr, err)
+		case <-vw.process.done:
+			return fmt.Errorf("写入帧数据失败，FFmpeg进程已退出: %v, 写入错误: %w", vw.process.err, classify(err, vw.stderr.Tail()))
 		default:
-			return fmt.Errorf("写入帧数据失败: %w", err)
+			return fmt.Errorf("写入帧数据失败: %w", classify(err, vw.stderr.Tail()))
 		}
 	}
 
@@ -206,6 +283,44 @@ func (vw *VideoWriter) WriteFrames(frames []image.Image) error {
 	return nil
 }
 
+// WriteFromChannel 持续写入 frames 中的帧，通道关闭后关闭写入器完成输出
+//
+// 生产者协程向通道发送帧，写入速度跟不上时发送方自然阻塞。写入期间一直持有写入器的锁，
+// 不再逐帧加锁，因此应通过 ctx 取消，而不是从其他协程调用 Abort。
+// 出错或 ctx 取消时中止写入并删除临时文件。
+func (vw *VideoWriter) WriteFromChannel(ctx context.Context, frames <-chan image.Image) error {
+	err := vw.consumeFrames(ctx, frames)
+	if err != nil {
+		vw.Abort()
+		return err
+	}
+	return vw.Close()
+}
+
+// consumeFrames 在锁内消费通道中的帧直到通道关闭
+func (vw *VideoWriter) consumeFrames(ctx context.Context, frames <-chan image.Image) error {
+	vw.mutex.Lock()
+	defer vw.mutex.Unlock()
+
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("写入被取消: %w", ctx.Err())
+		case frame, ok := <-frames:
+			if !ok {
+				// 取消后生产者也会关闭通道，此时不能按正常结束提交输出
+				if err := ctx.Err(); err != nil {
+					return fmt.Errorf("写入被取消: %w", err)
+				}
+				return nil
+			}
+			if err := vw.writeFrameLocked(frame); err != nil {
+				return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
+			}
+		}
+	}
+}
+
 // Close 关闭写入器
 func (vw *VideoWriter) Close() error {
 	vw.mutex.Lock()
@@ -216,6 +331,7 @@ func (vw *VideoWriter) Close() error {
 	}
 
 	vw.closed = true
+	vw.leak.Close()
 
 	// 关闭 stdin
 	if vw.stdin != nil {
@@ -224,8 +340,10 @@ func (vw *VideoWriter) Close() error {
 	}
 
 	// 等待进程结束
+	var waitErr error
 	if vw.process != nil {
-		vw.process.Wait()
+		dog := startWatchdog(vw.timeout, vw.cancel)
+		waitErr = dog.stop("等待编码结束", vw.process.Wait())
 		vw.process = nil
 	}
 
@@ -234,9 +352,57 @@ func (vw *VideoWriter) Close() error {
 		vw.cancel()
 	}
 
+	if waitErr != nil {
+		vw.removeTemp()
+		return fmt.Errorf("FFmpeg 进程异常退出: %w", classify(waitErr, vw.stderr.Tail()))
+	}
+
+	// 编码成功后再替换目标文件
+	if vw.tempFile != "" {
+		tempFile := vw.tempFile
+		vw.tempFile = ""
+		return CommitOutput(tempFile, vw.filename)
+	}
+
 	return nil
 }
 
+// Abort 中止写入：终止 FFmpeg 并删除临时文件，目标文件保持不变；已关闭时无操作
+func (vw *VideoWriter) Abort() error {
+	vw.mutex.Lock()
+	defer vw.mutex.Unlock()
+
+	if vw.closed {
+		return nil
+	}
+	vw.closed = true
+	vw.leak.Close()
+
+	// 先取消上下文以终止进程，避免 FFmpeg 把不完整的数据封装成文件
+	if vw.cancel != nil {
+		vw.cancel()
+	}
+	if vw.stdin != nil {
+		vw.stdin.Close()
+		vw.stdin = nil
+	}
+	if vw.process != nil {
+		vw.process.Wait()
+		vw.process = nil
+	}
+
+	vw.removeTemp()
+	return nil
+}
+
+// removeTemp 删除临时输出文件
+func (vw *VideoWriter) removeTemp() {
+	if vw.tempFile != "" {
+		os.Remove(vw.tempFile)
+		vw.tempFile = ""
+	}
+}
+
 // IsClosed 检查是否已关闭
 func (vw *VideoWriter) IsClosed() bool {
 	vw.mutex.RLock()
@@ -247,12 +413,18 @@ func (vw *VideoWriter) IsClosed() bool {
 // GetInfo 获取写入器信息
 func (vw *VideoWriter) GetInfo() map[string]interface{} {
 	return map[string]interface{}{
-		"filename": vw.filename,
-		"width":    vw.width,
-		"height":   vw.height,
-		"fps":      vw.fps,
-		"codec":    vw.codec,
-		"bitrate":  vw.bitrate,
-		"closed":   vw.closed,
+		"filename":   vw.filename,
+		"width":      vw.width,
+		"height":     vw.height,
+		"fps":        vw.fps.Float64(),
+		"frame_rate": vw.fps.String(),
+		"codec":      vw.codec,
+		"bitrate":    vw.bitrate,
+		"preset":     vw.preset,
+		"crf":        vw.crf,
+		"threads":    vw.threads,
+		"pix_fmt":    string(vw.pixFmt),
+		"filter":     vw.filter,
+		"closed":     vw.closed,
 	}
 }
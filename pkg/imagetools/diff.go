@@ -0,0 +1,104 @@
+// Package imagetools 提供帧比较等图像工具，用于特效回归测试和校验渲染结果
+package imagetools
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"moviepy-go/pkg/pixel"
+)
+
+// DiffResult 两帧按 RGBA 通道逐像素比较的结果
+type DiffResult struct {
+	SizeMismatch bool            // 尺寸不同，此时 Image 为 nil、其余统计为零
+	Pixels       int             // 比较的像素数
+	Mismatched   int             // 任一通道差值超过容差的像素数
+	MaxDelta     int             // 单个通道的最大差值（0–255）
+	MeanDelta    float64         // 全部通道差值的平均
+	PSNR         float64         // RGB 通道的峰值信噪比（dB，与 analysis.PSNR 相同），完全相同时为 +Inf
+	Bounds       image.Rectangle // 超出容差的像素的外接矩形（相对左上角），没有时为空
+	// Image 可视化差异：a 的灰度暗化作为底图，超出容差的像素标为红色，差值越大越亮，
+	// 在容差内但不为零的差异标为蓝色
+	Image *image.RGBA
+}
+
+// Equal 是否在容差内一致
+func (r *DiffResult) Equal() bool {
+	return !r.SizeMismatch && r.Mismatched == 0
+}
+
+// String 返回差异摘要
+func (r *DiffResult) String() string {
+	if r.SizeMismatch {
+		return "尺寸不同"
+	}
+	if r.Mismatched == 0 {
+		return fmt.Sprintf("一致（最大差值 %d）", r.MaxDelta)
+	}
+	return fmt.Sprintf("%d/%d 个像素超出容差，最大差值 %d，平均差值 %.3f，PSNR %.2f dB，范围 %v",
+		r.Mismatched, r.Pixels, r.MaxDelta, r.MeanDelta, r.PSNR, r.Bounds)
+}
+
+// Diff 比较 a 与 b，tolerance 为单个通道允许的最大差值（0 表示要求完全相同）
+//
+// 两帧按各自的左上角对齐，不要求 Bounds 原点相同。
+func Diff(a, b image.Image, tolerance int) *DiffResult {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Dx() != bb.Dx() || ab.Dy() != bb.Dy() {
+		return &DiffResult{SizeMismatch: true}
+	}
+	ra, rb := pixel.ToRGBA(a), pixel.ToRGBA(b)
+	width, height := ab.Dx(), ab.Dy()
+	result := &DiffResult{
+		Pixels: width * height,
+		Image:  image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+
+	var sum, sumSquares int64
+	for y := 0; y < height; y++ {
+		rowA := ra.Pix[ra.PixOffset(ab.Min.X, ab.Min.Y+y):][:width*4]
+		rowB := rb.Pix[rb.PixOffset(bb.Min.X, bb.Min.Y+y):][:width*4]
+		out := result.Image.Pix[y*result.Image.Stride:][:width*4]
+		for x := 0; x < width; x++ {
+			i := x * 4
+			delta := 0
+			for c := 0; c < 4; c++ {
+				d := int(rowA[i+c]) - int(rowB[i+c])
+				if d < 0 {
+					d = -d
+				}
+				delta = max(delta, d)
+				sum += int64(d)
+				if c < 3 {
+					sumSquares += int64(d * d)
+				}
+			}
+			result.MaxDelta = max(result.MaxDelta, delta)
+
+			// 底图：a 的亮度压到 0–85，使标记醒目
+			gray := uint8((int(rowA[i])*77 + int(rowA[i+1])*150 + int(rowA[i+2])*29) >> 8 / 3)
+			out[i], out[i+1], out[i+2], out[i+3] = gray, gray, gray, 255
+			switch {
+			case delta > tolerance:
+				out[i], out[i+1], out[i+2] = uint8(128+delta/2), 0, 0
+				result.Mismatched++
+				result.Bounds = result.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			case delta > 0:
+				out[i+2] = uint8(min(255, int(gray)+96+delta*8))
+			}
+		}
+	}
+
+	samples := float64(result.Pixels * 4)
+	if samples > 0 {
+		result.MeanDelta = float64(sum) / samples
+	}
+	if sumSquares == 0 {
+		result.PSNR = math.Inf(1)
+	} else {
+		mse := float64(sumSquares) / float64(result.Pixels*3)
+		result.PSNR = 10 * math.Log10(255*255/mse)
+	}
+	return result
+}
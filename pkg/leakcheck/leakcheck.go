@@ -0,0 +1,146 @@
+// Package leakcheck 在调试模式下追踪持有 FFmpeg 进程或读取器的对象，对象被垃圾回收时仍未关闭则报告泄漏
+//
+// 默认关闭，此时 Track 返回 nil，不产生任何开销。设置环境变量 MOVIEGO_LEAKCHECK=1 或调用 Enable(true)
+// 后，新创建的剪辑、读取器和写入器会记录创建时的调用栈；被回收时尚未 Close 的对象通过 SetHandler
+// 设置的函数报告（默认写入 log.Default()）。长期运行的服务可定期调用 Open 查看仍未关闭的对象。
+package leakcheck
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EnvVar 设置为非空且不为 "0" 时启用泄漏检查
+const EnvVar = "MOVIEGO_LEAKCHECK"
+
+// Leak 一个未关闭的对象
+type Leak struct {
+	Kind    string    // 对象类型，如 "VideoFileClip"、"VideoReader"
+	Name    string    // 通常为文件名
+	Created time.Time // 创建时间
+	Stack   string    // 创建时的调用栈
+}
+
+// String 返回可打印的泄漏描述
+func (l Leak) String() string {
+	return fmt.Sprintf("%s %s 未关闭（创建于 %s）\n%s", l.Kind, l.Name, l.Created.Format(time.RFC3339), l.Stack)
+}
+
+var (
+	enabled atomic.Bool
+	handler atomic.Pointer[func(Leak)]
+
+	mutex  sync.Mutex
+	guards = make(map[*state]struct{})
+)
+
+func init() {
+	if v := os.Getenv(EnvVar); v != "" && v != "0" {
+		enabled.Store(true)
+	}
+}
+
+// Enable 启用或关闭泄漏检查，只影响之后创建的对象
+func Enable(on bool) {
+	enabled.Store(on)
+}
+
+// Enabled 是否启用了泄漏检查
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// SetHandler 设置泄漏报告函数，nil 恢复默认（写入 log.Default()）；报告在垃圾回收的清理协程中调用
+func SetHandler(fn func(Leak)) {
+	if fn == nil {
+		handler.Store(nil)
+		return
+	}
+	handler.Store(&fn)
+}
+
+// Open 返回已追踪但尚未关闭、也未被回收的对象，按创建时间排序
+func Open() []Leak {
+	mutex.Lock()
+	leaks := make([]Leak, 0, len(guards))
+	for s := range guards {
+		leaks = append(leaks, s.leak)
+	}
+	mutex.Unlock()
+	sort.Slice(leaks, func(i, j int) bool { return leaks[i].Created.Before(leaks[j].Created) })
+	return leaks
+}
+
+// Guard 追踪一个对象的关闭状态，nil 的方法均为空操作
+type Guard struct {
+	state *state
+}
+
+// state 与被追踪对象分离，清理函数只引用它，不会使对象无法回收
+type state struct {
+	leak   Leak
+	closed atomic.Bool
+}
+
+// Track 开始追踪 obj，kind 和 name 用于报告；未启用时返回 nil
+//
+// 对象关闭时调用返回的 Guard 的 Close。对象在关闭前被回收视为泄漏。
+func Track[T any](obj *T, kind, name string) *Guard {
+	if !enabled.Load() {
+		return nil
+	}
+	s := &state{leak: Leak{Kind: kind, Name: name, Created: time.Now(), Stack: callers(3)}}
+	mutex.Lock()
+	guards[s] = struct{}{}
+	mutex.Unlock()
+	runtime.AddCleanup(obj, collected, s)
+	return &Guard{state: s}
+}
+
+// Close 标记对象已关闭，可重复调用
+func (g *Guard) Close() {
+	if g == nil || g.state.closed.Swap(true) {
+		return
+	}
+	mutex.Lock()
+	delete(guards, g.state)
+	mutex.Unlock()
+}
+
+// collected 对象被回收时调用，未关闭则报告泄漏
+func collected(s *state) {
+	if s.closed.Load() {
+		return
+	}
+	mutex.Lock()
+	delete(guards, s)
+	mutex.Unlock()
+	if fn := handler.Load(); fn != nil {
+		(*fn)(s.leak)
+		return
+	}
+	log.Printf("moviego: 泄漏: %v", s.leak)
+}
+
+// callers 格式化调用栈，skip 跳过 leakcheck 自身的帧
+func callers(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package pixel_test
+
+import (
+	"testing"
+
+	"moviepy-go/pkg/core/coretest"
+	"moviepy-go/pkg/pixel"
+)
+
+func TestBlendPremultipliedReference(t *testing.T) {
+	for mode := pixel.BlendNormal; mode <= pixel.BlendOverlay; mode++ {
+		t.Run(mode.String(), func(t *testing.T) {
+			coretest.CheckBlend(t, mode, func(dst, src []byte) { pixel.BlendPremultiplied(mode, dst, src) }, 1)
+		})
+	}
+}
+
+func TestBlendReference(t *testing.T) {
+	for mode := pixel.BlendNormal; mode <= pixel.BlendOverlay; mode++ {
+		t.Run(mode.String(), func(t *testing.T) {
+			coretest.CheckBlendOpaque(t, mode, func(dst, src []byte) { pixel.Blend(mode, dst, src) }, 1)
+		})
+	}
+}
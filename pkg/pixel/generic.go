@@ -0,0 +1,100 @@
+package pixel
+
+// affineGeneric affine 的可移植实现
+func affineGeneric(dst, src []byte, m, c int32) {
+	for i := 0; i+3 < len(src); i += 4 {
+		dst[i] = clampByte(int32(src[i])*m>>8 + c)
+		dst[i+1] = clampByte(int32(src[i+1])*m>>8 + c)
+		dst[i+2] = clampByte(int32(src[i+2])*m>>8 + c)
+		dst[i+3] = src[i+3]
+	}
+}
+
+// saturationGeneric Saturation 的可移植实现，f 为 factor·512
+func saturationGeneric(dst, src []byte, f int32) {
+	for i := 0; i+3 < len(src); i += 4 {
+		r, g, b := int32(src[i]), int32(src[i+1]), int32(src[i+2])
+		l := (77*r + 150*g + 29*b) >> 8
+		dst[i] = clampByte(l + (r-l)*f>>9)
+		dst[i+1] = clampByte(l + (g-l)*f>>9)
+		dst[i+2] = clampByte(l + (b-l)*f>>9)
+		dst[i+3] = src[i+3]
+	}
+}
+
+// blendGeneric Blend 的可移植实现，逐像素反预乘后混合
+func blendGeneric(mode BlendMode, dst, src []byte) {
+	for i := 0; i+3 < len(src); i += 4 {
+		a2 := int32(src[i+3])
+		if a2 == 0 {
+			continue
+		}
+		a1 := int32(dst[i+3])
+		for k := 0; k < 3; k++ {
+			base, over := int32(dst[i+k]), int32(src[i+k])
+			if a2 < 255 {
+				over = min(over*255/a2, 255)
+			}
+			v := blendChannel(mode, base, over)
+			if a2 < 255 {
+				v = div255(v*a2 + base*(255-a2))
+			}
+			dst[i+k] = byte(v)
+		}
+		dst[i+3] = byte(max(a1, a2))
+	}
+}
+
+// blendPremultipliedGeneric BlendPremultiplied 的可移植实现，分子按 255² 的比例计算后一次舍入
+func blendPremultipliedGeneric(mode BlendMode, dst, src []byte) {
+	for i := 0; i+3 < len(src); i += 4 {
+		as := int32(src[i+3])
+		if as == 0 {
+			continue
+		}
+		ab := int32(dst[i+3])
+		if ab == 0 {
+			copy(dst[i:i+4], src[i:i+4])
+			continue
+		}
+		ao := as + div255(ab*(255-as))
+		for k := 0; k < 3; k++ {
+			cb, cs := int32(dst[i+k]), int32(src[i+k])
+			b := blendChannel(mode, unpremultiply(cb, ab), unpremultiply(cs, as))
+			co := (cs*(255-ab)*255 + cb*(255-as)*255 + as*ab*b + 65025/2) / 65025
+			dst[i+k] = byte(min(co, ao))
+		}
+		dst[i+3] = byte(ao)
+	}
+}
+
+// unpremultiply 四舍五入地把预乘通道 c 还原为 alpha 为 a 时的非预乘值
+func unpremultiply(c, a int32) int32 {
+	if a == 255 {
+		return c
+	}
+	return min((c*255+a/2)/a, 255)
+}
+
+// blendChannel 单通道混合，输入输出均为 0–255
+func blendChannel(mode BlendMode, base, over int32) int32 {
+	switch mode {
+	case BlendAdd:
+		return min(base+over, 255)
+	case BlendMultiply:
+		return div255(base * over)
+	case BlendScreen:
+		return 255 - div255((255-base)*(255-over))
+	case BlendDarken:
+		return min(base, over)
+	case BlendLighten:
+		return max(base, over)
+	case BlendOverlay:
+		if base < 128 {
+			return div255(2 * base * over)
+		}
+		return 255 - div255(2*(255-base)*(255-over))
+	default:
+		return over
+	}
+}
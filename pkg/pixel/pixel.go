@@ -0,0 +1,174 @@
+// Package pixel 提供 8 位 RGBA 像素缓冲区上的颜色运算内核
+//
+// 每个内核都有可移植的 Go 实现；目前只有 amd64 提供 SIMD 实现，运行时检测到 AVX2 后启用，
+// arm64（NEON）等其他平台使用可移植实现。两者使用相同的定点公式，结果逐字节一致。
+// 缓冲区按 image.RGBA 的预乘 RGBA 字节顺序排列。
+package pixel
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// BlendMode 混合模式，与 compositing.CompositeMode 一一对应
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendAdd
+	BlendMultiply
+	BlendScreen
+	BlendDarken
+	BlendLighten
+	BlendOverlay
+)
+
+// blendModeNames 按 BlendMode 取值排列的名称
+var blendModeNames = []string{"normal", "add", "multiply", "screen", "darken", "lighten", "overlay"}
+
+// String 返回混合模式名称
+func (m BlendMode) String() string {
+	if m >= 0 && int(m) < len(blendModeNames) {
+		return blendModeNames[m]
+	}
+	return fmt.Sprintf("BlendMode(%d)", int(m))
+}
+
+// simdMaxFactor SIMD 内核的因子范围上限，超出时使用可移植实现（16 位定点不溢出）
+const simdMaxFactor = 64
+
+// Kernel 逐行处理的像素内核，dst 与 src 长度相同
+type Kernel func(dst, src []byte)
+
+// Implementation 返回当前使用的实现名称，如 "avx2" 或 "generic"
+func Implementation() string {
+	if simdEnabled {
+		return simdName
+	}
+	return "generic"
+}
+
+// SetSIMD 启用或禁用 SIMD 内核（用于对比和排查），CPU 不支持时始终禁用，返回设置后的状态
+func SetSIMD(enabled bool) bool {
+	simdEnabled = enabled && simdSupported
+	return simdEnabled
+}
+
+// ToRGBA 将 img 转为 *image.RGBA，已是 RGBA 时原样返回，保留原有的 Bounds
+func ToRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+// Apply 对 src 的每一行运行 kernel，返回原点为 (0,0) 的新图像
+func Apply(src image.Image, kernel Kernel) *image.RGBA {
+	in := ToRGBA(src)
+	bounds := in.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	rowBytes := bounds.Dx() * 4
+	if in.Stride == rowBytes && bounds.Min == (image.Point{}) {
+		kernel(out.Pix, in.Pix[:len(out.Pix)])
+		return out
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		offset := in.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+		kernel(out.Pix[y*out.Stride:y*out.Stride+rowBytes], in.Pix[offset:offset+rowBytes])
+	}
+	return out
+}
+
+// Brightness RGB 乘以 factor（不小于 0），alpha 不变
+func Brightness(dst, src []byte, factor float64) {
+	factor = math.Max(factor, 0)
+	affine(dst, src, factor, 0)
+}
+
+// Contrast 以 128 为中心按 factor 拉伸 RGB：v' = (v-128)·factor + 128，alpha 不变
+func Contrast(dst, src []byte, factor float64) {
+	affine(dst, src, factor, 128*(1-factor))
+}
+
+// affine 计算 v' = clamp(v·m/256 + c)，m = round(factor·256)
+func affine(dst, src []byte, factor, offset float64) {
+	m := int32(math.Round(factor * 256))
+	c := int32(math.Round(offset))
+	n := 0
+	if simdEnabled && factor >= 0 && factor <= simdMaxFactor {
+		n = affineSIMD(dst, src, uint16(m), int16(c))
+	}
+	affineGeneric(dst[n:], src[n:], m, c)
+}
+
+// Saturation 以 Rec.601 亮度为中心按 factor 调整饱和度：v' = L + (v-L)·factor，alpha 不变
+func Saturation(dst, src []byte, factor float64) {
+	f := int32(math.Round(factor * 512))
+	n := 0
+	if simdEnabled && math.Abs(factor) < simdMaxFactor {
+		n = saturationSIMD(dst, src, int16(f))
+	}
+	saturationGeneric(dst[n:], src[n:], f)
+}
+
+// Blend 将 src 按 mode 混合到 dst（原地），src 的 alpha 决定混合结果与底色的比例
+//
+// 整行不透明时使用 SIMD 内核，含透明像素的行逐像素处理。
+func Blend(mode BlendMode, dst, src []byte) {
+	n := 0
+	if simdEnabled && opaque(src) {
+		n = blendSIMD(mode, dst, src)
+	}
+	blendGeneric(mode, dst[n:], src[n:])
+}
+
+// BlendPremultiplied 按 W3C Compositing and Blending 规范的预乘 source-over 公式将 src 按 mode 混合到 dst（原地）
+//
+// co = cs·(1−αb) + cb·(1−αs) + αs·αb·B(Cb, Cs)，αo = αs + αb·(1−αs)。与 Blend 不同，dst 可以是半透明的
+// （如透明画布），反预乘时四舍五入以避免低 alpha 处的色带。两行都不透明时结果与 Blend 相同。
+func BlendPremultiplied(mode BlendMode, dst, src []byte) {
+	if opaque(src) && opaque(dst) {
+		Blend(mode, dst, src)
+		return
+	}
+	blendPremultipliedGeneric(mode, dst, src)
+}
+
+// Fade 所有通道（含 alpha）乘以 opacity（0–1），即调整预乘颜色的不透明度
+func Fade(dst, src []byte, opacity float64) {
+	f := int32(math.Round(math.Max(0, math.Min(1, opacity)) * 255))
+	for i := range src {
+		dst[i] = byte(div255(int32(src[i]) * f))
+	}
+}
+
+// opaque 判断缓冲区是否所有像素的 alpha 都为 255
+func opaque(buf []byte) bool {
+	for i := 3; i < len(buf); i += 4 {
+		if buf[i] != 255 {
+			return false
+		}
+	}
+	return true
+}
+
+// div255 四舍五入的 x/255，x 不超过 255·255
+func div255(x int32) int32 {
+	x += 128
+	return (x + x>>8) >> 8
+}
+
+// clampByte 限制在 0–255
+func clampByte(v int32) byte {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
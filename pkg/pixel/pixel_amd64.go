@@ -0,0 +1,107 @@
+package pixel
+
+const simdName = "avx2"
+
+// simdSupported CPU 与操作系统均支持 AVX2（含 YMM 状态保存）
+var simdSupported = detectAVX2()
+
+var simdEnabled = simdSupported
+
+// detectAVX2 通过 CPUID 与 XGETBV 检测 AVX2
+func detectAVX2() bool {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	const osxsave, avx = 1 << 27, 1 << 28
+	if ecx1&osxsave == 0 || ecx1&avx == 0 {
+		return false
+	}
+	// XCR0 的第 1、2 位表示操作系统保存 XMM 与 YMM 状态
+	if xcr0, _ := xgetbv(); xcr0&6 != 6 {
+		return false
+	}
+	_, ebx7, _, _ := cpuid(7, 0)
+	return ebx7&(1<<5) != 0
+}
+
+// affineSIMD 处理 32 字节整数倍的前缀，返回已处理的字节数
+func affineSIMD(dst, src []byte, m uint16, c int16) int {
+	n := len(src) &^ 31
+	if n == 0 {
+		return 0
+	}
+	// alpha 通道 m=256、c=0，结果不变
+	var mv, cv [16]uint16
+	for i := range mv {
+		if i%4 == 3 {
+			mv[i] = 256
+			continue
+		}
+		mv[i], cv[i] = m, uint16(c)
+	}
+	affineAVX2(dst[:n], src[:n], &mv, &cv)
+	return n
+}
+
+// saturationSIMD 处理 32 字节整数倍的前缀，返回已处理的字节数
+func saturationSIMD(dst, src []byte, f int16) int {
+	n := len(src) &^ 31
+	if n == 0 {
+		return 0
+	}
+	saturationAVX2(dst[:n], src[:n], f)
+	return n
+}
+
+// blendSIMD 处理 32 字节整数倍的前缀，src 须全部不透明，返回已处理的字节数
+func blendSIMD(mode BlendMode, dst, src []byte) int {
+	n := len(src) &^ 31
+	if n == 0 {
+		return 0
+	}
+	switch mode {
+	case BlendNormal:
+		copy(dst[:n], src[:n])
+	case BlendAdd:
+		addAVX2(dst[:n], src[:n])
+	case BlendDarken:
+		minAVX2(dst[:n], src[:n])
+	case BlendLighten:
+		maxAVX2(dst[:n], src[:n])
+	case BlendMultiply:
+		multiplyAVX2(dst[:n], src[:n], false)
+	case BlendScreen:
+		multiplyAVX2(dst[:n], src[:n], true)
+	default:
+		return 0
+	}
+	return n
+}
+
+// 以下在 pixel_amd64.s 中实现
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+func xgetbv() (eax, edx uint32)
+
+//go:noescape
+func affineAVX2(dst, src []byte, m, c *[16]uint16)
+
+//go:noescape
+func saturationAVX2(dst, src []byte, f int16)
+
+//go:noescape
+func addAVX2(dst, src []byte)
+
+//go:noescape
+func minAVX2(dst, src []byte)
+
+//go:noescape
+func maxAVX2(dst, src []byte)
+
+// multiplyAVX2 screen 为 true 时计算 255 - (255-a)(255-b)/255
+//
+//go:noescape
+func multiplyAVX2(dst, src []byte, screen bool)
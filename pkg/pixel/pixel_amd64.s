@@ -0,0 +1,252 @@
+#include "textflag.h"
+
+// 每个像素的 alpha 字节为 0xff
+DATA alphaMask<>+0(SB)/8, $0xff000000ff000000
+DATA alphaMask<>+8(SB)/8, $0xff000000ff000000
+DATA alphaMask<>+16(SB)/8, $0xff000000ff000000
+DATA alphaMask<>+24(SB)/8, $0xff000000ff000000
+GLOBL alphaMask<>(SB), RODATA|NOPTR, $32
+
+// Rec.601 亮度权重 77,150,29,0（和为 256），每个像素一组
+DATA lumaWeights<>+0(SB)/8, $0x0000001d0096004d
+DATA lumaWeights<>+8(SB)/8, $0x0000001d0096004d
+DATA lumaWeights<>+16(SB)/8, $0x0000001d0096004d
+DATA lumaWeights<>+24(SB)/8, $0x0000001d0096004d
+GLOBL lumaWeights<>(SB), RODATA|NOPTR, $32
+
+// div255 的舍入常数 128
+DATA round128<>+0(SB)/8, $0x0080008000800080
+DATA round128<>+8(SB)/8, $0x0080008000800080
+DATA round128<>+16(SB)/8, $0x0080008000800080
+DATA round128<>+24(SB)/8, $0x0080008000800080
+GLOBL round128<>(SB), RODATA|NOPTR, $32
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
+
+// func affineAVX2(dst, src []byte, m, c *[16]uint16)
+// 每次 32 字节：字节扩展为字，v' = (v<<8)·m >> 16 + c，饱和打包回字节
+TEXT ·affineAVX2(SB), NOSPLIT, $0-64
+	MOVQ dst_base+0(FP), DI
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), CX
+	MOVQ m+48(FP), AX
+	MOVQ c+56(FP), BX
+	VMOVDQU (AX), Y10
+	VMOVDQU (BX), Y11
+	SHRQ $5, CX
+	JZ done
+
+loop:
+	VPMOVZXBW (SI), Y1
+	VPMOVZXBW 16(SI), Y2
+	VPSLLW $8, Y1, Y1
+	VPSLLW $8, Y2, Y2
+	VPMULHUW Y10, Y1, Y1
+	VPMULHUW Y10, Y2, Y2
+	VPADDW Y11, Y1, Y1
+	VPADDW Y11, Y2, Y2
+	VPACKUSWB Y2, Y1, Y3
+	VPERMQ $0xD8, Y3, Y3
+	VMOVDQU Y3, (DI)
+	ADDQ $32, SI
+	ADDQ $32, DI
+	DECQ CX
+	JNZ loop
+
+done:
+	VZEROUPPER
+	RET
+
+// func saturationAVX2(dst, src []byte, f int16)
+// L = (77r+150g+29b)>>8 广播到像素的四个字，v' = L + ((v-L)<<7)·f >> 16，alpha 取原值
+TEXT ·saturationAVX2(SB), NOSPLIT, $0-50
+	MOVQ dst_base+0(FP), DI
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), CX
+	MOVWLZX f+48(FP), AX
+	MOVQ AX, X13
+	VPBROADCASTW X13, Y13
+	VMOVDQU lumaWeights<>(SB), Y12
+	SHRQ $5, CX
+	JZ done
+
+loop:
+	VPMOVZXBW (SI), Y1
+	VPMOVZXBW 16(SI), Y2
+
+	VPMADDWD Y12, Y1, Y4
+	VPSHUFD $0xB1, Y4, Y5
+	VPADDD Y5, Y4, Y4
+	VPSRLD $8, Y4, Y4
+	VPSHUFLW $0x00, Y4, Y4
+	VPSHUFHW $0x00, Y4, Y4
+	VPSUBW Y4, Y1, Y6
+	VPSLLW $7, Y6, Y6
+	VPMULHW Y13, Y6, Y6
+	VPADDW Y4, Y6, Y6
+	VPBLENDW $0x88, Y1, Y6, Y6
+
+	VPMADDWD Y12, Y2, Y7
+	VPSHUFD $0xB1, Y7, Y8
+	VPADDD Y8, Y7, Y7
+	VPSRLD $8, Y7, Y7
+	VPSHUFLW $0x00, Y7, Y7
+	VPSHUFHW $0x00, Y7, Y7
+	VPSUBW Y7, Y2, Y9
+	VPSLLW $7, Y9, Y9
+	VPMULHW Y13, Y9, Y9
+	VPADDW Y7, Y9, Y9
+	VPBLENDW $0x88, Y2, Y9, Y9
+
+	VPACKUSWB Y9, Y6, Y3
+	VPERMQ $0xD8, Y3, Y3
+	VMOVDQU Y3, (DI)
+	ADDQ $32, SI
+	ADDQ $32, DI
+	DECQ CX
+	JNZ loop
+
+done:
+	VZEROUPPER
+	RET
+
+// func addAVX2(dst, src []byte)
+TEXT ·addAVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), CX
+	VMOVDQU alphaMask<>(SB), Y15
+	SHRQ $5, CX
+	JZ done
+
+loop:
+	VMOVDQU (SI), Y1
+	VPADDUSB (DI), Y1, Y1
+	VPOR Y15, Y1, Y1
+	VMOVDQU Y1, (DI)
+	ADDQ $32, SI
+	ADDQ $32, DI
+	DECQ CX
+	JNZ loop
+
+done:
+	VZEROUPPER
+	RET
+
+// func minAVX2(dst, src []byte)
+TEXT ·minAVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), CX
+	VMOVDQU alphaMask<>(SB), Y15
+	SHRQ $5, CX
+	JZ done
+
+loop:
+	VMOVDQU (SI), Y1
+	VPMINUB (DI), Y1, Y1
+	VPOR Y15, Y1, Y1
+	VMOVDQU Y1, (DI)
+	ADDQ $32, SI
+	ADDQ $32, DI
+	DECQ CX
+	JNZ loop
+
+done:
+	VZEROUPPER
+	RET
+
+// func maxAVX2(dst, src []byte)
+TEXT ·maxAVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), CX
+	VMOVDQU alphaMask<>(SB), Y15
+	SHRQ $5, CX
+	JZ done
+
+loop:
+	VMOVDQU (SI), Y1
+	VPMAXUB (DI), Y1, Y1
+	VPOR Y15, Y1, Y1
+	VMOVDQU Y1, (DI)
+	ADDQ $32, SI
+	ADDQ $32, DI
+	DECQ CX
+	JNZ loop
+
+done:
+	VZEROUPPER
+	RET
+
+// func multiplyAVX2(dst, src []byte, screen bool)
+// v' = div255(a·b)；screen 时先对两侧取反（255-x），结果再取反
+TEXT ·multiplyAVX2(SB), NOSPLIT, $0-49
+	MOVQ dst_base+0(FP), DI
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), CX
+	MOVBLZX screen+48(FP), AX
+	VMOVDQU alphaMask<>(SB), Y15
+	VMOVDQU round128<>(SB), Y14
+	VPXOR Y13, Y13, Y13
+	TESTQ AX, AX
+	JZ ready
+	VPCMPEQB Y13, Y13, Y13
+
+ready:
+	SHRQ $5, CX
+	JZ done
+
+loop:
+	VMOVDQU (SI), Y1
+	VMOVDQU (DI), Y2
+	VPXOR Y13, Y1, Y1
+	VPXOR Y13, Y2, Y2
+	VPMOVZXBW X1, Y3
+	VEXTRACTI128 $1, Y1, X4
+	VPMOVZXBW X4, Y4
+	VPMOVZXBW X2, Y5
+	VEXTRACTI128 $1, Y2, X6
+	VPMOVZXBW X6, Y6
+	VPMULLW Y5, Y3, Y3
+	VPMULLW Y6, Y4, Y4
+
+	VPADDW Y14, Y3, Y3
+	VPSRLW $8, Y3, Y5
+	VPADDW Y5, Y3, Y3
+	VPSRLW $8, Y3, Y3
+	VPADDW Y14, Y4, Y4
+	VPSRLW $8, Y4, Y6
+	VPADDW Y6, Y4, Y4
+	VPSRLW $8, Y4, Y4
+
+	VPACKUSWB Y4, Y3, Y3
+	VPERMQ $0xD8, Y3, Y3
+	VPXOR Y13, Y3, Y3
+	VPOR Y15, Y3, Y3
+	VMOVDQU Y3, (DI)
+	ADDQ $32, SI
+	ADDQ $32, DI
+	DECQ CX
+	JNZ loop
+
+done:
+	VZEROUPPER
+	RET
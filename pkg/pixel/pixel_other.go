@@ -0,0 +1,15 @@
+//go:build !amd64
+
+package pixel
+
+// 其他平台（包括 arm64 NEON）暂无 SIMD 内核，全部使用可移植实现
+const (
+	simdName      = ""
+	simdSupported = false
+)
+
+var simdEnabled = false
+
+func affineSIMD(dst, src []byte, m uint16, c int16) int { return 0 }
+func saturationSIMD(dst, src []byte, f int16) int       { return 0 }
+func blendSIMD(mode BlendMode, dst, src []byte) int     { return 0 }
@@ -0,0 +1,69 @@
+package pixel
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Kernel64 在 image.RGBA64 布局（预乘 RGBA，每通道大端 16 位）的行上运行的内核，dst 与 src 长度相同
+//
+// 与 8 位内核的公式相同，但中间结果不在每一步舍入到 8 位，多个调整串联时不会累积成色阶断层。
+type Kernel64 func(dst, src []byte)
+
+// ToRGBA64 将 img 转为 *image.RGBA64，已是 RGBA64 时原样返回，保留原有的 Bounds
+func ToRGBA64(img image.Image) *image.RGBA64 {
+	if rgba64, ok := img.(*image.RGBA64); ok {
+		return rgba64
+	}
+	rgba64 := image.NewRGBA64(img.Bounds())
+	draw.Draw(rgba64, rgba64.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba64
+}
+
+// Brightness64 Brightness 的 16 位版本
+func Brightness64(dst, src []byte, factor float64) {
+	affine64(dst, src, math.Max(factor, 0), 0)
+}
+
+// Contrast64 Contrast 的 16 位版本，中心为 128 对应的 16 位值 32896
+func Contrast64(dst, src []byte, factor float64) {
+	affine64(dst, src, factor, 128*257*(1-factor))
+}
+
+// affine64 计算 v' = clamp(v·factor + offset)，alpha 不变
+func affine64(dst, src []byte, factor, offset float64) {
+	for i := 0; i+7 < len(src); i += 8 {
+		for k := 0; k < 6; k += 2 {
+			put16(dst[i+k:], clamp16(float64(get16(src[i+k:]))*factor+offset))
+		}
+		dst[i+6], dst[i+7] = src[i+6], src[i+7]
+	}
+}
+
+// Saturation64 Saturation 的 16 位版本
+func Saturation64(dst, src []byte, factor float64) {
+	for i := 0; i+7 < len(src); i += 8 {
+		r, g, b := float64(get16(src[i:])), float64(get16(src[i+2:])), float64(get16(src[i+4:]))
+		l := (77*r + 150*g + 29*b) / 256
+		put16(dst[i:], clamp16(l+(r-l)*factor))
+		put16(dst[i+2:], clamp16(l+(g-l)*factor))
+		put16(dst[i+4:], clamp16(l+(b-l)*factor))
+		dst[i+6], dst[i+7] = src[i+6], src[i+7]
+	}
+}
+
+// get16 读取大端 16 位通道
+func get16(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+// put16 写入大端 16 位通道
+func put16(b []byte, v uint16) {
+	b[0], b[1] = byte(v>>8), byte(v)
+}
+
+// clamp16 四舍五入并限制在 0–65535
+func clamp16(v float64) uint16 {
+	return uint16(math.Max(0, math.Min(math.Round(v), 65535)))
+}
@@ -0,0 +1,71 @@
+package pixel_test
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"moviepy-go/pkg/pixel"
+)
+
+// simdLengths 覆盖空行、不足一个向量、向量整数倍和带尾部余数的像素数
+var simdLengths = []int{0, 1, 3, 7, 8, 9, 15, 16, 17, 31, 33, 63, 65, 257}
+
+// randomPixels 生成 n 个随机像素，opaque 时 alpha 全为 255
+func randomPixels(rng *rand.Rand, n int, opaque bool) []byte {
+	buf := make([]byte, n*4)
+	rng.Read(buf)
+	if opaque {
+		for i := 3; i < len(buf); i += 4 {
+			buf[i] = 255
+		}
+	}
+	return buf
+}
+
+// compareSIMD 分别用 SIMD 和可移植实现运行 run，结果须逐字节一致
+func compareSIMD(t *testing.T, name string, dst, src []byte, run func(dst, src []byte)) {
+	t.Helper()
+	generic := bytes.Clone(dst)
+	pixel.SetSIMD(false)
+	run(generic, src)
+
+	simd := bytes.Clone(dst)
+	pixel.SetSIMD(true)
+	run(simd, src)
+
+	if !bytes.Equal(simd, generic) {
+		for i := range simd {
+			if simd[i] != generic[i] {
+				t.Fatalf("%s（%d 像素）: 第 %d 字节 SIMD 为 %d，可移植实现为 %d", name, len(src)/4, i, simd[i], generic[i])
+			}
+		}
+	}
+}
+
+func TestSIMDMatchesGeneric(t *testing.T) {
+	if !pixel.SetSIMD(true) {
+		t.Skip("CPU 不支持 SIMD 内核")
+	}
+	defer pixel.SetSIMD(true)
+
+	rng := rand.New(rand.NewSource(1))
+	factors := []float64{0, 0.3, 1, 1.7, 4, 63.9, 100}
+	for _, n := range simdLengths {
+		for _, opaque := range []bool{true, false} {
+			src := randomPixels(rng, n, opaque)
+			dst := make([]byte, len(src))
+			for _, f := range factors {
+				compareSIMD(t, "Brightness", dst, src, func(dst, src []byte) { pixel.Brightness(dst, src, f) })
+				compareSIMD(t, "Contrast", dst, src, func(dst, src []byte) { pixel.Contrast(dst, src, f) })
+				compareSIMD(t, "Saturation", dst, src, func(dst, src []byte) { pixel.Saturation(dst, src, f) })
+				compareSIMD(t, "Saturation 负因子", dst, src, func(dst, src []byte) { pixel.Saturation(dst, src, -f) })
+			}
+
+			base := randomPixels(rng, n, true)
+			for mode := pixel.BlendNormal; mode <= pixel.BlendOverlay; mode++ {
+				compareSIMD(t, "Blend "+mode.String(), base, src, func(dst, src []byte) { pixel.Blend(mode, dst, src) })
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package presets
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// Preset 面向发布平台的导出预设
+type Preset struct {
+	Name        string
+	Description string
+	Width       int // 目标分辨率，纯音频预设为 0
+	Height      int
+	AudioOnly   bool
+	// Loudness 目标综合响度（LUFS），TruePeak 真峰值上限（dBTP），由 WriteOptions 转为 loudnorm 音频滤镜；
+	// Loudness 为 0 时不做响度标准化
+	Loudness    float64
+	TruePeak    float64
+	MaxDuration time.Duration // 平台时长上限，0 表示不限
+	Write       core.WriteOptions
+}
+
+// 内置预设，码率和响度参考各平台的上传建议
+var (
+	mutex   sync.RWMutex
+	catalog = map[string]*Preset{
+		"youtube-1080p": {
+			Name: "youtube-1080p", Description: "YouTube 1080p SDR",
+			Width: 1920, Height: 1080, Loudness: -14, TruePeak: -1,
+			Write: core.WriteOptions{Codec: "libx264", Bitrate: "8000k", FPS: 30, CRF: 18, AudioCodec: "aac", AudioBitrate: "384k"},
+		},
+		"youtube-4k": {
+			Name: "youtube-4k", Description: "YouTube 2160p SDR",
+			Width: 3840, Height: 2160, Loudness: -14, TruePeak: -1,
+			Write: core.WriteOptions{Codec: "libx264", Bitrate: "35000k", FPS: 30, CRF: 18, AudioCodec: "aac", AudioBitrate: "384k"},
+		},
+		"youtube-shorts": {
+			Name: "youtube-shorts", Description: "YouTube Shorts 竖屏",
+			Width: 1080, Height: 1920, Loudness: -14, TruePeak: -1, MaxDuration: 60 * time.Second,
+			Write: core.WriteOptions{Codec: "libx264", Bitrate: "8000k", FPS: 30, CRF: 20, AudioCodec: "aac", AudioBitrate: "192k"},
+		},
+		"instagram-reel": {
+			Name: "instagram-reel", Description: "Instagram Reels 竖屏",
+			Width: 1080, Height: 1920, Loudness: -14, TruePeak: -1, MaxDuration: 90 * time.Second,
+			Write: core.WriteOptions{Codec: "libx264", Bitrate: "5000k", FPS: 30, CRF: 20, AudioCodec: "aac", AudioBitrate: "128k"},
+		},
+		"instagram-feed": {
+			Name: "instagram-feed", Description: "Instagram 方形动态",
+			Width: 1080, Height: 1080, Loudness: -14, TruePeak: -1, MaxDuration: 60 * time.Second,
+			Write: core.WriteOptions{Codec: "libx264", Bitrate: "5000k", FPS: 30, CRF: 20, AudioCodec: "aac", AudioBitrate: "128k"},
+		},
+		"tiktok": {
+			Name: "tiktok", Description: "TikTok 竖屏",
+			Width: 1080, Height: 1920, Loudness: -14, TruePeak: -1, MaxDuration: 10 * time.Minute,
+			Write: core.WriteOptions{Codec: "libx264", Bitrate: "6000k", FPS: 30, CRF: 20, AudioCodec: "aac", AudioBitrate: "128k"},
+		},
+		"web-720p": {
+			Name: "web-720p", Description: "网页内嵌 720p，兼顾体积",
+			Width: 1280, Height: 720, Loudness: -16, TruePeak: -1,
+			Write: core.WriteOptions{Codec: "libx264", Bitrate: "2500k", FPS: 30, CRF: 23, AudioCodec: "aac", AudioBitrate: "128k"},
+		},
+		"podcast": {
+			Name: "podcast", Description: "播客音频（单声道语音）",
+			AudioOnly: true, Loudness: -16, TruePeak: -1,
+			Write: core.WriteOptions{AudioCodec: "libmp3lame", AudioBitrate: "128k"},
+		},
+	}
+)
+
+// Register 注册自定义预设，名称重复时 panic
+func Register(preset *Preset) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if preset == nil || preset.Name == "" {
+		panic("presets: 预设名称不能为空")
+	}
+	if _, exists := catalog[preset.Name]; exists {
+		panic("presets: 预设 " + preset.Name + " 重复注册")
+	}
+	copied := *preset
+	catalog[preset.Name] = &copied
+}
+
+// Get 按名称返回预设的副本，修改副本不影响目录
+func Get(name string) (*Preset, error) {
+	mutex.RLock()
+	preset, ok := catalog[name]
+	mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的导出预设: %s", name)
+	}
+	copied := *preset
+	return &copied, nil
+}
+
+// Names 返回所有预设名称（已排序）
+func Names() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteOptions 返回预设写入选项的副本，可在其上继续设置 Progress、Context 等
+//
+// 设置了 Loudness 时在 AudioFilter 末尾追加 LoudnessFilter，导出的音轨按平台目标响度标准化。
+func (p *Preset) WriteOptions() *core.WriteOptions {
+	options := p.Write
+	if filter := p.LoudnessFilter(); filter != "" {
+		if options.AudioFilter != "" {
+			filter = options.AudioFilter + "," + filter
+		}
+		options.AudioFilter = filter
+	}
+	return &options
+}
+
+// LoudnessFilter 返回按 Loudness/TruePeak 标准化响度的 loudnorm 滤镜，未设置 Loudness 时为空
+func (p *Preset) LoudnessFilter() string {
+	if p.Loudness == 0 {
+		return ""
+	}
+	filter := fmt.Sprintf("loudnorm=I=%g", p.Loudness)
+	if p.TruePeak != 0 {
+		filter += fmt.Sprintf(":TP=%g", p.TruePeak)
+	}
+	return filter
+}
+
+// AspectRatio 目标宽高比，纯音频预设为 0
+func (p *Preset) AspectRatio() float64 {
+	if p.Height == 0 {
+		return 0
+	}
+	return float64(p.Width) / float64(p.Height)
+}
+
+// Check 检查剪辑是否符合预设，返回可读的警告（不阻止导出）
+func (p *Preset) Check(clip core.Clip) []string {
+	var warnings []string
+	if p.MaxDuration > 0 && clip.Duration() > p.MaxDuration {
+		warnings = append(warnings, fmt.Sprintf("时长 %v 超过 %s 的上限 %v", clip.Duration(), p.Name, p.MaxDuration))
+	}
+
+	videoClip, isVideo := clip.(core.VideoClip)
+	if p.AudioOnly || !isVideo {
+		if !p.AudioOnly {
+			warnings = append(warnings, fmt.Sprintf("%s 需要视频剪辑", p.Name))
+		}
+		return warnings
+	}
+
+	width, height := videoClip.Size()
+	if height > 0 {
+		aspect := float64(width) / float64(height)
+		if math.Abs(aspect-p.AspectRatio())/p.AspectRatio() > 0.01 {
+			warnings = append(warnings, fmt.Sprintf("宽高比 %dx%d 与 %s 的 %dx%d 不一致，导出后会出现黑边或需要裁剪",
+				width, height, p.Name, p.Width, p.Height))
+		}
+	}
+	if width < p.Width || height < p.Height {
+		warnings = append(warnings, fmt.Sprintf("分辨率 %dx%d 低于 %s 的 %dx%d，放大会降低清晰度",
+			width, height, p.Name, p.Width, p.Height))
+	}
+	if fps := clip.FPS(); fps > 0 && p.Write.FPS > 0 && fps < p.Write.FPS-0.01 {
+		warnings = append(warnings, fmt.Sprintf("帧率 %.3f 低于 %s 的 %.3f，会产生重复帧", fps, p.Name, p.Write.FPS))
+	}
+	return warnings
+}
+
+// Fit 将视频剪辑等比缩放到预设分辨率以内（尺寸取偶数），已符合时原样返回
+func (p *Preset) Fit(clip core.VideoClip) (core.VideoClip, error) {
+	if p.AudioOnly || p.Width == 0 || p.Height == 0 {
+		return clip, nil
+	}
+	width, height := clip.Size()
+	if width == p.Width && height == p.Height {
+		return clip, nil
+	}
+	scale := math.Min(float64(p.Width)/float64(width), float64(p.Height)/float64(height))
+	targetWidth := int(math.Round(float64(width)*scale)) &^ 1
+	targetHeight := int(math.Round(float64(height)*scale)) &^ 1
+	return clip.Resize(targetWidth, targetHeight)
+}
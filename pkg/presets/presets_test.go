@@ -0,0 +1,36 @@
+package presets
+
+import (
+	"testing"
+
+	"moviepy-go/pkg/core"
+)
+
+func TestWriteOptionsAppliesLoudness(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset Preset
+		want   string
+	}{
+		{"响度和真峰值", Preset{Loudness: -14, TruePeak: -1}, "loudnorm=I=-14:TP=-1"},
+		{"只有响度", Preset{Loudness: -16}, "loudnorm=I=-16"},
+		{"未设置响度", Preset{TruePeak: -1}, ""},
+		{"追加在已有滤镜之后", Preset{Loudness: -23, TruePeak: -2, Write: core.WriteOptions{AudioFilter: "highpass=f=80"}}, "highpass=f=80,loudnorm=I=-23:TP=-2"},
+	}
+	for _, tt := range tests {
+		if got := tt.preset.WriteOptions().AudioFilter; got != tt.want {
+			t.Errorf("%s: AudioFilter = %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+
+	// 内置预设都带有响度目标
+	for _, name := range Names() {
+		preset, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if preset.WriteOptions().AudioFilter == "" {
+			t.Errorf("%s 的写入选项缺少响度标准化", name)
+		}
+	}
+}
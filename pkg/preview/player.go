@@ -0,0 +1,238 @@
+package preview
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// PlayMode 预览播放方式
+type PlayMode int
+
+const (
+	// PlayPipe 逐帧渲染并通过管道实时送入 ffplay
+	PlayPipe PlayMode = iota
+	// PlayProxy 先快速编码低分辨率代理文件再用 ffplay 打开
+	PlayProxy
+)
+
+// PlayOptions ffplay 预览选项
+type PlayOptions struct {
+	Mode      PlayMode
+	FPS       float64 // 预览帧率，0 表示使用剪辑帧率
+	MaxWidth  int     // 预览最大宽度，默认 640
+	Audio     bool    // 是否同时播放音频（仅 PlayPipe，剪辑需提供 Audio() 访问器）
+	Player    string  // 播放器可执行文件，默认 ffplay
+	ProxyFile string  // 代理文件路径，默认写入临时目录
+}
+
+// Play 使用 ffplay 预览剪辑，阻塞直到播放结束或播放器被关闭
+func Play(clip core.VideoClip, options *PlayOptions) error {
+	if options == nil {
+		options = &PlayOptions{}
+	}
+	if options.FPS == 0 {
+		options.FPS = clip.FPS()
+	}
+	if options.FPS <= 0 {
+		options.FPS = 25.0
+	}
+	if options.MaxWidth == 0 {
+		options.MaxWidth = 640
+	}
+	if options.Player == "" {
+		options.Player = "ffplay"
+	}
+
+	var resize *effects.ResizeEffect
+	width, height := clip.Width(), clip.Height()
+	if width > options.MaxWidth {
+		resize = effects.NewResizeEffect(options.MaxWidth, height*options.MaxWidth/width)
+		width, height = resize.Size()
+	}
+
+	switch options.Mode {
+	case PlayProxy:
+		return playProxy(clip, resize, width, height, options)
+	default:
+		return playPipe(clip, resize, width, height, options)
+	}
+}
+
+// playPipe 将原始 RGB 帧通过 stdin 送入 ffplay
+func playPipe(clip core.VideoClip, resize *effects.ResizeEffect, width, height int, options *PlayOptions) error {
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-autoexit",
+		"-window_title", "MovieGo 预览",
+		"-f", "rawvideo",
+		"-pixel_format", "rgb24",
+		"-video_size", fmt.Sprintf("%dx%d", width, height),
+		"-framerate", strconv.FormatFloat(options.FPS, 'f', -1, 64),
+		"-i", "-",
+	}
+
+	cmd := exec.Command(options.Player, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("设置输入管道失败: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 %s 失败: %w", options.Player, err)
+	}
+
+	var audioCmd *exec.Cmd
+	if options.Audio {
+		if src, ok := clip.(core.AudioSource); ok && src.Audio() != nil {
+			audioCmd, err = startAudioPipe(src.Audio(), clip.Duration(), options.Player)
+			if err != nil {
+				stdin.Close()
+				cmd.Process.Kill()
+				cmd.Wait()
+				return err
+			}
+		}
+	}
+
+	writeErr := pipeFrames(stdin, clip, resize, width, height, options.FPS)
+	stdin.Close()
+
+	waitErr := cmd.Wait()
+	if audioCmd != nil {
+		audioCmd.Process.Kill()
+		audioCmd.Wait()
+	}
+
+	// 用户主动关闭播放窗口会导致管道断开，播放器正常退出时不视为错误
+	if writeErr != nil && waitErr != nil {
+		return writeErr
+	}
+	return nil
+}
+
+// pipeFrames 按帧率逐帧渲染并写入管道
+func pipeFrames(w io.Writer, clip core.VideoClip, resize *effects.ResizeEffect, width, height int, fps float64) error {
+	totalFrames := core.FrameCount(clip.Duration(), fps)
+	buf := make([]byte, width*height*3)
+
+	for i := 0; i < totalFrames; i++ {
+		t := core.FrameTime(i, fps)
+		frame, err := clip.GetFrame(t)
+		if err != nil {
+			return fmt.Errorf("获取第 %d 帧失败: %w", i, err)
+		}
+		if resize != nil {
+			if frame, err = resize.ApplyToFrame(frame); err != nil {
+				return fmt.Errorf("缩放第 %d 帧失败: %w", i, err)
+			}
+		}
+
+		ffmpeg.EncodeFrame(buf, frame, ffmpeg.PixelFormatRGB24, width, height)
+		if _, err := w.Write(buf); err != nil {
+			return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// startAudioPipe 启动一个无窗口的 ffplay 播放音频样本
+func startAudioPipe(audio core.AudioClip, duration time.Duration, player string) (*exec.Cmd, error) {
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-nodisp",
+		"-autoexit",
+		"-f", "f32le",
+		"-ar", strconv.Itoa(audio.SampleRate()),
+		"-ac", strconv.Itoa(audio.Channels()),
+		"-i", "-",
+	}
+
+	cmd := exec.Command(player, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("设置音频输入管道失败: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动音频播放失败: %w", err)
+	}
+
+	go func() {
+		defer stdin.Close()
+		for t := time.Duration(0); t < duration; {
+			samples, err := audio.GetAudioFrame(t)
+			if err != nil || len(samples) == 0 {
+				return
+			}
+			if _, err := stdin.Write(ffmpeg.EncodeFloat32LE(samples)); err != nil {
+				return
+			}
+			frames := len(samples) / audio.Channels()
+			t += time.Duration(float64(frames) / float64(audio.SampleRate()) * float64(time.Second))
+		}
+	}()
+
+	return cmd, nil
+}
+
+// playProxy 编码低分辨率代理文件后播放
+func playProxy(clip core.VideoClip, resize *effects.ResizeEffect, width, height int, options *PlayOptions) error {
+	// 代理文件随进程管理器关闭删除
+	processMgr := ffmpeg.NewProcessManager()
+	defer processMgr.Close()
+
+	proxyFile := options.ProxyFile
+	if proxyFile == "" {
+		path, err := processMgr.Temp().Path("proxy.mp4")
+		if err != nil {
+			return err
+		}
+		proxyFile = path
+	}
+
+	writer := ffmpeg.NewVideoWriter(proxyFile, width, height, &ffmpeg.VideoWriterOptions{
+		Codec:   "libx264",
+		Bitrate: "800k",
+		FPS:     options.FPS,
+		Preset:  "ultrafast",
+	}, processMgr)
+	if err := writer.Open(); err != nil {
+		return fmt.Errorf("打开代理写入器失败: %w", err)
+	}
+
+	totalFrames := core.FrameCount(clip.Duration(), options.FPS)
+	for i := 0; i < totalFrames; i++ {
+		t := core.FrameTime(i, options.FPS)
+		frame, err := clip.GetFrame(t)
+		if err != nil {
+			writer.Abort()
+			return fmt.Errorf("获取第 %d 帧失败: %w", i, err)
+		}
+		if resize != nil {
+			if frame, err = resize.ApplyToFrame(frame); err != nil {
+				writer.Abort()
+				return fmt.Errorf("缩放第 %d 帧失败: %w", i, err)
+			}
+		}
+		if err := writer.WriteFrame(frame); err != nil {
+			writer.Abort()
+			return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("关闭代理写入器失败: %w", err)
+	}
+
+	cmd := exec.Command(options.Player, "-hide_banner", "-loglevel", "error", "-autoexit", proxyFile)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s 播放失败: %w", options.Player, err)
+	}
+	return nil
+}
@@ -0,0 +1,424 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+)
+
+// Options 预览服务器选项
+type Options struct {
+	Addr     string  // 监听地址，默认 127.0.0.1:8080
+	FPS      float64 // 预览帧率，0 表示使用剪辑帧率
+	MaxWidth int     // 预览最大宽度，超过时按比例缩小，默认 640
+	Quality  int     // JPEG 质量 1-100，默认 70
+	Loop     bool    // 播放到结尾后是否从头循环
+}
+
+// Status 播放状态
+type Status struct {
+	Playing  bool    `json:"playing"`
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+}
+
+// Server 通过 HTTP 提供 MJPEG 预览流的服务器
+type Server struct {
+	clip     core.VideoClip
+	options  *Options
+	resize   *effects.ResizeEffect
+	listener net.Listener
+	server   *http.Server
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mutex       sync.RWMutex
+	playing     bool
+	position    time.Duration
+	latest      []byte
+	subscribers map[chan []byte]struct{}
+	closed      bool
+}
+
+const boundary = "moviegoframe"
+
+// NewServer 创建新的预览服务器
+func NewServer(clip core.VideoClip, options *Options) *Server {
+	if options == nil {
+		options = &Options{}
+	}
+	if options.Addr == "" {
+		options.Addr = "127.0.0.1:8080"
+	}
+	if options.FPS == 0 {
+		options.FPS = clip.FPS()
+	}
+	if options.FPS <= 0 {
+		options.FPS = 25.0
+	}
+	if options.MaxWidth == 0 {
+		options.MaxWidth = 640
+	}
+	if options.Quality == 0 {
+		options.Quality = 70
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		clip:        clip,
+		options:     options,
+		ctx:         ctx,
+		cancel:      cancel,
+		subscribers: make(map[chan []byte]struct{}),
+	}
+
+	// 超过最大宽度时按比例缩小
+	if clip.Width() > options.MaxWidth {
+		height := clip.Height() * options.MaxWidth / clip.Width()
+		s.resize = effects.NewResizeEffect(options.MaxWidth, height)
+	}
+
+	return s
+}
+
+// Start 开始监听并在后台播放，非阻塞
+func (s *Server) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("预览服务器已关闭")
+	}
+	if s.listener != nil {
+		return fmt.Errorf("预览服务器已启动")
+	}
+
+	listener, err := net.Listen("tcp", s.options.Addr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", s.options.Addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/stream", s.handleStream)
+	mux.HandleFunc("/frame.jpg", s.handleFrame)
+	mux.HandleFunc("/play", s.handlePlay)
+	mux.HandleFunc("/pause", s.handlePause)
+	mux.HandleFunc("/seek", s.handleSeek)
+	mux.HandleFunc("/status", s.handleStatus)
+
+	s.listener = listener
+	s.server = &http.Server{Handler: mux}
+	s.playing = true
+
+	go s.server.Serve(listener)
+	go s.playbackRoutine()
+
+	return nil
+}
+
+// Addr 返回实际监听地址
+func (s *Server) Addr() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.listener == nil {
+		return s.options.Addr
+	}
+	return s.listener.Addr().String()
+}
+
+// URL 返回预览页面地址
+func (s *Server) URL() string {
+	return "http://" + s.Addr() + "/"
+}
+
+// Play 开始播放
+func (s *Server) Play() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.playing = true
+}
+
+// Pause 暂停播放
+func (s *Server) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.playing = false
+}
+
+// Seek 跳转到指定时间
+func (s *Server) Seek(t time.Duration) error {
+	if t < 0 || t > s.clip.Duration() {
+		return core.ErrInvalidTimeRange
+	}
+	s.mutex.Lock()
+	s.position = t
+	s.mutex.Unlock()
+
+	// 暂停状态下也立即刷新当前帧
+	s.renderAt(t)
+	return nil
+}
+
+// Status 返回当前播放状态
+func (s *Server) Status() Status {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return Status{
+		Playing:  s.playing,
+		Position: s.position.Seconds(),
+		Duration: s.clip.Duration().Seconds(),
+	}
+}
+
+// Close 停止服务器
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	for ch := range s.subscribers {
+		close(ch)
+		delete(s.subscribers, ch)
+	}
+	server := s.server
+	s.mutex.Unlock()
+
+	s.cancel()
+	if server != nil {
+		return server.Close()
+	}
+	return nil
+}
+
+// playbackRoutine 按预览帧率推进播放位置并渲染帧
+func (s *Server) playbackRoutine() {
+	interval := time.Duration(float64(time.Second) / s.options.FPS)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.renderAt(0)
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.mutex.Lock()
+			if !s.playing {
+				s.mutex.Unlock()
+				continue
+			}
+			t := s.position + interval
+			if t >= s.clip.Duration() {
+				if s.options.Loop {
+					t = 0
+				} else {
+					t = s.clip.Duration() - interval
+					s.playing = false
+				}
+			}
+			s.position = t
+			s.mutex.Unlock()
+
+			s.renderAt(t)
+		}
+	}
+}
+
+// renderAt 渲染指定时间的帧并推送给所有订阅者
+func (s *Server) renderAt(t time.Duration) {
+	frame, err := s.clip.GetFrame(t)
+	if err != nil {
+		return
+	}
+
+	data, err := s.encode(frame)
+	if err != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.latest = data
+	for ch := range s.subscribers {
+		// 慢速客户端直接丢帧，不阻塞播放
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// encode 缩放并编码为 JPEG
+func (s *Server) encode(frame image.Image) ([]byte, error) {
+	if s.resize != nil {
+		resized, err := s.resize.ApplyToFrame(frame)
+		if err != nil {
+			return nil, err
+		}
+		frame = resized
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, frame, &jpeg.Options{Quality: s.options.Quality}); err != nil {
+		return nil, fmt.Errorf("编码 JPEG 失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// handleStream 输出 multipart/x-mixed-replace MJPEG 流
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	ch := make(chan []byte, 1)
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		http.Error(w, "预览服务器已关闭", http.StatusServiceUnavailable)
+		return
+	}
+	s.subscribers[ch] = struct{}{}
+	latest := s.latest
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+		s.mutex.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+boundary)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	flusher, _ := w.(http.Flusher)
+	writePart := func(data []byte) error {
+		if _, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", boundary, len(data)); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte("\r\n")); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if latest != nil {
+		if err := writePart(latest); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := writePart(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleFrame 返回当前帧 JPEG
+func (s *Server) handleFrame(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	latest := s.latest
+	s.mutex.RUnlock()
+
+	if latest == nil {
+		http.Error(w, "暂无可用帧", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write(latest)
+}
+
+// handlePlay 处理播放请求
+func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
+	s.Play()
+	s.writeStatus(w)
+}
+
+// handlePause 处理暂停请求
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.Pause()
+	s.writeStatus(w)
+}
+
+// handleSeek 处理跳转请求，参数 t 为秒
+func (s *Server) handleSeek(w http.ResponseWriter, r *http.Request) {
+	seconds, err := strconv.ParseFloat(r.URL.Query().Get("t"), 64)
+	if err != nil {
+		http.Error(w, "无效的时间参数", http.StatusBadRequest)
+		return
+	}
+	if err := s.Seek(time.Duration(seconds * float64(time.Second))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeStatus(w)
+}
+
+// handleStatus 返回播放状态
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.writeStatus(w)
+}
+
+// writeStatus 以 JSON 输出播放状态
+func (s *Server) writeStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Status())
+}
+
+// handleIndex 返回简单的播放页面
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, indexHTML)
+}
+
+const indexHTML = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>MovieGo 预览</title></head>
+<body style="background:#222;color:#eee;font-family:sans-serif">
+<img src="/stream" style="max-width:100%"><br>
+<button onclick="fetch('/play')">播放</button>
+<button onclick="fetch('/pause')">暂停</button>
+<input id="t" type="number" step="0.1" value="0" style="width:6em">
+<button onclick="fetch('/seek?t='+document.getElementById('t').value)">跳转</button>
+<span id="s"></span>
+<script>
+setInterval(function(){fetch('/status').then(function(r){return r.json()}).then(function(j){
+document.getElementById('s').textContent=j.position.toFixed(2)+' / '+j.duration.toFixed(2)+(j.playing?' 播放中':' 已暂停')})},500);
+</script>
+</body>
+</html>
+`
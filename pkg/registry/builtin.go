@@ -0,0 +1,377 @@
+package registry
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/effects"
+)
+
+// 注册 effects 包中的内置视频特效，参数名与构造函数参数一致
+func init() {
+	RegisterVideoEffect("resize", func(p Params) (effects.VideoEffect, error) {
+		width, err := p.Int("width", 0)
+		if err != nil {
+			return nil, err
+		}
+		height, err := p.Int("height", 0)
+		if err != nil {
+			return nil, err
+		}
+		return effects.NewResizeEffect(width, height), nil
+	})
+	RegisterVideoEffect("rotate", func(p Params) (effects.VideoEffect, error) {
+		angle, err := p.Float("angle", 0)
+		if err != nil {
+			return nil, err
+		}
+		options := &effects.RotateOptions{}
+		if options.Exact, err = p.Bool("exact", false); err != nil {
+			return nil, err
+		}
+		sampling, err := p.String("sampling", "bilinear")
+		if err != nil {
+			return nil, err
+		}
+		if options.Sampling, err = effects.ParseSampling(sampling); err != nil {
+			return nil, err
+		}
+		if options.Supersample, err = p.Int("supersample", 1); err != nil {
+			return nil, err
+		}
+		background, err := p.String("background", "")
+		if err != nil {
+			return nil, err
+		}
+		if background != "" {
+			if options.Background, err = ParseColor(background); err != nil {
+				return nil, err
+			}
+		}
+		return effects.NewRotateEffectWithOptions(angle, options), nil
+	})
+	RegisterVideoEffect("crop", cropEffect)
+	RegisterVideoEffect("margin", marginEffect)
+	RegisterVideoEffect("rounded_corners", func(p Params) (effects.VideoEffect, error) {
+		radius, err := p.Int("radius", 32)
+		if err != nil {
+			return nil, err
+		}
+		return effects.NewRoundedCornersEffect(radius), nil
+	})
+	RegisterVideoEffect("circle_mask", func(Params) (effects.VideoEffect, error) {
+		return effects.NewCircleMaskEffect(), nil
+	})
+	RegisterVideoEffect("brightness", func(p Params) (effects.VideoEffect, error) {
+		factor, err := p.Expr("factor", 1)
+		if err != nil {
+			return nil, err
+		}
+		if factor.IsConstant() {
+			return effects.NewBrightnessEffect(factor.At(0)), nil
+		}
+		return effects.NewAnimatedBrightnessEffect(factor.Func()), nil
+	})
+	RegisterVideoEffect("contrast", floatEffect("factor", 1, func(v float64) effects.VideoEffect {
+		return effects.NewContrastEffect(v)
+	}))
+	RegisterVideoEffect("blur", func(p Params) (effects.VideoEffect, error) {
+		radius, err := p.Int("radius", 2)
+		if err != nil {
+			return nil, err
+		}
+		return effects.NewBlurEffect(radius), nil
+	})
+	RegisterVideoEffect("sharpen", floatEffect("strength", 1, func(v float64) effects.VideoEffect {
+		return effects.NewSharpenEffect(v)
+	}))
+	RegisterVideoEffect("unsharp_mask", func(p Params) (effects.VideoEffect, error) {
+		amount, err := p.Float("amount", 1)
+		if err != nil {
+			return nil, err
+		}
+		radius, err := p.Float("radius", 1)
+		if err != nil {
+			return nil, err
+		}
+		threshold, err := p.Float("threshold", 0)
+		if err != nil {
+			return nil, err
+		}
+		return effects.NewUnsharpMaskEffect(amount, radius, threshold), nil
+	})
+	RegisterVideoEffect("saturation", floatEffect("factor", 1, func(v float64) effects.VideoEffect {
+		return effects.NewSaturationEffect(v)
+	}))
+	RegisterVideoEffect("noise", floatEffect("intensity", 0.1, func(v float64) effects.VideoEffect {
+		return effects.NewNoiseEffect(v)
+	}))
+	RegisterVideoEffect("sepia", floatEffect("strength", 1, func(v float64) effects.VideoEffect {
+		return effects.NewSepiaEffect(v)
+	}))
+	RegisterVideoEffect("vignette", func(p Params) (effects.VideoEffect, error) {
+		strength, err := p.Float("strength", 0.5)
+		if err != nil {
+			return nil, err
+		}
+		radius, err := p.Float("radius", 0.8)
+		if err != nil {
+			return nil, err
+		}
+		return effects.NewVignetteEffect(strength, radius), nil
+	})
+	RegisterVideoEffect("pixelate", func(p Params) (effects.VideoEffect, error) {
+		blockSize, err := p.Int("block_size", 16)
+		if err != nil {
+			return nil, err
+		}
+		return effects.NewPixelateEffect(blockSize), nil
+	})
+	RegisterVideoEffect("region", regionEffect)
+	RegisterVideoEffect("zoom_callout", zoomCalloutEffect)
+	RegisterVideoEffect("lut", func(p Params) (effects.VideoEffect, error) {
+		path, err := p.String("path", "")
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return nil, fmt.Errorf("lut 特效缺少 path 参数")
+		}
+		intensity, err := p.Float("intensity", 1)
+		if err != nil {
+			return nil, err
+		}
+		lut, err := effects.LoadCubeLUT(path)
+		if err != nil {
+			return nil, err
+		}
+		return effects.NewLUTEffect(lut, intensity), nil
+	})
+
+	// 预设不接受参数
+	for name, preset := range map[string]func() *effects.EffectChain{
+		"vintage":   effects.Vintage,
+		"cinematic": effects.Cinematic,
+		"warm":      effects.Warm,
+		"cool":      effects.Cool,
+		"dramatic":  effects.Dramatic,
+	} {
+		preset := preset
+		RegisterVideoEffect(name, func(Params) (effects.VideoEffect, error) {
+			return preset(), nil
+		})
+	}
+}
+
+// floatEffect 只有一个浮点参数的特效工厂
+func floatEffect(key string, def float64, build func(v float64) effects.VideoEffect) VideoEffectFactory {
+	return func(p Params) (effects.VideoEffect, error) {
+		v, err := p.Float(key, def)
+		if err != nil {
+			return nil, err
+		}
+		return build(v), nil
+	}
+}
+
+// cropEffect 按 region（命名区域）、relative（x/y/width/height 为 0–1 的比例）、
+// center（居中裁剪 width×height）或像素坐标创建裁剪特效，宽高必须为正
+func cropEffect(p Params) (effects.VideoEffect, error) {
+	region, err := p.String("region", "")
+	if err != nil {
+		return nil, err
+	}
+	if region != "" {
+		r, err := effects.ParseCropRegion(region)
+		if err != nil {
+			return nil, err
+		}
+		return effects.NewRegionCropEffect(r), nil
+	}
+	relative, err := p.Bool("relative", false)
+	if err != nil {
+		return nil, err
+	}
+	if relative {
+		var values [4]float64
+		for i, key := range []string{"x", "y", "width", "height"} {
+			def := 0.0
+			if i >= 2 {
+				def = 1
+			}
+			if values[i], err = p.Float(key, def); err != nil {
+				return nil, err
+			}
+			if values[i] < 0 || values[i] > 1 {
+				return nil, fmt.Errorf("crop 特效的相对 %s 超出 0–1: %g", key, values[i])
+			}
+		}
+		if values[2] == 0 || values[3] == 0 {
+			return nil, fmt.Errorf("crop 特效的相对宽高必须为正: %gx%g", values[2], values[3])
+		}
+		return effects.NewRelativeCropEffect(values[0], values[1], values[2], values[3]), nil
+	}
+
+	var values [4]int
+	for i, key := range []string{"x", "y", "width", "height"} {
+		if values[i], err = p.Int(key, 0); err != nil {
+			return nil, err
+		}
+	}
+	if values[2] <= 0 || values[3] <= 0 {
+		return nil, fmt.Errorf("crop 特效的宽高必须为正: %dx%d", values[2], values[3])
+	}
+	center, err := p.Bool("center", false)
+	if err != nil {
+		return nil, err
+	}
+	if center {
+		return effects.NewCenterCropEffect(values[2], values[3]), nil
+	}
+	return effects.NewCropEffect(values[0], values[1], values[2], values[3]), nil
+}
+
+// marginEffect 边距特效工厂：margin 为四边的默认宽度，top/right/bottom/left 单独覆盖，
+// color 为 "#RRGGBB"、"#RRGGBBAA" 或 "transparent"，默认黑色
+func marginEffect(p Params) (effects.VideoEffect, error) {
+	all, err := p.Int("margin", 0)
+	if err != nil {
+		return nil, err
+	}
+	var sides [4]int
+	for i, key := range []string{"top", "right", "bottom", "left"} {
+		v, err := p.Int(key, all)
+		if err != nil {
+			return nil, err
+		}
+		sides[i] = v
+	}
+	name, err := p.String("color", "#000000")
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseColor(name)
+	if err != nil {
+		return nil, err
+	}
+	return effects.NewMarginEffect(sides[0], sides[1], sides[2], sides[3], c), nil
+}
+
+// ParseColor 解析 "#RRGGBB"、"#RRGGBBAA" 或 "transparent"
+func ParseColor(s string) (color.Color, error) {
+	if strings.EqualFold(s, "transparent") {
+		return color.Transparent, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return nil, fmt.Errorf("无效的颜色: %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("无效的颜色: %q", s)
+	}
+	if len(hex) == 6 {
+		v = v<<8 | 0xff
+	}
+	// 半透明颜色按非预乘理解，由 draw 转换
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// regionEffect 区域特效工厂：x/y/width/height 可为表达式以移动区域，
+// effect 为内部特效名称（默认 pixelate），其余参数传给内部特效
+func regionEffect(p Params) (effects.VideoEffect, error) {
+	keys := []string{"x", "y", "width", "height"}
+	var rect [4]func(t time.Duration) float64
+	for i, key := range keys {
+		e, err := p.Expr(key, 0)
+		if err != nil {
+			return nil, err
+		}
+		rect[i] = e.Func()
+	}
+	name, err := p.String("effect", "pixelate")
+	if err != nil {
+		return nil, err
+	}
+
+	innerParams := Params{}
+	for key, value := range p {
+		switch key {
+		case "x", "y", "width", "height", "effect":
+		default:
+			innerParams[key] = value
+		}
+	}
+	inner, err := NewVideoEffect(name, innerParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return effects.NewAnimatedRegionEffect(func(t time.Duration) image.Rectangle {
+		x, y := int(rect[0](t)), int(rect[1](t))
+		return image.Rect(x, y, x+int(rect[2](t)), y+int(rect[3](t)))
+	}, inner), nil
+}
+
+// zoomCalloutEffect 放大镜插图特效工厂：x/y/width/height 为被放大的区域，start/end 为秒，
+// center_x/center_y 为插图中心（省略时自动放置），transition 为展开时长（秒），
+// border_color、border_width 和 lines 控制边框与连接线
+func zoomCalloutEffect(p Params) (effects.VideoEffect, error) {
+	var values [6]int
+	for i, key := range []string{"x", "y", "width", "height", "center_x", "center_y"} {
+		v, err := p.Int(key, 0)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	var seconds [3]float64
+	for i, key := range []string{"start", "end", "transition"} {
+		v, err := p.Float(key, 0)
+		if err != nil {
+			return nil, err
+		}
+		seconds[i] = v
+	}
+	if seconds[1] <= seconds[0] {
+		return nil, fmt.Errorf("zoom_callout 特效的 end 必须大于 start")
+	}
+	zoom, err := p.Float("zoom", 2)
+	if err != nil {
+		return nil, err
+	}
+	borderWidth, err := p.Int("border_width", 2)
+	if err != nil {
+		return nil, err
+	}
+	name, err := p.String("border_color", "#FFFFFF")
+	if err != nil {
+		return nil, err
+	}
+	borderColor, err := ParseColor(name)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := p.Bool("lines", false)
+	if err != nil {
+		return nil, err
+	}
+	if borderWidth == 0 {
+		// 选项中 0 表示默认宽度
+		borderWidth = -1
+	}
+
+	toDuration := func(v float64) time.Duration { return time.Duration(v * float64(time.Second)) }
+	region := image.Rect(values[0], values[1], values[0]+values[2], values[1]+values[3])
+	return effects.NewZoomCalloutEffectWithOptions(region, zoom, toDuration(seconds[0]), toDuration(seconds[1]), &effects.ZoomCalloutOptions{
+		Center:      image.Pt(values[4], values[5]),
+		Transition:  toDuration(seconds[2]),
+		BorderColor: borderColor,
+		BorderWidth: borderWidth,
+		Lines:       lines,
+	}), nil
+}
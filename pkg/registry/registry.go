@@ -0,0 +1,279 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/expr"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+// Params 特效参数，通常来自工程文件（JSON 值）或 CLI（字符串）
+type Params map[string]interface{}
+
+// VideoEffectFactory 按参数创建视频特效
+type VideoEffectFactory func(params Params) (effects.VideoEffect, error)
+
+// AudioEffectFactory 按参数创建音频特效
+type AudioEffectFactory func(params Params) (effects.AudioEffect, error)
+
+// SourceOpener 打开 uri 指向的剪辑，uri 包含完整的 scheme（如 "s3://bucket/key"）
+type SourceOpener func(uri string, processMgr *ffmpeg.ProcessManager) (core.Clip, error)
+
+// 全局注册表，外部包通常在 init 中注册
+var (
+	mutex        sync.RWMutex
+	videoEffects = map[string]VideoEffectFactory{}
+	audioEffects = map[string]AudioEffectFactory{}
+	sources      = map[string]SourceOpener{}
+)
+
+// RegisterVideoEffect 注册视频特效，名称重复时 panic（与 database/sql.Register 一致）
+func RegisterVideoEffect(name string, factory VideoEffectFactory) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if factory == nil {
+		panic("registry: 视频特效 " + name + " 的工厂函数为 nil")
+	}
+	if _, exists := videoEffects[name]; exists {
+		panic("registry: 视频特效 " + name + " 重复注册")
+	}
+	videoEffects[name] = factory
+}
+
+// RegisterAudioEffect 注册音频特效，名称重复时 panic
+func RegisterAudioEffect(name string, factory AudioEffectFactory) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if factory == nil {
+		panic("registry: 音频特效 " + name + " 的工厂函数为 nil")
+	}
+	if _, exists := audioEffects[name]; exists {
+		panic("registry: 音频特效 " + name + " 重复注册")
+	}
+	audioEffects[name] = factory
+}
+
+// RegisterSource 注册剪辑来源，scheme 不含 "://"（如 "s3"、"rtsp"）；名称重复时 panic
+func RegisterSource(scheme string, opener SourceOpener) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	scheme = strings.ToLower(scheme)
+	if opener == nil {
+		panic("registry: 来源 " + scheme + " 的打开函数为 nil")
+	}
+	if _, exists := sources[scheme]; exists {
+		panic("registry: 来源 " + scheme + " 重复注册")
+	}
+	sources[scheme] = opener
+}
+
+// NewVideoEffect 按名称创建已注册的视频特效
+func NewVideoEffect(name string, params Params) (effects.VideoEffect, error) {
+	mutex.RLock()
+	factory, ok := videoEffects[name]
+	mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: 视频特效 %s", core.ErrUnknownPlugin, name)
+	}
+	effect, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("创建视频特效 %s 失败: %w", name, err)
+	}
+	return effect, nil
+}
+
+// NewAudioEffect 按名称创建已注册的音频特效
+func NewAudioEffect(name string, params Params) (effects.AudioEffect, error) {
+	mutex.RLock()
+	factory, ok := audioEffects[name]
+	mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: 音频特效 %s", core.ErrUnknownPlugin, name)
+	}
+	effect, err := factory(params)
+	if err != nil {
+		return nil, fmt.Errorf("创建音频特效 %s 失败: %w", name, err)
+	}
+	return effect, nil
+}
+
+// Open 打开剪辑：带 scheme 的 uri 交给注册的来源，本地路径和 file:// 打开为 VideoFileClip
+func Open(uri string, processMgr *ffmpeg.ProcessManager) (core.Clip, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok || strings.EqualFold(scheme, "file") {
+		if ok {
+			uri = rest
+		}
+		clip := video.NewVideoFileClip(uri, processMgr)
+		if err := clip.Open(); err != nil {
+			return nil, err
+		}
+		return clip, nil
+	}
+
+	mutex.RLock()
+	opener, found := sources[strings.ToLower(scheme)]
+	mutex.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("%w: 来源 %s://", core.ErrUnknownPlugin, scheme)
+	}
+	return opener(uri, processMgr)
+}
+
+// OpenVideo 与 Open 相同，但要求结果为视频剪辑
+func OpenVideo(uri string, processMgr *ffmpeg.ProcessManager) (core.VideoClip, error) {
+	clip, err := Open(uri, processMgr)
+	if err != nil {
+		return nil, err
+	}
+	videoClip, ok := clip.(core.VideoClip)
+	if !ok {
+		clip.Close()
+		return nil, fmt.Errorf("%s 不是视频剪辑", uri)
+	}
+	return videoClip, nil
+}
+
+// VideoEffects 返回已注册的视频特效名称（已排序）
+func VideoEffects() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return sortedKeys(videoEffects)
+}
+
+// AudioEffects 返回已注册的音频特效名称（已排序）
+func AudioEffects() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return sortedKeys(audioEffects)
+}
+
+// Sources 返回已注册的来源 scheme（已排序）
+func Sources() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return sortedKeys(sources)
+}
+
+// sortedKeys 返回 map 的有序键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseParams 解析 CLI 形式的参数 "radius=3,strength=0.5"
+func ParseParams(s string) (Params, error) {
+	params := Params{}
+	if strings.TrimSpace(s) == "" {
+		return params, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("无效的参数: %s", pair)
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	return params, nil
+}
+
+// Float 读取浮点参数，缺失时返回 def；接受数字和数字字符串
+func (p Params) Float(key string, def float64) (float64, error) {
+	value, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("参数 %s 不是数字: %s", key, v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("参数 %s 不是数字: %v", key, value)
+}
+
+// Int 读取整数参数，缺失时返回 def
+func (p Params) Int(key string, def int) (int, error) {
+	f, err := p.Float(key, float64(def))
+	if err != nil {
+		return 0, err
+	}
+	if f != float64(int(f)) {
+		return 0, fmt.Errorf("参数 %s 不是整数: %v", key, f)
+	}
+	return int(f), nil
+}
+
+// String 读取字符串参数，缺失时返回 def
+func (p Params) String(key string, def string) (string, error) {
+	value, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("参数 %s 不是字符串: %v", key, value)
+	}
+	return s, nil
+}
+
+// Bool 读取布尔参数，缺失时返回 def；接受 true/false 和 "true"/"false"
+func (p Params) Bool(key string, def bool) (bool, error) {
+	value, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("参数 %s 不是布尔值: %s", key, v)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("参数 %s 不是布尔值: %v", key, value)
+}
+
+// Expr 读取动画参数，缺失时返回常量 def；数字为常量，字符串按 expr.Parse 解析（如 "1+0.5*sin(t)"）
+func (p Params) Expr(key string, def float64) (*expr.Expr, error) {
+	value, ok := p[key]
+	if !ok {
+		return expr.Constant(def), nil
+	}
+	if s, ok := value.(string); ok {
+		e, err := expr.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("参数 %s: %w", key, err)
+		}
+		return e, nil
+	}
+	f, err := p.Float(key, def)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Constant(f), nil
+}
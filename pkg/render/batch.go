@@ -0,0 +1,93 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"moviepy-go/pkg/core"
+)
+
+// BatchRenderFunc 渲染一条记录，project 为已填充的工程文件
+type BatchRenderFunc func(ctx context.Context, index int, record Record, project []byte) error
+
+// BatchOptions 批量渲染选项
+type BatchOptions struct {
+	Workers         int  // 同时渲染的记录数，默认 1；每条记录自身还会启动 FFmpeg 进程
+	ContinueOnError bool // 某条记录失败后继续渲染其余记录，默认中止
+	// Progress 每条记录结束后调用，done 为已结束的记录数
+	Progress func(done, total int, index int, err error)
+}
+
+// RenderBatch 用每条记录填充工程模板并调用 render，用于批量生成个性化视频
+//
+// 填充失败（缺少字段）也计为该记录失败。返回的错误合并了全部失败记录，每项注明记录序号；
+// 默认在第一个失败后取消 ctx 并不再开始新的记录。
+func RenderBatch(ctx context.Context, project []byte, records []Record, options *BatchOptions, render BatchRenderFunc) error {
+	if options == nil {
+		options = &BatchOptions{}
+	}
+	workers := options.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	workers = min(workers, len(records))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		errs  []error
+		done  int
+	)
+	finish := func(index int, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		done++
+		if err != nil {
+			errs = append(errs, fmt.Errorf("记录 %d: %w", index, err))
+			if !options.ContinueOnError {
+				cancel()
+			}
+		}
+		if options.Progress != nil {
+			options.Progress(done, len(records), index, err)
+		}
+	}
+
+	indices := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				filled, err := FillTemplate(project, records[index])
+				if err == nil {
+					err = render(core.WithJobID(ctx, fmt.Sprintf("record-%d", index)), index, records[index], filled)
+				}
+				finish(index, err)
+			}
+		}()
+	}
+
+feed:
+	for index := range records {
+		select {
+		case indices <- index:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(errs) == 0 && done < len(records) {
+		return fmt.Errorf("批量渲染在 %d/%d 条记录后取消: %w", done, len(records), ctx.Err())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,245 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// Segment 分段渲染中的一个时间段
+type Segment struct {
+	Index    int
+	Start    time.Duration
+	End      time.Duration
+	Filename string
+}
+
+// ChunkedOptions 分段渲染选项
+type ChunkedOptions struct {
+	Segments    int                // 分段数量，默认 4
+	Concurrency int                // 本地并发数，默认与分段数相同
+	TempDir     string             // 分段文件目录，默认在 ffmpeg.TempDir() 或输出文件同目录下创建临时目录
+	TempQuota   int64              // 未指定 TempDir 时分段文件总大小上限（字节），0 表示不限
+	KeepParts   bool               // 完成后保留分段文件
+	Write       *core.WriteOptions // 每个分段使用的写入选项（Progress 不会被调用）
+	ProcessMgr  *ffmpeg.ProcessManager
+}
+
+// PlanSegments 将渲染窗口 [start, end) 切分为 n 段，边界对齐到从 start 起的帧间隔，pattern 为包含一个 %d 的文件名模板
+//
+// 多机渲染时，每台机器对自己的分段调用 RenderSegment，最后在一台机器上调用 ConcatSegments。
+func PlanSegments(start, end time.Duration, fps float64, n int, pattern string) ([]Segment, error) {
+	if start < 0 || end <= start {
+		return nil, core.ErrInvalidTimeRange
+	}
+	if fps <= 0 {
+		return nil, fmt.Errorf("无效的帧率: %f", fps)
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("无效的分段数量: %d", n)
+	}
+	if !strings.Contains(pattern, "%") {
+		return nil, fmt.Errorf("文件名模板需要包含 %%d: %s", pattern)
+	}
+
+	// 按整帧划分，避免分段边界处重复或丢帧
+	duration := end - start
+	totalFrames := core.FrameCount(duration, fps)
+	if totalFrames < n {
+		n = totalFrames
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("时长不足一帧")
+	}
+
+	segments := make([]Segment, n)
+	for i := 0; i < n; i++ {
+		startFrame := totalFrames * i / n
+		endFrame := totalFrames * (i + 1) / n
+		segmentEnd := core.FrameTime(endFrame, fps)
+		if i == n-1 || segmentEnd > duration {
+			segmentEnd = duration
+		}
+		segments[i] = Segment{
+			Index:    i,
+			Start:    start + core.FrameTime(startFrame, fps),
+			End:      start + segmentEnd,
+			Filename: fmt.Sprintf(pattern, i),
+		}
+	}
+	return segments, nil
+}
+
+// RenderSegment 渲染单个分段，渲染窗口已由 PlanSegments 计入分段，options 的 StartTime/EndTime 被忽略
+func RenderSegment(clip core.Clip, segment Segment, options *core.WriteOptions) error {
+	subclip, err := clip.Subclip(segment.Start, segment.End)
+	if err != nil {
+		return fmt.Errorf("创建分段 %d 子剪辑失败: %w", segment.Index, err)
+	}
+	defer subclip.Close()
+	if err := subclip.WriteToFile(segment.Filename, segmentOptions(options)); err != nil {
+		return fmt.Errorf("渲染分段 %d 失败: %w", segment.Index, err)
+	}
+	return nil
+}
+
+// segmentOptions 返回分段的写入选项：渲染窗口清零，避免在子剪辑上再次截取
+func segmentOptions(options *core.WriteOptions) *core.WriteOptions {
+	segment := core.WriteOptions{}
+	if options != nil {
+		segment = *options
+	}
+	segment.StartTime, segment.EndTime = 0, 0
+	return &segment
+}
+
+// ConcatSegments 使用 concat 分离器无损拼接分段文件
+func ConcatSegments(ctx context.Context, parts []string, output string, processMgr *ffmpeg.ProcessManager) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("没有可拼接的分段")
+	}
+
+	listName, err := processMgr.Temp().CreateFile("concat-*.txt")
+	if err != nil {
+		return fmt.Errorf("创建拼接列表失败: %w", err)
+	}
+	defer processMgr.Temp().Remove(listName)
+	if err := ffmpeg.WriteConcatList(listName, parts); err != nil {
+		return err
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", listName,
+		"-c", "copy",
+		"-y",
+		output,
+	}
+
+	process, err := processMgr.StartProcess(ctx, "ffmpeg", args, nil)
+	if err != nil {
+		return fmt.Errorf("启动拼接进程失败: %w", err)
+	}
+	if err := process.Wait(); err != nil {
+		return fmt.Errorf("拼接分段失败: %w", err)
+	}
+	return nil
+}
+
+// RenderChunked 将剪辑切分为多段并行渲染，再无损拼接为输出文件
+func RenderChunked(clip core.Clip, output string, options *ChunkedOptions) error {
+	if options == nil {
+		options = &ChunkedOptions{}
+	}
+	if options.Segments <= 0 {
+		options.Segments = 4
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = options.Segments
+	}
+
+	write := core.WriteOptions{}
+	if options.Write != nil {
+		write = *options.Write
+	}
+	ctx := write.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tempDir := options.TempDir
+	var temp *ffmpeg.TempManager
+	if tempDir == "" {
+		parent := ffmpeg.TempDir()
+		if parent == "" {
+			parent = filepath.Dir(output)
+		}
+		temp = ffmpeg.NewTempManager(&ffmpeg.TempManagerOptions{Dir: parent, Quota: options.TempQuota})
+		if !options.KeepParts {
+			defer temp.Close()
+			defer temp.CleanupOnPanic()
+		}
+		dir, err := temp.Dir()
+		if err != nil {
+			return fmt.Errorf("创建分段目录失败: %w", err)
+		}
+		tempDir = dir
+	} else if !options.KeepParts {
+		defer os.RemoveAll(tempDir)
+	}
+
+	fps := write.FPS
+	if fps == 0 {
+		fps = clip.FPS()
+	}
+	start, end, err := core.RenderWindow(&write, clip.Duration())
+	if err != nil {
+		return err
+	}
+	pattern := filepath.Join(tempDir, "part-%04d"+filepath.Ext(output))
+	segments, err := PlanSegments(start, end, fps, options.Segments, pattern)
+	if err != nil {
+		return err
+	}
+
+	// 子剪辑在队列关闭、所有分段结束后释放
+	var subclips []core.Clip
+	defer func() {
+		for _, subclip := range subclips {
+			subclip.Close()
+		}
+	}()
+
+	queue := NewQueue(&QueueOptions{
+		Concurrency: options.Concurrency,
+		ProcessMgr:  options.ProcessMgr,
+	})
+	defer queue.Close()
+
+	jobs := make([]*Job, len(segments))
+	for i, segment := range segments {
+		subclip, err := clip.Subclip(segment.Start, segment.End)
+		if err != nil {
+			return fmt.Errorf("创建分段 %d 子剪辑失败: %w", i, err)
+		}
+		subclips = append(subclips, subclip)
+		segmentOptions := segmentOptions(&write)
+		segmentOptions.Progress = nil
+		job, err := queue.Submit(subclip, segment.Filename, segmentOptions, 0)
+		if err != nil {
+			return err
+		}
+		jobs[i] = job
+	}
+
+	parts := make([]string, len(segments))
+	for i, job := range jobs {
+		if err := job.Wait(); err != nil {
+			// 任一分段失败即取消其余分段
+			for _, other := range jobs {
+				other.Cancel()
+			}
+			return fmt.Errorf("渲染分段 %d 失败: %w", i, err)
+		}
+		if temp != nil {
+			if err := temp.CheckQuota(); err != nil {
+				for _, other := range jobs {
+					other.Cancel()
+				}
+				return err
+			}
+		}
+		parts[i] = segments[i].Filename
+	}
+
+	return ConcatSegments(ctx, parts, output, queue.ProcessManager())
+}
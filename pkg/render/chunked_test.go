@@ -0,0 +1,176 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/core/coretest"
+)
+
+func TestPlanSegments(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name       string
+		start, end time.Duration
+		fps        float64
+		n          int
+		want       [][2]time.Duration
+	}{
+		{"均分", 0, time.Second, 10, 2, [][2]time.Duration{{0, 500 * ms}, {500 * ms, time.Second}}},
+		{"按帧取整", 0, time.Second, 10, 3, [][2]time.Duration{{0, 300 * ms}, {300 * ms, 600 * ms}, {600 * ms, time.Second}}},
+		{"渲染窗口偏移", 2 * time.Second, 3 * time.Second, 10, 2, [][2]time.Duration{{2 * time.Second, 2500 * ms}, {2500 * ms, 3 * time.Second}}},
+		{"末段取到窗口结尾", 0, 1050 * ms, 10, 2, [][2]time.Duration{{0, 500 * ms}, {500 * ms, 1050 * ms}}},
+		{"分段数多于帧数", 0, 300 * ms, 10, 8, [][2]time.Duration{{0, 100 * ms}, {100 * ms, 200 * ms}, {200 * ms, 300 * ms}}},
+	}
+	for _, tt := range tests {
+		segments, err := PlanSegments(tt.start, tt.end, tt.fps, tt.n, "part-%d.mp4")
+		if err != nil {
+			t.Errorf("%s: 规划失败: %v", tt.name, err)
+			continue
+		}
+		if len(segments) != len(tt.want) {
+			t.Errorf("%s: 得到 %d 段，期望 %d", tt.name, len(segments), len(tt.want))
+			continue
+		}
+		for i, segment := range segments {
+			if segment.Index != i || segment.Start != tt.want[i][0] || segment.End != tt.want[i][1] {
+				t.Errorf("%s: 第 %d 段为 #%d %v-%v，期望 %v-%v", tt.name, i, segment.Index, segment.Start, segment.End, tt.want[i][0], tt.want[i][1])
+			}
+			if want := fmt.Sprintf("part-%d.mp4", i); segment.Filename != want {
+				t.Errorf("%s: 第 %d 段文件名 %s，期望 %s", tt.name, i, segment.Filename, want)
+			}
+		}
+	}
+}
+
+func TestPlanSegmentsRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end time.Duration
+		fps        float64
+		n          int
+		pattern    string
+	}{
+		{"空窗口", time.Second, time.Second, 10, 2, "p%d"},
+		{"负起点", -time.Second, time.Second, 10, 2, "p%d"},
+		{"无效帧率", 0, time.Second, 0, 2, "p%d"},
+		{"无效分段数", 0, time.Second, 10, 0, "p%d"},
+		{"模板缺少 %d", 0, time.Second, 10, 2, "part.mp4"},
+	}
+	for _, tt := range tests {
+		if _, err := PlanSegments(tt.start, tt.end, tt.fps, tt.n, tt.pattern); err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+	}
+}
+
+// segmentWrite 一次分段写入时子剪辑的时间段和收到的渲染窗口
+type segmentWrite struct {
+	start, duration    time.Duration
+	startTime, endTime time.Duration
+}
+
+// segmentLog 记录未关闭的子剪辑数和各分段的写入
+type segmentLog struct {
+	mutex  sync.Mutex
+	open   int
+	writes []segmentWrite
+	failAt time.Duration // 从该时间开始的分段写入失败，负数表示不失败
+}
+
+// segmentedClip 记录子剪辑引用的模拟剪辑，WriteToFile 写入占位文件
+type segmentedClip struct {
+	*coretest.MockVideoClip
+	start time.Duration // 在原剪辑中的起始时间
+	log   *segmentLog
+}
+
+func (sc *segmentedClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	sub, err := sc.MockVideoClip.Subclip(start, end)
+	if err != nil {
+		return nil, err
+	}
+	sc.log.mutex.Lock()
+	sc.log.open++
+	sc.log.mutex.Unlock()
+	return &segmentedClip{MockVideoClip: sub.(*coretest.MockVideoClip), start: sc.start + start, log: sc.log}, nil
+}
+
+func (sc *segmentedClip) Close() error {
+	sc.log.mutex.Lock()
+	sc.log.open--
+	sc.log.mutex.Unlock()
+	return sc.MockVideoClip.Close()
+}
+
+func (sc *segmentedClip) WriteToFile(filename string, options *core.WriteOptions) error {
+	sc.log.mutex.Lock()
+	sc.log.writes = append(sc.log.writes, segmentWrite{sc.start, sc.Duration(), options.StartTime, options.EndTime})
+	fail := sc.log.failAt >= 0 && sc.start >= sc.log.failAt
+	sc.log.mutex.Unlock()
+	if fail {
+		return errors.New("segment failed")
+	}
+	return os.WriteFile(filename, []byte(filename), 0o644)
+}
+
+func TestRenderChunkedAppliesWindowOnce(t *testing.T) {
+	pm, _ := newFakeFFmpeg(t)
+	log := &segmentLog{failAt: -1}
+	clip := &segmentedClip{MockVideoClip: coretest.NewCounterClip(4, 2, 4*time.Second, 10), log: log}
+	output := filepath.Join(t.TempDir(), "out.mp4")
+
+	options := &ChunkedOptions{
+		Segments:   2,
+		TempDir:    t.TempDir(),
+		Write:      &core.WriteOptions{StartTime: time.Second, EndTime: 3 * time.Second},
+		ProcessMgr: pm,
+	}
+	if err := RenderChunked(clip, output, options); err != nil {
+		t.Fatalf("分段渲染失败: %v", err)
+	}
+
+	if len(log.writes) != 2 {
+		t.Fatalf("写入了 %d 个分段，期望 2", len(log.writes))
+	}
+	starts := map[time.Duration]bool{}
+	for _, write := range log.writes {
+		if write.startTime != 0 || write.endTime != 0 {
+			t.Errorf("分段选项不应再带渲染窗口，实际 %v-%v", write.startTime, write.endTime)
+		}
+		if write.duration != time.Second {
+			t.Errorf("分段时长 %v，期望 1s", write.duration)
+		}
+		starts[write.start] = true
+	}
+	if !starts[time.Second] || !starts[2*time.Second] {
+		t.Fatalf("分段应从 1s 和 2s 开始，实际 %+v", log.writes)
+	}
+	if log.open != 0 {
+		t.Fatalf("渲染结束后仍有 %d 个子剪辑未关闭", log.open)
+	}
+}
+
+func TestRenderChunkedFailureClosesSubclips(t *testing.T) {
+	pm, _ := newFakeFFmpeg(t)
+	log := &segmentLog{failAt: 2 * time.Second}
+	clip := &segmentedClip{MockVideoClip: coretest.NewCounterClip(4, 2, 4*time.Second, 10), log: log}
+	output := filepath.Join(t.TempDir(), "out.mp4")
+
+	options := &ChunkedOptions{Segments: 4, Concurrency: 1, TempDir: t.TempDir(), ProcessMgr: pm}
+	if err := RenderChunked(clip, output, options); err == nil {
+		t.Fatal("分段失败时应返回错误")
+	}
+	if log.open != 0 {
+		t.Fatalf("失败后仍有 %d 个子剪辑未关闭", log.open)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Fatalf("失败时不应拼接输出: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package render
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"strings"
+	"sync"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+// Output 多路输出中的一路，编码参数为空时取共享写入选项
+type Output struct {
+	Filename string
+	Width    int     // 输出宽度，0 与源相同；只指定一边时按宽高比计算
+	Height   int     // 输出高度
+	FPS      float64 // 输出帧率，0 与源相同；低于源帧率时由 ffmpeg 丢帧（如 GIF 预览）
+	Codec    string  // 如 libx264、gif
+	Bitrate  string
+	CRF      int
+	Threads  int
+}
+
+// MultiOutputOptions 多路输出渲染选项
+type MultiOutputOptions struct {
+	// Write 共享写入选项：FPS/FrameRate、StartTime/EndTime/FrameStep、Proxy、Context、Progress、FrameHook
+	// 和 Filter（在各路的缩放之前应用）对所有输出生效；Cover、Verify 和 AVSyncCheck 对每路输出分别执行；
+	// 编码参数作为各路的默认值，音频编码器按各路的封装选择默认值
+	Write      *core.WriteOptions
+	ProcessMgr *ffmpeg.ProcessManager
+}
+
+// outputTarget 一路输出解析后的选项、音轨和实际写入路径
+type outputTarget struct {
+	filename      string
+	path          string // 实际写入路径，默认为同目录临时文件，全部输出完成后才提交
+	options       *core.WriteOptions
+	writerOptions *ffmpeg.VideoWriterOptions
+	audio         core.AudioClip
+	width, height int // 输出尺寸，由 FFmpeg 按宽高比计算时为 0
+}
+
+// RenderMulti 只渲染一次 clip，把每帧同时分发给多个写入器
+//
+// 特效和合成只在 Go 侧计算一次，缩放和降帧由各路 FFmpeg 进程完成，
+// 适合同时导出 1080p、720p 和 GIF 预览。代理切换、音轨混流、封面和输出校验与 video.Render 相同；
+// 各路先写入临时文件，全部完成后才提交，任一路失败时中止全部输出。
+func RenderMulti(clip core.VideoClip, outputs []Output, options *MultiOutputOptions) error {
+	if len(outputs) == 0 {
+		return fmt.Errorf("没有指定输出")
+	}
+	if options == nil {
+		options = &MultiOutputOptions{}
+	}
+	processMgr := options.ProcessMgr
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	write := core.WriteOptions{}
+	if options.Write != nil {
+		write = *options.Write
+	}
+	plan, err := video.PlanRender(clip, &write, video.RenderSpec{})
+	if err != nil {
+		return err
+	}
+	defer plan.Close()
+	shared := plan.Options
+
+	targets := make([]*outputTarget, len(outputs))
+	for i, output := range outputs {
+		target, err := newOutputTarget(plan, output)
+		if err != nil {
+			return err
+		}
+		targets[i] = target
+		if shared.OnCommand != nil {
+			command := ffmpeg.NewVideoWriter(target.filename, plan.Clip.Width(), plan.Clip.Height(), target.writerOptions, processMgr).Command()
+			shared.OnCommand(command.Name, command.Args)
+			plan.ReportAudio(target.filename, target.audio, target.options, processMgr)
+		}
+	}
+	if shared.DryRun {
+		return nil
+	}
+
+	base := shared.Context
+	if base == nil {
+		base = context.Background()
+	}
+
+	// 先确认音频编码器可用，避免画面编码完成后才失败
+	for _, target := range targets {
+		if target.audio != nil {
+			if err := processMgr.CheckEncoder(base, target.options.AudioCodec); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 默认写入同目录的临时文件，提交后临时文件已不存在，删除为空操作
+	for _, target := range targets {
+		target.path = target.filename
+		if !shared.DirectWrite {
+			if target.path, err = ffmpeg.TempOutputPath(target.filename); err != nil {
+				return err
+			}
+			defer os.Remove(target.path)
+		}
+	}
+
+	writers := make([]*ffmpeg.VideoWriter, len(targets))
+	for i, target := range targets {
+		writers[i] = ffmpeg.NewVideoWriter(target.path, plan.Clip.Width(), plan.Clip.Height(), target.writerOptions, processMgr)
+		if err := writers[i].Open(); err != nil {
+			for _, opened := range writers[:i] {
+				opened.Abort()
+			}
+			return fmt.Errorf("打开输出 %s 失败: %w", target.filename, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(base)
+	defer cancel()
+
+	// 每路一个协程和一个小缓冲通道，慢的编码器只阻塞自己的通道
+	var (
+		wg       sync.WaitGroup
+		errMutex sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		errMutex.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		errMutex.Unlock()
+		cancel()
+	}
+
+	channels := make([]chan image.Image, len(writers))
+	for i, writer := range writers {
+		channels[i] = make(chan image.Image, 2)
+		wg.Add(1)
+		go func(i int, writer *ffmpeg.VideoWriter) {
+			defer wg.Done()
+			if err := writer.WriteFromChannel(ctx, channels[i]); err != nil {
+				fail(fmt.Errorf("写入 %s 失败: %w", targets[i].filename, err))
+			}
+		}(i, writer)
+	}
+
+	totalFrames := plan.TotalFrames()
+	produce := func() error {
+		for i := 0; i < totalFrames; i++ {
+			if err := ctx.Err(); err != nil {
+				if base.Err() != nil {
+					return fmt.Errorf("%w: %v", core.ErrContextCancelled, err)
+				}
+				// 某一路失败导致取消，由 fail 记录原因
+				return nil
+			}
+
+			frame, _, err := plan.Frame(ctx, i)
+			if err != nil {
+				return err
+			}
+
+			for _, ch := range channels {
+				select {
+				case ch <- frame:
+				case <-ctx.Done():
+				}
+			}
+
+			if shared.Progress != nil {
+				shared.Progress(i+1, totalFrames)
+			}
+		}
+		return nil
+	}
+
+	// 生产端的错误优先于各路因取消而返回的错误
+	produceErr := produce()
+	if produceErr != nil {
+		cancel()
+	}
+	for _, ch := range channels {
+		close(ch)
+	}
+	wg.Wait()
+
+	// 任一路失败时其他各路同样不提交，已关闭的写入器 Abort 为空操作
+	if produceErr != nil || firstErr != nil {
+		for _, writer := range writers {
+			writer.Abort()
+		}
+	}
+	if produceErr != nil {
+		return produceErr
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	// 画面全部编码完成后逐路混流、嵌入封面并校验，都成功后才提交
+	for _, target := range targets {
+		if err := plan.Finish(base, target.path, target.audio, target.options, target.width, target.height, processMgr); err != nil {
+			return fmt.Errorf("输出 %s: %w", target.filename, err)
+		}
+	}
+	for _, target := range targets {
+		if target.path != target.filename {
+			if err := ffmpeg.CommitOutput(target.path, target.filename); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newOutputTarget 按输出参数解析一路输出的选项，缩放和降帧通过 -vf 滤镜完成
+func newOutputTarget(plan *video.RenderPlan, output Output) (*outputTarget, error) {
+	if output.Filename == "" {
+		return nil, fmt.Errorf("输出文件名不能为空")
+	}
+	if output.FPS < 0 {
+		return nil, fmt.Errorf("输出 %s 的帧率无效: %f", output.Filename, output.FPS)
+	}
+
+	options := *plan.Options
+	options.Codec = cmp.Or(output.Codec, options.Codec)
+	options.Bitrate = cmp.Or(output.Bitrate, options.Bitrate)
+	options.CRF = cmp.Or(output.CRF, options.CRF)
+	options.Threads = cmp.Or(output.Threads, options.Threads)
+	target := &outputTarget{
+		filename: output.Filename,
+		options:  &options,
+		audio:    plan.OutputAudio(output.Filename, &options),
+		width:    plan.Clip.Width(),
+		height:   plan.Clip.Height(),
+	}
+
+	var filters []string
+	if options.Filter != "" {
+		filters = append(filters, options.Filter)
+	}
+	if output.FPS > 0 && output.FPS < plan.OutputRate.Float64() {
+		filters = append(filters, "fps="+ffmpeg.RationalFromFloat(output.FPS).String())
+	}
+	if output.Width > 0 || output.Height > 0 {
+		target.width, target.height = output.Width, output.Height
+		// -2 让 FFmpeg 按宽高比计算并保持偶数，此时输出尺寸未知
+		width, height := output.Width, output.Height
+		if width == 0 || height == 0 {
+			target.width, target.height = 0, 0
+		}
+		if width == 0 {
+			width = -2
+		}
+		if height == 0 {
+			height = -2
+		}
+		filters = append(filters, fmt.Sprintf("scale=%d:%d", width, height))
+	}
+
+	// 尺寸未知时跳过尺寸检查
+	if err := options.Validate(output.Filename, target.width, target.height); err != nil {
+		return nil, err
+	}
+
+	target.writerOptions = plan.WriterOptions(&options)
+	target.writerOptions.Filter = strings.Join(filters, ",")
+	return target, nil
+}
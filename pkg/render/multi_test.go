@@ -0,0 +1,199 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/core/coretest"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// fakeEncoders 模拟 ffmpeg -encoders 的输出
+const fakeEncoders = "Encoders:\n ------\n V..... libx264 H.264\n A..... aac AAC\n A..... pcm_f32le PCM\n V..... gif GIF\n"
+
+// newFakeFFmpeg 用记录参数的脚本代替 ffmpeg，返回进程管理器和参数日志路径
+//
+// 查询编码器时输出 fakeEncoders；输出文件名包含 bad 时不读取 stdin 直接失败；
+// 混流时把两个输入依次拼接到最后一个参数；否则把 stdin 写入最后一个参数。
+func newFakeFFmpeg(t *testing.T) (*ffmpeg.ProcessManager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "ffmpeg.log")
+	path := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\necho \"$@\" >> '" + log + "'\n" +
+		"case \"$*\" in *-encoders*) printf '" + strings.ReplaceAll(fakeEncoders, "\n", "\\n") + "'; exit 0;; esac\n" +
+		"inputs=; prev=\nfor a; do [ \"$prev\" = -i ] && [ \"$a\" != - ] && inputs=\"$inputs $a\"; prev=$a; last=$a; done\n" +
+		"case \"$last\" in *bad*) exit 1;; esac\n" +
+		"case \"$*\" in *'-map 1:a'*) cat $inputs > \"$last\"; exit 0;; esac\n" +
+		"cat > \"$last\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pm := ffmpeg.NewProcessManagerWithOptions(&ffmpeg.ProcessManagerOptions{FFmpegPath: path, FFprobePath: path})
+	t.Cleanup(func() { pm.Close() })
+	return pm, log
+}
+
+// checkNoOutputs 确认目录中没有留下任何文件（包括临时文件）
+func checkNoOutputs(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		t.Fatalf("失败后留下了文件: %v", names)
+	}
+}
+
+func TestRenderMultiWritesEveryOutput(t *testing.T) {
+	pm, _ := newFakeFFmpeg(t)
+	clip := coretest.NewCounterClip(4, 2, time.Second, 10)
+	dir := t.TempDir()
+	outputs := []Output{
+		{Filename: filepath.Join(dir, "full.mp4")},
+		{Filename: filepath.Join(dir, "half.mp4"), Width: 2},
+	}
+
+	if err := RenderMulti(clip, outputs, &MultiOutputOptions{ProcessMgr: pm}); err != nil {
+		t.Fatalf("多路渲染失败: %v", err)
+	}
+	if calls := len(clip.Calls()); calls != 10 {
+		t.Fatalf("共享渲染应只取 10 帧，实际 %d", calls)
+	}
+	size := ffmpeg.PixelFormatRGB24.FrameSize(4, 2) * 10
+	for _, output := range outputs {
+		data, err := os.ReadFile(output.Filename)
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", output.Filename, err)
+		}
+		if len(data) != size {
+			t.Fatalf("%s 收到 %d 字节，期望 %d", output.Filename, len(data), size)
+		}
+	}
+}
+
+// proxyClip 模拟代理剪辑，WithoutProxy 返回原始分辨率的 full
+type proxyClip struct {
+	*coretest.MockVideoClip
+	full core.VideoClip
+}
+
+func (pc *proxyClip) WithoutProxy() (core.VideoClip, error) {
+	return pc.full, nil
+}
+
+func TestRenderMultiSharesRenderPipeline(t *testing.T) {
+	pm, log := newFakeFFmpeg(t)
+	withAudio, err := coretest.NewCounterClip(8, 4, time.Second, 10).WithAudio(coretest.NewSineClip(440, 0.5, time.Second, 2, 8000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clip := &proxyClip{MockVideoClip: coretest.NewCounterClip(4, 2, time.Second, 10), full: withAudio.(core.VideoClip)}
+	dir := t.TempDir()
+	outputs := []Output{
+		{Filename: filepath.Join(dir, "full.mp4")},
+		{Filename: filepath.Join(dir, "preview.gif"), Codec: "gif", Width: 4, Height: 2},
+	}
+
+	expects := map[string]core.OutputExpectation{}
+	write := &core.WriteOptions{
+		Verify: func(filename string, e core.OutputExpectation) error {
+			if filepath.Dir(filename) != dir || strings.HasSuffix(filename, "full.mp4") {
+				t.Errorf("应校验同目录的临时文件，实际 %s", filename)
+			}
+			expects[filepath.Ext(filename)] = e
+			return nil
+		},
+	}
+	if err := RenderMulti(clip, outputs, &MultiOutputOptions{Write: write, ProcessMgr: pm}); err != nil {
+		t.Fatalf("多路渲染失败: %v", err)
+	}
+	if calls := len(clip.Calls()); calls != 0 {
+		t.Fatalf("应按原始分辨率渲染，代理被取帧 %d 次", calls)
+	}
+
+	// 10 帧 8×4 画面，mp4 另有 1 秒双声道 float32 样本
+	frames := ffmpeg.PixelFormatRGB24.FrameSize(8, 4) * 10
+	sizes := map[string]int{"full.mp4": frames + 8000*2*4, "preview.gif": frames}
+	for name, size := range sizes {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("读取 %s 失败: %v", name, err)
+		}
+		if len(data) != size {
+			t.Fatalf("%s 共 %d 字节，期望 %d", name, len(data), size)
+		}
+	}
+
+	if mp4 := expects[".mp4"]; mp4.AudioStreams != 1 || mp4.AudioCodec != "aac" || mp4.Width != 8 || mp4.Height != 4 {
+		t.Fatalf("mp4 的输出期望 %+v 应为 8×4 并包含一条 aac 音频流", mp4)
+	}
+	if gif := expects[".gif"]; gif.AudioStreams != 0 || gif.VideoCodec != "gif" || gif.Width != 4 || gif.Height != 2 {
+		t.Fatalf("gif 的输出期望 %+v 应为 4×2 且不含音频", gif)
+	}
+	logged, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(logged), "-map 1:a"); n != 1 {
+		t.Fatalf("应只为 mp4 混入音轨，实际混流 %d 次:\n%s", n, logged)
+	}
+}
+
+func TestRenderMultiFrameErrorCommitsNothing(t *testing.T) {
+	pm, _ := newFakeFFmpeg(t)
+	clip := coretest.NewCounterClip(4, 2, time.Second, 10)
+	dir := t.TempDir()
+	outputs := []Output{
+		{Filename: filepath.Join(dir, "a.mp4")},
+		{Filename: filepath.Join(dir, "b.mp4")},
+	}
+
+	errHook := errors.New("hook failed")
+	write := &core.WriteOptions{
+		FrameHook: func(i int, t time.Duration, frame image.Image) (image.Image, error) {
+			if i == 5 {
+				return nil, errHook
+			}
+			return frame, nil
+		},
+	}
+	// 多次运行以覆盖取消与通道关闭之间的调度顺序
+	for run := 0; run < 20; run++ {
+		err := RenderMulti(clip, outputs, &MultiOutputOptions{Write: write, ProcessMgr: pm})
+		if !errors.Is(err, errHook) {
+			t.Fatalf("第 %d 次：应返回帧钩子错误，实际 %v", run, err)
+		}
+		checkNoOutputs(t, dir)
+	}
+}
+
+func TestRenderMultiFailedOutputAbortsSiblings(t *testing.T) {
+	pm, _ := newFakeFFmpeg(t)
+	// 帧大于管道缓冲区，写入失败的一路时必然得到 EPIPE
+	clip := coretest.NewCounterClip(256, 256, time.Second, 10)
+	dir := t.TempDir()
+	outputs := []Output{
+		{Filename: filepath.Join(dir, "good.mp4")},
+		{Filename: filepath.Join(dir, "bad.mp4")},
+	}
+
+	for run := 0; run < 5; run++ {
+		err := RenderMulti(clip, outputs, &MultiOutputOptions{ProcessMgr: pm})
+		if err == nil || !strings.Contains(err.Error(), "bad.mp4") {
+			t.Fatalf("第 %d 次：应返回 bad.mp4 的写入错误，实际 %v", run, err)
+		}
+		checkNoOutputs(t, dir)
+	}
+}
@@ -0,0 +1,388 @@
+package render
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// JobStatus 渲染任务状态
+type JobStatus int
+
+const (
+	JobPending JobStatus = iota
+	JobRunning
+	JobCompleted
+	JobFailed
+	JobCancelled
+)
+
+// String 返回状态名称
+func (s JobStatus) String() string {
+	switch s {
+	case JobPending:
+		return "pending"
+	case JobRunning:
+		return "running"
+	case JobCompleted:
+		return "completed"
+	case JobFailed:
+		return "failed"
+	case JobCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// Job 单个 WriteToFile 渲染任务
+type Job struct {
+	id       string
+	clip     core.Clip
+	filename string
+	options  core.WriteOptions
+	priority int
+	seq      int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mutex     sync.RWMutex
+	status    JobStatus
+	err       error
+	current   int
+	total     int
+	startTime time.Time
+	endTime   time.Time
+}
+
+// ID 返回任务 ID
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Filename 返回输出文件
+func (j *Job) Filename() string {
+	return j.filename
+}
+
+// Priority 返回优先级，数值越大越先执行
+func (j *Job) Priority() int {
+	return j.priority
+}
+
+// Status 返回当前状态
+func (j *Job) Status() JobStatus {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.status
+}
+
+// Progress 返回已写入帧数和总帧数
+func (j *Job) Progress() (current, total int) {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.current, j.total
+}
+
+// Err 返回任务错误，未结束或成功时为 nil
+func (j *Job) Err() error {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	return j.err
+}
+
+// Elapsed 返回任务已运行时长
+func (j *Job) Elapsed() time.Duration {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+	if j.startTime.IsZero() {
+		return 0
+	}
+	if j.endTime.IsZero() {
+		return time.Since(j.startTime)
+	}
+	return j.endTime.Sub(j.startTime)
+}
+
+// Done 返回任务结束时关闭的通道
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait 等待任务结束并返回其错误
+func (j *Job) Wait() error {
+	<-j.done
+	return j.Err()
+}
+
+// Cancel 取消任务，排队中的任务不会再执行，运行中的任务在下一帧停止
+func (j *Job) Cancel() {
+	j.cancel()
+}
+
+// finish 设置最终状态
+func (j *Job) finish(status JobStatus, err error) {
+	j.mutex.Lock()
+	j.status = status
+	j.err = err
+	j.endTime = time.Now()
+	j.mutex.Unlock()
+	close(j.done)
+}
+
+// QueueOptions 渲染队列选项
+type QueueOptions struct {
+	Concurrency int                    // 最大并发任务数，默认 1
+	ProcessMgr  *ffmpeg.ProcessManager // 共享的进程管理器，nil 时由队列创建并在 Close 时关闭
+	// OnProgress 任务进度回调，在渲染协程中调用
+	OnProgress func(job *Job, current, total int)
+	// OnFinish 任务结束回调，Wait 在所有回调返回后才返回
+	OnFinish func(job *Job)
+}
+
+// Queue 带优先级和并发限制的渲染任务队列
+type Queue struct {
+	options    *QueueOptions
+	processMgr *ffmpeg.ProcessManager
+	ownsMgr    bool
+
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	pending jobHeap
+	jobs    map[string]*Job
+	order   []*Job
+	nextSeq int
+	closed  bool
+	wg      sync.WaitGroup
+	active  sync.WaitGroup
+}
+
+// NewQueue 创建渲染队列并启动工作协程
+func NewQueue(options *QueueOptions) *Queue {
+	if options == nil {
+		options = &QueueOptions{}
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+
+	q := &Queue{
+		options:    options,
+		processMgr: options.ProcessMgr,
+		jobs:       make(map[string]*Job),
+	}
+	if q.processMgr == nil {
+		q.processMgr = ffmpeg.NewProcessManager()
+		q.ownsMgr = true
+	}
+	q.cond = sync.NewCond(&q.mutex)
+
+	for i := 0; i < options.Concurrency; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+
+	return q
+}
+
+// ProcessManager 返回队列共享的进程管理器，提交的剪辑应使用它创建
+func (q *Queue) ProcessManager() *ffmpeg.ProcessManager {
+	return q.processMgr
+}
+
+// Submit 提交渲染任务，priority 越大越先执行，相同优先级按提交顺序
+func (q *Queue) Submit(clip core.Clip, filename string, options *core.WriteOptions, priority int) (*Job, error) {
+	if clip == nil {
+		return nil, fmt.Errorf("剪辑不能为空")
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return nil, fmt.Errorf("渲染队列已关闭")
+	}
+
+	parent := context.Background()
+	job := &Job{
+		filename: filename,
+		clip:     clip,
+		priority: priority,
+		seq:      q.nextSeq,
+		done:     make(chan struct{}),
+		status:   JobPending,
+	}
+	if options != nil {
+		job.options = *options
+		if options.Context != nil {
+			parent = options.Context
+		}
+	}
+	job.ctx, job.cancel = context.WithCancel(parent)
+	job.id = fmt.Sprintf("job-%d", q.nextSeq+1)
+	q.nextSeq++
+
+	q.jobs[job.id] = job
+	q.order = append(q.order, job)
+	q.active.Add(1)
+	heap.Push(&q.pending, job)
+	q.cond.Signal()
+
+	return job, nil
+}
+
+// Job 按 ID 查找任务
+func (q *Queue) Job(id string) (*Job, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	job, ok := q.jobs[id]
+	return job, ok
+}
+
+// Jobs 按提交顺序返回所有任务
+func (q *Queue) Jobs() []*Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	jobs := make([]*Job, len(q.order))
+	copy(jobs, q.order)
+	return jobs
+}
+
+// Cancel 取消指定任务
+func (q *Queue) Cancel(id string) error {
+	job, ok := q.Job(id)
+	if !ok {
+		return fmt.Errorf("任务 %s 不存在", id)
+	}
+	job.Cancel()
+	return nil
+}
+
+// Wait 等待所有已提交的任务结束且 OnFinish 回调返回
+func (q *Queue) Wait() {
+	q.active.Wait()
+}
+
+// Close 取消所有任务、停止工作协程，并在队列拥有进程管理器时关闭它
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	if q.closed {
+		q.mutex.Unlock()
+		return nil
+	}
+	q.closed = true
+	for _, job := range q.order {
+		job.Cancel()
+	}
+	q.cond.Broadcast()
+	q.mutex.Unlock()
+
+	q.wg.Wait()
+
+	if q.ownsMgr {
+		return q.processMgr.Close()
+	}
+	return nil
+}
+
+// worker 从队列中取出任务并执行
+func (q *Queue) worker() {
+	defer q.wg.Done()
+
+	for {
+		q.mutex.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.pending) == 0 && q.closed {
+			q.mutex.Unlock()
+			return
+		}
+		job := heap.Pop(&q.pending).(*Job)
+		q.mutex.Unlock()
+
+		q.runJob(job)
+	}
+}
+
+// runJob 执行任务并调用 OnFinish，回调结束后才计为完成，Wait 返回时所有回调都已结束
+func (q *Queue) runJob(job *Job) {
+	defer q.active.Done()
+
+	q.run(job)
+	if q.options.OnFinish != nil {
+		q.options.OnFinish(job)
+	}
+}
+
+// run 执行单个任务
+func (q *Queue) run(job *Job) {
+	if err := job.ctx.Err(); err != nil {
+		job.finish(JobCancelled, fmt.Errorf("%w: %v", core.ErrContextCancelled, err))
+		return
+	}
+
+	job.mutex.Lock()
+	job.status = JobRunning
+	job.startTime = time.Now()
+	job.mutex.Unlock()
+
+	options := job.options
+	userProgress := options.Progress
+	options.Context = core.WithJobID(job.ctx, job.id)
+	options.Progress = func(current, total int) {
+		job.mutex.Lock()
+		job.current = current
+		job.total = total
+		job.mutex.Unlock()
+
+		if userProgress != nil {
+			userProgress(current, total)
+		}
+		if q.options.OnProgress != nil {
+			q.options.OnProgress(job, current, total)
+		}
+	}
+
+	err := job.clip.WriteToFile(job.filename, &options)
+	switch {
+	case err == nil:
+		job.finish(JobCompleted, nil)
+	case errors.Is(err, core.ErrContextCancelled) || job.ctx.Err() != nil:
+		job.finish(JobCancelled, err)
+	default:
+		job.finish(JobFailed, err)
+	}
+}
+
+// jobHeap 按优先级（高优先）和提交顺序排序的任务堆
+type jobHeap []*Job
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(*Job)) }
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	job := old[n-1]
+	*h = old[:n-1]
+	return job
+}
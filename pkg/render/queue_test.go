@@ -0,0 +1,42 @@
+package render
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core/coretest"
+)
+
+func TestQueueWaitIncludesOnFinish(t *testing.T) {
+	pm, _ := newFakeFFmpeg(t)
+	var finished atomic.Int32
+	queue := NewQueue(&QueueOptions{
+		Concurrency: 2,
+		ProcessMgr:  pm,
+		OnFinish: func(job *Job) {
+			// 回调慢于渲染，Wait 仍需等它返回
+			time.Sleep(50 * time.Millisecond)
+			if job.Status() != JobCompleted {
+				t.Errorf("%s 状态为 %v，期望 completed", job.ID(), job.Status())
+			}
+			finished.Add(1)
+		},
+	})
+	defer queue.Close()
+
+	dir := t.TempDir()
+	clip := &segmentedClip{MockVideoClip: coretest.NewCounterClip(4, 2, time.Second, 10), log: &segmentLog{failAt: -1}}
+	for i := 0; i < 3; i++ {
+		if _, err := queue.Submit(clip, filepath.Join(dir, fmt.Sprintf("out-%d.mp4", i)), nil, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queue.Wait()
+
+	if n := finished.Load(); n != 3 {
+		t.Fatalf("Wait 返回时只有 %d 个 OnFinish 回调结束，期望 3", n)
+	}
+}
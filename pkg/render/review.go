@@ -0,0 +1,155 @@
+package render
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/timeline"
+	"moviepy-go/pkg/video"
+)
+
+// DefaultReviewWatermark 审阅副本默认的水印文字
+const DefaultReviewWatermark = "DRAFT — do not distribute"
+
+// ReviewOptions 审阅副本导出选项
+type ReviewOptions struct {
+	Width   int    // 代理宽度，默认 960，源画面更窄时不放大；高度按宽高比取偶数
+	Bitrate string // 视频码率，默认 1500k
+
+	// Watermark 画面中央的半透明水印，默认 DefaultReviewWatermark；NoWatermark 时不显示
+	Watermark        string
+	NoWatermark      bool
+	WatermarkOpacity float64 // 水印不透明度，默认 0.3
+
+	// NoTimecode 不在左上角烧录时间码
+	NoTimecode bool
+	// TimecodeOffset 剪辑开头对应的时间码，如 time.Hour 使第一帧显示 01:00:00:00
+	TimecodeOffset time.Duration
+	// DropFrame 29.97/59.94 帧率下使用丢帧时间码
+	DropFrame bool
+	// FrameNumbers 在右上角烧录从 0 开始的输出帧号
+	FrameNumbers bool
+	FontFile     string // 烧录文字的字体文件，默认由 fontconfig 选择
+
+	// Write 其余写入选项（FPS、StartTime/EndTime、Context、Progress 等），Bitrate 和 Filter 由本选项决定
+	Write      *core.WriteOptions
+	ProcessMgr *ffmpeg.ProcessManager
+}
+
+// ExportReviewCopy 导出供审阅和批注的低码率代理：缩小到代理宽度，烧录时间码、水印和可选的帧号
+//
+// 文字由编码进程的 drawtext 滤镜逐帧绘制，不经过 Go 侧合成。音频与 clip.WriteToFile 相同。
+func ExportReviewCopy(clip core.VideoClip, output string, options *ReviewOptions) error {
+	if options == nil {
+		options = &ReviewOptions{}
+	}
+	processMgr := options.ProcessMgr
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	width := options.Width
+	if width <= 0 {
+		width = 960
+	}
+	proxy := clip
+	if width < clip.Width() {
+		height := clip.Height() * width / clip.Width()
+		resized := video.NewEffectVideoClip(clip, processMgr)
+		resized.AddEffect(effects.NewResizeEffect(width&^1, max(height&^1, 2)))
+		defer resized.Close()
+		proxy = resized
+	}
+
+	write := core.WriteOptions{}
+	if options.Write != nil {
+		write = *options.Write
+	}
+	write.Bitrate = options.Bitrate
+	if write.Bitrate == "" {
+		write.Bitrate = "1500k"
+	}
+
+	frameRate, err := ffmpeg.ResolveFrameRate(write.FrameRate, cmp.Or(write.FPS, proxy.FPS()))
+	if err != nil {
+		return err
+	}
+	filter, cleanup, err := reviewFilter(proxy.Height(), frameRate.Div(max(write.FrameStep, 1)), write.StartTime, options, processMgr)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	// 调用方的滤镜（如调色）先于烧录的文字
+	if write.Filter != "" && filter != "" {
+		filter = write.Filter + "," + filter
+	}
+	write.Filter = cmp.Or(filter, write.Filter)
+	return proxy.WriteToFile(output, &write)
+}
+
+// reviewFilter 组装烧录文字的 drawtext 滤镜链，rate 为输出帧率，start 为第一帧在剪辑中的时间
+func reviewFilter(height int, rate ffmpeg.Rational, start time.Duration, options *ReviewOptions, processMgr *ffmpeg.ProcessManager) (string, func(), error) {
+	var filters, files []string
+	cleanup := func() {
+		for _, file := range files {
+			processMgr.Temp().Remove(file)
+		}
+	}
+	font := "font=Monospace"
+	if options.FontFile != "" {
+		font = "fontfile=" + ffmpeg.QuoteFilterArg(options.FontFile)
+	}
+	size := max(height/24, 10)
+	box := []string{"fontcolor=white", "box=1", "boxcolor=black@0.5", fmt.Sprintf("boxborderw=%d", max(size/4, 2))}
+
+	if !options.NoWatermark {
+		text := options.Watermark
+		if text == "" {
+			text = DefaultReviewWatermark
+		}
+		opacity := options.WatermarkOpacity
+		if opacity <= 0 {
+			opacity = 0.3
+		}
+		textFile, err := processMgr.Temp().CreateTextFile("review-*.txt", text)
+		if err != nil {
+			cleanup()
+			return "", nil, err
+		}
+		files = append(files, textFile)
+		watermark := []string{
+			"textfile=" + ffmpeg.QuoteFilterArg(textFile), "expansion=none",
+			fmt.Sprintf("fontsize=%d", max(height/12, 12)), fmt.Sprintf("fontcolor=white@%.2f", min(opacity, 1)),
+			"x=(w-text_w)/2", "y=(h-text_h)/2",
+		}
+		if options.FontFile != "" {
+			watermark = append(watermark, font)
+		}
+		filters = append(filters, "drawtext="+strings.Join(watermark, ":"))
+	}
+
+	margin := height / 30
+	if !options.NoTimecode {
+		first := timeline.Frames(options.TimecodeOffset+start, rate)
+		timecode := []string{
+			"timecode=" + ffmpeg.QuoteFilterArg(timeline.Timecode(first, rate, options.DropFrame)),
+			"rate=" + rate.String(), font, fmt.Sprintf("fontsize=%d", size),
+			fmt.Sprintf("x=%d", margin), fmt.Sprintf("y=%d", margin),
+		}
+		filters = append(filters, "drawtext="+strings.Join(append(timecode, box...), ":"))
+	}
+	if options.FrameNumbers {
+		frames := []string{
+			"text=" + ffmpeg.QuoteFilterArg("%{frame_num}"), "start_number=0", font, fmt.Sprintf("fontsize=%d", size),
+			fmt.Sprintf("x=w-text_w-%d", margin), fmt.Sprintf("y=%d", margin),
+		}
+		filters = append(filters, "drawtext="+strings.Join(append(frames, box...), ":"))
+	}
+	return strings.Join(filters, ","), cleanup, nil
+}
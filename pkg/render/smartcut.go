@@ -0,0 +1,324 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// CutRange 源文件中要保留的一段时间 [Start, End)，End 为 0 表示文件末尾
+type CutRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// SmartCutPart 智能剪切计划中的一个片段，时间为源文件时间
+type SmartCutPart struct {
+	Range int // 所属 CutRange 的序号
+	Start time.Duration
+	End   time.Duration
+	Copy  bool // true 表示直接复制码流，false 表示重新编码
+}
+
+// SmartCutOptions 智能剪切选项
+type SmartCutOptions struct {
+	// Codec 重新编码片段使用的编码器，默认按源编码选择（h264→libx264、hevc→libx265 等）
+	Codec string
+	// CRF 重新编码片段的恒定质量因子，默认 18 以接近源画质
+	CRF int
+	// AudioCodec 输出的音频编码器，默认 aac；音频总是按保留的时间段整体重新编码，避免拼接处的空隙
+	AudioCodec   string
+	AudioBitrate string
+	// MinCopy 两个关键帧之间的可复制区段短于该值时整段重新编码，默认 1 秒
+	MinCopy time.Duration
+
+	TempDir    string // 片段文件目录，默认在 ffmpeg.TempDir() 或输出文件同目录下创建临时目录
+	KeepParts  bool   // 完成后保留片段文件
+	Context    context.Context
+	ProcessMgr *ffmpeg.ProcessManager
+}
+
+// SmartCutResult 智能剪切的结果
+type SmartCutResult struct {
+	Parts   []SmartCutPart
+	Copied  time.Duration // 直接复制的总时长
+	Encoded time.Duration // 重新编码的总时长
+}
+
+// smartCutEncoders 源编码到重新编码所用编码器的默认映射
+var smartCutEncoders = map[string]string{
+	"h264":       "libx264",
+	"hevc":       "libx265",
+	"vp9":        "libvpx-vp9",
+	"av1":        "libsvtav1",
+	"mpeg2video": "mpeg2video",
+	"mpeg4":      "mpeg4",
+}
+
+// PlanSmartCut 按关键帧把每段 CutRange 切分为头部重新编码、中间复制、尾部重新编码三部分
+//
+// 复制区段从 Start 之后的第一个关键帧到 End 之前的最后一个关键帧；区段不足 minCopy 时整段重新编码。
+// ranges 的 End 必须已确定（不为 0）。keyframes 末尾可附加文件时长，使延伸到文件末尾的区段不必重新编码尾部。
+func PlanSmartCut(keyframes []time.Duration, ranges []CutRange, minCopy time.Duration) ([]SmartCutPart, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("没有要保留的时间段")
+	}
+	var parts []SmartCutPart
+	for i, r := range ranges {
+		if r.Start < 0 || r.End <= r.Start {
+			return nil, fmt.Errorf("%w: 第 %d 段 [%v, %v)", core.ErrInvalidTimeRange, i, r.Start, r.End)
+		}
+
+		// 第一个不早于 Start 的关键帧和最后一个不晚于 End 的关键帧
+		first := sort.Search(len(keyframes), func(k int) bool { return keyframes[k] >= r.Start })
+		last := sort.Search(len(keyframes), func(k int) bool { return keyframes[k] > r.End }) - 1
+		if first >= len(keyframes) || last < first || keyframes[last]-keyframes[first] < minCopy {
+			parts = append(parts, SmartCutPart{Range: i, Start: r.Start, End: r.End})
+			continue
+		}
+
+		copyStart, copyEnd := keyframes[first], keyframes[last]
+		if r.Start < copyStart {
+			parts = append(parts, SmartCutPart{Range: i, Start: r.Start, End: copyStart})
+		}
+		parts = append(parts, SmartCutPart{Range: i, Start: copyStart, End: copyEnd, Copy: true})
+		if copyEnd < r.End {
+			parts = append(parts, SmartCutPart{Range: i, Start: copyEnd, End: r.End})
+		}
+	}
+	return parts, nil
+}
+
+// SmartCut 从 source 中截取 ranges 并按顺序拼接到 output，只重新编码切点附近的片段
+//
+// 关键帧之间未被剪切的 GOP 直接复制码流，速度接近 ConcatSegments，画质与源文件相同；切点到相邻关键帧
+// 之间的少量帧以与源相同的编码、分辨率和像素格式重新编码。适用于长录像的简单裁剪，片段之间是硬切，
+// 转场、特效或变速仍需完整渲染。片段以 MPEG-TS（H.264/HEVC/MPEG-2）或 Matroska 作中间格式，
+// 使各片段的编码参数随码流携带。
+func SmartCut(source string, ranges []CutRange, output string, options *SmartCutOptions) (*SmartCutResult, error) {
+	if options == nil {
+		options = &SmartCutOptions{}
+	}
+	if options.CRF == 0 {
+		options.CRF = 18
+	}
+	if options.AudioCodec == "" {
+		options.AudioCodec = "aac"
+	}
+	if options.MinCopy == 0 {
+		options.MinCopy = time.Second
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	processMgr := options.ProcessMgr
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	reader := ffmpeg.NewVideoReader(source, processMgr)
+	defer reader.Close()
+	if err := reader.Open(); err != nil {
+		return nil, err
+	}
+	info := reader.GetInfo()
+
+	codec := options.Codec
+	if codec == "" {
+		codec = smartCutEncoders[info.Codec]
+		if codec == "" {
+			return nil, fmt.Errorf("不支持智能剪切的视频编码: %s", info.Codec)
+		}
+	}
+	if err := processMgr.CheckEncoder(ctx, codec); err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(info.Duration * float64(time.Second))
+	resolved := make([]CutRange, len(ranges))
+	for i, r := range ranges {
+		if r.End == 0 || r.End > duration {
+			r.End = duration
+		}
+		resolved[i] = r
+	}
+
+	keyframes, err := reader.KeyframesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 文件末尾同样是干净的切点
+	bounds := append(slices.Clone(keyframes), duration)
+	parts, err := PlanSmartCut(bounds, resolved, options.MinCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	tempDir := options.TempDir
+	if tempDir == "" {
+		parent := ffmpeg.TempDir()
+		if parent == "" {
+			parent = filepath.Dir(output)
+		}
+		temp := ffmpeg.NewTempManager(&ffmpeg.TempManagerOptions{Dir: parent})
+		if !options.KeepParts {
+			defer temp.Close()
+			defer temp.CleanupOnPanic()
+		}
+		dir, err := temp.Dir()
+		if err != nil {
+			return nil, fmt.Errorf("创建片段目录失败: %w", err)
+		}
+		tempDir = dir
+	} else if !options.KeepParts {
+		defer os.RemoveAll(tempDir)
+	}
+
+	cut := &smartCutter{
+		source:     source,
+		info:       info,
+		codec:      codec,
+		options:    options,
+		processMgr: processMgr,
+		ext:        ".mkv",
+	}
+	switch info.Codec {
+	case "h264", "hevc", "mpeg2video":
+		cut.ext = ".ts"
+	}
+
+	result := &SmartCutResult{Parts: parts}
+	videoParts := make([]string, len(parts))
+	for i, part := range parts {
+		videoParts[i] = filepath.Join(tempDir, fmt.Sprintf("video-%04d%s", i, cut.ext))
+		if err := cut.video(ctx, part, videoParts[i]); err != nil {
+			return nil, fmt.Errorf("处理片段 %d [%v, %v) 失败: %w", i, part.Start, part.End, err)
+		}
+		if part.Copy {
+			result.Copied += part.End - part.Start
+		} else {
+			result.Encoded += part.End - part.Start
+		}
+	}
+
+	var audioParts []string
+	if info.HasAudio {
+		audioParts = make([]string, len(resolved))
+		for i, r := range resolved {
+			audioParts[i] = filepath.Join(tempDir, fmt.Sprintf("audio-%04d.wav", i))
+			if err := cut.audio(ctx, r, audioParts[i]); err != nil {
+				return nil, fmt.Errorf("截取第 %d 段音频失败: %w", i, err)
+			}
+		}
+	}
+
+	if err := cut.mux(ctx, tempDir, videoParts, audioParts, output); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// smartCutter 执行智能剪切各步骤的 FFmpeg 命令
+type smartCutter struct {
+	source     string
+	info       *ffmpeg.VideoInfo
+	codec      string
+	ext        string // 视频片段的中间格式扩展名
+	options    *SmartCutOptions
+	processMgr *ffmpeg.ProcessManager
+}
+
+// video 复制或重新编码一个视频片段，输入侧 -ss 对复制片段落在关键帧上，对重新编码片段精确解码
+func (sc *smartCutter) video(ctx context.Context, part SmartCutPart, filename string) error {
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-ss", ffmpeg.FormatSeconds(part.Start),
+		"-i", sc.source,
+		"-t", ffmpeg.FormatSeconds(part.End - part.Start),
+		"-map", "0:" + strconv.Itoa(sc.info.VideoStream),
+		"-an", "-sn", "-dn",
+	}
+	if part.Copy {
+		args = append(args, "-c:v", "copy")
+	} else {
+		args = append(args, "-c:v", sc.codec, "-crf", strconv.Itoa(sc.options.CRF))
+		if sc.codec == "libvpx-vp9" {
+			// libvpx 只有在码率为 0 时才按 CRF 恒定质量编码
+			args = append(args, "-b:v", "0")
+		}
+		if sc.info.PixelFormat != "" {
+			args = append(args, "-pix_fmt", sc.info.PixelFormat)
+		}
+	}
+	args = append(args, "-avoid_negative_ts", "make_zero", "-y", filename)
+	return sc.run(ctx, args)
+}
+
+// audio 把一段保留时间内的音频解码为 PCM，最终统一编码以保证拼接处连续
+func (sc *smartCutter) audio(ctx context.Context, r CutRange, filename string) error {
+	return sc.run(ctx, []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-ss", ffmpeg.FormatSeconds(r.Start),
+		"-i", sc.source,
+		"-t", ffmpeg.FormatSeconds(r.End - r.Start),
+		"-map", "0:" + strconv.Itoa(sc.info.AudioStream),
+		"-vn", "-sn", "-dn",
+		"-c:a", "pcm_f32le",
+		"-y", filename,
+	})
+}
+
+// mux 分别拼接视频和音频片段，复制视频并编码音频写入 output，失败时 output 保持不变
+func (sc *smartCutter) mux(ctx context.Context, tempDir string, videoParts, audioParts []string, output string) error {
+	videoList := filepath.Join(tempDir, "video.txt")
+	if err := ffmpeg.WriteConcatList(videoList, videoParts); err != nil {
+		return err
+	}
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-f", "concat", "-safe", "0", "-i", videoList,
+	}
+	if len(audioParts) > 0 {
+		audioList := filepath.Join(tempDir, "audio.txt")
+		if err := ffmpeg.WriteConcatList(audioList, audioParts); err != nil {
+			return err
+		}
+		args = append(args, "-f", "concat", "-safe", "0", "-i", audioList, "-map", "0:v", "-map", "1:a", "-c:a", sc.options.AudioCodec)
+		if sc.options.AudioBitrate != "" {
+			args = append(args, "-b:a", sc.options.AudioBitrate)
+		}
+	}
+	// 写入同目录的临时文件，拼接成功后才替换 output
+	staged, err := ffmpeg.TempOutputPath(output)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staged)
+	args = append(args, "-c:v", "copy", "-y", staged)
+	if err := sc.run(ctx, args); err != nil {
+		return fmt.Errorf("拼接片段失败: %w", err)
+	}
+	return ffmpeg.CommitOutput(staged, output)
+}
+
+// run 执行一条 FFmpeg 命令并等待结束
+func (sc *smartCutter) run(ctx context.Context, args []string) error {
+	process, err := sc.processMgr.StartProcess(ctx, "ffmpeg", args, nil)
+	if err != nil {
+		return fmt.Errorf("启动 FFmpeg 失败: %w", err)
+	}
+	return process.Wait()
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+func TestPlanSmartCut(t *testing.T) {
+	s := time.Second
+	// 每 2 秒一个关键帧，文件时长 10 秒附加在末尾
+	keyframes := []time.Duration{0, 2 * s, 4 * s, 6 * s, 8 * s, 10 * s}
+	tests := []struct {
+		name    string
+		ranges  []CutRange
+		minCopy time.Duration
+		want    []SmartCutPart
+	}{
+		{
+			"切点在关键帧上只复制", []CutRange{{2 * s, 6 * s}}, s,
+			[]SmartCutPart{{Range: 0, Start: 2 * s, End: 6 * s, Copy: true}},
+		},
+		{
+			"头尾重新编码", []CutRange{{1 * s, 7 * s}}, s,
+			[]SmartCutPart{
+				{Range: 0, Start: 1 * s, End: 2 * s},
+				{Range: 0, Start: 2 * s, End: 6 * s, Copy: true},
+				{Range: 0, Start: 6 * s, End: 7 * s},
+			},
+		},
+		{
+			"可复制区段短于 minCopy 时整段重新编码", []CutRange{{1 * s, 5 * s}}, 3 * s,
+			[]SmartCutPart{{Range: 0, Start: 1 * s, End: 5 * s}},
+		},
+		{
+			"区段内没有关键帧", []CutRange{{2500 * time.Millisecond, 3500 * time.Millisecond}}, s,
+			[]SmartCutPart{{Range: 0, Start: 2500 * time.Millisecond, End: 3500 * time.Millisecond}},
+		},
+		{
+			"延伸到文件末尾不重新编码尾部", []CutRange{{7 * s, 10 * s}}, s,
+			[]SmartCutPart{
+				{Range: 0, Start: 7 * s, End: 8 * s},
+				{Range: 0, Start: 8 * s, End: 10 * s, Copy: true},
+			},
+		},
+		{
+			"多段保留各自的序号", []CutRange{{0, 2 * s}, {8 * s, 10 * s}}, s,
+			[]SmartCutPart{
+				{Range: 0, Start: 0, End: 2 * s, Copy: true},
+				{Range: 1, Start: 8 * s, End: 10 * s, Copy: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		parts, err := PlanSmartCut(keyframes, tt.ranges, tt.minCopy)
+		if err != nil {
+			t.Errorf("%s: 规划失败: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(parts, tt.want) {
+			t.Errorf("%s: 计划 %+v，期望 %+v", tt.name, parts, tt.want)
+		}
+	}
+}
+
+func TestPlanSmartCutRejectsInvalidRanges(t *testing.T) {
+	keyframes := []time.Duration{0, time.Second}
+	if _, err := PlanSmartCut(keyframes, nil, time.Second); err == nil {
+		t.Error("没有时间段时应返回错误")
+	}
+	for _, r := range []CutRange{{-time.Second, time.Second}, {time.Second, time.Second}, {2 * time.Second, time.Second}} {
+		if _, err := PlanSmartCut(keyframes, []CutRange{r}, time.Second); !errors.Is(err, core.ErrInvalidTimeRange) {
+			t.Errorf("区段 %v 应返回 ErrInvalidTimeRange，实际 %v", r, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Record 一条数据记录，字段名到取值
+type Record map[string]string
+
+// templateField 匹配 {{name}}，名称两侧允许空格
+var templateField = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// TemplateFields 按首次出现的顺序返回工程文件中的占位字段
+func TemplateFields(project []byte) []string {
+	var fields []string
+	seen := map[string]bool{}
+	for _, match := range templateField.FindAllSubmatch(project, -1) {
+		name := string(match[1])
+		if !seen[name] {
+			seen[name] = true
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// FillTemplate 用 record 替换 JSON 工程文件中的 {{name}} 占位符
+//
+// 占位符应位于 JSON 字符串内（如 "text": "恭喜 {{name}}"、"file": "{{photo}}"），
+// 取值按 JSON 字符串转义，引号和换行不会破坏文件结构。记录缺少字段时返回错误并列出全部缺失字段。
+func FillTemplate(project []byte, record Record) ([]byte, error) {
+	var missing []string
+	filled := templateField.ReplaceAllFunc(project, func(match []byte) []byte {
+		name := string(templateField.FindSubmatch(match)[1])
+		value, ok := record[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		quoted, _ := json.Marshal(value)
+		return quoted[1 : len(quoted)-1]
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("记录缺少字段: %s", strings.Join(missing, ", "))
+	}
+	return filled, nil
+}
+
+// LoadRecords 读取数据记录，.csv 以首行为字段名，.json 为对象数组
+func LoadRecords(filename string) ([]Record, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取数据文件失败: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return parseCSVRecords(data)
+	case ".json":
+		return parseJSONRecords(data)
+	default:
+		return nil, fmt.Errorf("不支持的数据文件格式: %s", filename)
+	}
+}
+
+// parseCSVRecords 解析带表头的 CSV
+func parseCSVRecords(data []byte) ([]Record, error) {
+	rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析 CSV 失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	records := make([]Record, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(Record, len(header))
+		for i, name := range header {
+			record[strings.TrimSpace(name)] = row[i]
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseJSONRecords 解析对象数组，非字符串的值转换为其 JSON 文本（数字保持原样）
+func parseJSONRecords(data []byte) ([]Record, error) {
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("解析 JSON 数据失败: %w", err)
+	}
+	records := make([]Record, len(objects))
+	for i, object := range objects {
+		records[i] = make(Record, len(object))
+		for name, raw := range object {
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				records[i][name] = s
+			} else {
+				records[i][name] = string(raw)
+			}
+		}
+	}
+	return records, nil
+}
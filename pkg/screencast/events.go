@@ -0,0 +1,112 @@
+package screencast
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EventKind 录屏事件类型
+type EventKind int
+
+const (
+	// EventMove 光标移动到 (X, Y)
+	EventMove EventKind = iota
+	// EventClick 在 (X, Y) 处点击鼠标
+	EventClick
+	// EventKey 按下按键或组合键 Key，如 "Ctrl+S"
+	EventKey
+)
+
+// eventKindNames 按 EventKind 取值排列的名称
+var eventKindNames = []string{"move", "click", "key"}
+
+// String 返回事件类型名称
+func (k EventKind) String() string {
+	if k >= 0 && int(k) < len(eventKindNames) {
+		return eventKindNames[k]
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// ParseEventKind 解析事件类型名称
+func ParseEventKind(name string) (EventKind, error) {
+	for i, n := range eventKindNames {
+		if n == name {
+			return EventKind(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的录屏事件类型: %q（支持 %s）", name, strings.Join(eventKindNames, "、"))
+}
+
+// Event 录屏时记录的一个输入事件，坐标为录屏画面的像素
+type Event struct {
+	Time time.Duration
+	Kind EventKind
+	X, Y int
+	Key  string
+}
+
+// eventLine 事件文件中的一行
+type eventLine struct {
+	T    float64 `json:"t"`
+	Type string  `json:"type"`
+	X    int     `json:"x"`
+	Y    int     `json:"y"`
+	Key  string  `json:"key"`
+}
+
+// ReadEvents 读取事件文件
+func ReadEvents(filename string) ([]Event, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("打开事件文件失败: %w", err)
+	}
+	defer f.Close()
+	return ParseEvents(f)
+}
+
+// ParseEvents 解析 JSON Lines 格式的事件，每行形如
+//
+//	{"t": 1.25, "type": "click", "x": 640, "y": 360}
+//	{"t": 2.5, "type": "key", "key": "Ctrl+S"}
+//
+// t 为秒，空行被忽略，返回的事件按时间排序。
+func ParseEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var line eventLine
+		if err := json.Unmarshal([]byte(text), &line); err != nil {
+			return nil, fmt.Errorf("事件文件第 %d 行无效: %w", n, err)
+		}
+		kind, err := ParseEventKind(line.Type)
+		if err != nil {
+			return nil, fmt.Errorf("事件文件第 %d 行: %w", n, err)
+		}
+		if kind == EventKey && line.Key == "" {
+			return nil, fmt.Errorf("事件文件第 %d 行缺少 key", n)
+		}
+		events = append(events, Event{
+			Time: time.Duration(line.T * float64(time.Second)),
+			Kind: kind,
+			X:    line.X,
+			Y:    line.Y,
+			Key:  line.Key,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取事件文件失败: %w", err)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time < events[j].Time })
+	return events, nil
+}
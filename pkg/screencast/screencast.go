@@ -0,0 +1,385 @@
+// Package screencast 录屏教程的后期处理：按录制时记录的输入事件高亮光标和点击、显示按键，
+// 并在点击处自动推近，由 effects、video、subtitles 和 compositing 中的基础图层组合而成。
+package screencast
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/compositing"
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/subtitles"
+	"moviepy-go/pkg/video"
+)
+
+// CursorOptions 光标与点击高亮选项
+type CursorOptions struct {
+	Radius        float64       // 跟随光标的高亮圆半径，默认画面高度的 1/36
+	Color         color.Color   // 高亮圆颜色，默认半透明黄色
+	ClickColor    color.Color   // 点击波纹颜色，默认橙红色
+	ClickDuration time.Duration // 点击波纹扩散并淡出的时长，默认 400ms
+}
+
+// KeyOptions 按键显示选项
+type KeyOptions struct {
+	FontName string // 字体族名，默认由 fontconfig 选择
+	FontSize int    // 字号，默认画面高度的 1/16
+	// Hold 按键之后保持显示的时长，默认 1.5s；间隔短于 Hold 的按键连成一行显示
+	Hold    time.Duration
+	MarginV int // 距底边的距离，默认画面高度的 1/12
+	MaxKeys int // 一行最多显示的按键数，超出时去掉最早的，默认 6
+}
+
+// ZoomOptions 自动推近选项
+type ZoomOptions struct {
+	Factor float64 // 放大倍数，默认 2
+	// Transition 推近、拉远和在点击之间平移的时长，默认 500ms；推近在点击时刻完成
+	Transition time.Duration
+	// Hold 最后一次点击后保持放大的时长，默认 2s；间隔更短的点击之间镜头平移而不拉远
+	Hold time.Duration
+}
+
+// Options 录屏后期选项，某项为 nil 时不做该项处理；传入 nil 的 Options 时三项都使用默认值
+type Options struct {
+	Cursor *CursorOptions
+	Keys   *KeyOptions
+	Zoom   *ZoomOptions
+}
+
+// Clip 录屏后期处理的结果，以处理后的录屏为底、保留其音频的合成剪辑
+type Clip struct {
+	*compositing.CompositeVideoClip
+	layers []core.VideoClip // Process 创建的图层
+}
+
+// Process 按事件处理录屏剪辑：Zoom 推近画面，Cursor 叠加光标高亮，Keys 在底部显示按键
+//
+// 事件坐标为 clip 画面的像素，时间为 clip 内的时间。clip 归调用者所有，Close 只关闭 Process 创建的图层。
+func Process(clip core.VideoClip, events []Event, options *Options, processMgr *ffmpeg.ProcessManager) (*Clip, error) {
+	if options == nil {
+		options = &Options{Cursor: &CursorOptions{}, Keys: &KeyOptions{}, Zoom: &ZoomOptions{}}
+	}
+	width, height := clip.Width(), clip.Height()
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("无效的录屏尺寸: %dx%d", width, height)
+	}
+
+	result := &Clip{}
+	base := clip
+	var viewport func(t time.Duration) image.Rectangle
+	if options.Zoom != nil {
+		viewport = AutoZoom(events, width, height, options.Zoom)
+		zoomed := video.NewEffectVideoClip(clip, processMgr)
+		zoomed.AddEffect(effects.NewViewportEffect(viewport))
+		base = zoomed
+		result.layers = append(result.layers, zoomed)
+	}
+
+	layers := []core.VideoClip{base}
+	if options.Cursor != nil {
+		cursor := CursorLayer(events, width, height, clip.Duration(), clip.FPS(), viewport, options.Cursor)
+		layers = append(layers, cursor)
+		result.layers = append(result.layers, cursor)
+	}
+	if options.Keys != nil {
+		if script := KeystrokeScript(events, width, height, options.Keys); len(script.Events) > 0 {
+			keys := subtitles.NewASSClip(script, width, height, clip.Duration(), clip.FPS(), processMgr)
+			layers = append(layers, keys)
+			result.layers = append(result.layers, keys)
+		}
+	}
+	composite, err := compositing.NewCompositeVideoClip(layers, nil, compositing.Normal, processMgr)
+	if err != nil {
+		result.closeLayers()
+		return nil, err
+	}
+	result.CompositeVideoClip = composite
+	return result, nil
+}
+
+// Close 关闭合成剪辑和 Process 创建的图层
+func (c *Clip) Close() error {
+	return errors.Join(c.CompositeVideoClip.Close(), c.closeLayers())
+}
+
+// closeLayers 关闭 Process 创建的图层
+func (c *Clip) closeLayers() error {
+	var errs []error
+	for _, layer := range c.layers {
+		if err := layer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.layers = nil
+	return errors.Join(errs...)
+}
+
+// AutoZoom 返回随时间变化的视口：每次点击前推近到以点击处为中心、放大 Factor 倍的区域，
+// 此后 Hold 内的点击之间平移镜头，最后一次点击 Hold 后拉远；其余时间为整个画面
+//
+// 返回的函数可传给 effects.NewViewportEffect，也用于把画面坐标换算到推近后的画面。
+func AutoZoom(events []Event, width, height int, options *ZoomOptions) func(t time.Duration) image.Rectangle {
+	opts := ZoomOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Factor <= 1 {
+		opts.Factor = 2
+	}
+	if opts.Transition <= 0 {
+		opts.Transition = 500 * time.Millisecond
+	}
+	if opts.Hold <= 0 {
+		opts.Hold = 2 * time.Second
+	}
+
+	// 推近前拉远还未结束的点击归入同一段
+	type segment struct {
+		clicks []Event
+	}
+	var segments []segment
+	for _, event := range events {
+		if event.Kind != EventClick {
+			continue
+		}
+		if n := len(segments); n > 0 {
+			last := segments[n-1].clicks
+			if event.Time-opts.Transition <= last[len(last)-1].Time+opts.Hold+opts.Transition {
+				segments[n-1].clicks = append(segments[n-1].clicks, event)
+				continue
+			}
+		}
+		segments = append(segments, segment{clicks: []Event{event}})
+	}
+
+	full := image.Rect(0, 0, width, height)
+	zw := int(math.Round(float64(width) / opts.Factor))
+	zh := int(math.Round(float64(height) / opts.Factor))
+	transition := float64(opts.Transition)
+	return func(t time.Duration) image.Rectangle {
+		i := sort.Search(len(segments), func(i int) bool {
+			clicks := segments[i].clicks
+			return clicks[len(clicks)-1].Time+opts.Hold+opts.Transition > t
+		})
+		if i == len(segments) || t < segments[i].clicks[0].Time-opts.Transition {
+			return full
+		}
+		clicks := segments[i].clicks
+		first, last := clicks[0].Time, clicks[len(clicks)-1].Time
+
+		// 推近和拉远的进度
+		progress := 1.0
+		switch {
+		case t < first:
+			progress = video.EaseInOutCubic(1 - float64(first-t)/transition)
+		case t > last+opts.Hold:
+			progress = video.EaseInOutCubic(1 - float64(t-last-opts.Hold)/transition)
+		}
+
+		// 两次点击之间在后一次点击前的 Transition 内平移
+		j := sort.Search(len(clicks), func(j int) bool { return clicks[j].Time > t })
+		cx, cy := float64(clicks[max(j-1, 0)].X), float64(clicks[max(j-1, 0)].Y)
+		if j > 0 && j < len(clicks) {
+			from, to := clicks[j-1], clicks[j]
+			panStart := max(from.Time, to.Time-opts.Transition)
+			if t > panStart {
+				k := video.EaseInOutCubic(float64(t-panStart) / float64(to.Time-panStart))
+				cx += (float64(to.X) - cx) * k
+				cy += (float64(to.Y) - cy) * k
+			}
+		}
+
+		x := max(0, min(int(math.Round(cx))-zw/2, width-zw))
+		y := max(0, min(int(math.Round(cy))-zh/2, height-zh))
+		lerp := func(a, b int) int { return int(math.Round(float64(a) + float64(b-a)*progress)) }
+		return image.Rect(lerp(full.Min.X, x), lerp(full.Min.Y, y), lerp(full.Max.X, x+zw), lerp(full.Max.Y, y+zh))
+	}
+}
+
+// CursorLayer 创建光标高亮图层：跟随光标的半透明圆和点击处扩散的波纹
+//
+// 光标位置在 move 和 click 事件之间线性插值。viewport 为画面推近时的视口（见 AutoZoom），
+// 高亮按视口换算到推近后的画面；不推近时传入 nil。
+func CursorLayer(events []Event, width, height int, duration time.Duration, fps float64, viewport func(t time.Duration) image.Rectangle, options *CursorOptions) *video.ShapeClip {
+	opts := CursorOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Radius <= 0 {
+		opts.Radius = float64(height) / 36
+	}
+	if opts.Color == nil {
+		opts.Color = color.NRGBA{R: 255, G: 220, B: 0, A: 96}
+	}
+	if opts.ClickColor == nil {
+		opts.ClickColor = color.NRGBA{R: 255, G: 80, B: 40, A: 230}
+	}
+	if opts.ClickDuration <= 0 {
+		opts.ClickDuration = 400 * time.Millisecond
+	}
+
+	var pointer, clicks []Event
+	for _, event := range events {
+		switch event.Kind {
+		case EventClick:
+			clicks = append(clicks, event)
+			pointer = append(pointer, event)
+		case EventMove:
+			pointer = append(pointer, event)
+		}
+	}
+
+	return video.NewAnimatedShapeClip(width, height, duration, fps, func(t time.Duration) []video.Shape {
+		// 画面坐标到推近后画面的换算
+		offsetX, offsetY, scale := 0.0, 0.0, 1.0
+		if viewport != nil {
+			if vp := viewport(t); !vp.Empty() {
+				offsetX, offsetY = float64(vp.Min.X), float64(vp.Min.Y)
+				scale = float64(width) / float64(vp.Dx())
+			}
+		}
+		at := func(x, y float64) (float64, float64) {
+			return (x - offsetX) * scale, (y - offsetY) * scale
+		}
+
+		var shapes []video.Shape
+		if len(pointer) > 0 {
+			x, y := at(cursorAt(pointer, t))
+			shapes = append(shapes, video.Shape{Kind: video.ShapeCircle, X: x, Y: y, Radius: opts.Radius * scale, Fill: opts.Color})
+		}
+		for _, click := range clicks {
+			if t < click.Time || t >= click.Time+opts.ClickDuration {
+				continue
+			}
+			k := float64(t-click.Time) / float64(opts.ClickDuration)
+			x, y := at(float64(click.X), float64(click.Y))
+			shapes = append(shapes, video.Shape{
+				Kind:        video.ShapeCircle,
+				X:           x,
+				Y:           y,
+				Radius:      opts.Radius * (0.5 + k) * scale,
+				Stroke:      fadeColor(opts.ClickColor, 1-k),
+				StrokeWidth: math.Max(2, opts.Radius/6) * scale,
+			})
+		}
+		return shapes
+	})
+}
+
+// cursorAt 返回时间 t 处在相邻光标事件之间线性插值的位置
+func cursorAt(pointer []Event, t time.Duration) (float64, float64) {
+	i := sort.Search(len(pointer), func(i int) bool { return pointer[i].Time > t })
+	if i == 0 {
+		return float64(pointer[0].X), float64(pointer[0].Y)
+	}
+	from := pointer[i-1]
+	if i == len(pointer) || pointer[i].Time == from.Time {
+		return float64(from.X), float64(from.Y)
+	}
+	to := pointer[i]
+	k := float64(t-from.Time) / float64(to.Time-from.Time)
+	return float64(from.X) + float64(to.X-from.X)*k, float64(from.Y) + float64(to.Y-from.Y)*k
+}
+
+// fadeColor 把颜色的不透明度乘以 k
+func fadeColor(c color.Color, k float64) color.Color {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	n.A = uint8(math.Round(float64(n.A) * math.Max(0, math.Min(1, k))))
+	return n
+}
+
+// KeystrokeScript 生成在画面底部居中显示按键的 ASS 脚本，可由 subtitles.NewASSClip 渲染，
+// 也可写出为 .ass 文件在其他软件中调整
+func KeystrokeScript(events []Event, width, height int, options *KeyOptions) *subtitles.ASSScript {
+	opts := KeyOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.FontSize <= 0 {
+		opts.FontSize = max(1, height/16)
+	}
+	if opts.Hold <= 0 {
+		opts.Hold = 1500 * time.Millisecond
+	}
+	if opts.MarginV <= 0 {
+		opts.MarginV = height / 12
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = 6
+	}
+
+	script := &subtitles.ASSScript{
+		Info: []subtitles.ASSField{
+			{Key: "ScriptType", Value: "v4.00+"},
+			{Key: "PlayResX", Value: strconv.Itoa(width)},
+			{Key: "PlayResY", Value: strconv.Itoa(height)},
+		},
+		Styles: []subtitles.ASSStyle{{
+			Name:            "Keys",
+			FontName:        opts.FontName,
+			FontSize:        float64(opts.FontSize),
+			PrimaryColour:   color.RGBA{R: 255, G: 255, B: 255, A: 255},
+			SecondaryColour: color.RGBA{R: 255, G: 255, B: 255, A: 255},
+			OutlineColour:   color.RGBA{A: 255},
+			BackColour:      color.RGBA{A: 128},
+			Bold:            true,
+			ScaleX:          100,
+			ScaleY:          100,
+			BorderStyle:     1,
+			Outline:         math.Max(2, float64(opts.FontSize)/12),
+			Alignment:       2,
+			MarginL:         10,
+			MarginR:         10,
+			MarginV:         opts.MarginV,
+		}},
+	}
+
+	var keys []Event
+	for _, event := range events {
+		if event.Kind == EventKey {
+			keys = append(keys, event)
+		}
+	}
+	// 同一行的按键逐个追加，每次追加替换上一条字幕；只在一行出现和消失时淡入淡出
+	escape := strings.NewReplacer("{", "｛", "}", "｝", `\`, "＼", "\n", " ", "\r", "")
+	var line []string
+	for i, key := range keys {
+		first := i == 0 || key.Time-keys[i-1].Time > opts.Hold
+		if first {
+			line = line[:0]
+		}
+		line = append(line, escape.Replace(key.Key))
+		if len(line) > opts.MaxKeys {
+			line = line[len(line)-opts.MaxKeys:]
+		}
+
+		end := key.Time + opts.Hold
+		last := i == len(keys)-1 || keys[i+1].Time > end
+		if !last {
+			end = keys[i+1].Time
+		}
+		fadeIn, fadeOut := 0, 0
+		if first {
+			fadeIn = 80
+		}
+		if last {
+			fadeOut = 150
+		}
+		script.Events = append(script.Events, subtitles.ASSEvent{
+			Start: key.Time,
+			End:   end,
+			Style: "Keys",
+			Text:  fmt.Sprintf(`{\fad(%d,%d)}%s`, fadeIn, fadeOut, strings.Join(line, "  ")),
+		})
+	}
+	return script
+}
@@ -0,0 +1,756 @@
+package subtitles
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// ASSField [Script Info] 中的一项
+type ASSField struct {
+	Key, Value string
+}
+
+// ASSSection 本包不解析的段落（如 [Fonts]、[Graphics]、[Aegisub Project Garbage]），写出时原样保留
+type ASSSection struct {
+	Name  string
+	Lines []string
+}
+
+// ASSStyle [V4+ Styles] 中的样式，颜色为非预乘的 RGBA（A 为不透明度），尺寸以 PlayRes 为单位
+type ASSStyle struct {
+	Name            string
+	FontName        string
+	FontSize        float64
+	PrimaryColour   color.RGBA
+	SecondaryColour color.RGBA // 卡拉 OK 未唱到的颜色
+	OutlineColour   color.RGBA
+	BackColour      color.RGBA // 阴影颜色
+	Bold, Italic    bool
+	Underline       bool
+	StrikeOut       bool
+	ScaleX, ScaleY  float64 // 百分比，默认 100
+	Spacing         float64
+	Angle           float64
+	BorderStyle     int // 1 描边加阴影，3 不透明底框
+	Outline         float64
+	Shadow          float64
+	Alignment       int // 小键盘方位 1–9，2 为底部居中
+	MarginL         int
+	MarginR         int
+	MarginV         int
+	Encoding        int
+}
+
+// ASSEvent [Events] 中的一行，Text 保留原始的覆盖标签
+type ASSEvent struct {
+	Comment    bool // Comment 行不显示，写出时保留
+	Layer      int
+	Start, End time.Duration
+	Style      string
+	Name       string
+	MarginL    int // 非 0 时覆盖样式的边距
+	MarginR    int
+	MarginV    int
+	Effect     string
+	Text       string
+}
+
+// ASSScript 解析后的 ASS/SSA 字幕脚本
+//
+// SSA v4 脚本读入时转换为 v4+ 的字段（TertiaryColour 作为描边颜色，旧的对齐编号转换为小键盘方位），
+// 写出时总是使用 v4+ 格式。
+type ASSScript struct {
+	Info     []ASSField
+	Styles   []ASSStyle
+	Events   []ASSEvent
+	Sections []ASSSection
+}
+
+// assStyleFormat v4+ 样式的字段顺序
+var assStyleFormat = []string{"Name", "Fontname", "Fontsize", "PrimaryColour", "SecondaryColour", "OutlineColour", "BackColour",
+	"Bold", "Italic", "Underline", "StrikeOut", "ScaleX", "ScaleY", "Spacing", "Angle", "BorderStyle", "Outline", "Shadow",
+	"Alignment", "MarginL", "MarginR", "MarginV", "Encoding"}
+
+// assEventFormat v4+ 事件的字段顺序
+var assEventFormat = []string{"Layer", "Start", "End", "Style", "Name", "MarginL", "MarginR", "MarginV", "Effect", "Text"}
+
+// ReadASS 读取 .ass 或 .ssa 字幕文件
+func ReadASS(filename string) (*ASSScript, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("打开字幕文件失败: %w", err)
+	}
+	defer file.Close()
+	script, err := ParseASS(file)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", filepath.Base(filename), err)
+	}
+	return script, nil
+}
+
+// ParseASS 解析 ASS/SSA 脚本，字段顺序以各段的 Format 行为准
+func ParseASS(r io.Reader) (*ASSScript, error) {
+	script := &ASSScript{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	section := ""
+	legacy := false
+	var format []string
+	var extra *ASSSection
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if number == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section, format, extra = strings.ToLower(trimmed), nil, nil
+			switch section {
+			case "[script info]", "[v4+ styles]", "[v4 styles]", "[events]":
+				legacy = legacy || section == "[v4 styles]"
+			default:
+				script.Sections = append(script.Sections, ASSSection{Name: trimmed[1 : len(trimmed)-1]})
+				extra = &script.Sections[len(script.Sections)-1]
+			}
+			continue
+		}
+		if extra != nil {
+			extra.Lines = append(extra.Lines, line)
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "!:") {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch section {
+		case "[script info]":
+			script.Info = append(script.Info, ASSField{Key: key, Value: value})
+		case "[v4+ styles]", "[v4 styles]":
+			switch key {
+			case "Format":
+				format = splitFormat(value)
+			case "Style":
+				if format == nil {
+					return nil, fmt.Errorf("第 %d 行: Style 之前缺少 Format", number)
+				}
+				style, err := parseASSStyle(format, value, legacy)
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 行: %w", number, err)
+				}
+				script.Styles = append(script.Styles, style)
+			}
+		case "[events]":
+			switch key {
+			case "Format":
+				format = splitFormat(value)
+			case "Dialogue", "Comment":
+				if format == nil {
+					return nil, fmt.Errorf("第 %d 行: %s 之前缺少 Format", number, key)
+				}
+				event, err := parseASSEvent(format, value)
+				if err != nil {
+					return nil, fmt.Errorf("第 %d 行: %w", number, err)
+				}
+				event.Comment = key == "Comment"
+				script.Events = append(script.Events, event)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取字幕失败: %w", err)
+	}
+	return script, nil
+}
+
+// splitFormat 拆分 Format 行的字段名
+func splitFormat(value string) []string {
+	fields := strings.Split(value, ",")
+	for i, field := range fields {
+		fields[i] = strings.ToLower(strings.TrimSpace(field))
+	}
+	return fields
+}
+
+// splitValues 按 Format 的字段数拆分一行，最后一个字段（Text）可以包含逗号
+func splitValues(format []string, value string) map[string]string {
+	parts := strings.SplitN(value, ",", len(format))
+	values := make(map[string]string, len(format))
+	for i, part := range parts {
+		if format[i] == "text" {
+			values[format[i]] = part
+		} else {
+			values[format[i]] = strings.TrimSpace(part)
+		}
+	}
+	return values
+}
+
+// parseASSStyle 解析一行样式，legacy 为 SSA v4 的字段
+func parseASSStyle(format []string, value string, legacy bool) (ASSStyle, error) {
+	v := splitValues(format, value)
+	style := ASSStyle{
+		Name:     v["name"],
+		FontName: v["fontname"],
+		ScaleX:   100,
+		ScaleY:   100,
+	}
+	var err error
+	number := func(key string) float64 {
+		s, ok := v[key]
+		if !ok || s == "" || err != nil {
+			return 0
+		}
+		f, parseErr := strconv.ParseFloat(s, 64)
+		if parseErr != nil {
+			err = fmt.Errorf("样式 %s 的 %s 不是数字: %q", style.Name, key, s)
+		}
+		return f
+	}
+	colour := func(key string) color.RGBA {
+		c, parseErr := parseASSColor(v[key])
+		if parseErr != nil && err == nil {
+			err = fmt.Errorf("样式 %s 的 %s: %w", style.Name, key, parseErr)
+		}
+		return c
+	}
+
+	style.FontSize = number("fontsize")
+	style.PrimaryColour = colour("primarycolour")
+	style.SecondaryColour = colour("secondarycolour")
+	if _, ok := v["tertiarycolour"]; ok {
+		style.OutlineColour = colour("tertiarycolour")
+	} else {
+		style.OutlineColour = colour("outlinecolour")
+	}
+	style.BackColour = colour("backcolour")
+	style.Bold = number("bold") != 0
+	style.Italic = number("italic") != 0
+	style.Underline = number("underline") != 0
+	style.StrikeOut = number("strikeout") != 0
+	if _, ok := v["scalex"]; ok {
+		style.ScaleX = number("scalex")
+	}
+	if _, ok := v["scaley"]; ok {
+		style.ScaleY = number("scaley")
+	}
+	style.Spacing = number("spacing")
+	style.Angle = number("angle")
+	style.BorderStyle = int(number("borderstyle"))
+	style.Outline = number("outline")
+	style.Shadow = number("shadow")
+	style.Alignment = int(number("alignment"))
+	if legacy {
+		style.Alignment = legacyAlignment(style.Alignment)
+	}
+	if style.Alignment < 1 || style.Alignment > 9 {
+		style.Alignment = 2
+	}
+	style.MarginL = int(number("marginl"))
+	style.MarginR = int(number("marginr"))
+	style.MarginV = int(number("marginv"))
+	style.Encoding = int(number("encoding"))
+	return style, err
+}
+
+// parseASSEvent 解析一行事件
+func parseASSEvent(format []string, value string) (ASSEvent, error) {
+	v := splitValues(format, value)
+	start, err := parseASSTime(v["start"])
+	if err != nil {
+		return ASSEvent{}, err
+	}
+	end, err := parseASSTime(v["end"])
+	if err != nil {
+		return ASSEvent{}, err
+	}
+	event := ASSEvent{
+		Start:  start,
+		End:    end,
+		Style:  strings.TrimPrefix(v["style"], "*"),
+		Name:   v["name"],
+		Effect: v["effect"],
+		Text:   v["text"],
+	}
+	event.Layer, _ = strconv.Atoi(v["layer"])
+	event.MarginL, _ = strconv.Atoi(v["marginl"])
+	event.MarginR, _ = strconv.Atoi(v["marginr"])
+	event.MarginV, _ = strconv.Atoi(v["marginv"])
+	return event, nil
+}
+
+// legacyAlignment 把 SSA 的对齐编号（1–3 底部，+4 顶部，+8 中部）转换为小键盘方位
+func legacyAlignment(a int) int {
+	switch {
+	case a >= 9 && a <= 11:
+		return a - 5
+	case a >= 5 && a <= 7:
+		return a + 2
+	default:
+		return a
+	}
+}
+
+// parseASSColor 解析 &HAABBGGRR、&HBBGGRR& 或十进制（SSA）颜色，空字符串为不透明黑色
+func parseASSColor(s string) (color.RGBA, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return color.RGBA{A: 255}, nil
+	}
+	var value uint64
+	var err error
+	if upper := strings.ToUpper(s); strings.HasPrefix(upper, "&H") {
+		value, err = strconv.ParseUint(strings.TrimRight(upper[2:], "&"), 16, 32)
+	} else {
+		var signed int64
+		signed, err = strconv.ParseInt(s, 10, 64)
+		value = uint64(uint32(signed))
+	}
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("无效的颜色 %q", s)
+	}
+	return color.RGBA{
+		R: uint8(value),
+		G: uint8(value >> 8),
+		B: uint8(value >> 16),
+		A: 255 - uint8(value>>24),
+	}, nil
+}
+
+// parseASSTime 解析 h:mm:ss.cc
+func parseASSTime(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("无效的时间 %q", s)
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	seconds, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("无效的时间 %q", s)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)).Round(10*time.Millisecond), nil
+}
+
+// InfoValue 返回 [Script Info] 中 key 的值
+func (s *ASSScript) InfoValue(key string) string {
+	for _, field := range s.Info {
+		if strings.EqualFold(field.Key, key) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// PlayRes 返回脚本坐标系的尺寸，缺失时按 libass 的规则推算（都缺失时为 384×288）
+func (s *ASSScript) PlayRes() (int, int) {
+	x, _ := strconv.Atoi(s.InfoValue("PlayResX"))
+	y, _ := strconv.Atoi(s.InfoValue("PlayResY"))
+	switch {
+	case x <= 0 && y <= 0:
+		return 384, 288
+	case y <= 0:
+		if x == 1280 {
+			return x, 1024
+		}
+		return x, x * 3 / 4
+	case x <= 0:
+		if y == 1024 {
+			return 1280, y
+		}
+		return y * 4 / 3, y
+	}
+	return x, y
+}
+
+// Style 按名称查找样式，找不到时返回 Default 样式或 libass 的默认样式
+func (s *ASSScript) Style(name string) ASSStyle {
+	for _, style := range s.Styles {
+		if style.Name == name {
+			return style
+		}
+	}
+	for _, style := range s.Styles {
+		if strings.EqualFold(style.Name, "Default") {
+			return style
+		}
+	}
+	return ASSStyle{
+		Name: "Default", FontName: "Arial", FontSize: 18,
+		PrimaryColour: color.RGBA{255, 255, 255, 255}, SecondaryColour: color.RGBA{255, 0, 0, 255},
+		OutlineColour: color.RGBA{A: 255}, BackColour: color.RGBA{A: 255},
+		ScaleX: 100, ScaleY: 100, BorderStyle: 1, Outline: 2, Shadow: 2, Alignment: 2,
+		MarginL: 20, MarginR: 20, MarginV: 20,
+	}
+}
+
+// Write 以 v4+ 格式写出脚本
+func (s *ASSScript) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "[Script Info]\n")
+	hasType := false
+	for _, field := range s.Info {
+		if strings.EqualFold(field.Key, "ScriptType") {
+			fmt.Fprint(bw, "ScriptType: v4.00+\n")
+			hasType = true
+			continue
+		}
+		fmt.Fprintf(bw, "%s: %s\n", field.Key, field.Value)
+	}
+	if !hasType {
+		fmt.Fprint(bw, "ScriptType: v4.00+\n")
+	}
+
+	fmt.Fprintf(bw, "\n[V4+ Styles]\nFormat: %s\n", strings.Join(assStyleFormat, ", "))
+	for _, style := range s.Styles {
+		fmt.Fprintf(bw, "Style: %s,%s,%s,%s,%s,%s,%s,%d,%d,%d,%d,%s,%s,%s,%s,%d,%s,%s,%d,%d,%d,%d,%d\n",
+			style.Name, style.FontName, assNumber(style.FontSize),
+			assColor(style.PrimaryColour), assColor(style.SecondaryColour), assColor(style.OutlineColour), assColor(style.BackColour),
+			assBool(style.Bold), assBool(style.Italic), assBool(style.Underline), assBool(style.StrikeOut),
+			assNumber(style.ScaleX), assNumber(style.ScaleY), assNumber(style.Spacing), assNumber(style.Angle),
+			style.BorderStyle, assNumber(style.Outline), assNumber(style.Shadow),
+			style.Alignment, style.MarginL, style.MarginR, style.MarginV, style.Encoding)
+	}
+
+	fmt.Fprintf(bw, "\n[Events]\nFormat: %s\n", strings.Join(assEventFormat, ", "))
+	for _, event := range s.Events {
+		kind := "Dialogue"
+		if event.Comment {
+			kind = "Comment"
+		}
+		fmt.Fprintf(bw, "%s: %d,%s,%s,%s,%s,%04d,%04d,%04d,%s,%s\n", kind, event.Layer,
+			assTime(event.Start), assTime(event.End), event.Style, event.Name,
+			event.MarginL, event.MarginR, event.MarginV, event.Effect, event.Text)
+	}
+
+	for _, section := range s.Sections {
+		fmt.Fprintf(bw, "\n[%s]\n", section.Name)
+		for _, line := range section.Lines {
+			fmt.Fprintln(bw, line)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteFile 写出 .ass 文件
+func (s *ASSScript) WriteFile(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("创建字幕文件失败: %w", err)
+	}
+	err = s.Write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filename)
+		return err
+	}
+	return nil
+}
+
+// assNumber 格式化样式中的数值，整数不带小数点
+func assNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// assBool ASS 中的真为 -1
+func assBool(v bool) int {
+	if v {
+		return -1
+	}
+	return 0
+}
+
+// ASSTag 覆盖标签，如 \pos(10,20) 的 Name 为 "pos"、Args 为 ["10", "20"]
+type ASSTag struct {
+	Name string
+	Args []string
+}
+
+// ASSSegment 一段文字及其前面的覆盖标签
+type ASSSegment struct {
+	Tags []ASSTag
+	Text string // \N、\n 已转换为换行，\h 为不换行空格
+}
+
+// assTagNames 已知的覆盖标签名，按最长匹配识别，使 \fscx120、\1c&H0000FF& 等能拆出参数
+var assTagNames = []string{
+	"xbord", "ybord", "xshad", "yshad", "iclip", "alpha", "fscx", "fscy", "move", "clip", "blur", "bord", "shad", "fade",
+	"fsp", "frx", "fry", "frz", "fax", "fay", "fad", "pos", "org", "pbo", "an", "be", "fn", "fs", "fr", "fe", "kf", "ko",
+	"1c", "2c", "3c", "4c", "1a", "2a", "3a", "4a", "a", "b", "c", "i", "k", "K", "p", "q", "r", "s", "t", "u",
+}
+
+// Segments 把事件文字拆分为覆盖标签和文字段
+func (e *ASSEvent) Segments() []ASSSegment {
+	var segments []ASSSegment
+	var tags []ASSTag
+	var text strings.Builder
+	flush := func() {
+		if len(tags) > 0 || text.Len() > 0 {
+			segments = append(segments, ASSSegment{Tags: tags, Text: text.String()})
+		}
+		tags = nil
+		text.Reset()
+	}
+	s := e.Text
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				text.WriteString(s[i:])
+				i = len(s)
+				continue
+			}
+			if text.Len() > 0 {
+				flush()
+			}
+			tags = append(tags, parseASSTags(s[i+1:i+end])...)
+			i += end
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == 'N' || s[i+1] == 'n'):
+			text.WriteByte('\n')
+			i++
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == 'h':
+			text.WriteString("\u00a0")
+			i++
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+	flush()
+	return segments
+}
+
+// PlainText 返回去掉覆盖标签的文字
+func (e *ASSEvent) PlainText() string {
+	var b strings.Builder
+	for _, segment := range e.Segments() {
+		b.WriteString(segment.Text)
+	}
+	return b.String()
+}
+
+// parseASSTags 解析一个 {} 块中的标签，块中不以 \ 开头的内容（注释）被忽略
+func parseASSTags(block string) []ASSTag {
+	var tags []ASSTag
+	for i := strings.IndexByte(block, '\\'); i >= 0 && i < len(block); {
+		i++
+		// 参数延续到下一个括号外的 \，\t(...) 中可以嵌套标签
+		depth, end := 0, i
+		for ; end < len(block); end++ {
+			if block[end] == '(' {
+				depth++
+			} else if block[end] == ')' {
+				depth--
+			} else if block[end] == '\\' && depth <= 0 {
+				break
+			}
+		}
+		if tag, ok := parseASSTag(block[i:end]); ok {
+			tags = append(tags, tag)
+		}
+		i = end
+	}
+	return tags
+}
+
+// parseASSTag 解析单个标签（不含开头的 \）
+func parseASSTag(s string) (ASSTag, bool) {
+	name := ""
+	for _, candidate := range assTagNames {
+		if strings.HasPrefix(s, candidate) && len(candidate) > len(name) {
+			name = candidate
+		}
+	}
+	if name == "" {
+		n := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+		if n == 0 {
+			return ASSTag{}, false
+		}
+		if n < 0 {
+			n = len(s)
+		}
+		name = s[:n]
+	}
+	arg := strings.TrimSpace(s[len(name):])
+	tag := ASSTag{Name: name}
+	if strings.HasPrefix(arg, "(") {
+		arg = strings.TrimSuffix(strings.TrimPrefix(arg, "("), ")")
+		if name == "t" {
+			// \t 的最后一个参数是标签序列，本身可能包含逗号
+			parts := strings.SplitN(arg, "\\", 2)
+			for _, part := range strings.Split(strings.TrimSuffix(parts[0], ","), ",") {
+				if part = strings.TrimSpace(part); part != "" {
+					tag.Args = append(tag.Args, part)
+				}
+			}
+			if len(parts) == 2 {
+				tag.Args = append(tag.Args, "\\"+parts[1])
+			}
+			return tag, true
+		}
+		for _, part := range strings.Split(arg, ",") {
+			tag.Args = append(tag.Args, strings.TrimSpace(part))
+		}
+	} else if arg != "" {
+		tag.Args = []string{arg}
+	}
+	return tag, true
+}
+
+// Karaoke 返回按 \k、\kf、\ko、\K 计时的音节，没有卡拉 OK 标签时返回 nil
+//
+// 音节开头的空白表示词的边界，需要按词使用时见 ASSScript.Track。
+func (e *ASSEvent) Karaoke() []Word {
+	var syllables []Word
+	cursor := e.Start
+	for _, segment := range e.Segments() {
+		var duration time.Duration
+		timed := false
+		for _, tag := range segment.Tags {
+			switch tag.Name {
+			case "k", "kf", "ko", "K":
+				if len(tag.Args) > 0 {
+					cs, _ := strconv.ParseFloat(tag.Args[0], 64)
+					duration += time.Duration(cs * float64(10*time.Millisecond))
+				}
+				timed = true
+			}
+		}
+		if !timed {
+			if len(syllables) > 0 {
+				syllables[len(syllables)-1].Text += segment.Text
+			}
+			continue
+		}
+		syllables = append(syllables, Word{Text: segment.Text, Start: cursor, End: min(cursor+duration, e.End)})
+		cursor += duration
+	}
+	return syllables
+}
+
+// Track 转换为字幕轨：有卡拉 OK 标签的行按音节计时合并为词，其余行整行同时显示
+//
+// 只保留文字和时间，样式取 Default 样式的字体和颜色，字号和边距按输出尺寸使用默认值；
+// 需要完整保留 ASS 效果时使用 Burn。
+func (s *ASSScript) Track() *Track {
+	style := s.Style("Default")
+	track := &Track{Style: Style{
+		FontName:  style.FontName,
+		Color:     style.PrimaryColour,
+		BaseColor: style.SecondaryColour,
+		Outline:   int(style.Outline),
+	}}
+	track.Style.applyDefaults()
+
+	karaoke := false
+	for _, event := range s.Events {
+		if event.Comment {
+			continue
+		}
+		var words []Word
+		if syllables := event.Karaoke(); len(syllables) > 0 {
+			karaoke = true
+			for _, syllable := range syllables {
+				text := strings.ReplaceAll(syllable.Text, "\n", " ")
+				joined := len(words) > 0 && text != "" && !unicode.IsSpace([]rune(text)[0])
+				if joined {
+					last := &words[len(words)-1]
+					last.Text += text
+					last.End = syllable.End
+					continue
+				}
+				if text = strings.TrimSpace(text); text != "" {
+					words = append(words, Word{Text: text, Start: syllable.Start, End: syllable.End})
+				}
+			}
+		} else if text := strings.Join(strings.Fields(event.PlainText()), " "); text != "" {
+			words = []Word{{Text: text, Start: event.Start, End: event.End}}
+		}
+		if len(words) > 0 {
+			track.Cues = append(track.Cues, Cue{Start: event.Start, End: event.End, Words: words, Speaker: event.Name})
+		}
+	}
+	track.Style.Plain = !karaoke
+	sort.SliceStable(track.Cues, func(i, j int) bool { return track.Cues[i].Start < track.Cues[j].Start })
+	return track
+}
+
+// ASSBurnOptions 通过 ass 滤镜烧录字幕的选项
+type ASSBurnOptions struct {
+	// FontsDir 额外的字体目录，脚本引用的字体不在系统中时使用
+	FontsDir   string
+	ProcessMgr *ffmpeg.ProcessManager
+}
+
+// Burn 通过 FFmpeg 的 ass 滤镜（libass）把脚本烧录到 input 的画面中写入 output，音频流复制
+//
+// libass 支持全部 ASS 特性（定位、移动、旋转、卡拉 OK、\t 动画、矢量绘图等）；只用到基本样式时
+// 也可以用 NewASSClip 在 Go 中渲染为图层再合成。
+func (s *ASSScript) Burn(ctx context.Context, input, output string, options *ASSBurnOptions) error {
+	if options == nil {
+		options = &ASSBurnOptions{}
+	}
+	processMgr := options.ProcessMgr
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+	assFile, err := processMgr.Temp().CreateFile("subtitles-*.ass")
+	if err != nil {
+		return fmt.Errorf("创建字幕文件失败: %w", err)
+	}
+	defer processMgr.Temp().Remove(assFile)
+	if err := s.WriteFile(assFile); err != nil {
+		return err
+	}
+	return burnASS(ctx, input, output, assFile, options.FontsDir, processMgr)
+}
+
+// burnASS 用 ass 滤镜把 assFile 烧录到 input 写入 output，失败时 output 保持不变
+func burnASS(ctx context.Context, input, output, assFile, fontsDir string, processMgr *ffmpeg.ProcessManager) error {
+	filter := "ass=" + ffmpeg.QuoteFilterArg(assFile)
+	if fontsDir != "" {
+		filter += ":fontsdir=" + ffmpeg.QuoteFilterArg(fontsDir)
+	}
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-i", input,
+		"-vf", filter,
+		"-c:a", "copy",
+	}
+	// 写入同目录的临时文件，烧录成功后才替换 output
+	staged, err := ffmpeg.TempOutputPath(output)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staged)
+	process, err := processMgr.StartProcess(ctx, "ffmpeg", append(args, "-y", staged), nil)
+	if err != nil {
+		return fmt.Errorf("启动字幕烧录进程失败: %w", err)
+	}
+	if err := process.Wait(); err != nil {
+		return fmt.Errorf("烧录字幕失败: %w", err)
+	}
+	return ffmpeg.CommitOutput(staged, output)
+}
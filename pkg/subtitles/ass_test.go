@@ -0,0 +1,267 @@
+package subtitles
+
+import (
+	"context"
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/ffmpeg"
+)
+
+const testASS = "\ufeff[Script Info]\r\n" +
+	"; 注释行\r\n" +
+	"Title: 测试\r\n" +
+	"PlayResX: 1280\r\n" +
+	"PlayResY: 720\r\n" +
+	"\r\n" +
+	"[V4+ Styles]\r\n" +
+	"Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\r\n" +
+	"Style: Default,Arial,48,&H000000FF,&H00FFFFFF,&H80000000,&H00000000,-1,0,0,0,120,100,0,0,1,2.5,1,8,10,20,30,1\r\n" +
+	"Style: Sign,Noto Sans,36,&HFF00FF00,&H00FFFFFF,&H00000000,&H00000000,0,-1,0,0,100,100,0,0,3,0,0,0,0,0,0,1\r\n" +
+	"\r\n" +
+	"[Events]\r\n" +
+	"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\r\n" +
+	"Dialogue: 1,0:00:01.00,0:00:03.50,Default,小明,0,0,0,,第一行\\N第二行, 带逗号\r\n" +
+	"Comment: 0,0:00:04.00,0:00:05.00,*Sign,,5,6,7,Scroll up,注释\r\n" +
+	"Dialogue: 0,1:02:03.45,1:02:04.00,Sign,,0,0,0,,{\\pos(10,20)\\1c&H0000FF&}红{\\i1}斜\\h体\r\n" +
+	"\r\n" +
+	"[Fonts]\r\n" +
+	"fontname: a.ttf\r\n"
+
+func TestParseASSStyles(t *testing.T) {
+	script, err := ParseASS(strings.NewReader(testASS))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if got := script.InfoValue("title"); got != "测试" {
+		t.Errorf("Title 为 %q，期望 测试", got)
+	}
+	if w, h := script.PlayRes(); w != 1280 || h != 720 {
+		t.Errorf("PlayRes 为 %d×%d，期望 1280×720", w, h)
+	}
+	if len(script.Styles) != 2 {
+		t.Fatalf("解析出 %d 个样式，期望 2", len(script.Styles))
+	}
+
+	want := ASSStyle{
+		Name: "Default", FontName: "Arial", FontSize: 48,
+		PrimaryColour:   color.RGBA{R: 255, A: 255},
+		SecondaryColour: color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		OutlineColour:   color.RGBA{A: 127},
+		BackColour:      color.RGBA{A: 255},
+		Bold:            true,
+		ScaleX:          120, ScaleY: 100,
+		BorderStyle: 1, Outline: 2.5, Shadow: 1,
+		Alignment: 8, MarginL: 10, MarginR: 20, MarginV: 30, Encoding: 1,
+	}
+	if got := script.Style("Default"); got != want {
+		t.Errorf("Default 样式为\n%+v\n期望\n%+v", got, want)
+	}
+	sign := script.Style("Sign")
+	if !sign.Italic || sign.Bold || sign.PrimaryColour != (color.RGBA{G: 255}) || sign.BorderStyle != 3 {
+		t.Errorf("Sign 样式解析错误: %+v", sign)
+	}
+	if sign.Alignment != 2 {
+		t.Errorf("无效的对齐 0 应回退为 2，实际 %d", sign.Alignment)
+	}
+	if len(script.Sections) != 1 || script.Sections[0].Name != "Fonts" {
+		t.Errorf("未知段落应原样保留，实际 %+v", script.Sections)
+	}
+}
+
+func TestParseASSLegacyStyles(t *testing.T) {
+	ssa := "[V4 Styles]\n" +
+		"Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, TertiaryColour, BackColour, Bold, Italic, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, AlphaLevel, Encoding\n" +
+		"Style: Default,Arial,20,16777215,65535,255,0,0,0,1,2,0,6,30,30,10,0,0\n"
+	script, err := ParseASS(strings.NewReader(ssa))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	style := script.Style("Default")
+	if style.Alignment != 8 {
+		t.Errorf("SSA 对齐 6 应转换为小键盘 8，实际 %d", style.Alignment)
+	}
+	if style.OutlineColour != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("TertiaryColour 应作为描边颜色，实际 %v", style.OutlineColour)
+	}
+	if style.PrimaryColour != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("十进制颜色解析错误: %v", style.PrimaryColour)
+	}
+}
+
+func TestParseASSEvents(t *testing.T) {
+	script, err := ParseASS(strings.NewReader(testASS))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(script.Events) != 3 {
+		t.Fatalf("解析出 %d 个事件，期望 3", len(script.Events))
+	}
+
+	first := script.Events[0]
+	if first.Layer != 1 || first.Start != time.Second || first.End != 3500*time.Millisecond || first.Name != "小明" {
+		t.Errorf("第一个事件解析错误: %+v", first)
+	}
+	// Text 是最后一个字段，可以包含逗号；\N 为换行
+	if first.Text != `第一行\N第二行, 带逗号` {
+		t.Errorf("Text 为 %q", first.Text)
+	}
+	if got := first.PlainText(); got != "第一行\n第二行, 带逗号" {
+		t.Errorf("PlainText 为 %q", got)
+	}
+
+	comment := script.Events[1]
+	if !comment.Comment || comment.Style != "Sign" || comment.MarginL != 5 || comment.MarginR != 6 || comment.MarginV != 7 || comment.Effect != "Scroll up" {
+		t.Errorf("Comment 事件解析错误: %+v", comment)
+	}
+
+	third := script.Events[2]
+	if want := time.Hour + 2*time.Minute + 3450*time.Millisecond; third.Start != want {
+		t.Errorf("开始时间为 %v，期望 %v", third.Start, want)
+	}
+	if got := third.PlainText(); got != "红斜 体" {
+		t.Errorf("PlainText 为 %q", got)
+	}
+}
+
+func TestASSEventSegments(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []ASSSegment
+	}{
+		{"纯文字", "hello", []ASSSegment{{Text: "hello"}}},
+		{"位置和颜色", `{\pos(10,20)\1c&H0000FF&}红`, []ASSSegment{
+			{Tags: []ASSTag{{Name: "pos", Args: []string{"10", "20"}}, {Name: "1c", Args: []string{"&H0000FF&"}}}, Text: "红"},
+		}},
+		{"最长匹配", `{\fscx120\fs40\bord2}A`, []ASSSegment{
+			{Tags: []ASSTag{{Name: "fscx", Args: []string{"120"}}, {Name: "fs", Args: []string{"40"}}, {Name: "bord", Args: []string{"2"}}}, Text: "A"},
+		}},
+		{"多段", `a{\i1}b{\i0}c`, []ASSSegment{
+			{Text: "a"},
+			{Tags: []ASSTag{{Name: "i", Args: []string{"1"}}}, Text: "b"},
+			{Tags: []ASSTag{{Name: "i", Args: []string{"0"}}}, Text: "c"},
+		}},
+		{"嵌套动画", `{\t(0,500,\frz30\fscx50)}转`, []ASSSegment{
+			{Tags: []ASSTag{{Name: "t", Args: []string{"0", "500", `\frz30\fscx50`}}}, Text: "转"},
+		}},
+		{"块内注释", `{忽略\b1}粗`, []ASSSegment{{Tags: []ASSTag{{Name: "b", Args: []string{"1"}}}, Text: "粗"}}},
+		{"未闭合的块", `a{\b1`, []ASSSegment{{Text: `a{\b1`}}},
+		{"换行", `一\n二\N三`, []ASSSegment{{Text: "一\n二\n三"}}},
+		{"卡拉 OK", `{\k50}频{\kf25}道`, []ASSSegment{
+			{Tags: []ASSTag{{Name: "k", Args: []string{"50"}}}, Text: "频"},
+			{Tags: []ASSTag{{Name: "kf", Args: []string{"25"}}}, Text: "道"},
+		}},
+	}
+	for _, tt := range tests {
+		event := ASSEvent{Text: tt.text}
+		if got := event.Segments(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Segments 为 %+v，期望 %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestASSEventKaraoke(t *testing.T) {
+	event := ASSEvent{Start: time.Second, End: 2 * time.Second, Text: `{\k50}频{\kf25}道{\b1}!`}
+	want := []Word{
+		{Text: "频", Start: time.Second, End: 1500 * time.Millisecond},
+		{Text: "道!", Start: 1500 * time.Millisecond, End: 1750 * time.Millisecond},
+	}
+	if got := event.Karaoke(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Karaoke 为 %+v，期望 %+v", got, want)
+	}
+}
+
+func TestParseASSMalformed(t *testing.T) {
+	const styleFormat = "Format: Name, Fontname, Fontsize, PrimaryColour\n"
+	const eventFormat = "Format: Layer, Start, End, Style, Text\n"
+	tests := []struct {
+		name  string
+		input string
+		line  string // 错误信息中应包含的行号
+	}{
+		{"样式缺少 Format", "[V4+ Styles]\nStyle: Default,Arial,20,&H00FFFFFF\n", "第 2 行"},
+		{"事件缺少 Format", "[Events]\nDialogue: 0,0:00:00.00,0:00:01.00,Default,hi\n", "第 2 行"},
+		{"字号不是数字", "[V4+ Styles]\n" + styleFormat + "Style: Default,Arial,big,&H00FFFFFF\n", "第 3 行"},
+		{"无效颜色", "[V4+ Styles]\n" + styleFormat + "Style: Default,Arial,20,&HXYZ\n", "第 3 行"},
+		{"无效时间", "[Events]\n" + eventFormat + "Dialogue: 0,0:00.00,0:00:01.00,Default,hi\n", "第 3 行"},
+		{"时间不是数字", "[Events]\n" + eventFormat + "Dialogue: 0,0:00:00.00,0:aa:01.00,Default,hi\n", "第 3 行"},
+	}
+	for _, tt := range tests {
+		_, err := ParseASS(strings.NewReader(tt.input))
+		if err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.line) {
+			t.Errorf("%s: 错误 %q 应包含 %s", tt.name, err, tt.line)
+		}
+	}
+
+	// 没有冒号的行和未知的键被忽略
+	lenient := "[Events]\n" + eventFormat + "garbage line\nPicture: 0,0:00:00.00,0:00:01.00,Default,x\n" +
+		"Dialogue: 0,0:00:00.00,0:00:01.00,Default,hi\n"
+	script, err := ParseASS(strings.NewReader(lenient))
+	if err != nil {
+		t.Fatalf("无法识别的行应被忽略: %v", err)
+	}
+	if len(script.Events) != 1 || script.Events[0].Text != "hi" {
+		t.Fatalf("事件为 %+v，期望只有 hi", script.Events)
+	}
+}
+
+func TestASSWriteRoundTrip(t *testing.T) {
+	script, err := ParseASS(strings.NewReader(testASS))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	var out strings.Builder
+	if err := script.Write(&out); err != nil {
+		t.Fatalf("写出失败: %v", err)
+	}
+	again, err := ParseASS(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatalf("重新解析失败: %v", err)
+	}
+	if !reflect.DeepEqual(again.Styles, script.Styles) {
+		t.Errorf("样式往返后不一致:\n%+v\n%+v", again.Styles, script.Styles)
+	}
+	if !reflect.DeepEqual(again.Events, script.Events) {
+		t.Errorf("事件往返后不一致:\n%+v\n%+v", again.Events, script.Events)
+	}
+}
+
+func TestBurnFailureKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nfor a; do last=$a; done\necho partial > \"$last\"\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pm := ffmpeg.NewProcessManagerWithOptions(&ffmpeg.ProcessManagerOptions{FFmpegPath: path, FFprobePath: path})
+	defer pm.Close()
+
+	outDir := t.TempDir()
+	output := filepath.Join(outDir, "out.mp4")
+	if err := os.WriteFile(output, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script, err := ParseASS(strings.NewReader(testASS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := script.Burn(context.Background(), "in.mp4", output, &ASSBurnOptions{ProcessMgr: pm}); err == nil {
+		t.Fatal("FFmpeg 失败时应返回错误")
+	}
+	data, err := os.ReadFile(output)
+	if err != nil || string(data) != "previous" {
+		t.Fatalf("失败时不应改动已有输出，实际 %q (%v)", data, err)
+	}
+	if entries, _ := os.ReadDir(outDir); len(entries) != 1 {
+		t.Fatalf("失败后不应留下临时文件，目录中有 %d 个文件", len(entries))
+	}
+}
@@ -0,0 +1,440 @@
+package subtitles
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+// ASSClip 在 Go 中渲染 ASS 脚本得到的透明字幕图层，作为上层放入 CompositeVideoClip 与视频合成
+//
+// 每行字幕由 video.TextClip 渲染（复杂文字自动使用 Pango），首次显示时渲染并缓存。支持样式的字体、
+// 字号、粗斜体、主色与描边色、对齐和边距，以及行首的 \pos、\an、\a、\fn、\fs、\b、\i、\c、\1c、\3c、
+// \alpha、\1a、\3a、\bord、\fad 和 \r 标签；其余特性（卡拉 OK、\move、\t、旋转、裁剪、绘图、阴影、
+// 行内样式变化等）被忽略，可用 NativeUnsupported 检查，需要时改用 ASSScript.Burn。
+type ASSClip struct {
+	*core.BaseVideoClip
+	script     *ASSScript
+	scale      [2]float64 // PlayRes 到画布的缩放
+	events     []*nativeEvent
+	offset     time.Duration
+	mutex      *sync.Mutex
+	processMgr *ffmpeg.ProcessManager
+	owned      bool // processMgr 由本剪辑创建，Close 时关闭
+}
+
+// nativeEvent 一行字幕的渲染参数和缓存的画面
+type nativeEvent struct {
+	start, end      time.Duration
+	text            string
+	options         video.TextClipOptions
+	alignment       int
+	position        *[2]float64 // \pos 的坐标（PlayRes 单位）
+	marginL         int
+	marginR         int
+	marginV         int
+	fadeIn, fadeOut time.Duration
+
+	rendered bool
+	image    *image.RGBA // 裁剪到文字可见范围的画面
+	origin   image.Point // image 左上角在画布上的位置
+	err      error
+}
+
+// NewASSClip 创建在 width×height 画布上渲染 script 的字幕图层，坐标按 PlayRes 缩放
+//
+// processMgr 为 nil 时创建自己的进程管理器，并在 Close 时关闭。
+func NewASSClip(script *ASSScript, width, height int, duration time.Duration, fps float64, processMgr *ffmpeg.ProcessManager) *ASSClip {
+	owned := processMgr == nil
+	if owned {
+		processMgr = ffmpeg.NewProcessManager()
+	}
+	playResX, playResY := script.PlayRes()
+	scaleX, scaleY := float64(width)/float64(playResX), float64(height)/float64(playResY)
+
+	type indexed struct {
+		layer int
+		event *nativeEvent
+	}
+	var events []indexed
+	for _, event := range script.Events {
+		if event.Comment || event.End <= event.Start {
+			continue
+		}
+		native := script.nativeEvent(&event, scaleX, scaleY)
+		if strings.TrimSpace(native.text) == "" {
+			continue
+		}
+		events = append(events, indexed{event.Layer, native})
+	}
+	// 层号大的绘制在上，同层按脚本顺序
+	sort.SliceStable(events, func(i, j int) bool { return events[i].layer < events[j].layer })
+	clip := &ASSClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, duration, duration, fps, width, height),
+		script:        script,
+		scale:         [2]float64{scaleX, scaleY},
+		mutex:         &sync.Mutex{},
+		processMgr:    processMgr,
+		owned:         owned,
+	}
+	for _, e := range events {
+		clip.events = append(clip.events, e.event)
+	}
+	return clip
+}
+
+// GetFrame 返回时间 t 处显示的字幕，没有字幕时为全透明画面
+func (ac *ASSClip) GetFrame(t time.Duration) (image.Image, error) {
+	t += ac.offset
+	width, height := ac.Size()
+	frame := image.NewRGBA(image.Rect(0, 0, width, height))
+	for _, event := range ac.events {
+		if t < event.start || t >= event.end {
+			continue
+		}
+		if err := ac.render(event, width, height); err != nil {
+			return nil, err
+		}
+		if event.image == nil {
+			continue
+		}
+		alpha := event.opacity(t)
+		if alpha <= 0 {
+			continue
+		}
+		rect := event.image.Bounds().Sub(event.image.Bounds().Min).Add(event.origin)
+		mask := image.NewUniform(color.Alpha{A: uint8(math.Round(alpha * 255))})
+		draw.DrawMask(frame, rect, event.image, event.image.Bounds().Min, mask, image.Point{}, draw.Over)
+	}
+	return frame, nil
+}
+
+// render 首次显示时渲染字幕行，结果（包括错误）被缓存
+func (ac *ASSClip) render(event *nativeEvent, width, height int) error {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+	if event.rendered {
+		return event.err
+	}
+	event.rendered = true
+
+	text, err := video.NewTextClip(event.text, width, height, &event.options, time.Second, 1, ac.processMgr)
+	if err != nil {
+		event.err = fmt.Errorf("渲染 %v 处的字幕失败: %w", event.start, err)
+		return event.err
+	}
+	frame, err := text.GetFrame(0)
+	text.Close()
+	if err != nil {
+		event.err = err
+		return err
+	}
+	rgba, ok := frame.(*image.RGBA)
+	if !ok {
+		event.err = fmt.Errorf("文字画面不是 RGBA")
+		return event.err
+	}
+	ink := inkRect(rgba)
+	if ink.Empty() {
+		return nil
+	}
+	event.image = rgba.SubImage(ink).(*image.RGBA)
+	event.origin = event.place(ink.Dx(), ink.Dy(), width, height, ac.scale[0], ac.scale[1])
+	return nil
+}
+
+// Subclip 截取时间段，共享已渲染的字幕画面
+func (ac *ASSClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > ac.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	width, height := ac.Size()
+	return &ASSClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, end-start, end-start, ac.FPS(), width, height),
+		script:        ac.script,
+		scale:         ac.scale,
+		events:        ac.events,
+		offset:        ac.offset + start,
+		mutex:         ac.mutex,
+		processMgr:    ac.processMgr,
+	}, nil
+}
+
+// Close 关闭自己创建的进程管理器
+func (ac *ASSClip) Close() error {
+	if ac.owned {
+		return ac.processMgr.Close()
+	}
+	return nil
+}
+
+// opacity 返回 \fad 在时间 t 处的不透明度
+func (e *nativeEvent) opacity(t time.Duration) float64 {
+	alpha := 1.0
+	if e.fadeIn > 0 && t-e.start < e.fadeIn {
+		alpha = float64(t-e.start) / float64(e.fadeIn)
+	}
+	if e.fadeOut > 0 && e.end-t < e.fadeOut {
+		alpha = min(alpha, float64(e.end-t)/float64(e.fadeOut))
+	}
+	return alpha
+}
+
+// place 按对齐方式、边距或 \pos 计算 w×h 的文字在画布上的左上角，坐标按 scaleX、scaleY 从 PlayRes 换算
+func (e *nativeEvent) place(w, h, width, height int, scaleX, scaleY float64) image.Point {
+	column := (e.alignment - 1) % 3 // 0 左、1 中、2 右
+	row := (e.alignment - 1) / 3    // 0 底、1 中、2 顶
+	var x, y float64
+	if e.position != nil {
+		px, py := e.position[0]*scaleX, e.position[1]*scaleY
+		x = px - float64(w)*float64(column)/2
+		y = py - float64(h)*float64(2-row)/2
+	} else {
+		left, right := float64(e.marginL)*scaleX, float64(width)-float64(e.marginR)*scaleX
+		switch column {
+		case 0:
+			x = left
+		case 1:
+			x = (left+right)/2 - float64(w)/2
+		default:
+			x = right - float64(w)
+		}
+		switch row {
+		case 0:
+			y = float64(height) - float64(e.marginV)*scaleY - float64(h)
+		case 1:
+			y = (float64(height) - float64(h)) / 2
+		default:
+			y = float64(e.marginV) * scaleY
+		}
+	}
+	return image.Pt(int(math.Round(x)), int(math.Round(y)))
+}
+
+// nativeState 渲染一行字幕时的样式状态
+type nativeState struct {
+	style     ASSStyle
+	alignment int
+	position  *[2]float64
+	fade      [2]time.Duration
+}
+
+// nativeEvent 解析事件的样式和行首标签
+func (s *ASSScript) nativeEvent(event *ASSEvent, scaleX, scaleY float64) *nativeEvent {
+	base := s.Style(event.Style)
+	state := nativeState{style: base, alignment: base.Alignment}
+	var text strings.Builder
+	for _, segment := range event.Segments() {
+		// 行内样式变化不支持，只应用第一段文字之前的标签
+		if text.Len() == 0 {
+			for _, tag := range segment.Tags {
+				s.applyNativeTag(&state, tag, base)
+			}
+		}
+		text.WriteString(segment.Text)
+	}
+
+	style := state.style
+	native := &nativeEvent{
+		start:     event.Start,
+		end:       event.End,
+		text:      text.String(),
+		alignment: state.alignment,
+		position:  state.position,
+		marginL:   cmp.Or(event.MarginL, style.MarginL),
+		marginR:   cmp.Or(event.MarginR, style.MarginR),
+		marginV:   cmp.Or(event.MarginV, style.MarginV),
+		fadeIn:    state.fade[0],
+		fadeOut:   state.fade[1],
+		options: video.TextClipOptions{
+			FontSize: max(1, int(math.Round(style.FontSize*scaleY))),
+			Color:    drawtextColor(style.PrimaryColour),
+			Bold:     style.Bold,
+			Italic:   style.Italic,
+		},
+	}
+	if style.FontName != "" {
+		native.options.Fonts = []string{style.FontName}
+	}
+	if style.BorderStyle != 3 && style.Outline > 0 {
+		native.options.BorderWidth = max(1, int(math.Round(style.Outline*scaleY)))
+		native.options.BorderColor = drawtextColor(style.OutlineColour)
+	}
+	return native
+}
+
+// applyNativeTag 把行首标签应用到样式状态，不支持的标签被忽略
+func (s *ASSScript) applyNativeTag(state *nativeState, tag ASSTag, base ASSStyle) {
+	arg := ""
+	if len(tag.Args) > 0 {
+		arg = tag.Args[0]
+	}
+	number := func() (float64, bool) {
+		v, err := strconv.ParseFloat(arg, 64)
+		return v, err == nil
+	}
+	switch tag.Name {
+	case "pos":
+		if len(tag.Args) == 2 {
+			x, errX := strconv.ParseFloat(tag.Args[0], 64)
+			y, errY := strconv.ParseFloat(tag.Args[1], 64)
+			if errX == nil && errY == nil {
+				state.position = &[2]float64{x, y}
+			}
+		}
+	case "an":
+		if v, ok := number(); ok && v >= 1 && v <= 9 {
+			state.alignment = int(v)
+		}
+	case "a":
+		if v, ok := number(); ok {
+			state.alignment = legacyAlignment(int(v))
+		}
+	case "fn":
+		state.style.FontName = arg
+	case "fs":
+		if v, ok := number(); ok && v > 0 {
+			state.style.FontSize = v
+		}
+	case "b":
+		// \b 也可以是字重，如 \b700
+		if v, ok := number(); ok {
+			state.style.Bold = v == 1 || v >= 600
+		}
+	case "i":
+		if v, ok := number(); ok {
+			state.style.Italic = v != 0
+		}
+	case "bord":
+		if v, ok := number(); ok {
+			state.style.Outline = v
+		}
+	case "c", "1c":
+		state.style.PrimaryColour = overrideColor(state.style.PrimaryColour, arg)
+	case "3c":
+		state.style.OutlineColour = overrideColor(state.style.OutlineColour, arg)
+	case "alpha":
+		state.style.PrimaryColour.A = overrideAlpha(state.style.PrimaryColour.A, arg)
+		state.style.OutlineColour.A = overrideAlpha(state.style.OutlineColour.A, arg)
+	case "1a":
+		state.style.PrimaryColour.A = overrideAlpha(state.style.PrimaryColour.A, arg)
+	case "3a":
+		state.style.OutlineColour.A = overrideAlpha(state.style.OutlineColour.A, arg)
+	case "fad":
+		if len(tag.Args) == 2 {
+			in, _ := strconv.Atoi(tag.Args[0])
+			out, _ := strconv.Atoi(tag.Args[1])
+			state.fade = [2]time.Duration{time.Duration(in) * time.Millisecond, time.Duration(out) * time.Millisecond}
+		}
+	case "r":
+		if arg == "" {
+			state.style = base
+		} else {
+			state.style = s.Style(arg)
+		}
+		state.alignment = state.style.Alignment
+	}
+}
+
+// nativeTags NewASSClip 支持的覆盖标签
+var nativeTags = map[string]bool{
+	"pos": true, "an": true, "a": true, "fn": true, "fs": true, "b": true, "i": true, "bord": true,
+	"c": true, "1c": true, "3c": true, "alpha": true, "1a": true, "3a": true, "fad": true, "r": true,
+}
+
+// NativeUnsupported 列出脚本中 NewASSClip 会忽略的特性，为空时逐帧合成与 libass 的效果基本一致
+func (s *ASSScript) NativeUnsupported() []string {
+	seen := map[string]bool{}
+	var features []string
+	add := func(feature string) {
+		if !seen[feature] {
+			seen[feature] = true
+			features = append(features, feature)
+		}
+	}
+	for _, style := range s.Styles {
+		switch {
+		case style.BorderStyle == 3:
+			add("不透明底框（BorderStyle 3）")
+		case style.Shadow > 0:
+			add("阴影")
+		}
+		if style.Underline || style.StrikeOut {
+			add("下划线或删除线")
+		}
+		if style.ScaleX != 100 || style.ScaleY != 100 || style.Spacing != 0 || style.Angle != 0 {
+			add("样式的缩放、字距或旋转")
+		}
+	}
+	for _, event := range s.Events {
+		if event.Comment {
+			continue
+		}
+		text := false
+		for _, segment := range event.Segments() {
+			for _, tag := range segment.Tags {
+				switch {
+				case tag.Name == "k" || tag.Name == "kf" || tag.Name == "ko" || tag.Name == "K":
+					add("卡拉 OK（\\k）")
+				case text:
+					add("行内样式变化")
+				case !nativeTags[tag.Name]:
+					add("\\" + tag.Name)
+				}
+			}
+			text = text || segment.Text != ""
+		}
+	}
+	return features
+}
+
+// overrideColor 解析 \c&HBBGGRR& 并保留原有的透明度
+func overrideColor(c color.RGBA, arg string) color.RGBA {
+	parsed, err := parseASSColor(arg)
+	if err != nil {
+		return c
+	}
+	parsed.A = c.A
+	return parsed
+}
+
+// overrideAlpha 解析 \alpha&HAA& 为不透明度
+func overrideAlpha(a uint8, arg string) uint8 {
+	v, err := strconv.ParseUint(strings.Trim(strings.ToUpper(arg), "&H"), 16, 8)
+	if err != nil {
+		return a
+	}
+	return 255 - uint8(v)
+}
+
+// drawtextColor 转换为 drawtext 的颜色，如 #FFCC00@0.500
+func drawtextColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X@%.3f", c.R, c.G, c.B, float64(c.A)/255)
+}
+
+// inkRect 返回 alpha 非零像素的范围
+func inkRect(img *image.RGBA) image.Rectangle {
+	var ink image.Rectangle
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := img.Pix[(y-bounds.Min.Y)*img.Stride:]
+		for x := 0; x < bounds.Dx(); x++ {
+			if row[x*4+3] != 0 {
+				ink = ink.Union(image.Rect(x+bounds.Min.X, y, x+bounds.Min.X+1, y+1))
+			}
+		}
+	}
+	return ink
+}
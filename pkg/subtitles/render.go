@@ -0,0 +1,147 @@
+package subtitles
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// WriteASS 输出 ASS 字幕，PlayRes 为视频尺寸；逐词高亮使用 \kf 标签，由 libass 渲染填充过程
+func (t *Track) WriteASS(w io.Writer, width, height int) error {
+	s := t.Style
+	fontSize := s.FontSize
+	if fontSize <= 0 {
+		fontSize = height / 18
+	}
+	marginV := s.MarginV
+	if marginV <= 0 {
+		marginV = height / 20
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[Script Info]\nScriptType: v4.00+\nPlayResX: %d\nPlayResY: %d\nWrapStyle: 2\nScaledBorderAndShadow: yes\n\n", width, height)
+	fmt.Fprint(bw, "[V4+ Styles]\n")
+	fmt.Fprint(bw, "Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	// 卡拉 OK 填充时 SecondaryColour 为未读颜色、PrimaryColour 为已读颜色
+	primary := s.Color
+	if s.Plain {
+		primary = s.BaseColor
+	}
+	fmt.Fprintf(bw, "Style: Default,%s,%d,%s,%s,%s,%s,0,0,0,0,100,100,0,0,1,%d,0,2,40,40,%d,1\n\n",
+		s.FontName, fontSize, assColor(primary), assColor(s.BaseColor),
+		assColor(color.RGBA{0, 0, 0, 255}), assColor(color.RGBA{0, 0, 0, 128}), s.Outline, marginV)
+	fmt.Fprint(bw, "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	for _, cue := range t.Cues {
+		fmt.Fprintf(bw, "Dialogue: 0,%s,%s,Default,%s,0,0,0,,%s\n",
+			assTime(cue.Start), assTime(cue.End), escapeASS(cue.Speaker), t.assText(&cue))
+	}
+	return bw.Flush()
+}
+
+// assText 生成一行字幕的 ASS 文本，词间停顿用空的 \k 占位
+func (t *Track) assText(cue *Cue) string {
+	if t.Style.Plain {
+		return escapeASS(cue.Text())
+	}
+	var b strings.Builder
+	cursor := cue.Start
+	for i, word := range cue.Words {
+		if gap := centiseconds(word.Start - cursor); gap > 0 {
+			fmt.Fprintf(&b, `{\k%d}`, gap)
+		}
+		text := escapeASS(word.Text)
+		if i > 0 && !(isCJK(lastRune(cue.Words[i-1].Text)) && isCJK(firstRune(word.Text))) {
+			text = " " + text
+		}
+		fmt.Fprintf(&b, `{\kf%d}%s`, centiseconds(word.End-max(word.Start, cursor)), text)
+		cursor = max(word.End, cursor)
+	}
+	return b.String()
+}
+
+// WriteSRT 输出不带高亮的 SRT 字幕
+func (t *Track) WriteSRT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, cue := range t.Cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, srtTime(cue.Start), srtTime(cue.End), cue.Text())
+	}
+	return bw.Flush()
+}
+
+// WriteFile 按扩展名（.ass 或 .srt）写入字幕文件，ASS 需要视频尺寸
+func (t *Track) WriteFile(filename string, width, height int) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("创建字幕文件失败: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ass":
+		err = t.WriteASS(file, width, height)
+	case ".srt":
+		err = t.WriteSRT(file)
+	default:
+		err = fmt.Errorf("不支持的字幕格式: %s", filename)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filename)
+		return err
+	}
+	return nil
+}
+
+// Burn 将字幕烧录到 input 的画面中写入 output，音频流复制
+//
+// 通过 FFmpeg 的 ass 滤镜渲染（需要带 libass 的 FFmpeg），width/height 为 input 的画面尺寸。
+func (t *Track) Burn(ctx context.Context, input, output string, width, height int, processMgr *ffmpeg.ProcessManager) error {
+	assFile, err := processMgr.Temp().CreateFile("captions-*.ass")
+	if err != nil {
+		return fmt.Errorf("创建字幕文件失败: %w", err)
+	}
+	defer processMgr.Temp().Remove(assFile)
+	if err := t.WriteFile(assFile, width, height); err != nil {
+		return err
+	}
+
+	return burnASS(ctx, input, output, assFile, "", processMgr)
+}
+
+// assColor 转换为 ASS 的 &HAABBGGRR，AA 为透明度（00 不透明）
+func assColor(c color.RGBA) string {
+	return fmt.Sprintf("&H%02X%02X%02X%02X", 255-c.A, c.B, c.G, c.R)
+}
+
+// assTime 格式化为 h:mm:ss.cc
+func assTime(d time.Duration) string {
+	cs := centiseconds(d)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// srtTime 格式化为 hh:mm:ss,mmm
+func srtTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// centiseconds 四舍五入到百分之一秒，负数视为 0
+func centiseconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return (d.Milliseconds() + 5) / 10
+}
+
+// escapeASS 替换 ASS 覆盖标签使用的字符和换行
+func escapeASS(s string) string {
+	return strings.NewReplacer("{", "｛", "}", "｝", `\`, "＼", "\n", " ", "\r", "").Replace(s)
+}
@@ -0,0 +1,218 @@
+// Package subtitles 由语音识别结果生成字幕轨，支持卡拉 OK 式逐词高亮
+//
+// 识别引擎（Whisper 绑定、云端 API 等）通过 Transcriber 接口接入，本包只负责断行、计时和渲染。
+package subtitles
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Word 带时间戳的单词
+type Word struct {
+	Text       string
+	Start, End time.Duration
+	Confidence float64 // 0–1，引擎不提供时为 0
+}
+
+// Segment 识别出的一段语音，Words 为空时按字符数在段内均分时间
+type Segment struct {
+	Start, End time.Duration
+	Text       string
+	Words      []Word
+	Speaker    string
+}
+
+// Transcriber 语音识别引擎，返回按时间排序的语音段
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioFile string) ([]Segment, error)
+}
+
+// TranscriberFunc 函数形式的 Transcriber
+type TranscriberFunc func(ctx context.Context, audioFile string) ([]Segment, error)
+
+// Transcribe 调用函数本身
+func (f TranscriberFunc) Transcribe(ctx context.Context, audioFile string) ([]Segment, error) {
+	return f(ctx, audioFile)
+}
+
+// Style 字幕样式与断行规则
+type Style struct {
+	FontName  string     // 默认 Arial
+	FontSize  int        // 以 PlayResY 为基准的字号，默认 PlayResY/18
+	Color     color.RGBA // 已读（高亮）部分颜色，默认黄色
+	BaseColor color.RGBA // 未读部分颜色，默认白色
+	Outline   int        // 描边宽度，默认 3
+	MarginV   int        // 距底部的距离，默认 PlayResY/20
+	Plain     bool       // 关闭逐词高亮，整行同时显示
+	// 断行规则
+	MaxChars    int           // 每行最多字符数，默认 42
+	MaxDuration time.Duration // 每行最长时间，默认 5s
+	MaxGap      time.Duration // 词间停顿超过该值时换行，默认 700ms
+}
+
+// Cue 一行字幕
+type Cue struct {
+	Start, End time.Duration
+	Words      []Word
+	Speaker    string
+}
+
+// Text 返回整行文字
+func (c *Cue) Text() string {
+	texts := make([]string, len(c.Words))
+	for i, word := range c.Words {
+		texts[i] = word.Text
+	}
+	return joinWords(texts)
+}
+
+// Track 字幕轨
+type Track struct {
+	Cues  []Cue
+	Style Style
+}
+
+// FromTranscriber 用 transcriber 识别 audioFile 并生成字幕轨
+func FromTranscriber(ctx context.Context, transcriber Transcriber, audioFile string, style *Style) (*Track, error) {
+	segments, err := transcriber.Transcribe(ctx, audioFile)
+	if err != nil {
+		return nil, fmt.Errorf("语音识别失败: %w", err)
+	}
+	return FromSegments(segments, style)
+}
+
+// FromSegments 由已有的语音段生成字幕轨
+func FromSegments(segments []Segment, style *Style) (*Track, error) {
+	s := Style{}
+	if style != nil {
+		s = *style
+	}
+	s.applyDefaults()
+
+	segments = append([]Segment(nil), segments...)
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].Start < segments[j].Start })
+
+	track := &Track{Style: s}
+	for i, segment := range segments {
+		if segment.End < segment.Start {
+			return nil, fmt.Errorf("第 %d 段结束时间 %v 早于开始时间 %v", i, segment.End, segment.Start)
+		}
+		words := segment.Words
+		if len(words) == 0 {
+			words = splitSegment(segment)
+		}
+		track.Cues = append(track.Cues, s.breakLines(words, segment.Speaker)...)
+	}
+	return track, nil
+}
+
+// applyDefaults 补全默认样式
+func (s *Style) applyDefaults() {
+	if s.FontName == "" {
+		s.FontName = "Arial"
+	}
+	if s.Color == (color.RGBA{}) {
+		s.Color = color.RGBA{255, 220, 0, 255}
+	}
+	if s.BaseColor == (color.RGBA{}) {
+		s.BaseColor = color.RGBA{255, 255, 255, 255}
+	}
+	if s.Outline == 0 {
+		s.Outline = 3
+	}
+	if s.MaxChars <= 0 {
+		s.MaxChars = 42
+	}
+	if s.MaxDuration <= 0 {
+		s.MaxDuration = 5 * time.Second
+	}
+	if s.MaxGap <= 0 {
+		s.MaxGap = 700 * time.Millisecond
+	}
+}
+
+// breakLines 按字符数、时长和停顿把单词分成若干行
+func (s *Style) breakLines(words []Word, speaker string) []Cue {
+	var cues []Cue
+	var line []Word
+	chars := 0
+	flush := func() {
+		if len(line) > 0 {
+			cues = append(cues, Cue{Start: line[0].Start, End: line[len(line)-1].End, Words: line, Speaker: speaker})
+		}
+		line, chars = nil, 0
+	}
+	for _, word := range words {
+		word.Text = strings.TrimSpace(word.Text)
+		if word.Text == "" {
+			continue
+		}
+		n := utf8.RuneCountInString(word.Text)
+		if len(line) > 0 {
+			last := line[len(line)-1]
+			if chars+1+n > s.MaxChars || word.End-line[0].Start > s.MaxDuration || word.Start-last.End > s.MaxGap {
+				flush()
+			}
+		}
+		line = append(line, word)
+		chars += n + 1
+	}
+	flush()
+	return cues
+}
+
+// splitSegment 没有词级时间戳时按字符数在段内分配时间
+func splitSegment(segment Segment) []Word {
+	texts := strings.Fields(segment.Text)
+	if len(texts) == 0 {
+		return nil
+	}
+	total := 0
+	for _, text := range texts {
+		total += utf8.RuneCountInString(text)
+	}
+	span := segment.End - segment.Start
+	words := make([]Word, len(texts))
+	offset := 0
+	for i, text := range texts {
+		start := segment.Start + span*time.Duration(offset)/time.Duration(total)
+		offset += utf8.RuneCountInString(text)
+		words[i] = Word{Text: text, Start: start, End: segment.Start + span*time.Duration(offset)/time.Duration(total)}
+	}
+	return words
+}
+
+// joinWords 用空格连接单词，CJK 字符之间不加空格
+func joinWords(texts []string) string {
+	var b strings.Builder
+	for i, text := range texts {
+		if i > 0 && !(isCJK(lastRune(texts[i-1])) && isCJK(firstRune(text))) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// firstRune 返回首字符
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// lastRune 返回末字符
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// isCJK 是否为中日韩文字或全角标点
+func isCJK(r rune) bool {
+	return r >= 0x2E80 && r <= 0x9FFF || r >= 0xAC00 && r <= 0xD7AF || r >= 0xF900 && r <= 0xFAFF || r >= 0xFF00 && r <= 0xFFEF
+}
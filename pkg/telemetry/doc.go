@@ -0,0 +1,14 @@
+// Package telemetry 为渲染各阶段（解码、特效、合成、编码）提供追踪后端
+//
+// Recorder 在进程内按阶段汇总耗时，不需要额外依赖。OpenTelemetry 适配器依赖
+// go.opentelemetry.io/otel，默认不参与构建，使用前添加依赖并以 otel 标签编译：
+//
+//	go get go.opentelemetry.io/otel
+//	go build -tags otel ./...
+//
+// 示例：把渲染区间发送到已配置的 TracerProvider
+//
+//	core.SetTracer(telemetry.NewOTelTracer(otel.Tracer("moviego")))
+//
+// 区间名称见 core.SpanRender 等常量；渲染队列和批处理会附加 job.id，各阶段附加 clip.id。
+package telemetry
@@ -0,0 +1,73 @@
+//go:build otel
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"moviepy-go/pkg/core"
+)
+
+// OTelTracer 把 core.Tracer 的区间转交给 OpenTelemetry
+type OTelTracer struct {
+	tracer trace.Tracer
+}
+
+// NewOTelTracer 包装 OpenTelemetry 的 Tracer
+func NewOTelTracer(tracer trace.Tracer) *OTelTracer {
+	return &OTelTracer{tracer: tracer}
+}
+
+// Start 开始新区间，父区间取自 ctx
+func (ot *OTelTracer) Start(ctx context.Context, name string, attrs ...core.Attribute) (context.Context, core.Span) {
+	ctx, span := ot.tracer.Start(ctx, name, trace.WithAttributes(otelAttributes(attrs)...))
+	return ctx, otelSpan{span}
+}
+
+// otelSpan 包装 trace.Span
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(attrs ...core.Attribute) {
+	s.span.SetAttributes(otelAttributes(attrs)...)
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+// otelAttributes 转换属性，时长记录为毫秒
+func otelAttributes(attrs []core.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			kvs[i] = attribute.String(attr.Key, v)
+		case bool:
+			kvs[i] = attribute.Bool(attr.Key, v)
+		case int:
+			kvs[i] = attribute.Int(attr.Key, v)
+		case int64:
+			kvs[i] = attribute.Int64(attr.Key, v)
+		case float64:
+			kvs[i] = attribute.Float64(attr.Key, v)
+		case time.Duration:
+			kvs[i] = attribute.Float64(attr.Key+".ms", float64(v)/float64(time.Millisecond))
+		default:
+			kvs[i] = attribute.String(attr.Key, fmt.Sprint(v))
+		}
+	}
+	return kvs
+}
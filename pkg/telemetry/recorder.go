@@ -0,0 +1,123 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// StageStats 一类区间的汇总
+type StageStats struct {
+	Name   string
+	Count  int
+	Errors int
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Mean 平均耗时
+func (s StageStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// Recorder 在进程内按区间名称汇总耗时的 core.Tracer，用于没有追踪系统时定位慢的阶段
+//
+// 特效区间按 "moviego.effect/<特效名>" 分别汇总。
+type Recorder struct {
+	mutex  sync.Mutex
+	stages map[string]*StageStats
+}
+
+// NewRecorder 创建汇总器
+func NewRecorder() *Recorder {
+	return &Recorder{stages: make(map[string]*StageStats)}
+}
+
+// Start 开始计时，ctx 原样返回
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...core.Attribute) (context.Context, core.Span) {
+	if name == core.SpanEffect {
+		for _, attr := range attrs {
+			if attr.Key == "effect" {
+				name = fmt.Sprintf("%s/%v", name, attr.Value)
+				break
+			}
+		}
+	}
+	return ctx, &recorderSpan{recorder: r, name: name, start: time.Now()}
+}
+
+// Stats 返回各阶段汇总，按总耗时从大到小排序
+func (r *Recorder) Stats() []StageStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stats := make([]StageStats, 0, len(r.stages))
+	for _, s := range r.stages {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Total > stats[j].Total })
+	return stats
+}
+
+// Reset 清空汇总
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stages = make(map[string]*StageStats)
+}
+
+// String 返回可打印的汇总表
+func (r *Recorder) String() string {
+	var b strings.Builder
+	for _, s := range r.Stats() {
+		fmt.Fprintf(&b, "%-32s %8d 次  总计 %-12v 平均 %-12v 最长 %v",
+			s.Name, s.Count, s.Total.Round(time.Microsecond), s.Mean().Round(time.Microsecond), s.Max.Round(time.Microsecond))
+		if s.Errors > 0 {
+			fmt.Fprintf(&b, "  失败 %d", s.Errors)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// record 累计一个结束的区间
+func (r *Recorder) record(name string, elapsed time.Duration, failed bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s, ok := r.stages[name]
+	if !ok {
+		s = &StageStats{Name: name}
+		r.stages[name] = s
+	}
+	s.Count++
+	s.Total += elapsed
+	s.Max = max(s.Max, elapsed)
+	if failed {
+		s.Errors++
+	}
+}
+
+// recorderSpan 记录开始时间的区间
+type recorderSpan struct {
+	recorder *Recorder
+	name     string
+	start    time.Time
+	failed   bool
+}
+
+func (s *recorderSpan) SetAttributes(...core.Attribute) {}
+
+func (s *recorderSpan) RecordError(error) {
+	s.failed = true
+}
+
+func (s *recorderSpan) End() {
+	s.recorder.record(s.name, time.Since(s.start), s.failed)
+}
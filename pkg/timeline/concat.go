@@ -0,0 +1,189 @@
+package timeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// ConcatOptions 用 concat 分离器渲染时间线的选项
+type ConcatOptions struct {
+	// Copy 直接复制码流而不重新编码；入点不在关键帧上时片段开头会带有入点之前的画面
+	Copy bool
+	// Codec 视频编码器，默认 libx264
+	Codec   string
+	CRF     int    // 恒定质量因子，0 表示默认 23；设置 Bitrate 时忽略
+	Bitrate string // 视频码率，如 "4000k"
+	// AudioCodec 音频编码器，默认 aac
+	AudioCodec   string
+	AudioBitrate string
+
+	Context context.Context
+	// OnCommand 报告将要执行的 FFmpeg 命令
+	OnCommand  func(name string, args []string)
+	ProcessMgr *ffmpeg.ProcessManager
+}
+
+// WriteConcatScript 把只有一条视频轨道、首尾相接且没有转场的时间线写为 FFmpeg concat 分离器脚本
+//
+// 每个片段写为 file/inpoint/outpoint 三行，源文件使用绝对路径，需配合 -safe 0 使用。
+func WriteConcatScript(w io.Writer, tl *Timeline) error {
+	events, err := tl.cutList()
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "ffconcat version 1.0")
+	for _, event := range events {
+		if err := ffmpeg.WriteConcatFile(out, event.Source); err != nil {
+			return err
+		}
+		if event.SourceIn > 0 {
+			fmt.Fprintf(out, "inpoint %s\n", ffmpeg.FormatSeconds(event.SourceIn))
+		}
+		fmt.Fprintf(out, "outpoint %s\n", ffmpeg.FormatSeconds(event.SourceOut))
+	}
+	return out.Flush()
+}
+
+// RenderConcat 用一次 FFmpeg 调用渲染简单的剪切序列：生成 concat 脚本，由 FFmpeg 直接解码和编码，
+// 不经过 Go 逐帧处理
+//
+// 只支持 WriteConcatScript 能表示的时间线：一条视频轨道，片段首尾相接，没有转场和独立音轨。
+// 重新编码时按时间线的帧率和尺寸输出，不同尺寸的源文件等比缩放并加黑边。失败时 output 保持不变。
+func RenderConcat(tl *Timeline, output string, options *ConcatOptions) error {
+	if options == nil {
+		options = &ConcatOptions{}
+	}
+	if options.Codec == "" {
+		options.Codec = "libx264"
+	}
+	if options.AudioCodec == "" {
+		options.AudioCodec = "aac"
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	events, err := tl.cutList()
+	if err != nil {
+		return err
+	}
+	processMgr := options.ProcessMgr
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	listName, err := processMgr.Temp().CreateFile("concat-*.txt")
+	if err != nil {
+		return fmt.Errorf("创建拼接脚本失败: %w", err)
+	}
+	defer processMgr.Temp().Remove(listName)
+	listFile, err := os.Create(listName)
+	if err != nil {
+		return fmt.Errorf("创建拼接脚本失败: %w", err)
+	}
+	if err := WriteConcatScript(listFile, tl); err != nil {
+		listFile.Close()
+		return err
+	}
+	if err := listFile.Close(); err != nil {
+		return fmt.Errorf("写入拼接脚本失败: %w", err)
+	}
+
+	if options.OnCommand != nil {
+		options.OnCommand("ffmpeg", concatArgs(tl, listName, output, events[0].Audio, options))
+	}
+
+	// 写入同目录的临时文件，渲染成功后才替换 output
+	staged, err := ffmpeg.TempOutputPath(output)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staged)
+	process, err := processMgr.StartProcess(ctx, "ffmpeg", concatArgs(tl, listName, staged, events[0].Audio, options), nil)
+	if err != nil {
+		return fmt.Errorf("启动拼接进程失败: %w", err)
+	}
+	if err := process.Wait(); err != nil {
+		return fmt.Errorf("渲染时间线 %q 失败: %w", tl.Name, err)
+	}
+	return ffmpeg.CommitOutput(staged, output)
+}
+
+// concatArgs 构建读取 concat 脚本并编码（或复制）到 output 的 FFmpeg 参数
+func concatArgs(tl *Timeline, listName, output string, audio bool, options *ConcatOptions) []string {
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", listName,
+		"-map", "0:v:0",
+	}
+	if audio {
+		args = append(args, "-map", "0:a:0")
+	}
+	if options.Copy {
+		return append(args, "-c", "copy", "-y", output)
+	}
+
+	if tl.Width > 0 && tl.Height > 0 {
+		args = append(args, "-vf", fmt.Sprintf(
+			"scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1",
+			tl.Width, tl.Height, tl.Width, tl.Height))
+	}
+	args = append(args, "-r", tl.FrameRate.String(), "-c:v", options.Codec)
+	if options.Bitrate != "" {
+		args = append(args, "-b:v", options.Bitrate)
+	} else {
+		crf := options.CRF
+		if crf == 0 {
+			crf = 23
+		}
+		args = append(args, "-crf", strconv.Itoa(crf))
+	}
+	args = append(args, "-pix_fmt", "yuv420p")
+	if audio {
+		args = append(args, "-c:a", options.AudioCodec)
+		if options.AudioBitrate != "" {
+			args = append(args, "-b:a", options.AudioBitrate)
+		}
+	}
+	return append(args, "-y", output)
+}
+
+// cutList 返回 concat 分离器可以表示的片段序列，时间线包含其他内容时返回错误
+func (tl *Timeline) cutList() ([]*Event, error) {
+	if err := tl.Validate(); err != nil {
+		return nil, err
+	}
+	if len(tl.Tracks) != 1 || tl.Tracks[0].Kind != VideoTrack {
+		return nil, fmt.Errorf("concat 只支持一条视频轨道，时间线 %q 有 %d 条轨道", tl.Name, len(tl.Tracks))
+	}
+	events := tl.Tracks[0].sorted()
+	if len(events) == 0 {
+		return nil, fmt.Errorf("时间线 %q 没有片段", tl.Name)
+	}
+	var position time.Duration
+	for _, event := range events {
+		if event.RecordIn != position {
+			return nil, fmt.Errorf("concat 不支持空隙: %v 处的片段前有 %v 空白", event.RecordIn, event.RecordIn-position)
+		}
+		if event.Transition != nil {
+			return nil, fmt.Errorf("concat 不支持转场: %v 处的片段", event.RecordIn)
+		}
+		if event.Audio != events[0].Audio {
+			return nil, fmt.Errorf("concat 要求所有片段都带或都不带音频: %v 处的片段不一致", event.RecordIn)
+		}
+		position = event.RecordOut()
+	}
+	return events, nil
+}
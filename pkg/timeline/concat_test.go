@@ -0,0 +1,117 @@
+package timeline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/ffmpeg"
+)
+
+var rate25 = ffmpeg.Rational{Num: 25, Den: 1}
+
+func TestWriteConcatScript(t *testing.T) {
+	dir := t.TempDir()
+	tl := New("cut", rate25, 1920, 1080)
+	track := tl.AddTrack(VideoTrack)
+	track.Append(filepath.Join(dir, "a.mp4"), 0, 2*time.Second)
+	track.Append(filepath.Join(dir, "it's.mp4"), 1500*time.Millisecond, 3*time.Second)
+
+	var out strings.Builder
+	if err := WriteConcatScript(&out, tl); err != nil {
+		t.Fatalf("写入 concat 脚本失败: %v", err)
+	}
+	// 入点为 0 时省略 inpoint，路径中的单引号转义为 '\''
+	want := "ffconcat version 1.0\n" +
+		"file '" + dir + "/a.mp4'\n" +
+		"outpoint 2.000000\n" +
+		"file '" + dir + `/it'\''s.mp4'` + "\n" +
+		"inpoint 1.500000\n" +
+		"outpoint 3.000000\n"
+	if out.String() != want {
+		t.Fatalf("concat 脚本为\n%s\n期望\n%s", out.String(), want)
+	}
+}
+
+func TestCutListRejectsUnsupportedTimelines(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(tl *Timeline)
+	}{
+		{"没有轨道", func(tl *Timeline) {}},
+		{"没有片段", func(tl *Timeline) { tl.AddTrack(VideoTrack) }},
+		{"两条轨道", func(tl *Timeline) {
+			tl.AddTrack(VideoTrack).Append("a.mp4", 0, time.Second)
+			tl.AddTrack(AudioTrack).Append("music.wav", 0, time.Second)
+		}},
+		{"只有音频轨道", func(tl *Timeline) { tl.AddTrack(AudioTrack).Append("music.wav", 0, time.Second) }},
+		{"空隙", func(tl *Timeline) {
+			track := tl.AddTrack(VideoTrack)
+			track.Append("a.mp4", 0, time.Second)
+			track.Append("b.mp4", 0, time.Second).RecordIn = 2 * time.Second
+		}},
+		{"转场", func(tl *Timeline) {
+			track := tl.AddTrack(VideoTrack)
+			track.Append("a.mp4", 0, time.Second)
+			track.Append("b.mp4", 0, time.Second).Transition = &Transition{Duration: 200 * time.Millisecond}
+		}},
+		{"音频不一致", func(tl *Timeline) {
+			track := tl.AddTrack(VideoTrack)
+			track.Append("a.mp4", 0, time.Second).Audio = true
+			track.Append("b.mp4", 0, time.Second)
+		}},
+		{"无效入出点", func(tl *Timeline) { tl.AddTrack(VideoTrack).Append("a.mp4", time.Second, time.Second) }},
+	}
+	for _, tt := range tests {
+		tl := New(tt.name, rate25, 1920, 1080)
+		tt.build(tl)
+		if _, err := tl.cutList(); err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+	}
+
+	tl := New("no-rate", ffmpeg.Rational{}, 1920, 1080)
+	tl.AddTrack(VideoTrack).Append("a.mp4", 0, time.Second)
+	if _, err := tl.cutList(); err == nil {
+		t.Error("未设置帧率时应返回错误")
+	}
+}
+
+func TestRenderConcatFailureKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nfor a; do last=$a; done\necho partial > \"$last\"\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pm := ffmpeg.NewProcessManagerWithOptions(&ffmpeg.ProcessManagerOptions{FFmpegPath: path, FFprobePath: path})
+	defer pm.Close()
+
+	outDir := t.TempDir()
+	output := filepath.Join(outDir, "out.mp4")
+	if err := os.WriteFile(output, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tl := New("cut", rate25, 1920, 1080)
+	tl.AddTrack(VideoTrack).Append(filepath.Join(dir, "a.mp4"), 0, time.Second)
+
+	var reported []string
+	options := &ConcatOptions{
+		OnCommand:  func(name string, args []string) { reported = args },
+		ProcessMgr: pm,
+	}
+	if err := RenderConcat(tl, output, options); err == nil {
+		t.Fatal("FFmpeg 失败时应返回错误")
+	}
+	if len(reported) == 0 || reported[len(reported)-1] != output {
+		t.Fatalf("报告的命令应以最终输出结尾，实际 %v", reported)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil || string(data) != "previous" {
+		t.Fatalf("失败时不应改动已有输出，实际 %q (%v)", data, err)
+	}
+	if entries, _ := os.ReadDir(outDir); len(entries) != 1 {
+		t.Fatalf("失败后不应留下临时文件，目录中有 %d 个文件", len(entries))
+	}
+}
@@ -0,0 +1,113 @@
+package timeline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EDLOptions 导出 EDL 的选项
+type EDLOptions struct {
+	// Track 导出的轨道序号，默认 0；CMX3600 每个 EDL 只描述一条轨道
+	Track int
+	// Start 序列起始时间码对应的时间，默认 1 小时（01:00:00:00），与广播惯例一致
+	Start time.Duration
+}
+
+// WriteEDL 把时间线的一条轨道写为 CMX3600 EDL
+//
+// 视频片段的通道为 V（带音频时为 AA/V），音频轨道为 AA。源时间码从 00:00:00:00 起算，
+// 源文件自带时间码时需在剪辑软件中按文件名重新链接。转场写为溶解（D）。
+func WriteEDL(w io.Writer, tl *Timeline, options *EDLOptions) error {
+	if options == nil {
+		options = &EDLOptions{}
+	}
+	if options.Start == 0 {
+		options.Start = time.Hour
+	}
+	if err := tl.Validate(); err != nil {
+		return err
+	}
+	if options.Track < 0 || options.Track >= len(tl.Tracks) {
+		return fmt.Errorf("轨道 %d 不存在", options.Track)
+	}
+	track := tl.Tracks[options.Track]
+
+	fcm := "NON-DROP FRAME"
+	drop := tl.DropFrame && tl.FrameRate.Den == 1001
+	if drop {
+		fcm = "DROP FRAME"
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "TITLE: %s\n", edlTitle(tl.Name))
+	fmt.Fprintf(out, "FCM: %s\n\n", fcm)
+
+	timecode := func(d time.Duration) string {
+		return Timecode(Frames(d, tl.FrameRate), tl.FrameRate, drop)
+	}
+	var previous *Event
+	for i, event := range track.sorted() {
+		number := i + 1
+		channel := edlChannel(track.Kind, event)
+		recordIn := timecode(options.Start + event.RecordIn)
+		recordOut := timecode(options.Start + event.RecordOut())
+		if event.Transition != nil {
+			// 溶解前先写出点所在片段在切点处的零长度事件作为溶解起点，前面是空隙时从黑场（BL）溶解
+			fromReel, fromTC, fromName := "BL", timecode(0), "BLACK"
+			if previous != nil && previous.RecordOut() == event.RecordIn {
+				fromReel, fromTC, fromName = edlReel(previous), timecode(previous.SourceOut), filepath.Base(previous.Source)
+			}
+			fmt.Fprintf(out, "%03d  %-8s %-5s C        %s %s %s %s\n", number, fromReel, channel, fromTC, fromTC, recordIn, recordIn)
+			frames := Frames(event.Transition.Duration, tl.FrameRate)
+			fmt.Fprintf(out, "%03d  %-8s %-5s D    %03d %s %s %s %s\n", number, edlReel(event), channel, frames,
+				timecode(event.SourceIn), timecode(event.SourceOut), recordIn, recordOut)
+			fmt.Fprintf(out, "* FROM CLIP NAME: %s\n", fromName)
+			fmt.Fprintf(out, "* TO CLIP NAME: %s\n\n", filepath.Base(event.Source))
+		} else {
+			fmt.Fprintf(out, "%03d  %-8s %-5s C        %s %s %s %s\n", number, edlReel(event), channel,
+				timecode(event.SourceIn), timecode(event.SourceOut), recordIn, recordOut)
+			fmt.Fprintf(out, "* FROM CLIP NAME: %s\n\n", filepath.Base(event.Source))
+		}
+		previous = event
+	}
+	return out.Flush()
+}
+
+// edlChannel 返回片段的通道字段
+func edlChannel(kind TrackKind, event *Event) string {
+	switch {
+	case kind == AudioTrack:
+		return "AA"
+	case event.Audio:
+		return "AA/V"
+	default:
+		return "V"
+	}
+}
+
+// edlReel 返回卷名，CMX3600 限 8 个字符且不含空格
+func edlReel(event *Event) string {
+	reel := strings.ReplaceAll(event.Reel, " ", "_")
+	if reel == "" {
+		return "AX"
+	}
+	if runes := []rune(reel); len(runes) > 8 {
+		reel = string(runes[:8])
+	}
+	return reel
+}
+
+// edlTitle 返回标题，CMX3600 标题不超过 70 个字符
+func edlTitle(name string) string {
+	if name == "" {
+		name = "moviego"
+	}
+	if runes := []rune(name); len(runes) > 70 {
+		name = string(runes[:70])
+	}
+	return name
+}
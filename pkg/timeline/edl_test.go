@@ -0,0 +1,82 @@
+package timeline
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "用当前输出更新 testdata 中的期望文件")
+
+// checkGolden 比较 got 与 testdata/name，-update 时改为写入
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取期望文件失败: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s 输出与期望不一致:\n%s\n期望:\n%s", name, got, want)
+	}
+}
+
+// sampleTimeline 返回 29.97 fps 丢帧的示例时间线：带音频的片段、溶解转场、空隙和一条配乐轨道
+func sampleTimeline() *Timeline {
+	tl := New("Demo Cut", rate2997, 1920, 1080)
+	tl.DropFrame = true
+	video := tl.AddTrack(VideoTrack)
+	interview := video.Append("/media/interview take 1.mov", 2*time.Second, 12*time.Second)
+	interview.Audio = true
+	interview.Reel = "A001 C003"
+	broll := video.Append("/media/broll.mov", 65*time.Second, 70*time.Second)
+	broll.Transition = &Transition{Duration: time.Second}
+	closing := video.Append("/media/broll.mov", 0, 4*time.Second)
+	closing.RecordIn = 17 * time.Second
+	closing.Transition = &Transition{Duration: 500 * time.Millisecond}
+
+	music := tl.AddTrack(AudioTrack)
+	music.Append("/media/music.wav", 0, 21*time.Second)
+	return tl
+}
+
+func TestWriteEDLGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func() *Timeline
+		options *EDLOptions
+	}{
+		{"demo_video.edl", sampleTimeline, nil},
+		{"demo_music.edl", sampleTimeline, &EDLOptions{Track: 1, Start: 10 * time.Hour}},
+		{"ndf_25.edl", func() *Timeline {
+			tl := New("", rate25, 1280, 720)
+			track := tl.AddTrack(VideoTrack)
+			track.Append("shot1.mp4", 0, 2*time.Second)
+			track.Append("shot2.mp4", 1500*time.Millisecond, 3*time.Second)
+			return tl
+		}, nil},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if err := WriteEDL(&out, tt.build(), tt.options); err != nil {
+			t.Fatalf("%s: 写入 EDL 失败: %v", tt.name, err)
+		}
+		checkGolden(t, tt.name, out.String())
+	}
+}
+
+func TestWriteEDLRejectsMissingTrack(t *testing.T) {
+	var out strings.Builder
+	if err := WriteEDL(&out, sampleTimeline(), &EDLOptions{Track: 2}); err == nil {
+		t.Fatal("轨道不存在时应返回错误")
+	}
+}
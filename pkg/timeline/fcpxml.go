@@ -0,0 +1,332 @@
+package timeline
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+// fcpxmlVersion 导出的 FCPXML 版本，Final Cut Pro 10.4.1 起支持，DaVinci Resolve 和 Premiere 也能导入
+const fcpxmlVersion = "1.9"
+
+type fcpxmlDocument struct {
+	XMLName   xml.Name     `xml:"fcpxml"`
+	Version   string       `xml:"version,attr"`
+	Resources fcpResources `xml:"resources"`
+	Event     fcpEvent     `xml:"library>event"`
+}
+
+type fcpResources struct {
+	Format fcpFormat  `xml:"format"`
+	Assets []fcpAsset `xml:"asset"`
+}
+
+type fcpFormat struct {
+	ID            string `xml:"id,attr"`
+	FrameDuration string `xml:"frameDuration,attr"`
+	Width         int    `xml:"width,attr,omitempty"`
+	Height        int    `xml:"height,attr,omitempty"`
+}
+
+type fcpAsset struct {
+	ID       string      `xml:"id,attr"`
+	Name     string      `xml:"name,attr"`
+	Start    string      `xml:"start,attr"`
+	Duration string      `xml:"duration,attr"`
+	HasVideo string      `xml:"hasVideo,attr,omitempty"`
+	HasAudio string      `xml:"hasAudio,attr,omitempty"`
+	Format   string      `xml:"format,attr,omitempty"`
+	MediaRep fcpMediaRep `xml:"media-rep"`
+	end      time.Duration
+}
+
+type fcpMediaRep struct {
+	Kind string `xml:"kind,attr"`
+	Src  string `xml:"src,attr"`
+}
+
+type fcpEvent struct {
+	Name    string     `xml:"name,attr"`
+	Project fcpProject `xml:"project"`
+}
+
+type fcpProject struct {
+	Name     string      `xml:"name,attr"`
+	Sequence fcpSequence `xml:"sequence"`
+}
+
+type fcpSequence struct {
+	Format   string `xml:"format,attr"`
+	Duration string `xml:"duration,attr"`
+	TCStart  string `xml:"tcStart,attr"`
+	TCFormat string `xml:"tcFormat,attr"`
+	Spine    []any  `xml:"spine>_"`
+}
+
+// fcpClip 主故事情节中的片段（lane 为 0）或其上连接的片段
+type fcpClip struct {
+	XMLName   xml.Name `xml:"asset-clip"`
+	Ref       string   `xml:"ref,attr"`
+	Name      string   `xml:"name,attr"`
+	Lane      int      `xml:"lane,attr,omitempty"`
+	Offset    string   `xml:"offset,attr"`
+	Start     string   `xml:"start,attr"`
+	Duration  string   `xml:"duration,attr"`
+	SrcEnable string   `xml:"srcEnable,attr,omitempty"`
+	Connected []any    `xml:",omitempty"`
+	sourceIn  time.Duration
+	recordIn  time.Duration
+	recordOut time.Duration
+}
+
+// fcpGap 主故事情节中的空隙，也作为其他轨道片段的连接点
+type fcpGap struct {
+	XMLName   xml.Name `xml:"gap"`
+	Name      string   `xml:"name,attr"`
+	Offset    string   `xml:"offset,attr"`
+	Start     string   `xml:"start,attr"`
+	Duration  string   `xml:"duration,attr"`
+	Connected []any    `xml:",omitempty"`
+	recordIn  time.Duration
+	recordOut time.Duration
+}
+
+type fcpTransition struct {
+	XMLName  xml.Name `xml:"transition"`
+	Name     string   `xml:"name,attr"`
+	Offset   string   `xml:"offset,attr"`
+	Duration string   `xml:"duration,attr"`
+}
+
+// WriteFCPXML 把时间线写为 FCPXML 1.9
+//
+// 第一条视频轨道作为主故事情节（空隙写为 gap），其余视频轨道以正数 lane、音频轨道以负数
+// lane 连接到主故事情节上。只带视频的片段设置 srcEnable="video"。转场写为交叉溶解。
+// 源文件以 file:// 绝对路径引用，资源时长取各片段用到的最大出点。
+func WriteFCPXML(w io.Writer, tl *Timeline) error {
+	if err := tl.Validate(); err != nil {
+		return err
+	}
+	fx := &fcpxmlWriter{tl: tl, assets: map[string]*fcpAsset{}}
+
+	primary := -1
+	for i, track := range tl.Tracks {
+		if track.Kind == VideoTrack {
+			primary = i
+			break
+		}
+	}
+	var spine []any
+	if primary >= 0 {
+		spine = fx.spine(tl.Tracks[primary])
+	} else {
+		spine = fx.spine(&Track{})
+	}
+
+	lanes := map[TrackKind]int{}
+	for i, track := range tl.Tracks {
+		if i == primary {
+			continue
+		}
+		lane := lanes[track.Kind] + 1
+		lanes[track.Kind] = lane
+		if track.Kind == AudioTrack {
+			lane = -lane
+		}
+		for _, event := range track.sorted() {
+			if err := fx.connect(spine, event, track.Kind, lane); err != nil {
+				return err
+			}
+		}
+	}
+
+	tcFormat := "NDF"
+	if tl.DropFrame && tl.FrameRate.Den == 1001 {
+		tcFormat = "DF"
+	}
+	doc := fcpxmlDocument{
+		Version: fcpxmlVersion,
+		Resources: fcpResources{
+			Format: fcpFormat{
+				ID:            "r0",
+				FrameDuration: fx.ratio(int64(tl.FrameRate.Den), int64(tl.FrameRate.Num)),
+				Width:         tl.Width,
+				Height:        tl.Height,
+			},
+			Assets: fx.assetList(),
+		},
+		Event: fcpEvent{
+			Name: "moviego",
+			Project: fcpProject{
+				Name: edlTitle(tl.Name),
+				Sequence: fcpSequence{
+					Format:   "r0",
+					Duration: fx.time(tl.Duration()),
+					TCStart:  "0s",
+					TCFormat: tcFormat,
+					Spine:    spine,
+				},
+			},
+		},
+	}
+
+	if _, err := io.WriteString(w, xml.Header+"<!DOCTYPE fcpxml>\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("写入 FCPXML 失败: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// fcpxmlWriter 构建 FCPXML 时的状态
+type fcpxmlWriter struct {
+	tl     *Timeline
+	assets map[string]*fcpAsset
+	order  []string
+}
+
+// spine 构建主故事情节，以 gap 填补片段之间的空隙并延伸到时间线末尾
+func (fx *fcpxmlWriter) spine(track *Track) []any {
+	var spine []any
+	var position time.Duration
+	gap := func(end time.Duration) {
+		if end > position {
+			spine = append(spine, &fcpGap{
+				Name:      "Gap",
+				Offset:    fx.time(position),
+				Start:     fx.time(position),
+				Duration:  fx.time(end - position),
+				recordIn:  position,
+				recordOut: end,
+			})
+			position = end
+		}
+	}
+	for _, event := range track.sorted() {
+		gap(event.RecordIn)
+		if event.Transition != nil && event.RecordIn > 0 {
+			spine = append(spine, &fcpTransition{
+				Name:     "Cross Dissolve",
+				Offset:   fx.time(event.RecordIn),
+				Duration: fx.time(event.Transition.Duration),
+			})
+		}
+		clip := fx.clip(event, VideoTrack, 0)
+		clip.Offset = fx.time(event.RecordIn)
+		clip.recordIn, clip.recordOut = event.RecordIn, event.RecordOut()
+		spine = append(spine, clip)
+		position = event.RecordOut()
+	}
+	gap(fx.tl.Duration())
+	return spine
+}
+
+// connect 把片段连接到其开始时刻所在的主故事情节元素上，offset 使用该元素的本地时间
+func (fx *fcpxmlWriter) connect(spine []any, event *Event, kind TrackKind, lane int) error {
+	clip := fx.clip(event, kind, lane)
+	for _, item := range spine {
+		switch parent := item.(type) {
+		case *fcpClip:
+			if event.RecordIn >= parent.recordIn && event.RecordIn < parent.recordOut {
+				local := parent.sourceIn + event.RecordIn - parent.recordIn
+				clip.Offset = fx.time(local)
+				parent.Connected = append(parent.Connected, clip)
+				return nil
+			}
+		case *fcpGap:
+			if event.RecordIn >= parent.recordIn && event.RecordIn < parent.recordOut {
+				clip.Offset = fx.time(event.RecordIn)
+				parent.Connected = append(parent.Connected, clip)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%v 处的片段 %s 不在主故事情节范围内", event.RecordIn, filepath.Base(event.Source))
+}
+
+// clip 构建片段元素并登记其资源
+func (fx *fcpxmlWriter) clip(event *Event, kind TrackKind, lane int) *fcpClip {
+	asset := fx.asset(event, kind)
+	clip := &fcpClip{
+		Ref:      asset.ID,
+		Name:     asset.Name,
+		Lane:     lane,
+		Start:    fx.time(event.SourceIn),
+		Duration: fx.time(event.Duration()),
+		sourceIn: event.SourceIn,
+	}
+	if kind == VideoTrack && !event.Audio {
+		clip.SrcEnable = "video"
+	}
+	return clip
+}
+
+// asset 返回源文件对应的资源，首次引用时创建；时长取用到的最大出点
+func (fx *fcpxmlWriter) asset(event *Event, kind TrackKind) *fcpAsset {
+	asset, ok := fx.assets[event.Source]
+	if !ok {
+		src := event.Source
+		if abs, err := filepath.Abs(src); err == nil {
+			src = abs
+		}
+		asset = &fcpAsset{
+			ID:       fmt.Sprintf("r%d", len(fx.assets)+1),
+			Name:     filepath.Base(event.Source),
+			Start:    "0s",
+			MediaRep: fcpMediaRep{Kind: "original-media", Src: (&url.URL{Scheme: "file", Path: filepath.ToSlash(src)}).String()},
+		}
+		fx.assets[event.Source] = asset
+		fx.order = append(fx.order, event.Source)
+	}
+	if kind == VideoTrack {
+		asset.HasVideo = "1"
+		asset.Format = "r0"
+	}
+	if kind == AudioTrack || event.Audio {
+		asset.HasAudio = "1"
+	}
+	if event.SourceOut > asset.end {
+		asset.end = event.SourceOut
+		asset.Duration = fx.time(event.SourceOut)
+	}
+	return asset
+}
+
+// assetList 按首次引用的顺序返回资源
+func (fx *fcpxmlWriter) assetList() []fcpAsset {
+	assets := make([]fcpAsset, len(fx.order))
+	for i, source := range fx.order {
+		assets[i] = *fx.assets[source]
+	}
+	return assets
+}
+
+// time 把时间对齐到帧并写为 FCPXML 的有理数秒，如 "1001/30000s"
+func (fx *fcpxmlWriter) time(d time.Duration) string {
+	rate := fx.tl.FrameRate
+	frames := Frames(d, rate)
+	return fx.ratio(frames*int64(rate.Den), int64(rate.Num))
+}
+
+// ratio 约分并格式化 num/den 秒
+func (fx *fcpxmlWriter) ratio(num, den int64) string {
+	if num == 0 {
+		return "0s"
+	}
+	a, b := num, den
+	for b != 0 {
+		a, b = b, a%b
+	}
+	num, den = num/a, den/a
+	if den == 1 {
+		return fmt.Sprintf("%ds", num)
+	}
+	return fmt.Sprintf("%d/%ds", num, den)
+}
@@ -0,0 +1,41 @@
+package timeline
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFCPXMLGolden(t *testing.T) {
+	var out strings.Builder
+	if err := WriteFCPXML(&out, sampleTimeline()); err != nil {
+		t.Fatalf("写入 FCPXML 失败: %v", err)
+	}
+	checkGolden(t, "demo.fcpxml", out.String())
+
+	// 输出应是格式正确的 XML
+	decoder := xml.NewDecoder(strings.NewReader(out.String()))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("FCPXML 不是有效的 XML: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestWriteFCPXMLConnectsToGap(t *testing.T) {
+	tl := New("late", rate25, 1920, 1080)
+	tl.AddTrack(VideoTrack)
+	tl.AddTrack(VideoTrack).Append("/media/a.mov", 0, time.Second)
+	var out strings.Builder
+	if err := WriteFCPXML(&out, tl); err != nil {
+		t.Fatalf("主故事情节为空时其他轨道应连接到空隙上: %v", err)
+	}
+	if !strings.Contains(out.String(), `<gap name="Gap" offset="0s" start="0s" duration="1s">`) {
+		t.Fatalf("应以空隙填满主故事情节:\n%s", out.String())
+	}
+}
@@ -0,0 +1,50 @@
+package timeline
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// Frames 把时间换算为 rate 下最接近的帧数
+func Frames(d time.Duration, rate ffmpeg.Rational) int64 {
+	if rate.IsZero() {
+		return 0
+	}
+	return int64(math.Round(d.Seconds() * rate.Float64()))
+}
+
+// Timecode 把帧数格式化为 HH:MM:SS:FF 时间码，rate 为 29.97/59.94 且 drop 为 true 时使用丢帧时间码（分隔符为 ;）
+//
+// 非整数帧率按最接近的整数（如 30000/1001 按 30）计数，与剪辑软件的非丢帧时间码一致。
+func Timecode(frames int64, rate ffmpeg.Rational, drop bool) string {
+	fps := int64(math.Round(rate.Float64()))
+	if fps <= 0 {
+		fps = 25
+	}
+	separator := ":"
+	if drop && rate.Den == 1001 && fps%30 == 0 {
+		frames = dropFrameCount(frames, fps)
+		separator = ";"
+	}
+	ff := frames % fps
+	ss := frames / fps % 60
+	mm := frames / (fps * 60) % 60
+	hh := frames / (fps * 3600) % 24
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", hh, mm, ss, separator, ff)
+}
+
+// dropFrameCount 把实际帧数换算为丢帧时间码的标称帧数：除每第十分钟外，每分钟开头跳过 2（59.94 为 4）个编号
+func dropFrameCount(frames, fps int64) int64 {
+	drop := fps / 15
+	perMinute := fps*60 - drop
+	perTenMinutes := perMinute*10 + drop
+	tens, rest := frames/perTenMinutes, frames%perTenMinutes
+	frames += 9 * drop * tens
+	if rest > drop {
+		frames += drop * ((rest - drop) / perMinute)
+	}
+	return frames
+}
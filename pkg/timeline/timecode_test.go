@@ -0,0 +1,129 @@
+package timeline
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/ffmpeg"
+)
+
+var (
+	rate2997 = ffmpeg.Rational{Num: 30000, Den: 1001}
+	rate5994 = ffmpeg.Rational{Num: 60000, Den: 1001}
+)
+
+// parseTimecode 按标称帧率把时间码换算回帧数，丢帧时间码减去每分钟开头跳过的编号（第十分钟除外）
+func parseTimecode(tc string, fps int64, drop bool) (int64, error) {
+	var hh, mm, ss, ff int64
+	var separator byte
+	if _, err := fmt.Sscanf(tc, "%02d:%02d:%02d%c%02d", &hh, &mm, &ss, &separator, &ff); err != nil {
+		return 0, err
+	}
+	if want := map[bool]byte{true: ';', false: ':'}[drop]; separator != want {
+		return 0, fmt.Errorf("分隔符为 %c，期望 %c", separator, want)
+	}
+	frames := ((hh*60+mm)*60+ss)*fps + ff
+	if drop {
+		minutes := hh*60 + mm
+		frames -= fps / 15 * (minutes - minutes/10)
+	}
+	return frames, nil
+}
+
+func TestTimecode(t *testing.T) {
+	tests := []struct {
+		frames int64
+		rate   ffmpeg.Rational
+		drop   bool
+		want   string
+	}{
+		{0, rate25, false, "00:00:00:00"},
+		{24, rate25, false, "00:00:00:24"},
+		{90000, rate25, false, "01:00:00:00"},
+		{24 * 3600 * 25, rate25, false, "00:00:00:00"},
+		{1800, rate2997, false, "00:01:00:00"},
+		{107892, rate2997, false, "00:59:56:12"},
+		{1799, rate2997, true, "00:00:59;29"},
+		{1800, rate2997, true, "00:01:00;02"},
+		{17981, rate2997, true, "00:09:59;29"},
+		{17982, rate2997, true, "00:10:00;00"},
+		{107892, rate2997, true, "01:00:00;00"},
+		{3599, rate5994, true, "00:00:59;59"},
+		{3600, rate5994, true, "00:01:00;04"},
+		{215784, rate5994, true, "01:00:00;00"},
+		// 只有 1001 分母的 30 倍数帧率才使用丢帧时间码
+		{1800, ffmpeg.Rational{Num: 30, Den: 1}, true, "00:01:00:00"},
+		{1800, ffmpeg.Rational{Num: 24000, Den: 1001}, true, "00:01:15:00"},
+		// 未设置帧率时按 25 计数
+		{25, ffmpeg.Rational{}, false, "00:00:01:00"},
+	}
+	for _, tt := range tests {
+		if got := Timecode(tt.frames, tt.rate, tt.drop); got != tt.want {
+			t.Errorf("Timecode(%d, %v, %v) = %s，期望 %s", tt.frames, tt.rate, tt.drop, got, tt.want)
+		}
+	}
+}
+
+func TestTimecodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		rate ffmpeg.Rational
+		fps  int64
+		drop bool
+	}{
+		{"25 NDF", rate25, 25, false},
+		{"23.976 NDF", ffmpeg.Rational{Num: 24000, Den: 1001}, 24, false},
+		{"29.97 NDF", rate2997, 30, false},
+		{"29.97 DF", rate2997, 30, true},
+		{"59.94 DF", rate5994, 60, true},
+	}
+	for _, tt := range tests {
+		// 逐帧覆盖 21 分钟，包括每个分钟和两个十分钟边界
+		for frames := int64(0); frames < 21*60*tt.fps; frames++ {
+			tc := Timecode(frames, tt.rate, tt.drop)
+			got, err := parseTimecode(tc, tt.fps, tt.drop)
+			if err != nil {
+				t.Fatalf("%s: 解析第 %d 帧的时间码 %s 失败: %v", tt.name, frames, tc, err)
+			}
+			if got != frames {
+				t.Fatalf("%s: 第 %d 帧的时间码 %s 换算回 %d", tt.name, frames, tc, got)
+			}
+			if tt.drop {
+				var hh, mm, ss, ff int64
+				fmt.Sscanf(tc, "%02d:%02d:%02d;%02d", &hh, &mm, &ss, &ff)
+				if ss == 0 && mm%10 != 0 && ff < tt.fps/15 {
+					t.Fatalf("%s: 第 %d 帧使用了应跳过的编号 %s", tt.name, frames, tc)
+				}
+			}
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		rate ffmpeg.Rational
+		want int64
+	}{
+		{time.Second, rate25, 25},
+		{time.Hour, rate2997, 107892},
+		{time.Hour, rate5994, 215784},
+		{1001 * time.Millisecond / 30, rate2997, 1},
+		{19 * time.Millisecond, rate25, 0},
+		{21 * time.Millisecond, rate25, 1},
+		{time.Second, ffmpeg.Rational{}, 0},
+	}
+	for _, tt := range tests {
+		if got := Frames(tt.d, tt.rate); got != tt.want {
+			t.Errorf("Frames(%v, %v) = %d，期望 %d", tt.d, tt.rate, got, tt.want)
+		}
+	}
+	// 按帧率换算的时间与帧数往返一致
+	for frames := int64(0); frames < 10000; frames++ {
+		d := time.Duration(float64(frames) * float64(time.Second) / rate2997.Float64())
+		if got := Frames(d, rate2997); got != frames {
+			t.Fatalf("第 %d 帧的时间 %v 换算回 %d", frames, d, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package timeline 描述由源文件片段按时间排列组成的剪辑序列，可导出为 EDL（CMX3600）或 FCPXML，
+// 把在 Go 中拼装好的剪辑交给专业剪辑软件完成调色、混音等后期工作
+//
+// 时间线只记录片段引用（源文件、入出点和在序列中的位置），不包含特效、叠加或变速。简单的剪切序列
+// 还可以用 RenderConcat 交给一次 FFmpeg 调用直接渲染。
+package timeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/video"
+)
+
+// TrackKind 轨道类型
+type TrackKind int
+
+const (
+	// VideoTrack 视频轨道，片段可带有源文件的音频（见 Event.Audio）
+	VideoTrack TrackKind = iota
+	// AudioTrack 独立的音频轨道，如配乐或旁白
+	AudioTrack
+)
+
+// Transition 进入片段时的转场，从片段的 RecordIn 开始，持续期间上一片段需要有余量
+type Transition struct {
+	Duration time.Duration
+}
+
+// Event 时间线上的一个片段
+type Event struct {
+	Source    string        // 源文件路径
+	Reel      string        // EDL 卷名，为空时使用 "AX" 并以注释记录文件名
+	SourceIn  time.Duration // 源文件中的入点
+	SourceOut time.Duration // 源文件中的出点（不含）
+	RecordIn  time.Duration // 在序列中的开始位置
+	Audio     bool          // 视频片段是否同时使用源文件的音频
+	// Transition 进入本片段的转场，nil 表示硬切
+	Transition *Transition
+}
+
+// Duration 返回片段时长
+func (e *Event) Duration() time.Duration {
+	return e.SourceOut - e.SourceIn
+}
+
+// RecordOut 返回片段在序列中的结束位置（不含）
+func (e *Event) RecordOut() time.Duration {
+	return e.RecordIn + e.Duration()
+}
+
+// Track 一条轨道，片段按 RecordIn 排列且互不重叠
+type Track struct {
+	Kind   TrackKind
+	Events []*Event
+}
+
+// Append 把 source 的 [in, out) 追加到轨道末尾，返回新片段以便设置转场等属性
+func (t *Track) Append(source string, in, out time.Duration) *Event {
+	event := &Event{
+		Source:    source,
+		SourceIn:  in,
+		SourceOut: out,
+		RecordIn:  t.End(),
+		Audio:     t.Kind == AudioTrack,
+	}
+	t.Events = append(t.Events, event)
+	return event
+}
+
+// End 返回轨道最后一个片段的结束位置
+func (t *Track) End() time.Duration {
+	var end time.Duration
+	for _, event := range t.Events {
+		end = max(end, event.RecordOut())
+	}
+	return end
+}
+
+// sorted 返回按 RecordIn 排序的片段
+func (t *Track) sorted() []*Event {
+	events := append([]*Event(nil), t.Events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].RecordIn < events[j].RecordIn })
+	return events
+}
+
+// Timeline 剪辑序列
+type Timeline struct {
+	Name      string
+	FrameRate ffmpeg.Rational // 序列帧率，决定时间码
+	Width     int
+	Height    int
+	// DropFrame 29.97/59.94 帧率下使用丢帧时间码
+	DropFrame bool
+	Tracks    []*Track
+}
+
+// New 创建空的时间线
+func New(name string, frameRate ffmpeg.Rational, width, height int) *Timeline {
+	return &Timeline{Name: name, FrameRate: frameRate, Width: width, Height: height}
+}
+
+// AddTrack 添加一条轨道
+func (tl *Timeline) AddTrack(kind TrackKind) *Track {
+	track := &Track{Kind: kind}
+	tl.Tracks = append(tl.Tracks, track)
+	return track
+}
+
+// Duration 返回所有轨道中最晚的结束位置
+func (tl *Timeline) Duration() time.Duration {
+	var end time.Duration
+	for _, track := range tl.Tracks {
+		end = max(end, track.End())
+	}
+	return end
+}
+
+// Validate 检查帧率、入出点以及同一轨道内片段是否重叠
+func (tl *Timeline) Validate() error {
+	if tl.FrameRate.IsZero() {
+		return fmt.Errorf("时间线 %q 未设置帧率", tl.Name)
+	}
+	for i, track := range tl.Tracks {
+		events := track.sorted()
+		for j, event := range events {
+			if event.SourceIn < 0 || event.SourceOut <= event.SourceIn || event.RecordIn < 0 {
+				return fmt.Errorf("%w: 轨道 %d 片段 %s [%v, %v)", core.ErrInvalidTimeRange, i, filepath.Base(event.Source), event.SourceIn, event.SourceOut)
+			}
+			if j > 0 && event.RecordIn < events[j-1].RecordOut() {
+				return fmt.Errorf("轨道 %d 中 %v 处的片段与上一片段重叠", i, event.RecordIn)
+			}
+			if event.Transition != nil && event.Transition.Duration > event.Duration() {
+				return fmt.Errorf("轨道 %d 中 %v 处的转场长于片段", i, event.RecordIn)
+			}
+		}
+	}
+	return nil
+}
+
+// FromClips 按顺序把视频文件剪辑（通常是 Subclip 的结果）首尾相接排成一条视频轨道
+//
+// 帧率和尺寸取自第一个剪辑，带音轨的剪辑同时使用源文件音频。变速剪辑无法用片段引用表示，返回错误。
+func FromClips(name string, clips ...*video.VideoFileClip) (*Timeline, error) {
+	if len(clips) == 0 {
+		return nil, fmt.Errorf("没有剪辑")
+	}
+	first := clips[0]
+	tl := New(name, first.FrameRate(), first.Width(), first.Height())
+	track := tl.AddTrack(VideoTrack)
+	for i, clip := range clips {
+		in, out := clip.Start(), clip.End()
+		// 源文件区间与剪辑时长不一致说明经过变速
+		if diff := (out - in) - clip.Duration(); diff > time.Millisecond || diff < -time.Millisecond {
+			return nil, fmt.Errorf("第 %d 个剪辑经过变速，无法导出为片段引用", i)
+		}
+		event := track.Append(clip.Filename(), in, out)
+		event.Audio = clip.Audio() != nil
+	}
+	return tl, nil
+}
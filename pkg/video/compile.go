@@ -0,0 +1,565 @@
+package video
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/pixel"
+)
+
+// TimeRange 源剪辑中的一段 [Start, End)，Score 为精彩程度，用于排序和按总时长取舍
+type TimeRange struct {
+	Start, End time.Duration
+	Score      float64
+}
+
+// Duration 返回片段时长
+func (r TimeRange) Duration() time.Duration {
+	return r.End - r.Start
+}
+
+// CompileOrder 精彩集锦中片段的排列顺序
+type CompileOrder int
+
+const (
+	// CompileChronological 按片段在源剪辑中的时间排列，默认
+	CompileChronological CompileOrder = iota
+	// CompileByScore 按得分从高到低排列，得分相同时保持 segments 中的顺序
+	CompileByScore
+	// CompileAsGiven 保持 segments 中的顺序
+	CompileAsGiven
+)
+
+var compileOrderNames = []string{"chronological", "score", "given"}
+
+// String 返回排列顺序的名称
+func (o CompileOrder) String() string {
+	if o >= 0 && int(o) < len(compileOrderNames) {
+		return compileOrderNames[o]
+	}
+	return fmt.Sprintf("CompileOrder(%d)", int(o))
+}
+
+// ParseCompileOrder 解析排列顺序名称
+func ParseCompileOrder(name string) (CompileOrder, error) {
+	for i, n := range compileOrderNames {
+		if strings.EqualFold(name, n) {
+			return CompileOrder(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的排列顺序: %q（支持 %s）", name, strings.Join(compileOrderNames, "、"))
+}
+
+// CompileOptions 精彩集锦选项
+type CompileOptions struct {
+	Order CompileOrder
+
+	// Music 背景音乐，从集锦开头播放，比集锦短时循环；源剪辑有音轨时采样率和声道数需与其一致
+	Music       core.AudioClip
+	MusicVolume float64 // 背景音乐音量，默认 0.3
+	// MusicFadeOut 背景音乐在集锦结尾的淡出时长，默认 1 秒，负值表示不淡出
+	MusicFadeOut time.Duration
+
+	// Mute 不保留片段原声，只有背景音乐
+	Mute bool
+
+	// LinearLight 在线性光下交叉淡化，明暗差异大的片段之间过渡时中间帧不再发暗
+	LinearLight bool
+}
+
+// HighlightClip 由源剪辑的若干片段首尾相接组成的精彩集锦，相邻片段之间交叉淡化
+//
+// 源剪辑归调用者所有，需在集锦之后关闭。画面和音轨都在取帧时由源剪辑按需计算，不预先渲染。
+type HighlightClip struct {
+	*core.BaseVideoClip
+	source     core.VideoClip
+	plan       *highlightPlan
+	audio      *highlightAudio // nil 表示没有音轨
+	offset     time.Duration   // Subclip 后相对集锦开头的时间偏移
+	linear     bool            // 在线性光下交叉淡化
+	processMgr *ffmpeg.ProcessManager
+	ownsSource bool // source 由 WithoutProxy 创建，Close 时一并关闭
+	closed     bool
+}
+
+// highlightPlan 片段的排列，画面和音轨共用
+type highlightPlan struct {
+	segments   []TimeRange     // 按播放顺序
+	positions  []time.Duration // 各片段在集锦中的开始时间
+	transition time.Duration
+	duration   time.Duration
+}
+
+// Compile 从 clip 中取出 segments 并按顺序拼接为精彩集锦，相邻片段之间做 transition 时长的交叉淡化
+//
+// 片段边界对齐到源剪辑的帧间隔。maxDuration 大于 0 时按得分从高到低挑选放得下的片段，
+// 连最高分的片段都放不下时截取它的开头；挑选后再按 options.Order 排列。
+// transition 不超过最短片段的一半，为 0 时硬切。
+func Compile(clip core.VideoClip, segments []TimeRange, transition, maxDuration time.Duration, options *CompileOptions, processMgr *ffmpeg.ProcessManager) (*HighlightClip, error) {
+	if options == nil {
+		options = &CompileOptions{}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("没有可用的片段")
+	}
+	if transition < 0 {
+		return nil, fmt.Errorf("无效的转场时长: %v", transition)
+	}
+
+	fps := clip.FPS()
+	transition = alignToFrame(transition, fps)
+	candidates := make([]TimeRange, len(segments))
+	for i, segment := range segments {
+		start, end := alignToFrame(segment.Start, fps), alignToFrame(segment.End, fps)
+		if start < 0 || end > clip.Duration() || start >= end {
+			return nil, fmt.Errorf("%w: 第 %d 段 [%v, %v) 不在 [0, %v] 内或为空", core.ErrInvalidTimeRange, i, segment.Start, segment.End, clip.Duration())
+		}
+		candidates[i] = TimeRange{Start: start, End: end, Score: segment.Score}
+		transition = min(transition, candidates[i].Duration()/2)
+	}
+
+	selected := selectHighlights(candidates, transition, alignToFrame(maxDuration, fps))
+	switch options.Order {
+	case CompileChronological:
+		slices.SortStableFunc(selected, func(a, b indexedRange) int { return cmp.Compare(a.Start, b.Start) })
+	case CompileByScore:
+		slices.SortStableFunc(selected, func(a, b indexedRange) int { return cmp.Compare(b.Score, a.Score) })
+	case CompileAsGiven:
+		slices.SortStableFunc(selected, func(a, b indexedRange) int { return cmp.Compare(a.index, b.index) })
+	default:
+		return nil, fmt.Errorf("未知的排列顺序: %v", options.Order)
+	}
+
+	plan := &highlightPlan{transition: transition}
+	if len(selected) == 1 {
+		plan.transition = 0
+	}
+	for _, s := range selected {
+		if len(plan.segments) > 0 {
+			plan.duration -= plan.transition
+		}
+		plan.segments = append(plan.segments, s.TimeRange)
+		plan.positions = append(plan.positions, plan.duration)
+		plan.duration += s.Duration()
+	}
+
+	hc := &HighlightClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, plan.duration, plan.duration, fps, clip.Width(), clip.Height()),
+		source:        clip,
+		plan:          plan,
+		linear:        options.LinearLight,
+		processMgr:    processMgr,
+	}
+	audio, err := newHighlightAudio(clip, plan, options, processMgr)
+	if err != nil {
+		return nil, err
+	}
+	hc.audio = audio
+	return hc, nil
+}
+
+// indexedRange 带有在 segments 中序号的片段，用于恢复原顺序
+type indexedRange struct {
+	TimeRange
+	index int
+}
+
+// selectHighlights 在 maxDuration 内按得分从高到低挑选片段，maxDuration 不大于 0 时全部保留
+func selectHighlights(candidates []TimeRange, transition, maxDuration time.Duration) []indexedRange {
+	all := make([]indexedRange, len(candidates))
+	for i, c := range candidates {
+		all[i] = indexedRange{TimeRange: c, index: i}
+	}
+	if maxDuration <= 0 {
+		return all
+	}
+
+	slices.SortStableFunc(all, func(a, b indexedRange) int { return cmp.Compare(b.Score, a.Score) })
+	var selected []indexedRange
+	var total time.Duration
+	for _, c := range all {
+		length := c.Duration()
+		if len(selected) > 0 {
+			length -= transition
+		}
+		// 较长的高分片段放不下时，后面较短的片段仍可能放得下
+		if total+length <= maxDuration {
+			selected = append(selected, c)
+			total += length
+		}
+	}
+	if len(selected) == 0 {
+		best := all[0]
+		best.End = best.Start + maxDuration
+		selected = append(selected, best)
+	}
+	return selected
+}
+
+// Segments 返回按播放顺序排列的片段，边界已对齐到帧
+func (hc *HighlightClip) Segments() []TimeRange {
+	return slices.Clone(hc.plan.segments)
+}
+
+// Positions 返回各片段在集锦中的开始时间，与 Segments 一一对应
+func (hc *HighlightClip) Positions() []time.Duration {
+	return slices.Clone(hc.plan.positions)
+}
+
+// Transition 返回实际使用的转场时长
+func (hc *HighlightClip) Transition() time.Duration {
+	return hc.plan.transition
+}
+
+// segmentAt 返回 t 处开始最晚的片段序号
+func (p *highlightPlan) segmentAt(t time.Duration) int {
+	i, found := slices.BinarySearch(p.positions, t)
+	if !found {
+		i--
+	}
+	return max(i, 0)
+}
+
+// GetFrame 返回 t 处的画面，转场期间为前后两个片段的交叉淡化
+func (hc *HighlightClip) GetFrame(t time.Duration) (image.Image, error) {
+	if hc.closed {
+		return nil, fmt.Errorf("剪辑已关闭")
+	}
+	plan := hc.plan
+	t = max(0, min(t+hc.offset, plan.duration-1))
+	i := plan.segmentAt(t)
+	frame, err := hc.source.GetFrame(plan.segments[i].Start + t - plan.positions[i])
+	if err != nil {
+		return nil, fmt.Errorf("获取第 %d 段的画面失败: %w", i, err)
+	}
+	if i == 0 || t >= plan.positions[i]+plan.transition {
+		return frame, nil
+	}
+	previous, err := hc.source.GetFrame(plan.segments[i-1].Start + t - plan.positions[i-1])
+	if err != nil {
+		return nil, fmt.Errorf("获取第 %d 段的画面失败: %w", i-1, err)
+	}
+	return crossfade(previous, frame, float64(t-plan.positions[i])/float64(plan.transition), hc.linear), nil
+}
+
+// crossfade 按 alpha（0–1）从 a 过渡到 b，两帧尺寸需相同；linear 为 true 时在线性光下混合
+func crossfade(a, b image.Image, alpha float64, linear bool) *image.RGBA {
+	from, to := pixel.ToRGBA(a), pixel.ToRGBA(b)
+	bounds := from.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	w := int(alpha*256 + 0.5)
+	effects.Parallel(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			p := from.Pix[from.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:bounds.Dx()*4]
+			q := to.Pix[to.PixOffset(to.Rect.Min.X, to.Rect.Min.Y+y):][:len(p)]
+			row := out.Pix[out.PixOffset(0, y):][:len(p)]
+			if linear {
+				pixel.MixLinear(row, p, q, alpha)
+				continue
+			}
+			for x := range row {
+				row[x] = uint8((int(p[x])*(256-w) + int(q[x])*w + 128) >> 8)
+			}
+		}
+	})
+	return out
+}
+
+// GetAudioFrame 返回 t 处的音频帧
+func (hc *HighlightClip) GetAudioFrame(t time.Duration) ([]float64, error) {
+	if hc.closed {
+		return nil, fmt.Errorf("剪辑已关闭")
+	}
+	if hc.audio == nil {
+		return nil, fmt.Errorf("没有音频")
+	}
+	return hc.audio.GetAudioFrame(t)
+}
+
+// Audio 返回集锦的音轨：片段原声按转场交叉淡化并叠加背景音乐，源剪辑没有音轨且没有背景音乐时为 nil
+func (hc *HighlightClip) Audio() core.AudioClip {
+	if hc.audio == nil {
+		return nil
+	}
+	return hc.audio
+}
+
+// Subclip 截取集锦的时间段
+func (hc *HighlightClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > hc.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	sub := *hc
+	sub.BaseVideoClip = core.NewBaseVideoClip(0, end-start, end-start, hc.FPS(), hc.Width(), hc.Height())
+	sub.offset = hc.offset + start
+	sub.ownsSource = false // 源剪辑仍归 hc 所有
+	if hc.audio != nil {
+		sub.audio = hc.audio.window(start, end)
+	}
+	return &sub, nil
+}
+
+// WriteToFile 写入集锦，片段原声与背景音乐混合后的音轨按 AudioCodec 编码混入输出
+func (hc *HighlightClip) WriteToFile(filename string, options *core.WriteOptions) error {
+	if hc.closed {
+		return fmt.Errorf("剪辑已关闭")
+	}
+	clip := NewEffectVideoClip(hc, hc.processMgr)
+	defer clip.Close()
+	return clip.WriteToFile(filename, options)
+}
+
+// WithoutProxy 源剪辑包含代理时，返回按原始分辨率取帧的集锦，片段和音轨不变；否则返回 nil
+func (hc *HighlightClip) WithoutProxy() (core.VideoClip, error) {
+	full, err := FullResolution(hc.source)
+	if err != nil || full == nil {
+		return nil, err
+	}
+	clip := *hc
+	clip.BaseVideoClip = core.NewBaseVideoClip(0, hc.Duration(), hc.Duration(), hc.FPS(), full.Width(), full.Height())
+	clip.source = full
+	clip.ownsSource = true
+	return &clip, nil
+}
+
+// Close 关闭集锦，不关闭调用者传入的源剪辑和背景音乐
+func (hc *HighlightClip) Close() error {
+	if hc.closed {
+		return nil
+	}
+	hc.closed = true
+	if hc.ownsSource {
+		return hc.source.Close()
+	}
+	return nil
+}
+
+// highlightAudio 集锦的音轨，片段原声以等功率曲线交叉淡化，背景音乐循环叠加并在结尾淡出
+type highlightAudio struct {
+	*core.BaseAudioClip
+	plan         *highlightPlan
+	source       core.AudioClip // nil 表示不使用原声
+	music        core.AudioClip
+	musicVolume  float64
+	musicFadeOut time.Duration
+	offset       time.Duration
+	processMgr   *ffmpeg.ProcessManager
+}
+
+// newHighlightAudio 创建集锦的音轨，既没有原声也没有背景音乐时返回 nil
+func newHighlightAudio(clip core.VideoClip, plan *highlightPlan, options *CompileOptions, processMgr *ffmpeg.ProcessManager) (*highlightAudio, error) {
+	var source core.AudioClip
+	if src, ok := clip.(core.AudioSource); ok && !options.Mute {
+		source = src.Audio()
+	}
+	format := source
+	if format == nil {
+		format = options.Music
+	}
+	if format == nil {
+		return nil, nil
+	}
+	if source != nil && options.Music != nil &&
+		(options.Music.SampleRate() != source.SampleRate() || options.Music.Channels() != source.Channels()) {
+		return nil, fmt.Errorf("背景音乐的格式 %d Hz %d 声道与源音轨 %d Hz %d 声道不一致",
+			options.Music.SampleRate(), options.Music.Channels(), source.SampleRate(), source.Channels())
+	}
+	if options.Music != nil && options.Music.Duration() <= 0 {
+		return nil, fmt.Errorf("背景音乐时长为 0")
+	}
+
+	ha := &highlightAudio{
+		BaseAudioClip: core.NewBaseAudioClip(0, plan.duration, plan.duration, format.FPS(), format.Channels(), format.SampleRate()),
+		plan:          plan,
+		source:        source,
+		music:         options.Music,
+		musicVolume:   options.MusicVolume,
+		musicFadeOut:  options.MusicFadeOut,
+		processMgr:    processMgr,
+	}
+	if ha.musicVolume <= 0 {
+		ha.musicVolume = 0.3
+	}
+	if ha.musicFadeOut == 0 {
+		ha.musicFadeOut = time.Second
+	}
+	return ha, nil
+}
+
+// GetAudioFrame 返回从 t 开始 0.1 秒的交错样本
+func (ha *highlightAudio) GetAudioFrame(t time.Duration) ([]float64, error) {
+	rate, channels := ha.SampleRate(), ha.Channels()
+	t += ha.offset
+	n := rate / 10
+	out := make([]float64, n*channels)
+	// sampleTime 返回第 k 个样本在集锦中的时间
+	sampleTime := func(k int) time.Duration {
+		return t + time.Duration(k)*time.Second/time.Duration(rate)
+	}
+
+	plan := ha.plan
+	if ha.source != nil {
+		for i, segment := range plan.segments {
+			begin, end := plan.positions[i], plan.positions[i]+segment.Duration()
+			if end <= t || begin >= sampleTime(n) {
+				continue
+			}
+			first := 0
+			if begin > t {
+				first = int(math.Ceil((begin - t).Seconds() * float64(rate)))
+			}
+			samples, err := ha.source.GetAudioFrame(segment.Start + sampleTime(first) - begin)
+			if err != nil {
+				return nil, fmt.Errorf("获取第 %d 段的音频失败: %w", i, err)
+			}
+			for k := first; k < n && (k-first+1)*channels <= len(samples); k++ {
+				u := sampleTime(k)
+				if u >= end {
+					break
+				}
+				gain := 1.0
+				if i > 0 && u < begin+plan.transition {
+					gain *= math.Sin(math.Pi / 2 * float64(u-begin) / float64(plan.transition))
+				}
+				if i < len(plan.segments)-1 && u > end-plan.transition {
+					gain *= math.Sin(math.Pi / 2 * float64(end-u) / float64(plan.transition))
+				}
+				for c := range channels {
+					out[k*channels+c] += samples[(k-first)*channels+c] * gain
+				}
+			}
+		}
+	}
+
+	if ha.music != nil {
+		if err := ha.mixMusic(out, t, sampleTime); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// mixMusic 把背景音乐叠加到从 t 开始的样本上，跨过音乐结尾时从头接上
+func (ha *highlightAudio) mixMusic(out []float64, t time.Duration, sampleTime func(k int) time.Duration) error {
+	channels := ha.Channels()
+	length := ha.music.Duration()
+	start := t % length
+	samples, err := ha.music.GetAudioFrame(start)
+	if err != nil {
+		return fmt.Errorf("获取背景音乐失败: %w", err)
+	}
+	// wrap 为从音乐开头接续的第一个样本
+	wrap := int(math.Ceil((length - start).Seconds() * float64(ha.SampleRate())))
+	var looped []float64
+	for k := 0; k*channels < len(out); k++ {
+		u := sampleTime(k)
+		if u >= ha.plan.duration {
+			break
+		}
+		frame, j := samples, k
+		if k >= wrap {
+			if looped == nil {
+				if looped, err = ha.music.GetAudioFrame(0); err != nil {
+					return fmt.Errorf("获取背景音乐失败: %w", err)
+				}
+			}
+			frame, j = looped, k-wrap
+		}
+		if (j+1)*channels > len(frame) {
+			continue
+		}
+		gain := ha.musicVolume
+		if fade := ha.musicFadeOut; fade > 0 && u > ha.plan.duration-fade {
+			gain *= float64(ha.plan.duration-u) / float64(fade)
+		}
+		for c := range channels {
+			out[k*channels+c] += frame[j*channels+c] * gain
+		}
+	}
+	return nil
+}
+
+// Subclip 截取音轨的时间段
+func (ha *highlightAudio) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > ha.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	return ha.window(start, end), nil
+}
+
+// window 返回 [start, end) 时间段的音轨
+func (ha *highlightAudio) window(start, end time.Duration) *highlightAudio {
+	sub := *ha
+	sub.BaseAudioClip = core.NewBaseAudioClip(0, end-start, end-start, ha.FPS(), ha.Channels(), ha.SampleRate())
+	sub.offset = ha.offset + start
+	return &sub
+}
+
+// WriteToFile 写入音频文件
+func (ha *highlightAudio) WriteToFile(filename string, options *core.WriteOptions) error {
+	options = core.ApplyWriteDefaults(options)
+	ctx, done := core.BeginRender(options.Context, ha, filename)
+	defer done()
+
+	writer := ffmpeg.NewAudioWriter(filename, &ffmpeg.AudioWriterOptions{
+		Codec:       cmp.Or(options.AudioCodec, "aac"),
+		Bitrate:     cmp.Or(options.AudioBitrate, "128k"),
+		SampleRate:  ha.SampleRate(),
+		Channels:    ha.Channels(),
+		Filter:      options.AudioFilter,
+		DirectWrite: options.DirectWrite,
+		LogLevel:    ffmpeg.LogLevel(options.LogLevel),
+		Logger:      options.Logger,
+	}, ha.processMgr)
+	if options.OnCommand != nil {
+		command := writer.Command()
+		options.OnCommand(command.Name, command.Args)
+	}
+	if options.DryRun {
+		return nil
+	}
+	if err := writer.Open(); err != nil {
+		return fmt.Errorf("打开写入器失败: %w", err)
+	}
+	defer writer.Abort()
+
+	frameInterval := 100 * time.Millisecond
+	totalFrames := int((ha.Duration() + frameInterval - 1) / frameInterval)
+	for i := range totalFrames {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%w: %v", core.ErrContextCancelled, err)
+		}
+		t := time.Duration(i) * frameInterval
+		frame, err := ha.GetAudioFrame(t)
+		if err != nil {
+			return fmt.Errorf("获取第 %d 帧失败: %w", i, err)
+		}
+		// 最后一帧只保留音轨时长内的样本
+		if remaining := ha.Duration() - t; remaining < frameInterval {
+			frame = frame[:int(remaining.Seconds()*float64(ha.SampleRate()))*ha.Channels()]
+		}
+		if err := writer.WriteAudioFrame(frame); err != nil {
+			return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
+		}
+		if options.Progress != nil {
+			options.Progress(i+1, totalFrames)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("关闭写入器失败: %w", err)
+	}
+	return nil
+}
+
+// Close 音轨不持有资源
+func (ha *highlightAudio) Close() error {
+	return nil
+}
@@ -0,0 +1,198 @@
+package video
+
+import (
+	"container/list"
+	"hash/fnv"
+	"image"
+	"io"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+)
+
+// DefaultEffectCacheBytes 特效缓存默认容量，约 256MB（1080p RGBA 帧约 8MB）
+const DefaultEffectCacheBytes = 256 << 20
+
+// EffectCache 在多个 EffectVideoClip 之间共享特效链中间结果的帧缓存
+//
+// 键为（原始剪辑, 时间, 特效链前缀哈希，见 effects.CacheableEffect），多个输出共享同一前缀（如 缩放+稳定 后分别调色和直出）时，
+// 前缀部分只计算一次。按近似字节数做 LRU 淘汰。缓存的帧被多个剪辑共享，特效不得原地修改输入帧。
+type EffectCache struct {
+	mutex    sync.Mutex
+	maxBytes int64
+	bytes    int64
+	entries  map[effectCacheKey]*list.Element
+	lru      *list.List // 队首为最近使用
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// EffectCacheStats 特效缓存统计
+type EffectCacheStats struct {
+	Entries   int
+	Bytes     int64
+	MaxBytes  int64
+	Hits      int64 // 命中任意长度前缀的次数
+	Misses    int64 // 所有前缀都未命中、从原始帧开始计算的次数
+	Evictions int64
+}
+
+// effectCacheKey 缓存键
+type effectCacheKey struct {
+	source core.VideoClip // 原始剪辑，按指针区分
+	t      time.Duration
+	prefix uint64 // 前 n 个启用特效的哈希
+}
+
+// effectCacheEntry LRU 链表节点
+type effectCacheEntry struct {
+	key   effectCacheKey
+	frame image.Image
+	size  int64
+}
+
+// NewEffectCache 创建容量为 maxBytes 的特效缓存，maxBytes <= 0 时使用 DefaultEffectCacheBytes
+func NewEffectCache(maxBytes int64) *EffectCache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultEffectCacheBytes
+	}
+	return &EffectCache{
+		maxBytes: maxBytes,
+		entries:  make(map[effectCacheKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// SetMaxBytes 调整缓存容量，缩小时立即淘汰多余的帧；maxBytes <= 0 时使用 DefaultEffectCacheBytes
+func (c *EffectCache) SetMaxBytes(maxBytes int64) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultEffectCacheBytes
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.maxBytes = maxBytes
+	c.evict()
+}
+
+// MaxBytes 返回缓存容量
+func (c *EffectCache) MaxBytes() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.maxBytes
+}
+
+// Clear 清空缓存，保留统计
+func (c *EffectCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[effectCacheKey]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+// Stats 返回缓存统计
+func (c *EffectCache) Stats() EffectCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return EffectCacheStats{
+		Entries:   c.lru.Len(),
+		Bytes:     c.bytes,
+		MaxBytes:  c.maxBytes,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// lookup 从最长的前缀开始查找，返回命中的帧及其覆盖的特效数，全部未命中时返回 0
+func (c *EffectCache) lookup(source core.VideoClip, t time.Duration, prefixes []uint64) (image.Image, int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for n := len(prefixes); n > 0; n-- {
+		if element, ok := c.entries[effectCacheKey{source, t, prefixes[n-1]}]; ok {
+			c.lru.MoveToFront(element)
+			c.hits++
+			return element.Value.(*effectCacheEntry).frame, n
+		}
+	}
+	c.misses++
+	return nil, 0
+}
+
+// store 缓存前缀 prefix 处理后的帧，单帧超过容量时不缓存
+func (c *EffectCache) store(source core.VideoClip, t time.Duration, prefix uint64, frame image.Image) {
+	size := frameBytes(frame)
+	key := effectCacheKey{source, t, prefix}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if size > c.maxBytes {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&effectCacheEntry{key: key, frame: frame, size: size})
+	c.bytes += size
+	c.evict()
+}
+
+// evict 淘汰最久未使用的帧直到不超过容量，调用方需持有锁
+func (c *EffectCache) evict() {
+	for c.bytes > c.maxBytes {
+		element := c.lru.Back()
+		if element == nil {
+			return
+		}
+		entry := c.lru.Remove(element).(*effectCacheEntry)
+		delete(c.entries, entry.key)
+		c.bytes -= entry.size
+		c.evictions++
+	}
+}
+
+// frameBytes 估算帧占用的内存
+func frameBytes(frame image.Image) int64 {
+	switch img := frame.(type) {
+	case *image.RGBA:
+		return int64(len(img.Pix))
+	case *image.NRGBA:
+		return int64(len(img.Pix))
+	case *image.Gray:
+		return int64(len(img.Pix))
+	case *image.RGBA64:
+		return int64(len(img.Pix))
+	}
+	bounds := frame.Bounds()
+	return int64(bounds.Dx()) * int64(bounds.Dy()) * 4
+}
+
+// effectPrefixHashes 计算启用特效链每个前缀的哈希，prefixes[i] 覆盖 chain[0..i]
+//
+// 哈希基于各特效的 effects.CacheKey，参数被 UpdateEffect 修改（含嵌套特效）后得到新键；
+// 遇到不可缓存的特效时只返回它之前的前缀。线性光和 16 位模式下的结果不同，单独计入哈希。
+func effectPrefixHashes(chain []effects.VideoEffect, linearLight, highPrecision bool) []uint64 {
+	prefixes := make([]uint64, 0, len(chain))
+	hash := fnv.New64a()
+	if linearLight {
+		io.WriteString(hash, "linear;")
+	}
+	if highPrecision {
+		io.WriteString(hash, "rgba64;")
+	}
+	for _, effect := range chain {
+		key := effects.CacheKey(effect)
+		if key == "" {
+			break
+		}
+		io.WriteString(hash, key)
+		io.WriteString(hash, ";")
+		prefixes = append(prefixes, hash.Sum64())
+	}
+	return prefixes
+}
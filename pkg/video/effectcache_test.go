@@ -0,0 +1,103 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core/coretest"
+	"moviepy-go/pkg/effects"
+)
+
+func TestEffectPrefixHashesNestedUpdate(t *testing.T) {
+	inner := effects.NewBrightnessEffect(1.2)
+	chain := []effects.VideoEffect{
+		effects.NewBlurEffect(2),
+		effects.NewRegionEffect(image.Rect(0, 0, 10, 10), inner),
+	}
+	before := effectPrefixHashes(chain, false, false)
+	inner.SetFactor(0.5)
+	after := effectPrefixHashes(chain, false, false)
+
+	if len(before) != 2 || len(after) != 2 {
+		t.Fatalf("前缀数量 %d、%d，期望 2", len(before), len(after))
+	}
+	if before[0] != after[0] {
+		t.Errorf("未修改的前缀哈希发生变化")
+	}
+	if before[1] == after[1] {
+		t.Errorf("修改嵌套特效参数后前缀哈希未变化")
+	}
+}
+
+func TestEffectPrefixHashesSameParameters(t *testing.T) {
+	a := effectPrefixHashes([]effects.VideoEffect{effects.NewResizeEffect(640, 360), effects.NewContrastEffect(1.1)}, false, false)
+	b := effectPrefixHashes([]effects.VideoEffect{effects.NewResizeEffect(640, 360), effects.NewContrastEffect(1.1)}, false, false)
+	if len(a) != 2 || a[1] != b[1] {
+		t.Errorf("参数相同的独立特效链哈希应相同: %v、%v", a, b)
+	}
+	if c := effectPrefixHashes([]effects.VideoEffect{effects.NewResizeEffect(640, 360)}, true, false); c[0] == a[0] {
+		t.Errorf("线性光模式应得到不同的哈希")
+	}
+}
+
+func TestEffectPrefixHashesStopsAtUncacheable(t *testing.T) {
+	chain := []effects.VideoEffect{
+		effects.NewBlurEffect(1),
+		effects.NewNoiseEffect(0.2),
+		effects.NewBlurEffect(1),
+	}
+	if prefixes := effectPrefixHashes(chain, false, false); len(prefixes) != 1 {
+		t.Errorf("随机噪点之后的前缀不应缓存，得到 %d 个前缀", len(prefixes))
+	}
+}
+
+func TestUpdateEffectInvalidatesAnimatedKeys(t *testing.T) {
+	gain := 1.0
+	animated := effects.NewAnimatedBrightnessEffect(func(time.Duration) float64 { return gain })
+	region := effects.NewAnimatedRegionEffect(func(time.Duration) image.Rectangle { return image.Rect(0, 0, 2, 2) },
+		effects.NewAnimatedBrightnessEffect(func(time.Duration) float64 { return gain }))
+	clip := NewEffectVideoClipWithOptions(coretest.NewCounterClip(4, 4, time.Second, 10), &EffectClipOptions{Cache: NewEffectCache(0)}, nil)
+	clip.AddEffect(animated)
+	clip.AddEffect(region)
+
+	before := effectPrefixHashes(clip.effects, false, false)
+	if again := effectPrefixHashes(clip.effects, false, false); again[0] != before[0] || again[1] != before[1] {
+		t.Fatalf("未修改的动画特效哈希应保持不变")
+	}
+	// 函数内部状态的变化无法从键中看出，UpdateEffect 后应换用新键
+	if err := clip.UpdateEffect(0, func(effects.VideoEffect) error { gain = 2; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	after := effectPrefixHashes(clip.effects, false, false)
+	if after[0] == before[0] || after[1] == before[1] {
+		t.Errorf("UpdateEffect 后动画亮度的前缀哈希未变化")
+	}
+	if err := clip.UpdateEffect(1, func(effects.VideoEffect) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if nested := effectPrefixHashes(clip.effects, false, false); nested[0] != after[0] || nested[1] == after[1] {
+		t.Errorf("更新区域特效应只改变其所在及之后的前缀哈希")
+	}
+}
+
+func TestUpdateEffectRefreshesCachedFrames(t *testing.T) {
+	gain := 1.0
+	source := coretest.NewSolidClip(color.RGBA{200, 200, 200, 255}, 4, 4, time.Second, 10)
+	clip := NewEffectVideoClipWithOptions(source, &EffectClipOptions{Cache: NewEffectCache(0)}, nil)
+	clip.AddEffect(effects.NewAnimatedBrightnessEffect(func(time.Duration) float64 { return gain }))
+
+	for _, want := range []uint8{200, 0} {
+		frame, err := clip.GetFrame(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := color.RGBAModel.Convert(frame.At(0, 0)).(color.RGBA).R; r != want {
+			t.Fatalf("亮度因子为 %g 时 R=%d，期望 %d", gain, r, want)
+		}
+		if err := clip.UpdateEffect(0, func(effects.VideoEffect) error { gain = 0; return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -1,51 +1,177 @@
 package video
 
 import (
+	"context"
 	"fmt"
 	"image"
+	"image/draw"
+	"sync"
 	"time"
 
 	"moviepy-go/pkg/core"
 	"moviepy-go/pkg/effects"
 	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/leakcheck"
+	"moviepy-go/pkg/preview"
 )
 
+// EvenPolicy 特效链输出奇数尺寸时的处理方式（yuv420p 编码要求宽高为偶数）
+type EvenPolicy int
+
+const (
+	// EvenPad 在右侧和底部补一像素黑边，默认
+	EvenPad EvenPolicy = iota
+	// EvenCrop 裁掉右侧和底部多出的一像素
+	EvenCrop
+	// EvenError 保持奇数尺寸，WriteToFile 在启动编码前报错
+	EvenError
+)
+
+// EffectClipOptions 特效视频剪辑选项
+type EffectClipOptions struct {
+	EvenDimensions EvenPolicy
+	// Cache 共享的特效中间结果缓存，多个剪辑使用同一原始剪辑和相同特效前缀时复用计算，nil 表示不缓存
+	Cache *EffectCache
+	// LinearLight 支持线性光的特效（effects.LinearLightEffect，如模糊、缩放）先还原为线性光再处理，
+	// 避免 sRGB 编码值直接平均造成的偏暗；其他特效不受影响
+	LinearLight bool
+	// HighPrecision 支持 16 位精度的特效（effects.HighPrecisionEffect，如亮度、对比度、饱和度、LUT）
+	// 以 *image.RGBA64 处理并在特效之间传递，编码时才量化为 8 位，长特效链不会出现色阶断层
+	HighPrecision bool
+}
+
 // EffectVideoClip 支持特效的视频剪辑
 type EffectVideoClip struct {
 	*core.BaseVideoClip
 	originalClip core.VideoClip
 	effects      []effects.VideoEffect
+	disabled     []bool // 与 effects 一一对应，true 表示跳过该特效
+	options      EffectClipOptions
+	rawWidth     int // 特效链输出的尺寸，应用 EvenPolicy 之前
+	rawHeight    int
 	processMgr   *ffmpeg.ProcessManager
+	ownsOriginal bool // originalClip 由本剪辑派生创建，Close 时一并关闭
 	closed       bool
+	mutex        sync.RWMutex // 保护特效列表与参数和 closed，GetFrame 持读锁，修改特效持写锁
+	leak         *leakcheck.Guard
 }
 
 // NewEffectVideoClip 创建新的特效视频剪辑
 func NewEffectVideoClip(original core.VideoClip, processMgr *ffmpeg.ProcessManager) *EffectVideoClip {
-	return &EffectVideoClip{
+	return NewEffectVideoClipWithOptions(original, nil, processMgr)
+}
+
+// NewEffectVideoClipWithOptions 使用指定选项创建特效视频剪辑
+func NewEffectVideoClipWithOptions(original core.VideoClip, options *EffectClipOptions, processMgr *ffmpeg.ProcessManager) *EffectVideoClip {
+	if options == nil {
+		options = &EffectClipOptions{}
+	}
+	evc := &EffectVideoClip{
 		BaseVideoClip: core.NewBaseVideoClip(original.Start(), original.End(), original.Duration(), original.FPS(), original.Width(), original.Height()),
 		originalClip:  original,
 		effects:       make([]effects.VideoEffect, 0),
+		options:       *options,
 		processMgr:    processMgr,
 	}
+	evc.updateFinalDimensions()
+	return evc
 }
 
 // AddEffect 添加特效
 func (evc *EffectVideoClip) AddEffect(effect effects.VideoEffect) {
+	evc.mutex.Lock()
+	defer evc.mutex.Unlock()
 	evc.effects = append(evc.effects, effect)
+	evc.disabled = append(evc.disabled, false)
 
 	// 重新计算应用所有特效后的最终尺寸
 	evc.updateFinalDimensions()
 }
 
+// SetEffectEnabled 启用或禁用第 i 个特效，禁用的特效在 GetFrame 和尺寸计算中被跳过
+//
+// 可在两次 GetFrame 之间调用，供交互式预览切换特效而无需重建剪辑。
+func (evc *EffectVideoClip) SetEffectEnabled(i int, enabled bool) error {
+	evc.mutex.Lock()
+	defer evc.mutex.Unlock()
+	if i < 0 || i >= len(evc.effects) {
+		return fmt.Errorf("特效索引 %d 超出范围 [0, %d)", i, len(evc.effects))
+	}
+	evc.disabled[i] = !enabled
+	evc.updateFinalDimensions()
+	return nil
+}
+
+// EffectEnabled 返回第 i 个特效是否启用，索引越界时为 false
+func (evc *EffectVideoClip) EffectEnabled(i int) bool {
+	evc.mutex.RLock()
+	defer evc.mutex.RUnlock()
+	return i >= 0 && i < len(evc.effects) && !evc.disabled[i]
+}
+
+// UpdateEffect 在写锁内调用 update 修改第 i 个特效的参数，正在进行的 GetFrame 完成后才会执行；
+// 修改后调用 effects.MarkUpdated，特效缓存不会再返回修改前的帧
+//
+// 如 clip.UpdateEffect(0, func(e effects.VideoEffect) error { e.(*effects.BrightnessEffect).SetFactor(1.2); return nil })。
+func (evc *EffectVideoClip) UpdateEffect(i int, update func(effect effects.VideoEffect) error) error {
+	evc.mutex.Lock()
+	defer evc.mutex.Unlock()
+	if i < 0 || i >= len(evc.effects) {
+		return fmt.Errorf("特效索引 %d 超出范围 [0, %d)", i, len(evc.effects))
+	}
+	if err := update(evc.effects[i]); err != nil {
+		return fmt.Errorf("更新特效 %s 失败: %w", evc.effects[i].GetName(), err)
+	}
+	// 依赖函数的特效按修订号区分缓存键，换用新的修订号使共享缓存中的旧帧不再命中
+	effects.MarkUpdated(evc.effects[i])
+	// 参数可能改变输出尺寸（如缩放）
+	evc.updateFinalDimensions()
+	return nil
+}
+
+// ReplaceEffect 用 effect 替换第 i 个特效，保留其启用状态
+func (evc *EffectVideoClip) ReplaceEffect(i int, effect effects.VideoEffect) error {
+	return evc.UpdateEffect(i, func(effects.VideoEffect) error {
+		evc.effects[i] = effect
+		return nil
+	})
+}
+
+// copyEffectsTo 将特效及其启用状态复制到派生剪辑
+func (evc *EffectVideoClip) copyEffectsTo(dst *EffectVideoClip) {
+	evc.mutex.RLock()
+	defer evc.mutex.RUnlock()
+	for i, effect := range evc.effects {
+		dst.AddEffect(effect)
+		dst.disabled[len(dst.disabled)-1] = evc.disabled[i]
+	}
+	dst.mutex.Lock()
+	dst.updateFinalDimensions()
+	dst.mutex.Unlock()
+}
+
 // updateFinalDimensions 更新应用所有特效后的最终尺寸
 func (evc *EffectVideoClip) updateFinalDimensions() {
 	// 从原始剪辑尺寸开始
 	width := evc.originalClip.Width()
 	height := evc.originalClip.Height()
 
-	// 依次应用每个特效来计算最终尺寸
-	for _, effect := range evc.effects {
-		width, height = evc.calculateEffectDimensions(effect, width, height)
+	// 依次由每个启用特效的 OutputSize 推算最终尺寸，不运行像素处理
+	for i, effect := range evc.effects {
+		if !evc.disabled[i] {
+			width, height = effect.OutputSize(width, height)
+		}
+	}
+
+	// 在最终尺寸上统一处理奇数宽高，而不是由各个特效分别取整
+	evc.rawWidth, evc.rawHeight = width, height
+	switch evc.options.EvenDimensions {
+	case EvenPad:
+		width += width % 2
+		height += height % 2
+	case EvenCrop:
+		width -= width % 2
+		height -= height % 2
 	}
 
 	// 如果尺寸有变化，更新BaseVideoClip
@@ -57,49 +183,107 @@ func (evc *EffectVideoClip) updateFinalDimensions() {
 	}
 }
 
-// calculateEffectDimensions 计算特效应用到指定尺寸后的新尺寸
-func (evc *EffectVideoClip) calculateEffectDimensions(effect effects.VideoEffect, inputWidth, inputHeight int) (int, int) {
-	// 创建测试图像
-	testImg := image.NewRGBA(image.Rect(0, 0, inputWidth, inputHeight))
-
-	// 应用特效
-	resultImg, err := effect.ApplyToFrame(testImg)
-	if err != nil {
-		// 如果出错，返回输入尺寸
-		return inputWidth, inputHeight
-	}
-
-	bounds := resultImg.Bounds()
-	return bounds.Dx(), bounds.Dy()
-}
-
 // GetFrame 获取帧，应用所有特效
 func (evc *EffectVideoClip) GetFrame(t time.Duration) (image.Image, error) {
+	return evc.GetFrameContext(context.Background(), t)
+}
+
+// GetFrameContext 获取应用特效后的帧，追踪开启时为每个特效记录区间
+func (evc *EffectVideoClip) GetFrameContext(ctx context.Context, t time.Duration) (image.Image, error) {
+	// 应用所有启用的特效，持读锁避免与 UpdateEffect 和 Close 并发
+	evc.mutex.RLock()
+	defer evc.mutex.RUnlock()
 	if evc.closed {
 		return nil, fmt.Errorf("剪辑已关闭")
 	}
+	chain := make([]effects.VideoEffect, 0, len(evc.effects))
+	for i, effect := range evc.effects {
+		if !evc.disabled[i] {
+			chain = append(chain, effect)
+		}
+	}
 
-	// 从原始剪辑获取帧
-	frame, err := evc.originalClip.GetFrame(t)
-	if err != nil {
-		return nil, fmt.Errorf("获取原始帧失败: %w", err)
+	// 有缓存时从命中的最长前缀继续计算
+	var result image.Image
+	var prefixes []uint64
+	done := 0
+	cache := evc.options.Cache
+	if cache != nil && len(chain) > 0 {
+		prefixes = effectPrefixHashes(chain, evc.options.LinearLight, evc.options.HighPrecision)
+		result, done = cache.lookup(evc.originalClip, t, prefixes)
+	}
+	if done == 0 {
+		frame, err := core.GetFrameContext(ctx, evc.originalClip, t)
+		if err != nil {
+			return nil, fmt.Errorf("获取原始帧失败: %w", err)
+		}
+		result = frame
 	}
 
-	// 应用所有特效
-	result := frame
-	for _, effect := range evc.effects {
-		result, err = effect.ApplyToFrame(result)
+	tracing := core.Tracing()
+	for i := done; i < len(chain); i++ {
+		var err error
+		var span core.Span
+		effect := chain[i]
+		if tracing {
+			_, span = core.StartSpan(ctx, core.SpanEffect,
+				core.Attr("clip.id", core.ClipID(evc)), core.Attr("effect", effect.GetName()), core.Attr("index", i))
+		}
+		if linear, ok := effect.(effects.LinearLightEffect); ok && evc.options.LinearLight {
+			result, err = linear.ApplyToFrameLinear(result)
+		} else if deep, ok := effect.(effects.HighPrecisionEffect); ok && evc.options.HighPrecision {
+			result, err = deep.ApplyToFrame64(result, t)
+		} else if timed, ok := effect.(effects.TimedVideoEffect); ok {
+			result, err = timed.ApplyToFrameAt(result, t)
+		} else {
+			result, err = effect.ApplyToFrame(result)
+		}
+		if tracing {
+			core.EndSpan(span, err)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("应用特效 %s 失败: %w", effect.GetName(), err)
 		}
+		if cache != nil && i < len(prefixes) {
+			cache.store(evc.originalClip, t, prefixes[i], result)
+		}
 	}
 
-	return result, nil
+	return evc.fitEven(result), nil
+}
+
+// fitEven 按 EvenPolicy 将特效链输出补边或裁剪为剪辑尺寸，左上角对齐
+func (evc *EffectVideoClip) fitEven(frame image.Image) image.Image {
+	bounds := frame.Bounds()
+	if bounds.Dx() == evc.Width() && bounds.Dy() == evc.Height() {
+		return frame
+	}
+	// 只处理奇偶修正造成的一像素差异，其他尺寸变化（如动态特效）原样返回
+	if abs(bounds.Dx()-evc.Width()) > 1 || abs(bounds.Dy()-evc.Height()) > 1 {
+		return frame
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, evc.Width(), evc.Height()))
+	draw.Draw(dst, dst.Bounds(), image.Black, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), frame, bounds.Min, draw.Src)
+	return dst
+}
+
+// EvenPolicy 返回奇数尺寸的处理方式
+func (evc *EffectVideoClip) EvenPolicy() EvenPolicy {
+	return evc.options.EvenDimensions
+}
+
+// abs 整数绝对值
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
 }
 
 // GetAudioFrame 获取音频帧
 func (evc *EffectVideoClip) GetAudioFrame(t time.Duration) ([]float64, error) {
-	if evc.closed {
+	if evc.IsClosed() {
 		return nil, fmt.Errorf("剪辑已关闭")
 	}
 
@@ -111,6 +295,14 @@ func (evc *EffectVideoClip) GetAudioFrame(t time.Duration) ([]float64, error) {
 	return nil, fmt.Errorf("原始剪辑不支持音频")
 }
 
+// Audio 返回原始剪辑的音轨，原始剪辑不提供音轨时为 nil
+func (evc *EffectVideoClip) Audio() core.AudioClip {
+	if src, ok := evc.originalClip.(core.AudioSource); ok {
+		return src.Audio()
+	}
+	return nil
+}
+
 // Subclip 创建子剪辑
 func (evc *EffectVideoClip) Subclip(start, end time.Duration) (core.Clip, error) {
 	if start < 0 || end > evc.Duration() || start >= end {
@@ -123,23 +315,62 @@ func (evc *EffectVideoClip) Subclip(start, end time.Duration) (core.Clip, error)
 		return nil, fmt.Errorf("创建原始子剪辑失败: %w", err)
 	}
 
+	return evc.wrap(originalSubclip)
+}
+
+// WithStart 设置开始时间，由原始剪辑换算
+func (evc *EffectVideoClip) WithStart(start time.Duration) (core.Clip, error) {
+	original, err := evc.originalClip.WithStart(start)
+	if err != nil {
+		return nil, fmt.Errorf("设置原始剪辑开始时间失败: %w", err)
+	}
+	return evc.wrap(original)
+}
+
+// WithEnd 设置结束时间，由原始剪辑换算
+func (evc *EffectVideoClip) WithEnd(end time.Duration) (core.Clip, error) {
+	original, err := evc.originalClip.WithEnd(end)
+	if err != nil {
+		return nil, fmt.Errorf("设置原始剪辑结束时间失败: %w", err)
+	}
+	return evc.wrap(original)
+}
+
+// wrap 用当前特效包装派生出的原始剪辑
+//
+// 派生出的原始剪辑（如文件剪辑的子剪辑持有读取器引用）只被新的特效剪辑使用，归其所有，随其关闭。
+func (evc *EffectVideoClip) wrap(originalSubclip core.Clip) (core.Clip, error) {
 	// 转换为视频剪辑
 	videoSubclip, ok := originalSubclip.(core.VideoClip)
 	if !ok {
+		originalSubclip.Close()
 		return nil, fmt.Errorf("原始子剪辑不是视频剪辑")
 	}
 
 	// 创建新的特效剪辑
-	effectSubclip := NewEffectVideoClip(videoSubclip, evc.processMgr)
+	effectSubclip := NewEffectVideoClipWithOptions(videoSubclip, &evc.options, evc.processMgr)
+	effectSubclip.ownsOriginal = true
+	effectSubclip.leak = leakcheck.Track(effectSubclip, "EffectVideoClip", fmt.Sprintf("%T", videoSubclip))
 
 	// 复制特效
-	for _, effect := range evc.effects {
-		effectSubclip.AddEffect(effect)
-	}
+	evc.copyEffectsTo(effectSubclip)
 
 	return effectSubclip, nil
 }
 
+// WithoutProxy 原始剪辑包含代理时，用其原始分辨率版本和当前特效创建派生剪辑；否则返回 nil
+func (evc *EffectVideoClip) WithoutProxy() (core.VideoClip, error) {
+	full, err := FullResolution(evc.originalClip)
+	if err != nil || full == nil {
+		return nil, err
+	}
+	clip, err := evc.wrap(full)
+	if err != nil {
+		return nil, err
+	}
+	return clip.(*EffectVideoClip), nil
+}
+
 // WithSpeed 调整播放速度
 func (evc *EffectVideoClip) WithSpeed(factor float64) (core.Clip, error) {
 	if factor <= 0 {
@@ -152,21 +383,7 @@ func (evc *EffectVideoClip) WithSpeed(factor float64) (core.Clip, error) {
 		return nil, fmt.Errorf("调整原始剪辑速度失败: %w", err)
 	}
 
-	// 转换为视频剪辑
-	videoSpeedClip, ok := originalSpeedClip.(core.VideoClip)
-	if !ok {
-		return nil, fmt.Errorf("原始速度剪辑不是视频剪辑")
-	}
-
-	// 创建新的特效剪辑
-	effectSpeedClip := NewEffectVideoClip(videoSpeedClip, evc.processMgr)
-
-	// 复制特效
-	for _, effect := range evc.effects {
-		effectSpeedClip.AddEffect(effect)
-	}
-
-	return effectSpeedClip, nil
+	return evc.wrap(originalSpeedClip)
 }
 
 // WithVolume 调整音量
@@ -181,21 +398,7 @@ func (evc *EffectVideoClip) WithVolume(factor float64) (core.Clip, error) {
 		return nil, fmt.Errorf("调整原始剪辑音量失败: %w", err)
 	}
 
-	// 转换为视频剪辑
-	videoVolumeClip, ok := originalVolumeClip.(core.VideoClip)
-	if !ok {
-		return nil, fmt.Errorf("原始音量剪辑不是视频剪辑")
-	}
-
-	// 创建新的特效剪辑
-	effectVolumeClip := NewEffectVideoClip(videoVolumeClip, evc.processMgr)
-
-	// 复制特效
-	for _, effect := range evc.effects {
-		effectVolumeClip.AddEffect(effect)
-	}
-
-	return effectVolumeClip, nil
+	return evc.wrap(originalVolumeClip)
 }
 
 // WithAudio 添加音频
@@ -206,21 +409,7 @@ func (evc *EffectVideoClip) WithAudio(audio core.AudioClip) (core.Clip, error) {
 		return nil, fmt.Errorf("为原始剪辑添加音频失败: %w", err)
 	}
 
-	// 转换为视频剪辑
-	videoAudioClip, ok := originalAudioClip.(core.VideoClip)
-	if !ok {
-		return nil, fmt.Errorf("原始音频剪辑不是视频剪辑")
-	}
-
-	// 创建新的特效剪辑
-	effectAudioClip := NewEffectVideoClip(videoAudioClip, evc.processMgr)
-
-	// 复制特效
-	for _, effect := range evc.effects {
-		effectAudioClip.AddEffect(effect)
-	}
-
-	return effectAudioClip, nil
+	return evc.wrap(originalAudioClip)
 }
 
 // WithoutAudio 移除音频
@@ -231,128 +420,105 @@ func (evc *EffectVideoClip) WithoutAudio() (core.Clip, error) {
 		return nil, fmt.Errorf("移除原始剪辑音频失败: %w", err)
 	}
 
-	// 转换为视频剪辑
-	videoNoAudioClip, ok := originalNoAudioClip.(core.VideoClip)
-	if !ok {
-		return nil, fmt.Errorf("原始无音频剪辑不是视频剪辑")
-	}
-
-	// 创建新的特效剪辑
-	effectNoAudioClip := NewEffectVideoClip(videoNoAudioClip, evc.processMgr)
-
-	// 复制特效
-	for _, effect := range evc.effects {
-		effectNoAudioClip.AddEffect(effect)
-	}
-
-	return effectNoAudioClip, nil
+	return evc.wrap(originalNoAudioClip)
 }
 
 // WriteToFile 写入文件
 func (evc *EffectVideoClip) WriteToFile(filename string, options *core.WriteOptions) error {
-	if evc.closed {
-		return fmt.Errorf("剪辑已关闭")
-	}
-
-	// 设置默认选项
-	if options == nil {
-		options = &core.WriteOptions{}
-	}
-	if options.Codec == "" {
-		options.Codec = "libx264"
-	}
-	if options.Bitrate == "" {
-		options.Bitrate = "2000k"
-	}
-	if options.FPS == 0 {
-		options.FPS = evc.FPS()
-	}
-
-	// 创建视频写入器
-	writerOptions := &ffmpeg.VideoWriterOptions{
-		Codec:   options.Codec,
-		Bitrate: options.Bitrate,
-		FPS:     options.FPS,
-	}
-
-	writer := ffmpeg.NewVideoWriter(filename, evc.Width(), evc.Height(), writerOptions, evc.processMgr)
-
-	// 打开写入器
-	if err := writer.Open(); err != nil {
-		return fmt.Errorf("打开写入器失败: %w", err)
-	}
-	defer writer.Close()
-
-	// 计算总帧数
-	totalFrames := int(evc.Duration().Seconds() * options.FPS)
-	frameInterval := time.Duration(float64(time.Second) / options.FPS)
+	return core.TraceRender(options, evc, filename, func(ctx context.Context) error {
+		return evc.writeToFile(ctx, filename, options)
+	})
+}
 
-	fmt.Printf("开始写入特效视频: %s\n", filename)
-	fmt.Printf("特效数量: %d\n", len(evc.effects))
-	for i, effect := range evc.effects {
-		fmt.Printf("  特效 %d: %s\n", i+1, effect.GetName())
+// writeToFile 逐帧渲染并写入，ctx 携带渲染区间
+func (evc *EffectVideoClip) writeToFile(ctx context.Context, filename string, options *core.WriteOptions) error {
+	if evc.IsClosed() {
+		return fmt.Errorf("剪辑已关闭")
 	}
-	fmt.Printf("总帧数: %d, 帧间隔: %v\n", totalFrames, frameInterval)
-
-	// 逐帧写入
-	for i := 0; i < totalFrames; i++ {
-		t := time.Duration(i) * frameInterval
-		if t > evc.Duration() {
-			break
-		}
-
-		frame, err := evc.GetFrame(t)
-		if err != nil {
-			return fmt.Errorf("获取第 %d 帧失败: %w", i, err)
-		}
-
-		// 检查帧尺寸
-		bounds := frame.Bounds()
-		if bounds.Dx() != evc.Width() || bounds.Dy() != evc.Height() {
-			fmt.Printf("警告: 第 %d 帧尺寸不匹配，期望 %dx%d，实际 %dx%d\n",
-				i, evc.Width(), evc.Height(), bounds.Dx(), bounds.Dy())
-		}
-
-		if err := writer.WriteFrame(frame); err != nil {
-			return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
-		}
 
-		// 显示进度
-		if i%10 == 0 || i < 10 { // 前10帧每帧显示，之后每10帧显示
-			progress := float64(i) / float64(totalFrames) * 100
-			fmt.Printf("进度: %.1f%% (%d/%d)\n", progress, i, totalFrames)
-		}
-	}
+	return Render(ctx, evc, filename, options, RenderSpec{
+		Label:      "特效视频",
+		Bitrate:    "2000k",
+		ProcessMgr: evc.processMgr,
+		Check: func(clip core.VideoClip, options *core.WriteOptions) error {
+			// 切换回原始分辨率后按实际渲染的特效链尺寸检查
+			rendered, ok := clip.(*EffectVideoClip)
+			if !ok {
+				rendered = evc
+			}
+			if rendered.options.EvenDimensions == EvenError && (rendered.rawWidth%2 != 0 || rendered.rawHeight%2 != 0) && options.Codec != "gif" {
+				return fmt.Errorf("%w: 特效链输出尺寸 %dx%d 不是偶数，可改用 EvenPad 或 EvenCrop",
+					core.ErrInvalidWriteOptions, rendered.rawWidth, rendered.rawHeight)
+			}
+			return nil
+		},
+		Describe: func() {
+			fmt.Printf("特效数量: %d\n", len(evc.effects))
+			for i, effect := range evc.effects {
+				fmt.Printf("  特效 %d: %s\n", i+1, effect.GetName())
+			}
+		},
+	})
+}
 
-	fmt.Printf("特效视频写入完成: %s\n", filename)
-	return nil
+// IsClosed 检查是否已关闭
+func (evc *EffectVideoClip) IsClosed() bool {
+	evc.mutex.RLock()
+	defer evc.mutex.RUnlock()
+	return evc.closed
 }
 
 // Close 关闭剪辑
+//
+// NewEffectVideoClip 传入的原始剪辑归调用者所有，不在这里关闭；Subclip、WithSpeed 等
+// 派生的剪辑自己创建了原始剪辑的派生版本，Close 时一并关闭。
 func (evc *EffectVideoClip) Close() error {
+	evc.mutex.Lock()
+	defer evc.mutex.Unlock()
 	if evc.closed {
 		return nil
 	}
 	evc.closed = true
+	evc.leak.Close()
 
-	// 不关闭原始剪辑，让调用者管理剪辑的生命周期
-	// 这样可以避免多个特效操作之间的剪辑关闭冲突
-	// if evc.originalClip != nil {
-	// 	if closer, ok := evc.originalClip.(interface{ Close() error }); ok {
-	// 		closer.Close()
-	// 	}
-	// 	evc.originalClip = nil
-	// }
-
+	if evc.ownsOriginal {
+		return evc.originalClip.Close()
+	}
 	return nil
 }
 
-// GetEffects 获取所有特效
+// GetEffects 获取所有特效（含已禁用的），返回切片的副本
 func (evc *EffectVideoClip) GetEffects() []effects.VideoEffect {
-	return evc.effects
+	evc.mutex.RLock()
+	defer evc.mutex.RUnlock()
+	return append([]effects.VideoEffect(nil), evc.effects...)
 }
 
 // ClearEffects 清除所有特效
 func (evc *EffectVideoClip) ClearEffects() {
+	evc.mutex.Lock()
+	defer evc.mutex.Unlock()
 	evc.effects = make([]effects.VideoEffect, 0)
+	evc.disabled = nil
+	evc.updateFinalDimensions()
+}
+
+// SaveFrame 将 t 处的帧保存为图片，格式按扩展名（.png/.jpg/.webp）或 options.Format 决定
+func (evc *EffectVideoClip) SaveFrame(t time.Duration, path string, options *ffmpeg.ImageOptions) error {
+	frame, err := evc.GetFrame(t)
+	if err != nil {
+		return fmt.Errorf("获取帧失败: %w", err)
+	}
+	if err := ffmpeg.SaveImage(context.Background(), frame, path, options, evc.processMgr); err != nil {
+		return fmt.Errorf("保存帧失败: %w", err)
+	}
+	return nil
+}
+
+// Preview 使用 ffplay 预览应用特效后的剪辑
+func (evc *EffectVideoClip) Preview(options *preview.PlayOptions) error {
+	if evc.IsClosed() {
+		return fmt.Errorf("剪辑已关闭")
+	}
+	return preview.Play(evc, options)
 }
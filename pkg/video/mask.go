@@ -0,0 +1,318 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// MaskClip 灰度遮罩剪辑，白色为不透明、黑色为完全透明
+//
+// 通过 WithMask 或 effects.NewMaskEffect 作用到视频剪辑上，再用 CompositeVideoClip 合成。
+type MaskClip struct {
+	*core.BaseVideoClip
+	frame  func(t time.Duration) (*image.Gray, error)
+	offset time.Duration // Subclip 后相对生成函数的时间偏移
+
+	mutex  sync.Mutex
+	static *image.Gray // 与时间无关的遮罩只生成一次
+	cache  bool
+}
+
+// NewMaskClip 由生成函数创建遮罩剪辑，static 为 true 表示各时刻的遮罩相同
+func NewMaskClip(width, height int, duration time.Duration, fps float64, static bool, frame func(t time.Duration) (*image.Gray, error)) *MaskClip {
+	return &MaskClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, duration, duration, fps, width, height),
+		frame:         frame,
+		cache:         static,
+	}
+}
+
+// Mask 返回时间 t 处的遮罩
+func (mc *MaskClip) Mask(t time.Duration) (*image.Gray, error) {
+	if !mc.cache {
+		return mc.frame(t + mc.offset)
+	}
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+	if mc.static == nil {
+		mask, err := mc.frame(0)
+		if err != nil {
+			return nil, err
+		}
+		mc.static = mask
+	}
+	return mc.static, nil
+}
+
+// GetFrame 返回时间 t 处的遮罩
+func (mc *MaskClip) GetFrame(t time.Duration) (image.Image, error) {
+	return mc.Mask(t)
+}
+
+// Subclip 截取遮罩的时间段
+func (mc *MaskClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > mc.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	sub := NewMaskClip(mc.Width(), mc.Height(), end-start, mc.FPS(), mc.cache, mc.frame)
+	sub.offset = mc.offset + start
+	return sub, nil
+}
+
+// Close 遮罩不持有资源
+func (mc *MaskClip) Close() error {
+	return nil
+}
+
+// NewLuminanceMask 以视频剪辑逐帧的亮度作为遮罩
+func NewLuminanceMask(source core.VideoClip) *MaskClip {
+	return NewMaskClip(source.Width(), source.Height(), source.Duration(), source.FPS(), false, func(t time.Duration) (*image.Gray, error) {
+		frame, err := source.GetFrame(t)
+		if err != nil {
+			return nil, fmt.Errorf("获取亮度源帧失败: %w", err)
+		}
+		return luminance(frame), nil
+	})
+}
+
+// NewImageLuminanceMask 以静态图像的亮度作为遮罩
+func NewImageLuminanceMask(img image.Image, duration time.Duration, fps float64) *MaskClip {
+	bounds := img.Bounds()
+	mask := luminance(img)
+	return NewMaskClip(bounds.Dx(), bounds.Dy(), duration, fps, true, func(time.Duration) (*image.Gray, error) {
+		return mask, nil
+	})
+}
+
+// luminance 转换为以 (0,0) 为原点的灰度图
+func luminance(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	if gray, ok := img.(*image.Gray); ok && bounds.Min == (image.Point{}) {
+		return gray
+	}
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(gray, gray.Bounds(), img, bounds.Min, draw.Src)
+	return gray
+}
+
+// LinearGradient 线性渐变，坐标为相对尺寸的 0–1
+//
+// 起点 (X0,Y0) 处为黑色，终点 (X1,Y1) 处为白色，两端之外保持端点颜色。
+// Feather 为过渡带宽度（相对起止点距离），0 表示整段线性过渡，较小的值形成锐利的分割线。
+type LinearGradient struct {
+	X0, Y0, X1, Y1 float64
+	Feather        float64
+	Invert         bool
+	// ProgressAt 非 nil 时沿渐变方向平移过渡带，0 为全黑、1 为全白，用于擦除转场
+	ProgressAt func(t time.Duration) float64
+}
+
+// NewLinearGradientMask 创建线性渐变遮罩
+func NewLinearGradientMask(width, height int, gradient LinearGradient, duration time.Duration, fps float64) *MaskClip {
+	return NewMaskClip(width, height, duration, fps, gradient.ProgressAt == nil, func(t time.Duration) (*image.Gray, error) {
+		dx := (gradient.X1 - gradient.X0) * float64(width)
+		dy := (gradient.Y1 - gradient.Y0) * float64(height)
+		length2 := dx*dx + dy*dy
+		if length2 == 0 {
+			return nil, fmt.Errorf("渐变起点与终点相同")
+		}
+
+		feather := gradient.Feather
+		if feather <= 0 || feather > 1 {
+			feather = 1
+		}
+		// 过渡带中心：静态时位于中点，动画时从 -feather/2 移动到 1+feather/2
+		center := 0.5
+		if gradient.ProgressAt != nil {
+			progress := clamp01(gradient.ProgressAt(t))
+			center = 1 + feather/2 - progress*(1+feather)
+		}
+
+		mask := image.NewGray(image.Rect(0, 0, width, height))
+		x0, y0 := gradient.X0*float64(width), gradient.Y0*float64(height)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				// 像素中心在渐变轴上的投影位置，0 为起点、1 为终点
+				s := ((float64(x)+0.5-x0)*dx + (float64(y)+0.5-y0)*dy) / length2
+				v := clamp01((s-center)/feather + 0.5)
+				mask.Pix[y*mask.Stride+x] = grayLevel(v, gradient.Invert)
+			}
+		}
+		return mask, nil
+	})
+}
+
+// RadialGradient 径向渐变，中心为相对尺寸的 0–1，半径相对宽高中较短的一边
+//
+// Inner 以内为白色，Outer 以外为黑色，之间线性过渡。
+type RadialGradient struct {
+	CX, CY       float64
+	Inner, Outer float64
+	Invert       bool
+	// RadiusAt 非 nil 时按返回值缩放 Inner/Outer，用于圆形展开转场
+	RadiusAt func(t time.Duration) float64
+}
+
+// NewRadialGradientMask 创建径向渐变遮罩
+func NewRadialGradientMask(width, height int, gradient RadialGradient, duration time.Duration, fps float64) *MaskClip {
+	return NewMaskClip(width, height, duration, fps, gradient.RadiusAt == nil, func(t time.Duration) (*image.Gray, error) {
+		scale := 1.0
+		if gradient.RadiusAt != nil {
+			scale = math.Max(gradient.RadiusAt(t), 0)
+		}
+		unit := float64(min(width, height))
+		inner := gradient.Inner * scale * unit
+		outer := gradient.Outer * scale * unit
+		if outer < inner {
+			inner, outer = outer, inner
+		}
+		cx, cy := gradient.CX*float64(width), gradient.CY*float64(height)
+
+		mask := image.NewGray(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+				var v float64
+				switch {
+				case d <= inner:
+					v = 1
+				case d >= outer:
+					v = 0
+				default:
+					v = (outer - d) / (outer - inner)
+				}
+				mask.Pix[y*mask.Stride+x] = grayLevel(v, gradient.Invert)
+			}
+		}
+		return mask, nil
+	})
+}
+
+// TextMaskOptions 文字遮罩选项
+type TextMaskOptions struct {
+	FontFile string // 字体文件，默认由 fontconfig 选择
+	FontSize int    // 字号，默认高度的 1/4
+	X, Y     string // drawtext 位置表达式，默认居中
+	Invert   bool   // 文字透明、其余不透明
+}
+
+// NewTextMask 通过 FFmpeg drawtext 渲染文字形状的遮罩，文字为白色
+func NewTextMask(text string, width, height int, options *TextMaskOptions, duration time.Duration, fps float64, processMgr *ffmpeg.ProcessManager) (*MaskClip, error) {
+	if options == nil {
+		options = &TextMaskOptions{}
+	}
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	textFile, err := processMgr.Temp().CreateTextFile("drawtext-*.txt", text)
+	if err != nil {
+		return nil, err
+	}
+	defer processMgr.Temp().Remove(textFile)
+
+	output, err := processMgr.Output(context.Background(), "ffmpeg", textMaskArgs(textFile, width, height, options))
+	if err != nil {
+		return nil, fmt.Errorf("渲染文字遮罩失败: %w", err)
+	}
+	img, err := png.Decode(bytes.NewReader(output))
+	if err != nil {
+		return nil, fmt.Errorf("解码文字遮罩失败: %w", err)
+	}
+
+	mask := luminance(img)
+	if options.Invert {
+		for i, v := range mask.Pix {
+			mask.Pix[i] = 255 - v
+		}
+	}
+	return NewMaskClip(width, height, duration, fps, true, func(time.Duration) (*image.Gray, error) {
+		return mask, nil
+	}), nil
+}
+
+// textMaskArgs 在黑色画布上绘制 textFile 中的白色文字并输出一帧 PNG
+func textMaskArgs(textFile string, width, height int, options *TextMaskOptions) []string {
+	return []string{
+		"-hide_banner", "-loglevel", "error",
+		"-f", "lavfi",
+		"-i", fmt.Sprintf("color=c=black:s=%dx%d", width, height),
+		"-vf", drawtextFilter(textFile, options.FontFile, options.FontSize, height, options.X, options.Y, "fontcolor=white"),
+		"-frames:v", "1",
+		"-pix_fmt", "gray",
+		"-f", "image2pipe",
+		"-vcodec", "png",
+		"-",
+	}
+}
+
+// grayLevel 将 0–1 的值转换为灰度，invert 时反相
+func grayLevel(v float64, invert bool) uint8 {
+	if invert {
+		v = 1 - v
+	}
+	return uint8(math.Round(v * 255))
+}
+
+// clamp01 限制到 [0, 1]
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// WithMask 返回以 mask 亮度为 alpha 的特效剪辑，原剪辑的生命周期仍由调用者管理
+func (vfc *VideoFileClip) WithMask(mask core.VideoClip) (core.VideoClip, error) {
+	if mask == nil {
+		return nil, fmt.Errorf("遮罩不能为空")
+	}
+	masked := NewEffectVideoClip(vfc, vfc.processMgr)
+	masked.AddEffect(effects.NewMaskEffect(mask))
+	return masked, nil
+}
+
+// WithoutMask 文件剪辑本身没有遮罩，返回自身
+func (vfc *VideoFileClip) WithoutMask() (core.VideoClip, error) {
+	return vfc, nil
+}
+
+// WithMask 在现有特效之后追加遮罩，替换已有的遮罩
+func (evc *EffectVideoClip) WithMask(mask core.VideoClip) (core.VideoClip, error) {
+	if mask == nil {
+		return nil, fmt.Errorf("遮罩不能为空")
+	}
+	masked := evc.withoutMaskEffects()
+	masked.AddEffect(effects.NewMaskEffect(mask))
+	return masked, nil
+}
+
+// WithoutMask 返回去掉遮罩特效的剪辑
+func (evc *EffectVideoClip) WithoutMask() (core.VideoClip, error) {
+	return evc.withoutMaskEffects(), nil
+}
+
+// withoutMaskEffects 复制除遮罩外的所有特效
+func (evc *EffectVideoClip) withoutMaskEffects() *EffectVideoClip {
+	clip := NewEffectVideoClipWithOptions(evc.originalClip, &evc.options, evc.processMgr)
+	evc.mutex.RLock()
+	defer evc.mutex.RUnlock()
+	for i, effect := range evc.effects {
+		if _, isMask := effect.(*effects.MaskEffect); !isMask {
+			clip.AddEffect(effect)
+			if evc.disabled[i] {
+				clip.SetEffectEnabled(len(clip.effects)-1, false)
+			}
+		}
+	}
+	return clip
+}
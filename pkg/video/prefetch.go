@@ -0,0 +1,116 @@
+package video
+
+import (
+	"image"
+	"math"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// prefetchResult 一次预解码的结果
+type prefetchResult struct {
+	done  chan struct{}
+	frame image.Image
+	err   error
+}
+
+// prefetcher 顺序读取时在后台预解码后续帧
+//
+// 预取按帧序号进行：get(t) 的 t 落在第 i 帧的时间戳 core.FrameTime(i, fps) 上时命中对应序号的预取，
+// 并调度第 i+stride ... i+depth*stride 帧的解码，与调用方处理当前帧（特效、编码）的时间重叠。
+// stride 取最近两次请求的序号差，使隔帧导出（FrameStep）时只预取会被请求的帧；
+// 不在帧时间戳上的请求直接解码，不触发预取。
+type prefetcher struct {
+	depth int
+	fps   float64
+	limit time.Duration // 剪辑时长，超出的帧不预取
+	fetch func(t time.Duration) (image.Image, error)
+
+	mutex   sync.Mutex
+	pending map[int]*prefetchResult
+	last    int // 上一次请求的帧序号，-1 表示尚未请求
+}
+
+// newPrefetcher 创建预取器
+func newPrefetcher(depth int, fps float64, limit time.Duration, fetch func(t time.Duration) (image.Image, error)) *prefetcher {
+	return &prefetcher{
+		depth:   depth,
+		fps:     fps,
+		limit:   limit,
+		fetch:   fetch,
+		pending: make(map[int]*prefetchResult),
+		last:    -1,
+	}
+}
+
+// frameIndex 返回 t 对应的帧序号，t 不在帧时间戳上时 ok 为 false
+func (p *prefetcher) frameIndex(t time.Duration) (index int, ok bool) {
+	index = int(math.Round(t.Seconds() * p.fps))
+	return index, index >= 0 && core.FrameTime(index, p.fps) == t
+}
+
+// get 返回 t 处的帧，命中预取时等待其完成，否则直接解码
+func (p *prefetcher) get(t time.Duration) (image.Image, error) {
+	index, aligned := p.frameIndex(t)
+	if !aligned {
+		return p.fetch(t)
+	}
+
+	p.mutex.Lock()
+	result, ok := p.pending[index]
+	if ok {
+		delete(p.pending, index)
+	}
+	p.schedule(index)
+	p.mutex.Unlock()
+
+	if !ok {
+		return p.fetch(t)
+	}
+	<-result.done
+	return result.frame, result.err
+}
+
+// schedule 丢弃窗口外的预取结果并调度窗口内缺失的帧，调用方需持有锁
+func (p *prefetcher) schedule(index int) {
+	stride := 1
+	if diff := index - p.last; p.last >= 0 && diff > 1 && diff <= p.depth {
+		stride = diff
+	}
+	p.last = index
+
+	end := index + p.depth*stride
+	for pending := range p.pending {
+		// 跳转后窗口外的结果不再需要，正在进行的解码会自行结束
+		if pending <= index || pending > end {
+			delete(p.pending, pending)
+		}
+	}
+
+	for k := 1; k <= p.depth; k++ {
+		next := index + k*stride
+		t := core.FrameTime(next, p.fps)
+		if t >= p.limit {
+			break
+		}
+		if _, ok := p.pending[next]; ok {
+			continue
+		}
+		result := &prefetchResult{done: make(chan struct{})}
+		p.pending[next] = result
+		go func() {
+			result.frame, result.err = p.fetch(t)
+			close(result.done)
+		}()
+	}
+}
+
+// reset 丢弃所有预取结果，解码参数（如输出尺寸）变化后调用
+func (p *prefetcher) reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.pending = make(map[int]*prefetchResult)
+	p.last = -1
+}
@@ -0,0 +1,69 @@
+package video
+
+import (
+	"image"
+	"sync"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+func TestPrefetcherHitsFrameTimestamps(t *testing.T) {
+	for _, fps := range []float64{24, 25, 30000.0 / 1001, 30, 60} {
+		for _, step := range []int{1, 2} {
+			var mutex sync.Mutex
+			fetched := make(map[time.Duration]int)
+			frames := 300
+			p := newPrefetcher(4, fps, core.FrameTime(frames, fps), func(ts time.Duration) (image.Image, error) {
+				mutex.Lock()
+				fetched[ts]++
+				mutex.Unlock()
+				return image.NewRGBA(image.Rect(0, 0, 1, 1)), nil
+			})
+
+			requested := make(map[time.Duration]bool)
+			for i := 0; i < frames; i += step {
+				ts := core.FrameTime(i, fps)
+				requested[ts] = true
+				if _, err := p.get(ts); err != nil {
+					t.Fatalf("fps %g: 取帧失败: %v", fps, err)
+				}
+			}
+
+			mutex.Lock()
+			for ts, n := range fetched {
+				if n > 1 {
+					t.Errorf("fps %g step %d: %v 解码了 %d 次", fps, step, ts, n)
+				}
+				if _, ok := p.frameIndex(ts); !ok {
+					t.Errorf("fps %g step %d: 预取了不在帧时间戳上的 %v", fps, step, ts)
+				}
+			}
+			for ts := range requested {
+				if fetched[ts] != 1 {
+					t.Errorf("fps %g step %d: 请求的 %v 解码了 %d 次", fps, step, ts, fetched[ts])
+				}
+			}
+			// 只允许窗口末尾和第一次跳帧前按步长 1 预取的帧
+			if extra := len(fetched) - len(requested); extra > 2*4 {
+				t.Errorf("fps %g step %d: 多解码了 %d 帧", fps, step, extra)
+			}
+			mutex.Unlock()
+		}
+	}
+}
+
+func TestPrefetcherOffGridBypass(t *testing.T) {
+	calls := 0
+	p := newPrefetcher(4, 30, time.Second, func(time.Duration) (image.Image, error) {
+		calls++
+		return nil, nil
+	})
+	if _, err := p.get(core.FrameTime(3, 30) + time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || len(p.pending) != 0 {
+		t.Errorf("不在帧时间戳上的请求触发了预取: 解码 %d 次，待取 %d 帧", calls, len(p.pending))
+	}
+}
@@ -0,0 +1,25 @@
+package video
+
+import (
+	"moviepy-go/pkg/core"
+)
+
+// ProxyResolver 包含代理剪辑、可在渲染时切换回原始分辨率的剪辑
+//
+// 组合其他剪辑的类型（特效剪辑、合成剪辑、集锦等）对其子剪辑调用 FullResolution，
+// 使 Render 对整个剪辑图生效。
+type ProxyResolver interface {
+	// WithoutProxy 返回按原始分辨率渲染的派生剪辑，归调用者所有；剪辑图中没有代理时返回 nil
+	WithoutProxy() (core.VideoClip, error)
+}
+
+// FullResolution 返回 clip 按原始分辨率渲染的派生剪辑，clip 不包含代理时返回 nil
+//
+// 只创建新的剪辑，不修改与其他剪辑共享的读取器，代理模式的预览和其他子剪辑不受影响。
+func FullResolution(clip core.VideoClip) (core.VideoClip, error) {
+	resolver, ok := clip.(ProxyResolver)
+	if !ok {
+		return nil, nil
+	}
+	return resolver.WithoutProxy()
+}
@@ -0,0 +1,82 @@
+package video
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"moviepy-go/pkg/core"
+)
+
+// openProxyClip 打开 newFakeFFmpeg 模拟的 8×4 视频并以 4×2 的代理尺寸解码
+func openProxyClip(t *testing.T) (*VideoFileClip, string) {
+	t.Helper()
+	pm, log := newFakeFFmpeg(t)
+	input := filepath.Join(t.TempDir(), "in.mp4")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	clip := NewVideoFileClip(input, pm)
+	if err := clip.Open(); err != nil {
+		t.Fatalf("打开视频失败: %v", err)
+	}
+	t.Cleanup(func() { clip.Close() })
+	if err := clip.EnableProxy(4); err != nil {
+		t.Fatalf("启用代理失败: %v", err)
+	}
+	return clip, log
+}
+
+func TestRenderResolvesProxyThroughEffectClip(t *testing.T) {
+	source, log := openProxyClip(t)
+	sub, err := source.Subclip(0, source.Duration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	clip := NewEffectVideoClip(source, source.processMgr)
+	defer clip.Close()
+
+	for _, tc := range []struct {
+		name          string
+		proxy         bool
+		width, height int
+	}{
+		{"原始分辨率", false, 8, 4},
+		{"代理", true, 4, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var expect core.OutputExpectation
+			options := &core.WriteOptions{
+				Proxy:  tc.proxy,
+				Verify: func(filename string, e core.OutputExpectation) error { expect = e; return nil },
+			}
+			if err := clip.WriteToFile(filepath.Join(t.TempDir(), "out.mp4"), options); err != nil {
+				t.Fatalf("渲染失败: %v", err)
+			}
+			if expect.Width != tc.width || expect.Height != tc.height {
+				t.Fatalf("输出 %dx%d，期望 %dx%d", expect.Width, expect.Height, tc.width, tc.height)
+			}
+			data, err := os.ReadFile(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scaled := strings.Contains(string(data), "scale="); scaled != tc.proxy {
+				t.Fatalf("解码缩放 %v，期望 %v，ffmpeg 调用:\n%s", scaled, tc.proxy, data)
+			}
+			os.Remove(log)
+		})
+	}
+
+	// 渲染不修改共享读取器，源剪辑和共享读取器的子剪辑仍处于代理模式
+	if !source.IsProxy() || source.Width() != 4 {
+		t.Fatalf("渲染后源剪辑为 %dx%d，代理模式 %v，期望保持 4×2 代理", source.Width(), source.Height(), source.IsProxy())
+	}
+	if width := sub.(*VideoFileClip).Width(); width != 4 {
+		t.Fatalf("渲染后子剪辑宽度 %d，期望 4", width)
+	}
+	if clip.Width() != 4 {
+		t.Fatalf("渲染后特效剪辑宽度 %d，期望 4", clip.Width())
+	}
+}
@@ -0,0 +1,415 @@
+package video
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"time"
+
+	"moviepy-go/pkg/analysis"
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// RenderSpec 剪辑类型为 Render 提供的默认值和附加步骤，帧来源为传给 Render 的剪辑本身
+type RenderSpec struct {
+	// Label 日志中的剪辑类型，如 "特效视频"，为空时为 "视频"
+	Label string
+	// Bitrate 未指定码率时的默认值
+	Bitrate string
+	// FrameRate 未指定 FPS 和 FrameRate 时的默认精确帧率，零值时取剪辑的 FPS
+	FrameRate ffmpeg.Rational
+	// ProcessMgr 写入器、封面嵌入等使用的进程管理器
+	ProcessMgr *ffmpeg.ProcessManager
+
+	// Check 在选项解析完成、创建写入器之前校验剪辑特有的约束，可为 nil；
+	// clip 为实际渲染的剪辑，切换回原始分辨率后是 WithoutProxy 的结果而不是剪辑本身
+	Check func(clip core.VideoClip, options *core.WriteOptions) error
+	// Describe 开始写入时打印剪辑特有的信息（特效列表、合成模式等），可为 nil
+	Describe func()
+	// DryRun 试运行时在报告编码命令之后调用，可通过 options.OnCommand 报告解码等命令，可为 nil；clip 同 Check
+	DryRun func(clip core.VideoClip, options *core.WriteOptions)
+}
+
+// audioTempPattern 渲染时音轨临时文件的命名规则，音轨先无损写入再在混流时编码
+const audioTempPattern = "audio-*.wav"
+
+// RenderPlan 一次渲染解析完成的剪辑、选项和渲染窗口，Render 与多路输出渲染共用
+//
+// Clip 为实际渲染的剪辑：未设置 Options.Proxy 时是按原始分辨率渲染的派生剪辑，由 Close 释放。
+type RenderPlan struct {
+	Clip       core.VideoClip
+	Options    *core.WriteOptions
+	Start, End time.Duration   // 渲染窗口
+	Step       int             // 隔帧导出的步长，至少为 1
+	OutputRate ffmpeg.Rational // 隔帧导出后的编码帧率
+
+	full core.VideoClip
+}
+
+// PlanRender 应用默认选项、切换代理并解析帧率和渲染窗口；只使用 spec 的 Bitrate 和 FrameRate
+//
+// 音频编码参数按输出封装选择，由 OutputAudio 为每个输出单独补全。
+func PlanRender(clip core.VideoClip, options *core.WriteOptions, spec RenderSpec) (*RenderPlan, error) {
+	// 设置默认选项，未设置的字段先取 core.SetWriteDefaults 配置的值
+	options = core.ApplyWriteDefaults(options)
+	plan := &RenderPlan{Clip: clip, Options: options}
+
+	// 代理模式下默认切换回原始分辨率渲染，只作用于本次渲染使用的派生剪辑
+	if !options.Proxy {
+		full, err := FullResolution(clip)
+		if err != nil {
+			return nil, fmt.Errorf("切换原始分辨率失败: %w", err)
+		}
+		if full != nil {
+			plan.Clip, plan.full = full, full
+		}
+	}
+
+	if options.Codec == "" {
+		options.Codec = "libx264"
+	}
+	if options.Bitrate == "" {
+		options.Bitrate = spec.Bitrate
+	}
+	if options.FPS == 0 && options.FrameRate == "" {
+		if !spec.FrameRate.IsZero() {
+			options.FrameRate = spec.FrameRate.String()
+		} else {
+			options.FPS = plan.Clip.FPS()
+		}
+	}
+
+	// 使用分数帧率，避免长时间 NTSC 导出时的时间漂移
+	frameRate, err := ffmpeg.ResolveFrameRate(options.FrameRate, options.FPS)
+	if err != nil {
+		plan.Close()
+		return nil, err
+	}
+	options.FPS = frameRate.Float64()
+
+	// 只渲染指定窗口，隔帧导出时按降低后的帧率编码以保持时长
+	plan.Start, plan.End, err = core.RenderWindow(options, plan.Clip.Duration())
+	if err != nil {
+		plan.Close()
+		return nil, err
+	}
+	plan.Step = max(options.FrameStep, 1)
+	plan.OutputRate = frameRate.Div(plan.Step)
+	return plan, nil
+}
+
+// Close 释放切换代理时创建的派生剪辑
+func (p *RenderPlan) Close() error {
+	if p.full == nil {
+		return nil
+	}
+	full := p.full
+	p.full = nil
+	return full.Close()
+}
+
+// TotalFrames 渲染窗口内按 Step 导出的帧数
+func (p *RenderPlan) TotalFrames() int {
+	return (core.FrameCount(p.End-p.Start, p.Options.FPS) + p.Step - 1) / p.Step
+}
+
+// Frame 取第 i 个导出帧并应用 FrameHook，返回帧和它在剪辑中的时间
+func (p *RenderPlan) Frame(ctx context.Context, i int) (image.Image, time.Duration, error) {
+	t := p.Start + core.FrameTime(i*p.Step, p.Options.FPS)
+	frame, err := core.TraceFrame(ctx, p.Clip, i, t)
+	if err != nil {
+		return nil, t, fmt.Errorf("获取第 %d 帧失败: %w", i, err)
+	}
+	if p.Options.FrameHook != nil {
+		frame, err = p.Options.FrameHook(i, t, frame)
+		if err != nil {
+			return nil, t, fmt.Errorf("第 %d 帧处理钩子失败: %w", i, err)
+		}
+	}
+	return frame, t, nil
+}
+
+// WriterOptions 按 options 创建视频写入器选项；原子写入由调用者在 Finish 之后统一提交
+func (p *RenderPlan) WriterOptions(options *core.WriteOptions) *ffmpeg.VideoWriterOptions {
+	return &ffmpeg.VideoWriterOptions{
+		Codec:       options.Codec,
+		Bitrate:     options.Bitrate,
+		CRF:         options.CRF,
+		Threads:     options.Threads,
+		FPS:         p.OutputRate.Float64(),
+		FrameRate:   p.OutputRate,
+		DirectWrite: true,
+		LogLevel:    ffmpeg.LogLevel(options.LogLevel),
+		Logger:      options.Logger,
+		Filter:      options.Filter,
+		Dither:      ffmpeg.Dither(options.Dither),
+	}
+}
+
+// OutputAudio 按 filename 的封装为 options 补全音频编码默认值，返回要混入的音轨
+//
+// 音频编码器按封装格式选择默认值，如 webm 为 libopus；封装不能包含音频（gif 等）、
+// 剪辑没有音轨或音轨在渲染窗口开始前已结束时返回 nil。
+func (p *RenderPlan) OutputAudio(filename string, options *core.WriteOptions) core.AudioClip {
+	codec, ok := core.DefaultAudioCodec(filename)
+	if !ok {
+		return nil
+	}
+	options.AudioCodec = cmp.Or(options.AudioCodec, codec)
+	options.AudioBitrate = cmp.Or(options.AudioBitrate, "128k")
+	if src, ok := p.Clip.(core.AudioSource); ok && src.Audio() != nil && src.Audio().Duration() > p.Start {
+		return src.Audio()
+	}
+	return nil
+}
+
+// ReportAudio 通过 options.OnCommand 报告写入和混入音轨的命令，audio 为 nil 时无操作
+func (p *RenderPlan) ReportAudio(filename string, audio core.AudioClip, options *core.WriteOptions, processMgr *ffmpeg.ProcessManager) {
+	if audio == nil || options.OnCommand == nil {
+		return
+	}
+	command := newAudioTrackWriter(audioTempPattern, audio, options, processMgr).Command()
+	options.OnCommand(command.Name, command.Args)
+	command = ffmpeg.MuxAudioCommand(filename, audioTempPattern, muxAudioOptions(options))
+	options.OnCommand(command.Name, command.Args)
+}
+
+// Finish 对画面已编码完成的 output 混入音轨、嵌入封面、检查音画同步并校验输出，不提交文件
+//
+// width/height 为输出尺寸，为 0 时校验跳过该项（如由 FFmpeg 按宽高比缩放的输出）。
+func (p *RenderPlan) Finish(ctx context.Context, output string, audio core.AudioClip, options *core.WriteOptions, width, height int, processMgr *ffmpeg.ProcessManager) error {
+	if audio != nil {
+		if err := muxAudioTrack(ctx, output, audio, p.Start, p.End, options, processMgr); err != nil {
+			return err
+		}
+	}
+
+	if options.Cover != nil {
+		if err := ffmpeg.EmbedCoverImage(ctx, output, options.Cover, processMgr); err != nil {
+			return err
+		}
+	}
+
+	if options.AVSyncCheck {
+		analysis.WarnAVSync(output, options.AVSyncThreshold, options.Logger)
+	}
+
+	if options.Verify != nil {
+		expect := core.OutputExpectation{
+			Duration:     p.End - p.Start,
+			Width:        width,
+			Height:       height,
+			VideoCodec:   options.Codec,
+			VideoStreams: 1,
+		}
+		if audio != nil {
+			expect.AudioCodec = options.AudioCodec
+			expect.AudioStreams = 1
+		}
+		if err := options.Verify(output, expect); err != nil {
+			return fmt.Errorf("校验输出失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Render 逐帧渲染 clip 并编码为 filename，VideoFileClip、EffectVideoClip、CompositeVideoClip 等共用
+//
+// 负责代理切换、默认选项、分数帧率、渲染窗口与隔帧导出、试运行、进度、音轨混流、封面、音画同步检查和输出校验；
+// 未设置 options.Proxy 时整个剪辑图按原始分辨率渲染（见 FullResolution），
+// clip 的 Audio() 非 nil 时，渲染窗口内的音轨按 AudioCodec/AudioBitrate 编码后混入输出。
+// ctx 携带 core.TraceRender 创建的渲染区间。
+func Render(ctx context.Context, clip core.VideoClip, filename string, options *core.WriteOptions, spec RenderSpec) error {
+	label := spec.Label
+	if label == "" {
+		label = "视频"
+	}
+	processMgr := spec.ProcessMgr
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	plan, err := PlanRender(clip, options, spec)
+	if err != nil {
+		return err
+	}
+	defer plan.Close()
+	clip, options = plan.Clip, plan.Options
+	audio := plan.OutputAudio(filename, options)
+
+	width, height := clip.Width(), clip.Height()
+	if err := options.Validate(filename, width, height); err != nil {
+		return err
+	}
+	if spec.Check != nil {
+		if err := spec.Check(clip, options); err != nil {
+			return err
+		}
+	}
+	writerOptions := plan.WriterOptions(options)
+
+	// 报告将要执行的命令，试运行时到此为止
+	if options.OnCommand != nil {
+		command := ffmpeg.NewVideoWriter(filename, width, height, writerOptions, processMgr).Command()
+		options.OnCommand(command.Name, command.Args)
+		plan.ReportAudio(filename, audio, options, processMgr)
+	}
+	if options.DryRun {
+		if spec.DryRun != nil {
+			spec.DryRun(clip, options)
+		}
+		return nil
+	}
+
+	// 先确认音频编码器可用，避免画面编码完成后才失败
+	if audio != nil {
+		if err := processMgr.CheckEncoder(ctx, options.AudioCodec); err != nil {
+			return err
+		}
+	}
+
+	// 默认写入同目录的临时文件，混流、封面和校验都完成后才重命名为 filename，失败时保留原有文件
+	output := filename
+	if !options.DirectWrite {
+		if output, err = ffmpeg.TempOutputPath(filename); err != nil {
+			return err
+		}
+		// 提交后临时文件已不存在，删除为空操作
+		defer os.Remove(output)
+	}
+
+	// 打开写入器
+	writer := ffmpeg.NewVideoWriter(output, width, height, writerOptions, processMgr)
+	if err := writer.Open(); err != nil {
+		return fmt.Errorf("打开写入器失败: %w", err)
+	}
+	// 出错时中止写入，成功关闭后 Abort 为空操作
+	defer writer.Abort()
+
+	// 计算总帧数
+	totalFrames := plan.TotalFrames()
+	frameInterval := core.FrameTime(plan.Step, options.FPS)
+
+	fmt.Printf("开始写入%s: %s\n", label, filename)
+	if spec.Describe != nil {
+		spec.Describe()
+	}
+	fmt.Printf("总帧数: %d, 帧间隔: %v\n", totalFrames, frameInterval)
+
+	// 逐帧写入
+	for i := 0; i < totalFrames; i++ {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%w: %v", core.ErrContextCancelled, err)
+		}
+
+		frame, _, err := plan.Frame(ctx, i)
+		if err != nil {
+			return err
+		}
+
+		// 检查帧尺寸
+		if bounds := frame.Bounds(); bounds.Dx() != width || bounds.Dy() != height {
+			fmt.Printf("警告: 第 %d 帧尺寸不匹配，期望 %dx%d，实际 %dx%d\n",
+				i, width, height, bounds.Dx(), bounds.Dy())
+		}
+
+		if err := core.TraceEncode(ctx, i, func() error { return writer.WriteFrame(frame) }); err != nil {
+			return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
+		}
+
+		if options.Progress != nil {
+			options.Progress(i+1, totalFrames)
+		}
+
+		// 显示进度
+		if i%100 == 0 {
+			progress := float64(i) / float64(totalFrames) * 100
+			fmt.Printf("进度: %.1f%% (%d/%d)\n", progress, i, totalFrames)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("关闭写入器失败: %w", err)
+	}
+
+	if err := plan.Finish(ctx, output, audio, options, width, height, processMgr); err != nil {
+		return err
+	}
+
+	if output != filename {
+		if err := ffmpeg.CommitOutput(output, filename); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%s写入完成: %s\n", label, filename)
+	return nil
+}
+
+// newAudioTrackWriter 创建把 audio 无损写入 path 的写入器，编码留给混流步骤
+func newAudioTrackWriter(path string, audio core.AudioClip, options *core.WriteOptions, processMgr *ffmpeg.ProcessManager) *ffmpeg.AudioWriter {
+	return ffmpeg.NewAudioWriter(path, &ffmpeg.AudioWriterOptions{
+		Codec:       "pcm_f32le",
+		SampleRate:  audio.SampleRate(),
+		Channels:    audio.Channels(),
+		DirectWrite: true,
+		LogLevel:    ffmpeg.LogLevel(options.LogLevel),
+		Logger:      options.Logger,
+	}, processMgr)
+}
+
+// muxAudioOptions 按写入选项返回混流时音轨的编码参数
+func muxAudioOptions(options *core.WriteOptions) ffmpeg.MuxAudioOptions {
+	return ffmpeg.MuxAudioOptions{Codec: options.AudioCodec, Bitrate: options.AudioBitrate, Filter: options.AudioFilter}
+}
+
+// muxAudioTrack 把 audio 在渲染窗口 [start, end) 内的样本写入临时文件，再按 AudioCodec 和 AudioFilter 编码混入 filename
+//
+// 音轨比窗口短时只写到音轨结尾；样本按位置换算时间戳，不依赖各剪辑音频帧的长度。
+func muxAudioTrack(ctx context.Context, filename string, audio core.AudioClip, start, end time.Duration, options *core.WriteOptions, processMgr *ffmpeg.ProcessManager) error {
+	path, err := processMgr.Temp().CreateFile(audioTempPattern)
+	if err != nil {
+		return fmt.Errorf("创建音轨临时文件失败: %w", err)
+	}
+	defer processMgr.Temp().Remove(path)
+
+	writer := newAudioTrackWriter(path, audio, options, processMgr)
+	if err := writer.Open(); err != nil {
+		return fmt.Errorf("打开音频写入器失败: %w", err)
+	}
+	defer writer.Abort()
+
+	rate, channels := audio.SampleRate(), audio.Channels()
+	end = min(end, audio.Duration())
+	total := int((end-start).Seconds()*float64(rate) + 0.5)
+	for written := 0; written < total; {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%w: %v", core.ErrContextCancelled, err)
+		}
+		t := start + time.Duration(float64(written)*float64(time.Second)/float64(rate))
+		frame, err := audio.GetAudioFrame(t)
+		if err != nil {
+			return fmt.Errorf("获取 %v 处的音频帧失败: %w", t, err)
+		}
+		count := min(len(frame)/channels, total-written)
+		if count == 0 {
+			return fmt.Errorf("%w: %v 处的音频帧为空", core.ErrInvalidAudioFrame, t)
+		}
+		if err := writer.WriteAudioFrame(frame[:count*channels]); err != nil {
+			return fmt.Errorf("写入 %v 处的音频帧失败: %w", t, err)
+		}
+		written += count
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("关闭音频写入器失败: %w", err)
+	}
+
+	if err := ffmpeg.MuxAudio(ctx, filename, path, muxAudioOptions(options), processMgr); err != nil {
+		return fmt.Errorf("混入音轨失败: %w", err)
+	}
+	return nil
+}
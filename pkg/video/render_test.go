@@ -0,0 +1,267 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/core/coretest"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// fakeEncoders 模拟 ffmpeg -encoders 的输出
+const fakeEncoders = "Encoders:\n ------\n V..... libx264 H.264\n A..... aac AAC\n A..... pcm_f32le PCM\n V..... gif GIF\n"
+
+// fakeProbe 8×4、10 fps、1 秒、没有音轨的视频的 ffprobe 输出
+const fakeProbe = `{
+  "format": {"format_name": "mov,mp4", "duration": "1.000000"},
+  "streams": [{"index": 0, "codec_type": "video", "codec_name": "h264", "width": 8, "height": 4, "r_frame_rate": "10/1", "avg_frame_rate": "10/1", "pix_fmt": "yuv420p"}]
+}`
+
+// newFakeFFmpeg 用记录参数的脚本代替 ffmpeg 和 ffprobe，返回进程管理器和 ffmpeg 参数日志路径
+//
+// ffprobe 输出 fakeProbe。ffmpeg 查询编码器时输出 fakeEncoders；解码时按 scale 滤镜或 8×4 输出一帧黑色 rgb24；
+// 混流时把两个输入依次拼接到最后一个参数；否则把 stdin 写入最后一个参数。
+func newFakeFFmpeg(t *testing.T) (*ffmpeg.ProcessManager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "ffmpeg.log")
+	path := filepath.Join(dir, "ffmpeg")
+	probe := filepath.Join(dir, "ffprobe")
+	scripts := map[string]string{
+		path: "#!/bin/sh\necho \"$@\" >> '" + log + "'\n" +
+			"case \"$*\" in *-encoders*) printf '" + strings.ReplaceAll(fakeEncoders, "\n", "\\n") + "'; exit 0;; esac\n" +
+			"case \"$*\" in *image2pipe*) size=$(echo \"$*\" | sed -n 's/.*scale=\\([0-9]*\\):\\([0-9]*\\).*/\\1 \\2/p'); set -- ${size:-8 4}; head -c $(($1*$2*3)) /dev/zero; exit 0;; esac\n" +
+			"inputs=; prev=\nfor a; do [ \"$prev\" = -i ] && [ \"$a\" != - ] && inputs=\"$inputs $a\"; prev=$a; last=$a; done\n" +
+			"case \"$*\" in *'-map 1:a'*) cat $inputs > \"$last\"; exit 0;; esac\n" +
+			"cat > \"$last\"\n",
+		probe: "#!/bin/sh\ncat <<'JSON'\n" + fakeProbe + "\nJSON\n",
+	}
+	for name, content := range scripts {
+		if err := os.WriteFile(name, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pm := ffmpeg.NewProcessManagerWithOptions(&ffmpeg.ProcessManagerOptions{FFmpegPath: path, FFprobePath: probe})
+	t.Cleanup(func() { pm.Close() })
+	return pm, log
+}
+
+func TestRenderWindowAndFrameStep(t *testing.T) {
+	pm, _ := newFakeFFmpeg(t)
+	clip := coretest.NewCounterClip(4, 2, time.Second, 10)
+	output := filepath.Join(t.TempDir(), "out.mp4")
+
+	var progress []int
+	var expect core.OutputExpectation
+	options := &core.WriteOptions{
+		StartTime: 200 * time.Millisecond,
+		EndTime:   800 * time.Millisecond,
+		FrameStep: 2,
+		Progress:  func(done, total int) { progress = append(progress, done) },
+		Verify: func(filename string, e core.OutputExpectation) error {
+			expect = e
+			return nil
+		},
+	}
+	if err := Render(context.Background(), clip, output, options, RenderSpec{Bitrate: "1000k", ProcessMgr: pm}); err != nil {
+		t.Fatalf("渲染失败: %v", err)
+	}
+
+	want := []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 600 * time.Millisecond}
+	calls := clip.Calls()
+	if len(calls) != len(want) {
+		t.Fatalf("取帧时间 %v，期望 %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("第 %d 帧取帧时间 %v，期望 %v", i, calls[i], want[i])
+		}
+	}
+	if len(progress) != len(want) || progress[len(progress)-1] != len(want) {
+		t.Fatalf("进度回调 %v，期望 1..%d", progress, len(want))
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("读取输出失败: %v", err)
+	}
+	if size := ffmpeg.PixelFormatRGB24.FrameSize(4, 2) * len(want); len(data) != size {
+		t.Fatalf("编码器收到 %d 字节，期望 %d", len(data), size)
+	}
+
+	if expect.Duration != 600*time.Millisecond || expect.Width != 4 || expect.Height != 2 || expect.VideoCodec != "libx264" {
+		t.Fatalf("输出期望 %+v 与渲染参数不符", expect)
+	}
+}
+
+func TestRenderDryRunReportsCommand(t *testing.T) {
+	pm, log := newFakeFFmpeg(t)
+	clip := coretest.NewCounterClip(4, 2, time.Second, 25)
+	output := filepath.Join(t.TempDir(), "out.mp4")
+
+	var commands []string
+	dryRun := false
+	options := &core.WriteOptions{
+		DryRun:    true,
+		FrameStep: 5,
+		OnCommand: func(name string, args []string) { commands = append(commands, name+" "+strings.Join(args, " ")) },
+	}
+	spec := RenderSpec{Bitrate: "2000k", ProcessMgr: pm, DryRun: func(core.VideoClip, *core.WriteOptions) { dryRun = true }}
+	if err := Render(context.Background(), clip, output, options, spec); err != nil {
+		t.Fatalf("试运行失败: %v", err)
+	}
+
+	if len(commands) != 1 || !strings.Contains(commands[0], "-r 5 ") || !strings.Contains(commands[0], "-b:v 2000k") {
+		t.Fatalf("报告的命令 %q 应按 5 fps、2000k 编码", commands)
+	}
+	if !dryRun {
+		t.Fatal("试运行时应调用 RenderSpec.DryRun")
+	}
+	if len(clip.Calls()) != 0 {
+		t.Fatalf("试运行不应取帧，实际取帧 %v", clip.Calls())
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Fatalf("试运行不应创建输出文件: %v", err)
+	}
+	if _, err := os.Stat(log); !os.IsNotExist(err) {
+		t.Fatal("试运行不应启动 ffmpeg")
+	}
+}
+
+func TestRenderMuxesAudioWindow(t *testing.T) {
+	pm, log := newFakeFFmpeg(t)
+	// 音轨比渲染窗口的结尾短
+	sine := coretest.NewSineClip(440, 0.5, 700*time.Millisecond, 2, 8000)
+	withAudio, err := coretest.NewCounterClip(4, 2, time.Second, 10).WithAudio(sine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clip := withAudio.(core.VideoClip)
+	output := filepath.Join(t.TempDir(), "out.mp4")
+
+	var expect core.OutputExpectation
+	options := &core.WriteOptions{
+		StartTime:  200 * time.Millisecond,
+		EndTime:    800 * time.Millisecond,
+		AudioCodec: "aac",
+		Verify: func(filename string, e core.OutputExpectation) error {
+			expect = e
+			return nil
+		},
+	}
+	if err := Render(context.Background(), clip, output, options, RenderSpec{Bitrate: "1000k", ProcessMgr: pm}); err != nil {
+		t.Fatalf("渲染失败: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("读取输出失败: %v", err)
+	}
+	// 6 帧画面，加上 200–700ms 的双声道 float32 样本
+	video := ffmpeg.PixelFormatRGB24.FrameSize(4, 2) * 6
+	audio := 4000 * 2 * 4
+	if len(data) != video+audio {
+		t.Fatalf("输出 %d 字节，期望画面 %d + 音频 %d", len(data), video, audio)
+	}
+
+	logged, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logged), "-map 0:v -map 1:a -c:v copy -c:a aac -b:a 128k") {
+		t.Fatalf("未按 aac 混入音轨，ffmpeg 调用:\n%s", logged)
+	}
+	if expect.AudioStreams != 1 || expect.AudioCodec != "aac" || expect.VideoStreams != 1 {
+		t.Fatalf("输出期望 %+v 应包含一条 aac 音频流", expect)
+	}
+}
+
+func TestRenderAppliesAudioFilter(t *testing.T) {
+	pm, log := newFakeFFmpeg(t)
+	withAudio, err := coretest.NewCounterClip(4, 2, time.Second, 10).WithAudio(coretest.NewSineClip(440, 0.5, time.Second, 2, 8000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "out.mp4")
+
+	options := &core.WriteOptions{AudioFilter: "loudnorm=I=-14:TP=-1"}
+	if err := Render(context.Background(), withAudio.(core.VideoClip), output, options, RenderSpec{ProcessMgr: pm}); err != nil {
+		t.Fatalf("渲染失败: %v", err)
+	}
+	logged, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logged), "-c:v copy -af loudnorm=I=-14:TP=-1 -c:a aac") {
+		t.Fatalf("混流时未应用音频滤镜，ffmpeg 调用:\n%s", logged)
+	}
+}
+
+func TestRenderGIFSkipsAudio(t *testing.T) {
+	pm, log := newFakeFFmpeg(t)
+	withAudio, err := coretest.NewCounterClip(4, 2, time.Second, 10).WithAudio(coretest.NewSineClip(440, 0.5, time.Second, 2, 8000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "out.gif")
+
+	var expect core.OutputExpectation
+	options := &core.WriteOptions{
+		Codec:  "gif",
+		Verify: func(filename string, e core.OutputExpectation) error { expect = e; return nil },
+	}
+	if err := Render(context.Background(), withAudio.(core.VideoClip), output, options, RenderSpec{ProcessMgr: pm}); err != nil {
+		t.Fatalf("渲染 gif 失败: %v", err)
+	}
+	logged, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(logged), "-map 1:a") || strings.Contains(string(logged), "pcm_f32le") {
+		t.Fatalf("gif 不应写入或混入音轨，ffmpeg 调用:\n%s", logged)
+	}
+	if expect.AudioStreams != 0 || expect.AudioCodec != "" {
+		t.Fatalf("gif 的输出期望 %+v 不应包含音频流", expect)
+	}
+}
+
+func TestRenderKeepsOutputWhenVerifyFails(t *testing.T) {
+	pm, _ := newFakeFFmpeg(t)
+	withAudio, err := coretest.NewCounterClip(4, 2, time.Second, 10).WithAudio(coretest.NewSineClip(440, 0.5, time.Second, 2, 8000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.mp4")
+	if err := os.WriteFile(output, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	errBad := errors.New("bad output")
+	var verified string
+	options := &core.WriteOptions{
+		Verify: func(filename string, e core.OutputExpectation) error { verified = filename; return errBad },
+	}
+	if err := Render(context.Background(), withAudio.(core.VideoClip), output, options, RenderSpec{ProcessMgr: pm}); !errors.Is(err, errBad) {
+		t.Fatalf("校验失败时应返回校验错误，实际 %v", err)
+	}
+	if verified == output {
+		t.Fatal("应在重命名为目标文件之前校验")
+	}
+	if data, _ := os.ReadFile(output); string(data) != "previous" {
+		t.Fatalf("校验失败后目标文件被替换为 %d 字节", len(data))
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("渲染失败后留下了临时文件: %v", entries)
+	}
+}
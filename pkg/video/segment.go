@@ -0,0 +1,88 @@
+package video
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// SplitEvery 把剪辑切成每段时长为 d 的子剪辑，最后一段为剩余部分，适合按平台时长上限分段上传
+//
+// 切点对齐到剪辑的帧间隔，不足一帧的结尾并入最后一段。
+func SplitEvery(clip core.Clip, d time.Duration) ([]core.Clip, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("无效的分段时长: %v", d)
+	}
+	duration := clip.Duration()
+	var frame time.Duration
+	if clip.FPS() > 0 {
+		frame = time.Duration(float64(time.Second) / clip.FPS())
+	}
+	var cuts []time.Duration
+	for i := 1; ; i++ {
+		t := alignToFrame(time.Duration(i)*d, clip.FPS())
+		if t >= duration-frame {
+			break
+		}
+		cuts = append(cuts, t)
+	}
+	return SplitAt(clip, cuts...)
+}
+
+// SplitAt 在给定时间点切开剪辑，返回 len(times)+1 个首尾相接的子剪辑
+//
+// 切点对齐到剪辑的帧间隔，必须递增且位于剪辑内部。出错时已创建的子剪辑会被关闭。
+func SplitAt(clip core.Clip, times ...time.Duration) ([]core.Clip, error) {
+	duration := clip.Duration()
+	bounds := []time.Duration{0}
+	for _, t := range times {
+		aligned := alignToFrame(t, clip.FPS())
+		if aligned <= bounds[len(bounds)-1] || aligned >= duration {
+			return nil, fmt.Errorf("%w: 切点 %v 重复、未递增或不在 (0, %v) 内", core.ErrInvalidTimeRange, t, duration)
+		}
+		bounds = append(bounds, aligned)
+	}
+	bounds = append(bounds, duration)
+
+	segments := make([]core.Clip, 0, len(bounds)-1)
+	for i := 0; i+1 < len(bounds); i++ {
+		segment, err := clip.Subclip(bounds[i], bounds[i+1])
+		if err != nil {
+			for _, s := range segments {
+				s.Close()
+			}
+			return nil, fmt.Errorf("创建第 %d 段失败: %w", i, err)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// WriteSegments 把每段写入各自的文件，pattern 为包含一个 %d（如 part-%03d.mp4）的文件名模板，序号从 0 开始
+//
+// options 用于每一段。返回已写入的文件名，出错时包含出错之前写完的文件。
+func WriteSegments(segments []core.Clip, pattern string, options *core.WriteOptions) ([]string, error) {
+	if !strings.Contains(pattern, "%") {
+		return nil, fmt.Errorf("文件名模板需要包含 %%d: %s", pattern)
+	}
+	var written []string
+	for i, segment := range segments {
+		filename := fmt.Sprintf(pattern, i)
+		if err := segment.WriteToFile(filename, options); err != nil {
+			return written, fmt.Errorf("写入第 %d 段 %s 失败: %w", i, filename, err)
+		}
+		written = append(written, filename)
+	}
+	return written, nil
+}
+
+// alignToFrame 把时间取整到最近的帧时间，fps 无效时原样返回
+func alignToFrame(t time.Duration, fps float64) time.Duration {
+	if fps <= 0 {
+		return t
+	}
+	return core.FrameTime(int(math.Round(t.Seconds()*fps)), fps)
+}
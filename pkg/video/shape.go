@@ -0,0 +1,268 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+)
+
+// ShapeKind 形状类型
+type ShapeKind int
+
+const (
+	// ShapeRect 矩形，(X, Y) 为左上角，Radius 大于 0 时为圆角矩形
+	ShapeRect ShapeKind = iota
+	// ShapeCircle 圆，(X, Y) 为圆心
+	ShapeCircle
+	// ShapeLine (X, Y) 到 (X2, Y2) 的线段，圆头，只描边
+	ShapeLine
+	// ShapeArc 以 (X, Y) 为圆心、Radius 为半径的圆弧，圆头，只描边
+	ShapeArc
+)
+
+// Shape 一个矢量形状，坐标和尺寸为像素
+//
+// 填充在下、描边在上，描边以轮廓线为中心。边缘按像素中心到轮廓的距离做一个像素宽的抗锯齿。
+type Shape struct {
+	Kind          ShapeKind
+	X, Y          float64
+	Width, Height float64 // 矩形尺寸
+	X2, Y2        float64 // 线段终点
+	Radius        float64 // 圆和圆弧的半径，矩形的圆角半径
+	// StartAngle、EndAngle 圆弧的起止角度，0 为正上方，顺时针增加，跨度不小于 360 时为整圆
+	StartAngle, EndAngle float64
+
+	Fill        color.Color // 填充颜色，nil 表示不填充
+	Stroke      color.Color // 描边颜色，nil 表示不描边
+	StrokeWidth float64
+}
+
+// ProgressRing 返回圆环进度指示：底环 track（nil 时省略）和从正上方顺时针占 progress（0–1）的进度弧
+func ProgressRing(cx, cy, radius, thickness, progress float64, fg, track color.Color) []Shape {
+	var shapes []Shape
+	if track != nil {
+		shapes = append(shapes, Shape{Kind: ShapeCircle, X: cx, Y: cy, Radius: radius, Stroke: track, StrokeWidth: thickness})
+	}
+	if progress = math.Max(0, math.Min(1, progress)); progress > 0 {
+		shapes = append(shapes, Shape{Kind: ShapeArc, X: cx, Y: cy, Radius: radius, EndAngle: 360 * progress, Stroke: fg, StrokeWidth: thickness})
+	}
+	return shapes
+}
+
+// SpectrumBars 返回在 (x, y, width, height) 区域内底部对齐的频谱柱，每个 levels 值（0–1）对应一根柱的高度，
+// 柱间距为 gap，与 analysis.AudioEnvelope.SpectrumAt 配合生成随音乐跳动的频谱
+func SpectrumBars(x, y, width, height, gap float64, levels []float64, fill color.Color) []Shape {
+	if len(levels) == 0 {
+		return nil
+	}
+	barWidth := (width - gap*float64(len(levels)-1)) / float64(len(levels))
+	if barWidth <= 0 {
+		return nil
+	}
+	shapes := make([]Shape, 0, len(levels))
+	for i, level := range levels {
+		h := height * math.Max(0, math.Min(1, level))
+		if h <= 0 {
+			continue
+		}
+		shapes = append(shapes, Shape{
+			Kind:   ShapeRect,
+			X:      x + float64(i)*(barWidth+gap),
+			Y:      y + height - h,
+			Width:  barWidth,
+			Height: h,
+			Radius: math.Min(barWidth/2, h) / 2,
+			Fill:   fill,
+		})
+	}
+	return shapes
+}
+
+// ShapeClip 透明背景上的矢量形状，作为标注框、高亮框和进度指示等图层放入 CompositeVideoClip
+type ShapeClip struct {
+	*core.BaseVideoClip
+	shapes func(t time.Duration) []Shape
+	offset time.Duration // Subclip 后相对生成函数的时间偏移
+
+	mutex  sync.Mutex
+	static *image.RGBA // 与时间无关的形状只绘制一次
+	cache  bool
+}
+
+// NewShapeClip 创建 width×height、各时刻相同的形状剪辑
+func NewShapeClip(width, height int, shapes []Shape, duration time.Duration, fps float64) *ShapeClip {
+	return newShapeClip(width, height, duration, fps, true, func(time.Duration) []Shape { return shapes })
+}
+
+// NewAnimatedShapeClip 由生成函数创建形状剪辑，shapes 返回时间 t 处要绘制的形状，可随时间改变任意参数
+func NewAnimatedShapeClip(width, height int, duration time.Duration, fps float64, shapes func(t time.Duration) []Shape) *ShapeClip {
+	return newShapeClip(width, height, duration, fps, false, shapes)
+}
+
+// newShapeClip 创建形状剪辑，static 为 true 时缓存第一次绘制的画面
+func newShapeClip(width, height int, duration time.Duration, fps float64, static bool, shapes func(t time.Duration) []Shape) *ShapeClip {
+	return &ShapeClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, duration, duration, fps, width, height),
+		shapes:        shapes,
+		cache:         static,
+	}
+}
+
+// GetFrame 返回时间 t 处绘制了形状的预乘 RGBA 画面，静态形状返回共享的画面，调用方不应修改
+func (sc *ShapeClip) GetFrame(t time.Duration) (image.Image, error) {
+	if !sc.cache {
+		return sc.render(sc.shapes(t + sc.offset)), nil
+	}
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	if sc.static == nil {
+		sc.static = sc.render(sc.shapes(0))
+	}
+	return sc.static, nil
+}
+
+// render 按顺序绘制形状
+func (sc *ShapeClip) render(shapes []Shape) *image.RGBA {
+	frame := image.NewRGBA(image.Rect(0, 0, sc.Width(), sc.Height()))
+	for i := range shapes {
+		drawShape(frame, &shapes[i])
+	}
+	return frame
+}
+
+// Subclip 截取时间段，动画按原剪辑的时间计算
+func (sc *ShapeClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > sc.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	sub := newShapeClip(sc.Width(), sc.Height(), end-start, sc.FPS(), sc.cache, sc.shapes)
+	sub.offset = sc.offset + start
+	return sub, nil
+}
+
+// Close 形状剪辑不持有资源
+func (sc *ShapeClip) Close() error {
+	return nil
+}
+
+// drawShape 先填充再描边
+func drawShape(frame *image.RGBA, s *Shape) {
+	half := math.Max(s.StrokeWidth, 0) / 2
+	outline := s.Stroke != nil && half > 0
+	switch s.Kind {
+	case ShapeLine, ShapeArc:
+		if !outline {
+			return
+		}
+		distance := s.lineDistance
+		if s.Kind == ShapeArc {
+			distance = s.arcDistance
+		}
+		fillCoverage(frame, s.bounds(half), s.Stroke, func(x, y float64) float64 {
+			return half - distance(x, y)
+		})
+	default:
+		if s.Fill != nil {
+			fillCoverage(frame, s.bounds(0), s.Fill, s.inside)
+		}
+		if outline {
+			fillCoverage(frame, s.bounds(half), s.Stroke, func(x, y float64) float64 {
+				return half - math.Abs(s.inside(x, y))
+			})
+		}
+	}
+}
+
+// bounds 返回形状加上 margin 后可能覆盖的像素范围
+func (s *Shape) bounds(margin float64) image.Rectangle {
+	var x0, y0, x1, y1 float64
+	switch s.Kind {
+	case ShapeRect:
+		x0, y0, x1, y1 = s.X, s.Y, s.X+s.Width, s.Y+s.Height
+	case ShapeLine:
+		x0, y0, x1, y1 = math.Min(s.X, s.X2), math.Min(s.Y, s.Y2), math.Max(s.X, s.X2), math.Max(s.Y, s.Y2)
+	default:
+		x0, y0, x1, y1 = s.X-s.Radius, s.Y-s.Radius, s.X+s.Radius, s.Y+s.Radius
+	}
+	margin++
+	return image.Rect(int(math.Floor(x0-margin)), int(math.Floor(y0-margin)), int(math.Ceil(x1+margin)), int(math.Ceil(y1+margin)))
+}
+
+// inside 返回点到矩形或圆轮廓的有符号距离，内部为正
+func (s *Shape) inside(x, y float64) float64 {
+	if s.Kind == ShapeCircle {
+		return s.Radius - math.Hypot(x-s.X, y-s.Y)
+	}
+	// 圆角矩形的距离场：把点折到第一象限后与缩进了 radius 的矩形比较
+	hw, hh := s.Width/2, s.Height/2
+	radius := math.Max(0, math.Min(s.Radius, math.Min(hw, hh)))
+	qx := math.Abs(x-(s.X+hw)) - (hw - radius)
+	qy := math.Abs(y-(s.Y+hh)) - (hh - radius)
+	outside := math.Hypot(math.Max(qx, 0), math.Max(qy, 0))
+	return radius - outside - math.Min(math.Max(qx, qy), 0)
+}
+
+// lineDistance 返回点到线段的距离
+func (s *Shape) lineDistance(x, y float64) float64 {
+	dx, dy := s.X2-s.X, s.Y2-s.Y
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return math.Hypot(x-s.X, y-s.Y)
+	}
+	k := math.Max(0, math.Min(1, ((x-s.X)*dx+(y-s.Y)*dy)/length))
+	return math.Hypot(x-(s.X+k*dx), y-(s.Y+k*dy))
+}
+
+// arcDistance 返回点到圆弧的距离，角度范围外取到较近端点的距离
+func (s *Shape) arcDistance(x, y float64) float64 {
+	start, sweep := s.StartAngle, s.EndAngle-s.StartAngle
+	if sweep < 0 {
+		start, sweep = s.EndAngle, -sweep
+	}
+	dx, dy := x-s.X, y-s.Y
+	angle := math.Atan2(dx, -dy) * 180 / math.Pi
+	if sweep >= 360 || math.Mod(math.Mod(angle-start, 360)+360, 360) <= sweep {
+		return math.Abs(math.Hypot(dx, dy) - s.Radius)
+	}
+	end := func(deg float64) float64 {
+		rad := deg * math.Pi / 180
+		return math.Hypot(x-(s.X+s.Radius*math.Sin(rad)), y-(s.Y-s.Radius*math.Cos(rad)))
+	}
+	return math.Min(end(start), end(start+sweep))
+}
+
+// fillCoverage 在 area 内按覆盖率把 c 叠加到 frame 上，distance 以像素中心求值，内部为正
+func fillCoverage(frame *image.RGBA, area image.Rectangle, c color.Color, distance func(x, y float64) float64) {
+	area = area.Intersect(frame.Bounds())
+	if area.Empty() {
+		return
+	}
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return
+	}
+	effects.Parallel(area.Dy(), func(y0, y1 int) {
+		for y := area.Min.Y + y0; y < area.Min.Y+y1; y++ {
+			row := frame.Pix[frame.PixOffset(area.Min.X, y):]
+			for x := area.Min.X; x < area.Max.X; x++ {
+				cover := math.Max(0, math.Min(1, distance(float64(x)+0.5, float64(y)+0.5)+0.5))
+				if cover == 0 {
+					continue
+				}
+				// 预乘颜色按覆盖率缩放后做 source-over
+				sr, sg, sb, sa := float64(r)*cover, float64(g)*cover, float64(b)*cover, float64(a)*cover
+				keep := 1 - sa/0xffff
+				p := row[(x-area.Min.X)*4:]
+				p[0] = uint8((sr/0x101 + float64(p[0])*keep) + 0.5)
+				p[1] = uint8((sg/0x101 + float64(p[1])*keep) + 0.5)
+				p[2] = uint8((sb/0x101 + float64(p[2])*keep) + 0.5)
+				p[3] = uint8((sa/0x101 + float64(p[3])*keep) + 0.5)
+			}
+		}
+	})
+}
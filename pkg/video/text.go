@@ -0,0 +1,206 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// TextClipOptions 文字剪辑选项
+type TextClipOptions struct {
+	FontFile    string // 字体文件，默认由 fontconfig 选择
+	FontSize    int    // 字号，默认高度的 1/4
+	Color       string // drawtext 颜色，如 white、#FFCC00、black@0.5，默认 white
+	BorderWidth int    // 描边宽度，0 表示不描边
+	BorderColor string // 描边颜色，默认 black
+	X, Y        string // drawtext 位置表达式，默认居中；Pango 后端只支持像素坐标
+	// Bold、Italic 选择粗体、斜体字形，设置 FontFile 时由字体文件决定
+	Bold, Italic bool
+	// Fonts 字体族名回退链，如 {"Noto Sans", "Noto Sans Arabic", "Noto Sans CJK SC", "Noto Color Emoji"}；
+	// Pango 按顺序为每个字形选择字体，drawtext 只使用第一个
+	Fonts []string
+	// WrapWidth 自动换行宽度（像素），0 表示只在文字中的换行处分行；需要 Pango 后端
+	WrapWidth int
+	// Renderer 渲染后端，默认按文字内容自动选择（见 TextRendererAuto）
+	Renderer TextRenderer
+}
+
+// TextClip 透明背景上的静态文字，作为图层放入 CompositeVideoClip
+type TextClip struct {
+	*core.BaseVideoClip
+	text  string
+	frame *image.RGBA
+}
+
+// NewTextClip 通过 FFmpeg drawtext 在 width×height 的透明画布上渲染文字
+func NewTextClip(text string, width, height int, options *TextClipOptions, duration time.Duration, fps float64, processMgr *ffmpeg.ProcessManager) (*TextClip, error) {
+	if options == nil {
+		options = &TextClipOptions{}
+	}
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	options, err := resolveTextRenderer(options, text)
+	if err != nil {
+		return nil, err
+	}
+	frames, err := renderTextFrames(processMgr, []string{text}, width, height, options)
+	if err != nil {
+		return nil, err
+	}
+	return newTextClip(text, frames[0], duration, fps), nil
+}
+
+// renderTextFrames 渲染多段文字；drawtext 后端用一次 FFmpeg 调用完成，第 i 帧只启用第 i 个 drawtext
+//
+// options.Renderer 应已由 resolveTextRenderer 确定。
+func renderTextFrames(processMgr *ffmpeg.ProcessManager, texts []string, width, height int, options *TextClipOptions) ([]*image.RGBA, error) {
+	if options.Renderer == TextRendererPango {
+		return renderPangoFrames(processMgr, texts, width, height, options)
+	}
+	filters := make([]string, len(texts))
+	for i, text := range texts {
+		textFile, err := processMgr.Temp().CreateTextFile("drawtext-*.txt", text)
+		if err != nil {
+			return nil, err
+		}
+		defer processMgr.Temp().Remove(textFile)
+		filters[i] = textClipFilter(textFile, height, options)
+		if len(texts) > 1 {
+			filters[i] += ":enable=" + ffmpeg.QuoteFilterArg(fmt.Sprintf("eq(n,%d)", i))
+		}
+	}
+
+	output, err := processMgr.Output(context.Background(), "ffmpeg", textClipArgs(strings.Join(filters, ","), width, height, len(texts)))
+	if err != nil {
+		return nil, fmt.Errorf("渲染文字失败: %w", err)
+	}
+	reader := bytes.NewReader(output)
+	frames := make([]*image.RGBA, len(texts))
+	for i := range frames {
+		img, err := png.Decode(reader)
+		if err != nil {
+			return nil, fmt.Errorf("解码第 %d 帧文字图像失败: %w", i, err)
+		}
+		// PNG 解码为非预乘的 NRGBA，合成前转换为预乘的 RGBA
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(frame, frame.Bounds(), img, img.Bounds().Min, draw.Src)
+		frames[i] = frame
+	}
+	return frames, nil
+}
+
+// newTextClip 由已渲染的画面创建文字剪辑
+func newTextClip(text string, frame *image.RGBA, duration time.Duration, fps float64) *TextClip {
+	return &TextClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, duration, duration, fps, frame.Bounds().Dx(), frame.Bounds().Dy()),
+		text:          text,
+		frame:         frame,
+	}
+}
+
+// Text 返回文字内容
+func (tc *TextClip) Text() string {
+	return tc.text
+}
+
+// GetFrame 各时刻返回同一画面，调用方不应修改
+func (tc *TextClip) GetFrame(t time.Duration) (image.Image, error) {
+	return tc.frame, nil
+}
+
+// Subclip 截取时间段
+func (tc *TextClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > tc.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	return newTextClip(tc.text, tc.frame, end-start, tc.FPS()), nil
+}
+
+// Close 文字剪辑不持有资源
+func (tc *TextClip) Close() error {
+	return nil
+}
+
+// textClipFilter 返回按 options 样式绘制 textFile 中文字的 drawtext 滤镜
+func textClipFilter(textFile string, height int, options *TextClipOptions) string {
+	color := options.Color
+	if color == "" {
+		color = "white"
+	}
+	extra := []string{"fontcolor=" + ffmpeg.QuoteFilterArg(color)}
+	if options.BorderWidth > 0 {
+		borderColor := options.BorderColor
+		if borderColor == "" {
+			borderColor = "black"
+		}
+		extra = append(extra, fmt.Sprintf("borderw=%d", options.BorderWidth), "bordercolor="+ffmpeg.QuoteFilterArg(borderColor))
+	}
+	if options.FontFile == "" && (len(options.Fonts) > 0 || options.Bold || options.Italic) {
+		// font 为 fontconfig 模式，如 "Noto Sans:bold:italic"
+		font := "Sans"
+		if len(options.Fonts) > 0 {
+			font = options.Fonts[0]
+		}
+		if options.Bold {
+			font += ":bold"
+		}
+		if options.Italic {
+			font += ":italic"
+		}
+		extra = append(extra, "font="+ffmpeg.QuoteFilterArg(font))
+	}
+	return drawtextFilter(textFile, options.FontFile, options.FontSize, height, options.X, options.Y, extra...)
+}
+
+// textClipArgs 在透明画布上应用 filter 并输出 frames 帧 RGBA PNG
+func textClipArgs(filter string, width, height, frames int) []string {
+	return []string{
+		"-hide_banner", "-loglevel", "error",
+		"-f", "lavfi",
+		"-i", fmt.Sprintf("color=c=black@0.0:s=%dx%d,format=rgba", width, height),
+		"-vf", filter,
+		"-frames:v", strconv.Itoa(frames),
+		"-pix_fmt", "rgba",
+		"-f", "image2pipe",
+		"-vcodec", "png",
+		"-",
+	}
+}
+
+// drawtextFilter 组装 drawtext 滤镜，fontSize 为 0 时取 height/4，x、y 为空时居中
+func drawtextFilter(textFile, fontFile string, fontSize, height int, x, y string, extra ...string) string {
+	if fontSize <= 0 {
+		fontSize = height / 4
+	}
+	if x == "" {
+		x = "(w-text_w)/2"
+	}
+	if y == "" {
+		y = "(h-text_h)/2"
+	}
+
+	filter := []string{
+		"textfile=" + ffmpeg.QuoteFilterArg(textFile),
+		"expansion=none",
+		fmt.Sprintf("fontsize=%d", fontSize),
+		"x=" + ffmpeg.QuoteFilterArg(x),
+		"y=" + ffmpeg.QuoteFilterArg(y),
+	}
+	if fontFile != "" {
+		filter = append(filter, "fontfile="+ffmpeg.QuoteFilterArg(fontFile))
+	}
+	filter = append(filter, extra...)
+	return "drawtext=" + strings.Join(filter, ":")
+}
@@ -0,0 +1,437 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// TextAnimation 文字动画模式
+type TextAnimation int
+
+const (
+	// TextTypewriter 逐字符出现，空白不占时间
+	TextTypewriter TextAnimation = iota
+	// TextFadeWords 逐词淡入，上一个词完全出现后下一个词开始
+	TextFadeWords
+	// TextSlideIn 整体从 Offset 处滑入并淡入
+	TextSlideIn
+	// TextPopIn 整体以文字中心为原点从零放大弹出
+	TextPopIn
+)
+
+// String 返回动画模式名称
+func (a TextAnimation) String() string {
+	switch a {
+	case TextTypewriter:
+		return "typewriter"
+	case TextFadeWords:
+		return "fade_words"
+	case TextSlideIn:
+		return "slide_in"
+	case TextPopIn:
+		return "pop_in"
+	default:
+		return fmt.Sprintf("TextAnimation(%d)", int(a))
+	}
+}
+
+// ParseTextAnimation 解析动画模式名称
+func ParseTextAnimation(name string) (TextAnimation, error) {
+	for _, a := range []TextAnimation{TextTypewriter, TextFadeWords, TextSlideIn, TextPopIn} {
+		if a.String() == name {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("未知的文字动画: %q（支持 typewriter、fade_words、slide_in、pop_in）", name)
+}
+
+// Easing 缓动函数，把 [0, 1] 的线性进度映射为动画进度，可以短暂超出 1（如 EaseOutBack）
+type Easing func(p float64) float64
+
+// EaseLinear 匀速
+func EaseLinear(p float64) float64 {
+	return p
+}
+
+// EaseInCubic 由慢到快
+func EaseInCubic(p float64) float64 {
+	return p * p * p
+}
+
+// EaseOutCubic 由快到慢
+func EaseOutCubic(p float64) float64 {
+	q := 1 - p
+	return 1 - q*q*q
+}
+
+// EaseInOutCubic 两端慢、中间快
+func EaseInOutCubic(p float64) float64 {
+	if p < 0.5 {
+		return 4 * p * p * p
+	}
+	q := -2*p + 2
+	return 1 - q*q*q/2
+}
+
+// EaseOutBack 越过终点约 10% 后回弹，适合弹出效果
+func EaseOutBack(p float64) float64 {
+	const c1 = 1.70158
+	const c3 = c1 + 1
+	q := p - 1
+	return 1 + c3*q*q*q + c1*q*q
+}
+
+// ParseEasing 按名称返回缓动函数：linear、ease_in、ease_out、ease_in_out、back
+func ParseEasing(name string) (Easing, error) {
+	switch name {
+	case "linear":
+		return EaseLinear, nil
+	case "ease_in":
+		return EaseInCubic, nil
+	case "ease_out":
+		return EaseOutCubic, nil
+	case "ease_in_out":
+		return EaseInOutCubic, nil
+	case "back":
+		return EaseOutBack, nil
+	default:
+		return nil, fmt.Errorf("未知的缓动函数: %q（支持 linear、ease_in、ease_out、ease_in_out、back）", name)
+	}
+}
+
+// TextAnimationOptions 文字动画选项
+type TextAnimationOptions struct {
+	Mode TextAnimation
+	// Delay 动画开始前的等待时间，期间不显示文字
+	Delay time.Duration
+	// Duration 动画时长，默认打字机每字符 50ms、逐词每词 250ms、滑入和弹出 500ms，不超过剪辑剩余时长
+	Duration time.Duration
+	// Easing 缓动函数，默认打字机和逐词为 EaseLinear、滑入为 EaseOutCubic、弹出为 EaseOutBack；
+	// 打字机和逐词模式作用于整体进度
+	Easing Easing
+	// Offset 滑入的起始位置相对最终位置的偏移，默认从下方高度的 1/10 处滑入
+	Offset image.Point
+	// Progress 按剪辑时间直接给出 [0, 1] 的动画进度，设置后忽略 Delay、Duration 和 Easing，
+	// 可以接入 expr 表达式等关键帧曲线
+	Progress func(t time.Duration) float64
+}
+
+// AnimatedTextClip 带入场动画的文字剪辑，透明背景，动画结束后与 TextClip 相同
+//
+// 画面在创建时由 FFmpeg 一次渲染完成：打字机和逐词模式渲染每个字符（词）结束处的前缀，所有前缀使用
+// 与完整文字相同的起点，因此居中等依赖 text_w 的位置表达式不会随文字增长而移动。
+type AnimatedTextClip struct {
+	*core.BaseVideoClip
+	text      string
+	animation TextAnimationOptions
+	steps     []*image.RGBA   // 逐步显示的前缀画面，steps[0] 为空画面，最后一项为完整文字
+	ink       image.Rectangle // 完整文字的可见范围，弹出动画以其中心缩放
+	offset    time.Duration   // Subclip 后相对动画起点的偏移
+}
+
+// NewAnimatedTextClip 渲染带入场动画的文字，样式选项与 NewTextClip 相同，animation 为 nil 时使用打字机效果
+func NewAnimatedTextClip(text string, width, height int, options *TextClipOptions, animation *TextAnimationOptions,
+	duration time.Duration, fps float64, processMgr *ffmpeg.ProcessManager) (*AnimatedTextClip, error) {
+	if options == nil {
+		options = &TextClipOptions{}
+	}
+	if animation == nil {
+		animation = &TextAnimationOptions{}
+	}
+	if processMgr == nil {
+		processMgr = ffmpeg.NewProcessManager()
+		defer processMgr.Close()
+	}
+
+	options, err := resolveTextRenderer(options, text)
+	if err != nil {
+		return nil, err
+	}
+	full, err := renderTextFrames(processMgr, []string{text}, width, height, options)
+	if err != nil {
+		return nil, err
+	}
+	steps := []*image.RGBA{image.NewRGBA(image.Rect(0, 0, width, height)), full[0]}
+	ink := inkBounds(full[0])
+
+	var ends []int
+	switch animation.Mode {
+	case TextTypewriter:
+		ends = textUnitEnds(text, false)
+	case TextFadeWords:
+		ends = textUnitEnds(text, true)
+	case TextSlideIn, TextPopIn:
+	default:
+		return nil, fmt.Errorf("未知的文字动画: %v", animation.Mode)
+	}
+	if len(ends) > 1 && !ink.Empty() {
+		// 在四周各扩大 textMeasureMargin 的画布上从 (textMeasureMargin, textMeasureMargin) 绘制完整文字，
+		// 与实际渲染的可见范围比较求出绘制起点，前缀都从该起点绘制
+		reference, err := renderTextFrames(processMgr, []string{text}, width+2*textMeasureMargin, height+2*textMeasureMargin,
+			textOrigin(options, height, textMeasureMargin, textMeasureMargin))
+		if err != nil {
+			return nil, err
+		}
+		refInk := inkBounds(reference[0])
+		x := ink.Min.X - refInk.Min.X + textMeasureMargin
+		y := ink.Min.Y - refInk.Min.Y + textMeasureMargin
+
+		prefixes := make([]string, len(ends)-1)
+		for i, end := range ends[:len(ends)-1] {
+			prefixes[i] = text[:end]
+		}
+		frames, err := renderTextFrames(processMgr, prefixes, width, height, textOrigin(options, height, x, y))
+		if err != nil {
+			return nil, err
+		}
+		steps = append(append(steps[:1], frames...), full[0])
+	}
+
+	a := *animation
+	if a.Duration <= 0 {
+		switch a.Mode {
+		case TextTypewriter:
+			a.Duration = time.Duration(len(steps)-1) * 50 * time.Millisecond
+		case TextFadeWords:
+			a.Duration = time.Duration(len(steps)-1) * 250 * time.Millisecond
+		default:
+			a.Duration = 500 * time.Millisecond
+		}
+		if remaining := duration - a.Delay; remaining > 0 {
+			a.Duration = min(a.Duration, remaining)
+		}
+	}
+	if a.Easing == nil {
+		switch a.Mode {
+		case TextSlideIn:
+			a.Easing = EaseOutCubic
+		case TextPopIn:
+			a.Easing = EaseOutBack
+		default:
+			a.Easing = EaseLinear
+		}
+	}
+	if a.Offset == (image.Point{}) {
+		a.Offset = image.Pt(0, height/10)
+	}
+	return newAnimatedTextClip(text, a, steps, ink, 0, duration, fps), nil
+}
+
+// newAnimatedTextClip 由已渲染的画面创建动画文字剪辑
+func newAnimatedTextClip(text string, animation TextAnimationOptions, steps []*image.RGBA, ink image.Rectangle,
+	offset, duration time.Duration, fps float64) *AnimatedTextClip {
+	bounds := steps[0].Bounds()
+	return &AnimatedTextClip{
+		BaseVideoClip: core.NewBaseVideoClip(0, duration, duration, fps, bounds.Dx(), bounds.Dy()),
+		text:          text,
+		animation:     animation,
+		steps:         steps,
+		ink:           ink,
+		offset:        offset,
+	}
+}
+
+// Text 返回文字内容
+func (ac *AnimatedTextClip) Text() string {
+	return ac.text
+}
+
+// Animation 返回填充默认值后的动画选项
+func (ac *AnimatedTextClip) Animation() TextAnimationOptions {
+	return ac.animation
+}
+
+// progress 返回时间 t 处的线性进度和缓动后的进度
+func (ac *AnimatedTextClip) progress(t time.Duration) (linear, eased float64) {
+	t += ac.offset
+	if ac.animation.Progress != nil {
+		p := ac.animation.Progress(t)
+		return clamp01(p), p
+	}
+	if ac.animation.Duration <= 0 {
+		if t < ac.animation.Delay {
+			return 0, 0
+		}
+		return 1, 1
+	}
+	linear = clamp01(float64(t-ac.animation.Delay) / float64(ac.animation.Duration))
+	if linear == 0 {
+		return 0, 0
+	}
+	return linear, ac.animation.Easing(linear)
+}
+
+// GetFrame 返回时间 t 处的画面，动画开始前为空画面，结束后各时刻返回同一画面，调用方不应修改
+func (ac *AnimatedTextClip) GetFrame(t time.Duration) (image.Image, error) {
+	linear, eased := ac.progress(t)
+	last := ac.steps[len(ac.steps)-1]
+	// 缓动曲线可能越过终点再回落，只有两者都到达终点时才是最终画面
+	if linear >= 1 && eased == 1 {
+		return last, nil
+	}
+	if linear <= 0 && eased <= 0 {
+		return ac.steps[0], nil
+	}
+
+	switch ac.animation.Mode {
+	case TextTypewriter:
+		n := len(ac.steps) - 1
+		return ac.steps[min(n, int(clamp01(eased)*float64(n)))], nil
+	case TextFadeWords:
+		n := len(ac.steps) - 1
+		p := clamp01(eased) * float64(n)
+		k := min(n-1, int(p))
+		return lerpFrames(ac.steps[k], ac.steps[k+1], p-float64(k)), nil
+	case TextSlideIn:
+		dx := int(math.Round(float64(ac.animation.Offset.X) * (1 - eased)))
+		dy := int(math.Round(float64(ac.animation.Offset.Y) * (1 - eased)))
+		return shiftFrame(last, dx, dy, linear), nil
+	default:
+		return scaleFrame(last, ac.ink, eased, linear), nil
+	}
+}
+
+// Subclip 截取时间段，动画进度按原剪辑的时间计算
+func (ac *AnimatedTextClip) Subclip(start, end time.Duration) (core.Clip, error) {
+	if start < 0 || end > ac.Duration() || start >= end {
+		return nil, core.ErrInvalidTimeRange
+	}
+	return newAnimatedTextClip(ac.text, ac.animation, ac.steps, ac.ink, ac.offset+start, end-start, ac.FPS()), nil
+}
+
+// Close 文字剪辑不持有资源
+func (ac *AnimatedTextClip) Close() error {
+	return nil
+}
+
+// textMeasureMargin 测量文字起点时参考画布四周扩大的像素数，容纳字形向左上伸出的部分
+const textMeasureMargin = 16
+
+// textOrigin 返回把文字固定从 (x, y) 开始绘制的样式选项，默认字号按原画布高度 height 确定
+func textOrigin(options *TextClipOptions, height, x, y int) *TextClipOptions {
+	o := *options
+	if o.FontSize <= 0 {
+		o.FontSize = height / 4
+	}
+	o.X, o.Y = fmt.Sprint(x), fmt.Sprint(y)
+	return &o
+}
+
+// textUnitEnds 返回每个字符（words 为 true 时为每个词）结束处的字节偏移，空白不单独成为一步
+func textUnitEnds(text string, words bool) []int {
+	var ends []int
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		end := i + utf8.RuneLen(r)
+		if words && end < len(text) {
+			if next, _ := utf8.DecodeRuneInString(text[end:]); !unicode.IsSpace(next) {
+				continue
+			}
+		}
+		ends = append(ends, end)
+	}
+	return ends
+}
+
+// inkBounds 返回 alpha 非零像素的范围
+func inkBounds(img *image.RGBA) image.Rectangle {
+	var ink image.Rectangle
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := img.Pix[(y-bounds.Min.Y)*img.Stride:]
+		for x := 0; x < bounds.Dx(); x++ {
+			if row[x*4+3] != 0 {
+				ink = ink.Union(image.Rect(x+bounds.Min.X, y, x+bounds.Min.X+1, y+1))
+			}
+		}
+	}
+	return ink
+}
+
+// lerpFrames 在两幅预乘 RGBA 画面间线性插值
+func lerpFrames(from, to *image.RGBA, f float64) *image.RGBA {
+	out := image.NewRGBA(from.Bounds())
+	weight := int(math.Round(clamp01(f) * 256))
+	effects.Parallel(from.Bounds().Dy(), func(y0, y1 int) {
+		for i := y0 * from.Stride; i < y1*from.Stride; i++ {
+			a, b := int(from.Pix[i]), int(to.Pix[i])
+			out.Pix[i] = uint8(a + ((b-a)*weight)>>8)
+		}
+	})
+	return out
+}
+
+// shiftFrame 把画面平移 (dx, dy) 并乘以不透明度 alpha
+func shiftFrame(src *image.RGBA, dx, dy int, alpha float64) *image.RGBA {
+	bounds := src.Bounds()
+	out := image.NewRGBA(bounds)
+	weight := int(math.Round(clamp01(alpha) * 256))
+	width, height := bounds.Dx(), bounds.Dy()
+	effects.Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			sy := y - dy
+			if sy < 0 || sy >= height {
+				continue
+			}
+			for x := max(0, dx); x < min(width, width+dx); x++ {
+				si := sy*src.Stride + (x-dx)*4
+				di := y*out.Stride + x*4
+				for c := 0; c < 4; c++ {
+					out.Pix[di+c] = uint8(int(src.Pix[si+c]) * weight >> 8)
+				}
+			}
+		}
+	})
+	return out
+}
+
+// scaleFrame 以 ink 的中心把画面缩放 scale 倍（双线性采样）并乘以不透明度 alpha
+func scaleFrame(src *image.RGBA, ink image.Rectangle, scale, alpha float64) *image.RGBA {
+	bounds := src.Bounds()
+	out := image.NewRGBA(bounds)
+	if scale <= 0 || ink.Empty() {
+		return out
+	}
+	width, height := bounds.Dx(), bounds.Dy()
+	cx := float64(ink.Min.X+ink.Max.X) / 2
+	cy := float64(ink.Min.Y+ink.Max.Y) / 2
+	alpha = clamp01(alpha)
+	effects.Parallel(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			sy := cy + (float64(y)+0.5-cy)/scale - 0.5
+			for x := 0; x < width; x++ {
+				sx := cx + (float64(x)+0.5-cx)/scale - 0.5
+				if sx < -1 || sy < -1 || sx >= float64(width) || sy >= float64(height) {
+					continue
+				}
+				x0, y0f := math.Floor(sx), math.Floor(sy)
+				fx, fy := sx-x0, sy-y0f
+				di := y*out.Stride + x*4
+				for c := 0; c < 4; c++ {
+					v := (1-fy)*((1-fx)*rgbaAt(src, int(x0), int(y0f), c)+fx*rgbaAt(src, int(x0)+1, int(y0f), c)) +
+						fy*((1-fx)*rgbaAt(src, int(x0), int(y0f)+1, c)+fx*rgbaAt(src, int(x0)+1, int(y0f)+1, c))
+					out.Pix[di+c] = uint8(math.Round(v * alpha))
+				}
+			}
+		}
+	})
+	return out
+}
+
+// rgbaAt 返回 (x, y) 处通道 c 的值，画面外为 0
+func rgbaAt(img *image.RGBA, x, y, c int) float64 {
+	if x < 0 || y < 0 || x >= img.Bounds().Dx() || y >= img.Bounds().Dy() {
+		return 0
+	}
+	return float64(img.Pix[y*img.Stride+x*4+c])
+}
@@ -0,0 +1,324 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"moviepy-go/pkg/effects"
+	"moviepy-go/pkg/ffmpeg"
+)
+
+// TextRenderer 文字渲染后端
+type TextRenderer int
+
+const (
+	// TextRendererAuto 文字需要复杂排版（非拉丁文字、emoji、多个回退字体或自动换行）且系统安装了
+	// pango-view 时使用 Pango，否则使用 drawtext
+	TextRendererAuto TextRenderer = iota
+	// TextRendererDrawtext FFmpeg drawtext：只使用一个字体，没有逐字形回退，不支持彩色 emoji 和自动换行
+	TextRendererDrawtext
+	// TextRendererPango pango-view（Pango + HarfBuzz + cairo）：阿拉伯文等复杂文字整形、双向文本、
+	// 按 Fonts 和 fontconfig 逐字形回退、彩色 emoji，以及按 Unicode 规则（含中日韩）自动换行
+	TextRendererPango
+)
+
+// pangoBinary Pango 的命令行渲染工具，通常由 pango1.0-tools 或 pango 软件包提供
+const pangoBinary = "pango-view"
+
+// pangoAvailable 检查并缓存 pango-view 是否可用
+var pangoAvailable = sync.OnceValue(func() bool {
+	_, err := exec.LookPath(pangoBinary)
+	return err == nil
+})
+
+// resolveTextRenderer 返回选定具体后端的选项副本；同一剪辑的所有画面（如动画前缀）使用同一后端
+func resolveTextRenderer(options *TextClipOptions, text string) (*TextClipOptions, error) {
+	o := *options
+	switch o.Renderer {
+	case TextRendererAuto:
+		o.Renderer = TextRendererDrawtext
+		// 自定义字体文件需要字体族名才能交给 Pango，只给了文件时保持 drawtext
+		if pangoAvailable() && (o.FontFile == "" || len(o.Fonts) > 0) &&
+			(len(o.Fonts) > 1 || o.WrapWidth > 0 || needsShaping(text)) {
+			o.Renderer = TextRendererPango
+		}
+	case TextRendererDrawtext:
+		if o.WrapWidth > 0 {
+			return nil, fmt.Errorf("drawtext 不支持自动换行，请使用 TextRendererPango 或在文字中手动换行")
+		}
+	case TextRendererPango:
+		if !pangoAvailable() {
+			return nil, fmt.Errorf("未找到 %s，复杂文字排版需要安装 Pango 命令行工具（如 pango1.0-tools）", pangoBinary)
+		}
+		if o.FontFile != "" && len(o.Fonts) == 0 {
+			return nil, fmt.Errorf("使用 Pango 渲染字体文件 %s 时需要在 Fonts 中指定其字体族名", filepath.Base(o.FontFile))
+		}
+	default:
+		return nil, fmt.Errorf("未知的文字渲染后端: %d", o.Renderer)
+	}
+	return &o, nil
+}
+
+// needsShaping 判断文字是否含有 drawtext 单一字体难以正确显示的字符：拉丁、希腊、西里尔字母以外的文字和 emoji
+func needsShaping(text string) bool {
+	for _, r := range text {
+		// U+0000–U+052F 覆盖拉丁、希腊和西里尔字母及组合附加符号，U+2000–U+206F 为通用标点
+		if r > 0x052F && (r < 0x2000 || r > 0x206F) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderPangoFrames 用 pango-view 逐段渲染文字并放到 width×height 的透明画布上
+func renderPangoFrames(processMgr *ffmpeg.ProcessManager, texts []string, width, height int, options *TextClipOptions) ([]*image.RGBA, error) {
+	var env []string
+	if options.FontFile != "" {
+		configFile, err := pangoFontconfig(processMgr, options.FontFile)
+		if err != nil {
+			return nil, err
+		}
+		defer processMgr.Temp().Remove(configFile)
+		env = []string{"FONTCONFIG_FILE=" + configFile}
+	}
+	frames := make([]*image.RGBA, len(texts))
+	for i, text := range texts {
+		textFile, err := processMgr.Temp().CreateTextFile("pango-*.txt", text)
+		if err != nil {
+			return nil, err
+		}
+		defer processMgr.Temp().Remove(textFile)
+
+		color, alpha := splitDrawtextColor(options.Color, "white")
+		layer, err := renderPango(processMgr, textFile, height, color, options, env)
+		if err != nil {
+			return nil, err
+		}
+		if options.BorderWidth > 0 {
+			// pango-view 不支持描边：以描边颜色再渲染一次，膨胀 alpha 后垫在文字下面
+			borderColor, borderAlpha := splitDrawtextColor(options.BorderColor, "black")
+			border, err := renderPango(processMgr, textFile, height, borderColor, options, env)
+			if err != nil {
+				return nil, err
+			}
+			border = dilateAlpha(border, options.BorderWidth)
+			scaleAlpha(border, borderAlpha)
+			scaleAlpha(layer, alpha)
+			draw.Draw(border, border.Bounds(), layer, layer.Bounds().Min, draw.Over)
+			layer = border
+		} else {
+			scaleAlpha(layer, alpha)
+		}
+
+		origin, err := pangoOrigin(layer, width, height, options)
+		if err != nil {
+			return nil, err
+		}
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(frame, layer.Bounds().Add(origin), layer, layer.Bounds().Min, draw.Over)
+		frames[i] = frame
+	}
+	return frames, nil
+}
+
+// renderPango 以前景色 color 渲染 textFile 中的文字，返回大小与文字范围一致（含描边余量）的画面
+func renderPango(processMgr *ffmpeg.ProcessManager, textFile string, height int, color string, options *TextClipOptions, env []string) (*image.RGBA, error) {
+	outputFile, err := processMgr.Temp().CreateFile("pango-*.png")
+	if err != nil {
+		return nil, err
+	}
+	defer processMgr.Temp().Remove(outputFile)
+
+	process, err := processMgr.StartProcess(context.Background(), pangoBinary, pangoArgs(textFile, outputFile, height, color, options), env)
+	if err != nil {
+		return nil, fmt.Errorf("启动 Pango 渲染失败: %w", err)
+	}
+	if err := process.Wait(); err != nil {
+		return nil, fmt.Errorf("Pango 渲染文字失败: %w", err)
+	}
+
+	file, err := os.Open(outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取文字图像失败: %w", err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("解码文字图像失败: %w", err)
+	}
+	// cairo 输出非预乘的 PNG，合成前转换为预乘的 RGBA
+	layer := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(layer, layer.Bounds(), img, img.Bounds().Min, draw.Src)
+	return layer, nil
+}
+
+// pangoArgs 构建 pango-view 参数：按像素计字号（dpi 72），透明背景，四周留出描边宽度的余量
+func pangoArgs(textFile, outputFile string, height int, color string, options *TextClipOptions) []string {
+	fontSize := options.FontSize
+	if fontSize <= 0 {
+		fontSize = height / 4
+	}
+	fonts := options.Fonts
+	if len(fonts) == 0 {
+		fonts = []string{"Sans"}
+	}
+	args := []string{
+		"--no-display",
+		"--backend=cairo",
+		"--output=" + outputFile,
+		"--dpi=72",
+		fmt.Sprintf("--font=%s%s %d", strings.Join(fonts, ","), pangoStyle(options), fontSize),
+		"--foreground=" + color,
+		"--background=transparent",
+		fmt.Sprintf("--margin=%d", options.BorderWidth+2),
+	}
+	if options.WrapWidth > 0 {
+		args = append(args, fmt.Sprintf("--width=%d", options.WrapWidth), "--wrap=word-char")
+	}
+	return append(args, textFile)
+}
+
+// pangoStyle 返回 Pango 字体描述中的字形样式词
+func pangoStyle(options *TextClipOptions) string {
+	style := ""
+	if options.Bold {
+		style += " Bold"
+	}
+	if options.Italic {
+		style += " Italic"
+	}
+	return style
+}
+
+// pangoFontconfig 写入把字体文件所在目录加入搜索路径的 fontconfig 配置，返回配置文件路径
+func pangoFontconfig(processMgr *ffmpeg.ProcessManager, fontFile string) (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(fontFile))
+	if err != nil {
+		return "", fmt.Errorf("解析字体路径失败: %w", err)
+	}
+	base := os.Getenv("FONTCONFIG_FILE")
+	if base == "" {
+		base = "/etc/fonts/fonts.conf"
+	}
+	configFile, err := processMgr.Temp().CreateFile("fonts-*.conf")
+	if err != nil {
+		return "", err
+	}
+	config := fmt.Sprintf("<?xml version=\"1.0\"?>\n<fontconfig>\n  <include ignore_missing=\"yes\">%s</include>\n  <dir>%s</dir>\n</fontconfig>\n",
+		xmlEscape(base), xmlEscape(dir))
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		processMgr.Temp().Remove(configFile)
+		return "", fmt.Errorf("写入字体配置失败: %w", err)
+	}
+	return configFile, nil
+}
+
+// pangoOrigin 返回文字画面左上角在画布上的位置：X、Y 为空时按可见范围居中，否则为像素坐标
+func pangoOrigin(layer *image.RGBA, width, height int, options *TextClipOptions) (image.Point, error) {
+	ink := inkBounds(layer)
+	if ink.Empty() {
+		ink = layer.Bounds()
+	}
+	position := func(expr string, canvas, inkMin, inkSize int) (int, error) {
+		if expr == "" {
+			return (canvas-inkSize)/2 - inkMin, nil
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(expr), 64)
+		if err != nil {
+			return 0, fmt.Errorf("Pango 渲染只支持数值位置，不支持 drawtext 表达式 %q", expr)
+		}
+		return int(v), nil
+	}
+	x, err := position(options.X, width, ink.Min.X, ink.Dx())
+	if err != nil {
+		return image.Point{}, err
+	}
+	y, err := position(options.Y, height, ink.Min.Y, ink.Dy())
+	if err != nil {
+		return image.Point{}, err
+	}
+	return image.Pt(x, y), nil
+}
+
+// splitDrawtextColor 把 drawtext 颜色（如 #FFCC00、0xFFCC00、black@0.5）拆成 Pango 颜色和不透明度
+func splitDrawtextColor(color, fallback string) (string, float64) {
+	if color == "" {
+		color = fallback
+	}
+	alpha := 1.0
+	if base, suffix, ok := strings.Cut(color, "@"); ok {
+		color = base
+		if v, err := strconv.ParseFloat(suffix, 64); err == nil {
+			alpha = clamp01(v)
+		}
+	}
+	if strings.HasPrefix(color, "0x") || strings.HasPrefix(color, "0X") {
+		color = "#" + color[2:]
+	}
+	return color, alpha
+}
+
+// scaleAlpha 把预乘画面整体乘以不透明度 alpha
+func scaleAlpha(img *image.RGBA, alpha float64) {
+	if alpha >= 1 {
+		return
+	}
+	weight := int(alpha*256 + 0.5)
+	for i, v := range img.Pix {
+		img.Pix[i] = uint8(int(v) * weight >> 8)
+	}
+}
+
+// dilateAlpha 以半径 radius 的圆形膨胀画面（取邻域内 alpha 最大的像素），用于生成描边
+func dilateAlpha(src *image.RGBA, radius int) *image.RGBA {
+	out := image.NewRGBA(src.Bounds())
+	var offsets []image.Point
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if dx*dx+dy*dy <= radius*radius {
+				offsets = append(offsets, image.Pt(dx, dy))
+			}
+		}
+	}
+	effects.Parallel(src.Bounds().Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			dilateRow(src, out, offsets, y)
+		}
+	})
+	return out
+}
+
+// dilateRow 计算膨胀结果的第 y 行
+func dilateRow(src, out *image.RGBA, offsets []image.Point, y int) {
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	for x := 0; x < width; x++ {
+		best := -1
+		for _, o := range offsets {
+			sx, sy := x+o.X, y+o.Y
+			if sx < 0 || sy < 0 || sx >= width || sy >= height {
+				continue
+			}
+			i := sy*src.Stride + sx*4
+			if best < 0 || src.Pix[i+3] > src.Pix[best+3] {
+				best = i
+			}
+		}
+		if best >= 0 {
+			copy(out.Pix[y*out.Stride+x*4:y*out.Stride+x*4+4], src.Pix[best:best+4])
+		}
+	}
+}
+
+// xmlEscape 转义 fontconfig 配置中的路径
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
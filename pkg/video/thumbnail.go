@@ -0,0 +1,136 @@
+package video
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"time"
+
+	"moviepy-go/pkg/core"
+)
+
+// ThumbnailOptions 封面帧选择选项
+type ThumbnailOptions struct {
+	Candidates int     // 均匀采样的候选帧数，默认 20
+	SkipEdges  float64 // 跳过开头和结尾各占时长的比例（片头黑场、片尾字幕），默认 0.05
+	// FaceScore 可选的人脸检测，返回 0–1 的人脸显著度，nil 表示不考虑人脸
+	FaceScore  func(frame image.Image) float64
+	FaceWeight float64 // 人脸得分权重，默认 0.5（仅当 FaceScore 非 nil）
+}
+
+// ThumbnailResult 选出的封面帧及其得分
+type ThumbnailResult struct {
+	Time       time.Duration
+	Frame      image.Image
+	Score      float64 // 综合得分
+	Sharpness  float64 // 拉普拉斯方差归一化得分，0–1
+	Brightness float64 // 曝光得分，中等亮度为 1，全黑或过曝为 0
+	Contrast   float64 // 亮度标准差归一化得分，排除纯色帧
+	Face       float64
+}
+
+// BestThumbnail 在均匀采样的候选帧中选出最适合作为封面的一帧
+//
+// 得分综合清晰度、曝光和对比度，可选叠加人脸显著度；结果可通过 WriteOptions.Cover 嵌入输出文件。
+func BestThumbnail(clip core.VideoClip, options *ThumbnailOptions) (*ThumbnailResult, error) {
+	if options == nil {
+		options = &ThumbnailOptions{}
+	}
+	candidates := options.Candidates
+	if candidates <= 0 {
+		candidates = 20
+	}
+	skip := options.SkipEdges
+	if skip == 0 {
+		skip = 0.05
+	}
+	if skip < 0 || skip >= 0.5 {
+		return nil, fmt.Errorf("无效的跳过比例: %f", skip)
+	}
+	faceWeight := options.FaceWeight
+	if faceWeight == 0 {
+		faceWeight = 0.5
+	}
+
+	duration := clip.Duration()
+	if duration <= 0 {
+		return nil, core.ErrInvalidTimeRange
+	}
+	from := time.Duration(float64(duration) * skip)
+	span := duration - 2*from
+
+	var best *ThumbnailResult
+	for i := 0; i < candidates; i++ {
+		// 取每个区间的中点，避免落在结尾之外
+		t := from + time.Duration((float64(i)+0.5)/float64(candidates)*float64(span))
+		frame, err := clip.GetFrame(t)
+		if err != nil {
+			return nil, fmt.Errorf("获取候选帧 %v 失败: %w", t, err)
+		}
+
+		result := scoreThumbnail(frame)
+		result.Time = t
+		result.Score = 0.5*result.Sharpness + 0.3*result.Brightness + 0.2*result.Contrast
+		if options.FaceScore != nil {
+			result.Face = options.FaceScore(frame)
+			result.Score += faceWeight * result.Face
+		}
+		if best == nil || result.Score > best.Score {
+			best = result
+		}
+	}
+	return best, nil
+}
+
+// scoreThumbnail 计算单帧的清晰度、曝光和对比度得分
+func scoreThumbnail(frame image.Image) *ThumbnailResult {
+	bounds := frame.Bounds()
+	rgba, ok := frame.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), frame, bounds.Min, draw.Src)
+	}
+	width, height := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+
+	lum := make([]float64, width*height)
+	var sum, sumSq float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*rgba.Stride + x*4
+			v := (0.299*float64(rgba.Pix[i]) + 0.587*float64(rgba.Pix[i+1]) + 0.114*float64(rgba.Pix[i+2])) / 255
+			lum[y*width+x] = v
+			sum += v
+			sumSq += v * v
+		}
+	}
+	n := float64(width * height)
+	mean := sum / n
+	stddev := math.Sqrt(math.Max(sumSq/n-mean*mean, 0))
+
+	// 拉普拉斯方差衡量边缘强度，模糊帧的值很小
+	var lapSum, lapSq float64
+	count := 0
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			i := y*width + x
+			lap := 4*lum[i] - lum[i-1] - lum[i+1] - lum[i-width] - lum[i+width]
+			lapSum += lap
+			lapSq += lap * lap
+			count++
+		}
+	}
+	var lapVar float64
+	if count > 0 {
+		lapMean := lapSum / float64(count)
+		lapVar = lapSq/float64(count) - lapMean*lapMean
+	}
+
+	return &ThumbnailResult{
+		Frame: frame,
+		// 常见清晰画面的拉普拉斯方差约在 0.01 量级
+		Sharpness:  math.Min(lapVar/0.01, 1),
+		Brightness: 1 - math.Min(math.Abs(mean-0.5)*2, 1),
+		Contrast:   math.Min(stddev/0.25, 1),
+	}
+}
@@ -1,53 +1,101 @@
 package video
 
 import (
+	"context"
 	"fmt"
 	"image"
+	"sync"
 	"time"
 
 	"moviepy-go/pkg/audio"
 	"moviepy-go/pkg/core"
 	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/leakcheck"
+	"moviepy-go/pkg/preview"
 )
 
 // VideoFileClip 视频文件剪辑
+//
+// GetFrame 和 GetAudioFrame 可以被多个协程并发调用，每次调用启动独立的解码进程，
+// 并发数受 ProcessManager 的 MaxProcesses 限制。Close 可与读取并发调用，
+// 正在进行的读取会完成或返回错误。
 type VideoFileClip struct {
 	*core.BaseVideoClip
 	filename    string
 	reader      *ffmpeg.VideoReader
 	processMgr  *ffmpeg.ProcessManager
 	audio       core.AudioClip
+	ownsAudio   bool // audio 由本剪辑创建（打开文件或派生），Close 时一并关闭
 	closed      bool
-	speedFactor float64 // 速度调整因子，1.0表示正常速度
+	speedFactor float64      // 速度调整因子，1.0表示正常速度
+	fullSize    bool         // 按原始分辨率解码，忽略共享读取器上的代理尺寸，由 WithoutProxy 创建
+	prefetch    *prefetcher  // 后台预取，nil 表示关闭
+	mutex       sync.RWMutex // 保护 reader、audio、prefetch 和 closed
+	options     VideoFileClipOptions
+	leak        *leakcheck.Guard
+}
+
+// VideoFileClipOptions 打开视频文件的选项
+type VideoFileClipOptions struct {
+	// VideoStream 选择视频流，默认第一个
+	VideoStream ffmpeg.StreamSelector
+	// AudioStream 选择音轨（如 Language: "eng" 或第二条解说音轨），默认第一个
+	AudioStream ffmpeg.StreamSelector
+	// Timeout 单次探测、取帧或读取音频的最长时间，超时后终止 FFmpeg 并返回错误，0 表示不限；
+	// 单次调用的期限也可以通过 GetFrameContext 的 ctx 设置
+	Timeout time.Duration
 }
 
 // NewVideoFileClip 创建新的视频文件剪辑
 func NewVideoFileClip(filename string, processMgr *ffmpeg.ProcessManager) *VideoFileClip {
-	return &VideoFileClip{
+	return NewVideoFileClipWithOptions(filename, nil, processMgr)
+}
+
+// NewVideoFileClipWithOptions 使用指定选项创建视频文件剪辑
+func NewVideoFileClipWithOptions(filename string, options *VideoFileClipOptions, processMgr *ffmpeg.ProcessManager) *VideoFileClip {
+	if options == nil {
+		options = &VideoFileClipOptions{}
+	}
+	vfc := &VideoFileClip{
 		BaseVideoClip: core.NewBaseVideoClip(0, 0, 0, 0, 0, 0),
 		filename:      filename,
 		processMgr:    processMgr,
 		speedFactor:   1.0, // 默认正常速度
+		options:       *options,
 	}
+	vfc.leak = leakcheck.Track(vfc, "VideoFileClip", filename)
+	return vfc
 }
 
 // Open 打开视频文件
 func (vfc *VideoFileClip) Open() error {
+	vfc.mutex.Lock()
+	defer vfc.mutex.Unlock()
+
 	if vfc.closed {
 		return fmt.Errorf("剪辑已关闭")
 	}
 
+	// 重复打开时先释放上一次的读取器和音轨
+	vfc.releaseLocked()
+
 	// 创建读取器
-	vfc.reader = ffmpeg.NewVideoReader(vfc.filename, vfc.processMgr)
+	vfc.reader = ffmpeg.NewVideoReaderWithOptions(vfc.filename, &ffmpeg.VideoReaderOptions{
+		VideoStream: vfc.options.VideoStream,
+		AudioStream: vfc.options.AudioStream,
+		Timeout:     vfc.options.Timeout,
+	}, vfc.processMgr)
 
 	// 打开视频
 	if err := vfc.reader.Open(); err != nil {
+		vfc.releaseLocked()
 		return fmt.Errorf("打开视频失败: %w", err)
 	}
 
 	// 获取视频信息
 	info := vfc.reader.GetInfo()
 	if info == nil {
+		vfc.releaseLocked()
 		return fmt.Errorf("无法获取视频信息")
 	}
 
@@ -57,9 +105,15 @@ func (vfc *VideoFileClip) Open() error {
 
 	// 如果有音频，创建音频剪辑
 	if info.HasAudio {
-		audioClip := audio.NewAudioFileClip(vfc.filename, vfc.processMgr)
+		audioClip := audio.NewAudioFileClipWithOptions(vfc.filename, &audio.AudioFileClipOptions{
+			Stream:  vfc.options.AudioStream,
+			Timeout: vfc.options.Timeout,
+		}, vfc.processMgr)
 		if err := audioClip.Open(); err == nil {
 			vfc.audio = audioClip
+			vfc.ownsAudio = true
+		} else {
+			audioClip.Close()
 		}
 	}
 
@@ -68,14 +122,62 @@ func (vfc *VideoFileClip) Open() error {
 
 // GetFrame 获取指定时间的帧
 func (vfc *VideoFileClip) GetFrame(t time.Duration) (image.Image, error) {
-	if vfc.closed {
+	vfc.mutex.RLock()
+	prefetch := vfc.prefetch
+	vfc.mutex.RUnlock()
+
+	if prefetch != nil {
+		return prefetch.get(t)
+	}
+	return vfc.decodeFrame(context.Background(), t)
+}
+
+// GetFrameContext 与 GetFrame 相同，ctx 取消或到期时终止解码；追踪开启时记录解码区间
+//
+// 开启预取时帧由后台协程解码，ctx 只用于追踪。
+func (vfc *VideoFileClip) GetFrameContext(ctx context.Context, t time.Duration) (image.Image, error) {
+	vfc.mutex.RLock()
+	prefetch := vfc.prefetch
+	vfc.mutex.RUnlock()
+
+	decode := func() (image.Image, error) {
+		if prefetch != nil {
+			return prefetch.get(t)
+		}
+		return vfc.decodeFrame(ctx, t)
+	}
+	if !core.Tracing() {
+		return decode()
+	}
+	_, span := core.StartSpan(ctx, core.SpanDecode,
+		core.Attr("clip.id", core.ClipID(vfc)), core.Attr("file", vfc.filename), core.Attr("t", t))
+	frame, err := decode()
+	core.EndSpan(span, err)
+	return frame, err
+}
+
+// decodeFrame 通过读取器解码指定时间的帧
+func (vfc *VideoFileClip) decodeFrame(ctx context.Context, t time.Duration) (image.Image, error) {
+	vfc.mutex.RLock()
+	closed, reader := vfc.closed, vfc.reader
+	vfc.mutex.RUnlock()
+
+	if closed {
 		return nil, fmt.Errorf("剪辑已关闭")
 	}
 
-	if vfc.reader == nil {
+	if reader == nil {
 		return nil, fmt.Errorf("视频未打开")
 	}
 
+	if vfc.fullSize {
+		return reader.GetFrameSize(ctx, vfc.sourceTime(t), 0, 0)
+	}
+	return reader.GetFrameContext(ctx, vfc.sourceTime(t))
+}
+
+// sourceTime 将剪辑内时间换算为源文件时间
+func (vfc *VideoFileClip) sourceTime(t time.Duration) time.Duration {
 	// 对于子剪辑，需要调整时间偏移
 	absoluteTime := vfc.Start() + t
 
@@ -85,17 +187,66 @@ func (vfc *VideoFileClip) GetFrame(t time.Duration) (image.Image, error) {
 		// 例如：2倍速时，t=1s应该获取原视频t=2s的帧
 		absoluteTime = vfc.Start() + time.Duration(float64(t)*vfc.speedFactor)
 	}
+	return absoluteTime
+}
 
-	return vfc.reader.GetFrame(absoluteTime)
+// IsKeyFrame t 处的帧在源文件中是否为关键帧，实现 core.KeyFrameReporter
+//
+// 首次调用时通过 VideoReader.Keyframes 读取关键帧列表，派生剪辑共享该缓存。
+func (vfc *VideoFileClip) IsKeyFrame(t time.Duration) bool {
+	reader := vfc.getReader()
+	if reader == nil {
+		return false
+	}
+	return reader.IsKeyFrame(vfc.sourceTime(t))
+}
+
+// SetPrefetch 设置后台预取深度，顺序读取时提前解码后续 depth 帧，0 表示关闭
+//
+// 预取按剪辑帧率的帧序号进行，帧时间戳与 WriteToFile 的 core.FrameTime 一致。派生剪辑不继承该设置。
+func (vfc *VideoFileClip) SetPrefetch(depth int) error {
+	if depth < 0 {
+		return fmt.Errorf("无效的预取深度: %d", depth)
+	}
+
+	vfc.mutex.Lock()
+	defer vfc.mutex.Unlock()
+
+	if depth == 0 {
+		vfc.prefetch = nil
+		return nil
+	}
+	if vfc.FPS() <= 0 {
+		return fmt.Errorf("无效的帧率: %f", vfc.FPS())
+	}
+	vfc.prefetch = newPrefetcher(depth, vfc.FPS(), vfc.Duration(), func(t time.Duration) (image.Image, error) {
+		return vfc.decodeFrame(context.Background(), t)
+	})
+	return nil
+}
+
+// resetPrefetch 丢弃已预取的帧
+func (vfc *VideoFileClip) resetPrefetch() {
+	vfc.mutex.RLock()
+	prefetch := vfc.prefetch
+	vfc.mutex.RUnlock()
+
+	if prefetch != nil {
+		prefetch.reset()
+	}
 }
 
 // GetAudioFrame 获取指定时间的音频帧
 func (vfc *VideoFileClip) GetAudioFrame(t time.Duration) ([]float64, error) {
-	if vfc.closed {
+	vfc.mutex.RLock()
+	closed, audioClip := vfc.closed, vfc.audio
+	vfc.mutex.RUnlock()
+
+	if closed {
 		return nil, fmt.Errorf("剪辑已关闭")
 	}
 
-	if vfc.audio == nil {
+	if audioClip == nil {
 		// 返回静音
 		sampleRate := int(vfc.FPS())
 		if sampleRate == 0 {
@@ -104,7 +255,7 @@ func (vfc *VideoFileClip) GetAudioFrame(t time.Duration) ([]float64, error) {
 		return make([]float64, sampleRate), nil
 	}
 
-	return vfc.audio.GetAudioFrame(t)
+	return audioClip.GetAudioFrame(t)
 }
 
 // Subclip 创建子剪辑
@@ -113,44 +264,131 @@ func (vfc *VideoFileClip) Subclip(start, end time.Duration) (core.Clip, error) {
 		return nil, core.ErrInvalidTimeRange
 	}
 
-	// 创建新的子剪辑
-	subclip := &VideoFileClip{
-		BaseVideoClip: core.NewBaseVideoClip(start, end, end-start, vfc.FPS(), vfc.Width(), vfc.Height()),
+	// start、end 为剪辑内时间，换算为源文件时间，使嵌套子剪辑和变速剪辑的偏移正确累加
+	speed := vfc.speed()
+	sourceStart := vfc.Start() + time.Duration(float64(start)*speed)
+	sourceEnd := vfc.Start() + time.Duration(float64(end)*speed)
+
+	// 创建新的子剪辑，音轨截取相同的时间段以保持同步
+	subclip := vfc.derive(core.NewBaseVideoClip(sourceStart, sourceEnd, end-start, vfc.FPS(), vfc.Width(), vfc.Height()), vfc.subclipAudio(start, end), vfc.speedFactor)
+
+	return subclip, nil
+}
+
+// WithStart 设置源文件中的开始时间，结束时间不变；只能在当前范围内收缩
+func (vfc *VideoFileClip) WithStart(start time.Duration) (core.Clip, error) {
+	local := time.Duration(float64(start-vfc.Start()) / vfc.speed())
+	return vfc.Subclip(local, vfc.Duration())
+}
+
+// WithEnd 设置源文件中的结束时间，开始时间不变；只能在当前范围内收缩
+func (vfc *VideoFileClip) WithEnd(end time.Duration) (core.Clip, error) {
+	local := time.Duration(float64(end-vfc.Start()) / vfc.speed())
+	return vfc.Subclip(0, local)
+}
+
+// speed 返回有效的速度因子
+func (vfc *VideoFileClip) speed() float64 {
+	if vfc.speedFactor == 0 {
+		return 1.0
+	}
+	return vfc.speedFactor
+}
+
+// subclipAudio 截取与剪辑内时间段 [start, end) 对应的音轨，音频较短时截到其末尾
+func (vfc *VideoFileClip) subclipAudio(start, end time.Duration) core.AudioClip {
+	audioClip := vfc.Audio()
+	if audioClip == nil || start >= audioClip.Duration() {
+		return nil
+	}
+	if end > audioClip.Duration() {
+		end = audioClip.Duration()
+	}
+	sub, err := audioClip.Subclip(start, end)
+	if err != nil {
+		return nil
+	}
+	subAudio, _ := sub.(core.AudioClip)
+	return subAudio
+}
+
+// derive 创建共享同一读取器的派生剪辑，读取器引用计数加一
+//
+// 父剪辑和派生剪辑各自持有一个引用，可以按任意顺序关闭。audio 是为派生剪辑新建的音轨
+// （如父音轨的子剪辑）时由派生剪辑关闭；与父剪辑的音轨是同一对象时不关闭。
+func (vfc *VideoFileClip) derive(base *core.BaseVideoClip, audio core.AudioClip, speedFactor float64) *VideoFileClip {
+	clip := &VideoFileClip{
+		BaseVideoClip: base,
 		filename:      vfc.filename,
 		processMgr:    vfc.processMgr,
-		audio:         vfc.audio,
-		reader:        vfc.reader, // 共享同一个读取器
-		closed:        false,
-		speedFactor:   vfc.speedFactor, // 继承速度因子
+		audio:         audio,
+		ownsAudio:     audio != nil && audio != vfc.Audio(),
+		speedFactor:   speedFactor,
+		fullSize:      vfc.fullSize,
+		options:       vfc.options,
+	}
+	if reader := vfc.getReader(); reader != nil && reader.Retain() == nil {
+		clip.reader = reader
 	}
+	clip.leak = leakcheck.Track(clip, "VideoFileClip", vfc.filename)
+	return clip
+}
 
-	// 子剪辑不需要重新打开，因为它共享父剪辑的读取器
-	return subclip, nil
+// shareAudio 返回派生剪辑使用的音轨，文件音轨共享读取器以便独立关闭
+func (vfc *VideoFileClip) shareAudio() core.AudioClip {
+	audioClip := vfc.Audio()
+	if fileAudio, ok := audioClip.(*audio.AudioFileClip); ok {
+		return fileAudio.Clone()
+	}
+	return audioClip
 }
 
-// WithSpeed 调整播放速度
+// getReader 在读锁下返回当前读取器，未打开或已关闭时返回 nil
+func (vfc *VideoFileClip) getReader() *ffmpeg.VideoReader {
+	vfc.mutex.RLock()
+	defer vfc.mutex.RUnlock()
+	return vfc.reader
+}
+
+// WithSpeed 调整播放速度，音轨同步变速（音高随之改变）
 func (vfc *VideoFileClip) WithSpeed(factor float64) (core.Clip, error) {
+	return vfc.WithSpeedOptions(factor, nil)
+}
+
+// WithSpeedOptions 按选项调整播放速度，options 控制音轨是否保持音高
+func (vfc *VideoFileClip) WithSpeedOptions(factor float64, options *audio.SpeedOptions) (*VideoFileClip, error) {
 	if factor <= 0 {
 		return nil, core.ErrInvalidSpeedFactor
 	}
 
-	// 计算新的持续时间：速度加快时间变短，速度减慢时间变长
+	// 计算新的持续时间：速度加快时间变短，速度减慢时间变长；源文件范围不变
 	newDuration := time.Duration(float64(vfc.Duration()) / factor)
 
-	// 创建新的剪辑
-	speedClip := &VideoFileClip{
-		BaseVideoClip: core.NewBaseVideoClip(vfc.Start(), vfc.Start()+newDuration, newDuration, vfc.FPS(), vfc.Width(), vfc.Height()),
-		filename:      vfc.filename,
-		processMgr:    vfc.processMgr,
-		audio:         vfc.audio,
-		reader:        vfc.reader, // 共享同一个读取器
-		closed:        false,
-		speedFactor:   factor, // 添加速度因子字段
-	}
+	// 创建新的剪辑，速度因子相对源文件累乘
+	speedClip := vfc.derive(core.NewBaseVideoClip(vfc.Start(), vfc.End(), newDuration, vfc.FPS(), vfc.Width(), vfc.Height()), vfc.speedAudio(factor, options), vfc.speed()*factor)
 
 	return speedClip, nil
 }
 
+// speedAudio 返回按相同因子变速的音轨，音轨不支持变速时保持原样
+func (vfc *VideoFileClip) speedAudio(factor float64, options *audio.SpeedOptions) core.AudioClip {
+	switch audioClip := vfc.Audio().(type) {
+	case nil:
+		return nil
+	case *audio.AudioFileClip:
+		if sped, err := audioClip.WithSpeedOptions(factor, options); err == nil {
+			return sped
+		}
+	default:
+		if sped, err := audioClip.WithSpeed(factor); err == nil {
+			if spedAudio, ok := sped.(core.AudioClip); ok {
+				return spedAudio
+			}
+		}
+	}
+	return vfc.shareAudio()
+}
+
 // WithVolume 调整音量
 func (vfc *VideoFileClip) WithVolume(factor float64) (core.Clip, error) {
 	if factor < 0 {
@@ -158,31 +396,31 @@ func (vfc *VideoFileClip) WithVolume(factor float64) (core.Clip, error) {
 	}
 
 	// 创建新的剪辑
-	volumeClip := &VideoFileClip{
-		BaseVideoClip: core.NewBaseVideoClip(vfc.Start(), vfc.End(), vfc.Duration(), vfc.FPS(), vfc.Width(), vfc.Height()),
-		filename:      vfc.filename,
-		processMgr:    vfc.processMgr,
-		audio:         vfc.audio,  // 这里应该创建音量调整后的音频
-		reader:        vfc.reader, // 共享同一个读取器
-		closed:        false,
-		speedFactor:   vfc.speedFactor, // 继承速度因子
-	}
+	volumeClip := vfc.derive(core.NewBaseVideoClip(vfc.Start(), vfc.End(), vfc.Duration(), vfc.FPS(), vfc.Width(), vfc.Height()), vfc.volumeAudio(factor), vfc.speedFactor)
 
 	return volumeClip, nil
 }
 
+// volumeAudio 返回应用了增益的音轨，音轨不支持调整音量时保持原样
+func (vfc *VideoFileClip) volumeAudio(factor float64) core.AudioClip {
+	audioClip := vfc.Audio()
+	if audioClip == nil {
+		return nil
+	}
+	if adjusted, err := audioClip.WithVolume(factor); err == nil {
+		if adjustedAudio, ok := adjusted.(core.AudioClip); ok {
+			return adjustedAudio
+		}
+	}
+	return vfc.shareAudio()
+}
+
 // WithAudio 添加音频
 func (vfc *VideoFileClip) WithAudio(audio core.AudioClip) (core.Clip, error) {
 	// 创建新的剪辑
-	audioClip := &VideoFileClip{
-		BaseVideoClip: core.NewBaseVideoClip(vfc.Start(), vfc.End(), vfc.Duration(), vfc.FPS(), vfc.Width(), vfc.Height()),
-		filename:      vfc.filename,
-		processMgr:    vfc.processMgr,
-		audio:         audio,
-		reader:        vfc.reader, // 共享同一个读取器
-		closed:        false,
-		speedFactor:   vfc.speedFactor, // 继承速度因子
-	}
+	audioClip := vfc.derive(core.NewBaseVideoClip(vfc.Start(), vfc.End(), vfc.Duration(), vfc.FPS(), vfc.Width(), vfc.Height()), audio, vfc.speedFactor)
+	// 音轨由调用者创建，所有权仍归调用者
+	audioClip.ownsAudio = false
 
 	return audioClip, nil
 }
@@ -190,107 +428,212 @@ func (vfc *VideoFileClip) WithAudio(audio core.AudioClip) (core.Clip, error) {
 // WithoutAudio 移除音频
 func (vfc *VideoFileClip) WithoutAudio() (core.Clip, error) {
 	// 创建新的剪辑
-	noAudioClip := &VideoFileClip{
-		BaseVideoClip: core.NewBaseVideoClip(vfc.Start(), vfc.End(), vfc.Duration(), vfc.FPS(), vfc.Width(), vfc.Height()),
-		filename:      vfc.filename,
-		processMgr:    vfc.processMgr,
-		audio:         nil,
-		reader:        vfc.reader, // 共享同一个读取器
-		closed:        false,
-		speedFactor:   vfc.speedFactor, // 继承速度因子
-	}
+	noAudioClip := vfc.derive(core.NewBaseVideoClip(vfc.Start(), vfc.End(), vfc.Duration(), vfc.FPS(), vfc.Width(), vfc.Height()), nil, vfc.speedFactor)
 
 	return noAudioClip, nil
 }
 
 // WriteToFile 写入文件
 func (vfc *VideoFileClip) WriteToFile(filename string, options *core.WriteOptions) error {
-	if vfc.closed {
+	return core.TraceRender(options, vfc, filename, func(ctx context.Context) error {
+		return vfc.writeToFile(ctx, filename, options)
+	})
+}
+
+// writeToFile 逐帧渲染并写入，ctx 携带渲染区间
+func (vfc *VideoFileClip) writeToFile(ctx context.Context, filename string, options *core.WriteOptions) error {
+	if vfc.IsClosed() {
 		return fmt.Errorf("剪辑已关闭")
 	}
 
-	// 设置默认选项
-	if options == nil {
-		options = &core.WriteOptions{}
-	}
-	if options.Codec == "" {
-		options.Codec = "libx264"
-	}
-	if options.Bitrate == "" {
-		options.Bitrate = "1000k" // 降低比特率以提高兼容性
+	return Render(ctx, vfc, filename, options, RenderSpec{
+		Bitrate:    "1000k",         // 降低比特率以提高兼容性
+		FrameRate:  vfc.FrameRate(), // 默认沿用源文件的精确帧率
+		ProcessMgr: vfc.processMgr,
+		DryRun: func(clip core.VideoClip, options *core.WriteOptions) {
+			// 同时报告首帧的解码命令，后续帧仅 -ss 不同；代理切换后为原始分辨率剪辑的命令
+			rendered, ok := clip.(*VideoFileClip)
+			if !ok {
+				rendered = vfc
+			}
+			if command, ok := rendered.frameCommand(rendered.Start()); ok && options.OnCommand != nil {
+				options.OnCommand(command.Name, command.Args)
+			}
+		},
+	})
+}
+
+// frameCommand 返回解码源文件 t 处帧的 FFmpeg 命令，未打开时 ok 为 false
+func (vfc *VideoFileClip) frameCommand(t time.Duration) (command ffmpeg.Command, ok bool) {
+	reader := vfc.getReader()
+	if reader == nil {
+		return ffmpeg.Command{}, false
 	}
-	if options.FPS == 0 {
-		options.FPS = vfc.FPS()
+	if vfc.fullSize {
+		return reader.FrameCommandSize(t, 0, 0), true
 	}
+	return reader.FrameCommand(t), true
+}
 
-	// 创建视频写入器
-	writerOptions := &ffmpeg.VideoWriterOptions{
-		Codec:   options.Codec,
-		Bitrate: options.Bitrate,
-		FPS:     options.FPS,
+// Close 关闭剪辑
+func (vfc *VideoFileClip) Close() error {
+	vfc.mutex.Lock()
+	defer vfc.mutex.Unlock()
+
+	if vfc.closed {
+		return nil
 	}
 
-	writer := ffmpeg.NewVideoWriter(filename, vfc.Width(), vfc.Height(), writerOptions, vfc.processMgr)
+	vfc.closed = true
+	vfc.prefetch = nil
+	vfc.releaseLocked()
+	vfc.leak.Close()
 
-	// 打开写入器
-	if err := writer.Open(); err != nil {
-		return fmt.Errorf("打开写入器失败: %w", err)
+	return nil
+}
+
+// releaseLocked 释放读取器引用和自己创建的音轨，调用者需持有写锁
+//
+// 读取器在最后一个共享它的剪辑关闭时才真正关闭；WithAudio 传入的音轨归调用者所有，不在这里关闭。
+func (vfc *VideoFileClip) releaseLocked() {
+	if vfc.reader != nil {
+		vfc.reader.Release()
+		vfc.reader = nil
+	}
+	if vfc.audio != nil && vfc.ownsAudio {
+		vfc.audio.Close()
 	}
-	defer writer.Close()
+	vfc.audio = nil
+	vfc.ownsAudio = false
+}
 
-	// 计算总帧数
-	totalFrames := int(vfc.Duration().Seconds() * options.FPS)
-	frameInterval := time.Duration(float64(time.Second) / options.FPS)
+// IsClosed 检查是否已关闭
+func (vfc *VideoFileClip) IsClosed() bool {
+	vfc.mutex.RLock()
+	defer vfc.mutex.RUnlock()
+	return vfc.closed
+}
 
-	fmt.Printf("开始写入视频: %s\n", filename)
-	fmt.Printf("总帧数: %d, 帧间隔: %v\n", totalFrames, frameInterval)
+// Filename 返回源文件路径
+func (vfc *VideoFileClip) Filename() string {
+	return vfc.filename
+}
 
-	// 逐帧写入
-	for i := 0; i < totalFrames; i++ {
-		t := time.Duration(i) * frameInterval
-		if t > vfc.Duration() {
-			break
+// FrameRate 返回源文件的精确帧率，无法获取时由 FPS 换算
+func (vfc *VideoFileClip) FrameRate() ffmpeg.Rational {
+	if reader := vfc.getReader(); reader != nil {
+		if info := reader.GetInfo(); info != nil && !info.FrameRate.IsZero() {
+			return info.FrameRate
 		}
+	}
+	return ffmpeg.RationalFromFloat(vfc.FPS())
+}
 
-		frame, err := vfc.GetFrame(t)
-		if err != nil {
-			return fmt.Errorf("获取第 %d 帧失败: %w", i, err)
-		}
+// Audio 返回剪辑的音轨，没有音频时返回 nil
+func (vfc *VideoFileClip) Audio() core.AudioClip {
+	vfc.mutex.RLock()
+	defer vfc.mutex.RUnlock()
+	return vfc.audio
+}
 
-		if err := writer.WriteFrame(frame); err != nil {
-			return fmt.Errorf("写入第 %d 帧失败: %w", i, err)
-		}
+// SaveFrame 将 t 处的帧保存为图片，格式按扩展名（.png/.jpg/.webp）或 options.Format 决定
+func (vfc *VideoFileClip) SaveFrame(t time.Duration, path string, options *ffmpeg.ImageOptions) error {
+	frame, err := vfc.GetFrame(t)
+	if err != nil {
+		return fmt.Errorf("获取帧失败: %w", err)
+	}
+	if err := ffmpeg.SaveImage(context.Background(), frame, path, options, vfc.processMgr); err != nil {
+		return fmt.Errorf("保存帧失败: %w", err)
+	}
+	return nil
+}
 
-		// 显示进度
-		if i%100 == 0 {
-			progress := float64(i) / float64(totalFrames) * 100
-			fmt.Printf("进度: %.1f%% (%d/%d)\n", progress, i, totalFrames)
-		}
+// Preview 使用 ffplay 预览剪辑
+func (vfc *VideoFileClip) Preview(options *preview.PlayOptions) error {
+	if vfc.IsClosed() {
+		return fmt.Errorf("剪辑已关闭")
 	}
+	return preview.Play(vfc, options)
+}
 
-	fmt.Printf("视频写入完成: %s\n", filename)
-	return nil
+// EnableProxy 启用代理模式，解码时缩放到不超过 maxWidth 的宽度以加快预览和特效调试
+func (vfc *VideoFileClip) EnableProxy(maxWidth int) error {
+	reader := vfc.getReader()
+	if reader == nil {
+		return fmt.Errorf("视频未打开")
+	}
+	if maxWidth <= 0 {
+		return fmt.Errorf("无效的代理宽度: %d", maxWidth)
+	}
+	// 已预取的帧是旧尺寸
+	defer vfc.resetPrefetch()
+
+	info := reader.GetInfo()
+	if info.Width <= maxWidth {
+		return reader.SetOutputSize(0, 0)
+	}
+
+	// 保持宽高比并确保尺寸为偶数
+	width := maxWidth &^ 1
+	height := (info.Height * width / info.Width) &^ 1
+	if height < 2 {
+		height = 2
+	}
+	return reader.SetOutputSize(width, height)
 }
 
-// Close 关闭剪辑
-func (vfc *VideoFileClip) Close() error {
-	if vfc.closed {
-		return nil
+// DisableProxy 关闭代理模式，恢复原始分辨率解码
+func (vfc *VideoFileClip) DisableProxy() {
+	if reader := vfc.getReader(); reader != nil {
+		reader.SetOutputSize(0, 0)
+		vfc.resetPrefetch()
 	}
+}
 
-	vfc.closed = true
+// WithoutProxy 代理模式下返回按原始分辨率解码的派生剪辑，供 Render 在本次渲染中使用；不处于代理模式时返回 nil
+//
+// 派生剪辑共享读取器但不修改其输出尺寸，同一读取器上的其他子剪辑和预览仍按代理尺寸解码。
+func (vfc *VideoFileClip) WithoutProxy() (core.VideoClip, error) {
+	if !vfc.IsProxy() {
+		return nil, nil
+	}
+	info := vfc.getReader().GetInfo()
+	clip := vfc.derive(core.NewBaseVideoClip(vfc.Start(), vfc.End(), vfc.Duration(), vfc.FPS(), info.Width, info.Height), vfc.shareAudio(), vfc.speedFactor)
+	clip.fullSize = true
+	return clip, nil
+}
 
-	// 关闭读取器
-	if vfc.reader != nil {
-		// 读取器没有 Close 方法，但我们可以标记为关闭
-		vfc.reader = nil
+// IsProxy 检查是否处于代理模式
+func (vfc *VideoFileClip) IsProxy() bool {
+	reader := vfc.getReader()
+	if reader == nil || vfc.fullSize {
+		return false
 	}
+	info := reader.GetInfo()
+	width, height := reader.OutputSize()
+	return info != nil && (width != info.Width || height != info.Height)
+}
 
-	// 关闭音频
-	if vfc.audio != nil {
-		vfc.audio.Close()
-		vfc.audio = nil
+// Size 返回当前解码尺寸，代理模式下为缩小后的尺寸
+func (vfc *VideoFileClip) Size() (width, height int) {
+	reader := vfc.getReader()
+	if reader == nil {
+		return vfc.BaseVideoClip.Size()
+	}
+	if info := reader.GetInfo(); vfc.fullSize && info != nil {
+		return info.Width, info.Height
 	}
+	return reader.OutputSize()
+}
 
-	return nil
+// Width 返回当前解码宽度
+func (vfc *VideoFileClip) Width() int {
+	width, _ := vfc.Size()
+	return width
+}
+
+// Height 返回当前解码高度
+func (vfc *VideoFileClip) Height() int {
+	_, height := vfc.Size()
+	return height
 }
@@ -0,0 +1,52 @@
+package video
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/core/coretest"
+)
+
+func TestVideoFileClipWithVolumeScalesAudio(t *testing.T) {
+	source := NewVideoFileClip("in.mp4", nil)
+	defer source.Close()
+	sine := coretest.NewSineClip(440, 0.8, time.Second, 2, 8000)
+	withAudio, err := source.WithAudio(sine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer withAudio.Close()
+
+	quiet, err := withAudio.WithVolume(0.25)
+	if err != nil {
+		t.Fatalf("WithVolume 失败: %v", err)
+	}
+	defer quiet.Close()
+
+	original, err := withAudio.(*VideoFileClip).Audio().GetAudioFrame(300 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaled, err := quiet.(*VideoFileClip).Audio().GetAudioFrame(300 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scaled) != len(original) {
+		t.Fatalf("样本数 %d，期望 %d", len(scaled), len(original))
+	}
+	peak := 0.0
+	for i := range original {
+		if math.Abs(scaled[i]-original[i]*0.25) > 1e-9 {
+			t.Fatalf("第 %d 个样本 %g，期望 %g", i, scaled[i], original[i]*0.25)
+		}
+		peak = math.Max(peak, math.Abs(scaled[i]))
+	}
+	if peak < 0.19 || peak > 0.2+1e-9 {
+		t.Errorf("调整后的峰值 %g，期望约 0.2", peak)
+	}
+	if _, err := withAudio.WithVolume(-1); err != core.ErrInvalidVolumeFactor {
+		t.Errorf("负增益返回 %v，期望 ErrInvalidVolumeFactor", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package moviego 提供跨越各子包的进程级操作，如服务退出或测试结束时的 Shutdown
+package moviego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"moviepy-go/pkg/core"
+	"moviepy-go/pkg/ffmpeg"
+	"moviepy-go/pkg/leakcheck"
+)
+
+// shutdownGrace 取消渲染并终止进程后等待渲染返回的时间
+const shutdownGrace = 5 * time.Second
+
+// ShutdownReport Shutdown 的结果
+type ShutdownReport struct {
+	Drained    int               // 在期限内正常结束的渲染数
+	Cancelled  []core.RenderInfo // 期限到达时仍在进行、被取消的渲染
+	Terminated int               // 被终止的 FFmpeg/ffprobe 进程数
+	Remaining  []core.RenderInfo // 取消后宽限期内仍未返回的渲染
+	Leaks      []leakcheck.Leak  // 仍未关闭的剪辑、读取器和写入器，需启用 leakcheck
+}
+
+// String 返回可打印的摘要
+func (r *ShutdownReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "完成 %d 个渲染，取消 %d 个，终止 %d 个进程", r.Drained, len(r.Cancelled), r.Terminated)
+	if len(r.Remaining) > 0 {
+		fmt.Fprintf(&b, "，%d 个渲染未能退出", len(r.Remaining))
+	}
+	if len(r.Leaks) > 0 {
+		fmt.Fprintf(&b, "，%d 个对象未关闭:", len(r.Leaks))
+		for _, leak := range r.Leaks {
+			fmt.Fprintf(&b, "\n  %s %s（创建于 %s）", leak.Kind, leak.Name, leak.Created.Format(time.RFC3339))
+		}
+	}
+	return b.String()
+}
+
+// Shutdown 关闭进程内的所有媒体处理：等待进行中的 WriteToFile 结束，ctx 到期时取消剩余的渲染，
+// 然后终止所有进程管理器仍在运行的 FFmpeg/ffprobe 进程，并报告未关闭的对象
+//
+// 用于服务优雅退出和测试清理。有渲染被取消或未能退出、或发现未关闭的对象时返回错误，
+// 报告总是非 nil。进程管理器本身不被关闭，由创建者负责。未关闭对象的报告需要启用
+// leakcheck（MOVIEGO_LEAKCHECK=1 或 leakcheck.Enable）。
+func Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	report := &ShutdownReport{}
+	active := len(core.ActiveRenders())
+
+	var errs []error
+	if err := core.DrainRenders(ctx); err != nil {
+		report.Cancelled = core.CancelRenders()
+		errs = append(errs, fmt.Errorf("%d 个渲染未在期限内完成，已取消: %w", len(report.Cancelled), err))
+	}
+	report.Drained = max(0, active-len(report.Cancelled))
+
+	report.Terminated = ffmpeg.TerminateAll()
+
+	if len(report.Cancelled) > 0 {
+		grace, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer cancel()
+		if core.DrainRenders(grace) != nil {
+			report.Remaining = core.ActiveRenders()
+			errs = append(errs, fmt.Errorf("%d 个渲染在取消后 %v 内未退出", len(report.Remaining), shutdownGrace))
+		}
+	}
+
+	// 进程管理器通常由服务在 Shutdown 之后关闭，不计入
+	for _, leak := range leakcheck.Open() {
+		if leak.Kind != "ProcessManager" {
+			report.Leaks = append(report.Leaks, leak)
+		}
+	}
+	if len(report.Leaks) > 0 {
+		errs = append(errs, fmt.Errorf("%d 个对象未关闭", len(report.Leaks)))
+	}
+	return report, errors.Join(errs...)
+}